@@ -3,16 +3,25 @@ package analysis
 import (
 	"encoding/json"
 	"fmt"
+	"math"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"github.com/argoproj/argo-rollouts/analysis/hook"
+	"github.com/argoproj/argo-rollouts/analysis/sink"
+	"github.com/argoproj/argo-rollouts/metricproviders"
+	"github.com/argoproj/argo-rollouts/metricproviders/prometheus"
 	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
 	analysisutil "github.com/argoproj/argo-rollouts/utils/analysis"
+	"github.com/argoproj/argo-rollouts/utils/cron"
 	"github.com/argoproj/argo-rollouts/utils/defaults"
 	logutil "github.com/argoproj/argo-rollouts/utils/log"
 	templateutil "github.com/argoproj/argo-rollouts/utils/template"
@@ -25,12 +34,36 @@ const (
 	// DefaultErrorRetryInterval is the default interval to retry a measurement upon error, in the
 	// event an interval was not specified
 	DefaultErrorRetryInterval time.Duration = 10 * time.Second
+	// ProviderConfigReloadInterval is the interval at which the metric provider defaults, rate limits and circuit
+	// breakers are reloaded from the controller ConfigMap
+	ProviderConfigReloadInterval time.Duration = 1 * time.Minute
+	// DefaultRateLimitedRetryInterval is the interval to retry a measurement which was not taken because the rate
+	// limit of its metric provider backend was reached
+	DefaultRateLimitedRetryInterval time.Duration = 1 * time.Second
+	// MinDecisionInterval is the shortest interval between the measurements of a metric near its decision
+	MinDecisionInterval time.Duration = 5 * time.Second
+	// FallbackProviderMetadataKey is the key of the measurement metadata holding the type of the fallback provider
+	// which took the measurement
+	FallbackProviderMetadataKey = "fallback-provider"
+	// IntervalWindowMetadataKey is the key of the measurement metadata holding the interval window of the
+	// measurement, which is the index of the measurement among the measurements of the metric
+	IntervalWindowMetadataKey = "interval-window"
 )
 
 // Event reasons for analysis events
 const (
 	EventReasonStatusFailed    = "Failed"
 	EventReasonStatusCompleted = "Complete"
+	// EventReasonMetricFailed is the reason of the event of a metric which completed Failed
+	EventReasonMetricFailed = "MetricFailed"
+	// EventReasonMetricSucceeded is the reason of the event of a metric which completed Successful
+	EventReasonMetricSucceeded = "MetricSucceeded"
+	// EventReasonMeasurementError is the reason of the event of a measurement which errored after a measurement
+	// which did not, and of a metric which completed Error
+	EventReasonMeasurementError = "MeasurementError"
+	// EventReasonMetricMarginal is the reason of the event of a metric which completed Inconclusive with a
+	// marginalBand, which pauses the rollout until an operator promotes or aborts it
+	EventReasonMetricMarginal = "MetricMarginal"
 )
 
 // metricTask holds the metric which need to be measured during this reconciliation along with
@@ -50,6 +83,9 @@ func (c *Controller) reconcileAnalysisRun(origRun *v1alpha1.AnalysisRun) *v1alph
 	if run.Status.MetricResults == nil {
 		run.Status.MetricResults = make([]v1alpha1.MetricResult, 0)
 		err := analysisutil.ValidateMetrics(run.Spec.Metrics)
+		if err == nil {
+			err = metricproviders.ValidateAllowedProviders(run.Spec.Metrics, c.allowedProviders)
+		}
 		if err != nil {
 			message := fmt.Sprintf("analysis spec invalid: %v", err)
 			log.Warn(message)
@@ -60,9 +96,21 @@ func (c *Controller) reconcileAnalysisRun(origRun *v1alpha1.AnalysisRun) *v1alph
 		}
 	}
 
+	// the settings the metrics do not specify are taken from the provider defaults of the namespace. Only the status
+	// of the run is persisted, so the defaults in effect are re-applied on every reconciliation
+	for i := range run.Spec.Metrics {
+		metricproviders.ApplyProviderDefaults(c.getProviderDefaults(), run.Namespace, &run.Spec.Metrics[i])
+	}
+
+	if run.Spec.Suspend && !run.Spec.Terminate {
+		// The run is requeued when it is resumed since clearing the flag updates it
+		log.Info("analysis run is suspended: skipping measurements")
+		return run
+	}
+
 	tasks := generateMetricTasks(run)
 	log.Infof("taking %d measurements", len(tasks))
-	err := c.runMeasurements(run, tasks)
+	rateLimited, err := c.runMeasurements(run, tasks)
 	if err != nil {
 		message := fmt.Sprintf("unable to resolve metric arguments: %v", err)
 		log.Warn(message)
@@ -93,8 +141,22 @@ func (c *Controller) reconcileAnalysisRun(origRun *v1alpha1.AnalysisRun) *v1alph
 		// TODO(jessesuen): surface errors to controller so they can be retried
 		log.Warnf("Failed to garbage collect measurements: %v", err)
 	}
+	if c.maxMeasurementsPerRun > 0 {
+		trimMeasurementsPerRun(run, c.maxMeasurementsPerRun)
+	}
+	if run.Status.Phase.Completed() {
+		// The summary is computed once the measurements are garbage collected, so that its statistics describe the
+		// measurements retained in the status
+		run.Status.Summary = analysisutil.SummarizeMetrics(run)
+	}
 
 	nextReconcileTime := calculateNextReconcileTime(run)
+	if rateLimited && !run.Status.Phase.Completed() {
+		retryTime := time.Now().Add(DefaultRateLimitedRetryInterval)
+		if nextReconcileTime == nil || nextReconcileTime.After(retryTime) {
+			nextReconcileTime = &retryTime
+		}
+	}
 	if nextReconcileTime != nil {
 		enqueueSeconds := nextReconcileTime.Sub(time.Now())
 		if enqueueSeconds < 0 {
@@ -106,6 +168,19 @@ func (c *Controller) reconcileAnalysisRun(origRun *v1alpha1.AnalysisRun) *v1alph
 	return run
 }
 
+// notifyOutcome notifies the webhook of the outcome of the run once its completion is persisted
+func (c *Controller) notifyOutcome(origRun, run *v1alpha1.AnalysisRun) {
+	if c.hookNotifier == nil || origRun.Status.Phase.Completed() || !run.Status.Phase.Completed() {
+		return
+	}
+	webhook := hook.Webhook(run)
+	if webhook == nil {
+		return
+	}
+	logutil.WithAnalysisRun(run).Infof("Notifying the analysis hook of the outcome %s", run.Status.Phase)
+	c.hookNotifier.Notify(*webhook, hook.NewOutcome(run))
+}
+
 // resolveMetricArgs resolves args for single metric in AnalysisRun
 // Returns resolved metric
 // Uses ResolveQuotedArgs to handle escaped quotes
@@ -173,6 +248,20 @@ func generateMetricTasks(run *v1alpha1.AnalysisRun) []metricTask {
 					continue
 				}
 			}
+			if metric.Schedule != "" {
+				if run.Status.StartedAt == nil {
+					continue
+				}
+				scheduledTime, err := nextScheduledTime(run, metric, nil)
+				if err != nil {
+					logCtx.Warnf("failed to compute the scheduled time: %v", err)
+					continue
+				}
+				if scheduledTime.After(time.Now()) {
+					logCtx.Infof("waiting until the scheduled time of the first measurement")
+					continue
+				}
+			}
 			// measurement never taken
 			tasks = append(tasks, metricTask{metric: metric})
 			logCtx.Infof("running initial measurement")
@@ -184,9 +273,24 @@ func generateMetricTasks(run *v1alpha1.AnalysisRun) []metricTask {
 			// we have reached desired count
 			continue
 		}
+		if maxMeasurementsReached(metric, *metricResult) {
+			continue
+		}
 		// if we get here, we know we need to take a measurement (eventually). check last measurement
 		// to decide if it should be taken now. metric.Interval can be null because we may be
 		// retrying a metric due to error.
+		if metric.Schedule != "" {
+			scheduledTime, err := nextScheduledTime(run, metric, lastMeasurement)
+			if err != nil {
+				logCtx.Warnf("failed to compute the scheduled time: %v", err)
+				continue
+			}
+			if !scheduledTime.After(time.Now()) {
+				tasks = append(tasks, metricTask{metric: metric})
+				logCtx.Infof("running scheduled measurement")
+			}
+			continue
+		}
 		interval := DefaultErrorRetryInterval
 		if metric.Interval != "" {
 			metricInterval, err := metric.Interval.Duration()
@@ -196,6 +300,7 @@ func generateMetricTasks(run *v1alpha1.AnalysisRun) []metricTask {
 			}
 			interval = metricInterval
 		}
+		interval = decisionInterval(metric, *metricResult, interval, logCtx)
 		if time.Now().After(lastMeasurement.FinishedAt.Add(interval)) {
 			tasks = append(tasks, metricTask{metric: metric})
 			logCtx.Infof("running overdue measurement")
@@ -262,18 +367,20 @@ func (c *Controller) resolveArgs(tasks []metricTask, args []v1alpha1.Argument, n
 }
 
 // runMeasurements iterates a list of metric tasks, and runs, resumes, or terminates measurements
-func (c *Controller) runMeasurements(run *v1alpha1.AnalysisRun, tasks []metricTask) error {
+func (c *Controller) runMeasurements(run *v1alpha1.AnalysisRun, tasks []metricTask) (bool, error) {
 	var wg sync.WaitGroup
 	// resultsLock should be held whenever we are accessing or setting status.metricResults since
 	// we are performing queries in parallel
 	var resultsLock sync.Mutex
 	terminating := analysisutil.IsTerminating(run)
+	// rateLimited is set when a measurement is postponed because its provider backend is rate limited
+	var rateLimited int32
 
 	// resolve args for metricTasks
 	// get list of secret values for log redaction
 	tasks, secrets, err := c.resolveArgs(tasks, run.Spec.Args, run.Namespace)
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	for _, task := range tasks {
@@ -295,8 +402,55 @@ func (c *Controller) runMeasurements(run *v1alpha1.AnalysisRun, tasks []metricTa
 				}
 			}
 
+			// The interval window of a new measurement is derived from the recorded measurements. A measurement which
+			// was already taken for the window, such as by the controller before a restart, is resumed while it is
+			// in-progress and skipped once completed, so that the window is only counted once.
+			intervalWindow := metricResult.Count + metricResult.Error
+			if t.incompleteMeasurement != nil {
+				if window, ok := measurementIntervalWindow(*t.incompleteMeasurement); ok {
+					intervalWindow = window
+				}
+			} else if taken := intervalWindowMeasurement(metricResult, intervalWindow); taken != nil {
+				if taken.FinishedAt != nil {
+					log.Infof("skipping measurement: interval window %d already recorded", intervalWindow)
+					return
+				}
+				log.Infof("resuming in-progress measurement of interval window %d", intervalWindow)
+				t.incompleteMeasurement = taken
+			}
+
+			// Measurements are postponed, rather than errored, until the provider backend is below its rate limit.
+			// Only new measurements are rate limited: in-progress measurements are always resumed or terminated,
+			// since they do not start a new query of the provider backend.
+			if t.incompleteMeasurement == nil && !c.rateLimiters.TryAccept(run.Namespace, t.metric) {
+				log.Infof("measurement postponed: %s provider rate limit reached", metricproviders.Type(t.metric))
+				atomic.StoreInt32(&rateLimited, 1)
+				if len(metricResult.Measurements) == 0 {
+					// record the metric result so the run is not assessed as completed before the first measurement
+					metricResult.Phase = v1alpha1.AnalysisPhasePending
+					resultsLock.Lock()
+					analysisutil.SetResult(run, *metricResult)
+					resultsLock.Unlock()
+				}
+				return
+			}
+
 			var newMeasurement v1alpha1.Measurement
-			provider, err := c.newProvider(*log, t.metric)
+			metric := t.metric
+			if metric.RecentResultsWindow > 0 {
+				metric.RecentValues = recentValues(metricResult, int(metric.RecentResultsWindow)-1)
+			}
+			if capturesBaselineOnce(metric) {
+				// the provider reuses the baseline captured at the start of the run, without modifying the spec
+				prometheusMetric := *metric.Provider.Prometheus
+				prometheusMetric.CapturedBaseline = metricResult.Baseline
+				metric.Provider.Prometheus = &prometheusMetric
+			}
+			if t.incompleteMeasurement != nil && isFallbackMeasurement(*t.incompleteMeasurement) {
+				// the in-progress measurement was started by the fallback provider
+				metric = fallbackMetric(metric)
+			}
+			provider, err := c.newProvider(*log, metric)
 			if err != nil {
 				if t.incompleteMeasurement != nil {
 					newMeasurement = *t.incompleteMeasurement
@@ -308,21 +462,39 @@ func (c *Controller) runMeasurements(run *v1alpha1.AnalysisRun, tasks []metricTa
 				newMeasurement.Message = err.Error()
 			} else {
 				if t.incompleteMeasurement == nil {
-					newMeasurement = provider.Run(run, t.metric)
+					// The backend is not queried while its circuit is open, so that a backend which is down is
+					// not flooded with requests by every AnalysisRun
+					if c.circuitBreakers.Allow(metric) {
+						newMeasurement = c.runProvider(provider, run, metric)
+						c.circuitBreakers.Record(metric, newMeasurement.Phase)
+					} else {
+						log.Warnf("measurement short-circuited: %s provider circuit breaker is open", metricproviders.Type(metric))
+						newMeasurement = c.circuitBreakers.OpenMeasurement(metric)
+					}
 				} else {
 					// metric is incomplete. either terminate or resume it
 					if terminating {
 						log.Infof("terminating in-progress measurement")
-						newMeasurement = provider.Terminate(run, t.metric, *t.incompleteMeasurement)
+						newMeasurement = provider.Terminate(run, metric, *t.incompleteMeasurement)
 						if newMeasurement.Phase == v1alpha1.AnalysisPhaseSuccessful {
 							newMeasurement.Message = "metric terminated"
 						}
 					} else {
-						newMeasurement = provider.Resume(run, t.metric, *t.incompleteMeasurement)
+						newMeasurement = provider.Resume(run, metric, *t.incompleteMeasurement)
+						c.circuitBreakers.Record(metric, newMeasurement.Phase)
 					}
 				}
 			}
 
+			// Only errors of the provider are retried with the fallback provider. Measurements which do not meet
+			// the conditions are not.
+			if t.incompleteMeasurement == nil && newMeasurement.Phase == v1alpha1.AnalysisPhaseError && t.metric.FallbackProvider != nil {
+				log.Warnf("measurement had error: %s, querying the fallback provider", newMeasurement.Message)
+				newMeasurement = c.runFallbackProvider(run, t.metric, newMeasurement, *log)
+			}
+
+			newMeasurement.Metadata = withIntervalWindow(newMeasurement.Metadata, intervalWindow)
+
 			if newMeasurement.Phase.Completed() {
 				log.Infof("measurement completed %s", newMeasurement.Phase)
 				if newMeasurement.FinishedAt == nil {
@@ -344,6 +516,10 @@ func (c *Controller) runMeasurements(run *v1alpha1.AnalysisRun, tasks []metricTa
 					metricResult.ConsecutiveError = 0
 				case v1alpha1.AnalysisPhaseError:
 					metricResult.Error++
+					if inInitialErrorGrace(t.metric, *metricResult) {
+						log.Warnf("measurement had error within the initialErrorGrace (%d): %s", t.metric.InitialErrorGrace, newMeasurement.Message)
+						break
+					}
 					metricResult.ConsecutiveError++
 					log.Warnf("measurement had error: %s", newMeasurement.Message)
 				}
@@ -356,6 +532,21 @@ func (c *Controller) runMeasurements(run *v1alpha1.AnalysisRun, tasks []metricTa
 				}
 			}
 
+			// Only the first of consecutive measurement errors is recorded, so that an erroring metric does not record
+			// an event every interval
+			if newMeasurement.Phase == v1alpha1.AnalysisPhaseError && lastCompletedMeasurementPhase(metricResult) != v1alpha1.AnalysisPhaseError {
+				c.recorder.Eventf(run, corev1.EventTypeWarning, EventReasonMeasurementError, "metric '%s' measurement error: %s", t.metric.Name, newMeasurement.Message)
+			}
+
+			if newMeasurement.Phase.Completed() && c.measurementSink != nil {
+				c.measurementSink.Push(sink.NewRecord(run, t.metric.Name, intervalWindow, newMeasurement))
+			}
+
+			if baseline := capturedBaseline(t.metric, *metricResult, newMeasurement); baseline != "" {
+				log.Infof("captured baseline %s", baseline)
+				metricResult.Baseline = baseline
+			}
+
 			if t.incompleteMeasurement == nil {
 				metricResult.Measurements = append(metricResult.Measurements, newMeasurement)
 			} else {
@@ -370,15 +561,177 @@ func (c *Controller) runMeasurements(run *v1alpha1.AnalysisRun, tasks []metricTa
 	}
 	wg.Wait()
 
+	return atomic.LoadInt32(&rateLimited) == 1, nil
+}
+
+// inInitialErrorGrace returns whether the last error of the metric is ignored by its initialErrorGrace, which only
+// applies to the errors taken before any measurement completed without error
+func inInitialErrorGrace(metric v1alpha1.Metric, result v1alpha1.MetricResult) bool {
+	return result.Count == 0 && result.Error <= metric.InitialErrorGrace
+}
+
+// lastCompletedMeasurementPhase returns the phase of the last completed measurement of the metric
+func lastCompletedMeasurementPhase(metricResult *v1alpha1.MetricResult) v1alpha1.AnalysisPhase {
+	for i := len(metricResult.Measurements) - 1; i >= 0; i-- {
+		if metricResult.Measurements[i].Phase.Completed() {
+			return metricResult.Measurements[i].Phase
+		}
+	}
+	return ""
+}
+
+// recentValues returns the values of the most recent successful measurements, oldest first, up to the limit
+func recentValues(metricResult *v1alpha1.MetricResult, limit int) []string {
+	values := []string{}
+	for i := len(metricResult.Measurements) - 1; i >= 0 && len(values) < limit; i-- {
+		if metricResult.Measurements[i].Phase == v1alpha1.AnalysisPhaseSuccessful {
+			values = append([]string{metricResult.Measurements[i].Value}, values...)
+		}
+	}
+	return values
+}
+
+// fallbackMetric returns the metric with its provider replaced by its fallback provider
+func fallbackMetric(metric v1alpha1.Metric) v1alpha1.Metric {
+	fallback := metric.DeepCopy()
+	fallback.Provider = metric.FallbackProvider.MetricProvider()
+	fallback.FallbackProvider = nil
+	return *fallback
+}
+
+// capturesBaselineOnce returns whether the metric captures its baseline at the start of the run rather than with each
+// measurement
+func capturesBaselineOnce(metric v1alpha1.Metric) bool {
+	return metric.Provider.Prometheus != nil && metric.Provider.Prometheus.BaselineCapture == v1alpha1.BaselineCaptureRunStart
+}
+
+// capturedBaseline returns the baseline queried by the measurement which is captured for the rest of the run, or an
+// empty string if the metric does not capture its baseline once or already captured it. The baselines of errored or
+// fallback measurements, and NaN baselines, are not captured, so that the next measurement queries the baseline again
+func capturedBaseline(metric v1alpha1.Metric, metricResult v1alpha1.MetricResult, measurement v1alpha1.Measurement) string {
+	if !capturesBaselineOnce(metric) || metricResult.Baseline != "" {
+		return ""
+	}
+	if !measurement.Phase.Completed() || measurement.Phase == v1alpha1.AnalysisPhaseError || isFallbackMeasurement(measurement) {
+		return ""
+	}
+	baseline := measurement.Metadata[prometheus.BaselineMetadataKey]
+	if value, err := strconv.ParseFloat(baseline, 64); err != nil || math.IsNaN(value) {
+		return ""
+	}
+	return baseline
+}
+
+// measurementIntervalWindow returns the interval window recorded in the metadata of the measurement, if any
+func measurementIntervalWindow(measurement v1alpha1.Measurement) (int32, bool) {
+	window, err := strconv.ParseInt(measurement.Metadata[IntervalWindowMetadataKey], 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return int32(window), true
+}
+
+// intervalWindowMeasurement returns the recorded measurement of the metric taken for the interval window, if any
+func intervalWindowMeasurement(metricResult *v1alpha1.MetricResult, intervalWindow int32) *v1alpha1.Measurement {
+	for i := len(metricResult.Measurements) - 1; i >= 0; i-- {
+		if window, ok := measurementIntervalWindow(metricResult.Measurements[i]); ok && window == intervalWindow {
+			return &metricResult.Measurements[i]
+		}
+	}
 	return nil
 }
 
+// withIntervalWindow returns the measurement metadata with the interval window of the measurement. The metadata
+// is copied, since it may be shared with the measurement returned by the provider.
+func withIntervalWindow(metadata map[string]string, intervalWindow int32) map[string]string {
+	withWindow := make(map[string]string, len(metadata)+1)
+	for k, v := range metadata {
+		withWindow[k] = v
+	}
+	withWindow[IntervalWindowMetadataKey] = strconv.Itoa(int(intervalWindow))
+	return withWindow
+}
+
+// isFallbackMeasurement returns whether the measurement was taken by the fallback provider of the metric
+func isFallbackMeasurement(measurement v1alpha1.Measurement) bool {
+	_, ok := measurement.Metadata[FallbackProviderMetadataKey]
+	return ok
+}
+
+// runProvider takes a new measurement of the metric with the provider, recording the latency of the provider
+func (c *Controller) runProvider(provider metricproviders.Provider, run *v1alpha1.AnalysisRun, metric v1alpha1.Metric) v1alpha1.Measurement {
+	start := time.Now()
+	measurement := provider.Run(run, metric)
+	c.metricsServer.ObserveProviderLatency(metricproviders.Type(metric), time.Since(start))
+	return measurement
+}
+
+// runFallbackProvider takes a new measurement of the metric using its fallback provider after the measurement of
+// its provider errored. The measurement is marked so in-progress measurements are resumed by the fallback provider.
+func (c *Controller) runFallbackProvider(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, errMeasurement v1alpha1.Measurement, logCtx log.Entry) v1alpha1.Measurement {
+	fallback := fallbackMetric(metric)
+	provider, err := c.newProvider(logCtx, fallback)
+	if err != nil {
+		errMeasurement.Message = fmt.Sprintf("%s; fallback provider error: %v", errMeasurement.Message, err)
+		return errMeasurement
+	}
+	measurement := c.runProvider(provider, run, fallback)
+	if measurement.Phase == v1alpha1.AnalysisPhaseError {
+		measurement.Message = fmt.Sprintf("%s; fallback provider error: %s", errMeasurement.Message, measurement.Message)
+	}
+	if measurement.Metadata == nil {
+		measurement.Metadata = map[string]string{}
+	}
+	measurement.Metadata[FallbackProviderMetadataKey] = provider.Type()
+	return measurement
+}
+
+// recordMetricCompletedEvent records an event with the value of the last measurement and the conditions of a metric
+// which completed
+func (c *Controller) recordMetricCompletedEvent(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, result v1alpha1.MetricResult, metricStatus v1alpha1.AnalysisPhase) {
+	value := ""
+	if lastMeasurement := analysisutil.LastMeasurement(run, metric.Name); lastMeasurement != nil {
+		value = lastMeasurement.Value
+	}
+	conditions := []string{}
+	if metric.SuccessCondition != "" {
+		conditions = append(conditions, fmt.Sprintf("successCondition: '%s'", metric.SuccessCondition))
+	}
+	if metric.FailureCondition != "" {
+		conditions = append(conditions, fmt.Sprintf("failureCondition: '%s'", metric.FailureCondition))
+	}
+	conditionsStr := ""
+	if len(conditions) > 0 {
+		conditionsStr = fmt.Sprintf(" (%s)", strings.Join(conditions, ", "))
+	}
+	switch metricStatus {
+	case v1alpha1.AnalysisPhaseSuccessful:
+		c.recorder.Eventf(run, corev1.EventTypeNormal, EventReasonMetricSucceeded, "metric '%s' succeeded with value '%s'%s", metric.Name, value, conditionsStr)
+	case v1alpha1.AnalysisPhaseFailed:
+		c.recorder.Eventf(run, corev1.EventTypeWarning, EventReasonMetricFailed, "metric '%s' failed with value '%s'%s", metric.Name, value, conditionsStr)
+	case v1alpha1.AnalysisPhaseError:
+		c.recorder.Eventf(run, corev1.EventTypeWarning, EventReasonMeasurementError, "metric '%s' completed Error after %d consecutive measurement errors", metric.Name, result.ConsecutiveError)
+	case v1alpha1.AnalysisPhaseInconclusive:
+		if metric.MarginalBand != nil {
+			c.recorder.Eventf(run, corev1.EventTypeWarning, EventReasonMetricMarginal, "metric '%s' is marginal with value '%s' (marginalBand: [%s, %s]), awaiting promotion or abort", metric.Name, value, metric.MarginalBand.Lower, metric.MarginalBand.Upper)
+			break
+		}
+		fallthrough
+	default:
+		c.recorder.Eventf(run, corev1.EventTypeNormal, EventReasonStatusCompleted, "metric '%s' completed %s", metric.Name, metricStatus)
+	}
+}
+
 // assessRunStatus assesses the overall status of this AnalysisRun
 // If any metric is not yet completed, the AnalysisRun is still considered Running
-// Once all metrics are complete, the worst status is used as the overall AnalysisRun status
+// Once all metrics are complete, the worst status is used as the overall AnalysisRun status, unless the errored
+// metrics are inconclusive according to the metricErrorPolicy of the run
 func (c *Controller) assessRunStatus(run *v1alpha1.AnalysisRun) (v1alpha1.AnalysisPhase, string) {
 	var worstStatus v1alpha1.AnalysisPhase
 	var worstMessage string
+	var errored bool
+	var erroredMessage string
+	errorsInconclusive := run.Spec.MetricErrorPolicy == v1alpha1.MetricErrorPolicyInconclusive
 	terminating := analysisutil.IsTerminating(run)
 	everythingCompleted := true
 
@@ -395,12 +748,7 @@ func (c *Controller) assessRunStatus(run *v1alpha1.AnalysisRun) (v1alpha1.Analys
 			if result.Phase != metricStatus {
 				log.Infof("metric transitioned from %s -> %s", result.Phase, metricStatus)
 				if metricStatus.Completed() {
-					switch metricStatus {
-					case v1alpha1.AnalysisPhaseError, v1alpha1.AnalysisPhaseFailed:
-						c.recorder.Eventf(run, corev1.EventTypeWarning, EventReasonStatusFailed, "metric '%s' completed %s", metric.Name, metricStatus)
-					default:
-						c.recorder.Eventf(run, corev1.EventTypeNormal, EventReasonStatusCompleted, "metric '%s' completed %s", metric.Name, metricStatus)
-					}
+					c.recordMetricCompletedEvent(run, metric, *result, metricStatus)
 				}
 				if lastMeasurement := analysisutil.LastMeasurement(run, metric.Name); lastMeasurement != nil {
 					result.Message = lastMeasurement.Message
@@ -411,28 +759,59 @@ func (c *Controller) assessRunStatus(run *v1alpha1.AnalysisRun) (v1alpha1.Analys
 			if !metricStatus.Completed() {
 				// if any metric is in-progress, then entire analysis run will be considered running
 				everythingCompleted = false
+			} else if errorsInconclusive && metricStatus == v1alpha1.AnalysisPhaseError {
+				// errored metrics are left out of the worst status, remembering the first one
+				if !errored {
+					errored = true
+					erroredMessage = metricResultMessage(metric, *result, metricStatus)
+				}
 			} else {
 				// otherwise, remember the worst status of all completed metric results
 				if worstStatus == "" || analysisutil.IsWorse(worstStatus, metricStatus) {
 					worstStatus = metricStatus
-					_, message := assessMetricFailureInconclusiveOrError(metric, *result)
-					if message != "" {
-						worstMessage = fmt.Sprintf("metric \"%s\" assessed %s due to %s", metric.Name, metricStatus, message)
-						if result.Message != "" {
-							worstMessage += fmt.Sprintf(": \"Error Message: %s\"", result.Message)
-						}
+					if message := metricResultMessage(metric, *result, metricStatus); message != "" {
+						worstMessage = message
 					}
 				}
 			}
 		}
 	}
-	if !everythingCompleted || worstStatus == "" {
+	if !everythingCompleted {
+		return v1alpha1.AnalysisPhaseRunning, ""
+	}
+	if errored {
+		switch worstStatus {
+		case "":
+			// every metric errored, which leaves nothing to conclude from
+			return v1alpha1.AnalysisPhaseError, erroredMessage
+		case v1alpha1.AnalysisPhaseSuccessful:
+			return v1alpha1.AnalysisPhaseInconclusive, erroredMessage
+		}
+	}
+	if worstStatus == "" {
 		return v1alpha1.AnalysisPhaseRunning, ""
 	}
 
 	return worstStatus, worstMessage
 }
 
+// metricResultMessage returns the message explaining the status of a completed metric, or an empty message if the
+// metric has no limit or cap explaining it
+func metricResultMessage(metric v1alpha1.Metric, result v1alpha1.MetricResult, metricStatus v1alpha1.AnalysisPhase) string {
+	_, message := assessMetricFailureInconclusiveOrError(metric, result)
+	if message == "" && metricStatus != v1alpha1.AnalysisPhaseSuccessful {
+		_, message = assessMetricMaxMeasurements(metric, result)
+	}
+	if message == "" {
+		return ""
+	}
+	resultMessage := fmt.Sprintf("metric \"%s\" assessed %s due to %s", metric.Name, metricStatus, message)
+	if result.Message != "" {
+		resultMessage += fmt.Sprintf(": \"Error Message: %s\"", result.Message)
+	}
+	return resultMessage
+}
+
 // assessMetricStatus assesses the status of a single metric based on:
 // * current/latest measurement status
 // * parameters given by the metric (failureLimit, count, etc...)
@@ -472,6 +851,11 @@ func assessMetricStatus(metric v1alpha1.Metric, result v1alpha1.MetricResult, te
 		log.Infof("metric assessed %s: count (%d) reached", v1alpha1.AnalysisPhaseSuccessful, *effectiveCount)
 		return v1alpha1.AnalysisPhaseSuccessful
 	}
+	// If the metric reached its maxMeasurements cap, it stops measuring and completes with the configured phase
+	if phase, message := assessMetricMaxMeasurements(metric, result); phase != "" {
+		log.Infof("metric assessed %s: %s", phase, message)
+		return phase
+	}
 	// if we get here, this metric runs indefinitely
 	if terminating {
 		log.Infof("metric assessed %s: run terminated", v1alpha1.AnalysisPhaseSuccessful)
@@ -499,6 +883,58 @@ func assessMetricFailureInconclusiveOrError(metric v1alpha1.Metric, result v1alp
 	return phase, message
 }
 
+// maxMeasurementsReached returns whether the metric took the maximum number of measurements, including errors
+func maxMeasurementsReached(metric v1alpha1.Metric, result v1alpha1.MetricResult) bool {
+	return metric.MaxMeasurements > 0 && result.Count+result.Error >= metric.MaxMeasurements
+}
+
+// assessMetricMaxMeasurements returns the phase of a metric which reached its maxMeasurements, which defaults to
+// Successful, or an empty phase if the cap was not reached
+func assessMetricMaxMeasurements(metric v1alpha1.Metric, result v1alpha1.MetricResult) (v1alpha1.AnalysisPhase, string) {
+	if !maxMeasurementsReached(metric, result) {
+		return "", ""
+	}
+	phase := metric.MaxMeasurementsPhase
+	if phase == "" {
+		phase = v1alpha1.AnalysisPhaseSuccessful
+	}
+	return phase, fmt.Sprintf("maxMeasurements (%d) reached", metric.MaxMeasurements)
+}
+
+// nextScheduledTime returns the time of the next measurement of a metric with a schedule, which is the first time of
+// the schedule after the start of the last measurement, or after the start of the run and the initial delay of the
+// metric when no measurement was taken
+func nextScheduledTime(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, lastMeasurement *v1alpha1.Measurement) (time.Time, error) {
+	schedule, err := cron.Parse(metric.Schedule)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var from time.Time
+	if lastMeasurement != nil {
+		from = lastMeasurement.FinishedAt.Time
+		if lastMeasurement.StartedAt != nil {
+			from = lastMeasurement.StartedAt.Time
+		}
+	} else {
+		from = time.Now()
+		if run.Status.StartedAt != nil {
+			from = run.Status.StartedAt.Time
+		}
+		if metric.InitialDelay != "" {
+			duration, err := metric.InitialDelay.Duration()
+			if err != nil {
+				return time.Time{}, err
+			}
+			from = from.Add(duration)
+		}
+	}
+	scheduledTime := schedule.Next(from)
+	if scheduledTime.IsZero() {
+		return time.Time{}, fmt.Errorf("schedule '%s' has no next time", metric.Schedule)
+	}
+	return scheduledTime, nil
+}
+
 // calculateNextReconcileTime calculates the next time that this AnalysisRun should be reconciled,
 // based on the earliest time of all metrics intervals, counts, and their finishedAt timestamps
 func calculateNextReconcileTime(run *v1alpha1.AnalysisRun) *time.Time {
@@ -511,6 +947,17 @@ func calculateNextReconcileTime(run *v1alpha1.AnalysisRun) *time.Time {
 		logCtx := logutil.WithAnalysisRun(run).WithField("metric", metric.Name)
 		lastMeasurement := analysisutil.LastMeasurement(run, metric.Name)
 		if lastMeasurement == nil {
+			if metric.Schedule != "" {
+				scheduledTime, err := nextScheduledTime(run, metric, nil)
+				if err != nil {
+					logCtx.Warnf("failed to compute the scheduled time: %v", err)
+					continue
+				}
+				if reconcileTime == nil || reconcileTime.After(scheduledTime) {
+					reconcileTime = &scheduledTime
+				}
+				continue
+			}
 			if metric.InitialDelay != "" {
 				startTime := metav1.Now()
 				if run.Status.StartedAt != nil {
@@ -546,6 +993,20 @@ func calculateNextReconcileTime(run *v1alpha1.AnalysisRun) *time.Time {
 			// we have reached desired count
 			continue
 		}
+		if maxMeasurementsReached(metric, *metricResult) {
+			continue
+		}
+		if metric.Schedule != "" {
+			scheduledTime, err := nextScheduledTime(run, metric, lastMeasurement)
+			if err != nil {
+				logCtx.Warnf("failed to compute the scheduled time: %v", err)
+				continue
+			}
+			if reconcileTime == nil || reconcileTime.After(scheduledTime) {
+				reconcileTime = &scheduledTime
+			}
+			continue
+		}
 		var interval time.Duration
 		if metric.Interval != "" {
 			metricInterval, err := metric.Interval.Duration()
@@ -564,6 +1025,7 @@ func calculateNextReconcileTime(run *v1alpha1.AnalysisRun) *time.Time {
 			logCtx.Warnf("skipping requeue. no interval or error (count: %d, effectiveCount: %d)", metricResult.Count, metric.EffectiveCount())
 			continue
 		}
+		interval = decisionInterval(metric, *metricResult, interval, logCtx)
 		// Take the earliest time of all metrics
 		metricReconcileTime := lastMeasurement.FinishedAt.Add(interval)
 		if reconcileTime == nil || reconcileTime.After(metricReconcileTime) {
@@ -573,6 +1035,39 @@ func calculateNextReconcileTime(run *v1alpha1.AnalysisRun) *time.Time {
 	return reconcileTime
 }
 
+// decisionInterval returns the decisionInterval of the metric, bounded by the interval and MinDecisionInterval, when
+// a single measurement may fail the metric, or the interval otherwise
+func decisionInterval(metric v1alpha1.Metric, result v1alpha1.MetricResult, interval time.Duration, logCtx *log.Entry) time.Duration {
+	if metric.DecisionInterval == "" || !nearFailure(metric, result) {
+		return interval
+	}
+	accelerated, err := metric.DecisionInterval.Duration()
+	if err != nil {
+		logCtx.Warnf("failed to parse decision interval: %v", err)
+		return interval
+	}
+	if accelerated < MinDecisionInterval {
+		accelerated = MinDecisionInterval
+	}
+	if accelerated >= interval {
+		return interval
+	}
+	logCtx.Infof("metric is near a failure: measuring after the decision interval of %v", accelerated)
+	return accelerated
+}
+
+// nearFailure returns whether the failures, inconclusive measurements or consecutive errors of the metric are at
+// their limit, so that the next measurement may fail the metric, or assess it Inconclusive or Error
+func nearFailure(metric v1alpha1.Metric, result v1alpha1.MetricResult) bool {
+	if result.Failed > 0 && result.Failed >= metric.FailureLimit {
+		return true
+	}
+	if result.Inconclusive > 0 && result.Inconclusive >= metric.InconclusiveLimit {
+		return true
+	}
+	return result.ConsecutiveError > 0 && result.ConsecutiveError >= defaults.GetConsecutiveErrorLimitOrDefault(&metric)
+}
+
 // garbageCollectMeasurements trims the measurement history to the specified limit and GCs old measurements
 func (c *Controller) garbageCollectMeasurements(run *v1alpha1.AnalysisRun, limit int) error {
 	var errors []error
@@ -584,8 +1079,13 @@ func (c *Controller) garbageCollectMeasurements(run *v1alpha1.AnalysisRun, limit
 
 	for i, result := range run.Status.MetricResults {
 		length := len(result.Measurements)
-		if length > limit {
-			metric, ok := metricsByName[result.Name]
+		metric, ok := metricsByName[result.Name]
+		// The measurements of the recent results window of the metric are retained
+		metricLimit := limit
+		if ok && int(metric.RecentResultsWindow) > metricLimit {
+			metricLimit = int(metric.RecentResultsWindow)
+		}
+		if length > metricLimit {
 			if !ok {
 				continue
 			}
@@ -595,11 +1095,23 @@ func (c *Controller) garbageCollectMeasurements(run *v1alpha1.AnalysisRun, limit
 				errors = append(errors, err)
 				continue
 			}
-			err = provider.GarbageCollect(run, metric, limit)
+			err = provider.GarbageCollect(run, metric, metricLimit)
 			if err != nil {
 				return err
 			}
-			result.Measurements = result.Measurements[length-limit : length]
+			if metric.FallbackProvider != nil {
+				fallback := fallbackMetric(metric)
+				fallbackProvider, err := c.newProvider(*log, fallback)
+				if err != nil {
+					errors = append(errors, err)
+					continue
+				}
+				err = fallbackProvider.GarbageCollect(run, fallback, metricLimit)
+				if err != nil {
+					return err
+				}
+			}
+			result.Measurements = result.Measurements[length-metricLimit : length]
 		}
 		run.Status.MetricResults[i] = result
 	}
@@ -608,3 +1120,63 @@ func (c *Controller) garbageCollectMeasurements(run *v1alpha1.AnalysisRun, limit
 	}
 	return nil
 }
+
+// trimMeasurementsPerRun trims the oldest measurements of the run until the total number of measurements across all
+// metrics is within the limit. The most recent measurement of every metric and all failed measurements are retained,
+// even if this exceeds the limit.
+func trimMeasurementsPerRun(run *v1alpha1.AnalysisRun, limit int) {
+	total := 0
+	for _, result := range run.Status.MetricResults {
+		total += len(result.Measurements)
+	}
+	excess := total - limit
+	if excess <= 0 {
+		return
+	}
+
+	type trimCandidate struct {
+		resultIndex      int
+		measurementIndex int
+		startedAt        time.Time
+	}
+	candidates := []trimCandidate{}
+	for i, result := range run.Status.MetricResults {
+		for j := 0; j < len(result.Measurements)-1; j++ {
+			measurement := result.Measurements[j]
+			if measurement.Phase == v1alpha1.AnalysisPhaseFailed {
+				continue
+			}
+			candidate := trimCandidate{resultIndex: i, measurementIndex: j}
+			if measurement.StartedAt != nil {
+				candidate.startedAt = measurement.StartedAt.Time
+			}
+			candidates = append(candidates, candidate)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].startedAt.Before(candidates[j].startedAt)
+	})
+	if len(candidates) > excess {
+		candidates = candidates[:excess]
+	}
+
+	trimmed := make(map[int]map[int]bool)
+	for _, candidate := range candidates {
+		if trimmed[candidate.resultIndex] == nil {
+			trimmed[candidate.resultIndex] = make(map[int]bool)
+		}
+		trimmed[candidate.resultIndex][candidate.measurementIndex] = true
+	}
+	for i, result := range run.Status.MetricResults {
+		if len(trimmed[i]) == 0 {
+			continue
+		}
+		measurements := make([]v1alpha1.Measurement, 0, len(result.Measurements)-len(trimmed[i]))
+		for j, measurement := range result.Measurements {
+			if !trimmed[i][j] {
+				measurements = append(measurements, measurement)
+			}
+		}
+		run.Status.MetricResults[i].Measurements = measurements
+	}
+}
@@ -4,17 +4,26 @@ import (
 	"bytes"
 	"fmt"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
 
+	"github.com/argoproj/argo-rollouts/analysis/hook"
+	"github.com/argoproj/argo-rollouts/analysis/sink"
+	"github.com/argoproj/argo-rollouts/metricproviders"
+	"github.com/argoproj/argo-rollouts/metricproviders/prometheus"
 	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
 	"github.com/argoproj/argo-rollouts/utils/defaults"
+	"github.com/argoproj/argo-rollouts/utils/evaluate"
 )
 
 func timePtr(t metav1.Time) *metav1.Time {
@@ -627,6 +636,121 @@ func TestAssessMetricStatusCountReached(t *testing.T) {
 	assert.Equal(t, v1alpha1.AnalysisPhaseInconclusive, assessMetricStatus(metric, result, false))
 }
 
+func TestAssessMetricStatusMaxMeasurementsReached(t *testing.T) {
+	metric := v1alpha1.Metric{
+		Name:            "success-rate",
+		Interval:        "60s",
+		FailureLimit:    1,
+		MaxMeasurements: 5,
+	}
+	result := v1alpha1.MetricResult{
+		Successful: 3,
+		Count:      3,
+		Error:      1,
+		Measurements: []v1alpha1.Measurement{{
+			Value:      "99",
+			Phase:      v1alpha1.AnalysisPhaseSuccessful,
+			StartedAt:  timePtr(metav1.NewTime(time.Now().Add(-60 * time.Second))),
+			FinishedAt: timePtr(metav1.NewTime(time.Now().Add(-60 * time.Second))),
+		}},
+	}
+	assert.Equal(t, v1alpha1.AnalysisPhaseRunning, assessMetricStatus(metric, result, false))
+
+	// errored measurements count towards the cap
+	result.Successful = 4
+	result.Count = 4
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, assessMetricStatus(metric, result, false))
+	metric.MaxMeasurementsPhase = v1alpha1.AnalysisPhaseInconclusive
+	assert.Equal(t, v1alpha1.AnalysisPhaseInconclusive, assessMetricStatus(metric, result, false))
+
+	// the failure limit is assessed before the cap
+	result.Successful = 2
+	result.Failed = 2
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, assessMetricStatus(metric, result, false))
+}
+
+func TestGenerateMetricTasksMaxMeasurementsReached(t *testing.T) {
+	run := &v1alpha1.AnalysisRun{
+		Spec: v1alpha1.AnalysisRunSpec{
+			Metrics: []v1alpha1.Metric{{
+				Name:            "success-rate",
+				Interval:        "60s",
+				MaxMeasurements: 3,
+			}},
+		},
+		Status: v1alpha1.AnalysisRunStatus{
+			Phase: v1alpha1.AnalysisPhaseRunning,
+			MetricResults: []v1alpha1.MetricResult{{
+				Name:       "success-rate",
+				Phase:      v1alpha1.AnalysisPhaseRunning,
+				Count:      2,
+				Successful: 2,
+				Measurements: []v1alpha1.Measurement{{
+					Value:      "99",
+					Phase:      v1alpha1.AnalysisPhaseSuccessful,
+					StartedAt:  timePtr(metav1.NewTime(time.Now().Add(-61 * time.Second))),
+					FinishedAt: timePtr(metav1.NewTime(time.Now().Add(-61 * time.Second))),
+				}},
+			}},
+		},
+	}
+	{
+		// ensure we take measurements until the cap is reached
+		tasks := generateMetricTasks(run)
+		assert.Equal(t, 1, len(tasks))
+		assert.NotNil(t, calculateNextReconcileTime(run))
+	}
+	{
+		// ensure we stop measuring once the cap is reached
+		run.Status.MetricResults[0].Count = 3
+		run.Status.MetricResults[0].Successful = 3
+		tasks := generateMetricTasks(run)
+		assert.Equal(t, 0, len(tasks))
+		assert.Nil(t, calculateNextReconcileTime(run))
+	}
+}
+
+func TestAssessRunStatusMaxMeasurementsReached(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+	c, _, _ := f.newController(noResyncPeriodFunc)
+	run := &v1alpha1.AnalysisRun{
+		Spec: v1alpha1.AnalysisRunSpec{
+			Metrics: []v1alpha1.Metric{{
+				Name:                 "success-rate",
+				Interval:             "60s",
+				MaxMeasurements:      2,
+				MaxMeasurementsPhase: v1alpha1.AnalysisPhaseFailed,
+			}},
+		},
+		Status: v1alpha1.AnalysisRunStatus{
+			Phase: v1alpha1.AnalysisPhaseRunning,
+			MetricResults: []v1alpha1.MetricResult{{
+				Name:       "success-rate",
+				Phase:      v1alpha1.AnalysisPhaseRunning,
+				Count:      2,
+				Successful: 2,
+				Measurements: []v1alpha1.Measurement{{
+					Value:      "99",
+					Phase:      v1alpha1.AnalysisPhaseSuccessful,
+					StartedAt:  timePtr(metav1.NewTime(time.Now().Add(-60 * time.Second))),
+					FinishedAt: timePtr(metav1.NewTime(time.Now().Add(-60 * time.Second))),
+				}},
+			}},
+		},
+	}
+	status, message := c.assessRunStatus(run)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, status)
+	assert.Equal(t, "metric \"success-rate\" assessed Failed due to maxMeasurements (2) reached", message)
+
+	// the run is successful without a message when the cap is reached with the default phase
+	run.Spec.Metrics[0].MaxMeasurementsPhase = ""
+	run.Status.MetricResults[0].Phase = v1alpha1.AnalysisPhaseRunning
+	status, message = c.assessRunStatus(run)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, status)
+	assert.Equal(t, "", message)
+}
+
 func TestCalculateNextReconcileTimeInterval(t *testing.T) {
 	now := metav1.Now()
 	nowMinus30 := metav1.NewTime(now.Add(time.Second * -30))
@@ -671,6 +795,89 @@ func TestCalculateNextReconcileTimeInterval(t *testing.T) {
 	assert.Nil(t, calculateNextReconcileTime(run))
 }
 
+func newDecisionIntervalRun(failed int32, finishedAgo time.Duration) *v1alpha1.AnalysisRun {
+	finishedAt := metav1.NewTime(time.Now().Add(-finishedAgo))
+	return &v1alpha1.AnalysisRun{
+		Spec: v1alpha1.AnalysisRunSpec{
+			Metrics: []v1alpha1.Metric{{
+				Name:             "success-rate",
+				Interval:         "5m",
+				DecisionInterval: "30s",
+				FailureLimit:     2,
+			}},
+		},
+		Status: v1alpha1.AnalysisRunStatus{
+			Phase: v1alpha1.AnalysisPhaseRunning,
+			MetricResults: []v1alpha1.MetricResult{{
+				Name:   "success-rate",
+				Phase:  v1alpha1.AnalysisPhaseRunning,
+				Count:  failed + 1,
+				Failed: failed,
+				Measurements: []v1alpha1.Measurement{{
+					Value:      "80",
+					Phase:      v1alpha1.AnalysisPhaseFailed,
+					StartedAt:  &finishedAt,
+					FinishedAt: &finishedAt,
+				}},
+			}},
+		},
+	}
+}
+
+func TestCalculateNextReconcileTimeDecisionInterval(t *testing.T) {
+	// one failure away from failing, the metric is measured after the decision interval
+	run := newDecisionIntervalRun(2, 10*time.Second)
+	finishedAt := run.Status.MetricResults[0].Measurements[0].FinishedAt.Time
+	assert.Equal(t, finishedAt.Add(30*time.Second), *calculateNextReconcileTime(run))
+
+	// further from the failure limit, the metric is measured after its interval
+	run = newDecisionIntervalRun(1, 10*time.Second)
+	finishedAt = run.Status.MetricResults[0].Measurements[0].FinishedAt.Time
+	assert.Equal(t, finishedAt.Add(5*time.Minute), *calculateNextReconcileTime(run))
+
+	// the decision interval is opt-in
+	run = newDecisionIntervalRun(2, 10*time.Second)
+	run.Spec.Metrics[0].DecisionInterval = ""
+	finishedAt = run.Status.MetricResults[0].Measurements[0].FinishedAt.Time
+	assert.Equal(t, finishedAt.Add(5*time.Minute), *calculateNextReconcileTime(run))
+}
+
+func TestCalculateNextReconcileTimeDecisionIntervalBounds(t *testing.T) {
+	// the decision interval is not shorter than the minimum
+	run := newDecisionIntervalRun(2, 0)
+	run.Spec.Metrics[0].DecisionInterval = "1s"
+	finishedAt := run.Status.MetricResults[0].Measurements[0].FinishedAt.Time
+	assert.Equal(t, finishedAt.Add(MinDecisionInterval), *calculateNextReconcileTime(run))
+
+	// nor longer than the interval
+	run.Spec.Metrics[0].DecisionInterval = "10m"
+	assert.Equal(t, finishedAt.Add(5*time.Minute), *calculateNextReconcileTime(run))
+}
+
+func TestCalculateNextReconcileTimeDecisionIntervalNearErrorOrInconclusive(t *testing.T) {
+	run := newDecisionIntervalRun(0, 0)
+	finishedAt := run.Status.MetricResults[0].Measurements[0].FinishedAt.Time
+	run.Status.MetricResults[0].ConsecutiveError = 4
+	assert.Equal(t, finishedAt.Add(30*time.Second), *calculateNextReconcileTime(run))
+
+	run.Status.MetricResults[0].ConsecutiveError = 0
+	run.Spec.Metrics[0].InconclusiveLimit = 1
+	run.Status.MetricResults[0].Inconclusive = 1
+	assert.Equal(t, finishedAt.Add(30*time.Second), *calculateNextReconcileTime(run))
+}
+
+func TestGenerateMetricTasksDecisionInterval(t *testing.T) {
+	// one failure away from failing, the measurement is taken after the decision interval
+	run := newDecisionIntervalRun(2, 31*time.Second)
+	assert.Len(t, generateMetricTasks(run), 1)
+	run = newDecisionIntervalRun(2, 20*time.Second)
+	assert.Len(t, generateMetricTasks(run), 0)
+
+	// further from the failure limit, the measurement waits for the interval
+	run = newDecisionIntervalRun(1, 31*time.Second)
+	assert.Len(t, generateMetricTasks(run), 0)
+}
+
 func TestCalculateNextReconcileTimeInitialDelay(t *testing.T) {
 	now := metav1.Now()
 	nowMinus30 := metav1.NewTime(now.Add(time.Second * -30))
@@ -711,6 +918,93 @@ func TestCalculateNextReconcileTimeInitialDelay(t *testing.T) {
 
 }
 
+func TestGenerateMetricTasksSchedule(t *testing.T) {
+	now := time.Now()
+	run := &v1alpha1.AnalysisRun{
+		Spec: v1alpha1.AnalysisRunSpec{
+			Metrics: []v1alpha1.Metric{{
+				Name:     "success-rate",
+				Schedule: "* * * * *",
+			}},
+		},
+		Status: v1alpha1.AnalysisRunStatus{
+			Phase: v1alpha1.AnalysisPhaseRunning,
+		},
+	}
+	{
+		// ensure we wait for the start of the run
+		assert.Equal(t, 0, len(generateMetricTasks(run)))
+	}
+	{
+		// ensure we wait for the first scheduled time after the start of the run
+		run.Status.StartedAt = timePtr(metav1.NewTime(now))
+		assert.Equal(t, 0, len(generateMetricTasks(run)))
+	}
+	{
+		// ensure we take the first measurement once its scheduled time passed
+		run.Status.StartedAt = timePtr(metav1.NewTime(now.Add(-2 * time.Minute)))
+		assert.Equal(t, 1, len(generateMetricTasks(run)))
+	}
+	{
+		// ensure we wait for the scheduled time after the last measurement
+		run.Status.MetricResults = []v1alpha1.MetricResult{{
+			Name:  "success-rate",
+			Phase: v1alpha1.AnalysisPhaseRunning,
+			Count: 1,
+			Measurements: []v1alpha1.Measurement{{
+				Value:      "99",
+				Phase:      v1alpha1.AnalysisPhaseSuccessful,
+				StartedAt:  timePtr(metav1.NewTime(now)),
+				FinishedAt: timePtr(metav1.NewTime(now)),
+			}},
+		}}
+		assert.Equal(t, 0, len(generateMetricTasks(run)))
+	}
+	{
+		// ensure we take a measurement once the scheduled time after the last measurement passed
+		run.Status.MetricResults[0].Measurements[0].StartedAt = timePtr(metav1.NewTime(now.Add(-2 * time.Minute)))
+		run.Status.MetricResults[0].Measurements[0].FinishedAt = timePtr(metav1.NewTime(now.Add(-2 * time.Minute)))
+		assert.Equal(t, 1, len(generateMetricTasks(run)))
+	}
+}
+
+func TestCalculateNextReconcileTimeSchedule(t *testing.T) {
+	startedAt := time.Date(2020, 6, 15, 10, 30, 15, 0, time.UTC)
+	run := &v1alpha1.AnalysisRun{
+		Spec: v1alpha1.AnalysisRunSpec{
+			Metrics: []v1alpha1.Metric{{
+				Name:         "success-rate",
+				Schedule:     "0 */2 * * *",
+				InitialDelay: "2h",
+			}},
+		},
+		Status: v1alpha1.AnalysisRunStatus{
+			Phase:     v1alpha1.AnalysisPhaseRunning,
+			StartedAt: timePtr(metav1.NewTime(startedAt)),
+		},
+	}
+	// ensure we requeue at the first scheduled time after the start delay
+	assert.Equal(t, time.Date(2020, 6, 15, 14, 0, 0, 0, time.UTC), *calculateNextReconcileTime(run))
+
+	// ensure we requeue at the scheduled time after the start of the last measurement
+	run.Status.MetricResults = []v1alpha1.MetricResult{{
+		Name:  "success-rate",
+		Phase: v1alpha1.AnalysisPhaseRunning,
+		Count: 1,
+		Measurements: []v1alpha1.Measurement{{
+			Value:      "99",
+			Phase:      v1alpha1.AnalysisPhaseSuccessful,
+			StartedAt:  timePtr(metav1.NewTime(time.Date(2020, 6, 15, 14, 0, 5, 0, time.UTC))),
+			FinishedAt: timePtr(metav1.NewTime(time.Date(2020, 6, 15, 14, 0, 10, 0, time.UTC))),
+		}},
+	}}
+	assert.Equal(t, time.Date(2020, 6, 15, 16, 0, 0, 0, time.UTC), *calculateNextReconcileTime(run))
+
+	// skip an invalid schedule
+	run.Spec.Metrics[0].Schedule = "not-a-schedule"
+	assert.Nil(t, calculateNextReconcileTime(run))
+}
+
 func TestCalculateNextReconcileTimeNoInterval(t *testing.T) {
 	now := metav1.Now()
 	run := &v1alpha1.AnalysisRun{
@@ -884,6 +1178,46 @@ func TestReconcileAnalysisRunInitial(t *testing.T) {
 	}
 }
 
+func TestReconcileAnalysisRunSummary(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+	c, _, _ := f.newController(noResyncPeriodFunc)
+	run := &v1alpha1.AnalysisRun{
+		Spec: v1alpha1.AnalysisRunSpec{
+			Metrics: []v1alpha1.Metric{{
+				Name:     "success-rate",
+				Interval: "60s",
+				Count:    3,
+				Provider: v1alpha1.MetricProvider{
+					Prometheus: &v1alpha1.PrometheusMetric{},
+				},
+			}},
+		},
+	}
+	measurement := newMeasurement(v1alpha1.AnalysisPhaseSuccessful)
+	measurement.Value = "0.95"
+	f.provider.On("Run", mock.Anything, mock.Anything, mock.Anything).Return(measurement, nil)
+
+	// the summary is omitted while the run is running
+	newRun := c.reconcileAnalysisRun(run)
+	assert.Equal(t, v1alpha1.AnalysisPhaseRunning, newRun.Status.Phase)
+	assert.Nil(t, newRun.Status.Summary)
+
+	// the summary is computed when the run completes
+	run.Spec.Metrics[0].Count = 1
+	newRun = c.reconcileAnalysisRun(run)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, newRun.Status.Phase)
+	assert.Equal(t, []v1alpha1.MetricSummary{{
+		Name:         "success-rate",
+		Phase:        v1alpha1.AnalysisPhaseSuccessful,
+		Measurements: 1,
+		Successful:   1,
+		Min:          "0.95",
+		Max:          "0.95",
+		Avg:          "0.95",
+	}}, newRun.Status.Summary)
+}
+
 func TestReconcileAnalysisRunInvalid(t *testing.T) {
 	f := newFixture(t)
 	defer f.Close()
@@ -962,6 +1296,49 @@ func TestReconcileAnalysisRunResumeInProgress(t *testing.T) {
 	assert.NotNil(t, newRun.Status.MetricResults[0].Measurements[0].FinishedAt)
 }
 
+func TestReconcileAnalysisRunSuspendAndResume(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+	c, _, _ := f.newController(noResyncPeriodFunc)
+
+	run := newRun()
+	run.Status.MetricResults[0].Count = 1
+	run.Status.MetricResults[0].Successful = 1
+	run.Status.MetricResults[0].Measurements[0].FinishedAt = timePtr(metav1.NewTime(time.Now().Add(-90 * time.Second)))
+	run.Spec.Suspend = true
+
+	// the measurement of metric1 is overdue, but no measurement is taken while the run is suspended
+	suspendedRun := c.reconcileAnalysisRun(run)
+	assert.Equal(t, run.Status, suspendedRun.Status)
+	f.provider.AssertNotCalled(t, "Run", mock.Anything, mock.Anything, mock.Anything)
+
+	f.provider.On("Run", mock.Anything, mock.Anything, mock.Anything).Return(newMeasurement(v1alpha1.AnalysisPhaseSuccessful), nil)
+	suspendedRun.Spec.Suspend = false
+	resumedRun := c.reconcileAnalysisRun(suspendedRun)
+	assert.Equal(t, v1alpha1.AnalysisPhaseRunning, resumedRun.Status.Phase)
+	assert.Len(t, resumedRun.Status.MetricResults[0].Measurements, 2)
+	assert.Equal(t, int32(2), resumedRun.Status.MetricResults[0].Count)
+	assert.Equal(t, int32(2), resumedRun.Status.MetricResults[0].Successful)
+	// the measurement of metric2 is not due yet
+	assert.Len(t, resumedRun.Status.MetricResults[1].Measurements, 2)
+}
+
+func TestReconcileAnalysisRunTerminateSuspended(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+	c, _, _ := f.newController(noResyncPeriodFunc)
+
+	// mocks terminate to cancel the in-progress measurement
+	f.provider.On("Terminate", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(newMeasurement(v1alpha1.AnalysisPhaseSuccessful), nil)
+
+	run := newTerminatingRun(v1alpha1.AnalysisPhaseFailed)
+	run.Spec.Suspend = true
+	run.Spec.Terminate = true
+	newRun := c.reconcileAnalysisRun(run)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, newRun.Status.Phase)
+	assert.NotNil(t, newRun.Status.MetricResults[0].Measurements[0].FinishedAt)
+}
+
 // TestRunMeasurementsResetConsecutiveErrorCounter verifies we reset the metric consecutiveError counter
 // when metric measures success, failed, or inconclusive.
 func TestRunMeasurementsResetConsecutiveErrorCounter(t *testing.T) {
@@ -1006,6 +1383,70 @@ func TestRunMeasurementsResetConsecutiveErrorCounter(t *testing.T) {
 	}
 }
 
+// TestRunMeasurementsInitialErrorGrace verifies the errors of the first measurements are not counted towards the
+// consecutiveErrorLimit, while the errors after a measurement completed without error still are
+func TestRunMeasurementsInitialErrorGrace(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+	c, _, _ := f.newController(noResyncPeriodFunc)
+
+	metric := v1alpha1.Metric{
+		Name:                  "test",
+		Interval:              "60s",
+		InitialErrorGrace:     2,
+		ConsecutiveErrorLimit: pointer.Int32Ptr(0),
+		Provider: v1alpha1.MetricProvider{
+			Job: &v1alpha1.JobMetric{},
+		},
+	}
+	f.provider.On("Run", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(newMeasurement(v1alpha1.AnalysisPhaseError), nil)
+
+	// The first error of the cold start is ignored
+	run := &v1alpha1.AnalysisRun{
+		Spec: v1alpha1.AnalysisRunSpec{
+			Metrics: []v1alpha1.Metric{metric},
+		},
+	}
+	newRun := c.reconcileAnalysisRun(run)
+	assert.Equal(t, v1alpha1.AnalysisPhaseRunning, newRun.Status.Phase)
+	assert.Equal(t, int32(1), newRun.Status.MetricResults[0].Error)
+	assert.Equal(t, int32(0), newRun.Status.MetricResults[0].ConsecutiveError)
+
+	// The errors beyond the grace are counted
+	past := metav1.NewTime(time.Now().Add(-2 * time.Minute))
+	erroredMeasurement := v1alpha1.Measurement{Phase: v1alpha1.AnalysisPhaseError, StartedAt: &past, FinishedAt: &past}
+	run.Status = v1alpha1.AnalysisRunStatus{
+		Phase: v1alpha1.AnalysisPhaseRunning,
+		MetricResults: []v1alpha1.MetricResult{{
+			Name:         "test",
+			Phase:        v1alpha1.AnalysisPhaseRunning,
+			Error:        2,
+			Measurements: []v1alpha1.Measurement{erroredMeasurement, erroredMeasurement},
+		}},
+	}
+	newRun = c.reconcileAnalysisRun(run)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, newRun.Status.Phase)
+	assert.Equal(t, int32(1), newRun.Status.MetricResults[0].ConsecutiveError)
+
+	// A later streak of errors errors the run, even within the number of errors of the grace
+	successfulMeasurement := v1alpha1.Measurement{Phase: v1alpha1.AnalysisPhaseSuccessful, StartedAt: &past, FinishedAt: &past}
+	run.Status = v1alpha1.AnalysisRunStatus{
+		Phase: v1alpha1.AnalysisPhaseRunning,
+		MetricResults: []v1alpha1.MetricResult{{
+			Name:         "test",
+			Phase:        v1alpha1.AnalysisPhaseRunning,
+			Count:        1,
+			Successful:   1,
+			Measurements: []v1alpha1.Measurement{successfulMeasurement},
+		}},
+	}
+	newRun = c.reconcileAnalysisRun(run)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, newRun.Status.Phase)
+	assert.Equal(t, int32(1), newRun.Status.MetricResults[0].Error)
+	assert.Equal(t, int32(1), newRun.Status.MetricResults[0].ConsecutiveError)
+	assert.Equal(t, "metric \"test\" assessed Error due to consecutiveErrors (1) > consecutiveErrorLimit (0)", newRun.Status.Message)
+}
+
 // TestTrimMeasurementHistory verifies we trim the measurement list appropriately to the correct length
 // and retain the newest measurements
 func TestTrimMeasurementHistory(t *testing.T) {
@@ -1034,462 +1475,1686 @@ func TestTrimMeasurementHistory(t *testing.T) {
 	}
 }
 
-// TestResolveMetricArgs verifies that metric arguments are resolved
-func TestResolveMetricArgs(t *testing.T) {
-	f := newFixture(t)
-	defer f.Close()
-	c, _, _ := f.newController(noResyncPeriodFunc)
-	arg1, arg2 := "success-rate", "success-rate2"
-	args := []v1alpha1.Argument{
-		{
-			Name:  "metric-name",
-			Value: &arg1,
-		},
-		{
-			Name:  "metric-name2",
-			Value: &arg2,
+func TestRecentValues(t *testing.T) {
+	metricResult := &v1alpha1.MetricResult{
+		Measurements: []v1alpha1.Measurement{
+			{Phase: v1alpha1.AnalysisPhaseSuccessful, Value: "0.1"},
+			{Phase: v1alpha1.AnalysisPhaseSuccessful, Value: "0.2"},
+			{Phase: v1alpha1.AnalysisPhaseFailed, Value: "0.9"},
+			{Phase: v1alpha1.AnalysisPhaseError, Message: "intentional error"},
+			{Phase: v1alpha1.AnalysisPhaseSuccessful, Value: "0.3"},
 		},
 	}
-	metric1 := v1alpha1.Metric{Name: "metric-name", SuccessCondition: "result > {{args.metric-name}}"}
-	metric2 := v1alpha1.Metric{Name: "metric-name2", SuccessCondition: "result < {{args.metric-name2}}"}
-	newMetric1, _ := c.resolveMetricArgs(metric1, args)
-	newMetric2, _ := c.resolveMetricArgs(metric2, args)
-	assert.Equal(t, fmt.Sprintf("result > %s", arg1), newMetric1.SuccessCondition)
-	assert.Equal(t, fmt.Sprintf("result < %s", arg2), newMetric2.SuccessCondition)
+	// Only successful measurements are part of the window
+	assert.Equal(t, []string{"0.2", "0.3"}, recentValues(metricResult, 2))
+	assert.Equal(t, []string{"0.1", "0.2", "0.3"}, recentValues(metricResult, 5))
+	assert.Equal(t, []string{}, recentValues(metricResult, 0))
+	// At the start of a run the window is shorter than its size
+	assert.Equal(t, []string{}, recentValues(&v1alpha1.MetricResult{}, 4))
 }
 
-//TestResolveMetricArgsWithQuotes verifies that metric arguments with quotes are resolved
-func TestResolveMetricArgsWithQuotes(t *testing.T) {
+func TestRunMeasurementsWithRecentResultsWindow(t *testing.T) {
 	f := newFixture(t)
 	defer f.Close()
 	c, _, _ := f.newController(noResyncPeriodFunc)
-	arg := "foo \"bar\" baz"
 
-	arguments := []v1alpha1.Argument{{
-		Name:  "rate",
-		Value: &arg,
-	}}
-	metric := v1alpha1.Metric{
-		Name:             "rate",
-		SuccessCondition: "{{args.rate}}",
+	finishedAt := metav1.NewTime(time.Now().Add(-2 * time.Minute))
+	measurement := func(phase v1alpha1.AnalysisPhase, value string) v1alpha1.Measurement {
+		return v1alpha1.Measurement{Phase: phase, Value: value, StartedAt: &finishedAt, FinishedAt: &finishedAt}
 	}
-	newMetric, err := c.resolveMetricArgs(metric, arguments)
-	assert.NoError(t, err)
-	assert.Equal(t, fmt.Sprintf(arg), newMetric.SuccessCondition)
-}
-
-func TestResolveMetricArgsUnableToSubstitute(t *testing.T) {
-	f := newFixture(t)
-	defer f.Close()
-	c, _, _ := f.newController(noResyncPeriodFunc)
 	run := &v1alpha1.AnalysisRun{
 		Spec: v1alpha1.AnalysisRunSpec{
 			Metrics: []v1alpha1.Metric{{
-				Name:             "rate",
-				SuccessCondition: "{{args.does-not-exist}}",
+				Name:                "error-rate",
+				Interval:            "60s",
+				RecentResultsWindow: 3,
+				FailureLimit:        2,
+				SuccessCondition:    "avg(recentResults) < 0.5",
 				Provider: v1alpha1.MetricProvider{
-					Prometheus: &v1alpha1.PrometheusMetric{
-						Query: "{{args.metric-name}}",
-					},
+					Prometheus: &v1alpha1.PrometheusMetric{},
 				},
 			}},
 		},
-	}
-	newRun := c.reconcileAnalysisRun(run)
-	assert.Equal(t, newRun.Status.Phase, v1alpha1.AnalysisPhaseError)
-	assert.Equal(t, newRun.Status.Message, "unable to resolve metric arguments: failed to resolve {{args.metric-name}}")
-}
-
-func TestSecretContentReferenceValueFromError(t *testing.T) {
-	f := newFixture(t)
-	defer f.Close()
-	c, _, _ := f.newController(noResyncPeriodFunc)
-	argName := "apikey"
-	argVal := "value"
-	run := &v1alpha1.AnalysisRun{
-		Spec: v1alpha1.AnalysisRunSpec{
-			Args: []v1alpha1.Argument{{
-				Name:  argName,
-				Value: &argVal,
-				ValueFrom: &v1alpha1.ValueFrom{
-					SecretKeyRef: &v1alpha1.SecretKeyRef{
-						Name: "web-metric-secret",
-						Key:  "apikey",
-					},
-				}},
-			},
-			Metrics: []v1alpha1.Metric{{
-				Name: "rate",
-				Provider: v1alpha1.MetricProvider{
-					Web: &v1alpha1.WebMetric{
-						Headers: []v1alpha1.WebMetricHeader{{
-							Key:   "apikey",
-							Value: "{{args.apikey}}",
-						}},
-					},
+		Status: v1alpha1.AnalysisRunStatus{
+			Phase: v1alpha1.AnalysisPhaseRunning,
+			MetricResults: []v1alpha1.MetricResult{{
+				Name:  "error-rate",
+				Phase: v1alpha1.AnalysisPhaseRunning,
+				Measurements: []v1alpha1.Measurement{
+					measurement(v1alpha1.AnalysisPhaseSuccessful, "0.1"),
+					measurement(v1alpha1.AnalysisPhaseSuccessful, "0.2"),
+					measurement(v1alpha1.AnalysisPhaseFailed, "0.9"),
+					measurement(v1alpha1.AnalysisPhaseSuccessful, "0.3"),
 				},
+				Count:      4,
+				Successful: 3,
+				Failed:     1,
 			}},
 		},
 	}
-	newRun := c.reconcileAnalysisRun(run)
-	assert.Equal(t, v1alpha1.AnalysisPhaseError, newRun.Status.Phase)
-	assert.Equal(t, fmt.Sprintf("unable to resolve metric arguments: arg '%v' has both Value and ValueFrom fields", argName), newRun.Status.Message)
+	var metrics []v1alpha1.Metric
+	f.provider.On("Run", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		metrics = append(metrics, args.Get(1).(v1alpha1.Metric))
+	}).Return(newMeasurement(v1alpha1.AnalysisPhaseSuccessful), nil)
+
+	// The provider evaluates the current result along with the values of the last successful measurements
+	c.reconcileAnalysisRun(run)
+	assert.Len(t, metrics, 1)
+	assert.Equal(t, []string{"0.2", "0.3"}, metrics[0].RecentValues)
+
+	// At the start of the run, the window only holds the current result
+	run.Status = v1alpha1.AnalysisRunStatus{}
+	c.reconcileAnalysisRun(run)
+	assert.Len(t, metrics, 2)
+	assert.Equal(t, []string{}, metrics[1].RecentValues)
 }
 
-// TestSecretContentReferenceSuccess verifies that secret arguments are properly resolved
-func TestSecretContentReferenceSuccess(t *testing.T) {
+func TestRunMeasurementsCapturesBaselineOnce(t *testing.T) {
 	f := newFixture(t)
-	secret := &corev1.Secret{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "web-metric-secret",
-			Namespace: metav1.NamespaceDefault,
-		},
-		Data: map[string][]byte{
-			"apikey": []byte("12345"),
-		},
-	}
 	defer f.Close()
-	f.secretRunLister = append(f.secretRunLister, secret)
 	c, _, _ := f.newController(noResyncPeriodFunc)
-	argName := "apikey"
+
+	measurementWithBaseline := func(baseline string) v1alpha1.Measurement {
+		measurement := newMeasurement(v1alpha1.AnalysisPhaseSuccessful)
+		measurement.Metadata = map[string]string{prometheus.BaselineMetadataKey: baseline}
+		return measurement
+	}
+	// overdue moves the measurements of the run back so that the next measurement is due
+	overdue := func(run *v1alpha1.AnalysisRun) {
+		past := metav1.NewTime(time.Now().Add(-2 * time.Minute))
+		for i := range run.Status.MetricResults[0].Measurements {
+			run.Status.MetricResults[0].Measurements[i].StartedAt = &past
+			run.Status.MetricResults[0].Measurements[i].FinishedAt = &past
+		}
+	}
 	run := &v1alpha1.AnalysisRun{
-		ObjectMeta: metav1.ObjectMeta{
-			Namespace: metav1.NamespaceDefault,
-		},
 		Spec: v1alpha1.AnalysisRunSpec{
-			Args: []v1alpha1.Argument{{
-				Name: argName,
-				ValueFrom: &v1alpha1.ValueFrom{
-					SecretKeyRef: &v1alpha1.SecretKeyRef{
-						Name: "web-metric-secret",
-						Key:  "apikey",
-					},
-				},
-			}},
 			Metrics: []v1alpha1.Metric{{
-				Name: "rate",
+				Name:             "error-rate",
+				Interval:         "60s",
+				SuccessCondition: "canary <= baseline * 1.2",
 				Provider: v1alpha1.MetricProvider{
-					Web: &v1alpha1.WebMetric{
-						Headers: []v1alpha1.WebMetricHeader{{
-							Key:   "apikey",
-							Value: "{{args.apikey}}",
-						}},
+					Prometheus: &v1alpha1.PrometheusMetric{
+						Query:           "canary",
+						BaselineQuery:   "baseline",
+						BaselineCapture: v1alpha1.BaselineCaptureRunStart,
 					},
 				},
 			}},
 		},
+		Status: v1alpha1.AnalysisRunStatus{
+			Phase: v1alpha1.AnalysisPhaseRunning,
+		},
 	}
-	f.provider.On("Run", mock.Anything, mock.Anything, mock.Anything).Return(newMeasurement(v1alpha1.AnalysisPhaseSuccessful), nil)
+	var metrics []v1alpha1.Metric
+	recordMetric := func(args mock.Arguments) {
+		metrics = append(metrics, args.Get(1).(v1alpha1.Metric))
+	}
+	f.provider.On("Run", mock.Anything, mock.Anything).Run(recordMetric).Return(measurementWithBaseline("NaN"), nil).Once()
+	f.provider.On("Run", mock.Anything, mock.Anything).Run(recordMetric).Return(measurementWithBaseline("0.02"), nil).Once()
+	f.provider.On("Run", mock.Anything, mock.Anything).Run(recordMetric).Return(measurementWithBaseline("0.05"), nil).Once()
+
+	// A NaN baseline is not captured, so that the next measurement queries the baseline again
 	newRun := c.reconcileAnalysisRun(run)
-	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, newRun.Status.Phase)
+	assert.Len(t, metrics, 1)
+	assert.Empty(t, metrics[0].Provider.Prometheus.CapturedBaseline)
+	assert.Empty(t, newRun.Status.MetricResults[0].Baseline)
+
+	overdue(newRun)
+	newRun = c.reconcileAnalysisRun(newRun)
+	assert.Len(t, metrics, 2)
+	assert.Empty(t, metrics[1].Provider.Prometheus.CapturedBaseline)
+	assert.Equal(t, "0.02", newRun.Status.MetricResults[0].Baseline)
+
+	// The captured baseline is reused by the following measurements and is not replaced
+	overdue(newRun)
+	newRun = c.reconcileAnalysisRun(newRun)
+	assert.Len(t, metrics, 3)
+	assert.Equal(t, "0.02", metrics[2].Provider.Prometheus.CapturedBaseline)
+	assert.Equal(t, "0.02", newRun.Status.MetricResults[0].Baseline)
+	assert.Len(t, newRun.Status.MetricResults[0].Measurements, 3)
+
+	// The spec of the run is not modified
+	assert.Empty(t, newRun.Spec.Metrics[0].Provider.Prometheus.CapturedBaseline)
 }
 
-// TestSecretContentReferenceProviderError verifies that secret values are redacted in logs
-func TestSecretContentReferenceProviderError(t *testing.T) {
-	buf := bytes.NewBufferString("")
-	log.SetOutput(buf)
-	f := newFixture(t)
-	secretName, secretKey, secretValue := "web-metric-secret", "apikey", "12345"
-	arg := "success-rate"
-	secret := &corev1.Secret{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      secretName,
-			Namespace: metav1.NamespaceDefault,
-		},
-		Data: map[string][]byte{
-			secretKey: []byte(secretValue),
+func TestCapturedBaseline(t *testing.T) {
+	metric := v1alpha1.Metric{
+		Name: "error-rate",
+		Provider: v1alpha1.MetricProvider{
+			Prometheus: &v1alpha1.PrometheusMetric{BaselineQuery: "baseline", BaselineCapture: v1alpha1.BaselineCaptureRunStart},
 		},
 	}
+	measurement := newMeasurement(v1alpha1.AnalysisPhaseFailed)
+	measurement.Metadata = map[string]string{prometheus.BaselineMetadataKey: "0.02"}
+	assert.Equal(t, "0.02", capturedBaseline(metric, v1alpha1.MetricResult{}, measurement))
+
+	// The baseline is captured once
+	assert.Empty(t, capturedBaseline(metric, v1alpha1.MetricResult{Baseline: "0.01"}, measurement))
+
+	// The baselines of errored and fallback measurements are not captured
+	errored := measurement
+	errored.Phase = v1alpha1.AnalysisPhaseError
+	assert.Empty(t, capturedBaseline(metric, v1alpha1.MetricResult{}, errored))
+	fallback := *measurement.DeepCopy()
+	fallback.Metadata[FallbackProviderMetadataKey] = "Prometheus"
+	assert.Empty(t, capturedBaseline(metric, v1alpha1.MetricResult{}, fallback))
+
+	// The baseline is queried with each measurement by default
+	metric.Provider.Prometheus.BaselineCapture = v1alpha1.BaselineCaptureEveryMeasurement
+	assert.Empty(t, capturedBaseline(metric, v1alpha1.MetricResult{}, measurement))
+}
+
+func TestGarbageCollectMeasurementsRetainsRecentResultsWindow(t *testing.T) {
+	f := newFixture(t)
 	defer f.Close()
-	f.secretRunLister = append(f.secretRunLister, secret)
 	c, _, _ := f.newController(noResyncPeriodFunc)
+
 	run := &v1alpha1.AnalysisRun{
-		ObjectMeta: metav1.ObjectMeta{
-			Namespace: metav1.NamespaceDefault,
-		},
 		Spec: v1alpha1.AnalysisRunSpec{
-			Args: []v1alpha1.Argument{
-				{
-					Name: "secret",
-					ValueFrom: &v1alpha1.ValueFrom{
-						SecretKeyRef: &v1alpha1.SecretKeyRef{
-							Name: secretName,
-							Key:  secretKey,
-						},
-					},
-				},
-				{
-					Name:  "metric-name",
-					Value: &arg,
-				},
-			},
 			Metrics: []v1alpha1.Metric{{
-				Name:             "rate",
-				SuccessCondition: "result > {{args.metric-name}}",
+				Name:                "error-rate",
+				RecentResultsWindow: 12,
 				Provider: v1alpha1.MetricProvider{
-					Web: &v1alpha1.WebMetric{
-						Headers: []v1alpha1.WebMetricHeader{{
-							Key:   "apikey",
-							Value: "{{args.secret}}",
-						}},
-					},
+					Prometheus: &v1alpha1.PrometheusMetric{},
 				},
 			}},
 		},
 	}
+	result := v1alpha1.MetricResult{Name: "error-rate"}
+	for i := 0; i < 15; i++ {
+		result.Measurements = append(result.Measurements, newMeasurement(v1alpha1.AnalysisPhaseSuccessful))
+	}
+	run.Status.MetricResults = []v1alpha1.MetricResult{result}
+	f.provider.On("GarbageCollect", mock.Anything, mock.Anything, 12).Return(nil)
 
-	error := fmt.Errorf("Error with Header Value: %v", secretValue)
-	expectedValue := "Error with Header Value: *****"
-	measurement := newMeasurement(v1alpha1.AnalysisPhaseError)
-	measurement.Message = error.Error()
+	err := c.garbageCollectMeasurements(run, DefaultMeasurementHistoryLimit)
+	assert.NoError(t, err)
+	assert.Len(t, run.Status.MetricResults[0].Measurements, 12)
+}
 
-	f.provider.On("Run", mock.Anything, mock.Anything, mock.Anything).Return(measurement)
-	newRun := c.reconcileAnalysisRun(run)
-	logMessage := buf.String()
+type fakeSink struct {
+	lock    sync.Mutex
+	records []sink.Record
+}
 
-	assert.Equal(t, expectedValue, newRun.Status.MetricResults[0].Measurements[0].Message)
-	assert.False(t, strings.Contains(logMessage, "12345"))
-	assert.True(t, strings.Contains(logMessage, "*****"))
+func (s *fakeSink) Push(record sink.Record) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.records = append(s.records, record)
 }
 
-//TestSecretContentReferenceAndMultipleArgResolutionSuccess verifies that both secret and non-secret arguments are resolved properly
-func TestSecretContentReferenceAndMultipleArgResolutionSuccess(t *testing.T) {
+func TestRunMeasurementsPushesCompletedMeasurementsToSink(t *testing.T) {
 	f := newFixture(t)
-	secretName, secretKey, secretValue := "web-metric-secret", "apikey", "12345"
-	arg := "success-rate"
-	secret := &corev1.Secret{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      secretName,
-			Namespace: metav1.NamespaceDefault,
-		},
-		Data: map[string][]byte{
-			secretKey: []byte(secretValue),
-		},
-	}
 	defer f.Close()
-	f.secretRunLister = append(f.secretRunLister, secret)
 	c, _, _ := f.newController(noResyncPeriodFunc)
-	run := &v1alpha1.AnalysisRun{
+	measurementSink := &fakeSink{}
+	c.measurementSink = measurementSink
+
+	run := newSinkRun()
+	measurement := newMeasurement(v1alpha1.AnalysisPhaseFailed)
+	f.provider.On("Run", mock.Anything, mock.Anything).Return(measurement, nil)
+
+	c.reconcileAnalysisRun(run)
+	expected := sink.Record{
+		ID:          "run-uid.success-rate.0",
+		Namespace:   metav1.NamespaceDefault,
+		AnalysisRun: "run",
+		Metric:      "success-rate",
+		Value:       "100",
+		Phase:       v1alpha1.AnalysisPhaseFailed,
+		Timestamp:   measurement.FinishedAt.Time,
+	}
+	assert.Equal(t, []sink.Record{expected}, measurementSink.records)
+}
+
+func newSinkRun() *v1alpha1.AnalysisRun {
+	return &v1alpha1.AnalysisRun{
 		ObjectMeta: metav1.ObjectMeta{
+			Name:      "run",
 			Namespace: metav1.NamespaceDefault,
+			UID:       "run-uid",
 		},
 		Spec: v1alpha1.AnalysisRunSpec{
-			Args: []v1alpha1.Argument{
-				{
-					Name: "secret",
-					ValueFrom: &v1alpha1.ValueFrom{
-						SecretKeyRef: &v1alpha1.SecretKeyRef{
-							Name: secretName,
-							Key:  secretKey,
-						},
-					},
-				},
-				{
-					Name:  "metric-name",
-					Value: &arg,
-				},
-			},
 			Metrics: []v1alpha1.Metric{{
-				Name:             "secret",
-				SuccessCondition: "result > {{args.metric-name}}",
+				Name:     "success-rate",
+				Interval: "60s",
 				Provider: v1alpha1.MetricProvider{
-					Web: &v1alpha1.WebMetric{
-						Headers: []v1alpha1.WebMetricHeader{{
-							Key:   "apikey",
-							Value: "{{args.secret}}",
-						}},
-					},
+					Prometheus: &v1alpha1.PrometheusMetric{},
 				},
 			}},
 		},
+		Status: v1alpha1.AnalysisRunStatus{
+			Phase: v1alpha1.AnalysisPhaseRunning,
+		},
 	}
-
-	f.provider.On("Run", mock.Anything, mock.Anything, mock.Anything).Return(newMeasurement(v1alpha1.AnalysisPhaseSuccessful), nil)
-	newRun := c.reconcileAnalysisRun(run)
-	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, newRun.Status.Phase)
 }
 
-func TestSecretNotFound(t *testing.T) {
+func TestRunMeasurementsKeepsIntervalWindowAcrossRestart(t *testing.T) {
 	f := newFixture(t)
 	defer f.Close()
-	c, _, _ := f.newController(noResyncPeriodFunc)
+	measurementSink := &fakeSink{}
 
-	args := []v1alpha1.Argument{{
-		Name: "secret-does-not-exist",
-		ValueFrom: &v1alpha1.ValueFrom{
-			SecretKeyRef: &v1alpha1.SecretKeyRef{
-				Name: "secret-does-not-exist",
-			},
-			//SecretKeyRef: nil,
-		},
-	}}
-	tasks := []metricTask{{
-		metric: v1alpha1.Metric{
-			Name:             "metric-name",
-			SuccessCondition: "{{args.secret-does-not-exist}}",
-		},
-		incompleteMeasurement: nil,
-	}}
-	_, _, err := c.resolveArgs(tasks, args, metav1.NamespaceDefault)
-	assert.Equal(t, "secret \"secret-does-not-exist\" not found", err.Error())
+	run := newSinkRun()
+	c, _, _ := f.newController(noResyncPeriodFunc)
+	c.measurementSink = measurementSink
+	now := metav1.Now()
+	inFlight := v1alpha1.Measurement{
+		Phase:     v1alpha1.AnalysisPhaseRunning,
+		StartedAt: &now,
+	}
+	f.provider.On("Run", mock.Anything, mock.Anything).Return(inFlight, nil)
+	scheduledRun := c.reconcileAnalysisRun(run)
+	result := scheduledRun.Status.MetricResults[0]
+	assert.Equal(t, int32(0), result.Count)
+	assert.Len(t, result.Measurements, 1)
+	assert.Equal(t, "0", result.Measurements[0].Metadata[IntervalWindowMetadataKey])
+
+	// The controller restarts between scheduling the measurement and recording its result. The new controller
+	// issues the measurement of the interval window again, which resumes the in-flight measurement instead of
+	// taking a new one.
+	restarted, _, _ := f.newController(noResyncPeriodFunc)
+	restarted.measurementSink = measurementSink
+	f.provider.On("Run", mock.Anything, mock.Anything).Return(newMeasurement(v1alpha1.AnalysisPhaseSuccessful), nil)
+	f.provider.On("Resume", mock.Anything, mock.Anything, mock.Anything).Return(newMeasurement(v1alpha1.AnalysisPhaseFailed), nil)
+	_, err := restarted.runMeasurements(scheduledRun, []metricTask{{metric: scheduledRun.Spec.Metrics[0]}})
+	assert.NoError(t, err)
+	f.provider.AssertNumberOfCalls(t, "Run", 0)
+	f.provider.AssertNumberOfCalls(t, "Resume", 1)
+	result = scheduledRun.Status.MetricResults[0]
+	assert.Equal(t, int32(1), result.Count)
+	assert.Equal(t, int32(1), result.Failed)
+	assert.Len(t, result.Measurements, 1)
+	assert.Equal(t, "0", result.Measurements[0].Metadata[IntervalWindowMetadataKey])
+	assert.Len(t, measurementSink.records, 1)
+	assert.Equal(t, "run-uid.success-rate.0", measurementSink.records[0].ID)
+
+	// Once recorded, the next measurement is taken for the next interval window
+	result.Measurements[0].FinishedAt = timePtr(metav1.NewTime(time.Now().Add(-61 * time.Second)))
+	scheduledRun.Status.MetricResults[0] = result
+	recordedRun := restarted.reconcileAnalysisRun(scheduledRun)
+	f.provider.AssertNumberOfCalls(t, "Run", 1)
+	result = recordedRun.Status.MetricResults[0]
+	assert.Equal(t, int32(2), result.Count)
+	assert.Equal(t, "1", result.Measurements[1].Metadata[IntervalWindowMetadataKey])
+	assert.Len(t, measurementSink.records, 2)
+	assert.Equal(t, "run-uid.success-rate.1", measurementSink.records[1].ID)
 }
 
-func TestArgDoesNotContainSecretRefError(t *testing.T) {
+func TestRunMeasurementsSkipsRecordedIntervalWindow(t *testing.T) {
 	f := newFixture(t)
 	defer f.Close()
 	c, _, _ := f.newController(noResyncPeriodFunc)
 
-	args := []v1alpha1.Argument{{
-		Name: "secret-empty",
-		ValueFrom: &v1alpha1.ValueFrom{
-			SecretKeyRef: nil,
-		},
-	}}
-	tasks := []metricTask{{
-		metric: v1alpha1.Metric{
-			Name:             "metric-name",
-			SuccessCondition: "{{args.secret-empty}}",
-		},
-		incompleteMeasurement: nil,
+	// The measurement of the interval window is recorded, but not counted in the metric result
+	run := newSinkRun()
+	measurement := newMeasurement(v1alpha1.AnalysisPhaseSuccessful)
+	measurement.Metadata = map[string]string{IntervalWindowMetadataKey: "0"}
+	run.Status.MetricResults = []v1alpha1.MetricResult{{
+		Name:         "success-rate",
+		Phase:        v1alpha1.AnalysisPhaseRunning,
+		Measurements: []v1alpha1.Measurement{measurement},
 	}}
-	_, _, err := c.resolveArgs(tasks, args, metav1.NamespaceDefault)
-	assert.Equal(t, "arg 'secret-empty' does not contain a secret reference", err.Error())
+	_, err := c.runMeasurements(run, []metricTask{{metric: run.Spec.Metrics[0]}})
+	assert.NoError(t, err)
+	f.provider.AssertNotCalled(t, "Run", mock.Anything, mock.Anything)
+	assert.Len(t, run.Status.MetricResults[0].Measurements, 1)
+	assert.Equal(t, int32(0), run.Status.MetricResults[0].Count)
 }
 
-func TestKeyNotInSecret(t *testing.T) {
-	f := newFixture(t)
-	secret := &corev1.Secret{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "secret-name",
-			Namespace: metav1.NamespaceDefault,
-		},
+type fakeNotifier struct {
+	webhooks []v1alpha1.AnalysisWebhook
+	outcomes []hook.Outcome
+}
+
+func (n *fakeNotifier) Notify(webhook v1alpha1.AnalysisWebhook, outcome hook.Outcome) {
+	n.webhooks = append(n.webhooks, webhook)
+	n.outcomes = append(n.outcomes, outcome)
+}
+
+func TestNotifyOutcome(t *testing.T) {
+	for _, phase := range []v1alpha1.AnalysisPhase{v1alpha1.AnalysisPhaseSuccessful, v1alpha1.AnalysisPhaseFailed} {
+		f := newFixture(t)
+		c, _, _ := f.newController(noResyncPeriodFunc)
+		notifier := &fakeNotifier{}
+		c.hookNotifier = notifier
+
+		run := newSinkRun()
+		run.Spec.Metrics[0].Count = 1
+		run.Spec.Hooks = &v1alpha1.AnalysisHooks{
+			OnSuccess: &v1alpha1.AnalysisWebhook{URL: "http://ci.example.com/success"},
+			OnFailure: &v1alpha1.AnalysisWebhook{URL: "http://ci.example.com/failure"},
+		}
+		f.provider.On("Run", mock.Anything, mock.Anything).Return(newMeasurement(phase), nil)
+
+		newRun := c.reconcileAnalysisRun(run)
+		assert.Equal(t, phase, newRun.Status.Phase)
+		c.notifyOutcome(run, newRun)
+		assert.Len(t, notifier.outcomes, 1)
+		assert.Equal(t, phase, notifier.outcomes[0].Phase)
+		assert.Equal(t, "run-uid", notifier.outcomes[0].ID)
+		if phase == v1alpha1.AnalysisPhaseSuccessful {
+			assert.Equal(t, "http://ci.example.com/success", notifier.webhooks[0].URL)
+		} else {
+			assert.Equal(t, "http://ci.example.com/failure", notifier.webhooks[0].URL)
+		}
+
+		// the outcome is notified once, when the completion of the run is persisted
+		c.notifyOutcome(newRun, c.reconcileAnalysisRun(newRun))
+		assert.Len(t, notifier.outcomes, 1)
+		f.Close()
 	}
+}
+
+func TestNotifyOutcomeSkipsTerminatedRuns(t *testing.T) {
+	f := newFixture(t)
 	defer f.Close()
-	f.secretRunLister = append(f.secretRunLister, secret)
 	c, _, _ := f.newController(noResyncPeriodFunc)
+	notifier := &fakeNotifier{}
+	c.hookNotifier = notifier
 
-	args := []v1alpha1.Argument{{
-		Name: "secret-wrong-key",
+	run := newSinkRun()
+	run.Spec.Terminate = true
+	run.Status.MetricResults = []v1alpha1.MetricResult{{
+		Name:         "success-rate",
+		Phase:        v1alpha1.AnalysisPhaseRunning,
+		Count:        1,
+		Successful:   1,
+		Measurements: []v1alpha1.Measurement{newMeasurement(v1alpha1.AnalysisPhaseSuccessful)},
+	}}
+	run.Spec.Hooks = &v1alpha1.AnalysisHooks{
+		OnSuccess: &v1alpha1.AnalysisWebhook{URL: "http://ci.example.com/success"},
+	}
+	newRun := c.reconcileAnalysisRun(run)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, newRun.Status.Phase)
+	c.notifyOutcome(run, newRun)
+	assert.Empty(t, notifier.outcomes)
+}
+
+func TestRunMeasurementsDoesNotPushRunningMeasurementsToSink(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+	c, _, _ := f.newController(noResyncPeriodFunc)
+	measurementSink := &fakeSink{}
+	c.measurementSink = measurementSink
+
+	run := newRun()
+	run.Status = v1alpha1.AnalysisRunStatus{Phase: v1alpha1.AnalysisPhaseRunning}
+	f.provider.On("Run", mock.Anything, mock.Anything).Return(newMeasurement(v1alpha1.AnalysisPhaseRunning), nil)
+
+	c.reconcileAnalysisRun(run)
+	assert.Empty(t, measurementSink.records)
+}
+
+func TestTrimMeasurementsPerRun(t *testing.T) {
+	measurementAt := func(phase v1alpha1.AnalysisPhase, value string, minutesAgo int) v1alpha1.Measurement {
+		startedAt := metav1.NewTime(time.Now().Add(-time.Duration(minutesAgo) * time.Minute))
+		return v1alpha1.Measurement{Phase: phase, Value: value, StartedAt: &startedAt, FinishedAt: &startedAt}
+	}
+	run := &v1alpha1.AnalysisRun{
+		Status: v1alpha1.AnalysisRunStatus{
+			MetricResults: []v1alpha1.MetricResult{
+				{
+					Name: "success-rate",
+					Measurements: []v1alpha1.Measurement{
+						measurementAt(v1alpha1.AnalysisPhaseSuccessful, "1", 6),
+						measurementAt(v1alpha1.AnalysisPhaseFailed, "2", 5),
+						measurementAt(v1alpha1.AnalysisPhaseSuccessful, "3", 2),
+						measurementAt(v1alpha1.AnalysisPhaseSuccessful, "4", 1),
+					},
+				},
+				{
+					Name: "latency",
+					Measurements: []v1alpha1.Measurement{
+						measurementAt(v1alpha1.AnalysisPhaseSuccessful, "5", 4),
+						measurementAt(v1alpha1.AnalysisPhaseSuccessful, "6", 3),
+					},
+				},
+			},
+		},
+	}
+	values := func() []string {
+		values := []string{}
+		for _, result := range run.Status.MetricResults {
+			for _, measurement := range result.Measurements {
+				values = append(values, measurement.Value)
+			}
+		}
+		return values
+	}
+
+	trimMeasurementsPerRun(run, 10)
+	assert.Equal(t, []string{"1", "2", "3", "4", "5", "6"}, values())
+
+	// The oldest measurements are trimmed first, except failures
+	trimMeasurementsPerRun(run, 4)
+	assert.Equal(t, []string{"2", "3", "4", "6"}, values())
+
+	// The most recent measurement of every metric and the failures exceed the limit
+	trimMeasurementsPerRun(run, 1)
+	assert.Equal(t, []string{"2", "4", "6"}, values())
+}
+
+func TestMaxMeasurementsPerRunBoundsStatusOverManyReconciles(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+	c, _, _ := f.newController(noResyncPeriodFunc)
+	c.maxMeasurementsPerRun = 5
+
+	run := &v1alpha1.AnalysisRun{
+		Spec: v1alpha1.AnalysisRunSpec{
+			Metrics: []v1alpha1.Metric{
+				{
+					Name:         "success-rate",
+					Interval:     "60s",
+					FailureLimit: 100,
+					Provider: v1alpha1.MetricProvider{
+						Prometheus: &v1alpha1.PrometheusMetric{},
+					},
+				},
+				{
+					Name:         "latency",
+					Interval:     "60s",
+					FailureLimit: 100,
+					Provider: v1alpha1.MetricProvider{
+						Prometheus: &v1alpha1.PrometheusMetric{},
+					},
+				},
+			},
+		},
+	}
+	f.provider.On("Run", mock.Anything, mock.Anything, mock.Anything).Return(newMeasurement(v1alpha1.AnalysisPhaseFailed), nil).Once()
+	f.provider.On("Run", mock.Anything, mock.Anything, mock.Anything).Return(newMeasurement(v1alpha1.AnalysisPhaseSuccessful), nil)
+	f.provider.On("GarbageCollect", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	for i := 0; i < 50; i++ {
+		run = c.reconcileAnalysisRun(run)
+		assert.Equal(t, v1alpha1.AnalysisPhaseRunning, run.Status.Phase)
+		total := 0
+		for _, result := range run.Status.MetricResults {
+			total += len(result.Measurements)
+		}
+		assert.True(t, total <= 5, "reconcile %d retained %d measurements", i, total)
+
+		// Age the measurements past the interval so the next reconcile takes a new measurement of every metric
+		for j := range run.Status.MetricResults {
+			measurements := run.Status.MetricResults[j].Measurements
+			for k := range measurements {
+				startedAt := metav1.NewTime(measurements[k].StartedAt.Add(-2 * time.Minute))
+				finishedAt := metav1.NewTime(measurements[k].FinishedAt.Add(-2 * time.Minute))
+				measurements[k].StartedAt = &startedAt
+				measurements[k].FinishedAt = &finishedAt
+			}
+		}
+	}
+
+	assert.Equal(t, int32(50), run.Status.MetricResults[0].Count)
+	assert.Equal(t, int32(50), run.Status.MetricResults[1].Count)
+	// The failed measurement of the first reconcile is retained
+	failed := 0
+	for _, result := range run.Status.MetricResults {
+		for _, measurement := range result.Measurements {
+			if measurement.Phase == v1alpha1.AnalysisPhaseFailed {
+				failed++
+			}
+		}
+	}
+	assert.Equal(t, 1, failed)
+	// The most recent measurement of every metric is retained
+	for _, result := range run.Status.MetricResults {
+		lastMeasurement := result.Measurements[len(result.Measurements)-1]
+		assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, lastMeasurement.Phase)
+		assert.True(t, time.Now().Add(-2*time.Minute).Equal(lastMeasurement.StartedAt.Time))
+	}
+}
+
+// TestResolveMetricArgs verifies that metric arguments are resolved
+func TestResolveMetricArgs(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+	c, _, _ := f.newController(noResyncPeriodFunc)
+	arg1, arg2 := "success-rate", "success-rate2"
+	args := []v1alpha1.Argument{
+		{
+			Name:  "metric-name",
+			Value: &arg1,
+		},
+		{
+			Name:  "metric-name2",
+			Value: &arg2,
+		},
+	}
+	metric1 := v1alpha1.Metric{Name: "metric-name", SuccessCondition: "result > {{args.metric-name}}"}
+	metric2 := v1alpha1.Metric{Name: "metric-name2", SuccessCondition: "result < {{args.metric-name2}}"}
+	newMetric1, _ := c.resolveMetricArgs(metric1, args)
+	newMetric2, _ := c.resolveMetricArgs(metric2, args)
+	assert.Equal(t, fmt.Sprintf("result > %s", arg1), newMetric1.SuccessCondition)
+	assert.Equal(t, fmt.Sprintf("result < %s", arg2), newMetric2.SuccessCondition)
+}
+
+//TestResolveMetricArgsWithQuotes verifies that metric arguments with quotes are resolved
+func TestResolveMetricArgsWithQuotes(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+	c, _, _ := f.newController(noResyncPeriodFunc)
+	arg := "foo \"bar\" baz"
+
+	arguments := []v1alpha1.Argument{{
+		Name:  "rate",
+		Value: &arg,
+	}}
+	metric := v1alpha1.Metric{
+		Name:             "rate",
+		SuccessCondition: "{{args.rate}}",
+	}
+	newMetric, err := c.resolveMetricArgs(metric, arguments)
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf(arg), newMetric.SuccessCondition)
+}
+
+// TestResolveMetricArgsWithSprigFunctions verifies that the Sprig functions of a template pipeline are rendered in the
+// query of a Datadog metric
+func TestResolveMetricArgsWithSprigFunctions(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+	c, _, _ := f.newController(noResyncPeriodFunc)
+	arguments := []v1alpha1.Argument{
+		{Name: "env", Value: pointer.StringPtr(" Production ")},
+		{Name: "service", Value: pointer.StringPtr("Checkout_API")},
+		{Name: "region", Value: pointer.StringPtr("")},
+	}
+	metric := v1alpha1.Metric{
+		Name: "availability",
+		Provider: v1alpha1.MetricProvider{
+			Datadog: &v1alpha1.DatadogMetric{
+				SLOID: `{{ .service | lower | replace "_" "-" }}-{{ .env | trim | lower }}-{{ .region | default "us" }}`,
+			},
+		},
+	}
+	newMetric, err := c.resolveMetricArgs(metric, arguments)
+	assert.NoError(t, err)
+	assert.Equal(t, "checkout-api-production-us", newMetric.Provider.Datadog.SLOID)
+
+	metric.Provider.Datadog.SLOID = `{{ env "HOME" }}`
+	_, err = c.resolveMetricArgs(metric, arguments)
+	assert.EqualError(t, err, `failed to resolve {{ env \"HOME\" }}`)
+}
+
+// TestResolveMetricArgsWithCanaryWeight verifies that the conditions of a metric can depend on the canary weight
+func TestResolveMetricArgsWithCanaryWeight(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+	c, _, _ := f.newController(noResyncPeriodFunc)
+	metric := v1alpha1.Metric{
+		Name:             "success-rate",
+		SuccessCondition: "result >= 0.95 + 0.0004 * {{args.canary-weight}}",
+		FailureCondition: "result < 0.95 + 0.0004 * {{args.canary-weight}}",
+	}
+	logCtx := *log.NewEntry(log.New())
+
+	lowWeight := "20"
+	newMetric, err := c.resolveMetricArgs(metric, []v1alpha1.Argument{{Name: "canary-weight", Value: &lowWeight}})
+	assert.NoError(t, err)
+	assert.Equal(t, "result >= 0.95 + 0.0004 * 20", newMetric.SuccessCondition)
+	phase, err := evaluate.EvaluateResult(0.97, *newMetric, logCtx)
+	assert.NoError(t, err)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, phase)
+
+	// The same result fails the stricter threshold of a higher weight
+	highWeight := "100"
+	newMetric, err = c.resolveMetricArgs(metric, []v1alpha1.Argument{{Name: "canary-weight", Value: &highWeight}})
+	assert.NoError(t, err)
+	assert.Equal(t, "result < 0.95 + 0.0004 * 100", newMetric.FailureCondition)
+	phase, err = evaluate.EvaluateResult(0.97, *newMetric, logCtx)
+	assert.NoError(t, err)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, phase)
+}
+
+// TestResolveMetricArgsJobContainers verifies the command, args and env of the containers of a job metric are
+// substituted with the arguments before the job is created
+func TestResolveMetricArgsJobContainers(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+	c, _, _ := f.newController(noResyncPeriodFunc)
+	podHash, environment := "6c54544bf9", "staging"
+	args := []v1alpha1.Argument{
+		{Name: "canary-hash", Value: &podHash},
+		{Name: "environment", Value: &environment},
+	}
+	metric := v1alpha1.Metric{
+		Name: "integration-test",
+		Provider: v1alpha1.MetricProvider{
+			Job: &v1alpha1.JobMetric{
+				Spec: batchv1.JobSpec{
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{{
+								Name:    "test",
+								Command: []string{"/bin/sh", "-c"},
+								Args:    []string{"./run-tests.sh --env {{args.environment}} --selector rollouts-pod-template-hash={{args.canary-hash}}"},
+								Env:     []corev1.EnvVar{{Name: "ENVIRONMENT", Value: "{{args.environment}}"}},
+							}},
+						},
+					},
+				},
+			},
+		},
+	}
+	newMetric, err := c.resolveMetricArgs(metric, args)
+	assert.NoError(t, err)
+	container := newMetric.Provider.Job.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, []string{"/bin/sh", "-c"}, container.Command)
+	assert.Equal(t, []string{"./run-tests.sh --env staging --selector rollouts-pod-template-hash=6c54544bf9"}, container.Args)
+	assert.Equal(t, "staging", container.Env[0].Value)
+}
+
+func TestResolveMetricArgsUnableToSubstitute(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+	c, _, _ := f.newController(noResyncPeriodFunc)
+	run := &v1alpha1.AnalysisRun{
+		Spec: v1alpha1.AnalysisRunSpec{
+			Metrics: []v1alpha1.Metric{{
+				Name:             "rate",
+				SuccessCondition: "{{args.does-not-exist}}",
+				Provider: v1alpha1.MetricProvider{
+					Prometheus: &v1alpha1.PrometheusMetric{
+						Query: "{{args.metric-name}}",
+					},
+				},
+			}},
+		},
+	}
+	newRun := c.reconcileAnalysisRun(run)
+	assert.Equal(t, newRun.Status.Phase, v1alpha1.AnalysisPhaseError)
+	assert.Equal(t, newRun.Status.Message, "unable to resolve metric arguments: failed to resolve {{args.metric-name}}")
+}
+
+func TestSecretContentReferenceValueFromError(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+	c, _, _ := f.newController(noResyncPeriodFunc)
+	argName := "apikey"
+	argVal := "value"
+	run := &v1alpha1.AnalysisRun{
+		Spec: v1alpha1.AnalysisRunSpec{
+			Args: []v1alpha1.Argument{{
+				Name:  argName,
+				Value: &argVal,
+				ValueFrom: &v1alpha1.ValueFrom{
+					SecretKeyRef: &v1alpha1.SecretKeyRef{
+						Name: "web-metric-secret",
+						Key:  "apikey",
+					},
+				}},
+			},
+			Metrics: []v1alpha1.Metric{{
+				Name: "rate",
+				Provider: v1alpha1.MetricProvider{
+					Web: &v1alpha1.WebMetric{
+						Headers: []v1alpha1.WebMetricHeader{{
+							Key:   "apikey",
+							Value: "{{args.apikey}}",
+						}},
+					},
+				},
+			}},
+		},
+	}
+	newRun := c.reconcileAnalysisRun(run)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, newRun.Status.Phase)
+	assert.Equal(t, fmt.Sprintf("unable to resolve metric arguments: arg '%v' has both Value and ValueFrom fields", argName), newRun.Status.Message)
+}
+
+// TestSecretContentReferenceSuccess verifies that secret arguments are properly resolved
+func TestSecretContentReferenceSuccess(t *testing.T) {
+	f := newFixture(t)
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "web-metric-secret",
+			Namespace: metav1.NamespaceDefault,
+		},
+		Data: map[string][]byte{
+			"apikey": []byte("12345"),
+		},
+	}
+	defer f.Close()
+	f.secretRunLister = append(f.secretRunLister, secret)
+	c, _, _ := f.newController(noResyncPeriodFunc)
+	argName := "apikey"
+	run := &v1alpha1.AnalysisRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: metav1.NamespaceDefault,
+		},
+		Spec: v1alpha1.AnalysisRunSpec{
+			Args: []v1alpha1.Argument{{
+				Name: argName,
+				ValueFrom: &v1alpha1.ValueFrom{
+					SecretKeyRef: &v1alpha1.SecretKeyRef{
+						Name: "web-metric-secret",
+						Key:  "apikey",
+					},
+				},
+			}},
+			Metrics: []v1alpha1.Metric{{
+				Name: "rate",
+				Provider: v1alpha1.MetricProvider{
+					Web: &v1alpha1.WebMetric{
+						Headers: []v1alpha1.WebMetricHeader{{
+							Key:   "apikey",
+							Value: "{{args.apikey}}",
+						}},
+					},
+				},
+			}},
+		},
+	}
+	f.provider.On("Run", mock.Anything, mock.Anything, mock.Anything).Return(newMeasurement(v1alpha1.AnalysisPhaseSuccessful), nil)
+	newRun := c.reconcileAnalysisRun(run)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, newRun.Status.Phase)
+}
+
+// TestSecretContentReferenceProviderError verifies that secret values are redacted in logs
+func TestSecretContentReferenceProviderError(t *testing.T) {
+	buf := bytes.NewBufferString("")
+	log.SetOutput(buf)
+	f := newFixture(t)
+	secretName, secretKey, secretValue := "web-metric-secret", "apikey", "12345"
+	arg := "success-rate"
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secretName,
+			Namespace: metav1.NamespaceDefault,
+		},
+		Data: map[string][]byte{
+			secretKey: []byte(secretValue),
+		},
+	}
+	defer f.Close()
+	f.secretRunLister = append(f.secretRunLister, secret)
+	c, _, _ := f.newController(noResyncPeriodFunc)
+	run := &v1alpha1.AnalysisRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: metav1.NamespaceDefault,
+		},
+		Spec: v1alpha1.AnalysisRunSpec{
+			Args: []v1alpha1.Argument{
+				{
+					Name: "secret",
+					ValueFrom: &v1alpha1.ValueFrom{
+						SecretKeyRef: &v1alpha1.SecretKeyRef{
+							Name: secretName,
+							Key:  secretKey,
+						},
+					},
+				},
+				{
+					Name:  "metric-name",
+					Value: &arg,
+				},
+			},
+			Metrics: []v1alpha1.Metric{{
+				Name:             "rate",
+				SuccessCondition: "result > {{args.metric-name}}",
+				Provider: v1alpha1.MetricProvider{
+					Web: &v1alpha1.WebMetric{
+						Headers: []v1alpha1.WebMetricHeader{{
+							Key:   "apikey",
+							Value: "{{args.secret}}",
+						}},
+					},
+				},
+			}},
+		},
+	}
+
+	error := fmt.Errorf("Error with Header Value: %v", secretValue)
+	expectedValue := "Error with Header Value: *****"
+	measurement := newMeasurement(v1alpha1.AnalysisPhaseError)
+	measurement.Message = error.Error()
+
+	f.provider.On("Run", mock.Anything, mock.Anything, mock.Anything).Return(measurement)
+	newRun := c.reconcileAnalysisRun(run)
+	logMessage := buf.String()
+
+	assert.Equal(t, expectedValue, newRun.Status.MetricResults[0].Measurements[0].Message)
+	assert.False(t, strings.Contains(logMessage, "12345"))
+	assert.True(t, strings.Contains(logMessage, "*****"))
+}
+
+//TestSecretContentReferenceAndMultipleArgResolutionSuccess verifies that both secret and non-secret arguments are resolved properly
+func TestSecretContentReferenceAndMultipleArgResolutionSuccess(t *testing.T) {
+	f := newFixture(t)
+	secretName, secretKey, secretValue := "web-metric-secret", "apikey", "12345"
+	arg := "success-rate"
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secretName,
+			Namespace: metav1.NamespaceDefault,
+		},
+		Data: map[string][]byte{
+			secretKey: []byte(secretValue),
+		},
+	}
+	defer f.Close()
+	f.secretRunLister = append(f.secretRunLister, secret)
+	c, _, _ := f.newController(noResyncPeriodFunc)
+	run := &v1alpha1.AnalysisRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: metav1.NamespaceDefault,
+		},
+		Spec: v1alpha1.AnalysisRunSpec{
+			Args: []v1alpha1.Argument{
+				{
+					Name: "secret",
+					ValueFrom: &v1alpha1.ValueFrom{
+						SecretKeyRef: &v1alpha1.SecretKeyRef{
+							Name: secretName,
+							Key:  secretKey,
+						},
+					},
+				},
+				{
+					Name:  "metric-name",
+					Value: &arg,
+				},
+			},
+			Metrics: []v1alpha1.Metric{{
+				Name:             "secret",
+				SuccessCondition: "result > {{args.metric-name}}",
+				Provider: v1alpha1.MetricProvider{
+					Web: &v1alpha1.WebMetric{
+						Headers: []v1alpha1.WebMetricHeader{{
+							Key:   "apikey",
+							Value: "{{args.secret}}",
+						}},
+					},
+				},
+			}},
+		},
+	}
+
+	f.provider.On("Run", mock.Anything, mock.Anything, mock.Anything).Return(newMeasurement(v1alpha1.AnalysisPhaseSuccessful), nil)
+	newRun := c.reconcileAnalysisRun(run)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, newRun.Status.Phase)
+}
+
+func TestSecretNotFound(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+	c, _, _ := f.newController(noResyncPeriodFunc)
+
+	args := []v1alpha1.Argument{{
+		Name: "secret-does-not-exist",
+		ValueFrom: &v1alpha1.ValueFrom{
+			SecretKeyRef: &v1alpha1.SecretKeyRef{
+				Name: "secret-does-not-exist",
+			},
+			//SecretKeyRef: nil,
+		},
+	}}
+	tasks := []metricTask{{
+		metric: v1alpha1.Metric{
+			Name:             "metric-name",
+			SuccessCondition: "{{args.secret-does-not-exist}}",
+		},
+		incompleteMeasurement: nil,
+	}}
+	_, _, err := c.resolveArgs(tasks, args, metav1.NamespaceDefault)
+	assert.Equal(t, "secret \"secret-does-not-exist\" not found", err.Error())
+}
+
+func TestArgDoesNotContainSecretRefError(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+	c, _, _ := f.newController(noResyncPeriodFunc)
+
+	args := []v1alpha1.Argument{{
+		Name: "secret-empty",
+		ValueFrom: &v1alpha1.ValueFrom{
+			SecretKeyRef: nil,
+		},
+	}}
+	tasks := []metricTask{{
+		metric: v1alpha1.Metric{
+			Name:             "metric-name",
+			SuccessCondition: "{{args.secret-empty}}",
+		},
+		incompleteMeasurement: nil,
+	}}
+	_, _, err := c.resolveArgs(tasks, args, metav1.NamespaceDefault)
+	assert.Equal(t, "arg 'secret-empty' does not contain a secret reference", err.Error())
+}
+
+func TestKeyNotInSecret(t *testing.T) {
+	f := newFixture(t)
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "secret-name",
+			Namespace: metav1.NamespaceDefault,
+		},
+	}
+	defer f.Close()
+	f.secretRunLister = append(f.secretRunLister, secret)
+	c, _, _ := f.newController(noResyncPeriodFunc)
+
+	args := []v1alpha1.Argument{{
+		Name: "secret-wrong-key",
 		ValueFrom: &v1alpha1.ValueFrom{
 			SecretKeyRef: &v1alpha1.SecretKeyRef{
 				Name: "secret-name",
 				Key:  "key-name",
 			},
 		},
-	}}
-	tasks := []metricTask{{
-		metric: v1alpha1.Metric{
-			Name:             "metric-name",
-			SuccessCondition: "{{args.secret-wrong-key}}",
+	}}
+	tasks := []metricTask{{
+		metric: v1alpha1.Metric{
+			Name:             "metric-name",
+			SuccessCondition: "{{args.secret-wrong-key}}",
+		},
+		incompleteMeasurement: nil,
+	}}
+	_, _, err := c.resolveArgs(tasks, args, metav1.NamespaceDefault)
+	assert.Equal(t, "key 'key-name' does not exist in secret 'secret-name'", err.Error())
+}
+
+// TestAssessMetricFailureInconclusiveOrError verifies that assessMetricFailureInconclusiveOrError returns the correct phases and messages
+// for Failed, Inconclusive, and Error metrics respectively
+func TestAssessMetricFailureInconclusiveOrError(t *testing.T) {
+	metric := v1alpha1.Metric{}
+	result := v1alpha1.MetricResult{
+		Failed: 1,
+		Measurements: []v1alpha1.Measurement{{
+			Phase: v1alpha1.AnalysisPhaseFailed,
+		}},
+	}
+	phase, msg := assessMetricFailureInconclusiveOrError(metric, result)
+	expectedMsg := fmt.Sprintf("failed (%d) > failureLimit (%d)", result.Failed, metric.FailureLimit)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, phase)
+	assert.Equal(t, expectedMsg, msg)
+	assert.Equal(t, phase, assessMetricStatus(metric, result, true))
+
+	result = v1alpha1.MetricResult{
+		Inconclusive: 1,
+		Measurements: []v1alpha1.Measurement{{
+			Phase: v1alpha1.AnalysisPhaseInconclusive,
+		}},
+	}
+	phase, msg = assessMetricFailureInconclusiveOrError(metric, result)
+	expectedMsg = fmt.Sprintf("inconclusive (%d) > inconclusiveLimit (%d)", result.Inconclusive, metric.InconclusiveLimit)
+	assert.Equal(t, v1alpha1.AnalysisPhaseInconclusive, phase)
+	assert.Equal(t, expectedMsg, msg)
+	assert.Equal(t, phase, assessMetricStatus(metric, result, true))
+
+	result = v1alpha1.MetricResult{
+		ConsecutiveError: 5, //default ConsecutiveErrorLimit for Metrics is 4
+		Measurements: []v1alpha1.Measurement{{
+			Phase: v1alpha1.AnalysisPhaseError,
+		}},
+	}
+	phase, msg = assessMetricFailureInconclusiveOrError(metric, result)
+	expectedMsg = fmt.Sprintf("consecutiveErrors (%d) > consecutiveErrorLimit (%d)", result.ConsecutiveError, defaults.DefaultConsecutiveErrorLimit)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, phase)
+	assert.Equal(t, expectedMsg, msg)
+	assert.Equal(t, phase, assessMetricStatus(metric, result, true))
+}
+
+func TestAssessRunStatusErrorMessageAnalysisPhaseFail(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+	c, _, _ := f.newController(noResyncPeriodFunc)
+
+	run := newTerminatingRun(v1alpha1.AnalysisPhaseFailed)
+	run.Status.MetricResults[0].Phase = v1alpha1.AnalysisPhaseSuccessful
+	status, message := c.assessRunStatus(run)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, status)
+	assert.Equal(t, "metric \"failed-metric\" assessed Failed due to failed (1) > failureLimit (0)", message)
+}
+
+// TestAssessRunStatusErrorMessageFromProvider verifies that the message returned by assessRunStatus
+// includes the error message from the provider
+func TestAssessRunStatusErrorMessageFromProvider(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+	c, _, _ := f.newController(noResyncPeriodFunc)
+
+	run := newTerminatingRun(v1alpha1.AnalysisPhaseFailed)
+	run.Status.MetricResults[0].Phase = v1alpha1.AnalysisPhaseSuccessful // All metrics must complete, or assessRunStatus will not return message
+
+	providerMessage := "Provider error"
+	run.Status.MetricResults[1].Message = providerMessage
+
+	status, message := c.assessRunStatus(run)
+	expectedMessage := fmt.Sprintf("metric \"failed-metric\" assessed Failed due to failed (1) > failureLimit (0): \"Error Message: %s\"", providerMessage)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, status)
+	assert.Equal(t, expectedMessage, message)
+}
+
+// TestAssessRunStatusMultipleFailures verifies that if there are multiple failed metrics, assessRunStatus returns the message
+// from the first failed metric
+func TestAssessRunStatusMultipleFailures(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+	c, _, _ := f.newController(noResyncPeriodFunc)
+
+	run := newTerminatingRun(v1alpha1.AnalysisPhaseFailed)
+	run.Status.MetricResults[0].Phase = v1alpha1.AnalysisPhaseFailed
+	run.Status.MetricResults[0].Failed = 1
+
+	status, message := c.assessRunStatus(run)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, status)
+	assert.Equal(t, "metric \"run-forever\" assessed Failed due to failed (1) > failureLimit (0)", message)
+}
+
+// TestAssessRunStatusWorstMessageInReconcileAnalysisRun verifies that the worstMessage returned by assessRunStatus is set as the
+// status of the AnalysisRun returned by reconcileAnalysisRun
+func TestAssessRunStatusWorstMessageInReconcileAnalysisRun(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+	c, _, _ := f.newController(noResyncPeriodFunc)
+
+	run := newTerminatingRun(v1alpha1.AnalysisPhaseFailed)
+	run.Status.MetricResults[0].Phase = v1alpha1.AnalysisPhaseFailed
+	run.Status.MetricResults[0].Failed = 1
+
+	f.provider.On("Run", mock.Anything, mock.Anything, mock.Anything).Return(newMeasurement(v1alpha1.AnalysisPhaseFailed), nil)
+
+	newRun := c.reconcileAnalysisRun(run)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, newRun.Status.Phase)
+	assert.Equal(t, "metric \"run-forever\" assessed Failed due to failed (1) > failureLimit (0)", newRun.Status.Message)
+}
+
+// TestAssessRunStatusMetricErrorPolicy verifies that the errored metrics of a run whose errored metrics are
+// inconclusive leave the other metrics to decide the phase of the run
+func TestAssessRunStatusMetricErrorPolicy(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+	c, _, _ := f.newController(noResyncPeriodFunc)
+	run := &v1alpha1.AnalysisRun{
+		Spec: v1alpha1.AnalysisRunSpec{
+			Metrics: []v1alpha1.Metric{
+				{Name: "latency"},
+				{Name: "success-rate"},
+			},
+			MetricErrorPolicy: v1alpha1.MetricErrorPolicyInconclusive,
+		},
+	}
+	errored := v1alpha1.MetricResult{
+		Name:             "success-rate",
+		Phase:            v1alpha1.AnalysisPhaseError,
+		Error:            5,
+		ConsecutiveError: 5,
+		Message:          "connection refused",
+	}
+	erroredMessage := "metric \"success-rate\" assessed Error due to consecutiveErrors (5) > consecutiveErrorLimit (4): \"Error Message: connection refused\""
+	for _, test := range []struct {
+		latency v1alpha1.AnalysisPhase
+		phase   v1alpha1.AnalysisPhase
+		message string
+	}{
+		// the run keeps measuring the available metrics
+		{v1alpha1.AnalysisPhaseRunning, v1alpha1.AnalysisPhaseRunning, ""},
+		// the available metrics passed, but the errored metric leaves the run inconclusive
+		{v1alpha1.AnalysisPhaseSuccessful, v1alpha1.AnalysisPhaseInconclusive, erroredMessage},
+		// a failed metric still fails the run
+		{v1alpha1.AnalysisPhaseFailed, v1alpha1.AnalysisPhaseFailed, ""},
+		// every metric errored, the first of which has no limit exceeded to explain it
+		{v1alpha1.AnalysisPhaseError, v1alpha1.AnalysisPhaseError, ""},
+	} {
+		run.Status = v1alpha1.AnalysisRunStatus{
+			Phase: v1alpha1.AnalysisPhaseRunning,
+			MetricResults: []v1alpha1.MetricResult{
+				{Name: "latency", Phase: test.latency},
+				errored,
+			},
+		}
+		status, message := c.assessRunStatus(run)
+		assert.Equal(t, test.phase, status)
+		assert.Equal(t, test.message, message)
+	}
+
+	// the errored metric errors the run with the default policy
+	run.Spec.MetricErrorPolicy = ""
+	run.Status.MetricResults[0].Phase = v1alpha1.AnalysisPhaseSuccessful
+	status, message := c.assessRunStatus(run)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, status)
+	assert.Equal(t, erroredMessage, message)
+}
+
+// TestReconcileAnalysisRunMetricErrorPolicy verifies that a metric whose provider is unavailable does not terminate a
+// run whose errored metrics are inconclusive
+func TestReconcileAnalysisRunMetricErrorPolicy(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+	c, _, _ := f.newController(noResyncPeriodFunc)
+	run := newRun()
+	run.Spec.MetricErrorPolicy = v1alpha1.MetricErrorPolicyInconclusive
+	run.Spec.Metrics = []v1alpha1.Metric{
+		{Name: "latency", Count: 2, Interval: "30s", Provider: v1alpha1.MetricProvider{Job: &v1alpha1.JobMetric{}}},
+		{Name: "success-rate", Count: 2, Interval: "30s", Provider: v1alpha1.MetricProvider{Job: &v1alpha1.JobMetric{}}},
+	}
+	overdue := v1alpha1.Measurement{
+		Value:      "1",
+		Phase:      v1alpha1.AnalysisPhaseSuccessful,
+		StartedAt:  timePtr(metav1.NewTime(time.Now().Add(-60 * time.Second))),
+		FinishedAt: timePtr(metav1.NewTime(time.Now().Add(-60 * time.Second))),
+	}
+	run.Status = v1alpha1.AnalysisRunStatus{
+		Phase: v1alpha1.AnalysisPhaseRunning,
+		MetricResults: []v1alpha1.MetricResult{
+			{
+				Name:         "latency",
+				Phase:        v1alpha1.AnalysisPhaseRunning,
+				Count:        1,
+				Successful:   1,
+				Measurements: []v1alpha1.Measurement{overdue},
+			},
+			{
+				Name:             "success-rate",
+				Phase:            v1alpha1.AnalysisPhaseError,
+				Error:            5,
+				ConsecutiveError: 5,
+				Measurements:     []v1alpha1.Measurement{newMeasurement(v1alpha1.AnalysisPhaseError)},
+			},
+		},
+	}
+	f.provider.On("Run", mock.Anything, mock.Anything, mock.Anything).Return(newMeasurement(v1alpha1.AnalysisPhaseSuccessful), nil)
+
+	newRun := c.reconcileAnalysisRun(run)
+	assert.Equal(t, v1alpha1.AnalysisPhaseInconclusive, newRun.Status.Phase)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, newRun.Status.MetricResults[0].Phase)
+	assert.Equal(t, int32(2), newRun.Status.MetricResults[0].Count)
+	assert.Contains(t, newRun.Status.Message, "metric \"success-rate\" assessed Error")
+}
+
+func TestRunMeasurementsRateLimited(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+	c, _, _ := f.newController(noResyncPeriodFunc)
+	c.rateLimiters = metricproviders.NewRateLimiters([]metricproviders.RateLimit{{
+		Provider:          "Prometheus",
+		RequestsPerSecond: 0.001,
+		Burst:             1,
+	}}, nil)
+
+	run := &v1alpha1.AnalysisRun{
+		Spec: v1alpha1.AnalysisRunSpec{
+			Metrics: []v1alpha1.Metric{{
+				Name: "success-rate",
+				Provider: v1alpha1.MetricProvider{
+					Prometheus: &v1alpha1.PrometheusMetric{},
+				},
+			}, {
+				Name: "latency",
+				Provider: v1alpha1.MetricProvider{
+					Prometheus: &v1alpha1.PrometheusMetric{},
+				},
+			}},
+		},
+	}
+	f.provider.On("Run", mock.Anything, mock.Anything, mock.Anything).Return(newMeasurement(v1alpha1.AnalysisPhaseSuccessful), nil)
+
+	newRun := c.reconcileAnalysisRun(run)
+	// only one of the metrics is measured and the other is postponed without an error
+	assert.Equal(t, v1alpha1.AnalysisPhaseRunning, newRun.Status.Phase)
+	assert.Len(t, newRun.Status.MetricResults, 2)
+	measured := 0
+	for _, result := range newRun.Status.MetricResults {
+		assert.Equal(t, int32(0), result.Error)
+		assert.Equal(t, int32(0), result.ConsecutiveError)
+		if len(result.Measurements) == 1 {
+			assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, result.Phase)
+			measured++
+		} else {
+			assert.Equal(t, v1alpha1.AnalysisPhasePending, result.Phase)
+			assert.Len(t, result.Measurements, 0)
+		}
+	}
+	assert.Equal(t, 1, measured)
+	f.provider.AssertNumberOfCalls(t, "Run", 1)
+}
+
+func TestResumeMeasurementNotRateLimited(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+	c, _, _ := f.newController(noResyncPeriodFunc)
+	c.rateLimiters = metricproviders.NewRateLimiters([]metricproviders.RateLimit{{
+		Provider:          "Job",
+		RequestsPerSecond: 0.001,
+		Burst:             1,
+	}}, nil)
+	// the rate limit is reached
+	assert.True(t, c.rateLimiters.TryAccept("", v1alpha1.Metric{Provider: v1alpha1.MetricProvider{Job: &v1alpha1.JobMetric{}}}))
+
+	run := &v1alpha1.AnalysisRun{
+		Spec: v1alpha1.AnalysisRunSpec{
+			Metrics: []v1alpha1.Metric{{
+				Name: "test",
+				Provider: v1alpha1.MetricProvider{
+					Job: &v1alpha1.JobMetric{},
+				},
+			}},
+		},
+		Status: v1alpha1.AnalysisRunStatus{
+			Phase: v1alpha1.AnalysisPhaseRunning,
+			MetricResults: []v1alpha1.MetricResult{{
+				Name:  "test",
+				Phase: v1alpha1.AnalysisPhaseRunning,
+				Measurements: []v1alpha1.Measurement{{
+					Phase:     v1alpha1.AnalysisPhaseRunning,
+					StartedAt: timePtr(metav1.NewTime(time.Now().Add(-60 * time.Second))),
+				}},
+			}},
+		},
+	}
+	f.provider.On("Resume", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(newMeasurement(v1alpha1.AnalysisPhaseSuccessful), nil)
+
+	newRun := c.reconcileAnalysisRun(run)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, newRun.Status.Phase)
+	f.provider.AssertNumberOfCalls(t, "Resume", 1)
+	f.provider.AssertNotCalled(t, "Run", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLoadProviderConfig(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+	c, _, _ := f.newController(noResyncPeriodFunc)
+	assert.Nil(t, c.getProviderDefaults())
+	// the settings are loaded when the controller runs, not when it is constructed
+	assert.Empty(t, f.kubeclient.Actions())
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      metricproviders.ConfigMapName,
+			Namespace: defaults.Namespace(),
 		},
-		incompleteMeasurement: nil,
+		Data: map[string]string{
+			metricproviders.ProviderDefaultsConfigMapKey: `
+- consecutiveErrorLimit: 1
+`,
+		},
+	}
+	_, err := f.kubeclient.CoreV1().ConfigMaps(cm.Namespace).Create(cm)
+	assert.NoError(t, err)
+	c.loadProviderConfig()
+	assert.Equal(t, []metricproviders.ProviderDefaults{{ConsecutiveErrorLimit: pointer.Int32Ptr(1)}}, c.getProviderDefaults())
+
+	// invalid settings keep the previously loaded settings
+	cm.Data[metricproviders.ProviderDefaultsConfigMapKey] = "invalid"
+	_, err = f.kubeclient.CoreV1().ConfigMaps(cm.Namespace).Update(cm)
+	assert.NoError(t, err)
+	c.loadProviderConfig()
+	assert.Equal(t, []metricproviders.ProviderDefaults{{ConsecutiveErrorLimit: pointer.Int32Ptr(1)}}, c.getProviderDefaults())
+}
+
+func TestReconcileAnalysisRunAppliesProviderDefaults(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+	c, _, _ := f.newController(noResyncPeriodFunc)
+	c.providerDefaults = []metricproviders.ProviderDefaults{{
+		ConsecutiveErrorLimit: pointer.Int32Ptr(0),
+	}, {
+		Namespace:             "team-a",
+		ConsecutiveErrorLimit: pointer.Int32Ptr(1),
 	}}
-	_, _, err := c.resolveArgs(tasks, args, metav1.NamespaceDefault)
-	assert.Equal(t, "key 'key-name' does not exist in secret 'secret-name'", err.Error())
+
+	newDefaultsRun := func(namespace string, consecutiveErrorLimit *int32) *v1alpha1.AnalysisRun {
+		return &v1alpha1.AnalysisRun{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+			Spec: v1alpha1.AnalysisRunSpec{
+				Metrics: []v1alpha1.Metric{{
+					Name:                  "success-rate",
+					ConsecutiveErrorLimit: consecutiveErrorLimit,
+					Provider: v1alpha1.MetricProvider{
+						Prometheus: &v1alpha1.PrometheusMetric{},
+					},
+				}},
+			},
+		}
+	}
+	f.provider.On("Run", mock.Anything, mock.Anything, mock.Anything).Return(newMeasurement(v1alpha1.AnalysisPhaseError), nil)
+
+	// the cluster-wide default does not retry errored measurements
+	run := c.reconcileAnalysisRun(newDefaultsRun("team-b", nil))
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, run.Status.Phase)
+	assert.Equal(t, "metric \"success-rate\" assessed Error due to consecutiveErrors (1) > consecutiveErrorLimit (0)", run.Status.Message)
+
+	// the default of the namespace takes precedence
+	run = c.reconcileAnalysisRun(newDefaultsRun("team-a", nil))
+	assert.Equal(t, v1alpha1.AnalysisPhaseRunning, run.Status.Phase)
+
+	// the limit of the metric takes precedence over the defaults
+	run = c.reconcileAnalysisRun(newDefaultsRun("team-b", pointer.Int32Ptr(3)))
+	assert.Equal(t, v1alpha1.AnalysisPhaseRunning, run.Status.Phase)
 }
 
-// TestAssessMetricFailureInconclusiveOrError verifies that assessMetricFailureInconclusiveOrError returns the correct phases and messages
-// for Failed, Inconclusive, and Error metrics respectively
-func TestAssessMetricFailureInconclusiveOrError(t *testing.T) {
-	metric := v1alpha1.Metric{}
-	result := v1alpha1.MetricResult{
-		Failed: 1,
-		Measurements: []v1alpha1.Measurement{{
-			Phase: v1alpha1.AnalysisPhaseFailed,
-		}},
+func newFallbackProviderRun() *v1alpha1.AnalysisRun {
+	return &v1alpha1.AnalysisRun{
+		Spec: v1alpha1.AnalysisRunSpec{
+			Metrics: []v1alpha1.Metric{{
+				Name: "success-rate",
+				Provider: v1alpha1.MetricProvider{
+					Prometheus: &v1alpha1.PrometheusMetric{},
+				},
+				FallbackProvider: &v1alpha1.FallbackMetricProvider{
+					Web: &v1alpha1.WebMetric{},
+				},
+			}},
+		},
 	}
-	phase, msg := assessMetricFailureInconclusiveOrError(metric, result)
-	expectedMsg := fmt.Sprintf("failed (%d) > failureLimit (%d)", result.Failed, metric.FailureLimit)
-	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, phase)
-	assert.Equal(t, expectedMsg, msg)
-	assert.Equal(t, phase, assessMetricStatus(metric, result, true))
+}
 
-	result = v1alpha1.MetricResult{
-		Inconclusive: 1,
-		Measurements: []v1alpha1.Measurement{{
-			Phase: v1alpha1.AnalysisPhaseInconclusive,
+func isPrimaryMetric(metric v1alpha1.Metric) bool {
+	return metric.Provider.Prometheus != nil
+}
+
+func isFallbackMetric(metric v1alpha1.Metric) bool {
+	return metric.Provider.Web != nil
+}
+
+func TestRunMeasurementsFallbackProvider(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+	c, _, _ := f.newController(noResyncPeriodFunc)
+
+	errMeasurement := newMeasurement(v1alpha1.AnalysisPhaseError)
+	errMeasurement.Message = "connection refused"
+	f.provider.On("Run", mock.Anything, mock.MatchedBy(isPrimaryMetric)).Return(errMeasurement, nil)
+	f.provider.On("Run", mock.Anything, mock.MatchedBy(isFallbackMetric)).Return(newMeasurement(v1alpha1.AnalysisPhaseSuccessful), nil)
+	f.provider.On("Type").Return("WebMetric")
+
+	newRun := c.reconcileAnalysisRun(newFallbackProviderRun())
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, newRun.Status.Phase)
+	result := newRun.Status.MetricResults[0]
+	assert.Equal(t, int32(1), result.Successful)
+	assert.Equal(t, int32(0), result.Error)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, result.Measurements[0].Phase)
+	assert.Equal(t, "WebMetric", result.Measurements[0].Metadata[FallbackProviderMetadataKey])
+	f.provider.AssertNumberOfCalls(t, "Run", 2)
+}
+
+func TestRunMeasurementsFallbackProviderError(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+	c, _, _ := f.newController(noResyncPeriodFunc)
+
+	primaryErr := newMeasurement(v1alpha1.AnalysisPhaseError)
+	primaryErr.Message = "connection refused"
+	fallbackErr := newMeasurement(v1alpha1.AnalysisPhaseError)
+	fallbackErr.Message = "received non 2xx response code: 503"
+	f.provider.On("Run", mock.Anything, mock.MatchedBy(isPrimaryMetric)).Return(primaryErr, nil)
+	f.provider.On("Run", mock.Anything, mock.MatchedBy(isFallbackMetric)).Return(fallbackErr, nil)
+	f.provider.On("Type").Return("WebMetric")
+
+	newRun := c.reconcileAnalysisRun(newFallbackProviderRun())
+	result := newRun.Status.MetricResults[0]
+	assert.Equal(t, int32(1), result.Error)
+	assert.Equal(t, int32(1), result.ConsecutiveError)
+	assert.Equal(t, "connection refused; fallback provider error: received non 2xx response code: 503", result.Measurements[0].Message)
+}
+
+func TestRunMeasurementsFallbackProviderNotUsedOnFailure(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+	c, _, _ := f.newController(noResyncPeriodFunc)
+
+	f.provider.On("Run", mock.Anything, mock.Anything).Return(newMeasurement(v1alpha1.AnalysisPhaseFailed), nil)
+
+	newRun := c.reconcileAnalysisRun(newFallbackProviderRun())
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, newRun.Status.Phase)
+	assert.Equal(t, int32(1), newRun.Status.MetricResults[0].Failed)
+	f.provider.AssertNumberOfCalls(t, "Run", 1)
+	f.provider.AssertCalled(t, "Run", mock.Anything, mock.MatchedBy(isPrimaryMetric))
+}
+
+func TestResumeFallbackProviderMeasurement(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+	c, _, _ := f.newController(noResyncPeriodFunc)
+
+	run := newFallbackProviderRun()
+	run.Status = v1alpha1.AnalysisRunStatus{
+		Phase: v1alpha1.AnalysisPhaseRunning,
+		MetricResults: []v1alpha1.MetricResult{{
+			Name:  "success-rate",
+			Phase: v1alpha1.AnalysisPhaseRunning,
+			Measurements: []v1alpha1.Measurement{{
+				Phase:     v1alpha1.AnalysisPhaseRunning,
+				StartedAt: timePtr(metav1.NewTime(time.Now().Add(-60 * time.Second))),
+				Metadata:  map[string]string{FallbackProviderMetadataKey: "WebMetric"},
+			}},
 		}},
 	}
-	phase, msg = assessMetricFailureInconclusiveOrError(metric, result)
-	expectedMsg = fmt.Sprintf("inconclusive (%d) > inconclusiveLimit (%d)", result.Inconclusive, metric.InconclusiveLimit)
-	assert.Equal(t, v1alpha1.AnalysisPhaseInconclusive, phase)
-	assert.Equal(t, expectedMsg, msg)
-	assert.Equal(t, phase, assessMetricStatus(metric, result, true))
+	f.provider.On("Resume", mock.Anything, mock.MatchedBy(isFallbackMetric), mock.Anything).Return(newMeasurement(v1alpha1.AnalysisPhaseSuccessful), nil)
 
-	result = v1alpha1.MetricResult{
-		ConsecutiveError: 5, //default ConsecutiveErrorLimit for Metrics is 4
-		Measurements: []v1alpha1.Measurement{{
-			Phase: v1alpha1.AnalysisPhaseError,
-		}},
+	newRun := c.reconcileAnalysisRun(run)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, newRun.Status.Phase)
+	f.provider.AssertNumberOfCalls(t, "Resume", 1)
+}
+
+func TestReconcileAnalysisRunDisallowedProvider(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+	c, _, _ := f.newController(noResyncPeriodFunc)
+	c.allowedProviders = []string{"WebMetric"}
+	run := &v1alpha1.AnalysisRun{
+		Spec: v1alpha1.AnalysisRunSpec{
+			Metrics: []v1alpha1.Metric{{
+				Name: "success-rate",
+				Provider: v1alpha1.MetricProvider{
+					Prometheus: &v1alpha1.PrometheusMetric{},
+				},
+			}},
+		},
 	}
-	phase, msg = assessMetricFailureInconclusiveOrError(metric, result)
-	expectedMsg = fmt.Sprintf("consecutiveErrors (%d) > consecutiveErrorLimit (%d)", result.ConsecutiveError, defaults.DefaultConsecutiveErrorLimit)
-	assert.Equal(t, v1alpha1.AnalysisPhaseError, phase)
-	assert.Equal(t, expectedMsg, msg)
-	assert.Equal(t, phase, assessMetricStatus(metric, result, true))
+	newRun := c.reconcileAnalysisRun(run)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, newRun.Status.Phase)
+	assert.Equal(t, "analysis spec invalid: provider 'Prometheus' of metric 'success-rate' is not allowed", newRun.Status.Message)
+	assert.Len(t, newRun.Status.MetricResults, 0)
+	f.provider.AssertNotCalled(t, "Run", mock.Anything, mock.Anything)
 }
 
-func TestAssessRunStatusErrorMessageAnalysisPhaseFail(t *testing.T) {
+func TestRunMeasurementsCircuitBreakerOpen(t *testing.T) {
 	f := newFixture(t)
 	defer f.Close()
 	c, _, _ := f.newController(noResyncPeriodFunc)
+	c.circuitBreakers = metricproviders.NewCircuitBreakers([]metricproviders.CircuitBreakerConfig{{
+		Provider:         "Prometheus",
+		FailureThreshold: 1,
+		Cooldown:         "1h",
+		OpenPhase:        v1alpha1.AnalysisPhaseInconclusive,
+	}})
 
-	run := newTerminatingRun(v1alpha1.AnalysisPhaseFailed)
-	run.Status.MetricResults[0].Phase = v1alpha1.AnalysisPhaseSuccessful
-	status, message := c.assessRunStatus(run)
-	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, status)
-	assert.Equal(t, "metric \"failed-metric\" assessed Failed due to failed (1) > failureLimit (0)", message)
+	run := &v1alpha1.AnalysisRun{
+		Spec: v1alpha1.AnalysisRunSpec{
+			Metrics: []v1alpha1.Metric{{
+				Name:              "success-rate",
+				Interval:          "60s",
+				InconclusiveLimit: 2,
+				Provider: v1alpha1.MetricProvider{
+					Prometheus: &v1alpha1.PrometheusMetric{},
+				},
+			}},
+		},
+	}
+	f.provider.On("Run", mock.Anything, mock.Anything, mock.Anything).Return(newMeasurement(v1alpha1.AnalysisPhaseError), nil).Once()
+
+	// the errored measurement opens the circuit
+	newRun := c.reconcileAnalysisRun(run)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, newRun.Status.MetricResults[0].Measurements[0].Phase)
+
+	// the following measurement is short-circuited without querying the provider
+	newRun.Status.MetricResults[0].Measurements[0].FinishedAt = timePtr(metav1.NewTime(time.Now().Add(-61 * time.Second)))
+	newRun = c.reconcileAnalysisRun(newRun)
+	f.provider.AssertNumberOfCalls(t, "Run", 1)
+	assert.Len(t, newRun.Status.MetricResults[0].Measurements, 2)
+	measurement := newRun.Status.MetricResults[0].Measurements[1]
+	assert.Equal(t, v1alpha1.AnalysisPhaseInconclusive, measurement.Phase)
+	assert.Equal(t, metricproviders.CircuitBreakerOpenValue, measurement.Metadata[metricproviders.CircuitBreakerMetadataKey])
+	assert.Equal(t, int32(1), newRun.Status.MetricResults[0].Inconclusive)
 }
 
-// TestAssessRunStatusErrorMessageFromProvider verifies that the message returned by assessRunStatus
-// includes the error message from the provider
-func TestAssessRunStatusErrorMessageFromProvider(t *testing.T) {
+// TestRecordMetricEventsOnTransitions verifies an event is recorded with the value and the conditions of a metric when
+// it completes, and is not recorded again by the following reconciliations
+func TestRecordMetricEventsOnTransitions(t *testing.T) {
 	f := newFixture(t)
 	defer f.Close()
 	c, _, _ := f.newController(noResyncPeriodFunc)
+	recorder := record.NewFakeRecorder(10)
+	c.recorder = recorder
 
-	run := newTerminatingRun(v1alpha1.AnalysisPhaseFailed)
-	run.Status.MetricResults[0].Phase = v1alpha1.AnalysisPhaseSuccessful // All metrics must complete, or assessRunStatus will not return message
+	failed := newMeasurement(v1alpha1.AnalysisPhaseFailed)
+	failed.Value = "0.82"
+	run := &v1alpha1.AnalysisRun{
+		Spec: v1alpha1.AnalysisRunSpec{
+			Metrics: []v1alpha1.Metric{
+				{
+					Name:             "success-rate",
+					SuccessCondition: "result >= 0.95",
+				},
+				{
+					Name:             "latency",
+					SuccessCondition: "result < 500",
+					FailureCondition: "result >= 1000",
+				},
+			},
+		},
+		Status: v1alpha1.AnalysisRunStatus{
+			Phase: v1alpha1.AnalysisPhaseRunning,
+			MetricResults: []v1alpha1.MetricResult{
+				{
+					Name:         "success-rate",
+					Phase:        v1alpha1.AnalysisPhaseRunning,
+					Count:        1,
+					Failed:       1,
+					Measurements: []v1alpha1.Measurement{failed},
+				},
+				{
+					Name:         "latency",
+					Phase:        v1alpha1.AnalysisPhaseRunning,
+					Count:        1,
+					Successful:   1,
+					Measurements: []v1alpha1.Measurement{newMeasurement(v1alpha1.AnalysisPhaseSuccessful)},
+				},
+			},
+		},
+	}
+	status, _ := c.assessRunStatus(run)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, status)
+	assert.Len(t, recorder.Events, 2)
+	assert.Equal(t, "Warning MetricFailed metric 'success-rate' failed with value '0.82' (successCondition: 'result >= 0.95')", <-recorder.Events)
+	assert.Equal(t, "Normal MetricSucceeded metric 'latency' succeeded with value '100' (successCondition: 'result < 500', failureCondition: 'result >= 1000')", <-recorder.Events)
 
-	providerMessage := "Provider error"
-	run.Status.MetricResults[1].Message = providerMessage
+	c.assessRunStatus(run)
+	assert.Len(t, recorder.Events, 0)
+}
 
-	status, message := c.assessRunStatus(run)
-	expectedMessage := fmt.Sprintf("metric \"failed-metric\" assessed Failed due to failed (1) > failureLimit (0): \"Error Message: %s\"", providerMessage)
-	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, status)
-	assert.Equal(t, expectedMessage, message)
+// TestRecordMetricMarginalEvent verifies a metric which completes Inconclusive with a marginal band records an event
+// asking for a decision on the rollout
+func TestRecordMetricMarginalEvent(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+	c, _, _ := f.newController(noResyncPeriodFunc)
+	recorder := record.NewFakeRecorder(10)
+	c.recorder = recorder
+
+	marginal := newMeasurement(v1alpha1.AnalysisPhaseInconclusive)
+	marginal.Value = "[0.93]"
+	run := &v1alpha1.AnalysisRun{
+		Spec: v1alpha1.AnalysisRunSpec{
+			Metrics: []v1alpha1.Metric{{
+				Name:             "success-rate",
+				SuccessCondition: "result[0] >= 0.95",
+				MarginalBand: &v1alpha1.MarginalBand{
+					Lower: "0.90",
+					Upper: "0.95",
+				},
+			}},
+		},
+		Status: v1alpha1.AnalysisRunStatus{
+			Phase: v1alpha1.AnalysisPhaseRunning,
+			MetricResults: []v1alpha1.MetricResult{{
+				Name:         "success-rate",
+				Phase:        v1alpha1.AnalysisPhaseRunning,
+				Count:        1,
+				Inconclusive: 1,
+				Measurements: []v1alpha1.Measurement{marginal},
+			}},
+		},
+	}
+	status, _ := c.assessRunStatus(run)
+	assert.Equal(t, v1alpha1.AnalysisPhaseInconclusive, status)
+	assert.Len(t, recorder.Events, 1)
+	assert.Equal(t, "Warning MetricMarginal metric 'success-rate' is marginal with value '[0.93]' (marginalBand: [0.90, 0.95]), awaiting promotion or abort", <-recorder.Events)
 }
 
-// TestAssessRunStatusMultipleFailures verifies that if there are multiple failed metrics, assessRunStatus returns the message
-// from the first failed metric
-func TestAssessRunStatusMultipleFailures(t *testing.T) {
+// TestReconcileAnalysisRunWithTemplatedMarginalBand verifies a marginal band referencing arguments is not rejected
+// before the arguments are resolved
+func TestReconcileAnalysisRunWithTemplatedMarginalBand(t *testing.T) {
 	f := newFixture(t)
 	defer f.Close()
 	c, _, _ := f.newController(noResyncPeriodFunc)
 
-	run := newTerminatingRun(v1alpha1.AnalysisPhaseFailed)
-	run.Status.MetricResults[0].Phase = v1alpha1.AnalysisPhaseFailed
-	run.Status.MetricResults[0].Failed = 1
+	run := &v1alpha1.AnalysisRun{
+		Spec: v1alpha1.AnalysisRunSpec{
+			Args: []v1alpha1.Argument{{
+				Name:  "marginal-lower",
+				Value: pointer.StringPtr("0.90"),
+			}},
+			Metrics: []v1alpha1.Metric{{
+				Name:             "success-rate",
+				SuccessCondition: "result >= 0.95",
+				MarginalBand: &v1alpha1.MarginalBand{
+					Lower: "{{args.marginal-lower}}",
+					Upper: "0.95",
+				},
+				Provider: v1alpha1.MetricProvider{
+					Prometheus: &v1alpha1.PrometheusMetric{},
+				},
+			}},
+		},
+	}
+	f.provider.On("Run", mock.Anything, mock.Anything, mock.Anything).Return(newMeasurement(v1alpha1.AnalysisPhaseSuccessful), nil)
 
-	status, message := c.assessRunStatus(run)
-	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, status)
-	assert.Equal(t, "metric \"run-forever\" assessed Failed due to failed (1) > failureLimit (0)", message)
+	newRun := c.reconcileAnalysisRun(run)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, newRun.Status.Phase)
+	f.provider.AssertNumberOfCalls(t, "Run", 1)
 }
 
-// TestAssessRunStatusWorstMessageInReconcileAnalysisRun verifies that the worstMessage returned by assessRunStatus is set as the
-// status of the AnalysisRun returned by reconcileAnalysisRun
-func TestAssessRunStatusWorstMessageInReconcileAnalysisRun(t *testing.T) {
+// TestRunMeasurementsRecordsFirstMeasurementError verifies only the first of consecutive measurement errors records
+// an event
+func TestRunMeasurementsRecordsFirstMeasurementError(t *testing.T) {
 	f := newFixture(t)
 	defer f.Close()
 	c, _, _ := f.newController(noResyncPeriodFunc)
+	recorder := record.NewFakeRecorder(10)
+	c.recorder = recorder
 
-	run := newTerminatingRun(v1alpha1.AnalysisPhaseFailed)
-	run.Status.MetricResults[0].Phase = v1alpha1.AnalysisPhaseFailed
-	run.Status.MetricResults[0].Failed = 1
+	metric := v1alpha1.Metric{
+		Name: "success-rate",
+		Provider: v1alpha1.MetricProvider{
+			Job: &v1alpha1.JobMetric{},
+		},
+	}
+	run := &v1alpha1.AnalysisRun{
+		Spec: v1alpha1.AnalysisRunSpec{
+			Metrics: []v1alpha1.Metric{metric},
+		},
+		Status: v1alpha1.AnalysisRunStatus{
+			Phase: v1alpha1.AnalysisPhaseRunning,
+		},
+	}
+	errMeasurement := newMeasurement(v1alpha1.AnalysisPhaseError)
+	errMeasurement.Message = "connection refused"
+	f.provider.On("Run", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(errMeasurement, nil)
 
-	f.provider.On("Run", mock.Anything, mock.Anything, mock.Anything).Return(newMeasurement(v1alpha1.AnalysisPhaseFailed), nil)
+	_, err := c.runMeasurements(run, []metricTask{{metric: metric}})
+	assert.NoError(t, err)
+	assert.Len(t, recorder.Events, 1)
+	assert.Equal(t, "Warning MeasurementError metric 'success-rate' measurement error: connection refused", <-recorder.Events)
 
-	newRun := c.reconcileAnalysisRun(run)
-	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, newRun.Status.Phase)
-	assert.Equal(t, "metric \"run-forever\" assessed Failed due to failed (1) > failureLimit (0)", newRun.Status.Message)
+	_, err = c.runMeasurements(run, []metricTask{{metric: metric}})
+	assert.NoError(t, err)
+	assert.Len(t, recorder.Events, 0)
+	assert.Len(t, run.Status.MetricResults[0].Measurements, 2)
 }
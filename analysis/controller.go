@@ -1,6 +1,7 @@
 package analysis
 
 import (
+	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -11,10 +12,13 @@ import (
 	coreinformers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/kubernetes"
 	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 
+	"github.com/argoproj/argo-rollouts/analysis/hook"
+	"github.com/argoproj/argo-rollouts/analysis/sink"
 	"github.com/argoproj/argo-rollouts/controller/metrics"
 	"github.com/argoproj/argo-rollouts/metricproviders"
 	register "github.com/argoproj/argo-rollouts/pkg/apis/rollouts"
@@ -45,6 +49,29 @@ type Controller struct {
 
 	newProvider func(logCtx log.Entry, metric v1alpha1.Metric) (metricproviders.Provider, error)
 
+	// rateLimiters limits the rate of measurements taken against each metric provider backend
+	rateLimiters *metricproviders.RateLimiters
+
+	// providerDefaults are the settings applied to the metrics of each namespace which do not specify them
+	providerDefaults     []metricproviders.ProviderDefaults
+	providerDefaultsLock sync.RWMutex
+
+	// circuitBreakers stop querying the metric provider backends which consistently error
+	circuitBreakers *metricproviders.CircuitBreakers
+
+	// maxMeasurementsPerRun limits the total number of measurements retained in the status of an AnalysisRun.
+	// Unlimited when 0
+	maxMeasurementsPerRun int
+
+	// measurementSink receives the completed measurements. Disabled when nil
+	measurementSink sink.Sink
+
+	// hookNotifier notifies the webhooks of the outcome of the completed runs. Disabled when nil
+	hookNotifier hook.Notifier
+
+	// allowedProviders are the provider types which metrics may use. All the provider types are allowed when empty
+	allowedProviders []string
+
 	// used for unit testing
 	enqueueAnalysis      func(obj interface{})
 	enqueueAnalysisAfter func(obj interface{}, duration time.Duration)
@@ -64,6 +91,7 @@ type Controller struct {
 // ControllerConfig describes the data required to instantiate a new analysis controller
 type ControllerConfig struct {
 	KubeClientSet        kubernetes.Interface
+	KubeConfig           *rest.Config
 	ArgoProjClientset    clientset.Interface
 	AnalysisRunInformer  informers.AnalysisRunInformer
 	SecretInformer       coreinformers.SecretInformer
@@ -72,22 +100,38 @@ type ControllerConfig struct {
 	AnalysisRunWorkQueue workqueue.RateLimitingInterface
 	MetricsServer        *metrics.MetricsServer
 	Recorder             record.EventRecorder
+	// MaxMeasurementsPerRun limits the total number of measurements retained in the status of an AnalysisRun
+	MaxMeasurementsPerRun int
+	// MeasurementSink receives the completed measurements of the AnalysisRuns
+	MeasurementSink sink.Sink
+	// HookNotifier notifies the webhooks of the outcome of the completed AnalysisRuns
+	HookNotifier hook.Notifier
+	// AllowedProviders are the provider types which metrics may use. All the provider types are allowed when empty
+	AllowedProviders []string
+	// RecordProviderResponseBodies records the response bodies of failed provider calls in the measurement metadata
+	RecordProviderResponseBodies bool
+	// MaxProviderResponseBytes is the maximum size of the response bodies read by the HTTP based providers
+	MaxProviderResponseBytes int64
 }
 
 // NewController returns a new analysis controller
 func NewController(cfg ControllerConfig) *Controller {
 
 	controller := &Controller{
-		kubeclientset:        cfg.KubeClientSet,
-		argoProjClientset:    cfg.ArgoProjClientset,
-		analysisRunLister:    cfg.AnalysisRunInformer.Lister(),
-		metricsServer:        cfg.MetricsServer,
-		analysisRunWorkQueue: cfg.AnalysisRunWorkQueue,
-		secretLister:         cfg.SecretInformer.Lister(),
-		jobInformer:          cfg.JobInformer,
-		analysisRunSynced:    cfg.AnalysisRunInformer.Informer().HasSynced,
-		recorder:             cfg.Recorder,
-		resyncPeriod:         cfg.ResyncPeriod,
+		kubeclientset:         cfg.KubeClientSet,
+		argoProjClientset:     cfg.ArgoProjClientset,
+		analysisRunLister:     cfg.AnalysisRunInformer.Lister(),
+		metricsServer:         cfg.MetricsServer,
+		analysisRunWorkQueue:  cfg.AnalysisRunWorkQueue,
+		secretLister:          cfg.SecretInformer.Lister(),
+		jobInformer:           cfg.JobInformer,
+		analysisRunSynced:     cfg.AnalysisRunInformer.Informer().HasSynced,
+		recorder:              cfg.Recorder,
+		resyncPeriod:          cfg.ResyncPeriod,
+		maxMeasurementsPerRun: cfg.MaxMeasurementsPerRun,
+		measurementSink:       cfg.MeasurementSink,
+		hookNotifier:          cfg.HookNotifier,
+		allowedProviders:      cfg.AllowedProviders,
 	}
 
 	controller.enqueueAnalysis = func(obj interface{}) {
@@ -98,11 +142,18 @@ func NewController(cfg ControllerConfig) *Controller {
 	}
 
 	providerFactory := metricproviders.ProviderFactory{
-		KubeClient: controller.kubeclientset,
-		JobLister:  cfg.JobInformer.Lister(),
+		KubeClient:           controller.kubeclientset,
+		KubeConfig:           cfg.KubeConfig,
+		JobLister:            cfg.JobInformer.Lister(),
+		AllowedProviders:     cfg.AllowedProviders,
+		RecordResponseBodies: cfg.RecordProviderResponseBodies,
+		MaxResponseBytes:     cfg.MaxProviderResponseBytes,
 	}
 	controller.newProvider = providerFactory.NewProvider
 
+	controller.rateLimiters = metricproviders.NewRateLimiters(nil, nil)
+	controller.circuitBreakers = metricproviders.NewCircuitBreakers(nil)
+
 	cfg.JobInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
 			controller.enqueueIfCompleted(obj)
@@ -127,8 +178,57 @@ func NewController(cfg ControllerConfig) *Controller {
 	return controller
 }
 
+// loadProviderConfig loads the defaults, the rate limits and the circuit breakers of the metric providers from the
+// controller ConfigMap. The settings which fail to load keep their previous value.
+func (c *Controller) loadProviderConfig() {
+	providerDefaults, err := metricproviders.GetProviderDefaults(c.kubeclientset)
+	if err != nil {
+		log.Warnf("Failed to load metric provider defaults, keeping the previous defaults: %v", err)
+		providerDefaults = c.getProviderDefaults()
+	}
+	c.providerDefaultsLock.Lock()
+	c.providerDefaults = providerDefaults
+	c.providerDefaultsLock.Unlock()
+
+	rateLimits, err := metricproviders.GetRateLimits(c.kubeclientset)
+	if err != nil {
+		log.Warnf("Failed to load metric provider rate limits, keeping the previous rate limits: %v", err)
+	} else {
+		c.rateLimiters.SetConfig(rateLimits, providerDefaults)
+	}
+
+	circuitBreakers, err := metricproviders.GetCircuitBreakers(c.kubeclientset)
+	if err != nil {
+		log.Warnf("Failed to load metric provider circuit breakers, keeping the previous circuit breakers: %v", err)
+	} else {
+		c.circuitBreakers.SetConfigs(circuitBreakers)
+	}
+}
+
+// getProviderDefaults returns the metric provider defaults loaded from the controller ConfigMap
+func (c *Controller) getProviderDefaults() []metricproviders.ProviderDefaults {
+	c.providerDefaultsLock.RLock()
+	defer c.providerDefaultsLock.RUnlock()
+	return c.providerDefaults
+}
+
 func (c *Controller) Run(threadiness int, stopCh <-chan struct{}) error {
 	log.Info("Starting analysis workers")
+	// The metric provider settings are loaded before the workers start, and reloaded periodically so that changes to
+	// the controller ConfigMap are applied without restarting the controller
+	c.loadProviderConfig()
+	go func() {
+		ticker := time.NewTicker(ProviderConfigReloadInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.loadProviderConfig()
+			case <-stopCh:
+				return
+			}
+		}
+	}()
 	for i := 0; i < threadiness; i++ {
 		go wait.Until(func() {
 			controllerutil.RunWorker(c.analysisRunWorkQueue, logutil.AnalysisRunKey, c.syncHandler, c.metricsServer)
@@ -170,7 +270,12 @@ func (c *Controller) syncHandler(key string) error {
 	}
 
 	newRun := c.reconcileAnalysisRun(run)
-	return c.persistAnalysisRunStatus(run, newRun.Status)
+	err = c.persistAnalysisRunStatus(run, newRun.Status)
+	if err != nil {
+		return err
+	}
+	c.notifyOutcome(run, newRun)
+	return nil
 }
 
 func (c *Controller) enqueueIfCompleted(obj interface{}) {
@@ -0,0 +1,216 @@
+package hook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/argoproj/argo-rollouts/analysis/sink"
+	register "github.com/argoproj/argo-rollouts/pkg/apis/rollouts"
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	analysisutil "github.com/argoproj/argo-rollouts/utils/analysis"
+	logutil "github.com/argoproj/argo-rollouts/utils/log"
+)
+
+const (
+	// DefaultQueueSize is the number of outcomes buffered by the HTTP notifier before new outcomes are dropped
+	DefaultQueueSize = 100
+	// DefaultTimeout is the timeout of a request to a webhook
+	DefaultTimeout = 10 * time.Second
+)
+
+// DefaultBackoff is the backoff between the attempts to post an outcome to a webhook
+var DefaultBackoff = wait.Backoff{
+	Duration: time.Second,
+	Factor:   2,
+	Jitter:   0.1,
+	Steps:    5,
+}
+
+// Outcome is the outcome of a completed AnalysisRun posted to its webhooks
+type Outcome struct {
+	// ID identifies the AnalysisRun. It is posted as the idempotency key, which lets the receivers discard an outcome
+	// notified again, such as after a controller restart.
+	ID          string                 `json:"id"`
+	Namespace   string                 `json:"namespace"`
+	Rollout     string                 `json:"rollout,omitempty"`
+	AnalysisRun string                 `json:"analysisRun"`
+	Phase       v1alpha1.AnalysisPhase `json:"phase"`
+	Message     string                 `json:"message,omitempty"`
+	Metrics     []MetricOutcome        `json:"metrics"`
+	Timestamp   time.Time              `json:"timestamp"`
+}
+
+// MetricOutcome is the outcome of a metric of a completed AnalysisRun
+type MetricOutcome struct {
+	Name    string                 `json:"name"`
+	Phase   v1alpha1.AnalysisPhase `json:"phase"`
+	Message string                 `json:"message,omitempty"`
+	// Value is the value of the last measurement of the metric
+	Value string `json:"value,omitempty"`
+}
+
+// NewOutcome returns the outcome of the completed AnalysisRun
+func NewOutcome(run *v1alpha1.AnalysisRun) Outcome {
+	outcome := Outcome{
+		ID:          string(run.UID),
+		Namespace:   run.Namespace,
+		AnalysisRun: run.Name,
+		Phase:       run.Status.Phase,
+		Message:     run.Status.Message,
+		Metrics:     []MetricOutcome{},
+		Timestamp:   time.Now().UTC(),
+	}
+	if controllerRef := metav1.GetControllerOf(run); controllerRef != nil && controllerRef.Kind == register.RolloutKind {
+		outcome.Rollout = controllerRef.Name
+	}
+	for _, result := range run.Status.MetricResults {
+		metric := MetricOutcome{
+			Name:    result.Name,
+			Phase:   result.Phase,
+			Message: result.Message,
+		}
+		if measurement := analysisutil.LastMeasurement(run, result.Name); measurement != nil {
+			metric.Value = measurement.Value
+		}
+		outcome.Metrics = append(outcome.Metrics, metric)
+	}
+	return outcome
+}
+
+// Webhook returns the webhook of the AnalysisRun notified of its outcome, or nil if the run does not notify one.
+// Terminated runs do not notify their webhooks since their outcome does not reflect the metrics.
+func Webhook(run *v1alpha1.AnalysisRun) *v1alpha1.AnalysisWebhook {
+	if run.Spec.Hooks == nil || run.Spec.Terminate {
+		return nil
+	}
+	switch run.Status.Phase {
+	case v1alpha1.AnalysisPhaseSuccessful:
+		return run.Spec.Hooks.OnSuccess
+	case v1alpha1.AnalysisPhaseFailed, v1alpha1.AnalysisPhaseError:
+		return run.Spec.Hooks.OnFailure
+	}
+	return nil
+}
+
+// Notifier notifies the webhooks of the outcome of the AnalysisRuns
+type Notifier interface {
+	// Notify queues the outcome to be posted to the webhook without blocking
+	Notify(webhook v1alpha1.AnalysisWebhook, outcome Outcome)
+}
+
+// notification is an outcome queued to be posted to a webhook
+type notification struct {
+	webhook v1alpha1.AnalysisWebhook
+	outcome Outcome
+}
+
+// HTTPNotifier posts the outcomes as JSON to the webhooks. Outcomes are posted in the background, and failed posts
+// are retried with an exponential backoff so that an unavailable webhook does not slow down the analysis.
+type HTTPNotifier struct {
+	client        *http.Client
+	backoff       wait.Backoff
+	notifications chan notification
+}
+
+// NewHTTPNotifier returns a notifier posting the outcomes to the webhooks
+func NewHTTPNotifier() *HTTPNotifier {
+	return &HTTPNotifier{
+		client:        &http.Client{Timeout: DefaultTimeout},
+		backoff:       DefaultBackoff,
+		notifications: make(chan notification, DefaultQueueSize),
+	}
+}
+
+// Notify queues the outcome to be posted to the webhook. The outcome is dropped when the queue is full.
+func (n *HTTPNotifier) Notify(webhook v1alpha1.AnalysisWebhook, outcome Outcome) {
+	select {
+	case n.notifications <- notification{webhook: webhook, outcome: outcome}:
+	default:
+		outcomeLog(outcome).Warn("Analysis hook queue is full, dropping the outcome")
+	}
+}
+
+func outcomeLog(outcome Outcome) *log.Entry {
+	return log.WithField(logutil.NamespaceKey, outcome.Namespace).WithField(logutil.AnalysisRunKey, outcome.AnalysisRun)
+}
+
+// Run posts the queued outcomes until the stop channel is closed
+func (n *HTTPNotifier) Run(stopCh <-chan struct{}) {
+	log.Info("Starting analysis hook notifier")
+	for {
+		select {
+		case <-stopCh:
+			log.Info("Shutting down analysis hook notifier")
+			return
+		case notification := <-n.notifications:
+			n.post(notification)
+		}
+	}
+}
+
+// post posts the outcome to the webhook, retrying failed attempts with the backoff. The outcome is dropped once the
+// attempts are exhausted or when the webhook rejects it.
+func (n *HTTPNotifier) post(notification notification) {
+	logCtx := outcomeLog(notification.outcome).WithField("url", notification.webhook.URL)
+	body, err := json.Marshal(notification.outcome)
+	if err != nil {
+		logCtx.Errorf("Failed to marshal the analysis outcome: %v", err)
+		return
+	}
+	var lastErr error
+	err = wait.ExponentialBackoff(n.backoff, func() (bool, error) {
+		retry, err := n.send(notification.webhook, notification.outcome.ID, body)
+		if err == nil {
+			return true, nil
+		}
+		if !retry {
+			return false, err
+		}
+		lastErr = err
+		logCtx.Warnf("Failed to post the analysis outcome, retrying: %v", err)
+		return false, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		err = lastErr
+	}
+	if err != nil {
+		logCtx.Errorf("Dropping the analysis outcome after failing to post it: %v", err)
+		return
+	}
+	logCtx.Infof("Posted the analysis outcome %s", notification.outcome.Phase)
+}
+
+// send posts the body to the webhook with the ID of the outcome as the idempotency key, and returns whether a failure
+// should be retried
+func (n *HTTPNotifier) send(webhook v1alpha1.AnalysisWebhook, id string, body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	for _, header := range webhook.Headers {
+		req.Header.Set(header.Key, header.Value)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(sink.IdempotencyKeyHeader, id)
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = ioutil.ReadAll(resp.Body)
+	if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
+		return false, nil
+	}
+	err = fmt.Errorf("received status code %d", resp.StatusCode)
+	// Client errors other than rate limiting are not resolved by retrying
+	retry := resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+	return retry, err
+}
@@ -0,0 +1,209 @@
+package hook
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/argoproj/argo-rollouts/analysis/sink"
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+)
+
+// receiver is a stub webhook which responds with the queued status codes, then with 200
+type receiver struct {
+	lock     sync.Mutex
+	statuses []int
+	attempts int
+	outcomes []Outcome
+	headers  []http.Header
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.attempts++
+	if len(r.statuses) > 0 {
+		status := r.statuses[0]
+		r.statuses = r.statuses[1:]
+		w.WriteHeader(status)
+		return
+	}
+	body, _ := ioutil.ReadAll(req.Body)
+	outcome := Outcome{}
+	if err := json.Unmarshal(body, &outcome); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	r.outcomes = append(r.outcomes, outcome)
+	r.headers = append(r.headers, req.Header)
+}
+
+func newTestNotifier(statuses ...int) (*HTTPNotifier, *receiver, *httptest.Server) {
+	r := &receiver{statuses: statuses}
+	server := httptest.NewServer(r)
+	n := NewHTTPNotifier()
+	n.backoff = wait.Backoff{Duration: time.Millisecond, Factor: 1, Steps: 3}
+	return n, r, server
+}
+
+func newRun(phase v1alpha1.AnalysisPhase) *v1alpha1.AnalysisRun {
+	return &v1alpha1.AnalysisRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "guestbook-6c54544bf9-2",
+			Namespace: "default",
+			UID:       "guestbook-uid",
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(&v1alpha1.Rollout{ObjectMeta: metav1.ObjectMeta{Name: "guestbook"}}, v1alpha1.SchemeGroupVersion.WithKind("Rollout")),
+			},
+		},
+		Spec: v1alpha1.AnalysisRunSpec{
+			Metrics: []v1alpha1.Metric{{Name: "success-rate"}},
+			Hooks: &v1alpha1.AnalysisHooks{
+				OnSuccess: &v1alpha1.AnalysisWebhook{URL: "http://ci.example.com/success"},
+				OnFailure: &v1alpha1.AnalysisWebhook{URL: "http://ci.example.com/failure"},
+			},
+		},
+		Status: v1alpha1.AnalysisRunStatus{
+			Phase: phase,
+			MetricResults: []v1alpha1.MetricResult{{
+				Name:  "success-rate",
+				Phase: phase,
+				Measurements: []v1alpha1.Measurement{
+					{Phase: v1alpha1.AnalysisPhaseSuccessful, Value: "[0.99]"},
+					{Phase: phase, Value: "[0.97]"},
+				},
+			}},
+		},
+	}
+}
+
+func newOutcome(phase v1alpha1.AnalysisPhase) Outcome {
+	return Outcome{
+		ID:          "guestbook-uid",
+		Namespace:   "default",
+		Rollout:     "guestbook",
+		AnalysisRun: "guestbook-6c54544bf9-2",
+		Phase:       phase,
+		Metrics: []MetricOutcome{{
+			Name:  "success-rate",
+			Phase: phase,
+			Value: "[0.97]",
+		}},
+		Timestamp: time.Date(2020, 8, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestNewOutcome(t *testing.T) {
+	run := newRun(v1alpha1.AnalysisPhaseFailed)
+	run.Status.Message = "metric \"success-rate\" assessed Failed due to failed (1) > failureLimit (0)"
+	outcome := NewOutcome(run)
+	assert.False(t, outcome.Timestamp.IsZero())
+	expected := newOutcome(v1alpha1.AnalysisPhaseFailed)
+	expected.Message = run.Status.Message
+	expected.Timestamp = outcome.Timestamp
+	assert.Equal(t, expected, outcome)
+
+	// The rollout is omitted for AnalysisRuns which are not owned by a rollout
+	run.OwnerReferences = []metav1.OwnerReference{
+		*metav1.NewControllerRef(&v1alpha1.Experiment{ObjectMeta: metav1.ObjectMeta{Name: "experiment"}}, v1alpha1.SchemeGroupVersion.WithKind("Experiment")),
+	}
+	assert.Equal(t, "", NewOutcome(run).Rollout)
+}
+
+func TestWebhook(t *testing.T) {
+	assert.Equal(t, "http://ci.example.com/success", Webhook(newRun(v1alpha1.AnalysisPhaseSuccessful)).URL)
+	assert.Equal(t, "http://ci.example.com/failure", Webhook(newRun(v1alpha1.AnalysisPhaseFailed)).URL)
+	assert.Equal(t, "http://ci.example.com/failure", Webhook(newRun(v1alpha1.AnalysisPhaseError)).URL)
+	assert.Nil(t, Webhook(newRun(v1alpha1.AnalysisPhaseInconclusive)))
+	assert.Nil(t, Webhook(newRun(v1alpha1.AnalysisPhaseRunning)))
+
+	// terminated runs do not notify their webhooks
+	terminated := newRun(v1alpha1.AnalysisPhaseSuccessful)
+	terminated.Spec.Terminate = true
+	assert.Nil(t, Webhook(terminated))
+
+	onFailureOnly := newRun(v1alpha1.AnalysisPhaseSuccessful)
+	onFailureOnly.Spec.Hooks.OnSuccess = nil
+	assert.Nil(t, Webhook(onFailureOnly))
+
+	noHooks := newRun(v1alpha1.AnalysisPhaseSuccessful)
+	noHooks.Spec.Hooks = nil
+	assert.Nil(t, Webhook(noHooks))
+}
+
+func TestPostOutcomes(t *testing.T) {
+	for _, phase := range []v1alpha1.AnalysisPhase{v1alpha1.AnalysisPhaseSuccessful, v1alpha1.AnalysisPhaseFailed} {
+		n, r, server := newTestNotifier()
+		webhook := v1alpha1.AnalysisWebhook{
+			URL:     server.URL,
+			Headers: []v1alpha1.WebMetricHeader{{Key: "Authorization", Value: "Bearer token"}},
+		}
+		n.post(notification{webhook: webhook, outcome: newOutcome(phase)})
+		assert.Equal(t, 1, r.attempts)
+		assert.Equal(t, []Outcome{newOutcome(phase)}, r.outcomes)
+		assert.Equal(t, "Bearer token", r.headers[0].Get("Authorization"))
+		assert.Equal(t, "application/json", r.headers[0].Get("Content-Type"))
+		assert.Equal(t, "guestbook-uid", r.headers[0].Get(sink.IdempotencyKeyHeader))
+		server.Close()
+	}
+}
+
+func TestPostRetriesServerErrors(t *testing.T) {
+	n, r, server := newTestNotifier(http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	defer server.Close()
+	n.post(notification{webhook: v1alpha1.AnalysisWebhook{URL: server.URL}, outcome: newOutcome(v1alpha1.AnalysisPhaseSuccessful)})
+	assert.Equal(t, 3, r.attempts)
+	assert.Equal(t, []Outcome{newOutcome(v1alpha1.AnalysisPhaseSuccessful)}, r.outcomes)
+}
+
+func TestPostDropsOutcomeAfterRetries(t *testing.T) {
+	n, r, server := newTestNotifier(http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+	defer server.Close()
+	n.post(notification{webhook: v1alpha1.AnalysisWebhook{URL: server.URL}, outcome: newOutcome(v1alpha1.AnalysisPhaseSuccessful)})
+	assert.Equal(t, 3, r.attempts)
+	assert.Empty(t, r.outcomes)
+}
+
+func TestPostDoesNotRetryClientErrors(t *testing.T) {
+	n, r, server := newTestNotifier(http.StatusUnauthorized)
+	defer server.Close()
+	n.post(notification{webhook: v1alpha1.AnalysisWebhook{URL: server.URL}, outcome: newOutcome(v1alpha1.AnalysisPhaseFailed)})
+	assert.Equal(t, 1, r.attempts)
+	assert.Empty(t, r.outcomes)
+}
+
+func TestNotifyDropsOutcomesWhenQueueIsFull(t *testing.T) {
+	n, _, server := newTestNotifier()
+	defer server.Close()
+	n.notifications = make(chan notification, 1)
+	n.Notify(v1alpha1.AnalysisWebhook{URL: server.URL}, newOutcome(v1alpha1.AnalysisPhaseSuccessful))
+	n.Notify(v1alpha1.AnalysisWebhook{URL: server.URL}, newOutcome(v1alpha1.AnalysisPhaseSuccessful))
+	assert.Len(t, n.notifications, 1)
+}
+
+func TestRun(t *testing.T) {
+	n, r, server := newTestNotifier(http.StatusBadGateway)
+	defer server.Close()
+	stopCh := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		n.Run(stopCh)
+		close(done)
+	}()
+	n.Notify(v1alpha1.AnalysisWebhook{URL: server.URL}, newOutcome(v1alpha1.AnalysisPhaseFailed))
+	assert.Eventually(t, func() bool {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		return len(r.outcomes) == 1
+	}, time.Second, 10*time.Millisecond)
+	close(stopCh)
+	<-done
+}
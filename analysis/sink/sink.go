@@ -0,0 +1,180 @@
+package sink
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	register "github.com/argoproj/argo-rollouts/pkg/apis/rollouts"
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	logutil "github.com/argoproj/argo-rollouts/utils/log"
+)
+
+const (
+	// DefaultQueueSize is the number of records buffered by the HTTP sink before new records are dropped
+	DefaultQueueSize = 1000
+	// DefaultTimeout is the timeout of a request to the endpoint of the HTTP sink
+	DefaultTimeout = 10 * time.Second
+)
+
+// DefaultBackoff is the backoff between the attempts to post a record to the endpoint of the HTTP sink
+var DefaultBackoff = wait.Backoff{
+	Duration: time.Second,
+	Factor:   2,
+	Jitter:   0.1,
+	Steps:    5,
+}
+
+// IdempotencyKeyHeader is the header holding the ID of the record posted by the HTTP sink
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// Record is a completed measurement pushed to a sink
+type Record struct {
+	// ID identifies the measurement by the AnalysisRun, the metric and the interval window of the measurement. A
+	// measurement which is taken again for the same interval window, such as after a controller restart before the
+	// measurement was recorded in the AnalysisRun, has the same ID, which lets the receivers discard the duplicate.
+	ID          string `json:"id"`
+	Namespace   string `json:"namespace"`
+	Rollout     string `json:"rollout,omitempty"`
+	AnalysisRun string `json:"analysisRun"`
+	Metric      string `json:"metric"`
+	// IntervalWindow is the index of the interval window of the measurement, starting at 0 for the first
+	// measurement of the metric
+	IntervalWindow int32                  `json:"intervalWindow"`
+	Value          string                 `json:"value"`
+	Phase          v1alpha1.AnalysisPhase `json:"phase"`
+	Timestamp      time.Time              `json:"timestamp"`
+}
+
+// NewRecord returns the record of a completed measurement of a metric of the AnalysisRun taken for the interval window
+func NewRecord(run *v1alpha1.AnalysisRun, metricName string, intervalWindow int32, measurement v1alpha1.Measurement) Record {
+	record := Record{
+		ID:             fmt.Sprintf("%s.%s.%d", run.UID, metricName, intervalWindow),
+		Namespace:      run.Namespace,
+		AnalysisRun:    run.Name,
+		Metric:         metricName,
+		IntervalWindow: intervalWindow,
+		Value:          measurement.Value,
+		Phase:          measurement.Phase,
+	}
+	if controllerRef := metav1.GetControllerOf(run); controllerRef != nil && controllerRef.Kind == register.RolloutKind {
+		record.Rollout = controllerRef.Name
+	}
+	if measurement.FinishedAt != nil {
+		record.Timestamp = measurement.FinishedAt.Time
+	}
+	return record
+}
+
+// Sink receives the completed measurements of the AnalysisRuns
+type Sink interface {
+	// Push queues the record without blocking
+	Push(record Record)
+}
+
+// HTTPSink posts the records as JSON to an endpoint. Records are posted in the background, and failed posts are
+// retried with an exponential backoff so that an unavailable endpoint does not slow down the analysis.
+type HTTPSink struct {
+	url     string
+	client  *http.Client
+	backoff wait.Backoff
+	records chan Record
+}
+
+// NewHTTPSink returns a sink posting the records to the URL
+func NewHTTPSink(url string) *HTTPSink {
+	return &HTTPSink{
+		url:     url,
+		client:  &http.Client{Timeout: DefaultTimeout},
+		backoff: DefaultBackoff,
+		records: make(chan Record, DefaultQueueSize),
+	}
+}
+
+// Push queues the record to be posted. The record is dropped when the queue is full.
+func (s *HTTPSink) Push(record Record) {
+	select {
+	case s.records <- record:
+	default:
+		recordLog(record).Warn("Measurement sink queue is full, dropping the measurement")
+	}
+}
+
+func recordLog(record Record) *log.Entry {
+	return log.WithField(logutil.NamespaceKey, record.Namespace).WithField(logutil.AnalysisRunKey, record.AnalysisRun).WithField("metric", record.Metric)
+}
+
+// Run posts the queued records until the stop channel is closed
+func (s *HTTPSink) Run(stopCh <-chan struct{}) {
+	log.Infof("Starting measurement sink posting to %s", s.url)
+	for {
+		select {
+		case <-stopCh:
+			log.Info("Shutting down measurement sink")
+			return
+		case record := <-s.records:
+			s.post(record)
+		}
+	}
+}
+
+// post posts the record, retrying failed attempts with the backoff. The record is dropped once the attempts are
+// exhausted or when the endpoint rejects it.
+func (s *HTTPSink) post(record Record) {
+	logCtx := recordLog(record)
+	body, err := json.Marshal(record)
+	if err != nil {
+		logCtx.Errorf("Failed to marshal the measurement: %v", err)
+		return
+	}
+	var lastErr error
+	err = wait.ExponentialBackoff(s.backoff, func() (bool, error) {
+		retry, err := s.send(record.ID, body)
+		if err == nil {
+			return true, nil
+		}
+		if !retry {
+			return false, err
+		}
+		lastErr = err
+		logCtx.Warnf("Failed to post the measurement, retrying: %v", err)
+		return false, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		err = lastErr
+	}
+	if err != nil {
+		logCtx.Errorf("Dropping the measurement after failing to post it: %v", err)
+	}
+}
+
+// send posts the body with the ID of the record as the idempotency key, and returns whether a failure should be
+// retried
+func (s *HTTPSink) send(id string, body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(IdempotencyKeyHeader, id)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = ioutil.ReadAll(resp.Body)
+	if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
+		return false, nil
+	}
+	err = fmt.Errorf("received status code %d", resp.StatusCode)
+	// Client errors other than rate limiting are not resolved by retrying
+	retry := resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+	return retry, err
+}
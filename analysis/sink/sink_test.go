@@ -0,0 +1,164 @@
+package sink
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+)
+
+// receiver is a stub endpoint which responds with the queued status codes, then with 200
+type receiver struct {
+	lock     sync.Mutex
+	statuses []int
+	attempts int
+	records  []Record
+	// idempotencyKeys are the idempotency keys of the received records
+	idempotencyKeys []string
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.attempts++
+	if len(r.statuses) > 0 {
+		status := r.statuses[0]
+		r.statuses = r.statuses[1:]
+		w.WriteHeader(status)
+		return
+	}
+	body, _ := ioutil.ReadAll(req.Body)
+	record := Record{}
+	if err := json.Unmarshal(body, &record); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	r.records = append(r.records, record)
+	r.idempotencyKeys = append(r.idempotencyKeys, req.Header.Get(IdempotencyKeyHeader))
+}
+
+func newTestSink(statuses ...int) (*HTTPSink, *receiver, *httptest.Server) {
+	r := &receiver{statuses: statuses}
+	server := httptest.NewServer(r)
+	s := NewHTTPSink(server.URL)
+	s.backoff = wait.Backoff{Duration: time.Millisecond, Factor: 1, Steps: 3}
+	return s, r, server
+}
+
+func newRecord() Record {
+	return Record{
+		ID:             "guestbook-uid.success-rate.2",
+		Namespace:      "default",
+		Rollout:        "guestbook",
+		AnalysisRun:    "guestbook-6c54544bf9-2",
+		Metric:         "success-rate",
+		IntervalWindow: 2,
+		Value:          "[0.97]",
+		Phase:          v1alpha1.AnalysisPhaseSuccessful,
+		Timestamp:      time.Date(2020, 8, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestNewRecord(t *testing.T) {
+	finishedAt := metav1.NewTime(time.Date(2020, 8, 1, 12, 0, 0, 0, time.UTC))
+	run := &v1alpha1.AnalysisRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "guestbook-6c54544bf9-2",
+			Namespace: "default",
+			UID:       "guestbook-uid",
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(&v1alpha1.Rollout{ObjectMeta: metav1.ObjectMeta{Name: "guestbook"}}, v1alpha1.SchemeGroupVersion.WithKind("Rollout")),
+			},
+		},
+	}
+	measurement := v1alpha1.Measurement{
+		Phase:      v1alpha1.AnalysisPhaseSuccessful,
+		Value:      "[0.97]",
+		FinishedAt: &finishedAt,
+	}
+	assert.Equal(t, newRecord(), NewRecord(run, "success-rate", 2, measurement))
+
+	// The rollout is omitted for AnalysisRuns which are not owned by a rollout
+	run.OwnerReferences = []metav1.OwnerReference{
+		*metav1.NewControllerRef(&v1alpha1.Experiment{ObjectMeta: metav1.ObjectMeta{Name: "experiment"}}, v1alpha1.SchemeGroupVersion.WithKind("Experiment")),
+	}
+	assert.Equal(t, "", NewRecord(run, "success-rate", 2, measurement).Rollout)
+}
+
+func TestPost(t *testing.T) {
+	s, r, server := newTestSink()
+	defer server.Close()
+	s.post(newRecord())
+	assert.Equal(t, 1, r.attempts)
+	assert.Equal(t, []Record{newRecord()}, r.records)
+}
+
+func TestPostSetsIdempotencyKey(t *testing.T) {
+	s, r, server := newTestSink(http.StatusServiceUnavailable)
+	defer server.Close()
+	s.post(newRecord())
+	// a measurement taken again for the same interval window is posted with the same key
+	s.post(newRecord())
+	assert.Equal(t, []string{"guestbook-uid.success-rate.2", "guestbook-uid.success-rate.2"}, r.idempotencyKeys)
+}
+
+func TestPostRetriesServerErrors(t *testing.T) {
+	s, r, server := newTestSink(http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	defer server.Close()
+	s.post(newRecord())
+	assert.Equal(t, 3, r.attempts)
+	assert.Equal(t, []Record{newRecord()}, r.records)
+}
+
+func TestPostDropsRecordAfterRetries(t *testing.T) {
+	s, r, server := newTestSink(http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+	defer server.Close()
+	s.post(newRecord())
+	assert.Equal(t, 3, r.attempts)
+	assert.Empty(t, r.records)
+}
+
+func TestPostDoesNotRetryClientErrors(t *testing.T) {
+	s, r, server := newTestSink(http.StatusBadRequest)
+	defer server.Close()
+	s.post(newRecord())
+	assert.Equal(t, 1, r.attempts)
+	assert.Empty(t, r.records)
+}
+
+func TestPushDropsRecordsWhenQueueIsFull(t *testing.T) {
+	s, _, server := newTestSink()
+	defer server.Close()
+	s.records = make(chan Record, 1)
+	s.Push(newRecord())
+	s.Push(newRecord())
+	assert.Len(t, s.records, 1)
+}
+
+func TestRun(t *testing.T) {
+	s, r, server := newTestSink(http.StatusBadGateway)
+	defer server.Close()
+	stopCh := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		s.Run(stopCh)
+		close(done)
+	}()
+	s.Push(newRecord())
+	assert.Eventually(t, func() bool {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		return len(r.records) == 1
+	}, time.Second, 10*time.Millisecond)
+	close(stopCh)
+	<-done
+}
@@ -10,6 +10,7 @@ import (
 	"k8s.io/klog"
 
 	"github.com/argoproj/argo-rollouts/pkg/kubectl-argo-rollouts/cmd"
+	"github.com/argoproj/argo-rollouts/pkg/kubectl-argo-rollouts/cmd/status"
 	"github.com/argoproj/argo-rollouts/pkg/kubectl-argo-rollouts/options"
 )
 
@@ -19,6 +20,9 @@ func main() {
 	o := options.NewArgoRolloutsOptions(streams)
 	root := cmd.NewCmdArgoRollouts(o)
 	if err := root.Execute(); err != nil {
+		if exitErr, ok := err.(*status.ExitError); ok {
+			os.Exit(exitErr.Code)
+		}
 		os.Exit(1)
 	}
 }
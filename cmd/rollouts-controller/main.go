@@ -21,15 +21,20 @@ import (
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/klog"
 
+	"github.com/argoproj/argo-rollouts/analysis/hook"
+	"github.com/argoproj/argo-rollouts/analysis/sink"
 	"github.com/argoproj/argo-rollouts/controller"
 	"github.com/argoproj/argo-rollouts/controller/metrics"
 	jobprovider "github.com/argoproj/argo-rollouts/metricproviders/job"
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
 	clientset "github.com/argoproj/argo-rollouts/pkg/client/clientset/versioned"
 	"github.com/argoproj/argo-rollouts/pkg/signals"
 	controllerutil "github.com/argoproj/argo-rollouts/utils/controller"
 	istioutil "github.com/argoproj/argo-rollouts/utils/istio"
 	kubeclientmetrics "github.com/argoproj/argo-rollouts/utils/kubeclientmetrics"
+	metricutil "github.com/argoproj/argo-rollouts/utils/metric"
 	"github.com/argoproj/argo-rollouts/utils/tolerantinformer"
+	"github.com/argoproj/argo-rollouts/webhook"
 )
 
 const (
@@ -37,25 +42,35 @@ const (
 	cliName                    = "argo-rollouts"
 	defaultIstioVersion        = "v1alpha3"
 	defaultTrafficSplitVersion = "v1alpha1"
+	defaultTraefikVersion      = "v1alpha1"
 )
 
 func newCommand() *cobra.Command {
 	var (
-		clientConfig        clientcmd.ClientConfig
-		rolloutResyncPeriod int64
-		logLevel            string
-		glogLevel           int
-		metricsPort         int
-		instanceID          string
-		rolloutThreads      int
-		experimentThreads   int
-		analysisThreads     int
-		serviceThreads      int
-		ingressThreads      int
-		istioVersion        string
-		trafficSplitVersion string
-		albIngressClasses   []string
-		nginxIngressClasses []string
+		clientConfig          clientcmd.ClientConfig
+		rolloutResyncPeriod   int64
+		logLevel              string
+		glogLevel             int
+		metricsPort           int
+		instanceID            string
+		rolloutThreads        int
+		experimentThreads     int
+		analysisThreads       int
+		serviceThreads        int
+		ingressThreads        int
+		istioVersion          string
+		trafficSplitVersion   string
+		traefikVersion        string
+		albIngressClasses     []string
+		nginxIngressClasses   []string
+		webhookPort           int
+		webhookCertFile       string
+		webhookKeyFile        string
+		maxMeasurementsPerRun int
+		measurementSinkURL    string
+		allowedProviders      []string
+		recordResponseBodies  bool
+		maxResponseBytes      int64
 	)
 	var command = cobra.Command{
 		Use:   cliName,
@@ -107,6 +122,14 @@ func newCommand() *cobra.Command {
 				kubeinformers.WithTweakListOptions(func(options *metav1.ListOptions) {
 					options.LabelSelector = jobprovider.AnalysisRunUIDLabelKey
 				}))
+			// The pods informer only watches the pods of the ReplicaSets of the rollouts
+			podInformerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(
+				kubeClient,
+				resyncDuration,
+				kubeinformers.WithNamespace(namespace),
+				kubeinformers.WithTweakListOptions(func(options *metav1.ListOptions) {
+					options.LabelSelector = v1alpha1.DefaultRolloutUniqueLabelKey
+				}))
 			istioGVR := istioutil.GetIstioGVR(istioVersion)
 			// We need three dynamic informer factories:
 			// 1. The first is the dynamic informer for rollouts, analysisruns, analysistemplates, experiments
@@ -119,8 +142,17 @@ func newCommand() *cobra.Command {
 			// 3. We finally need an istio dynamic informer factory which uses different resync
 			// period and does not use a tweakListFunc.
 			istioDynamicInformerFactory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(dynamicClient, 0, namespace, nil)
+			var measurementSink sink.Sink
+			if measurementSinkURL != "" {
+				httpSink := sink.NewHTTPSink(measurementSinkURL)
+				go httpSink.Run(stopCh)
+				measurementSink = httpSink
+			}
+			hookNotifier := hook.NewHTTPNotifier()
+			go hookNotifier.Run(stopCh)
 			cm := controller.NewManager(
 				namespace,
+				config,
 				kubeClient,
 				rolloutClient,
 				dynamicClient,
@@ -130,6 +162,7 @@ func newCommand() *cobra.Command {
 				kubeInformerFactory.Extensions().V1beta1().Ingresses(),
 				kubeInformerFactory.Core().V1().Secrets(),
 				jobInformerFactory.Batch().V1().Jobs(),
+				podInformerFactory.Core().V1().Pods(),
 				tolerantinformer.NewTolerantRolloutInformer(dynamicInformerFactory),
 				tolerantinformer.NewTolerantExperimentInformer(dynamicInformerFactory),
 				tolerantinformer.NewTolerantAnalysisRunInformer(dynamicInformerFactory),
@@ -142,20 +175,43 @@ func newCommand() *cobra.Command {
 				k8sRequestProvider,
 				istioVersion,
 				trafficSplitVersion,
+				traefikVersion,
 				nginxIngressClasses,
-				albIngressClasses)
+				albIngressClasses,
+				maxMeasurementsPerRun,
+				measurementSink,
+				hookNotifier,
+				allowedProviders,
+				recordResponseBodies,
+				maxResponseBytes)
 			// notice that there is no need to run Start methods in a separate goroutine. (i.e. go kubeInformerFactory.Start(stopCh)
 			// Start method is non-blocking and runs all registered informers in a dedicated goroutine.
 			dynamicInformerFactory.Start(stopCh)
 			clusterDynamicInformerFactory.Start(stopCh)
 			kubeInformerFactory.Start(stopCh)
 			jobInformerFactory.Start(stopCh)
+			podInformerFactory.Start(stopCh)
 
 			// Check if Istio installed on cluster before starting dynamicInformerFactory
 			if istioutil.DoesIstioExist(dynamicClient, namespace, istioVersion) {
 				istioDynamicInformerFactory.Start(stopCh)
 			}
 
+			if webhookPort > 0 {
+				webhookServer := webhook.NewWebhookServer(webhook.ServerConfig{
+					Addr:              fmt.Sprintf("0.0.0.0:%d", webhookPort),
+					ArgoprojClientset: rolloutClient,
+					AllowedProviders:  allowedProviders,
+				})
+				go func() {
+					log.Infof("Starting Webhook Server at %s", webhookServer.Addr)
+					err := webhookServer.ListenAndServeTLS(webhookCertFile, webhookKeyFile)
+					if err != nil {
+						log.Fatalf("Error running webhook server: %s", err.Error())
+					}
+				}()
+			}
+
 			if err = cm.Run(rolloutThreads, serviceThreads, ingressThreads, experimentThreads, analysisThreads, stopCh); err != nil {
 				log.Fatalf("Error running controller: %s", err.Error())
 			}
@@ -179,8 +235,17 @@ func newCommand() *cobra.Command {
 	command.Flags().IntVar(&ingressThreads, "ingress-threads", controller.DefaultIngressThreads, "Set the number of worker threads for the Ingress controller")
 	command.Flags().StringVar(&istioVersion, "istio-api-version", defaultIstioVersion, "Set the default Istio apiVersion that controller should look when manipulating VirtualServices.")
 	command.Flags().StringVar(&trafficSplitVersion, "traffic-split-api-version", defaultTrafficSplitVersion, "Set the default TrafficSplit apiVersion that controller uses when creating TrafficSplits.")
+	command.Flags().StringVar(&traefikVersion, "traefik-api-version", defaultTraefikVersion, "Set the default Traefik apiVersion that controller uses when updating IngressRoutes.")
 	command.Flags().StringArrayVar(&albIngressClasses, "alb-ingress-classes", defaultALBIngressClass, "Defines all the ingress class annotations that the alb ingress controller operates on. Defaults to alb")
 	command.Flags().StringArrayVar(&nginxIngressClasses, "nginx-ingress-classes", defaultNGINXIngressClass, "Defines all the ingress class annotations that the nginx ingress controller operates on. Defaults to nginx")
+	command.Flags().IntVar(&maxMeasurementsPerRun, "max-measurements-per-run", 0, "Set the maximum number of measurements retained in the status of an AnalysisRun across all its metrics, keeping the most recent and failed measurements. Unlimited when 0")
+	command.Flags().StringVar(&measurementSinkURL, "measurement-sink-url", "", "Set the URL of an endpoint receiving every completed measurement as JSON, such as a gateway to a time series database. Disabled when empty")
+	command.Flags().StringSliceVar(&allowedProviders, "analysis-provider-allowlist", nil, "Set the metric provider types which analyses may use, such as Prometheus,WebMetric. AnalysisRuns using other providers are errored, and rejected by the validating admission webhook. All the providers are allowed when empty")
+	command.Flags().BoolVar(&recordResponseBodies, "record-provider-response-bodies", false, "Record the response bodies of failed metric provider calls in the measurements for debugging, truncated and with the secrets redacted. Supported by the WebMetric, Decision, Elasticsearch, Alertmanager, Loki, Pingdom, Datadog, Incident and CommitStatus providers")
+	command.Flags().Int64Var(&maxResponseBytes, "max-provider-response-bytes", metricutil.DefaultMaxResponseBytes, "Set the maximum size of the response bodies read by the HTTP based metric providers, above which the measurements error. The metrics of the WebMetric, Decision, Elasticsearch, Alertmanager, Loki, Pingdom, Datadog, Incident and CommitStatus providers may override it with maxResponseBytes. Unlimited when 0")
+	command.Flags().IntVar(&webhookPort, "webhook-port", 0, "Set the port the validating admission webhook should be exposed over. Disabled when 0")
+	command.Flags().StringVar(&webhookCertFile, "webhook-tls-cert-file", "/tmp/k8s-webhook-server/serving-certs/tls.crt", "Path to the TLS certificate of the validating admission webhook")
+	command.Flags().StringVar(&webhookKeyFile, "webhook-tls-key-file", "/tmp/k8s-webhook-server/serving-certs/tls.key", "Path to the TLS private key of the validating admission webhook")
 	return &command
 }
 
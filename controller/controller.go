@@ -21,11 +21,14 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 
 	"github.com/argoproj/argo-rollouts/analysis"
+	"github.com/argoproj/argo-rollouts/analysis/hook"
+	"github.com/argoproj/argo-rollouts/analysis/sink"
 	"github.com/argoproj/argo-rollouts/controller/metrics"
 	"github.com/argoproj/argo-rollouts/experiments"
 	"github.com/argoproj/argo-rollouts/ingress"
@@ -33,6 +36,8 @@ import (
 	rolloutscheme "github.com/argoproj/argo-rollouts/pkg/client/clientset/versioned/scheme"
 	informers "github.com/argoproj/argo-rollouts/pkg/client/informers/externalversions/rollouts/v1alpha1"
 	"github.com/argoproj/argo-rollouts/rollout"
+	"github.com/argoproj/argo-rollouts/rollout/trafficrouting/plugin"
+	"github.com/argoproj/argo-rollouts/rollout/trafficrouting/plugin/configmap"
 	"github.com/argoproj/argo-rollouts/service"
 )
 
@@ -79,6 +84,7 @@ type Manager struct {
 	serviceSynced                 cache.InformerSynced
 	ingressSynced                 cache.InformerSynced
 	jobSynced                     cache.InformerSynced
+	podSynced                     cache.InformerSynced
 	replicasSetSynced             cache.InformerSynced
 	istioVirtualServiceSynced     cache.InformerSynced
 
@@ -90,6 +96,7 @@ type Manager struct {
 
 	defaultIstioVersion        string
 	defaultTrafficSplitVersion string
+	defaultTraefikVersion      string
 
 	dynamicClientSet dynamic.Interface
 
@@ -99,6 +106,7 @@ type Manager struct {
 // NewManager returns a new manager to manage all the controllers
 func NewManager(
 	namespace string,
+	kubeConfig *rest.Config,
 	kubeclientset kubernetes.Interface,
 	argoprojclientset clientset.Interface,
 	dynamicclientset dynamic.Interface,
@@ -108,6 +116,7 @@ func NewManager(
 	ingressesInformer extensionsinformers.IngressInformer,
 	secretInformer coreinformers.SecretInformer,
 	jobInformer batchinformers.JobInformer,
+	podInformer coreinformers.PodInformer,
 	rolloutsInformer informers.RolloutInformer,
 	experimentsInformer informers.ExperimentInformer,
 	analysisRunInformer informers.AnalysisRunInformer,
@@ -120,8 +129,15 @@ func NewManager(
 	k8sRequestProvider *metrics.K8sRequestsCountProvider,
 	defaultIstioVersion string,
 	defaultTrafficSplitVersion string,
+	defaultTraefikVersion string,
 	nginxIngressClasses []string,
 	albIngressClasses []string,
+	maxMeasurementsPerRun int,
+	measurementSink sink.Sink,
+	hookNotifier hook.Notifier,
+	allowedProviders []string,
+	recordProviderResponseBodies bool,
+	maxProviderResponseBytes int64,
 ) *Manager {
 
 	utilruntime.Must(rolloutscheme.AddToScheme(scheme.Scheme))
@@ -162,6 +178,7 @@ func NewManager(
 		IstioVirtualServiceInformer:     istioVirtualServiceInformer,
 		ReplicaSetInformer:              replicaSetInformer,
 		ServicesInformer:                servicesInformer,
+		PodInformer:                     podInformer,
 		IngressInformer:                 ingressesInformer,
 		RolloutsInformer:                rolloutsInformer,
 		ResyncPeriod:                    resyncPeriod,
@@ -172,6 +189,8 @@ func NewManager(
 		Recorder:                        recorder,
 		DefaultIstioVersion:             defaultIstioVersion,
 		DefaultTrafficSplitVersion:      defaultTrafficSplitVersion,
+		DefaultTraefikVersion:           defaultTraefikVersion,
+		TrafficRouterPlugins:            newTrafficRouterPlugins(kubeclientset),
 	})
 
 	experimentController := experiments.NewController(experiments.ControllerConfig{
@@ -190,15 +209,22 @@ func NewManager(
 	})
 
 	analysisController := analysis.NewController(analysis.ControllerConfig{
-		KubeClientSet:        kubeclientset,
-		ArgoProjClientset:    argoprojclientset,
-		AnalysisRunInformer:  analysisRunInformer,
-		SecretInformer:       secretInformer,
-		JobInformer:          jobInformer,
-		ResyncPeriod:         resyncPeriod,
-		AnalysisRunWorkQueue: analysisRunWorkqueue,
-		MetricsServer:        metricsServer,
-		Recorder:             recorder,
+		KubeClientSet:                kubeclientset,
+		KubeConfig:                   kubeConfig,
+		ArgoProjClientset:            argoprojclientset,
+		AnalysisRunInformer:          analysisRunInformer,
+		SecretInformer:               secretInformer,
+		JobInformer:                  jobInformer,
+		ResyncPeriod:                 resyncPeriod,
+		AnalysisRunWorkQueue:         analysisRunWorkqueue,
+		MetricsServer:                metricsServer,
+		Recorder:                     recorder,
+		MaxMeasurementsPerRun:        maxMeasurementsPerRun,
+		MeasurementSink:              measurementSink,
+		HookNotifier:                 hookNotifier,
+		AllowedProviders:             allowedProviders,
+		RecordProviderResponseBodies: recordProviderResponseBodies,
+		MaxProviderResponseBytes:     maxProviderResponseBytes,
 	})
 
 	serviceController := service.NewController(service.ControllerConfig{
@@ -232,6 +258,7 @@ func NewManager(
 		ingressSynced:                 ingressesInformer.Informer().HasSynced,
 		secretSynced:                  secretInformer.Informer().HasSynced,
 		jobSynced:                     jobInformer.Informer().HasSynced,
+		podSynced:                     podInformer.Informer().HasSynced,
 		experimentSynced:              experimentsInformer.Informer().HasSynced,
 		analysisRunSynced:             analysisRunInformer.Informer().HasSynced,
 		analysisTemplateSynced:        analysisTemplateInformer.Informer().HasSynced,
@@ -250,6 +277,7 @@ func NewManager(
 		analysisController:            analysisController,
 		defaultIstioVersion:           defaultIstioVersion,
 		defaultTrafficSplitVersion:    defaultTrafficSplitVersion,
+		defaultTraefikVersion:         defaultTraefikVersion,
 		dynamicClientSet:              dynamicclientset,
 		namespace:                     namespace,
 	}
@@ -257,6 +285,14 @@ func NewManager(
 	return cm
 }
 
+// newTrafficRouterPlugins returns the routers of the traffic router plugins built into the controller. The routers of
+// custom in-process plugins are registered here under the name of their plugin.
+func newTrafficRouterPlugins(kubeclientset kubernetes.Interface) plugin.Routers {
+	return plugin.Routers{
+		configmap.PluginName: configmap.NewRouter(kubeclientset),
+	}
+}
+
 // Run will set up the event handlers for types we are interested in, as well
 // as syncing informer caches and starting workers. It will block until stopCh
 // is closed, at which point it will shutdown the workqueue and wait for
@@ -271,7 +307,7 @@ func (c *Manager) Run(rolloutThreadiness, serviceThreadiness, ingressThreadiness
 	defer c.analysisRunWorkqueue.ShutDown()
 	// Wait for the caches to be synced before starting workers
 	log.Info("Waiting for controller's informer caches to sync")
-	if ok := cache.WaitForCacheSync(stopCh, c.serviceSynced, c.ingressSynced, c.secretSynced, c.jobSynced, c.rolloutSynced, c.experimentSynced, c.analysisRunSynced, c.analysisTemplateSynced, c.clusterAnalysisTemplateSynced, c.replicasSetSynced); !ok {
+	if ok := cache.WaitForCacheSync(stopCh, c.serviceSynced, c.ingressSynced, c.secretSynced, c.jobSynced, c.podSynced, c.rolloutSynced, c.experimentSynced, c.analysisRunSynced, c.analysisTemplateSynced, c.clusterAnalysisTemplateSynced, c.replicasSetSynced); !ok {
 		return fmt.Errorf("failed to wait for caches to sync")
 	}
 	// Check if Istio exists
@@ -3,12 +3,16 @@ package metrics
 import (
 	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 
 	"github.com/argoproj/argo-rollouts/metricproviders"
+	register "github.com/argoproj/argo-rollouts/pkg/apis/rollouts"
 	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
 	rolloutlister "github.com/argoproj/argo-rollouts/pkg/client/listers/rollouts/v1alpha1"
 	"github.com/argoproj/argo-rollouts/utils/analysis"
+	"github.com/argoproj/argo-rollouts/utils/annotations"
+	"github.com/argoproj/argo-rollouts/utils/evaluate"
 )
 
 var (
@@ -45,6 +49,22 @@ var (
 		descAnalysisRunMetricPhase,
 		nil,
 	)
+
+	descAnalysisRunRevisionLabels = append(descDefaultLabels, "rollout", "revision")
+
+	descAnalysisRunRevisionPhase = prometheus.NewDesc(
+		"analysis_run_revision_phase",
+		"Information on the state of the Analysis Run of a revision of a rollout",
+		append(descAnalysisRunRevisionLabels, "phase"),
+		nil,
+	)
+
+	descAnalysisRunRevisionMetricValue = prometheus.NewDesc(
+		"analysis_run_revision_metric_value",
+		"The value of the latest measurement of a metric of the Analysis Run of a revision of a rollout",
+		append(descAnalysisRunRevisionLabels, "metric"),
+		nil,
+	)
 )
 
 type analysisRunCollector struct {
@@ -109,4 +129,37 @@ func collectAnalysisRuns(ch chan<- prometheus.Metric, ar *v1alpha1.AnalysisRun)
 		addGauge(descMetricPhase, boolFloat64(calculatedPhase == v1alpha1.AnalysisPhaseRunning), metric.Name, metricType, string(v1alpha1.AnalysisPhaseRunning))
 		addGauge(descMetricPhase, boolFloat64(calculatedPhase == v1alpha1.AnalysisPhaseInconclusive), metric.Name, metricType, string(v1alpha1.AnalysisPhaseInconclusive))
 	}
+	collectAnalysisRunRevision(ch, ar)
+}
+
+// collectAnalysisRunRevision collects the phase and the latest metric values of an AnalysisRun created by a rollout,
+// labeled by the rollout and the revision of the rollout the run analyzes
+func collectAnalysisRunRevision(ch chan<- prometheus.Metric, ar *v1alpha1.AnalysisRun) {
+	controllerRef := metav1.GetControllerOf(ar)
+	revision := ar.Annotations[annotations.RevisionAnnotation]
+	if controllerRef == nil || controllerRef.Kind != register.RolloutKind || revision == "" {
+		return
+	}
+	addGauge := func(desc *prometheus.Desc, v float64, lv ...string) {
+		lv = append([]string{ar.Namespace, ar.Name, controllerRef.Name, revision}, lv...)
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, lv...)
+	}
+
+	calculatedPhase := ar.Status.Phase
+	addGauge(descAnalysisRunRevisionPhase, boolFloat64(calculatedPhase == v1alpha1.AnalysisPhasePending || calculatedPhase == ""), string(v1alpha1.AnalysisPhasePending))
+	addGauge(descAnalysisRunRevisionPhase, boolFloat64(calculatedPhase == v1alpha1.AnalysisPhaseError), string(v1alpha1.AnalysisPhaseError))
+	addGauge(descAnalysisRunRevisionPhase, boolFloat64(calculatedPhase == v1alpha1.AnalysisPhaseFailed), string(v1alpha1.AnalysisPhaseFailed))
+	addGauge(descAnalysisRunRevisionPhase, boolFloat64(calculatedPhase == v1alpha1.AnalysisPhaseSuccessful), string(v1alpha1.AnalysisPhaseSuccessful))
+	addGauge(descAnalysisRunRevisionPhase, boolFloat64(calculatedPhase == v1alpha1.AnalysisPhaseRunning), string(v1alpha1.AnalysisPhaseRunning))
+	addGauge(descAnalysisRunRevisionPhase, boolFloat64(calculatedPhase == v1alpha1.AnalysisPhaseInconclusive), string(v1alpha1.AnalysisPhaseInconclusive))
+	for _, metric := range ar.Spec.Metrics {
+		measurement := analysis.LastMeasurement(ar, metric.Name)
+		if measurement == nil {
+			continue
+		}
+		// Only the values which are a single number are exposed, e.g. not the result of a job
+		if value, err := evaluate.ValueAsFloat(measurement.Value); err == nil {
+			addGauge(descAnalysisRunRevisionMetricValue, value, metric.Name)
+		}
+	}
 }
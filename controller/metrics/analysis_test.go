@@ -75,6 +75,51 @@ analysis_run_phase{name="http-benchmark-test-tr8rn",namespace="jesse-test",phase
 
 `
 
+const fakeRolloutAnalysisRun = `
+apiVersion: argoproj.io/v1alpha1
+kind: AnalysisRun
+metadata:
+  name: guestbook-6c54544bf9-2-1
+  namespace: default
+  annotations:
+    rollout.argoproj.io/revision: "2"
+  ownerReferences:
+  - apiVersion: argoproj.io/v1alpha1
+    kind: Rollout
+    name: guestbook
+    uid: 7c5e8f2a-3a4b-4c9d-8e1f-0a2b3c4d5e6f
+    controller: true
+spec:
+  metrics:
+  - name: success-rate
+    provider:
+      prometheus:
+        address: http://prometheus.example.com:9090
+        query: sum(rate(http_requests_total{code!~"5.*"}[5m])) / sum(rate(http_requests_total[5m]))
+  - name: job
+    provider:
+      job:
+        spec:
+          template:
+            spec:
+              containers: []
+status:
+  phase: Running
+  metricResults:
+  - name: success-rate
+    phase: Running
+    measurements:
+    - phase: Successful
+      value: "[0.99]"
+      startedAt: "2020-03-16T20:02:14Z"
+      finishedAt: "2020-03-16T20:02:15Z"
+  - name: job
+    phase: Running
+    measurements:
+    - phase: Running
+      startedAt: "2020-03-16T20:02:14Z"
+`
+
 func newFakeAnalysisRun(fakeAnalysisRun string) *v1alpha1.AnalysisRun {
 	var ar v1alpha1.AnalysisRun
 	err := yaml.Unmarshal([]byte(fakeAnalysisRun), &ar)
@@ -97,6 +142,38 @@ func TestCollectAnalysisRuns(t *testing.T) {
 	}
 }
 
+func TestCollectAnalysisRunRevision(t *testing.T) {
+	run := newFakeAnalysisRun(fakeRolloutAnalysisRun)
+	collect := func(expectedResponse string) {
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(NewAnalysisRunCollector(fakeAnalysisRunLister{analysisRuns: []*v1alpha1.AnalysisRun{run}}))
+		mux := http.NewServeMux()
+		mux.Handle(MetricsPath, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+		testHttpResponse(t, mux, expectedResponse)
+	}
+
+	// the job metric has no numeric value
+	collect(`# HELP analysis_run_revision_metric_value The value of the latest measurement of a metric of the Analysis Run of a revision of a rollout
+# TYPE analysis_run_revision_metric_value gauge
+analysis_run_revision_metric_value{metric="success-rate",name="guestbook-6c54544bf9-2-1",namespace="default",revision="2",rollout="guestbook"} 0.99
+# HELP analysis_run_revision_phase Information on the state of the Analysis Run of a revision of a rollout
+# TYPE analysis_run_revision_phase gauge
+analysis_run_revision_phase{name="guestbook-6c54544bf9-2-1",namespace="default",phase="Running",revision="2",rollout="guestbook"} 1
+analysis_run_revision_phase{name="guestbook-6c54544bf9-2-1",namespace="default",phase="Successful",revision="2",rollout="guestbook"} 0`)
+
+	// the gauges follow the latest measurement and the completion of the run
+	run.Status.MetricResults[0].Measurements = append(run.Status.MetricResults[0].Measurements, v1alpha1.Measurement{
+		Phase: v1alpha1.AnalysisPhaseSuccessful,
+		Value: "[0.97]",
+	})
+	run.Status.MetricResults[0].Phase = v1alpha1.AnalysisPhaseSuccessful
+	run.Status.MetricResults[1].Phase = v1alpha1.AnalysisPhaseSuccessful
+	run.Status.Phase = v1alpha1.AnalysisPhaseSuccessful
+	collect(`analysis_run_revision_metric_value{metric="success-rate",name="guestbook-6c54544bf9-2-1",namespace="default",revision="2",rollout="guestbook"} 0.97
+analysis_run_revision_phase{name="guestbook-6c54544bf9-2-1",namespace="default",phase="Running",revision="2",rollout="guestbook"} 0
+analysis_run_revision_phase{name="guestbook-6c54544bf9-2-1",namespace="default",phase="Successful",revision="2",rollout="guestbook"} 1`)
+}
+
 func TestCollectAnalysisRunsListFails(t *testing.T) {
 	buf := bytes.NewBufferString("")
 	logrus.SetOutput(buf)
@@ -29,6 +29,8 @@ type MetricsServer struct {
 	errorAnalysisRunCounter       *prometheus.CounterVec
 
 	k8sRequestsCounter *K8sRequestsCountProvider
+
+	providerLatency *ProviderLatency
 }
 
 const (
@@ -123,6 +125,8 @@ func NewMetricsServer(cfg ServerConfig) *MetricsServer {
 		// contains process, golang and controller workqueues metrics
 		registry.DefaultGatherer,
 	}, promhttp.HandlerOpts{}))
+	providerLatency := NewProviderLatency()
+	mux.Handle(ProviderLatencyPath, providerLatency)
 	return &MetricsServer{
 		Server: &http.Server{
 			Addr:    cfg.Addr,
@@ -138,6 +142,8 @@ func NewMetricsServer(cfg ServerConfig) *MetricsServer {
 		errorAnalysisRunCounter:       errorAnalysisRunCounter,
 
 		k8sRequestsCounter: cfg.K8SRequestProvider,
+
+		providerLatency: providerLatency,
 	}
 }
 
@@ -156,6 +162,11 @@ func (m *MetricsServer) IncAnalysisRunReconcile(ar *v1alpha1.AnalysisRun, durati
 	m.reconcileAnalysisRunHistogram.WithLabelValues(ar.Namespace, ar.Name).Observe(duration.Seconds())
 }
 
+// ObserveProviderLatency records the latency of a measurement taken by a metric provider type
+func (m *MetricsServer) ObserveProviderLatency(providerType string, duration time.Duration) {
+	m.providerLatency.Observe(providerType, duration)
+}
+
 // IncError increments the reconcile counter for an rollout
 func (m *MetricsServer) IncError(namespace, name string, kind string) {
 	switch kind {
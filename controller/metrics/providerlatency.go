@@ -0,0 +1,138 @@
+package metrics
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// ProviderLatencyPath is the endpoint summarizing the latency of the measurements of each metric provider type
+	ProviderLatencyPath = "/debug/provider-latency"
+)
+
+// providerLatencyBuckets are the upper bounds, in seconds, of the buckets of the latency histograms
+var providerLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// latencyHistogram counts the latencies of a provider type in the providerLatencyBuckets, and in a last bucket for the
+// latencies above the last bound
+type latencyHistogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+	max    float64
+}
+
+func newLatencyHistogram() *latencyHistogram {
+	return &latencyHistogram{
+		counts: make([]uint64, len(providerLatencyBuckets)+1),
+	}
+}
+
+func (h *latencyHistogram) observe(seconds float64) {
+	i := sort.SearchFloat64s(providerLatencyBuckets, seconds)
+	h.counts[i]++
+	h.count++
+	h.sum += seconds
+	h.max = math.Max(h.max, seconds)
+}
+
+// quantile estimates the quantile of the latencies by interpolating linearly within the bucket holding it, like the
+// histogram_quantile function of Prometheus. The estimate is capped to the maximum latency observed.
+func (h *latencyHistogram) quantile(q float64) float64 {
+	if h.count == 0 {
+		return 0
+	}
+	rank := q * float64(h.count)
+	var cumulative uint64
+	for i, count := range h.counts {
+		if count == 0 || float64(cumulative+count) < rank {
+			cumulative += count
+			continue
+		}
+		if i == len(providerLatencyBuckets) {
+			return h.max
+		}
+		lower := 0.0
+		if i > 0 {
+			lower = providerLatencyBuckets[i-1]
+		}
+		upper := providerLatencyBuckets[i]
+		estimate := lower + (upper-lower)*(rank-float64(cumulative))/float64(count)
+		return math.Min(estimate, h.max)
+	}
+	return h.max
+}
+
+// ProviderLatencySummary summarizes the latency, in seconds, of the measurements of a metric provider type
+type ProviderLatencySummary struct {
+	Provider string  `json:"provider"`
+	Count    uint64  `json:"count"`
+	Mean     float64 `json:"mean"`
+	P50      float64 `json:"p50"`
+	P95      float64 `json:"p95"`
+	P99      float64 `json:"p99"`
+	Max      float64 `json:"max"`
+}
+
+// ProviderLatency aggregates the latency of the measurements taken by the metric providers across all the
+// AnalysisRuns in a histogram per provider type, and serves their summary on demand
+type ProviderLatency struct {
+	lock       sync.Mutex
+	histograms map[string]*latencyHistogram
+}
+
+// NewProviderLatency returns an empty ProviderLatency
+func NewProviderLatency() *ProviderLatency {
+	return &ProviderLatency{
+		histograms: map[string]*latencyHistogram{},
+	}
+}
+
+// Observe records the latency of a measurement taken by the provider type
+func (p *ProviderLatency) Observe(providerType string, duration time.Duration) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	h, ok := p.histograms[providerType]
+	if !ok {
+		h = newLatencyHistogram()
+		p.histograms[providerType] = h
+	}
+	h.observe(duration.Seconds())
+}
+
+// Summary returns the latency summary of each provider type, the slowest first by their p99
+func (p *ProviderLatency) Summary() []ProviderLatencySummary {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	summaries := make([]ProviderLatencySummary, 0, len(p.histograms))
+	for providerType, h := range p.histograms {
+		summaries = append(summaries, ProviderLatencySummary{
+			Provider: providerType,
+			Count:    h.count,
+			Mean:     h.sum / float64(h.count),
+			P50:      h.quantile(0.5),
+			P95:      h.quantile(0.95),
+			P99:      h.quantile(0.99),
+			Max:      h.max,
+		})
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].P99 != summaries[j].P99 {
+			return summaries[i].P99 > summaries[j].P99
+		}
+		return summaries[i].Provider < summaries[j].Provider
+	})
+	return summaries
+}
+
+// ServeHTTP writes the latency summary of the provider types as JSON
+func (p *ProviderLatency) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(p.Summary()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProviderLatencyQuantiles(t *testing.T) {
+	p := NewProviderLatency()
+	// 100 measurements, uniformly within the (0.1s, 0.25s] bucket except for a slow one
+	for i := 1; i <= 99; i++ {
+		p.Observe("Prometheus", 200*time.Millisecond)
+	}
+	p.Observe("Prometheus", 20*time.Second)
+
+	summaries := p.Summary()
+	assert.Len(t, summaries, 1)
+	summary := summaries[0]
+	assert.Equal(t, "Prometheus", summary.Provider)
+	assert.Equal(t, uint64(100), summary.Count)
+	assert.InDelta(t, 0.398, summary.Mean, 0.0001)
+	// The rank of the p50 is 50 of the 99 measurements in the (0.1s, 0.25s] bucket
+	assert.InDelta(t, 0.1+0.15*50/99, summary.P50, 0.0001)
+	assert.InDelta(t, 0.1+0.15*95/99, summary.P95, 0.0001)
+	assert.InDelta(t, 0.25, summary.P99, 0.0001)
+	assert.Equal(t, 20.0, summary.Max)
+}
+
+func TestProviderLatencyQuantileCappedToMax(t *testing.T) {
+	p := NewProviderLatency()
+	p.Observe("WebMetric", 60*time.Millisecond)
+	p.Observe("Job", 2*time.Minute)
+
+	summaries := p.Summary()
+	assert.Len(t, summaries, 2)
+	// The estimate within the (0.05s, 0.1s] bucket does not exceed the latency observed
+	assert.Equal(t, "WebMetric", summaries[1].Provider)
+	assert.InDelta(t, 0.06, summaries[1].P99, 0.0001)
+	// The latencies above the last bucket are estimated by the maximum
+	assert.Equal(t, "Job", summaries[0].Provider)
+	assert.Equal(t, 120.0, summaries[0].P50)
+}
+
+func TestProviderLatencySlowestFirst(t *testing.T) {
+	p := NewProviderLatency()
+	p.Observe("Prometheus", 30*time.Millisecond)
+	p.Observe("Kayenta", 8*time.Second)
+	p.Observe("Datadog", 700*time.Millisecond)
+	p.Observe("Wavefront", 30*time.Millisecond)
+
+	providers := []string{}
+	for _, summary := range p.Summary() {
+		providers = append(providers, summary.Provider)
+	}
+	assert.Equal(t, []string{"Kayenta", "Datadog", "Prometheus", "Wavefront"}, providers)
+}
+
+func TestProviderLatencyEndpoint(t *testing.T) {
+	metricsServ := NewMetricsServer(ServerConfig{
+		RolloutLister:      fakeRolloutLister{},
+		ExperimentLister:   fakeExperimentLister{},
+		AnalysisRunLister:  fakeAnalysisRunLister{},
+		K8SRequestProvider: &K8sRequestsCountProvider{},
+	})
+	metricsServ.ObserveProviderLatency("Prometheus", 40*time.Millisecond)
+
+	req, err := http.NewRequest("GET", ProviderLatencyPath, nil)
+	assert.NoError(t, err)
+	rr := httptest.NewRecorder()
+	metricsServ.Handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	var summaries []ProviderLatencySummary
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &summaries))
+	assert.Len(t, summaries, 1)
+	assert.Equal(t, "Prometheus", summaries[0].Provider)
+	assert.Equal(t, uint64(1), summaries[0].Count)
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"k8s.io/utils/pointer"
 )
 
 const (
@@ -62,6 +63,41 @@ rollout_info_replicas_available{name="guestbook-bluegreen",namespace="default",s
 # TYPE rollout_info_replicas_unavailable gauge
 rollout_info_replicas_unavailable{name="guestbook-bluegreen",namespace="default",strategy="blueGreen"} 0`
 
+const fakeCanaryRollout = `
+apiVersion: argoproj.io/v1alpha1
+kind: Rollout
+metadata:
+  name: guestbook-canary
+  namespace: default
+  annotations:
+    rollout.argoproj.io/revision: "2"
+spec:
+  replicas: 10
+  selector:
+    matchLabels:
+      app: guestbook
+  template:
+    metadata:
+      labels:
+        app: guestbook
+    spec:
+      containers:
+      - name: guestbook
+        image: gcr.io/heptio-images/ks-guestbook-demo:0.2
+  strategy:
+    canary:
+      steps:
+      - setWeight: 20
+      - pause: {}
+      - setWeight: 50
+      - pause: {}
+status:
+  replicas: 12
+  availableReplicas: 12
+  stableRS: 5b7b9b8f6d
+  currentPodHash: 6c54544bf9
+`
+
 func newFakeRollout(fakeRollout string) *v1alpha1.Rollout {
 	var rollout v1alpha1.Rollout
 	err := yaml.Unmarshal([]byte(fakeRollout), &rollout)
@@ -84,6 +120,29 @@ func TestCollectRollouts(t *testing.T) {
 	}
 }
 
+func TestCollectRolloutRevisionCanaryWeight(t *testing.T) {
+	rollout := newFakeRollout(fakeCanaryRollout)
+	// the weight follows the steps of the revision, up to the full weight once all the steps completed
+	for _, test := range []struct {
+		stepIndex int32
+		weight    string
+	}{
+		{0, "20"},
+		{1, "20"},
+		{2, "50"},
+		{4, "100"},
+	} {
+		rollout.Status.CurrentStepIndex = pointer.Int32Ptr(test.stepIndex)
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(NewRolloutCollector(fakeRolloutLister{rollouts: []*v1alpha1.Rollout{rollout}}))
+		mux := http.NewServeMux()
+		mux.Handle(MetricsPath, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+		testHttpResponse(t, mux, `# HELP rollout_revision_canary_weight The traffic weight of the canary of the current revision of the rollout.
+# TYPE rollout_revision_canary_weight gauge
+rollout_revision_canary_weight{name="guestbook-canary",namespace="default",revision="2"} `+test.weight)
+	}
+}
+
 func TestCollectRolloutsListFails(t *testing.T) {
 	buf := bytes.NewBufferString("")
 	logrus.SetOutput(buf)
@@ -7,8 +7,10 @@ import (
 
 	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
 	rolloutlister "github.com/argoproj/argo-rollouts/pkg/client/listers/rollouts/v1alpha1"
+	"github.com/argoproj/argo-rollouts/utils/annotations"
 	"github.com/argoproj/argo-rollouts/utils/conditions"
 	"github.com/argoproj/argo-rollouts/utils/defaults"
+	replicasetutil "github.com/argoproj/argo-rollouts/utils/replicaset"
 )
 
 var (
@@ -43,6 +45,15 @@ var (
 		descRolloutReconcilePhaseLabels,
 		nil,
 	)
+
+	descRolloutRevisionLabels = append(descDefaultLabels, "revision")
+
+	descRolloutRevisionCanaryWeight = prometheus.NewDesc(
+		"rollout_revision_canary_weight",
+		"The traffic weight of the canary of the current revision of the rollout.",
+		descRolloutRevisionLabels,
+		nil,
+	)
 )
 
 // RolloutPhase the phases of a reconcile can have
@@ -144,4 +155,11 @@ func collectRollouts(ch chan<- prometheus.Metric, rollout *v1alpha1.Rollout) {
 	addGauge(descRolloutPhaseLabels, boolFloat64(calculatedPhase == RolloutTimeout), string(RolloutTimeout))
 	addGauge(descRolloutPhaseLabels, boolFloat64(calculatedPhase == RolloutError), string(RolloutError))
 	addGauge(descRolloutPhaseLabels, boolFloat64(calculatedPhase == RolloutAbort), string(RolloutAbort))
+
+	// The canary weight is labeled by the revision instead of the strategy, which lets a dashboard follow a deploy
+	revision := rollout.Annotations[annotations.RevisionAnnotation]
+	if rollout.Spec.Strategy.Canary != nil && revision != "" {
+		weight := replicasetutil.GetCurrentSetWeight(rollout)
+		ch <- prometheus.MustNewConstMetric(descRolloutRevisionCanaryWeight, prometheus.GaugeValue, float64(weight), rollout.Namespace, rollout.Name, revision)
+	}
 }
@@ -227,6 +227,138 @@ func TestCreateAnalysisRunWithArg(t *testing.T) {
 	assert.Equal(t, v1alpha1.AnalysisPhasePending, patchedEx.Status.AnalysisRuns[0].Phase)
 }
 
+// TestCreateAnalysisRunWithTemplateHashArgs verifies the pod hashes of the templates are injected as args
+func TestCreateAnalysisRunWithTemplateHashArgs(t *testing.T) {
+	templates := generateTemplates("a", "b")
+	aTemplates := generateAnalysisTemplates("compare")
+	aTemplates[0].Spec.Args = []v1alpha1.Argument{
+		{Name: "templates.a.hash"},
+		{Name: "templates.b.hash"},
+	}
+	e := newExperiment("foo", templates, "")
+	e.Spec.Analyses = []v1alpha1.ExperimentAnalysisTemplateRef{
+		{
+			Name:         "compare",
+			TemplateName: aTemplates[0].Name,
+		},
+	}
+	e.Status.Phase = v1alpha1.AnalysisPhaseRunning
+	e.Status.AvailableAt = now()
+	rsA := templateToRS(e, templates[0], 1)
+	rsA.Labels = map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: "abcd"}
+	rsB := templateToRS(e, templates[1], 1)
+	rsB.Labels = map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: "efgh"}
+	ar := analysisTemplateToRun("compare", e, &aTemplates[0].Spec)
+
+	f := newFixture(t, e, rsA, rsB, &aTemplates[0])
+	defer f.Close()
+
+	createIdx := f.expectCreateAnalysisRunAction(ar)
+	f.expectPatchExperimentAction(e)
+	f.run(getKey(e, t))
+
+	createdAr := f.getCreatedAnalysisRun(createIdx)
+	assert.Equal(t, []v1alpha1.Argument{
+		{Name: "templates.a.hash", Value: pointer.StringPtr("abcd")},
+		{Name: "templates.b.hash", Value: pointer.StringPtr("efgh")},
+	}, createdAr.Spec.Args)
+}
+
+// TestCreateAnalysisRunArgsOverrideTemplateHashArgs verifies the args of the analysis take precedence over the
+// injected template hashes, and that the hashes are not added to templates which do not declare them
+func TestCreateAnalysisRunArgsOverrideTemplateHashArgs(t *testing.T) {
+	templates := generateTemplates("a", "b")
+	aTemplates := generateAnalysisTemplates("compare")
+	aTemplates[0].Spec.Args = []v1alpha1.Argument{
+		{Name: "templates.a.hash"},
+	}
+	e := newExperiment("foo", templates, "")
+	e.Spec.Analyses = []v1alpha1.ExperimentAnalysisTemplateRef{
+		{
+			Name:         "compare",
+			TemplateName: aTemplates[0].Name,
+			Args: []v1alpha1.Argument{{
+				Name:  "templates.a.hash",
+				Value: pointer.StringPtr("{{templates.b.hash}}"),
+			}},
+		},
+	}
+	e.Status.Phase = v1alpha1.AnalysisPhaseRunning
+	e.Status.AvailableAt = now()
+	rsA := templateToRS(e, templates[0], 1)
+	rsA.Labels = map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: "abcd"}
+	rsB := templateToRS(e, templates[1], 1)
+	rsB.Labels = map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: "efgh"}
+	ar := analysisTemplateToRun("compare", e, &aTemplates[0].Spec)
+
+	f := newFixture(t, e, rsA, rsB, &aTemplates[0])
+	defer f.Close()
+
+	createIdx := f.expectCreateAnalysisRunAction(ar)
+	f.expectPatchExperimentAction(e)
+	f.run(getKey(e, t))
+
+	createdAr := f.getCreatedAnalysisRun(createIdx)
+	assert.Equal(t, []v1alpha1.Argument{
+		{Name: "templates.a.hash", Value: pointer.StringPtr("efgh")},
+	}, createdAr.Spec.Args)
+}
+
+// TestCreateAnalysisRunWithExperimentArgs verifies the args of the experiment are propagated to the AnalysisRuns
+// whose templates declare them, and that the args of an analysis take precedence over them
+func TestCreateAnalysisRunWithExperimentArgs(t *testing.T) {
+	templates := generateTemplates("bar")
+	aTemplates := generateAnalysisTemplates("success-rate", "latency")
+	aTemplates[0].Spec.Args = []v1alpha1.Argument{
+		{Name: "service-name"},
+		{Name: "threshold", Value: pointer.StringPtr("0.90")},
+	}
+	aTemplates[1].Spec.Args = []v1alpha1.Argument{
+		{Name: "service-name"},
+	}
+	e := newExperiment("foo", templates, "")
+	e.Spec.Args = []v1alpha1.Argument{
+		{Name: "service-name", Value: pointer.StringPtr("foo-{{templates.bar.podTemplateHash}}")},
+		{Name: "threshold", Value: pointer.StringPtr("0.95")},
+	}
+	e.Spec.Analyses = []v1alpha1.ExperimentAnalysisTemplateRef{
+		{
+			Name:         "success-rate",
+			TemplateName: aTemplates[0].Name,
+		},
+		{
+			Name:         "latency",
+			TemplateName: aTemplates[1].Name,
+			Args: []v1alpha1.Argument{{
+				Name:  "service-name",
+				Value: pointer.StringPtr("bar"),
+			}},
+		},
+	}
+	e.Status.Phase = v1alpha1.AnalysisPhaseRunning
+	e.Status.AvailableAt = now()
+	rs := templateToRS(e, templates[0], 1)
+	rs.Labels = map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: "abcd"}
+	successRateAr := analysisTemplateToRun("success-rate", e, &aTemplates[0].Spec)
+	latencyAr := analysisTemplateToRun("latency", e, &aTemplates[1].Spec)
+
+	f := newFixture(t, e, rs, &aTemplates[0], &aTemplates[1])
+	defer f.Close()
+
+	successRateIdx := f.expectCreateAnalysisRunAction(successRateAr)
+	latencyIdx := f.expectCreateAnalysisRunAction(latencyAr)
+	f.expectPatchExperimentAction(e)
+	f.run(getKey(e, t))
+
+	assert.Equal(t, []v1alpha1.Argument{
+		{Name: "service-name", Value: pointer.StringPtr("foo-abcd")},
+		{Name: "threshold", Value: pointer.StringPtr("0.95")},
+	}, f.getCreatedAnalysisRun(successRateIdx).Spec.Args)
+	assert.Equal(t, []v1alpha1.Argument{
+		{Name: "service-name", Value: pointer.StringPtr("bar")},
+	}, f.getCreatedAnalysisRun(latencyIdx).Spec.Args)
+}
+
 // TestCreateAnalysisRunWithClusterTemplate ensures we create the AnalysisRun when we become available
 func TestCreateAnalysisRunWithClusterTemplate(t *testing.T) {
 	templates := generateTemplates("bar")
@@ -359,10 +359,17 @@ func (ec *experimentContext) reconcileAnalysisRun(analysis v1alpha1.ExperimentAn
 // run with a collision counter increase.
 func (ec *experimentContext) createAnalysisRun(analysis v1alpha1.ExperimentAnalysisTemplateRef) (*v1alpha1.AnalysisRun, error) {
 	analysisRunIf := ec.argoProjClientset.ArgoprojV1alpha1().AnalysisRuns(ec.ex.Namespace)
+	experimentArgs, err := ec.ResolveAnalysisRunArgs(ec.ex.Spec.Args)
+	if err != nil {
+		return nil, err
+	}
 	args, err := ec.ResolveAnalysisRunArgs(analysis.Args)
 	if err != nil {
 		return nil, err
 	}
+	// The args of the analysis take precedence over the args of the experiment, which take precedence over the
+	// injected template hashes
+	args = append(append(ec.templateHashArgs(), experimentArgs...), args...)
 	run, err := ec.newAnalysisRun(analysis, args)
 	if err != nil {
 		return nil, err
@@ -386,6 +393,27 @@ func (ec *experimentContext) ResolveAnalysisRunArgs(args []v1alpha1.Argument) ([
 	return resolvedArgs, nil
 }
 
+// templateHashArgs returns an argument with the pod hash of each template of the experiment. The arguments are only
+// added to the AnalysisRuns whose templates declare them, which allows a single metric to compare the templates.
+func (ec *experimentContext) templateHashArgs() []v1alpha1.Argument {
+	args := []v1alpha1.Argument{}
+	for _, template := range ec.ex.Spec.Templates {
+		rs, ok := ec.templateRSs[template.Name]
+		if !ok || rs == nil {
+			continue
+		}
+		hash := rs.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+		if hash == "" {
+			continue
+		}
+		args = append(args, v1alpha1.Argument{
+			Name:  templateutil.ExperimentTemplateHashArg(template.Name),
+			Value: &hash,
+		})
+	}
+	return args
+}
+
 func (ec *experimentContext) calculateStatus() *v1alpha1.ExperimentStatus {
 	prevStatus := ec.newStatus.DeepCopy()
 	switch ec.newStatus.Phase {
@@ -508,6 +536,11 @@ func (ec *experimentContext) newAnalysisRun(analysis v1alpha1.ExperimentAnalysis
 		if err != nil {
 			return nil, err
 		}
+		clusterTemplate = clusterTemplate.DeepCopy()
+		clusterTemplate.Spec, err = analysisutil.ExpandMetricMixins(clusterTemplate.Spec, ec.clusterAnalysisTemplateLister.Get)
+		if err != nil {
+			return nil, err
+		}
 		name := fmt.Sprintf("%s-%s", ec.ex.Name, analysis.Name)
 
 		run, err := analysisutil.NewAnalysisRunFromClusterTemplate(clusterTemplate, args, name, "", ec.ex.Namespace)
@@ -525,6 +558,11 @@ func (ec *experimentContext) newAnalysisRun(analysis v1alpha1.ExperimentAnalysis
 		if err != nil {
 			return nil, err
 		}
+		template = template.DeepCopy()
+		template.Spec, err = analysisutil.ExpandMetricMixins(template.Spec, ec.clusterAnalysisTemplateLister.Get)
+		if err != nil {
+			return nil, err
+		}
 		name := fmt.Sprintf("%s-%s", ec.ex.Name, analysis.Name)
 
 		run, err := analysisutil.NewAnalysisRunFromTemplate(template, args, name, "", ec.ex.Namespace)
@@ -3,8 +3,10 @@ module github.com/argoproj/argo-rollouts
 go 1.13
 
 require (
+	github.com/Masterminds/sprig/v3 v3.2.2
 	github.com/antonmedv/expr v1.4.2
 	github.com/argoproj/pkg v0.0.0-20200624215116-23e74cb168fe
+	github.com/aws/aws-sdk-go v1.35.0
 	github.com/bouk/monkey v1.0.0
 	github.com/cucumber/godog v0.10.0
 	github.com/docker/docker v1.4.2-0.20190327010347-be7ac8be2ae0 // indirect
@@ -13,7 +15,6 @@ require (
 	github.com/go-openapi/spec v0.19.3
 	github.com/gofrs/uuid v3.3.0+incompatible // indirect
 	github.com/gregjones/httpcache v0.0.0-20190611155906-901d90724c79 // indirect
-	github.com/imdario/mergo v0.3.6 // indirect
 	github.com/jstemmer/go-junit-report v0.9.1
 	github.com/juju/ansiterm v0.0.0-20180109212912-720a0952cc2a
 	github.com/lunixbochs/vtclean v1.0.0 // indirect
@@ -29,6 +30,7 @@ require (
 	github.com/stretchr/testify v1.6.1
 	github.com/valyala/fasttemplate v1.2.1
 	github.com/vektra/mockery v1.1.2
+	google.golang.org/grpc v1.23.1
 	gopkg.in/yaml.v2 v2.3.0
 	gopkg.in/yaml.v3 v3.0.0-20200615113413-eeeca48fe776 // indirect
 	k8s.io/api v0.17.4
@@ -34,14 +34,16 @@ func (c *Controller) syncALBIngress(ingress *extensionsv1beta1.Ingress, rollouts
 	for roName := range managedActions {
 		if _, ok := actionHasExistingRollout[roName]; !ok {
 			modified = true
-			actionKey := managedActions[roName]
+			actionKeys := managedActions.ActionKeys(roName)
 			delete(managedActions, roName)
-			resetALBAction, err := getResetALBActionStr(ingress, actionKey)
-			if err != nil {
-				logrus.WithField(logutil.IngressKey, ingress.Name).WithField(logutil.NamespaceKey, ingress.Namespace).Error(err)
-				return nil
+			for _, actionKey := range actionKeys {
+				resetALBAction, err := getResetALBActionStr(ingress, actionKey)
+				if err != nil {
+					logrus.WithField(logutil.IngressKey, ingress.Name).WithField(logutil.NamespaceKey, ingress.Namespace).Error(err)
+					return nil
+				}
+				newIngress.Annotations[actionKey] = resetALBAction
 			}
-			newIngress.Annotations[actionKey] = resetALBAction
 		}
 	}
 	if !modified {
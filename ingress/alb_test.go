@@ -179,3 +179,30 @@ func TestALBIngressResetAction(t *testing.T) {
 	expectedAction := `{"Type":"forward","ForwardConfig":{"TargetGroups":[{"ServiceName":"stable-service","ServicePort":"80","Weight":100}]}}`
 	assert.Equal(t, expectedAction, annotations[albActionAnnotation("stable-service")])
 }
+
+func TestALBIngressResetMultipleActions(t *testing.T) {
+	ing := newALBIngress("test-ingress", 80, "stable-service", "non-existing-rollout")
+	ing.Annotations[albActionAnnotation("http-service")] = fmt.Sprintf(actionTemplate, "http-service", 8080, "http-service-canary", 8080)
+	ing.Annotations[ingressutil.ManagedActionsAnnotation] = fmt.Sprintf("non-existing-rollout:%s;%s", albActionAnnotation("stable-service"), albActionAnnotation("http-service"))
+
+	ctrl, kubeclient, enqueuedObjects := newFakeIngressController(ing, nil)
+	err := ctrl.syncIngress("default/test-ingress")
+	assert.Nil(t, err)
+	assert.Len(t, enqueuedObjects, 0)
+	actions := kubeclient.Actions()
+	assert.Len(t, actions, 1)
+	updateAction, ok := actions[0].(k8stesting.UpdateAction)
+	if !ok {
+		assert.FailNow(t, "Client call was not an update")
+	}
+	acc, err := meta.Accessor(updateAction.GetObject())
+	if err != nil {
+		panic(err)
+	}
+	annotations := acc.GetAnnotations()
+	assert.NotContains(t, annotations, ingressutil.ManagedActionsAnnotation)
+	expectedAction := `{"Type":"forward","ForwardConfig":{"TargetGroups":[{"ServiceName":"stable-service","ServicePort":"80","Weight":100}]}}`
+	assert.Equal(t, expectedAction, annotations[albActionAnnotation("stable-service")])
+	expectedAction = `{"Type":"forward","ForwardConfig":{"TargetGroups":[{"ServiceName":"http-service","ServicePort":"8080","Weight":100}]}}`
+	assert.Equal(t, expectedAction, annotations[albActionAnnotation("http-service")])
+}
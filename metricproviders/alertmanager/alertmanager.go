@@ -0,0 +1,193 @@
+package alertmanager
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/argoproj/argo-rollouts/utils/evaluate"
+	metricutil "github.com/argoproj/argo-rollouts/utils/metric"
+)
+
+const (
+	// ProviderType indicates the provider is Prometheus Alertmanager
+	ProviderType = "Alertmanager"
+	// AlertsMetadataKey is the key of the measurement metadata listing the names of the matching alerts
+	AlertsMetadataKey = "alerts"
+	// alertNameLabel is the label holding the name of an alert
+	alertNameLabel = "alertname"
+)
+
+// alert is an alert returned by the alerts API of Alertmanager
+type alert struct {
+	Labels map[string]string `json:"labels"`
+}
+
+// Provider counts the active alerts of Alertmanager matching the matchers of the metric
+// Implements the Provider Interface
+type Provider struct {
+	logCtx log.Entry
+	client *http.Client
+	// recordResponseBodies records the response bodies of failed requests in the measurement metadata
+	recordResponseBodies bool
+}
+
+// Type indicates provider is an Alertmanager provider
+func (p *Provider) Type() string {
+	return ProviderType
+}
+
+// Run counts the matching alerts and evaluates the count
+func (p *Provider) Run(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric) v1alpha1.Measurement {
+	startTime := metav1.Now()
+	measurement := v1alpha1.Measurement{
+		StartedAt: &startTime,
+	}
+
+	alertsURL, err := newAlertsURL(metric.Provider.Alertmanager)
+	if err != nil {
+		return metricutil.MarkMeasurementError(measurement, err)
+	}
+	response, err := p.client.Get(alertsURL)
+	if err != nil {
+		return metricutil.MarkMeasurementError(measurement, err)
+	}
+	defer response.Body.Close()
+
+	bodyBytes, err := metricutil.ReadResponseBody(response.Body)
+	if err != nil {
+		return metricutil.MarkMeasurementError(measurement, err)
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return metricutil.MarkMeasurementError(measurement, fmt.Errorf("received non 2xx response code: %v: %s", response.StatusCode, strings.TrimSpace(string(bodyBytes))))
+	}
+	var alerts []alert
+	if err := json.Unmarshal(bodyBytes, &alerts); err != nil {
+		err = &metricutil.ResponseError{Err: fmt.Errorf("Could not parse JSON body: %v", err), Body: bodyBytes}
+		return metricutil.MarkMeasurementResponseError(measurement, err, p.recordResponseBodies)
+	}
+
+	count := len(alerts)
+	measurement.Value = strconv.Itoa(count)
+	phase, err := evaluate.EvaluateResult(count, metric, p.logCtx)
+	if err != nil {
+		return metricutil.MarkMeasurementError(measurement, err)
+	}
+	measurement.Phase = phase
+	if count > 0 {
+		measurement.Metadata = map[string]string{
+			AlertsMetadataKey: alertNames(alerts),
+		}
+	}
+	finishedTime := metav1.Now()
+	measurement.FinishedAt = &finishedTime
+	return measurement
+}
+
+// newAlertsURL returns the URL of the alerts API listing the active alerts matching the matchers of the metric
+func newAlertsURL(metric *v1alpha1.AlertmanagerMetric) (string, error) {
+	address, err := url.Parse(metric.Address)
+	if err != nil {
+		return "", err
+	}
+	address.Path = strings.TrimSuffix(address.Path, "/") + "/api/v2/alerts"
+	query := url.Values{}
+	query.Set("active", "true")
+	query.Set("silenced", strconv.FormatBool(metric.Silenced))
+	query.Set("inhibited", strconv.FormatBool(metric.Inhibited))
+	for _, matcher := range metric.Matchers {
+		filter, err := newFilter(matcher)
+		if err != nil {
+			return "", err
+		}
+		query.Add("filter", filter)
+	}
+	address.RawQuery = query.Encode()
+	return address.String(), nil
+}
+
+// newFilter returns the Alertmanager filter of the matcher (e.g. alertname="HighErrorRate")
+func newFilter(matcher v1alpha1.AlertmanagerMatcher) (string, error) {
+	var operator string
+	switch matcher.Type {
+	case "", v1alpha1.AlertmanagerMatchEqual:
+		operator = "="
+	case v1alpha1.AlertmanagerMatchNotEqual:
+		operator = "!="
+	case v1alpha1.AlertmanagerMatchRegex:
+		operator = "=~"
+	case v1alpha1.AlertmanagerMatchNotRegex:
+		operator = "!~"
+	default:
+		return "", fmt.Errorf("invalid type '%s' of the matcher of label '%s'", matcher.Type, matcher.Name)
+	}
+	return matcher.Name + operator + strconv.Quote(matcher.Value), nil
+}
+
+// alertNames returns the sorted names of the alerts, without duplicates
+func alertNames(alerts []alert) string {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, a := range alerts {
+		name := a.Labels[alertNameLabel]
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// Resume should not be used the Alertmanager provider since all the work should occur in the Run method
+func (p *Provider) Resume(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, measurement v1alpha1.Measurement) v1alpha1.Measurement {
+	p.logCtx.Warn("Alertmanager provider should not execute the Resume method")
+	return measurement
+}
+
+// Terminate should not be used the Alertmanager provider since all the work should occur in the Run method
+func (p *Provider) Terminate(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, measurement v1alpha1.Measurement) v1alpha1.Measurement {
+	p.logCtx.Warn("Alertmanager provider should not execute the Terminate method")
+	return measurement
+}
+
+// GarbageCollect is a no-op for the Alertmanager provider
+func (p *Provider) GarbageCollect(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, limit int) error {
+	return nil
+}
+
+// NewAlertmanagerHttpClient returns a http client using the timeout of the metric
+func NewAlertmanagerHttpClient(metric v1alpha1.Metric) *http.Client {
+	var timeout time.Duration
+
+	// Using a default timeout of 10 seconds
+	if metric.Provider.Alertmanager.TimeoutSeconds <= 0 {
+		timeout = time.Duration(10) * time.Second
+	} else {
+		timeout = time.Duration(metric.Provider.Alertmanager.TimeoutSeconds) * time.Second
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+	}
+}
+
+// NewAlertmanagerProvider creates a new Alertmanager provider. When recordResponseBodies is true, the response
+// bodies which cannot be parsed are recorded in the measurement metadata
+func NewAlertmanagerProvider(logCtx log.Entry, client *http.Client, recordResponseBodies bool) *Provider {
+	return &Provider{
+		logCtx:               logCtx,
+		client:               client,
+		recordResponseBodies: recordResponseBodies,
+	}
+}
@@ -0,0 +1,171 @@
+package alertmanager
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+)
+
+const (
+	firingResponse = `[
+		{"labels": {"alertname": "HighErrorRate", "rollouts_pod_template_hash": "6c54544bf9"}, "status": {"state": "active"}},
+		{"labels": {"alertname": "HighLatency", "rollouts_pod_template_hash": "6c54544bf9"}, "status": {"state": "active"}},
+		{"labels": {"alertname": "HighErrorRate", "rollouts_pod_template_hash": "6c54544bf9", "instance": "b"}, "status": {"state": "active"}}
+	]`
+	noAlertsResponse = `[]`
+)
+
+func newMetric(successCondition string, matchers ...v1alpha1.AlertmanagerMatcher) v1alpha1.Metric {
+	return v1alpha1.Metric{
+		Name:             "alerts",
+		SuccessCondition: successCondition,
+		Provider: v1alpha1.MetricProvider{
+			Alertmanager: &v1alpha1.AlertmanagerMetric{
+				Matchers: matchers,
+			},
+		},
+	}
+}
+
+// newStubAlertmanager returns a stub of the alerts API, along with the URLs of the requests it received
+func newStubAlertmanager(status int, response string) (*httptest.Server, *[]*url.URL) {
+	requests := []*url.URL{}
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		requests = append(requests, req.URL)
+		rw.Header().Set("Content-Type", "application/json")
+		rw.WriteHeader(status)
+		io.WriteString(rw, response)
+	}))
+	return server, &requests
+}
+
+func TestType(t *testing.T) {
+	p := NewAlertmanagerProvider(*log.WithField("", ""), nil, false)
+	assert.Equal(t, ProviderType, p.Type())
+}
+
+func TestRunWithFiringAlerts(t *testing.T) {
+	server, requests := newStubAlertmanager(http.StatusOK, firingResponse)
+	defer server.Close()
+	metric := newMetric("result == 0",
+		v1alpha1.AlertmanagerMatcher{Name: "alertname", Value: "High.*", Type: v1alpha1.AlertmanagerMatchRegex},
+		v1alpha1.AlertmanagerMatcher{Name: "rollouts_pod_template_hash", Value: "6c54544bf9"},
+	)
+	metric.Provider.Alertmanager.Address = server.URL
+	p := NewAlertmanagerProvider(*log.WithField("", ""), NewAlertmanagerHttpClient(metric), false)
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, measurement.Phase)
+	assert.Equal(t, "3", measurement.Value)
+	assert.Equal(t, "HighErrorRate,HighLatency", measurement.Metadata[AlertsMetadataKey])
+	assert.NotNil(t, measurement.StartedAt)
+	assert.NotNil(t, measurement.FinishedAt)
+
+	assert.Len(t, *requests, 1)
+	assert.Equal(t, "/api/v2/alerts", (*requests)[0].Path)
+	query := (*requests)[0].Query()
+	assert.Equal(t, []string{`alertname=~"High.*"`, `rollouts_pod_template_hash="6c54544bf9"`}, query["filter"])
+	assert.Equal(t, "true", query.Get("active"))
+	assert.Equal(t, "false", query.Get("silenced"))
+	assert.Equal(t, "false", query.Get("inhibited"))
+}
+
+func TestRunWithoutAlerts(t *testing.T) {
+	server, _ := newStubAlertmanager(http.StatusOK, noAlertsResponse)
+	defer server.Close()
+	metric := newMetric("result == 0", v1alpha1.AlertmanagerMatcher{Name: "alertname", Value: "HighErrorRate"})
+	metric.Provider.Alertmanager.Address = server.URL
+	p := NewAlertmanagerProvider(*log.WithField("", ""), NewAlertmanagerHttpClient(metric), false)
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+	assert.Equal(t, "0", measurement.Value)
+	assert.Nil(t, measurement.Metadata)
+}
+
+func TestRunSilencedAndInhibitedAlerts(t *testing.T) {
+	server, requests := newStubAlertmanager(http.StatusOK, noAlertsResponse)
+	defer server.Close()
+	metric := newMetric("result == 0",
+		v1alpha1.AlertmanagerMatcher{Name: "severity", Value: "info", Type: v1alpha1.AlertmanagerMatchNotEqual},
+		v1alpha1.AlertmanagerMatcher{Name: "team", Value: "web|api", Type: v1alpha1.AlertmanagerMatchNotRegex},
+	)
+	// Alertmanager may be served under a path prefix
+	metric.Provider.Alertmanager.Address = server.URL + "/alertmanager/"
+	metric.Provider.Alertmanager.Silenced = true
+	metric.Provider.Alertmanager.Inhibited = true
+	p := NewAlertmanagerProvider(*log.WithField("", ""), NewAlertmanagerHttpClient(metric), false)
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+	assert.Len(t, *requests, 1)
+	request := (*requests)[0]
+	assert.Equal(t, "/alertmanager/api/v2/alerts", request.Path)
+	query := request.Query()
+	assert.Equal(t, []string{`severity!="info"`, `team!~"web|api"`}, query["filter"])
+	assert.Equal(t, "true", query.Get("silenced"))
+	assert.Equal(t, "true", query.Get("inhibited"))
+}
+
+func TestRunErrorResponse(t *testing.T) {
+	server, _ := newStubAlertmanager(http.StatusBadRequest, `"bad matcher format: alertname"`)
+	defer server.Close()
+	metric := newMetric("result == 0", v1alpha1.AlertmanagerMatcher{Name: "alertname", Value: "HighErrorRate"})
+	metric.Provider.Alertmanager.Address = server.URL
+	p := NewAlertmanagerProvider(*log.WithField("", ""), NewAlertmanagerHttpClient(metric), false)
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, `received non 2xx response code: 400: "bad matcher format: alertname"`, measurement.Message)
+}
+
+func TestRunInvalidResponse(t *testing.T) {
+	server, _ := newStubAlertmanager(http.StatusOK, `{"status": "success"}`)
+	defer server.Close()
+	metric := newMetric("result == 0", v1alpha1.AlertmanagerMatcher{Name: "alertname", Value: "HighErrorRate"})
+	metric.Provider.Alertmanager.Address = server.URL
+	p := NewAlertmanagerProvider(*log.WithField("", ""), NewAlertmanagerHttpClient(metric), false)
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Contains(t, measurement.Message, "Could not parse JSON body")
+}
+
+func TestRunInvalidMatcherType(t *testing.T) {
+	metric := newMetric("result == 0", v1alpha1.AlertmanagerMatcher{Name: "alertname", Value: "HighErrorRate", Type: "Like"})
+	p := NewAlertmanagerProvider(*log.WithField("", ""), NewAlertmanagerHttpClient(metric), false)
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "invalid type 'Like' of the matcher of label 'alertname'", measurement.Message)
+}
+
+func TestNewFilterQuotesValue(t *testing.T) {
+	filter, err := newFilter(v1alpha1.AlertmanagerMatcher{Name: "summary", Value: `error "rate"`})
+	assert.NoError(t, err)
+	assert.Equal(t, `summary="error \"rate\""`, filter)
+}
+
+func TestResumeShouldNotBeUsed(t *testing.T) {
+	p := NewAlertmanagerProvider(*log.WithField("", ""), nil, false)
+	measurement := v1alpha1.Measurement{Phase: v1alpha1.AnalysisPhaseRunning}
+	assert.Equal(t, measurement, p.Resume(&v1alpha1.AnalysisRun{}, newMetric("result == 0"), measurement))
+}
+
+func TestTerminateShouldNotBeUsed(t *testing.T) {
+	p := NewAlertmanagerProvider(*log.WithField("", ""), nil, false)
+	measurement := v1alpha1.Measurement{Phase: v1alpha1.AnalysisPhaseRunning}
+	assert.Equal(t, measurement, p.Terminate(&v1alpha1.AnalysisRun{}, newMetric("result == 0"), measurement))
+}
+
+func TestGarbageCollect(t *testing.T) {
+	p := NewAlertmanagerProvider(*log.WithField("", ""), nil, false)
+	assert.NoError(t, p.GarbageCollect(&v1alpha1.AnalysisRun{}, newMetric("result == 0"), 0))
+}
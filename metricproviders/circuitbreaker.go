@@ -0,0 +1,261 @@
+package metricproviders
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/ghodss/yaml"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/argoproj/argo-rollouts/utils/defaults"
+)
+
+const (
+	// CircuitBreakersConfigMapKey is the key of the ConfigMap holding the metric provider circuit breakers
+	CircuitBreakersConfigMapKey = "metricProviderCircuitBreakers"
+	// CircuitBreakerMetadataKey is the key of the measurement metadata set when a measurement was not taken because
+	// the circuit of its provider backend is open
+	CircuitBreakerMetadataKey = "circuit-breaker"
+	// CircuitBreakerOpenValue is the value of the measurement metadata set when the circuit is open
+	CircuitBreakerOpenValue = "open"
+)
+
+// CircuitBreakerConfig stops querying a metric provider backend after consecutive failed measurements
+type CircuitBreakerConfig struct {
+	// Provider is the provider type the circuit breaker applies to (e.g. Prometheus)
+	Provider string `json:"provider"`
+	// Address limits the circuit breaker to a single endpoint of the provider. If omitted, it applies to each of the
+	// endpoints of the provider which do not have a circuit breaker of their own
+	Address string `json:"address,omitempty"`
+	// FailureThreshold is the number of consecutive errored measurements which opens the circuit
+	FailureThreshold int `json:"failureThreshold"`
+	// Window is the duration in which the consecutive errored measurements must occur. If omitted, the errored
+	// measurements are counted until a measurement succeeds
+	Window v1alpha1.DurationString `json:"window,omitempty"`
+	// Cooldown is the duration the circuit stays open before a single trial measurement is allowed
+	Cooldown v1alpha1.DurationString `json:"cooldown"`
+	// OpenPhase is the phase of the measurements which are not taken while the circuit is open, either Error or
+	// Inconclusive (default: Error)
+	OpenPhase v1alpha1.AnalysisPhase `json:"openPhase,omitempty"`
+}
+
+type circuitState string
+
+const (
+	// circuitClosed lets all the measurements query the backend
+	circuitClosed circuitState = "Closed"
+	// circuitOpen short-circuits the measurements until the cooldown passes
+	circuitOpen circuitState = "Open"
+	// circuitHalfOpen lets a single trial measurement query the backend, which closes the circuit if it succeeds
+	circuitHalfOpen circuitState = "HalfOpen"
+)
+
+// circuitBreaker holds the state of the circuit of a single provider backend
+type circuitBreaker struct {
+	state        circuitState
+	failures     int
+	firstFailure time.Time
+	openedAt     time.Time
+	trialStarted bool
+}
+
+// CircuitBreakers holds the circuit breakers of the metric provider backends, which are shared across all AnalysisRuns
+type CircuitBreakers struct {
+	configs  []CircuitBreakerConfig
+	lock     sync.Mutex
+	breakers map[string]*circuitBreaker
+	// now is overridden in unit tests
+	now func() time.Time
+}
+
+// NewCircuitBreakers returns the circuit breakers for the configurations. Providers without a circuit breaker are
+// always queried.
+func NewCircuitBreakers(configs []CircuitBreakerConfig) *CircuitBreakers {
+	return &CircuitBreakers{
+		configs:  configs,
+		breakers: map[string]*circuitBreaker{},
+		now:      time.Now,
+	}
+}
+
+// SetConfigs replaces the configurations of the circuit breakers, such as when the controller ConfigMap is reloaded.
+// The state of the circuits is reset only when the configurations changed.
+func (c *CircuitBreakers) SetConfigs(configs []CircuitBreakerConfig) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if reflect.DeepEqual(c.configs, configs) {
+		return
+	}
+	c.configs = configs
+	c.breakers = map[string]*circuitBreaker{}
+}
+
+// Allow returns true if a measurement of the metric can query its backend, or false if the circuit of the backend
+// is open. Once the cooldown has passed, a single trial measurement is allowed until its result is recorded.
+func (c *CircuitBreakers) Allow(metric v1alpha1.Metric) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	config := c.getConfig(metric)
+	if config == nil {
+		return true
+	}
+	breaker := c.getBreaker(metric)
+	switch breaker.state {
+	case circuitOpen:
+		cooldown, _ := config.Cooldown.Duration()
+		if c.now().Sub(breaker.openedAt) < cooldown {
+			return false
+		}
+		breaker.state = circuitHalfOpen
+		breaker.trialStarted = true
+		return true
+	case circuitHalfOpen:
+		if breaker.trialStarted {
+			return false
+		}
+		breaker.trialStarted = true
+		return true
+	}
+	return true
+}
+
+// Record records the phase of a measurement of the metric. Errored measurements count towards opening the circuit,
+// while any other phase closes it. Measurements recorded while the circuit is open are ignored.
+func (c *CircuitBreakers) Record(metric v1alpha1.Metric, phase v1alpha1.AnalysisPhase) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	config := c.getConfig(metric)
+	if config == nil {
+		return
+	}
+	breaker := c.getBreaker(metric)
+	now := c.now()
+	if breaker.state == circuitOpen {
+		// measurements which started before the circuit opened neither close it nor extend the cooldown
+		return
+	}
+	if phase != v1alpha1.AnalysisPhaseError {
+		*breaker = circuitBreaker{state: circuitClosed}
+		return
+	}
+	if breaker.state == circuitHalfOpen {
+		// the trial measurement failed
+		breaker.state = circuitOpen
+		breaker.openedAt = now
+		breaker.trialStarted = false
+		return
+	}
+	window, _ := config.Window.Duration()
+	if breaker.failures == 0 || (window > 0 && now.Sub(breaker.firstFailure) > window) {
+		breaker.failures = 0
+		breaker.firstFailure = now
+	}
+	breaker.failures++
+	if breaker.failures >= config.FailureThreshold {
+		breaker.state = circuitOpen
+		breaker.openedAt = now
+	}
+}
+
+// OpenMeasurement returns the measurement of the metric recorded instead of querying its backend while the circuit
+// is open
+func (c *CircuitBreakers) OpenMeasurement(metric v1alpha1.Metric) v1alpha1.Measurement {
+	phase := v1alpha1.AnalysisPhaseError
+	c.lock.Lock()
+	if config := c.getConfig(metric); config != nil && config.OpenPhase != "" {
+		phase = config.OpenPhase
+	}
+	c.lock.Unlock()
+	now := metav1.NewTime(c.now())
+	return v1alpha1.Measurement{
+		Phase:      phase,
+		Message:    fmt.Sprintf("circuit breaker of the %s provider is open after consecutive errors", Type(metric)),
+		StartedAt:  &now,
+		FinishedAt: &now,
+		Metadata: map[string]string{
+			CircuitBreakerMetadataKey: CircuitBreakerOpenValue,
+		},
+	}
+}
+
+// getBreaker returns the circuit breaker of the metric's endpoint. The lock must be held.
+func (c *CircuitBreakers) getBreaker(metric v1alpha1.Metric) *circuitBreaker {
+	key := Type(metric) + "/" + Address(metric)
+	breaker, ok := c.breakers[key]
+	if !ok {
+		breaker = &circuitBreaker{state: circuitClosed}
+		c.breakers[key] = breaker
+	}
+	return breaker
+}
+
+// getConfig returns the circuit breaker configuration of the metric's endpoint, falling back to the configuration
+// of its provider type. The lock must be held.
+func (c *CircuitBreakers) getConfig(metric v1alpha1.Metric) *CircuitBreakerConfig {
+	providerType := Type(metric)
+	address := Address(metric)
+	var providerConfig *CircuitBreakerConfig
+	for i := range c.configs {
+		config := c.configs[i]
+		if config.Provider != providerType {
+			continue
+		}
+		if config.Address == "" {
+			providerConfig = &config
+		} else if config.Address == address {
+			return &config
+		}
+	}
+	return providerConfig
+}
+
+// GetCircuitBreakers reads the metric provider circuit breakers from the controller ConfigMap. No circuit breakers
+// are returned if the ConfigMap or its key does not exist.
+func GetCircuitBreakers(kubeclientset kubernetes.Interface) ([]CircuitBreakerConfig, error) {
+	cm, err := kubeclientset.CoreV1().ConfigMaps(defaults.Namespace()).Get(ConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	data, ok := cm.Data[CircuitBreakersConfigMapKey]
+	if !ok {
+		return nil, nil
+	}
+	var configs []CircuitBreakerConfig
+	if err := yaml.Unmarshal([]byte(data), &configs); err != nil {
+		return nil, fmt.Errorf("invalid %s in ConfigMap %s: %v", CircuitBreakersConfigMapKey, ConfigMapName, err)
+	}
+	for _, config := range configs {
+		if err := validateCircuitBreaker(config); err != nil {
+			return nil, fmt.Errorf("invalid %s in ConfigMap %s: %v", CircuitBreakersConfigMapKey, ConfigMapName, err)
+		}
+	}
+	return configs, nil
+}
+
+func validateCircuitBreaker(config CircuitBreakerConfig) error {
+	if config.Provider == "" || config.FailureThreshold <= 0 || config.Cooldown == "" {
+		return fmt.Errorf("provider, failureThreshold and cooldown are required")
+	}
+	if _, err := config.Cooldown.Duration(); err != nil {
+		return fmt.Errorf("invalid cooldown: %v", err)
+	}
+	if config.Window != "" {
+		if _, err := config.Window.Duration(); err != nil {
+			return fmt.Errorf("invalid window: %v", err)
+		}
+	}
+	switch config.OpenPhase {
+	case "", v1alpha1.AnalysisPhaseError, v1alpha1.AnalysisPhaseInconclusive:
+	default:
+		return fmt.Errorf("openPhase must be Error or Inconclusive")
+	}
+	return nil
+}
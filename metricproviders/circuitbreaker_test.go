@@ -0,0 +1,236 @@
+package metricproviders
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/argoproj/argo-rollouts/utils/defaults"
+)
+
+func newTestCircuitBreakers(configs ...CircuitBreakerConfig) (*CircuitBreakers, *time.Time) {
+	now := time.Date(2020, 8, 1, 12, 0, 0, 0, time.UTC)
+	c := NewCircuitBreakers(configs)
+	c.now = func() time.Time {
+		return now
+	}
+	return c, &now
+}
+
+func (c *CircuitBreakers) state(metric v1alpha1.Metric) circuitState {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.getBreaker(metric).state
+}
+
+func TestCircuitBreakersWithoutConfig(t *testing.T) {
+	c, _ := newTestCircuitBreakers()
+	metric := newPrometheusMetric("http://prometheus:9090")
+	for i := 0; i < 10; i++ {
+		c.Record(metric, v1alpha1.AnalysisPhaseError)
+		assert.True(t, c.Allow(metric))
+	}
+}
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	c, now := newTestCircuitBreakers(CircuitBreakerConfig{
+		Provider:         "Prometheus",
+		FailureThreshold: 3,
+		Cooldown:         "1m",
+	})
+	metric := newPrometheusMetric("http://prometheus:9090")
+
+	// closed: errors below the threshold do not open the circuit, and a success resets the count
+	c.Record(metric, v1alpha1.AnalysisPhaseError)
+	c.Record(metric, v1alpha1.AnalysisPhaseError)
+	c.Record(metric, v1alpha1.AnalysisPhaseSuccessful)
+	c.Record(metric, v1alpha1.AnalysisPhaseError)
+	c.Record(metric, v1alpha1.AnalysisPhaseError)
+	assert.Equal(t, circuitClosed, c.state(metric))
+	assert.True(t, c.Allow(metric))
+
+	// open: the measurements are short-circuited until the cooldown passes
+	c.Record(metric, v1alpha1.AnalysisPhaseError)
+	assert.Equal(t, circuitOpen, c.state(metric))
+	assert.False(t, c.Allow(metric))
+	*now = now.Add(59 * time.Second)
+	assert.False(t, c.Allow(metric))
+
+	// half-open: a single trial measurement is allowed, and its failure opens the circuit again
+	*now = now.Add(time.Second)
+	assert.True(t, c.Allow(metric))
+	assert.Equal(t, circuitHalfOpen, c.state(metric))
+	assert.False(t, c.Allow(metric))
+	c.Record(metric, v1alpha1.AnalysisPhaseError)
+	assert.Equal(t, circuitOpen, c.state(metric))
+	assert.False(t, c.Allow(metric))
+
+	// a successful trial measurement closes the circuit
+	*now = now.Add(time.Minute)
+	assert.True(t, c.Allow(metric))
+	c.Record(metric, v1alpha1.AnalysisPhaseFailed)
+	assert.Equal(t, circuitClosed, c.state(metric))
+	assert.True(t, c.Allow(metric))
+	assert.True(t, c.Allow(metric))
+}
+
+func TestCircuitBreakerIgnoresRecordsWhileOpen(t *testing.T) {
+	c, _ := newTestCircuitBreakers(CircuitBreakerConfig{
+		Provider:         "Prometheus",
+		FailureThreshold: 1,
+		Cooldown:         "1m",
+	})
+	metric := newPrometheusMetric("http://prometheus:9090")
+	c.Record(metric, v1alpha1.AnalysisPhaseError)
+	assert.Equal(t, circuitOpen, c.state(metric))
+	c.Record(metric, v1alpha1.AnalysisPhaseSuccessful)
+	assert.Equal(t, circuitOpen, c.state(metric))
+}
+
+func TestCircuitBreakerWindow(t *testing.T) {
+	c, now := newTestCircuitBreakers(CircuitBreakerConfig{
+		Provider:         "Prometheus",
+		FailureThreshold: 2,
+		Window:           "1m",
+		Cooldown:         "1m",
+	})
+	metric := newPrometheusMetric("http://prometheus:9090")
+	c.Record(metric, v1alpha1.AnalysisPhaseError)
+	// the errors are counted again once the window has passed since the first error
+	*now = now.Add(2 * time.Minute)
+	c.Record(metric, v1alpha1.AnalysisPhaseError)
+	assert.Equal(t, circuitClosed, c.state(metric))
+	*now = now.Add(30 * time.Second)
+	c.Record(metric, v1alpha1.AnalysisPhaseError)
+	assert.Equal(t, circuitOpen, c.state(metric))
+}
+
+func TestCircuitBreakersPerEndpoint(t *testing.T) {
+	c, _ := newTestCircuitBreakers(CircuitBreakerConfig{
+		Provider:         "Prometheus",
+		FailureThreshold: 1,
+		Cooldown:         "1m",
+	}, CircuitBreakerConfig{
+		Provider:         "Prometheus",
+		Address:          "http://prometheus-b:9090",
+		FailureThreshold: 2,
+		Cooldown:         "1m",
+	})
+	metricA := newPrometheusMetric("http://prometheus-a:9090")
+	metricB := newPrometheusMetric("http://prometheus-b:9090")
+	metricC := newPrometheusMetric("http://prometheus-c:9090")
+
+	// each endpoint has its own circuit
+	c.Record(metricA, v1alpha1.AnalysisPhaseError)
+	assert.False(t, c.Allow(metricA))
+	assert.True(t, c.Allow(metricC))
+
+	// endpoints with a configuration of their own use it
+	c.Record(metricB, v1alpha1.AnalysisPhaseError)
+	assert.True(t, c.Allow(metricB))
+	c.Record(metricB, v1alpha1.AnalysisPhaseError)
+	assert.False(t, c.Allow(metricB))
+}
+
+func TestCircuitBreakersSetConfigs(t *testing.T) {
+	config := CircuitBreakerConfig{
+		Provider:         "Prometheus",
+		FailureThreshold: 1,
+		Cooldown:         "1m",
+	}
+	c, _ := newTestCircuitBreakers(config)
+	metric := newPrometheusMetric("http://prometheus:9090")
+	c.Record(metric, v1alpha1.AnalysisPhaseError)
+	assert.False(t, c.Allow(metric))
+
+	// an unchanged configuration keeps the state of the circuits
+	c.SetConfigs([]CircuitBreakerConfig{config})
+	assert.False(t, c.Allow(metric))
+
+	// a changed configuration closes the circuits
+	config.FailureThreshold = 2
+	c.SetConfigs([]CircuitBreakerConfig{config})
+	assert.Equal(t, circuitClosed, c.state(metric))
+	c.Record(metric, v1alpha1.AnalysisPhaseError)
+	assert.True(t, c.Allow(metric))
+
+	c.SetConfigs(nil)
+	c.Record(metric, v1alpha1.AnalysisPhaseError)
+	c.Record(metric, v1alpha1.AnalysisPhaseError)
+	assert.True(t, c.Allow(metric))
+}
+
+func TestOpenMeasurement(t *testing.T) {
+	c, _ := newTestCircuitBreakers(CircuitBreakerConfig{
+		Provider:         "Prometheus",
+		FailureThreshold: 1,
+		Cooldown:         "1m",
+	}, CircuitBreakerConfig{
+		Provider:         "WebMetric",
+		FailureThreshold: 1,
+		Cooldown:         "1m",
+		OpenPhase:        v1alpha1.AnalysisPhaseInconclusive,
+	})
+	measurement := c.OpenMeasurement(newPrometheusMetric("http://prometheus:9090"))
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "circuit breaker of the Prometheus provider is open after consecutive errors", measurement.Message)
+	assert.Equal(t, CircuitBreakerOpenValue, measurement.Metadata[CircuitBreakerMetadataKey])
+	assert.NotNil(t, measurement.StartedAt)
+	assert.NotNil(t, measurement.FinishedAt)
+
+	web := v1alpha1.Metric{
+		Name: "web",
+		Provider: v1alpha1.MetricProvider{
+			Web: &v1alpha1.WebMetric{URL: "http://metrics.example.com/api"},
+		},
+	}
+	assert.Equal(t, v1alpha1.AnalysisPhaseInconclusive, c.OpenMeasurement(web).Phase)
+}
+
+func TestGetCircuitBreakers(t *testing.T) {
+	configs, err := GetCircuitBreakers(k8sfake.NewSimpleClientset())
+	assert.NoError(t, err)
+	assert.Nil(t, configs)
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ConfigMapName,
+			Namespace: defaults.Namespace(),
+		},
+		Data: map[string]string{
+			CircuitBreakersConfigMapKey: `
+- provider: Prometheus
+  failureThreshold: 5
+  window: 5m
+  cooldown: 2m
+  openPhase: Inconclusive
+`,
+		},
+	}
+	configs, err = GetCircuitBreakers(k8sfake.NewSimpleClientset(cm))
+	assert.NoError(t, err)
+	assert.Equal(t, []CircuitBreakerConfig{
+		{Provider: "Prometheus", FailureThreshold: 5, Window: "5m", Cooldown: "2m", OpenPhase: v1alpha1.AnalysisPhaseInconclusive},
+	}, configs)
+
+	cm.Data[CircuitBreakersConfigMapKey] = `
+- provider: Prometheus
+  failureThreshold: 5
+`
+	_, err = GetCircuitBreakers(k8sfake.NewSimpleClientset(cm))
+	assert.EqualError(t, err, "invalid metricProviderCircuitBreakers in ConfigMap argo-rollouts-config: provider, failureThreshold and cooldown are required")
+
+	cm.Data[CircuitBreakersConfigMapKey] = `
+- provider: Prometheus
+  failureThreshold: 5
+  cooldown: 2m
+  openPhase: Failed
+`
+	_, err = GetCircuitBreakers(k8sfake.NewSimpleClientset(cm))
+	assert.EqualError(t, err, "invalid metricProviderCircuitBreakers in ConfigMap argo-rollouts-config: openPhase must be Error or Inconclusive")
+}
@@ -0,0 +1,527 @@
+package commitstatus
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/argoproj/argo-rollouts/utils/evaluate"
+	metricutil "github.com/argoproj/argo-rollouts/utils/metric"
+)
+
+const (
+	// ProviderType indicates the provider is the commit status of a VCS
+	ProviderType = "CommitStatus"
+	// DefaultGitHubAddress is the address of the GitHub API used when the metric does not specify one
+	DefaultGitHubAddress = "https://api.github.com"
+	// DefaultGitLabAddress is the address of the GitLab API used when the metric does not specify one
+	DefaultGitLabAddress = "https://gitlab.com/api/v4"
+	// defaultPollInterval is the delay between two polls when the metric does not specify one
+	defaultPollInterval = 30 * time.Second
+	// defaultResultTimeout is how long to wait for the pending checks when the metric does not specify it
+	defaultResultTimeout = 30 * time.Minute
+	// pageSize is the number of checks requested at once, which is the maximum of the APIs
+	pageSize = "100"
+	// maxPages is the maximum number of pages of checks requested for a commit
+	maxPages = 10
+
+	// StateSuccess is the state of a check which succeeded
+	StateSuccess = "success"
+	// StatePending is the state of a check which did not complete yet
+	StatePending = "pending"
+	// StateFailure is the state of a check which failed
+	StateFailure = "failure"
+)
+
+// combinedStatusResponse is the response of the combined status API of GitHub, holding the latest status of each
+// context of the commit
+type combinedStatusResponse struct {
+	Statuses []struct {
+		Context string `json:"context"`
+		State   string `json:"state"`
+	} `json:"statuses"`
+}
+
+// checkRunsResponse is the response of the check runs API of GitHub, holding the latest check run of each name of the
+// commit
+type checkRunsResponse struct {
+	CheckRuns []struct {
+		Name       string `json:"name"`
+		Status     string `json:"status"`
+		Conclusion string `json:"conclusion"`
+	} `json:"check_runs"`
+}
+
+// gitLabStatus is a commit status returned by the commit statuses API of GitLab
+type gitLabStatus struct {
+	Name         string `json:"name"`
+	Status       string `json:"status"`
+	AllowFailure bool   `json:"allow_failure"`
+}
+
+// Provider aggregates the checks of a GitHub or GitLab commit, polling the checks until none is pending
+// Implements the Provider Interface
+type Provider struct {
+	logCtx        log.Entry
+	client        *http.Client
+	kubeclientset kubernetes.Interface
+	// recordResponseBodies records the response bodies of failed requests in the measurement metadata
+	recordResponseBodies bool
+}
+
+// Type indicates provider is a CommitStatus provider
+func (p *Provider) Type() string {
+	return ProviderType
+}
+
+// Run starts the measurement and evaluates the checks of the commit if none is pending
+func (p *Provider) Run(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric) v1alpha1.Measurement {
+	startTime := metav1.Now()
+	measurement := v1alpha1.Measurement{
+		StartedAt: &startTime,
+	}
+	return p.poll(run, metric, measurement)
+}
+
+// Resume polls the checks of the commit again and evaluates them once none is pending
+func (p *Provider) Resume(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, measurement v1alpha1.Measurement) v1alpha1.Measurement {
+	return p.poll(run, metric, measurement)
+}
+
+// poll requests the checks of the commit. The measurement keeps running and is resumed after the poll interval while
+// a check is pending, until the result timeout expires
+func (p *Provider) poll(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, measurement v1alpha1.Measurement) v1alpha1.Measurement {
+	commitMetric := metric.Provider.CommitStatus
+	token, err := p.token(run.Namespace, commitMetric)
+	if err != nil {
+		return metricutil.MarkMeasurementError(measurement, err)
+	}
+	var checks map[string]string
+	switch commitMetric.VCS {
+	case v1alpha1.CommitStatusVCSGitHub:
+		checks, err = p.gitHubChecks(commitMetric, token)
+	case v1alpha1.CommitStatusVCSGitLab:
+		checks, err = p.gitLabChecks(commitMetric, token)
+	default:
+		err = fmt.Errorf("unsupported vcs '%s'", commitMetric.VCS)
+	}
+	if err != nil {
+		return metricutil.MarkMeasurementResponseError(measurement, err, p.recordResponseBodies, token)
+	}
+	checks = requiredChecks(checks, commitMetric.RequiredChecks)
+	state := aggregateState(checks)
+	if state == StatePending {
+		now := time.Now()
+		if now.Sub(measurement.StartedAt.Time) >= resultTimeout(commitMetric) {
+			return metricutil.MarkMeasurementError(measurement, fmt.Errorf("Checks of commit '%s' still pending after %s: %s", commitMetric.SHA, resultTimeout(commitMetric), pendingDescription(checks)))
+		}
+		resumeTime := metav1.NewTime(now.Add(pollInterval(commitMetric)))
+		measurement.Phase = v1alpha1.AnalysisPhaseRunning
+		measurement.ResumeAt = &resumeTime
+		return measurement
+	}
+
+	value := map[string]interface{}{
+		"state":  state,
+		"checks": checks,
+	}
+	valueBytes, err := json.Marshal(value)
+	if err != nil {
+		return metricutil.MarkMeasurementError(measurement, err)
+	}
+	measurement.Value = string(valueBytes)
+	if state == StateFailure {
+		measurement.Message = fmt.Sprintf("Checks of commit '%s' failed: %s", commitMetric.SHA, strings.Join(checksInState(checks, StateFailure), ", "))
+	}
+	if metric.SuccessCondition == "" && metric.FailureCondition == "" && metric.InconclusiveCondition == "" {
+		measurement.Phase = v1alpha1.AnalysisPhaseFailed
+		if state == StateSuccess {
+			measurement.Phase = v1alpha1.AnalysisPhaseSuccessful
+		}
+	} else {
+		phase, err := evaluate.EvaluateResult(value, metric, p.logCtx)
+		if err != nil {
+			return metricutil.MarkMeasurementError(measurement, err)
+		}
+		measurement.Phase = phase
+	}
+	measurement.ResumeAt = nil
+	finishedTime := metav1.Now()
+	measurement.FinishedAt = &finishedTime
+	return measurement
+}
+
+// token returns the token of the API read from the secret of the metric, or an empty token if the metric does not
+// reference one
+func (p *Provider) token(namespace string, metric *v1alpha1.CommitStatusMetric) (string, error) {
+	ref := metric.TokenSecretRef
+	if ref == nil {
+		return "", nil
+	}
+	secret, err := p.kubeclientset.CoreV1().Secrets(namespace).Get(ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	token, ok := secret.Data[ref.Key]
+	if !ok {
+		return "", fmt.Errorf("key '%s' does not exist in secret '%s'", ref.Key, ref.Name)
+	}
+	return strings.TrimSpace(string(token)), nil
+}
+
+// gitHubChecks returns the state of the commit statuses and of the check runs of a GitHub commit by name. A name
+// reported by both takes the worst of their states
+func (p *Provider) gitHubChecks(metric *v1alpha1.CommitStatusMetric, token string) (map[string]string, error) {
+	headers := map[string]string{"Accept": "application/vnd.github+json"}
+	if token != "" {
+		headers["Authorization"] = "Bearer " + token
+	}
+	query := url.Values{}
+	query.Set("per_page", pageSize)
+	repository := strings.Split(metric.Repository, "/")
+	checks := map[string]string{}
+
+	statusURL, err := apiURL(Address(metric), query, append(append([]string{"repos"}, repository...), "commits", metric.SHA, "status")...)
+	if err != nil {
+		return nil, err
+	}
+	err = p.getPages(statusURL, headers, func(body []byte) error {
+		var statuses combinedStatusResponse
+		if err := json.Unmarshal(body, &statuses); err != nil {
+			return err
+		}
+		for _, status := range statuses.Statuses {
+			state := StatePending
+			switch status.State {
+			case "success":
+				state = StateSuccess
+			case "failure", "error":
+				state = StateFailure
+			}
+			addCheck(checks, status.Context, state)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	checkRunsURL, err := apiURL(Address(metric), query, append(append([]string{"repos"}, repository...), "commits", metric.SHA, "check-runs")...)
+	if err != nil {
+		return nil, err
+	}
+	err = p.getPages(checkRunsURL, headers, func(body []byte) error {
+		var checkRuns checkRunsResponse
+		if err := json.Unmarshal(body, &checkRuns); err != nil {
+			return err
+		}
+		for _, checkRun := range checkRuns.CheckRuns {
+			state := StatePending
+			if checkRun.Status == "completed" {
+				switch checkRun.Conclusion {
+				case "success", "neutral", "skipped":
+					state = StateSuccess
+				default:
+					state = StateFailure
+				}
+			}
+			addCheck(checks, checkRun.Name, state)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return checks, nil
+}
+
+// gitLabChecks returns the state of the latest commit statuses of a GitLab commit by name. The failed statuses of the
+// jobs allowed to fail succeed
+func (p *Provider) gitLabChecks(metric *v1alpha1.CommitStatusMetric, token string) (map[string]string, error) {
+	headers := map[string]string{}
+	if token != "" {
+		headers["PRIVATE-TOKEN"] = token
+	}
+	query := url.Values{}
+	query.Set("per_page", pageSize)
+	statusesURL, err := apiURL(Address(metric), query, "projects", metric.Repository, "repository", "commits", metric.SHA, "statuses")
+	if err != nil {
+		return nil, err
+	}
+	checks := map[string]string{}
+	err = p.getPages(statusesURL, headers, func(body []byte) error {
+		var statuses []gitLabStatus
+		if err := json.Unmarshal(body, &statuses); err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := StatePending
+			switch status.Status {
+			case "success", "skipped":
+				state = StateSuccess
+			case "failed", "canceled":
+				state = StateFailure
+				if status.AllowFailure {
+					state = StateSuccess
+				}
+			}
+			addCheck(checks, status.Name, state)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return checks, nil
+}
+
+// getPages requests the URL with the headers and passes the JSON body of the response to the decode function, then
+// does the same for each next page of the response, which is linked by the Link header of GitHub or numbered by the
+// X-Next-Page header of GitLab
+func (p *Provider) getPages(requestURL string, headers map[string]string, decode func(body []byte) error) error {
+	for page := 1; requestURL != ""; page++ {
+		if page > maxPages {
+			return fmt.Errorf("more than %d pages of checks", maxPages)
+		}
+		bodyBytes, nextURL, err := p.get(requestURL, headers)
+		if err != nil {
+			return err
+		}
+		if err := decode(bodyBytes); err != nil {
+			return &metricutil.ResponseError{Err: fmt.Errorf("Could not parse JSON body: %v", err), Body: bodyBytes}
+		}
+		requestURL = nextURL
+	}
+	return nil
+}
+
+// get requests the URL with the headers and returns the body of the response and the URL of its next page, if any
+func (p *Provider) get(requestURL string, headers map[string]string) ([]byte, string, error) {
+	request, err := http.NewRequest(http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	for key, headerValue := range headers {
+		request.Header.Set(key, headerValue)
+	}
+	response, err := p.client.Do(request)
+	if err != nil {
+		return nil, "", err
+	}
+	defer response.Body.Close()
+
+	bodyBytes, err := metricutil.ReadResponseBody(response.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return nil, "", &metricutil.ResponseError{Err: fmt.Errorf("received non 2xx response code: %v", response.StatusCode), Body: bodyBytes}
+	}
+	nextURL, err := nextPageURL(request.URL, response.Header)
+	if err != nil {
+		return nil, "", err
+	}
+	return bodyBytes, nextURL, nil
+}
+
+// nextPageURL returns the URL of the page following the response to the request URL, or an empty URL on the last
+// page. The next page must be on the host of the request, which the token of the metric is sent to.
+func nextPageURL(requestURL *url.URL, header http.Header) (string, error) {
+	if page := header.Get("X-Next-Page"); page != "" {
+		next := *requestURL
+		query := next.Query()
+		query.Set("page", page)
+		next.RawQuery = query.Encode()
+		return next.String(), nil
+	}
+	link := nextLink(header.Get("Link"))
+	if link == "" {
+		return "", nil
+	}
+	next, err := requestURL.Parse(link)
+	if err != nil {
+		return "", fmt.Errorf("invalid next page link '%s': %v", link, err)
+	}
+	if next.Scheme != requestURL.Scheme || next.Host != requestURL.Host {
+		return "", fmt.Errorf("next page link '%s' is not on the host of the API", link)
+	}
+	return next.String(), nil
+}
+
+// nextLink returns the link of the rel="next" relation of a Link header, such as
+// `<https://api.github.com/repositories/1/commits/sha/check-runs?page=2>; rel="next"`
+func nextLink(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		parts := strings.Split(link, ";")
+		for _, param := range parts[1:] {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(parts[0]), "<>")
+			}
+		}
+	}
+	return ""
+}
+
+// apiURL returns the URL of the API at the address followed by the path segments, which are escaped so that a
+// segment holding a slash, such as the path of a GitLab project, remains a single segment
+func apiURL(address string, query url.Values, segments ...string) (string, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", err
+	}
+	path := strings.TrimSuffix(u.Path, "/")
+	rawPath := strings.TrimSuffix(u.EscapedPath(), "/")
+	for _, segment := range segments {
+		path += "/" + segment
+		rawPath += "/" + url.PathEscape(segment)
+	}
+	u.Path = path
+	u.RawPath = rawPath
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// addCheck records the state of the check, keeping the worst state of a check reported more than once
+func addCheck(checks map[string]string, name, state string) {
+	if current, ok := checks[name]; ok && severity(current) >= severity(state) {
+		return
+	}
+	checks[name] = state
+}
+
+func severity(state string) int {
+	switch state {
+	case StateFailure:
+		return 2
+	case StatePending:
+		return 1
+	}
+	return 0
+}
+
+// requiredChecks returns the required checks, which are pending when they are not reported yet. All the checks are
+// required when the metric does not list any
+func requiredChecks(checks map[string]string, required []string) map[string]string {
+	if len(required) == 0 {
+		return checks
+	}
+	filtered := make(map[string]string, len(required))
+	for _, name := range required {
+		state, ok := checks[name]
+		if !ok {
+			state = StatePending
+		}
+		filtered[name] = state
+	}
+	return filtered
+}
+
+// aggregateState returns failure when a check failed, pending when a check is pending or when no check is reported
+// yet, and success when all the checks succeeded
+func aggregateState(checks map[string]string) string {
+	if len(checks) == 0 {
+		return StatePending
+	}
+	state := StateSuccess
+	for _, checkState := range checks {
+		if severity(checkState) > severity(state) {
+			state = checkState
+		}
+	}
+	return state
+}
+
+// checksInState returns the sorted names of the checks in the state
+func checksInState(checks map[string]string, state string) []string {
+	var names []string
+	for name, checkState := range checks {
+		if checkState == state {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func pendingDescription(checks map[string]string) string {
+	if len(checks) == 0 {
+		return "no check reported"
+	}
+	return strings.Join(checksInState(checks, StatePending), ", ")
+}
+
+// Address returns the address of the API of the metric
+func Address(metric *v1alpha1.CommitStatusMetric) string {
+	if metric.Address != "" {
+		return metric.Address
+	}
+	if metric.VCS == v1alpha1.CommitStatusVCSGitLab {
+		return DefaultGitLabAddress
+	}
+	return DefaultGitHubAddress
+}
+
+func pollInterval(metric *v1alpha1.CommitStatusMetric) time.Duration {
+	if metric.PollIntervalSeconds <= 0 {
+		return defaultPollInterval
+	}
+	return time.Duration(metric.PollIntervalSeconds) * time.Second
+}
+
+func resultTimeout(metric *v1alpha1.CommitStatusMetric) time.Duration {
+	if metric.ResultTimeoutSeconds <= 0 {
+		return defaultResultTimeout
+	}
+	return time.Duration(metric.ResultTimeoutSeconds) * time.Second
+}
+
+// Terminate stops waiting for the pending checks of the commit
+func (p *Provider) Terminate(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, measurement v1alpha1.Measurement) v1alpha1.Measurement {
+	now := metav1.Now()
+	measurement.FinishedAt = &now
+	measurement.Phase = v1alpha1.AnalysisPhaseSuccessful
+	measurement.ResumeAt = nil
+	p.logCtx.Infof("stopped waiting for the checks of commit '%s'", metric.Provider.CommitStatus.SHA)
+	return measurement
+}
+
+// GarbageCollect is a no-op for the CommitStatus provider
+func (p *Provider) GarbageCollect(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, limit int) error {
+	return nil
+}
+
+// NewCommitStatusHttpClient returns a http client using the timeout of the metric
+func NewCommitStatusHttpClient(metric v1alpha1.Metric) *http.Client {
+	var timeout time.Duration
+
+	// Using a default timeout of 10 seconds
+	if metric.Provider.CommitStatus.TimeoutSeconds <= 0 {
+		timeout = time.Duration(10) * time.Second
+	} else {
+		timeout = time.Duration(metric.Provider.CommitStatus.TimeoutSeconds) * time.Second
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+	}
+}
+
+// NewCommitStatusProvider creates a new CommitStatus provider reading the tokens with the kubeclientset. When
+// recordResponseBodies is true, the response bodies of the failed requests are recorded in the measurement metadata
+func NewCommitStatusProvider(logCtx log.Entry, client *http.Client, kubeclientset kubernetes.Interface, recordResponseBodies bool) *Provider {
+	return &Provider{
+		logCtx:               logCtx,
+		client:               client,
+		kubeclientset:        kubeclientset,
+		recordResponseBodies: recordResponseBodies,
+	}
+}
@@ -0,0 +1,400 @@
+package commitstatus
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	metricutil "github.com/argoproj/argo-rollouts/utils/metric"
+)
+
+const sha = "6dcb09b5b57875f334f61aebed695e2e4193db5e"
+
+func newRun() *v1alpha1.AnalysisRun {
+	return &v1alpha1.AnalysisRun{ObjectMeta: metav1.ObjectMeta{Name: "guestbook-6c54544bf9-2", Namespace: "default"}}
+}
+
+func newMetric(vcs v1alpha1.CommitStatusVCS, address string) v1alpha1.Metric {
+	return v1alpha1.Metric{
+		Name: "required-checks",
+		Provider: v1alpha1.MetricProvider{
+			CommitStatus: &v1alpha1.CommitStatusMetric{
+				VCS:            vcs,
+				Address:        address,
+				Repository:     "argoproj/argo-rollouts",
+				SHA:            sha,
+				TokenSecretRef: &v1alpha1.SecretKeyRef{Name: "vcs", Key: "token"},
+			},
+		},
+	}
+}
+
+func newTestProvider(metric v1alpha1.Metric) *Provider {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "vcs", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("my-token\n")},
+	}
+	return NewCommitStatusProvider(*log.WithField("", ""), NewCommitStatusHttpClient(metric), k8sfake.NewSimpleClientset(secret), false)
+}
+
+// newServer returns a stub API responding to the requests of each path with the responses in turn, repeating the last
+// one, and counting the requests of each path
+func newServer(t *testing.T, status int, responses map[string][]string) (*httptest.Server, map[string]int) {
+	requests := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		pathResponses, ok := responses[req.URL.EscapedPath()]
+		if !assert.True(t, ok, "unexpected request of %s", req.URL.EscapedPath()) {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		assert.Equal(t, "100", req.URL.Query().Get("per_page"))
+		if req.Header.Get("PRIVATE-TOKEN") == "" {
+			assert.Equal(t, "Bearer my-token", req.Header.Get("Authorization"))
+		} else {
+			assert.Equal(t, "my-token", req.Header.Get("PRIVATE-TOKEN"))
+		}
+		response := pathResponses[len(pathResponses)-1]
+		if requests[req.URL.EscapedPath()] < len(pathResponses) {
+			response = pathResponses[requests[req.URL.EscapedPath()]]
+		}
+		requests[req.URL.EscapedPath()]++
+		rw.Header().Set("Content-Type", "application/json")
+		rw.WriteHeader(status)
+		io.WriteString(rw, response)
+	}))
+	return server, requests
+}
+
+const (
+	statusPath    = "/repos/argoproj/argo-rollouts/commits/" + sha + "/status"
+	checkRunsPath = "/repos/argoproj/argo-rollouts/commits/" + sha + "/check-runs"
+	gitLabPath    = "/projects/argoproj%2Fargo-rollouts/repository/commits/" + sha + "/statuses"
+)
+
+func TestType(t *testing.T) {
+	p := newTestProvider(newMetric(v1alpha1.CommitStatusVCSGitHub, ""))
+	assert.Equal(t, ProviderType, p.Type())
+}
+
+func TestRunGitHubSuccess(t *testing.T) {
+	server, _ := newServer(t, 200, map[string][]string{
+		statusPath:    {`{"state":"success","statuses":[{"context":"ci/build","state":"success"}]}`},
+		checkRunsPath: {`{"total_count":2,"check_runs":[{"name":"unit-tests","status":"completed","conclusion":"success"},{"name":"lint","status":"completed","conclusion":"skipped"}]}`},
+	})
+	defer server.Close()
+	metric := newMetric(v1alpha1.CommitStatusVCSGitHub, server.URL)
+	p := newTestProvider(metric)
+
+	measurement := p.Run(newRun(), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+	assert.Equal(t, `{"checks":{"ci/build":"success","lint":"success","unit-tests":"success"},"state":"success"}`, measurement.Value)
+	assert.Empty(t, measurement.Message)
+	assert.NotNil(t, measurement.StartedAt)
+	assert.NotNil(t, measurement.FinishedAt)
+	assert.Nil(t, measurement.ResumeAt)
+}
+
+func TestRunGitHubFailure(t *testing.T) {
+	server, _ := newServer(t, 200, map[string][]string{
+		statusPath:    {`{"state":"failure","statuses":[{"context":"security/scan","state":"error"},{"context":"ci/build","state":"success"}]}`},
+		checkRunsPath: {`{"check_runs":[{"name":"unit-tests","status":"completed","conclusion":"timed_out"},{"name":"e2e","status":"in_progress","conclusion":null}]}`},
+	})
+	defer server.Close()
+	metric := newMetric(v1alpha1.CommitStatusVCSGitHub, server.URL)
+	p := newTestProvider(metric)
+
+	// A failed check fails the measurement without waiting for the pending checks
+	measurement := p.Run(newRun(), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, measurement.Phase)
+	assert.Equal(t, "Checks of commit '"+sha+"' failed: security/scan, unit-tests", measurement.Message)
+	assert.Contains(t, measurement.Value, `"state":"failure"`)
+	assert.Contains(t, measurement.Value, `"e2e":"pending"`)
+}
+
+func TestRunRequiredChecks(t *testing.T) {
+	server, requests := newServer(t, 200, map[string][]string{
+		statusPath: {`{"statuses":[{"context":"flaky/integration","state":"failure"}]}`},
+		checkRunsPath: {
+			`{"check_runs":[{"name":"unit-tests","status":"queued"}]}`,
+			`{"check_runs":[{"name":"unit-tests","status":"completed","conclusion":"success"}]}`,
+			`{"check_runs":[{"name":"unit-tests","status":"completed","conclusion":"success"},{"name":"deploy/staging","status":"completed","conclusion":"neutral"}]}`,
+		},
+	})
+	defer server.Close()
+	metric := newMetric(v1alpha1.CommitStatusVCSGitHub, server.URL)
+	metric.Provider.CommitStatus.RequiredChecks = []string{"unit-tests", "deploy/staging"}
+	metric.Provider.CommitStatus.PollIntervalSeconds = 60
+	p := newTestProvider(metric)
+
+	measurement := p.Run(newRun(), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseRunning, measurement.Phase)
+	assert.Nil(t, measurement.FinishedAt)
+	assert.NotNil(t, measurement.ResumeAt)
+	assert.True(t, measurement.ResumeAt.After(time.Now().Add(50*time.Second)))
+
+	// A required check which is not reported yet is pending
+	measurement = p.Resume(newRun(), metric, measurement)
+	assert.Equal(t, v1alpha1.AnalysisPhaseRunning, measurement.Phase)
+
+	// The failure of a check which is not required is ignored
+	measurement = p.Resume(newRun(), metric, measurement)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+	assert.Equal(t, `{"checks":{"deploy/staging":"success","unit-tests":"success"},"state":"success"}`, measurement.Value)
+	assert.NotNil(t, measurement.FinishedAt)
+	assert.Nil(t, measurement.ResumeAt)
+	assert.Equal(t, 3, requests[checkRunsPath])
+}
+
+func TestRunGitLab(t *testing.T) {
+	server, _ := newServer(t, 200, map[string][]string{
+		gitLabPath: {
+			`[]`,
+			`[{"name":"build","status":"success"},{"name":"test","status":"running"}]`,
+			`[{"name":"build","status":"success"},{"name":"test","status":"success"},{"name":"audit","status":"failed","allow_failure":true}]`,
+		},
+	})
+	defer server.Close()
+	metric := newMetric(v1alpha1.CommitStatusVCSGitLab, server.URL)
+	p := newTestProvider(metric)
+
+	// A commit without any check is pending
+	measurement := p.Run(newRun(), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseRunning, measurement.Phase)
+	measurement = p.Resume(newRun(), metric, measurement)
+	assert.Equal(t, v1alpha1.AnalysisPhaseRunning, measurement.Phase)
+
+	// The failure of a job allowed to fail succeeds
+	measurement = p.Resume(newRun(), metric, measurement)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+	assert.Equal(t, `{"checks":{"audit":"success","build":"success","test":"success"},"state":"success"}`, measurement.Value)
+}
+
+func TestRunGitLabFailure(t *testing.T) {
+	server, _ := newServer(t, 200, map[string][]string{
+		gitLabPath: {`[{"name":"build","status":"success"},{"name":"test","status":"canceled"}]`},
+	})
+	defer server.Close()
+	metric := newMetric(v1alpha1.CommitStatusVCSGitLab, server.URL)
+	p := newTestProvider(metric)
+
+	measurement := p.Run(newRun(), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, measurement.Phase)
+	assert.Equal(t, "Checks of commit '"+sha+"' failed: test", measurement.Message)
+}
+
+// newPagedServer returns a stub API responding to the requests of each path with the page of the page parameter, and
+// linking the next page with the X-Next-Page header of GitLab or the Link header of GitHub
+func newPagedServer(t *testing.T, vcs v1alpha1.CommitStatusVCS, pages map[string][]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		pathPages, ok := pages[req.URL.EscapedPath()]
+		if !assert.True(t, ok, "unexpected request of %s", req.URL.EscapedPath()) {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		assert.Equal(t, "100", req.URL.Query().Get("per_page"))
+		page := 1
+		if req.URL.Query().Get("page") != "" {
+			page, _ = strconv.Atoi(req.URL.Query().Get("page"))
+		}
+		if page < len(pathPages) {
+			if vcs == v1alpha1.CommitStatusVCSGitLab {
+				rw.Header().Set("X-Next-Page", strconv.Itoa(page+1))
+			} else {
+				next := fmt.Sprintf("http://%s%s?per_page=100&page=%d", req.Host, req.URL.EscapedPath(), page+1)
+				last := fmt.Sprintf("http://%s%s?per_page=100&page=%d", req.Host, req.URL.EscapedPath(), len(pathPages))
+				rw.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next", <%s>; rel="last"`, next, last))
+			}
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		io.WriteString(rw, pathPages[page-1])
+	}))
+}
+
+func TestRunGitHubPages(t *testing.T) {
+	server := newPagedServer(t, v1alpha1.CommitStatusVCSGitHub, map[string][]string{
+		statusPath: {
+			`{"state":"success","statuses":[{"context":"ci/build","state":"success"}]}`,
+			`{"state":"success","statuses":[{"context":"ci/deploy","state":"success"}]}`,
+		},
+		checkRunsPath: {
+			`{"total_count":2,"check_runs":[{"name":"unit-tests","status":"completed","conclusion":"success"}]}`,
+			`{"total_count":2,"check_runs":[{"name":"e2e","status":"completed","conclusion":"failure"}]}`,
+		},
+	})
+	defer server.Close()
+	metric := newMetric(v1alpha1.CommitStatusVCSGitHub, server.URL)
+	p := newTestProvider(metric)
+
+	// The checks of the second pages are aggregated with the checks of the first pages
+	measurement := p.Run(newRun(), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, measurement.Phase)
+	assert.Equal(t, `{"checks":{"ci/build":"success","ci/deploy":"success","e2e":"failure","unit-tests":"success"},"state":"failure"}`, measurement.Value)
+}
+
+func TestRunGitLabPages(t *testing.T) {
+	server := newPagedServer(t, v1alpha1.CommitStatusVCSGitLab, map[string][]string{
+		gitLabPath: {
+			`[{"name":"build","status":"success"}]`,
+			`[{"name":"test","status":"running"}]`,
+		},
+	})
+	defer server.Close()
+	metric := newMetric(v1alpha1.CommitStatusVCSGitLab, server.URL)
+	p := newTestProvider(metric)
+
+	// The pending check of the second page keeps the measurement running
+	measurement := p.Run(newRun(), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseRunning, measurement.Phase)
+}
+
+func TestNextPageURL(t *testing.T) {
+	requestURL, _ := url.Parse("https://api.github.com/repos/argoproj/argo-rollouts/commits/" + sha + "/status?per_page=100")
+
+	next, err := nextPageURL(requestURL, http.Header{})
+	assert.NoError(t, err)
+	assert.Empty(t, next)
+
+	header := http.Header{}
+	header.Set("Link", `<https://api.github.com/repositories/1/commits/`+sha+`/status?per_page=100&page=1>; rel="prev", <https://api.github.com/repositories/1/commits/`+sha+`/status?per_page=100&page=3>; rel="next"`)
+	next, err = nextPageURL(requestURL, header)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://api.github.com/repositories/1/commits/"+sha+"/status?per_page=100&page=3", next)
+
+	header = http.Header{}
+	header.Set("X-Next-Page", "2")
+	next, err = nextPageURL(requestURL, header)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://api.github.com/repos/argoproj/argo-rollouts/commits/"+sha+"/status?page=2&per_page=100", next)
+
+	// The token of the metric is not sent to another host
+	header = http.Header{}
+	header.Set("Link", `<https://attacker.example.com/page=2>; rel="next"`)
+	_, err = nextPageURL(requestURL, header)
+	assert.EqualError(t, err, "next page link 'https://attacker.example.com/page=2' is not on the host of the API")
+}
+
+func TestRunEvaluatesConditions(t *testing.T) {
+	server, _ := newServer(t, 200, map[string][]string{
+		gitLabPath: {`[{"name":"build","status":"success"},{"name":"audit","status":"failed"}]`},
+	})
+	defer server.Close()
+	metric := newMetric(v1alpha1.CommitStatusVCSGitLab, server.URL)
+	metric.SuccessCondition = "result.checks.build == 'success'"
+	p := newTestProvider(metric)
+
+	measurement := p.Run(newRun(), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+
+	metric.SuccessCondition = "result.state == 'success'"
+	measurement = p.Run(newRun(), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, measurement.Phase)
+}
+
+func TestResumeResultTimeout(t *testing.T) {
+	server, _ := newServer(t, 200, map[string][]string{
+		gitLabPath: {`[{"name":"build","status":"success"},{"name":"test","status":"pending"}]`},
+	})
+	defer server.Close()
+	metric := newMetric(v1alpha1.CommitStatusVCSGitLab, server.URL)
+	metric.Provider.CommitStatus.ResultTimeoutSeconds = 300
+	p := newTestProvider(metric)
+
+	startedAt := metav1.NewTime(time.Now().Add(-5 * time.Minute))
+	measurement := v1alpha1.Measurement{
+		Phase:     v1alpha1.AnalysisPhaseRunning,
+		StartedAt: &startedAt,
+	}
+	measurement = p.Resume(newRun(), metric, measurement)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "Checks of commit '"+sha+"' still pending after 5m0s: test", measurement.Message)
+}
+
+func TestRunNon2xxResponse(t *testing.T) {
+	server, _ := newServer(t, 401, map[string][]string{
+		statusPath: {`{"message":"Bad credentials my-token"}`},
+	})
+	defer server.Close()
+	metric := newMetric(v1alpha1.CommitStatusVCSGitHub, server.URL)
+	p := newTestProvider(metric)
+	p.recordResponseBodies = true
+
+	measurement := p.Run(newRun(), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "received non 2xx response code: 401", measurement.Message)
+	assert.Equal(t, `{"message":"Bad credentials <redacted>"}`, measurement.Metadata[metricutil.ResponseBodyMetadataKey])
+}
+
+func TestRunInvalidJSON(t *testing.T) {
+	server, _ := newServer(t, 200, map[string][]string{
+		gitLabPath: {`not json`},
+	})
+	defer server.Close()
+	metric := newMetric(v1alpha1.CommitStatusVCSGitLab, server.URL)
+	p := newTestProvider(metric)
+
+	measurement := p.Run(newRun(), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Contains(t, measurement.Message, "Could not parse JSON body")
+}
+
+func TestRunTokenSecret(t *testing.T) {
+	metric := newMetric(v1alpha1.CommitStatusVCSGitHub, "http://127.0.0.1")
+	p := newTestProvider(metric)
+
+	// The secret is read from the namespace of the AnalysisRun
+	run := newRun()
+	run.Namespace = "other"
+	measurement := p.Run(run, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, `secrets "vcs" not found`, measurement.Message)
+
+	metric.Provider.CommitStatus.TokenSecretRef.Key = "password"
+	measurement = p.Run(newRun(), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "key 'password' does not exist in secret 'vcs'", measurement.Message)
+}
+
+func TestTerminate(t *testing.T) {
+	metric := newMetric(v1alpha1.CommitStatusVCSGitHub, "")
+	p := newTestProvider(metric)
+	startedAt := metav1.Now()
+	resumeAt := metav1.NewTime(time.Now().Add(time.Minute))
+	measurement := v1alpha1.Measurement{
+		Phase:     v1alpha1.AnalysisPhaseRunning,
+		StartedAt: &startedAt,
+		ResumeAt:  &resumeAt,
+	}
+	measurement = p.Terminate(newRun(), metric, measurement)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+	assert.NotNil(t, measurement.FinishedAt)
+	assert.Nil(t, measurement.ResumeAt)
+}
+
+func TestAddress(t *testing.T) {
+	assert.Equal(t, DefaultGitHubAddress, Address(&v1alpha1.CommitStatusMetric{VCS: v1alpha1.CommitStatusVCSGitHub}))
+	assert.Equal(t, DefaultGitLabAddress, Address(&v1alpha1.CommitStatusMetric{VCS: v1alpha1.CommitStatusVCSGitLab}))
+	assert.Equal(t, "https://github.example.com/api/v3", Address(&v1alpha1.CommitStatusMetric{VCS: v1alpha1.CommitStatusVCSGitHub, Address: "https://github.example.com/api/v3"}))
+}
+
+func TestAPIURL(t *testing.T) {
+	u, err := apiURL(DefaultGitLabAddress+"/", nil, "projects", "group/project", "repository", "commits", sha, "statuses")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://gitlab.com/api/v4/projects/group%2Fproject/repository/commits/"+sha+"/statuses", u)
+}
+
+func TestGarbageCollect(t *testing.T) {
+	p := newTestProvider(newMetric(v1alpha1.CommitStatusVCSGitHub, ""))
+	assert.NoError(t, p.GarbageCollect(nil, v1alpha1.Metric{}, 0))
+}
@@ -0,0 +1,373 @@
+package datadog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/argoproj/argo-rollouts/utils/evaluate"
+	metricutil "github.com/argoproj/argo-rollouts/utils/metric"
+)
+
+const (
+	// ProviderType indicates the provider is Datadog
+	ProviderType = "Datadog"
+	// DefaultAddress is the address of the Datadog API used when the metric does not specify one
+	DefaultAddress = "https://api.datadoghq.com"
+	// DefaultTimeframe is the timeframe of the SLO used when the metric does not specify one
+	DefaultTimeframe = "30d"
+	// DefaultLogsTimeframe is the duration over which the logs are counted when the metric does not specify one
+	DefaultLogsTimeframe = 5 * time.Minute
+	// logsStatusDone is the status of a logs aggregation which completed
+	logsStatusDone = "done"
+)
+
+// timeframes are the durations of the timeframes supported by the SLOs of Datadog
+var timeframes = map[string]time.Duration{
+	"7d":  7 * 24 * time.Hour,
+	"30d": 30 * 24 * time.Hour,
+	"90d": 90 * 24 * time.Hour,
+}
+
+// sloHistoryResponse is the response of the SLO history API of Datadog
+type sloHistoryResponse struct {
+	Data struct {
+		Overall struct {
+			SLIValue             *float64           `json:"sli_value"`
+			ErrorBudgetRemaining map[string]float64 `json:"error_budget_remaining"`
+		} `json:"overall"`
+		Thresholds map[string]struct {
+			Target float64 `json:"target"`
+		} `json:"thresholds"`
+	} `json:"data"`
+	Errors []struct {
+		Error string `json:"error"`
+	} `json:"errors"`
+}
+
+// logsAggregateRequest is the request of the logs aggregate API of Datadog
+type logsAggregateRequest struct {
+	Compute []logsCompute `json:"compute"`
+	Filter  logsFilter    `json:"filter"`
+}
+
+type logsCompute struct {
+	Aggregation string `json:"aggregation"`
+}
+
+type logsFilter struct {
+	Query string `json:"query"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// logsAggregateResponse is the response of the logs aggregate API of Datadog
+type logsAggregateResponse struct {
+	Data struct {
+		Buckets []struct {
+			Computes map[string]float64 `json:"computes"`
+		} `json:"buckets"`
+	} `json:"data"`
+	Meta struct {
+		Status string `json:"status"`
+	} `json:"meta"`
+	Errors []string `json:"errors"`
+}
+
+// Provider evaluates the status of a Datadog SLO over its timeframe, or the count of the logs matching a query
+// Implements the Provider Interface
+type Provider struct {
+	logCtx log.Entry
+	client *http.Client
+	// recordResponseBodies records the response bodies of failed requests in the measurement metadata
+	recordResponseBodies bool
+}
+
+// Type indicates provider is a Datadog provider
+func (p *Provider) Type() string {
+	return ProviderType
+}
+
+// Run requests the history of the SLO over its timeframe and evaluates its SLI value and remaining error budget
+func (p *Provider) Run(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric) v1alpha1.Measurement {
+	startTime := metav1.Now()
+	measurement := v1alpha1.Measurement{
+		StartedAt: &startTime,
+	}
+	datadogMetric := metric.Provider.Datadog
+	if datadogMetric.Logs != nil {
+		return p.runLogs(measurement, metric)
+	}
+	timeframe := Timeframe(datadogMetric)
+	history, err := p.sloHistory(datadogMetric, timeframe, startTime.Time)
+	if err != nil {
+		return p.markError(measurement, datadogMetric, err)
+	}
+	if history.Data.Overall.SLIValue == nil {
+		return metricutil.MarkMeasurementError(measurement, fmt.Errorf("SLO '%s' has no data over the timeframe '%s'", datadogMetric.SLOID, timeframe))
+	}
+	errorBudgetRemaining, ok := history.Data.Overall.ErrorBudgetRemaining[timeframe]
+	if !ok {
+		return metricutil.MarkMeasurementError(measurement, fmt.Errorf("SLO '%s' has no error budget over the timeframe '%s'", datadogMetric.SLOID, timeframe))
+	}
+
+	value := map[string]interface{}{
+		"sliValue":             *history.Data.Overall.SLIValue,
+		"errorBudgetRemaining": errorBudgetRemaining,
+		"timeframe":            timeframe,
+	}
+	if threshold, ok := history.Data.Thresholds[timeframe]; ok {
+		value["target"] = threshold.Target
+	}
+	valueBytes, err := json.Marshal(value)
+	if err != nil {
+		return metricutil.MarkMeasurementError(measurement, err)
+	}
+	measurement.Value = string(valueBytes)
+	if metric.SuccessCondition == "" && metric.FailureCondition == "" && metric.InconclusiveCondition == "" {
+		measurement.Phase = v1alpha1.AnalysisPhaseSuccessful
+		if errorBudgetRemaining <= 0 {
+			measurement.Phase = v1alpha1.AnalysisPhaseFailed
+			measurement.Message = fmt.Sprintf("error budget of SLO '%s' is exhausted over the timeframe '%s'", datadogMetric.SLOID, timeframe)
+		}
+	} else {
+		phase, err := evaluate.EvaluateResult(value, metric, p.logCtx)
+		if err != nil {
+			return metricutil.MarkMeasurementError(measurement, err)
+		}
+		measurement.Phase = phase
+	}
+	finishedTime := metav1.Now()
+	measurement.FinishedAt = &finishedTime
+	return measurement
+}
+
+// runLogs counts the logs matching the query of the metric over its timeframe, and evaluates the count
+func (p *Provider) runLogs(measurement v1alpha1.Measurement, metric v1alpha1.Metric) v1alpha1.Measurement {
+	datadogMetric := metric.Provider.Datadog
+	timeframe, err := LogsTimeframe(datadogMetric.Logs)
+	if err != nil {
+		return metricutil.MarkMeasurementError(measurement, err)
+	}
+	count, err := p.countLogs(datadogMetric, timeframe, measurement.StartedAt.Time)
+	if err != nil {
+		return p.markError(measurement, datadogMetric, err)
+	}
+	measurement.Value = strconv.FormatFloat(count, 'f', -1, 64)
+	phase, err := evaluate.EvaluateResult(count, metric, p.logCtx)
+	if err != nil {
+		return metricutil.MarkMeasurementError(measurement, err)
+	}
+	measurement.Phase = phase
+	finishedTime := metav1.Now()
+	measurement.FinishedAt = &finishedTime
+	return measurement
+}
+
+// countLogs requests the count of the logs matching the query of the metric over the timeframe ending at the time,
+// summing the counts of the buckets of the aggregation
+func (p *Provider) countLogs(metric *v1alpha1.DatadogMetric, timeframe time.Duration, to time.Time) (float64, error) {
+	aggregateURL, err := apiURL(metric, "/api/v2/logs/analytics/aggregate")
+	if err != nil {
+		return 0, err
+	}
+	body, err := json.Marshal(logsAggregateRequest{
+		Compute: []logsCompute{{Aggregation: "count"}},
+		Filter: logsFilter{
+			Query: metric.Logs.Query,
+			From:  strconv.FormatInt(to.Add(-timeframe).UnixNano()/int64(time.Millisecond), 10),
+			To:    strconv.FormatInt(to.UnixNano()/int64(time.Millisecond), 10),
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+	bodyBytes, err := p.do(metric, http.MethodPost, aggregateURL.String(), body)
+	if err != nil {
+		return 0, err
+	}
+	var aggregate logsAggregateResponse
+	if err := json.Unmarshal(bodyBytes, &aggregate); err != nil {
+		return 0, &metricutil.ResponseError{Err: fmt.Errorf("Could not parse JSON body: %v", err), Body: bodyBytes}
+	}
+	if len(aggregate.Errors) > 0 {
+		return 0, &metricutil.ResponseError{Err: errors.New(strings.Join(aggregate.Errors, "; ")), Body: bodyBytes}
+	}
+	// the counts of an aggregation which did not complete, e.g. which timed out, are partial
+	if aggregate.Meta.Status != "" && aggregate.Meta.Status != logsStatusDone {
+		return 0, &metricutil.ResponseError{Err: fmt.Errorf("logs aggregation did not complete: %s", aggregate.Meta.Status), Body: bodyBytes}
+	}
+	count := float64(0)
+	for _, bucket := range aggregate.Data.Buckets {
+		for _, value := range bucket.Computes {
+			count += value
+		}
+	}
+	return count, nil
+}
+
+// sloHistory requests the history of the SLO over the timeframe ending at the time
+func (p *Provider) sloHistory(metric *v1alpha1.DatadogMetric, timeframe string, to time.Time) (*sloHistoryResponse, error) {
+	historyURL, err := newSLOHistoryURL(metric, timeframe, to)
+	if err != nil {
+		return nil, err
+	}
+	bodyBytes, err := p.do(metric, http.MethodGet, historyURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	var history sloHistoryResponse
+	if err := json.Unmarshal(bodyBytes, &history); err != nil {
+		return nil, &metricutil.ResponseError{Err: fmt.Errorf("Could not parse JSON body: %v", err), Body: bodyBytes}
+	}
+	if len(history.Errors) > 0 {
+		messages := make([]string, 0, len(history.Errors))
+		for _, historyErr := range history.Errors {
+			messages = append(messages, historyErr.Error)
+		}
+		return nil, &metricutil.ResponseError{Err: errors.New(strings.Join(messages, "; ")), Body: bodyBytes}
+	}
+	return &history, nil
+}
+
+// do sends a request with the headers of the metric to the URL and returns the body of its 2xx response
+func (p *Provider) do(metric *v1alpha1.DatadogMetric, method, requestURL string, body []byte) ([]byte, error) {
+	request, err := http.NewRequest(method, requestURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	for _, header := range metric.Headers {
+		request.Header.Set(header.Key, header.Value)
+	}
+	response, err := p.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	bodyBytes, err := metricutil.ReadResponseBody(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return nil, &metricutil.ResponseError{Err: fmt.Errorf("received non 2xx response code: %v", response.StatusCode), Body: bodyBytes}
+	}
+	return bodyBytes, nil
+}
+
+// markError marks the measurement as errored, recording the response body when enabled. The values of the headers
+// are redacted from the recorded body
+func (p *Provider) markError(measurement v1alpha1.Measurement, metric *v1alpha1.DatadogMetric, err error) v1alpha1.Measurement {
+	headerValues := make([]string, 0, len(metric.Headers))
+	for _, header := range metric.Headers {
+		headerValues = append(headerValues, header.Value)
+	}
+	return metricutil.MarkMeasurementResponseError(measurement, err, p.recordResponseBodies, headerValues...)
+}
+
+// newSLOHistoryURL returns the URL of the history API of the SLO over the timeframe ending at the time
+func newSLOHistoryURL(metric *v1alpha1.DatadogMetric, timeframe string, to time.Time) (string, error) {
+	address, err := apiURL(metric, "/api/v1/slo/"+url.PathEscape(metric.SLOID)+"/history")
+	if err != nil {
+		return "", err
+	}
+	query := url.Values{}
+	query.Set("from_ts", strconv.FormatInt(to.Add(-timeframes[timeframe]).Unix(), 10))
+	query.Set("to_ts", strconv.FormatInt(to.Unix(), 10))
+	address.RawQuery = query.Encode()
+	return address.String(), nil
+}
+
+// apiURL returns the URL of the path of the Datadog API of the metric
+func apiURL(metric *v1alpha1.DatadogMetric, path string) (*url.URL, error) {
+	address, err := url.Parse(Address(metric))
+	if err != nil {
+		return nil, err
+	}
+	address.Path = strings.TrimSuffix(address.Path, "/") + path
+	return address, nil
+}
+
+// Address returns the address of the Datadog API of the metric
+func Address(metric *v1alpha1.DatadogMetric) string {
+	if metric.Address == "" {
+		return DefaultAddress
+	}
+	return metric.Address
+}
+
+// Timeframe returns the timeframe of the SLO of the metric
+func Timeframe(metric *v1alpha1.DatadogMetric) string {
+	if metric.Timeframe == "" {
+		return DefaultTimeframe
+	}
+	return metric.Timeframe
+}
+
+// LogsTimeframe returns the duration over which the logs of the query are counted
+func LogsTimeframe(logs *v1alpha1.DatadogLogsQuery) (time.Duration, error) {
+	if logs.Timeframe == "" {
+		return DefaultLogsTimeframe, nil
+	}
+	timeframe, err := logs.Timeframe.Duration()
+	if err != nil {
+		return 0, fmt.Errorf("invalid logs timeframe '%s': %v", logs.Timeframe, err)
+	}
+	return timeframe, nil
+}
+
+// Resume should not be used by the Datadog provider since all the requests should complete immediately
+func (p *Provider) Resume(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, measurement v1alpha1.Measurement) v1alpha1.Measurement {
+	p.logCtx.Warn("Datadog provider should not execute the Resume method")
+	return measurement
+}
+
+// Terminate should not be used by the Datadog provider since all the requests should complete immediately
+func (p *Provider) Terminate(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, measurement v1alpha1.Measurement) v1alpha1.Measurement {
+	p.logCtx.Warn("Datadog provider should not execute the Terminate method")
+	return measurement
+}
+
+// GarbageCollect is a no-op for the Datadog provider
+func (p *Provider) GarbageCollect(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, limit int) error {
+	return nil
+}
+
+// NewDatadogHttpClient returns a http client using the timeout of the metric
+func NewDatadogHttpClient(metric v1alpha1.Metric) *http.Client {
+	var timeout time.Duration
+
+	// Using a default timeout of 10 seconds
+	if metric.Provider.Datadog.TimeoutSeconds <= 0 {
+		timeout = time.Duration(10) * time.Second
+	} else {
+		timeout = time.Duration(metric.Provider.Datadog.TimeoutSeconds) * time.Second
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+	}
+}
+
+// NewDatadogProvider creates a new Datadog provider. When recordResponseBodies is true, the response bodies of the
+// failed requests are recorded in the measurement metadata
+func NewDatadogProvider(logCtx log.Entry, client *http.Client, recordResponseBodies bool) *Provider {
+	return &Provider{
+		logCtx:               logCtx,
+		client:               client,
+		recordResponseBodies: recordResponseBodies,
+	}
+}
@@ -0,0 +1,297 @@
+package datadog
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	metricutil "github.com/argoproj/argo-rollouts/utils/metric"
+)
+
+const sloID = "e8f4a6a4c1a55b1e9e3f9c5a2d7b6c01"
+
+func newMetric(address string) v1alpha1.Metric {
+	return v1alpha1.Metric{
+		Name: "checkout-slo",
+		Provider: v1alpha1.MetricProvider{
+			Datadog: &v1alpha1.DatadogMetric{
+				Address: address,
+				SLOID:   sloID,
+				Headers: []v1alpha1.WebMetricHeader{
+					{Key: "DD-API-KEY", Value: "my-api-key"},
+					{Key: "DD-APPLICATION-KEY", Value: "my-app-key"},
+				},
+			},
+		},
+	}
+}
+
+func newTestProvider(metric v1alpha1.Metric) *Provider {
+	return NewDatadogProvider(*log.WithField("", ""), NewDatadogHttpClient(metric), false)
+}
+
+// newServer returns a stub of the SLO history API of Datadog, checking the timeframe of the requests
+func newServer(t *testing.T, timeframe time.Duration, status int, response string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/api/v1/slo/"+sloID+"/history", req.URL.Path)
+		assert.Equal(t, "my-api-key", req.Header.Get("DD-API-KEY"))
+		assert.Equal(t, "my-app-key", req.Header.Get("DD-APPLICATION-KEY"))
+		from, err := strconv.ParseInt(req.URL.Query().Get("from_ts"), 10, 64)
+		assert.NoError(t, err)
+		to, err := strconv.ParseInt(req.URL.Query().Get("to_ts"), 10, 64)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(timeframe.Seconds()), to-from)
+		rw.Header().Set("Content-Type", "application/json")
+		rw.WriteHeader(status)
+		io.WriteString(rw, response)
+	}))
+}
+
+const historyResponse = `{
+	"data": {
+		"from_ts": 1617035116,
+		"to_ts": 1619627116,
+		"type": "metric",
+		"thresholds": {
+			"30d": {"target": 99.9, "target_display": "99.9", "timeframe": "30d"}
+		},
+		"overall": {
+			"name": "Checkout availability",
+			"sli_value": 99.95,
+			"span_precision": 2,
+			"error_budget_remaining": {"30d": 50}
+		}
+	},
+	"errors": null
+}`
+
+func TestType(t *testing.T) {
+	p := newTestProvider(newMetric(""))
+	assert.Equal(t, ProviderType, p.Type())
+}
+
+func TestRunSuccessful(t *testing.T) {
+	server := newServer(t, 30*24*time.Hour, 200, historyResponse)
+	defer server.Close()
+	metric := newMetric(server.URL)
+	p := newTestProvider(metric)
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+	assert.Equal(t, `{"errorBudgetRemaining":50,"sliValue":99.95,"target":99.9,"timeframe":"30d"}`, measurement.Value)
+	assert.Empty(t, measurement.Message)
+	assert.NotNil(t, measurement.StartedAt)
+	assert.NotNil(t, measurement.FinishedAt)
+}
+
+func TestRunErrorBudgetExhausted(t *testing.T) {
+	server := newServer(t, 7*24*time.Hour, 200, `{"data":{"overall":{"sli_value":99.1,"error_budget_remaining":{"7d":-80}}}}`)
+	defer server.Close()
+	metric := newMetric(server.URL)
+	metric.Provider.Datadog.Timeframe = "7d"
+	p := newTestProvider(metric)
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, measurement.Phase)
+	assert.Equal(t, `{"errorBudgetRemaining":-80,"sliValue":99.1,"timeframe":"7d"}`, measurement.Value)
+	assert.Equal(t, "error budget of SLO '"+sloID+"' is exhausted over the timeframe '7d'", measurement.Message)
+}
+
+func TestRunEvaluatesConditions(t *testing.T) {
+	server := newServer(t, 30*24*time.Hour, 200, historyResponse)
+	defer server.Close()
+	metric := newMetric(server.URL)
+	metric.SuccessCondition = "result.errorBudgetRemaining >= 60"
+	p := newTestProvider(metric)
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, measurement.Phase)
+
+	metric.SuccessCondition = "result.errorBudgetRemaining >= 20 && result.sliValue > result.target"
+	measurement = p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+}
+
+func TestRunNoData(t *testing.T) {
+	server := newServer(t, 30*24*time.Hour, 200, `{"data":{"overall":{"sli_value":null,"error_budget_remaining":{}}}}`)
+	defer server.Close()
+	metric := newMetric(server.URL)
+	p := newTestProvider(metric)
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "SLO '"+sloID+"' has no data over the timeframe '30d'", measurement.Message)
+}
+
+func TestRunNoErrorBudget(t *testing.T) {
+	server := newServer(t, 90*24*time.Hour, 200, historyResponse)
+	defer server.Close()
+	metric := newMetric(server.URL)
+	metric.Provider.Datadog.Timeframe = "90d"
+	p := newTestProvider(metric)
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "SLO '"+sloID+"' has no error budget over the timeframe '90d'", measurement.Message)
+}
+
+func TestRunErrors(t *testing.T) {
+	server := newServer(t, 30*24*time.Hour, 200, `{"data":{},"errors":[{"error":"SLO not found"}]}`)
+	defer server.Close()
+	metric := newMetric(server.URL)
+	p := newTestProvider(metric)
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "SLO not found", measurement.Message)
+}
+
+func TestRunNon2xxResponse(t *testing.T) {
+	server := newServer(t, 30*24*time.Hour, 403, `{"errors":["Forbidden"]}`)
+	defer server.Close()
+	metric := newMetric(server.URL)
+	p := NewDatadogProvider(*log.WithField("", ""), NewDatadogHttpClient(metric), true)
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "received non 2xx response code: 403", measurement.Message)
+	assert.Equal(t, `{"errors":["Forbidden"]}`, measurement.Metadata[metricutil.ResponseBodyMetadataKey])
+}
+
+func TestRunInvalidJSON(t *testing.T) {
+	server := newServer(t, 30*24*time.Hour, 200, `not json`)
+	defer server.Close()
+	metric := newMetric(server.URL)
+	p := newTestProvider(metric)
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Contains(t, measurement.Message, "Could not parse JSON body")
+}
+
+func newLogsMetric(address string) v1alpha1.Metric {
+	metric := newMetric(address)
+	metric.Name = "checkout-error-logs"
+	metric.SuccessCondition = "result < 10"
+	metric.Provider.Datadog.SLOID = ""
+	metric.Provider.Datadog.Logs = &v1alpha1.DatadogLogsQuery{
+		Query: "service:checkout status:error version:canary",
+	}
+	return metric
+}
+
+// newLogsServer returns a stub of the logs aggregate API of Datadog, checking the query and the timeframe of the
+// requests
+func newLogsServer(t *testing.T, timeframe time.Duration, status int, response string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, http.MethodPost, req.Method)
+		assert.Equal(t, "/api/v2/logs/analytics/aggregate", req.URL.Path)
+		assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+		assert.Equal(t, "my-api-key", req.Header.Get("DD-API-KEY"))
+		assert.Equal(t, "my-app-key", req.Header.Get("DD-APPLICATION-KEY"))
+		var aggregate logsAggregateRequest
+		assert.NoError(t, json.NewDecoder(req.Body).Decode(&aggregate))
+		assert.Equal(t, []logsCompute{{Aggregation: "count"}}, aggregate.Compute)
+		assert.Equal(t, "service:checkout status:error version:canary", aggregate.Filter.Query)
+		from, err := strconv.ParseInt(aggregate.Filter.From, 10, 64)
+		assert.NoError(t, err)
+		to, err := strconv.ParseInt(aggregate.Filter.To, 10, 64)
+		assert.NoError(t, err)
+		assert.Equal(t, timeframe.Milliseconds(), to-from)
+		rw.Header().Set("Content-Type", "application/json")
+		rw.WriteHeader(status)
+		io.WriteString(rw, response)
+	}))
+}
+
+func TestRunLogsSuccessful(t *testing.T) {
+	server := newLogsServer(t, 5*time.Minute, 200, `{
+	"data": {"buckets": [{"by": {}, "computes": {"c0": 4}}]},
+	"meta": {"elapsed": 132, "request_id": "pddv1ChZqM0RBdU", "status": "done"}
+}`)
+	defer server.Close()
+	metric := newLogsMetric(server.URL)
+	p := newTestProvider(metric)
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+	assert.Equal(t, "4", measurement.Value)
+	assert.NotNil(t, measurement.FinishedAt)
+}
+
+func TestRunLogsFailed(t *testing.T) {
+	server := newLogsServer(t, 15*time.Minute, 200, `{"data": {"buckets": [{"computes": {"c0": 8}}, {"computes": {"c0": 5}}]}, "meta": {"status": "done"}}`)
+	defer server.Close()
+	metric := newLogsMetric(server.URL)
+	metric.Provider.Datadog.Logs.Timeframe = "15m"
+	p := newTestProvider(metric)
+
+	// the counts of the buckets are summed
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, measurement.Phase)
+	assert.Equal(t, "13", measurement.Value)
+}
+
+func TestRunLogsNoBuckets(t *testing.T) {
+	server := newLogsServer(t, 5*time.Minute, 200, `{"data": {"buckets": []}, "meta": {"status": "done"}}`)
+	defer server.Close()
+	metric := newLogsMetric(server.URL)
+	p := newTestProvider(metric)
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+	assert.Equal(t, "0", measurement.Value)
+}
+
+func TestRunLogsTimedOut(t *testing.T) {
+	server := newLogsServer(t, 5*time.Minute, 200, `{"data": {"buckets": [{"computes": {"c0": 2}}]}, "meta": {"status": "timeout"}}`)
+	defer server.Close()
+	metric := newLogsMetric(server.URL)
+	p := newTestProvider(metric)
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "logs aggregation did not complete: timeout", measurement.Message)
+}
+
+func TestRunLogsErrors(t *testing.T) {
+	server := newLogsServer(t, 5*time.Minute, 400, `{"errors": ["Invalid query"]}`)
+	defer server.Close()
+	metric := newLogsMetric(server.URL)
+	p := NewDatadogProvider(*log.WithField("", ""), NewDatadogHttpClient(metric), true)
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "received non 2xx response code: 400", measurement.Message)
+	assert.Equal(t, `{"errors": ["Invalid query"]}`, measurement.Metadata[metricutil.ResponseBodyMetadataKey])
+}
+
+func TestRunLogsInvalidTimeframe(t *testing.T) {
+	metric := newLogsMetric("")
+	metric.Provider.Datadog.Logs.Timeframe = "5x"
+	p := newTestProvider(metric)
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Contains(t, measurement.Message, "invalid logs timeframe '5x'")
+}
+
+func TestAddress(t *testing.T) {
+	assert.Equal(t, DefaultAddress, Address(&v1alpha1.DatadogMetric{}))
+	assert.Equal(t, "https://api.datadoghq.eu", Address(&v1alpha1.DatadogMetric{Address: "https://api.datadoghq.eu"}))
+}
+
+func TestNewDatadogHttpClient(t *testing.T) {
+	metric := newMetric("")
+	assert.Equal(t, 10*time.Second, NewDatadogHttpClient(metric).Timeout)
+	metric.Provider.Datadog.TimeoutSeconds = 30
+	assert.Equal(t, 30*time.Second, NewDatadogHttpClient(metric).Timeout)
+}
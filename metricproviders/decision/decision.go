@@ -0,0 +1,231 @@
+package decision
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/jsonpath"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/argoproj/argo-rollouts/utils/evaluate"
+	metricutil "github.com/argoproj/argo-rollouts/utils/metric"
+)
+
+const (
+	// ProviderType indicates the provider is an external decision API
+	ProviderType = "Decision"
+	// DefaultPassJSONPath selects the decision when the metric does not specify a JSONPath
+	DefaultPassJSONPath = "{$.pass}"
+	// DefaultReasonJSONPath selects the reason when the metric does not specify a JSONPath
+	DefaultReasonJSONPath = "{$.reason}"
+)
+
+// Provider requests an external decision API and maps its boolean decision to the phase of the measurement
+// Implements the Provider Interface
+type Provider struct {
+	logCtx       log.Entry
+	client       *http.Client
+	passParser   *jsonpath.JSONPath
+	reasonParser *jsonpath.JSONPath
+	// recordResponseBodies records the response bodies of failed requests in the measurement metadata
+	recordResponseBodies bool
+}
+
+// Type indicates provider is a Decision provider
+func (p *Provider) Type() string {
+	return ProviderType
+}
+
+// Run requests the decision. The measurement is successful when the decision passes and failed otherwise, unless
+// the metric has conditions, which are then evaluated against the decision.
+func (p *Provider) Run(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric) v1alpha1.Measurement {
+	startTime := metav1.Now()
+	measurement := v1alpha1.Measurement{
+		StartedAt: &startTime,
+	}
+
+	data, body, err := p.request(metric.Provider.Decision)
+	if err != nil {
+		return p.markError(measurement, metric, err)
+	}
+	pass, err := p.parsePass(data)
+	if err != nil {
+		return p.markError(measurement, metric, &metricutil.ResponseError{Err: err, Body: body})
+	}
+
+	measurement.Value = strconv.FormatBool(pass)
+	measurement.Message = p.parseReason(data)
+	if metric.SuccessCondition == "" && metric.FailureCondition == "" && metric.InconclusiveCondition == "" {
+		measurement.Phase = v1alpha1.AnalysisPhaseFailed
+		if pass {
+			measurement.Phase = v1alpha1.AnalysisPhaseSuccessful
+		}
+	} else {
+		phase, err := evaluate.EvaluateResult(pass, metric, p.logCtx)
+		if err != nil {
+			return metricutil.MarkMeasurementError(measurement, err)
+		}
+		measurement.Phase = phase
+	}
+	finishedTime := metav1.Now()
+	measurement.FinishedAt = &finishedTime
+	return measurement
+}
+
+// markError marks the measurement as errored, recording the response body when enabled. The values of the headers
+// are redacted from the recorded body
+func (p *Provider) markError(measurement v1alpha1.Measurement, metric v1alpha1.Metric, err error) v1alpha1.Measurement {
+	headerValues := make([]string, 0, len(metric.Provider.Decision.Headers))
+	for _, header := range metric.Provider.Decision.Headers {
+		headerValues = append(headerValues, header.Value)
+	}
+	return metricutil.MarkMeasurementResponseError(measurement, err, p.recordResponseBodies, headerValues...)
+}
+
+// request sends the request of the metric and returns the decoded JSON body along with the raw body
+func (p *Provider) request(metric *v1alpha1.DecisionMetric) (interface{}, []byte, error) {
+	method := metric.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	request, err := http.NewRequest(method, metric.URL, strings.NewReader(metric.Body))
+	if err != nil {
+		return nil, nil, err
+	}
+	if metric.Body != "" {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	for _, header := range metric.Headers {
+		request.Header.Set(header.Key, header.Value)
+	}
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer response.Body.Close()
+
+	bodyBytes, err := metricutil.ReadResponseBody(response.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	var data interface{}
+	jsonErr := json.Unmarshal(bodyBytes, &data)
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		if jsonErr == nil {
+			if reason := p.parseReason(data); reason != "" {
+				return nil, nil, &metricutil.ResponseError{Err: fmt.Errorf("received non 2xx response code: %v: %s", response.StatusCode, reason), Body: bodyBytes}
+			}
+		}
+		return nil, nil, &metricutil.ResponseError{Err: fmt.Errorf("received non 2xx response code: %v", response.StatusCode), Body: bodyBytes}
+	}
+	if jsonErr != nil {
+		return nil, nil, &metricutil.ResponseError{Err: fmt.Errorf("Could not parse JSON body: %v", jsonErr), Body: bodyBytes}
+	}
+	return data, bodyBytes, nil
+}
+
+// parsePass returns the decision selected in the body, which must be a boolean or a string holding a boolean
+func (p *Provider) parsePass(data interface{}) (bool, error) {
+	results, err := p.passParser.FindResults(data)
+	if err != nil {
+		return false, fmt.Errorf("Could not find the decision in body: %v", err)
+	}
+	for _, result := range results {
+		for _, value := range result {
+			if !value.IsValid() {
+				continue
+			}
+			switch decision := value.Interface().(type) {
+			case bool:
+				return decision, nil
+			case string:
+				pass, err := strconv.ParseBool(decision)
+				if err != nil {
+					return false, fmt.Errorf("Could not parse the decision '%s' as a boolean", decision)
+				}
+				return pass, nil
+			default:
+				return false, fmt.Errorf("Could not parse the decision '%v' as a boolean", decision)
+			}
+		}
+	}
+	return false, fmt.Errorf("Could not find the decision in body")
+}
+
+// parseReason returns the reason selected in the body, or an empty string if the body has no reason
+func (p *Provider) parseReason(data interface{}) string {
+	buf := new(bytes.Buffer)
+	if err := p.reasonParser.Execute(buf, data); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+// Resume should not be used the Decision provider since all the work should occur in the Run method
+func (p *Provider) Resume(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, measurement v1alpha1.Measurement) v1alpha1.Measurement {
+	p.logCtx.Warn("Decision provider should not execute the Resume method")
+	return measurement
+}
+
+// Terminate should not be used the Decision provider since all the work should occur in the Run method
+func (p *Provider) Terminate(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, measurement v1alpha1.Measurement) v1alpha1.Measurement {
+	p.logCtx.Warn("Decision provider should not execute the Terminate method")
+	return measurement
+}
+
+// GarbageCollect is a no-op for the Decision provider
+func (p *Provider) GarbageCollect(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, limit int) error {
+	return nil
+}
+
+// NewDecisionHttpClient returns a http client using the timeout of the metric
+func NewDecisionHttpClient(metric v1alpha1.Metric) *http.Client {
+	var timeout time.Duration
+
+	// Using a default timeout of 10 seconds
+	if metric.Provider.Decision.TimeoutSeconds <= 0 {
+		timeout = time.Duration(10) * time.Second
+	} else {
+		timeout = time.Duration(metric.Provider.Decision.TimeoutSeconds) * time.Second
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+	}
+}
+
+// NewDecisionProvider creates a new Decision provider, parsing the JSONPaths of the metric. When recordResponseBodies
+// is true, the response bodies of failed requests are recorded in the measurement metadata
+func NewDecisionProvider(logCtx log.Entry, client *http.Client, metric v1alpha1.Metric, recordResponseBodies bool) (*Provider, error) {
+	passJSONPath := metric.Provider.Decision.PassJSONPath
+	if passJSONPath == "" {
+		passJSONPath = DefaultPassJSONPath
+	}
+	passParser := jsonpath.New("pass")
+	if err := passParser.Parse(passJSONPath); err != nil {
+		return nil, fmt.Errorf("Could not parse passJsonPath: %v", err)
+	}
+	reasonJSONPath := metric.Provider.Decision.ReasonJSONPath
+	if reasonJSONPath == "" {
+		reasonJSONPath = DefaultReasonJSONPath
+	}
+	reasonParser := jsonpath.New("reason").AllowMissingKeys(true)
+	if err := reasonParser.Parse(reasonJSONPath); err != nil {
+		return nil, fmt.Errorf("Could not parse reasonJsonPath: %v", err)
+	}
+	return &Provider{
+		logCtx:               logCtx,
+		client:               client,
+		passParser:           passParser,
+		reasonParser:         reasonParser,
+		recordResponseBodies: recordResponseBodies,
+	}, nil
+}
@@ -0,0 +1,241 @@
+package decision
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	metricutil "github.com/argoproj/argo-rollouts/utils/metric"
+)
+
+func newMetric(url string) v1alpha1.Metric {
+	return v1alpha1.Metric{
+		Name: "feature-flags",
+		Provider: v1alpha1.MetricProvider{
+			Decision: &v1alpha1.DecisionMetric{
+				URL: url,
+			},
+		},
+	}
+}
+
+func newTestProvider(t *testing.T, metric v1alpha1.Metric) *Provider {
+	p, err := NewDecisionProvider(*log.WithField("", ""), NewDecisionHttpClient(metric), metric, false)
+	assert.NoError(t, err)
+	return p
+}
+
+func newServer(status int, response string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		rw.WriteHeader(status)
+		io.WriteString(rw, response)
+	}))
+}
+
+func TestType(t *testing.T) {
+	p := newTestProvider(t, newMetric(""))
+	assert.Equal(t, ProviderType, p.Type())
+}
+
+func TestRunSuite(t *testing.T) {
+	tests := []struct {
+		name            string
+		serverStatus    int
+		serverResponse  string
+		passJSONPath    string
+		reasonJSONPath  string
+		expectedValue   string
+		expectedPhase   v1alpha1.AnalysisPhase
+		expectedMessage string
+	}{
+		{
+			name:            "pass",
+			serverStatus:    200,
+			serverResponse:  `{"pass": true, "reason": "canary cohort healthy"}`,
+			expectedValue:   "true",
+			expectedPhase:   v1alpha1.AnalysisPhaseSuccessful,
+			expectedMessage: "canary cohort healthy",
+		},
+		{
+			name:            "fail",
+			serverStatus:    200,
+			serverResponse:  `{"pass": false, "reason": "flag new-checkout disabled for the canary cohort"}`,
+			expectedValue:   "false",
+			expectedPhase:   v1alpha1.AnalysisPhaseFailed,
+			expectedMessage: "flag new-checkout disabled for the canary cohort",
+		},
+		{
+			name:           "fail without reason",
+			serverStatus:   200,
+			serverResponse: `{"pass": false}`,
+			expectedValue:  "false",
+			expectedPhase:  v1alpha1.AnalysisPhaseFailed,
+		},
+		{
+			name:            "custom JSONPaths with string decision",
+			serverStatus:    200,
+			serverResponse:  `{"result": {"allowed": "TRUE", "details": {"summary": "all flags evaluated"}}}`,
+			passJSONPath:    "{$.result.allowed}",
+			reasonJSONPath:  "{$.result.details.summary}",
+			expectedValue:   "true",
+			expectedPhase:   v1alpha1.AnalysisPhaseSuccessful,
+			expectedMessage: "all flags evaluated",
+		},
+		{
+			name:            "missing decision",
+			serverStatus:    200,
+			serverResponse:  `{"reason": "unknown cohort"}`,
+			expectedPhase:   v1alpha1.AnalysisPhaseError,
+			expectedMessage: "Could not find the decision in body: pass is not found",
+		},
+		{
+			name:            "non boolean decision",
+			serverStatus:    200,
+			serverResponse:  `{"pass": "maybe"}`,
+			expectedPhase:   v1alpha1.AnalysisPhaseError,
+			expectedMessage: "Could not parse the decision 'maybe' as a boolean",
+		},
+		{
+			name:            "error response with reason",
+			serverStatus:    503,
+			serverResponse:  `{"reason": "flag store unavailable"}`,
+			expectedPhase:   v1alpha1.AnalysisPhaseError,
+			expectedMessage: "received non 2xx response code: 503: flag store unavailable",
+		},
+		{
+			name:            "error response without JSON body",
+			serverStatus:    500,
+			serverResponse:  `internal error`,
+			expectedPhase:   v1alpha1.AnalysisPhaseError,
+			expectedMessage: "received non 2xx response code: 500",
+		},
+		{
+			name:            "invalid JSON body",
+			serverStatus:    200,
+			serverResponse:  `pass`,
+			expectedPhase:   v1alpha1.AnalysisPhaseError,
+			expectedMessage: "Could not parse JSON body: invalid character 'p' looking for beginning of value",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newServer(test.serverStatus, test.serverResponse)
+			defer server.Close()
+			metric := newMetric(server.URL)
+			metric.Provider.Decision.PassJSONPath = test.passJSONPath
+			metric.Provider.Decision.ReasonJSONPath = test.reasonJSONPath
+			p := newTestProvider(t, metric)
+
+			measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+			assert.Equal(t, test.expectedPhase, measurement.Phase)
+			assert.Equal(t, test.expectedValue, measurement.Value)
+			assert.Equal(t, test.expectedMessage, measurement.Message)
+			assert.NotNil(t, measurement.StartedAt)
+			assert.NotNil(t, measurement.FinishedAt)
+		})
+	}
+}
+
+func TestRunEvaluatesConditions(t *testing.T) {
+	server := newServer(200, `{"pass": false, "reason": "flag evaluation is warming up"}`)
+	defer server.Close()
+	metric := newMetric(server.URL)
+	metric.SuccessCondition = "result == true"
+	metric.InconclusiveCondition = "result == false"
+	p := newTestProvider(t, metric)
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseInconclusive, measurement.Phase)
+	assert.Equal(t, "flag evaluation is warming up", measurement.Message)
+}
+
+func TestRunPostWithHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, http.MethodPost, req.Method)
+		assert.Equal(t, "Bearer token", req.Header.Get("Authorization"))
+		assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+		body, _ := ioutil.ReadAll(req.Body)
+		assert.Equal(t, `{"cohort": "canary"}`, string(body))
+		io.WriteString(rw, `{"pass": true}`)
+	}))
+	defer server.Close()
+	metric := newMetric(server.URL)
+	metric.Provider.Decision.Method = http.MethodPost
+	metric.Provider.Decision.Body = `{"cohort": "canary"}`
+	metric.Provider.Decision.Headers = []v1alpha1.WebMetricHeader{{Key: "Authorization", Value: "Bearer token"}}
+	p := newTestProvider(t, metric)
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+	assert.Equal(t, "", measurement.Message)
+}
+
+func TestRunRecordsResponseBody(t *testing.T) {
+	server := newServer(503, `{"reason": "flag store unavailable", "apiKey": "k3y"}`)
+	defer server.Close()
+	metric := newMetric(server.URL)
+
+	measurement := newTestProvider(t, metric).Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Nil(t, measurement.Metadata)
+
+	p, err := NewDecisionProvider(*log.WithField("", ""), NewDecisionHttpClient(metric), metric, true)
+	assert.NoError(t, err)
+	measurement = p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "received non 2xx response code: 503: flag store unavailable", measurement.Message)
+	assert.Equal(t, `{"reason": "flag store unavailable", "apiKey": "<redacted>"}`, measurement.Metadata[metricutil.ResponseBodyMetadataKey])
+
+	// The values of the headers are redacted
+	server = newServer(200, `{"passed": true, "user": "Bearer token"}`)
+	defer server.Close()
+	metric = newMetric(server.URL)
+	metric.Provider.Decision.Headers = []v1alpha1.WebMetricHeader{{Key: "Authorization", Value: "Bearer token"}}
+	p, err = NewDecisionProvider(*log.WithField("", ""), NewDecisionHttpClient(metric), metric, true)
+	assert.NoError(t, err)
+	measurement = p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, `{"passed": true, "user": "<redacted>"}`, measurement.Metadata[metricutil.ResponseBodyMetadataKey])
+}
+
+func TestRunRequestError(t *testing.T) {
+	metric := newMetric("http://")
+	p := newTestProvider(t, metric)
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.NotEmpty(t, measurement.Message)
+}
+
+func TestNewDecisionProviderInvalidJSONPath(t *testing.T) {
+	metric := newMetric("http://decisions.example.com")
+	metric.Provider.Decision.PassJSONPath = "{$.pass"
+	_, err := NewDecisionProvider(*log.WithField("", ""), NewDecisionHttpClient(metric), metric, false)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Could not parse passJsonPath")
+}
+
+func TestResumeShouldNotBeUsed(t *testing.T) {
+	p := newTestProvider(t, newMetric(""))
+	measurement := v1alpha1.Measurement{Phase: v1alpha1.AnalysisPhaseRunning}
+	assert.Equal(t, measurement, p.Resume(&v1alpha1.AnalysisRun{}, newMetric(""), measurement))
+}
+
+func TestTerminateShouldNotBeUsed(t *testing.T) {
+	p := newTestProvider(t, newMetric(""))
+	measurement := v1alpha1.Measurement{Phase: v1alpha1.AnalysisPhaseRunning}
+	assert.Equal(t, measurement, p.Terminate(&v1alpha1.AnalysisRun{}, newMetric(""), measurement))
+}
+
+func TestGarbageCollect(t *testing.T) {
+	p := newTestProvider(t, newMetric(""))
+	assert.NoError(t, p.GarbageCollect(&v1alpha1.AnalysisRun{}, newMetric(""), 0))
+}
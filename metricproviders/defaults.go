@@ -0,0 +1,162 @@
+package metricproviders
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ghodss/yaml"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/argoproj/argo-rollouts/utils/defaults"
+)
+
+const (
+	// ProviderDefaultsConfigMapKey is the key of the ConfigMap holding the default settings of the metric providers
+	ProviderDefaultsConfigMapKey = "metricProviderDefaults"
+)
+
+// ProviderDefaults are the settings applied to the metrics of a namespace which do not specify them
+type ProviderDefaults struct {
+	// Namespace is the namespace of the AnalysisRuns the defaults apply to. If omitted, the defaults apply to all the
+	// namespaces which do not have defaults of their own
+	Namespace string `json:"namespace,omitempty"`
+	// Provider is the provider type the defaults apply to (e.g. Prometheus). If omitted, the defaults apply to all
+	// the provider types which do not have defaults of their own
+	Provider string `json:"provider,omitempty"`
+	// TimeoutSeconds is the timeout of the requests of the providers which have a timeoutSeconds setting
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+	// ConsecutiveErrorLimit is the number of consecutive errored measurements retried before the metric errors
+	ConsecutiveErrorLimit *int32 `json:"consecutiveErrorLimit,omitempty"`
+	// RequestsPerSecond is the sustained number of requests per second allowed to the provider by the AnalysisRuns of
+	// each namespace. Unlimited if omitted
+	RequestsPerSecond float32 `json:"requestsPerSecond,omitempty"`
+	// Burst is the maximum number of requests allowed at once by the AnalysisRuns of each namespace
+	Burst int `json:"burst,omitempty"`
+}
+
+// matchingDefaults returns the defaults applying to the provider type in the namespace, the most specific first. The
+// defaults of the namespace take precedence over the defaults of all the namespaces, and the defaults of the provider
+// type over the defaults of all the provider types.
+func matchingDefaults(defaults []ProviderDefaults, namespace, providerType string) []ProviderDefaults {
+	var matching []ProviderDefaults
+	for _, d := range defaults {
+		if (d.Namespace == "" || d.Namespace == namespace) && (d.Provider == "" || d.Provider == providerType) {
+			matching = append(matching, d)
+		}
+	}
+	specificity := func(d ProviderDefaults) int {
+		s := 0
+		if d.Namespace != "" {
+			s += 2
+		}
+		if d.Provider != "" {
+			s++
+		}
+		return s
+	}
+	sort.SliceStable(matching, func(i, j int) bool {
+		return specificity(matching[i]) > specificity(matching[j])
+	})
+	return matching
+}
+
+// ApplyProviderDefaults sets the settings of the metric which are not specified from the defaults of its provider
+// type in the namespace. Each setting is taken from the most specific defaults specifying it.
+func ApplyProviderDefaults(defaults []ProviderDefaults, namespace string, metric *v1alpha1.Metric) {
+	timeoutSeconds := providerTimeoutSeconds(metric)
+	for _, d := range matchingDefaults(defaults, namespace, Type(*metric)) {
+		if timeoutSeconds != nil && *timeoutSeconds == 0 && d.TimeoutSeconds > 0 {
+			*timeoutSeconds = d.TimeoutSeconds
+		}
+		if metric.ConsecutiveErrorLimit == nil && d.ConsecutiveErrorLimit != nil {
+			limit := *d.ConsecutiveErrorLimit
+			metric.ConsecutiveErrorLimit = &limit
+		}
+	}
+}
+
+// defaultRateLimit returns the rate limit of the provider type of the metric in the namespace from the most
+// specific defaults specifying one, or nil if none does
+func defaultRateLimit(defaults []ProviderDefaults, namespace string, metric v1alpha1.Metric) *RateLimit {
+	providerType := Type(metric)
+	for _, d := range matchingDefaults(defaults, namespace, providerType) {
+		if d.RequestsPerSecond > 0 {
+			return &RateLimit{
+				Provider:          providerType,
+				RequestsPerSecond: d.RequestsPerSecond,
+				Burst:             d.Burst,
+			}
+		}
+	}
+	return nil
+}
+
+// providerTimeoutSeconds returns the timeout setting of the metric's provider, or nil if the provider does not have one
+func providerTimeoutSeconds(metric *v1alpha1.Metric) *int {
+	if metric.Provider.Web != nil {
+		return &metric.Provider.Web.TimeoutSeconds
+	} else if metric.Provider.Elasticsearch != nil {
+		return &metric.Provider.Elasticsearch.TimeoutSeconds
+	} else if metric.Provider.Alertmanager != nil {
+		return &metric.Provider.Alertmanager.TimeoutSeconds
+	} else if metric.Provider.Decision != nil {
+		return &metric.Provider.Decision.TimeoutSeconds
+	} else if metric.Provider.Loki != nil {
+		return &metric.Provider.Loki.TimeoutSeconds
+	} else if metric.Provider.Pingdom != nil {
+		return &metric.Provider.Pingdom.TimeoutSeconds
+	} else if metric.Provider.GRPC != nil {
+		return &metric.Provider.GRPC.TimeoutSeconds
+	} else if metric.Provider.Datadog != nil {
+		return &metric.Provider.Datadog.TimeoutSeconds
+	} else if metric.Provider.Incident != nil {
+		return &metric.Provider.Incident.TimeoutSeconds
+	} else if metric.Provider.XRay != nil {
+		return &metric.Provider.XRay.TimeoutSeconds
+	} else if metric.Provider.CommitStatus != nil {
+		return &metric.Provider.CommitStatus.TimeoutSeconds
+	}
+	return nil
+}
+
+// GetProviderDefaults reads the metric provider defaults from the controller ConfigMap. No defaults are returned if
+// the ConfigMap or its key does not exist.
+func GetProviderDefaults(kubeclientset kubernetes.Interface) ([]ProviderDefaults, error) {
+	cm, err := kubeclientset.CoreV1().ConfigMaps(defaults.Namespace()).Get(ConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	data, ok := cm.Data[ProviderDefaultsConfigMapKey]
+	if !ok {
+		return nil, nil
+	}
+	var providerDefaults []ProviderDefaults
+	if err := yaml.Unmarshal([]byte(data), &providerDefaults); err != nil {
+		return nil, fmt.Errorf("invalid %s in ConfigMap %s: %v", ProviderDefaultsConfigMapKey, ConfigMapName, err)
+	}
+	for _, d := range providerDefaults {
+		if err := validateProviderDefaults(d); err != nil {
+			return nil, fmt.Errorf("invalid %s in ConfigMap %s: %v", ProviderDefaultsConfigMapKey, ConfigMapName, err)
+		}
+	}
+	return providerDefaults, nil
+}
+
+func validateProviderDefaults(d ProviderDefaults) error {
+	if d.TimeoutSeconds < 0 {
+		return fmt.Errorf("timeoutSeconds must be >= 0")
+	}
+	if d.ConsecutiveErrorLimit != nil && *d.ConsecutiveErrorLimit < 0 {
+		return fmt.Errorf("consecutiveErrorLimit must be >= 0")
+	}
+	if d.RequestsPerSecond < 0 || d.Burst < 0 || (d.RequestsPerSecond > 0) != (d.Burst > 0) {
+		return fmt.Errorf("requestsPerSecond and burst must be specified together")
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package metricproviders
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/pointer"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/argoproj/argo-rollouts/utils/defaults"
+)
+
+func newWebMetric() v1alpha1.Metric {
+	return v1alpha1.Metric{
+		Name: "web",
+		Provider: v1alpha1.MetricProvider{
+			Web: &v1alpha1.WebMetric{URL: "http://metrics.example.com/api"},
+		},
+	}
+}
+
+var testProviderDefaults = []ProviderDefaults{{
+	TimeoutSeconds:        20,
+	ConsecutiveErrorLimit: pointer.Int32Ptr(8),
+}, {
+	Namespace:      "team-a",
+	TimeoutSeconds: 5,
+}, {
+	Namespace:             "team-a",
+	Provider:              "WebMetric",
+	ConsecutiveErrorLimit: pointer.Int32Ptr(2),
+}, {
+	Provider:       "WebMetric",
+	TimeoutSeconds: 30,
+}}
+
+func TestApplyProviderDefaults(t *testing.T) {
+	// the defaults of the namespace take precedence, and each setting comes from the most specific defaults setting it
+	metric := newWebMetric()
+	ApplyProviderDefaults(testProviderDefaults, "team-a", &metric)
+	assert.Equal(t, 5, metric.Provider.Web.TimeoutSeconds)
+	assert.Equal(t, int32(2), *metric.ConsecutiveErrorLimit)
+
+	// the defaults of the provider type take precedence in the namespaces without defaults of their own
+	metric = newWebMetric()
+	ApplyProviderDefaults(testProviderDefaults, "team-b", &metric)
+	assert.Equal(t, 30, metric.Provider.Web.TimeoutSeconds)
+	assert.Equal(t, int32(8), *metric.ConsecutiveErrorLimit)
+
+	// the providers without a timeout setting only get the other defaults
+	metric = newPrometheusMetric("http://prometheus:9090")
+	ApplyProviderDefaults(testProviderDefaults, "team-b", &metric)
+	assert.Equal(t, int32(8), *metric.ConsecutiveErrorLimit)
+}
+
+func TestApplyProviderDefaultsDoesNotOverrideMetric(t *testing.T) {
+	metric := newWebMetric()
+	metric.Provider.Web.TimeoutSeconds = 60
+	metric.ConsecutiveErrorLimit = pointer.Int32Ptr(0)
+	ApplyProviderDefaults(testProviderDefaults, "team-a", &metric)
+	assert.Equal(t, 60, metric.Provider.Web.TimeoutSeconds)
+	assert.Equal(t, int32(0), *metric.ConsecutiveErrorLimit)
+}
+
+func TestApplyProviderDefaultsWithoutDefaults(t *testing.T) {
+	metric := newWebMetric()
+	ApplyProviderDefaults(nil, "team-a", &metric)
+	assert.Equal(t, 0, metric.Provider.Web.TimeoutSeconds)
+	assert.Nil(t, metric.ConsecutiveErrorLimit)
+}
+
+func TestRateLimitersWithDefaults(t *testing.T) {
+	r := NewRateLimiters([]RateLimit{{
+		Provider:          "Prometheus",
+		RequestsPerSecond: 0.001,
+		Burst:             1,
+	}}, []ProviderDefaults{{
+		RequestsPerSecond: 0.001,
+		Burst:             1,
+	}, {
+		Namespace:         "team-a",
+		RequestsPerSecond: 0.001,
+		Burst:             2,
+	}})
+
+	// the default rate limit is enforced in each namespace separately
+	assert.True(t, r.TryAccept("team-a", newWebMetric()))
+	assert.True(t, r.TryAccept("team-a", newWebMetric()))
+	assert.False(t, r.TryAccept("team-a", newWebMetric()))
+	assert.True(t, r.TryAccept("team-b", newWebMetric()))
+	assert.False(t, r.TryAccept("team-b", newWebMetric()))
+
+	// the configured rate limits take precedence over the defaults, and are shared by all the namespaces
+	assert.True(t, r.TryAccept("team-a", newPrometheusMetric("http://prometheus:9090")))
+	assert.False(t, r.TryAccept("team-b", newPrometheusMetric("http://prometheus:9090")))
+}
+
+func TestGetProviderDefaults(t *testing.T) {
+	providerDefaults, err := GetProviderDefaults(k8sfake.NewSimpleClientset())
+	assert.NoError(t, err)
+	assert.Nil(t, providerDefaults)
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ConfigMapName,
+			Namespace: defaults.Namespace(),
+		},
+		Data: map[string]string{
+			ProviderDefaultsConfigMapKey: `
+- timeoutSeconds: 20
+  consecutiveErrorLimit: 8
+- namespace: team-a
+  provider: Prometheus
+  requestsPerSecond: 2
+  burst: 5
+`,
+		},
+	}
+	providerDefaults, err = GetProviderDefaults(k8sfake.NewSimpleClientset(cm))
+	assert.NoError(t, err)
+	assert.Equal(t, []ProviderDefaults{
+		{TimeoutSeconds: 20, ConsecutiveErrorLimit: pointer.Int32Ptr(8)},
+		{Namespace: "team-a", Provider: "Prometheus", RequestsPerSecond: 2, Burst: 5},
+	}, providerDefaults)
+
+	cm.Data[ProviderDefaultsConfigMapKey] = `
+- provider: Prometheus
+  requestsPerSecond: 2
+`
+	_, err = GetProviderDefaults(k8sfake.NewSimpleClientset(cm))
+	assert.EqualError(t, err, "invalid metricProviderDefaults in ConfigMap argo-rollouts-config: requestsPerSecond and burst must be specified together")
+
+	cm.Data[ProviderDefaultsConfigMapKey] = `
+- timeoutSeconds: -1
+`
+	_, err = GetProviderDefaults(k8sfake.NewSimpleClientset(cm))
+	assert.EqualError(t, err, "invalid metricProviderDefaults in ConfigMap argo-rollouts-config: timeoutSeconds must be >= 0")
+}
@@ -0,0 +1,226 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/jsonpath"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/argoproj/argo-rollouts/utils/defaults"
+	"github.com/argoproj/argo-rollouts/utils/evaluate"
+	metricutil "github.com/argoproj/argo-rollouts/utils/metric"
+)
+
+const (
+	//ProviderType indicates the provider is elasticsearch
+	ProviderType = "Elasticsearch"
+	// UsernameSecretKey is the key of the secret holding the username used for basic authentication
+	UsernameSecretKey = "username"
+	// PasswordSecretKey is the key of the secret holding the password used for basic authentication
+	PasswordSecretKey = "password"
+	// APIKeySecretKey is the key of the secret holding the API key used for API key authentication
+	APIKeySecretKey = "apiKey"
+)
+
+// Credentials holds the authentication options used by the search request
+type Credentials struct {
+	Username string
+	Password string
+	APIKey   string
+}
+
+// Provider contains all the required components to run an Elasticsearch search
+// Implements the Provider Interface
+type Provider struct {
+	logCtx      log.Entry
+	client      *http.Client
+	jsonParser  *jsonpath.JSONPath
+	credentials Credentials
+	// recordResponseBodies records the response bodies of failed searches in the measurement metadata
+	recordResponseBodies bool
+}
+
+// errorResponse is the body returned by Elasticsearch when a search fails
+type errorResponse struct {
+	Error struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error"`
+}
+
+// Type incidates provider is a Elasticsearch provider
+func (p *Provider) Type() string {
+	return ProviderType
+}
+
+// Run performs the search of the metric and evaluates the selected value
+func (p *Provider) Run(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric) v1alpha1.Measurement {
+	startTime := metav1.Now()
+	measurement := v1alpha1.Measurement{
+		StartedAt: &startTime,
+	}
+
+	searchURL, err := newSearchURL(metric.Provider.Elasticsearch)
+	if err != nil {
+		return metricutil.MarkMeasurementError(measurement, err)
+	}
+	request, err := http.NewRequest("POST", searchURL, strings.NewReader(metric.Provider.Elasticsearch.Body))
+	if err != nil {
+		return metricutil.MarkMeasurementError(measurement, err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if p.credentials.APIKey != "" {
+		request.Header.Set("Authorization", "ApiKey "+p.credentials.APIKey)
+	} else if p.credentials.Username != "" {
+		request.SetBasicAuth(p.credentials.Username, p.credentials.Password)
+	}
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		return metricutil.MarkMeasurementError(measurement, err)
+	}
+	defer response.Body.Close()
+
+	bodyBytes, err := metricutil.ReadResponseBody(response.Body)
+	if err != nil {
+		return metricutil.MarkMeasurementError(measurement, err)
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		err = &metricutil.ResponseError{Err: newSearchError(response.StatusCode, bodyBytes), Body: bodyBytes}
+		return metricutil.MarkMeasurementResponseError(measurement, err, p.recordResponseBodies, p.credentials.Password, p.credentials.APIKey)
+	}
+
+	value, status, err := p.parseResponse(metric, bodyBytes)
+	if err != nil {
+		err = &metricutil.ResponseError{Err: err, Body: bodyBytes}
+		return metricutil.MarkMeasurementResponseError(measurement, err, p.recordResponseBodies, p.credentials.Password, p.credentials.APIKey)
+	}
+
+	measurement.Value = value
+	measurement.Phase = status
+	finishedTime := metav1.Now()
+	measurement.FinishedAt = &finishedTime
+	return measurement
+}
+
+func (p *Provider) parseResponse(metric v1alpha1.Metric, bodyBytes []byte) (string, v1alpha1.AnalysisPhase, error) {
+	var data interface{}
+	err := json.Unmarshal(bodyBytes, &data)
+	if err != nil {
+		return "", v1alpha1.AnalysisPhaseError, fmt.Errorf("Could not parse JSON body: %v", err)
+	}
+
+	buf := new(bytes.Buffer)
+	err = p.jsonParser.Execute(buf, data)
+	if err != nil {
+		return "", v1alpha1.AnalysisPhaseError, fmt.Errorf("Could not find JSONPath in body: %s", err)
+	}
+	out := buf.String()
+	result, err := strconv.ParseFloat(out, 64)
+	if err != nil {
+		return out, v1alpha1.AnalysisPhaseError, fmt.Errorf("Could not parse '%s' selected by JSONPath as a number", out)
+	}
+
+	status, err := evaluate.EvaluateResult(result, metric, p.logCtx)
+	return out, status, err
+}
+
+// newSearchURL returns the URL of the search API for the index of the metric
+func newSearchURL(metric *v1alpha1.ElasticsearchMetric) (string, error) {
+	address, err := url.Parse(metric.Address)
+	if err != nil {
+		return "", err
+	}
+	address.Path = strings.TrimSuffix(address.Path, "/") + "/" + metric.Index + "/_search"
+	return address.String(), nil
+}
+
+// newSearchError returns an error describing a failed search, including query parse errors
+func newSearchError(statusCode int, bodyBytes []byte) error {
+	var errResponse errorResponse
+	if err := json.Unmarshal(bodyBytes, &errResponse); err == nil && errResponse.Error.Type != "" {
+		return fmt.Errorf("search failed with response code %d: %s: %s", statusCode, errResponse.Error.Type, errResponse.Error.Reason)
+	}
+	return fmt.Errorf("received non 2xx response code: %v", statusCode)
+}
+
+// Resume should not be used the Elasticsearch provider since all the work should occur in the Run method
+func (p *Provider) Resume(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, measurement v1alpha1.Measurement) v1alpha1.Measurement {
+	p.logCtx.Warn("Elasticsearch provider should not execute the Resume method")
+	return measurement
+}
+
+// Terminate should not be used the Elasticsearch provider since all the work should occur in the Run method
+func (p *Provider) Terminate(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, measurement v1alpha1.Measurement) v1alpha1.Measurement {
+	p.logCtx.Warn("Elasticsearch provider should not execute the Terminate method")
+	return measurement
+}
+
+// GarbageCollect is a no-op for the Elasticsearch provider
+func (p *Provider) GarbageCollect(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, limit int) error {
+	return nil
+}
+
+// NewElasticsearchHttpClient returns a http client using the timeout of the metric
+func NewElasticsearchHttpClient(metric v1alpha1.Metric) *http.Client {
+	var timeout time.Duration
+
+	// Using a default timeout of 10 seconds
+	if metric.Provider.Elasticsearch.TimeoutSeconds <= 0 {
+		timeout = time.Duration(10) * time.Second
+	} else {
+		timeout = time.Duration(metric.Provider.Elasticsearch.TimeoutSeconds) * time.Second
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+	}
+}
+
+// NewElasticsearchJsonParser returns a parser for the JSONPath of the metric
+func NewElasticsearchJsonParser(metric v1alpha1.Metric) (*jsonpath.JSONPath, error) {
+	jsonParser := jsonpath.New("metrics")
+	err := jsonParser.Parse(metric.Provider.Elasticsearch.JSONPath)
+	return jsonParser, err
+}
+
+// NewElasticsearchCredentials reads the credentials of the metric from its secret in the controller namespace
+func NewElasticsearchCredentials(metric v1alpha1.Metric, kubeclientset kubernetes.Interface) (Credentials, error) {
+	credentials := Credentials{}
+	if metric.Provider.Elasticsearch.SecretName == "" {
+		return credentials, nil
+	}
+	secret, err := kubeclientset.CoreV1().Secrets(defaults.Namespace()).Get(metric.Provider.Elasticsearch.SecretName, metav1.GetOptions{})
+	if err != nil {
+		return credentials, err
+	}
+	credentials.Username = string(secret.Data[UsernameSecretKey])
+	credentials.Password = string(secret.Data[PasswordSecretKey])
+	credentials.APIKey = string(secret.Data[APIKeySecretKey])
+	if credentials.Username == "" && credentials.APIKey == "" {
+		return credentials, fmt.Errorf("secret '%s' must have either a '%s' or '%s' key", metric.Provider.Elasticsearch.SecretName, UsernameSecretKey, APIKeySecretKey)
+	}
+	return credentials, nil
+}
+
+// NewElasticsearchProvider creates a new Elasticsearch provider. When recordResponseBodies is true, the response
+// bodies of failed searches are recorded in the measurement metadata
+func NewElasticsearchProvider(logCtx log.Entry, client *http.Client, jsonParser *jsonpath.JSONPath, credentials Credentials, recordResponseBodies bool) *Provider {
+	return &Provider{
+		logCtx:               logCtx,
+		client:               client,
+		jsonParser:           jsonParser,
+		credentials:          credentials,
+		recordResponseBodies: recordResponseBodies,
+	}
+}
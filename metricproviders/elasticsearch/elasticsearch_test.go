@@ -0,0 +1,238 @@
+package elasticsearch
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	kubetesting "k8s.io/client-go/testing"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	metricutil "github.com/argoproj/argo-rollouts/utils/metric"
+)
+
+const (
+	hitCountResponse = `{"took": 3, "hits": {"total": {"value": 4, "relation": "eq"}, "hits": []}}`
+	aggResponse      = `{"took": 3, "hits": {"total": {"value": 100, "relation": "eq"}}, "aggregations": {"error_rate": {"value": 0.02}}}`
+	parseErrResponse = `{"error": {"root_cause": [], "type": "parsing_exception", "reason": "unknown query [mtch]"}, "status": 400}`
+)
+
+func newMetric(successCondition, jsonPath string) v1alpha1.Metric {
+	return v1alpha1.Metric{
+		Name:             "foo",
+		SuccessCondition: successCondition,
+		Provider: v1alpha1.MetricProvider{
+			Elasticsearch: &v1alpha1.ElasticsearchMetric{
+				Index:    "logs-*",
+				Body:     `{"query": {"match": {"level": "error"}}}`,
+				JSONPath: jsonPath,
+			},
+		},
+	}
+}
+
+func TestType(t *testing.T) {
+	p := NewElasticsearchProvider(*log.WithField("", ""), nil, nil, Credentials{}, false)
+	assert.Equal(t, ProviderType, p.Type())
+}
+
+func TestRunSuite(t *testing.T) {
+	tests := []struct {
+		serverStatus         int
+		serverResponse       string
+		metric               v1alpha1.Metric
+		expectedValue        string
+		expectedPhase        v1alpha1.AnalysisPhase
+		expectedErrorMessage string
+	}{
+		// When_hitCountMatchesCondition_Then_Succeed
+		{
+			serverStatus:   200,
+			serverResponse: hitCountResponse,
+			metric:         newMetric("result < 5", "{$.hits.total.value}"),
+			expectedValue:  "4",
+			expectedPhase:  v1alpha1.AnalysisPhaseSuccessful,
+		},
+		// When_hitCountDoesNotMatchCondition_Then_Failure
+		{
+			serverStatus:   200,
+			serverResponse: hitCountResponse,
+			metric:         newMetric("result < 3", "{$.hits.total.value}"),
+			expectedValue:  "4",
+			expectedPhase:  v1alpha1.AnalysisPhaseFailed,
+		},
+		// When_aggregationMatchesCondition_Then_Succeed
+		{
+			serverStatus:   200,
+			serverResponse: aggResponse,
+			metric:         newMetric("result <= 0.05", "{$.aggregations.error_rate.value}"),
+			expectedValue:  "0.02",
+			expectedPhase:  v1alpha1.AnalysisPhaseSuccessful,
+		},
+		// When_queryCanNotBeParsed_Then_Error
+		{
+			serverStatus:         400,
+			serverResponse:       parseErrResponse,
+			metric:               newMetric("result < 5", "{$.hits.total.value}"),
+			expectedPhase:        v1alpha1.AnalysisPhaseError,
+			expectedErrorMessage: "search failed with response code 400: parsing_exception: unknown query [mtch]",
+		},
+		// When_non2xxWithoutErrorBody_Then_Error
+		{
+			serverStatus:         503,
+			serverResponse:       "Service Unavailable",
+			metric:               newMetric("result < 5", "{$.hits.total.value}"),
+			expectedPhase:        v1alpha1.AnalysisPhaseError,
+			expectedErrorMessage: "received non 2xx response code: 503",
+		},
+		// When_selectedValueIsNotANumber_Then_Error
+		{
+			serverStatus:         200,
+			serverResponse:       hitCountResponse,
+			metric:               newMetric("result < 5", "{$.hits.total.relation}"),
+			expectedPhase:        v1alpha1.AnalysisPhaseError,
+			expectedErrorMessage: "Could not parse 'eq' selected by JSONPath as a number",
+		},
+		// When_JSONPathIsNotFound_Then_Error
+		{
+			serverStatus:         200,
+			serverResponse:       hitCountResponse,
+			metric:               newMetric("result < 5", "{$.aggregations.error_rate.value}"),
+			expectedPhase:        v1alpha1.AnalysisPhaseError,
+			expectedErrorMessage: "Could not find JSONPath in body: aggregations is not found",
+		},
+	}
+
+	for _, test := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			assert.Equal(t, "POST", req.Method)
+			assert.Equal(t, "/logs-%2A/_search", req.URL.EscapedPath())
+			assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+			body, err := ioutil.ReadAll(req.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, test.metric.Provider.Elasticsearch.Body, string(body))
+
+			rw.Header().Set("Content-Type", "application/json")
+			rw.WriteHeader(test.serverStatus)
+			io.WriteString(rw, test.serverResponse)
+		}))
+		defer server.Close()
+		test.metric.Provider.Elasticsearch.Address = server.URL
+
+		jsonParser, err := NewElasticsearchJsonParser(test.metric)
+		assert.NoError(t, err)
+		provider := NewElasticsearchProvider(*log.WithField("test", "test"), server.Client(), jsonParser, Credentials{}, false)
+
+		measurement := provider.Run(&v1alpha1.AnalysisRun{}, test.metric)
+		assert.NotNil(t, measurement.StartedAt)
+		assert.NotNil(t, measurement.FinishedAt)
+		assert.Equal(t, test.expectedPhase, measurement.Phase)
+		if test.expectedPhase == v1alpha1.AnalysisPhaseError {
+			assert.Equal(t, test.expectedErrorMessage, measurement.Message)
+		} else {
+			assert.Equal(t, test.expectedValue, measurement.Value)
+		}
+	}
+}
+
+func TestRunWithCredentials(t *testing.T) {
+	tests := []struct {
+		credentials           Credentials
+		expectedAuthorization string
+	}{
+		{
+			credentials:           Credentials{Username: "user", Password: "pass"},
+			expectedAuthorization: "Basic dXNlcjpwYXNz",
+		},
+		{
+			credentials:           Credentials{APIKey: "abc123"},
+			expectedAuthorization: "ApiKey abc123",
+		},
+	}
+
+	for _, test := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			assert.Equal(t, test.expectedAuthorization, req.Header.Get("Authorization"))
+			rw.Header().Set("Content-Type", "application/json")
+			io.WriteString(rw, hitCountResponse)
+		}))
+		defer server.Close()
+		metric := newMetric("result < 5", "{$.hits.total.value}")
+		metric.Provider.Elasticsearch.Address = server.URL
+
+		jsonParser, err := NewElasticsearchJsonParser(metric)
+		assert.NoError(t, err)
+		provider := NewElasticsearchProvider(*log.WithField("test", "test"), server.Client(), jsonParser, test.credentials, false)
+		measurement := provider.Run(&v1alpha1.AnalysisRun{}, metric)
+		assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+	}
+}
+
+func TestRunRecordsResponseBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		rw.WriteHeader(http.StatusUnauthorized)
+		io.WriteString(rw, `{"error": {"type": "security_exception", "reason": "unable to authenticate with provided credentials abc123"}}`)
+	}))
+	defer server.Close()
+	metric := newMetric("result < 5", "{$.hits.total.value}")
+	metric.Provider.Elasticsearch.Address = server.URL
+	jsonParser, err := NewElasticsearchJsonParser(metric)
+	assert.NoError(t, err)
+
+	provider := NewElasticsearchProvider(*log.WithField("test", "test"), server.Client(), jsonParser, Credentials{APIKey: "abc123"}, true)
+	measurement := provider.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "search failed with response code 401: security_exception: unable to authenticate with provided credentials abc123", measurement.Message)
+	assert.Equal(t, `{"error": {"type": "security_exception", "reason": "unable to authenticate with provided credentials <redacted>"}}`, measurement.Metadata[metricutil.ResponseBodyMetadataKey])
+}
+
+func TestNewElasticsearchCredentials(t *testing.T) {
+	metric := newMetric("result < 5", "{$.hits.total.value}")
+	credentials, err := NewElasticsearchCredentials(metric, k8sfake.NewSimpleClientset())
+	assert.NoError(t, err)
+	assert.Equal(t, Credentials{}, credentials)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "es-credentials",
+		},
+		Data: map[string][]byte{
+			UsernameSecretKey: []byte("user"),
+			PasswordSecretKey: []byte("pass"),
+		},
+	}
+	fakeClient := k8sfake.NewSimpleClientset()
+	fakeClient.PrependReactor("get", "*", func(action kubetesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, secret, nil
+	})
+	metric.Provider.Elasticsearch.SecretName = "es-credentials"
+	credentials, err = NewElasticsearchCredentials(metric, fakeClient)
+	assert.NoError(t, err)
+	assert.Equal(t, Credentials{Username: "user", Password: "pass"}, credentials)
+
+	secret.Data = map[string][]byte{}
+	_, err = NewElasticsearchCredentials(metric, fakeClient)
+	assert.EqualError(t, err, "secret 'es-credentials' must have either a 'username' or 'apiKey' key")
+}
+
+func TestResumeAndTerminateAndGarbageCollect(t *testing.T) {
+	p := NewElasticsearchProvider(*log.WithField("", ""), nil, nil, Credentials{}, false)
+	metric := newMetric("result < 5", "{$.hits.total.value}")
+	now := metav1.Now()
+	measurement := v1alpha1.Measurement{
+		StartedAt: &now,
+		Phase:     v1alpha1.AnalysisPhaseRunning,
+	}
+	assert.Equal(t, measurement, p.Resume(&v1alpha1.AnalysisRun{}, metric, measurement))
+	assert.Equal(t, measurement, p.Terminate(&v1alpha1.AnalysisRun{}, metric, measurement))
+	assert.NoError(t, p.GarbageCollect(&v1alpha1.AnalysisRun{}, metric, 0))
+}
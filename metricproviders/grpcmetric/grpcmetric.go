@@ -0,0 +1,325 @@
+package grpcmetric
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/argoproj/argo-rollouts/utils/evaluate"
+	metricutil "github.com/argoproj/argo-rollouts/utils/metric"
+)
+
+const (
+	// ProviderType indicates the provider is gRPC
+	ProviderType = "GRPC"
+	// defaultTimeout is the timeout of a call when the metric does not specify one
+	defaultTimeout = 10 * time.Second
+)
+
+// Wire types of the protobuf binary format
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// rawMessage is a protobuf message already encoded in the binary format. It is sent and received as is by the
+// protobuf codec of gRPC, which lets the provider invoke any method without the generated code of its messages
+type rawMessage []byte
+
+func (m *rawMessage) Reset()         { *m = nil }
+func (m *rawMessage) String() string { return fmt.Sprintf("%x", []byte(*m)) }
+func (*rawMessage) ProtoMessage()    {}
+
+// Marshal returns the encoded message
+func (m *rawMessage) Marshal() ([]byte, error) {
+	return *m, nil
+}
+
+// Unmarshal copies the encoded message, since gRPC may reuse the buffer
+func (m *rawMessage) Unmarshal(data []byte) error {
+	*m = append((*m)[:0], data...)
+	return nil
+}
+
+// wireField is a field of a message decoded from the protobuf binary format. The value of a varint or fixed field is
+// held by number, and the value of a length-delimited field by bytes
+type wireField struct {
+	fieldNumber int
+	wireType    int
+	number      uint64
+	bytes       []byte
+}
+
+// Provider invokes a gRPC method and evaluates a numeric field of its response
+// Implements the Provider Interface
+type Provider struct {
+	logCtx log.Entry
+	// dialOptions are added to the options of the connections to the servers
+	dialOptions []grpc.DialOption
+}
+
+// Type indicates provider is a gRPC provider
+func (p *Provider) Type() string {
+	return ProviderType
+}
+
+// Run invokes the method of the metric and evaluates the field of the response
+func (p *Provider) Run(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric) v1alpha1.Measurement {
+	startTime := metav1.Now()
+	measurement := v1alpha1.Measurement{
+		StartedAt: &startTime,
+	}
+
+	grpcMetric := metric.Provider.GRPC
+	response, err := p.invoke(grpcMetric)
+	if err != nil {
+		return metricutil.MarkMeasurementError(measurement, err)
+	}
+	value, err := extractField(response, grpcMetric.Field, fieldType(grpcMetric))
+	if err != nil {
+		return metricutil.MarkMeasurementError(measurement, err)
+	}
+
+	measurement.Value = formatValue(value)
+	phase, err := evaluate.EvaluateResult(value, metric, p.logCtx)
+	if err != nil {
+		return metricutil.MarkMeasurementError(measurement, err)
+	}
+	measurement.Phase = phase
+	finishedTime := metav1.Now()
+	measurement.FinishedAt = &finishedTime
+	return measurement
+}
+
+// invoke calls the method of the metric with its request and returns the encoded response
+func (p *Provider) invoke(metric *v1alpha1.GRPCMetric) ([]byte, error) {
+	request, err := base64.StdEncoding.DecodeString(metric.Request)
+	if err != nil {
+		return nil, fmt.Errorf("Could not decode the request: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout(metric))
+	defer cancel()
+
+	dialOptions := append([]grpc.DialOption{transportCredentials(metric)}, p.dialOptions...)
+	conn, err := grpc.DialContext(ctx, metric.Address, dialOptions...)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	requestMessage := rawMessage(request)
+	var responseMessage rawMessage
+	if err := conn.Invoke(ctx, metric.Method, &requestMessage, &responseMessage); err != nil {
+		return nil, err
+	}
+	return responseMessage, nil
+}
+
+// transportCredentials returns the option connecting to the server over TLS when the metric configures it, or in
+// plaintext otherwise
+func transportCredentials(metric *v1alpha1.GRPCMetric) grpc.DialOption {
+	if metric.TLS == nil {
+		return grpc.WithInsecure()
+	}
+	return grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
+		ServerName:         metric.TLS.ServerName,
+		InsecureSkipVerify: metric.TLS.InsecureSkipVerify,
+	}))
+}
+
+// extractField returns the value of the field at the path of field numbers of the encoded message. A scalar field
+// missing from the message has the default value of its type, as protobuf does not encode default values
+func extractField(message []byte, path string, fieldType v1alpha1.GRPCFieldType) (interface{}, error) {
+	fieldNumbers, err := parseFieldPath(path)
+	if err != nil {
+		return nil, err
+	}
+	last := len(fieldNumbers) - 1
+	for i, fieldNumber := range fieldNumbers {
+		fields, err := decodeFields(message)
+		if err != nil {
+			return nil, err
+		}
+		// The last occurrence of a field wins, as for the scalar fields of protobuf
+		var field *wireField
+		for j := range fields {
+			if fields[j].fieldNumber == fieldNumber {
+				field = &fields[j]
+			}
+		}
+		if i == last {
+			if field == nil {
+				field = &wireField{wireType: expectedWireType(fieldType)}
+			}
+			return fieldValue(*field, path, fieldType)
+		}
+		messagePath := strings.Join(strings.Split(path, ".")[:i+1], ".")
+		if field == nil {
+			return nil, fmt.Errorf("Message field %s of the response is not set", messagePath)
+		}
+		if field.wireType != wireBytes {
+			return nil, fmt.Errorf("Field %s of the response is not a message", messagePath)
+		}
+		message = field.bytes
+	}
+	return nil, fmt.Errorf("Field path '%s' is empty", path)
+}
+
+// fieldValue converts the value of the decoded field to a float64, or to a bool for a bool field
+func fieldValue(field wireField, path string, fieldType v1alpha1.GRPCFieldType) (interface{}, error) {
+	if field.wireType != expectedWireType(fieldType) {
+		return nil, fmt.Errorf("Field %s of the response has wire type %d, which does not match the type %s", path, field.wireType, fieldType)
+	}
+	switch fieldType {
+	case v1alpha1.GRPCFieldTypeDouble:
+		return math.Float64frombits(field.number), nil
+	case v1alpha1.GRPCFieldTypeFloat:
+		return float64(math.Float32frombits(uint32(field.number))), nil
+	case v1alpha1.GRPCFieldTypeInt64:
+		return float64(int64(field.number)), nil
+	case v1alpha1.GRPCFieldTypeUint64:
+		return float64(field.number), nil
+	case v1alpha1.GRPCFieldTypeInt32:
+		return float64(int32(field.number)), nil
+	case v1alpha1.GRPCFieldTypeUint32:
+		return float64(uint32(field.number)), nil
+	case v1alpha1.GRPCFieldTypeSint64:
+		return float64(int64(field.number>>1) ^ -int64(field.number&1)), nil
+	case v1alpha1.GRPCFieldTypeSint32:
+		return float64(int32(int64(field.number>>1) ^ -int64(field.number&1))), nil
+	case v1alpha1.GRPCFieldTypeBool:
+		return field.number != 0, nil
+	}
+	return nil, fmt.Errorf("Field type '%s' is not supported", fieldType)
+}
+
+// expectedWireType returns the wire type encoding the fields of the type
+func expectedWireType(fieldType v1alpha1.GRPCFieldType) int {
+	switch fieldType {
+	case v1alpha1.GRPCFieldTypeDouble:
+		return wireFixed64
+	case v1alpha1.GRPCFieldTypeFloat:
+		return wireFixed32
+	}
+	return wireVarint
+}
+
+// decodeFields decodes the fields of a message encoded in the protobuf binary format, without decoding the messages
+// nested in its length-delimited fields
+func decodeFields(message []byte) ([]wireField, error) {
+	var fields []wireField
+	for len(message) > 0 {
+		key, n := binary.Uvarint(message)
+		if n <= 0 {
+			return nil, fmt.Errorf("Could not decode the response: invalid field key")
+		}
+		message = message[n:]
+		field := wireField{fieldNumber: int(key >> 3), wireType: int(key & 7)}
+		switch field.wireType {
+		case wireVarint:
+			field.number, n = binary.Uvarint(message)
+			if n <= 0 {
+				return nil, fmt.Errorf("Could not decode the response: invalid varint of field %d", field.fieldNumber)
+			}
+			message = message[n:]
+		case wireFixed64:
+			if len(message) < 8 {
+				return nil, fmt.Errorf("Could not decode the response: truncated field %d", field.fieldNumber)
+			}
+			field.number = binary.LittleEndian.Uint64(message)
+			message = message[8:]
+		case wireFixed32:
+			if len(message) < 4 {
+				return nil, fmt.Errorf("Could not decode the response: truncated field %d", field.fieldNumber)
+			}
+			field.number = uint64(binary.LittleEndian.Uint32(message))
+			message = message[4:]
+		case wireBytes:
+			length, n := binary.Uvarint(message)
+			if n <= 0 || length > uint64(len(message)-n) {
+				return nil, fmt.Errorf("Could not decode the response: truncated field %d", field.fieldNumber)
+			}
+			field.bytes = message[n : n+int(length)]
+			message = message[n+int(length):]
+		default:
+			return nil, fmt.Errorf("Could not decode the response: unsupported wire type %d of field %d", field.wireType, field.fieldNumber)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// parseFieldPath parses the path of a field, made of field numbers separated by dots
+func parseFieldPath(path string) ([]int, error) {
+	parts := strings.Split(path, ".")
+	fieldNumbers := make([]int, 0, len(parts))
+	for _, part := range parts {
+		fieldNumber, err := strconv.Atoi(part)
+		if err != nil || fieldNumber <= 0 {
+			return nil, fmt.Errorf("Field path '%s' must be made of positive field numbers separated by dots", path)
+		}
+		fieldNumbers = append(fieldNumbers, fieldNumber)
+	}
+	return fieldNumbers, nil
+}
+
+func formatValue(value interface{}) string {
+	if f, ok := value.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+func fieldType(metric *v1alpha1.GRPCMetric) v1alpha1.GRPCFieldType {
+	if metric.FieldType == "" {
+		return v1alpha1.GRPCFieldTypeDouble
+	}
+	return metric.FieldType
+}
+
+func timeout(metric *v1alpha1.GRPCMetric) time.Duration {
+	if metric.TimeoutSeconds <= 0 {
+		return defaultTimeout
+	}
+	return time.Duration(metric.TimeoutSeconds) * time.Second
+}
+
+// Resume should not be used by the gRPC provider since all the work should occur in the Run method
+func (p *Provider) Resume(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, measurement v1alpha1.Measurement) v1alpha1.Measurement {
+	p.logCtx.Warn("GRPC provider should not execute the Resume method")
+	return measurement
+}
+
+// Terminate should not be used by the gRPC provider since all the work should occur in the Run method
+func (p *Provider) Terminate(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, measurement v1alpha1.Measurement) v1alpha1.Measurement {
+	p.logCtx.Warn("GRPC provider should not execute the Terminate method")
+	return measurement
+}
+
+// GarbageCollect is a no-op for the gRPC provider
+func (p *Provider) GarbageCollect(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, limit int) error {
+	return nil
+}
+
+// NewGRPCProvider creates a new gRPC provider. The dial options are added to the options of the connections to the
+// servers
+func NewGRPCProvider(logCtx log.Entry, dialOptions ...grpc.DialOption) *Provider {
+	return &Provider{
+		logCtx:      logCtx,
+		dialOptions: dialOptions,
+	}
+}
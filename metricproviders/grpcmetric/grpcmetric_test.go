@@ -0,0 +1,290 @@
+package grpcmetric
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"math"
+	"net"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/grpc/testdata"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+)
+
+func appendKey(b []byte, fieldNumber, wireType int) []byte {
+	return appendVarint(b, uint64(fieldNumber<<3|wireType))
+}
+
+func appendVarint(b []byte, v uint64) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(buf, v)
+	return append(b, buf[:n]...)
+}
+
+func appendVarintField(b []byte, fieldNumber int, v uint64) []byte {
+	return appendVarint(appendKey(b, fieldNumber, wireVarint), v)
+}
+
+func appendDoubleField(b []byte, fieldNumber int, v float64) []byte {
+	b = appendKey(b, fieldNumber, wireFixed64)
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, math.Float64bits(v))
+	return append(b, buf...)
+}
+
+func appendFloatField(b []byte, fieldNumber int, v float32) []byte {
+	b = appendKey(b, fieldNumber, wireFixed32)
+	buf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(buf, math.Float32bits(v))
+	return append(b, buf...)
+}
+
+func appendMessageField(b []byte, fieldNumber int, message []byte) []byte {
+	b = appendVarint(appendKey(b, fieldNumber, wireBytes), uint64(len(message)))
+	return append(b, message...)
+}
+
+// newServer starts an in-memory gRPC server answering every method with the handler, and returns the dial option
+// connecting to it and the function stopping it
+func newServer(handler func(method string, request []byte) ([]byte, error), opts ...grpc.ServerOption) (grpc.DialOption, func()) {
+	lis := bufconn.Listen(1024 * 1024)
+	opts = append(opts, grpc.UnknownServiceHandler(func(srv interface{}, stream grpc.ServerStream) error {
+		method, _ := grpc.MethodFromServerStream(stream)
+		var request rawMessage
+		if err := stream.RecvMsg(&request); err != nil {
+			return err
+		}
+		response, err := handler(method, request)
+		if err != nil {
+			return err
+		}
+		responseMessage := rawMessage(response)
+		return stream.SendMsg(&responseMessage)
+	}))
+	server := grpc.NewServer(opts...)
+	go server.Serve(lis)
+	dialer := grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.Dial()
+	})
+	return dialer, server.Stop
+}
+
+// metricsResponse returns the response of a method holding a success rate of 0.97 in field 1, a message with a
+// request count of 42 and a latency delta of -3 in field 2, and a healthy flag in field 3
+func metricsResponse() []byte {
+	var counts []byte
+	counts = appendVarintField(counts, 1, 42)
+	counts = appendVarintField(counts, 2, 5) // zigzag encoding of -3
+	var response []byte
+	response = appendDoubleField(response, 1, 0.97)
+	response = appendMessageField(response, 2, counts)
+	response = appendVarintField(response, 3, 1)
+	return response
+}
+
+func newMetric(field string, fieldType v1alpha1.GRPCFieldType, successCondition string) v1alpha1.Metric {
+	return v1alpha1.Metric{
+		Name:             "foo",
+		SuccessCondition: successCondition,
+		Provider: v1alpha1.MetricProvider{
+			GRPC: &v1alpha1.GRPCMetric{
+				Address:   "bufnet",
+				Method:    "/metrics.v1.Metrics/GetSuccessRate",
+				Field:     field,
+				FieldType: fieldType,
+			},
+		},
+	}
+}
+
+func TestType(t *testing.T) {
+	e := log.Entry{}
+	p := NewGRPCProvider(e)
+	assert.Equal(t, ProviderType, p.Type())
+}
+
+func TestRunSuccessfully(t *testing.T) {
+	request := appendVarintField(nil, 1, 7)
+	var receivedMethod string
+	var receivedRequest []byte
+	dialer, stop := newServer(func(method string, req []byte) ([]byte, error) {
+		receivedMethod = method
+		receivedRequest = req
+		return metricsResponse(), nil
+	})
+	defer stop()
+
+	e := log.Entry{}
+	p := NewGRPCProvider(e, dialer)
+	metric := newMetric("1", "", "result >= 0.95")
+	metric.Provider.GRPC.Request = base64.StdEncoding.EncodeToString(request)
+	measurement := p.Run(newAnalysisRun(), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+	assert.Equal(t, "0.97", measurement.Value)
+	assert.NotNil(t, measurement.StartedAt)
+	assert.NotNil(t, measurement.FinishedAt)
+	assert.Equal(t, "/metrics.v1.Metrics/GetSuccessRate", receivedMethod)
+	assert.Equal(t, request, receivedRequest)
+}
+
+func TestRunFailedCondition(t *testing.T) {
+	dialer, stop := newServer(func(method string, req []byte) ([]byte, error) {
+		return metricsResponse(), nil
+	})
+	defer stop()
+
+	e := log.Entry{}
+	p := NewGRPCProvider(e, dialer)
+	measurement := p.Run(newAnalysisRun(), newMetric("1", v1alpha1.GRPCFieldTypeDouble, "result >= 0.99"))
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, measurement.Phase)
+	assert.Equal(t, "0.97", measurement.Value)
+}
+
+func TestRunNestedFields(t *testing.T) {
+	dialer, stop := newServer(func(method string, req []byte) ([]byte, error) {
+		return metricsResponse(), nil
+	})
+	defer stop()
+
+	e := log.Entry{}
+	p := NewGRPCProvider(e, dialer)
+	tests := []struct {
+		field         string
+		fieldType     v1alpha1.GRPCFieldType
+		condition     string
+		expectedValue string
+	}{
+		{"2.1", v1alpha1.GRPCFieldTypeInt64, "result == 42", "42"},
+		{"2.2", v1alpha1.GRPCFieldTypeSint32, "result == -3", "-3"},
+		{"3", v1alpha1.GRPCFieldTypeBool, "result == true", "true"},
+		// Fields set to their default value are not encoded
+		{"2.3", v1alpha1.GRPCFieldTypeUint64, "result == 0", "0"},
+	}
+	for _, test := range tests {
+		measurement := p.Run(newAnalysisRun(), newMetric(test.field, test.fieldType, test.condition))
+		assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase, test.field)
+		assert.Equal(t, test.expectedValue, measurement.Value, test.field)
+	}
+}
+
+func TestRunInvalidField(t *testing.T) {
+	dialer, stop := newServer(func(method string, req []byte) ([]byte, error) {
+		return metricsResponse(), nil
+	})
+	defer stop()
+
+	e := log.Entry{}
+	p := NewGRPCProvider(e, dialer)
+	tests := []struct {
+		field         string
+		fieldType     v1alpha1.GRPCFieldType
+		expectedError string
+	}{
+		{"1", v1alpha1.GRPCFieldTypeInt64, "Field 1 of the response has wire type 1, which does not match the type int64"},
+		{"1.1", v1alpha1.GRPCFieldTypeInt64, "Field 1 of the response is not a message"},
+		{"4.1", v1alpha1.GRPCFieldTypeInt64, "Message field 4 of the response is not set"},
+		{"a.1", v1alpha1.GRPCFieldTypeInt64, "Field path 'a.1' must be made of positive field numbers separated by dots"},
+	}
+	for _, test := range tests {
+		measurement := p.Run(newAnalysisRun(), newMetric(test.field, test.fieldType, "result == 0"))
+		assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase, test.field)
+		assert.Equal(t, test.expectedError, measurement.Message, test.field)
+	}
+}
+
+func TestRunServerError(t *testing.T) {
+	dialer, stop := newServer(func(method string, req []byte) ([]byte, error) {
+		return nil, status.Error(codes.Unavailable, "metrics are not ready")
+	})
+	defer stop()
+
+	e := log.Entry{}
+	p := NewGRPCProvider(e, dialer)
+	measurement := p.Run(newAnalysisRun(), newMetric("1", "", "result >= 0.95"))
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Contains(t, measurement.Message, "metrics are not ready")
+}
+
+func TestRunInvalidRequest(t *testing.T) {
+	e := log.Entry{}
+	p := NewGRPCProvider(e)
+	metric := newMetric("1", "", "result >= 0.95")
+	metric.Provider.GRPC.Request = "not base64!"
+	measurement := p.Run(newAnalysisRun(), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Contains(t, measurement.Message, "Could not decode the request")
+}
+
+func TestRunTLS(t *testing.T) {
+	creds, err := credentials.NewServerTLSFromFile(testdata.Path("server1.pem"), testdata.Path("server1.key"))
+	assert.NoError(t, err)
+	dialer, stop := newServer(func(method string, req []byte) ([]byte, error) {
+		return metricsResponse(), nil
+	}, grpc.Creds(creds))
+	defer stop()
+
+	e := log.Entry{}
+	p := NewGRPCProvider(e, dialer)
+	metric := newMetric("1", "", "result >= 0.95")
+	metric.Provider.GRPC.TLS = &v1alpha1.GRPCMetricTLS{InsecureSkipVerify: true}
+	measurement := p.Run(newAnalysisRun(), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+	assert.Equal(t, "0.97", measurement.Value)
+}
+
+func TestExtractField(t *testing.T) {
+	var message []byte
+	message = appendFloatField(message, 1, 0.5)
+	message = appendVarintField(message, 2, math.MaxUint64) // -1 as an int32 or int64
+	message = appendVarintField(message, 3, 3)              // zigzag encoding of -2
+	message = appendVarintField(message, 4, 10)
+	message = appendVarintField(message, 4, 20)
+
+	tests := []struct {
+		field     string
+		fieldType v1alpha1.GRPCFieldType
+		expected  interface{}
+	}{
+		{"1", v1alpha1.GRPCFieldTypeFloat, float64(0.5)},
+		{"2", v1alpha1.GRPCFieldTypeInt32, float64(-1)},
+		{"2", v1alpha1.GRPCFieldTypeInt64, float64(-1)},
+		{"2", v1alpha1.GRPCFieldTypeUint32, float64(math.MaxUint32)},
+		{"3", v1alpha1.GRPCFieldTypeSint64, float64(-2)},
+		// The last occurrence of a field wins
+		{"4", v1alpha1.GRPCFieldTypeUint64, float64(20)},
+		{"5", v1alpha1.GRPCFieldTypeBool, false},
+	}
+	for _, test := range tests {
+		value, err := extractField(message, test.field, test.fieldType)
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, value, "%s %s", test.field, test.fieldType)
+	}
+
+	_, err := extractField(message[:len(message)-1], "4", v1alpha1.GRPCFieldTypeUint64)
+	assert.EqualError(t, err, "Could not decode the response: invalid varint of field 4")
+	_, err = extractField(appendMessageField(nil, 1, []byte{1, 2, 3})[:3], "1.1", v1alpha1.GRPCFieldTypeUint64)
+	assert.EqualError(t, err, "Could not decode the response: truncated field 1")
+}
+
+func TestResumeTerminateGarbageCollect(t *testing.T) {
+	e := log.NewEntry(log.New())
+	p := NewGRPCProvider(*e)
+	metric := newMetric("1", "", "result >= 0.95")
+	measurement := v1alpha1.Measurement{Phase: v1alpha1.AnalysisPhaseRunning}
+	assert.Equal(t, measurement, p.Resume(newAnalysisRun(), metric, measurement))
+	assert.Equal(t, measurement, p.Terminate(newAnalysisRun(), metric, measurement))
+	assert.NoError(t, p.GarbageCollect(newAnalysisRun(), metric, 0))
+}
+
+func newAnalysisRun() *v1alpha1.AnalysisRun {
+	return &v1alpha1.AnalysisRun{}
+}
@@ -0,0 +1,258 @@
+package incident
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/argoproj/argo-rollouts/utils/evaluate"
+	metricutil "github.com/argoproj/argo-rollouts/utils/metric"
+)
+
+const (
+	// ProviderType indicates the provider is an incident-management API
+	ProviderType = "Incident"
+	// IncidentsMetadataKey is the key of the measurement metadata listing the identifiers of the matching incidents
+	IncidentsMetadataKey = "incidents"
+	// DefaultServiceField is the field of an incident holding its service when the metric does not specify one
+	DefaultServiceField = "service"
+	// DefaultStatusField is the field of an incident holding its status when the metric does not specify one
+	DefaultStatusField = "status"
+	// DefaultIDField is the field of an incident holding its identifier when the metric does not specify one
+	DefaultIDField = "id"
+)
+
+// DefaultActiveStatuses are the statuses of the active incidents when the metric does not specify them
+var DefaultActiveStatuses = []string{"triggered", "acknowledged"}
+
+// Provider counts the active incidents of an incident-management API affecting the service of the metric
+// Implements the Provider Interface
+type Provider struct {
+	logCtx log.Entry
+	client *http.Client
+	// recordResponseBodies records the response bodies of failed requests in the measurement metadata
+	recordResponseBodies bool
+}
+
+// Type indicates provider is an incident provider
+func (p *Provider) Type() string {
+	return ProviderType
+}
+
+// Run lists the incidents, counts the active incidents affecting the service and evaluates the count
+func (p *Provider) Run(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric) v1alpha1.Measurement {
+	startTime := metav1.Now()
+	measurement := v1alpha1.Measurement{
+		StartedAt: &startTime,
+	}
+	incidentMetric := metric.Provider.Incident
+	incidents, err := p.listIncidents(incidentMetric)
+	if err != nil {
+		return p.markError(measurement, incidentMetric, err)
+	}
+
+	ids := []string{}
+	for _, incident := range incidents {
+		if affectsService(incident, incidentMetric) && isActive(incident, incidentMetric) {
+			ids = append(ids, fieldString(incident, idField(incidentMetric)))
+		}
+	}
+	count := len(ids)
+	measurement.Value = strconv.Itoa(count)
+	phase, err := evaluate.EvaluateResult(count, metric, p.logCtx)
+	if err != nil {
+		return metricutil.MarkMeasurementError(measurement, err)
+	}
+	measurement.Phase = phase
+	if count > 0 {
+		sort.Strings(ids)
+		measurement.Metadata = map[string]string{
+			IncidentsMetadataKey: strings.Join(ids, ","),
+		}
+	}
+	finishedTime := metav1.Now()
+	measurement.FinishedAt = &finishedTime
+	return measurement
+}
+
+// listIncidents requests the incidents of the API and returns the list of incidents of the response
+func (p *Provider) listIncidents(metric *v1alpha1.IncidentMetric) ([]map[string]interface{}, error) {
+	request, err := http.NewRequest(http.MethodGet, metric.Address, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, header := range metric.Headers {
+		request.Header.Set(header.Key, header.Value)
+	}
+	response, err := p.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	bodyBytes, err := metricutil.ReadResponseBody(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return nil, &metricutil.ResponseError{Err: fmt.Errorf("received non 2xx response code: %v", response.StatusCode), Body: bodyBytes}
+	}
+	var body interface{}
+	if err := json.Unmarshal(bodyBytes, &body); err != nil {
+		return nil, &metricutil.ResponseError{Err: fmt.Errorf("Could not parse JSON body: %v", err), Body: bodyBytes}
+	}
+	items, ok := fieldValue(body, metric.ItemsField).([]interface{})
+	if !ok {
+		return nil, &metricutil.ResponseError{Err: fmt.Errorf("field '%s' of the response is not a list of incidents", metric.ItemsField), Body: bodyBytes}
+	}
+	incidents := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		incident, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, &metricutil.ResponseError{Err: fmt.Errorf("incident '%v' is not an object", item), Body: bodyBytes}
+		}
+		incidents = append(incidents, incident)
+	}
+	return incidents, nil
+}
+
+// markError marks the measurement as errored, recording the response body when enabled. The values of the headers
+// are redacted from the recorded body
+func (p *Provider) markError(measurement v1alpha1.Measurement, metric *v1alpha1.IncidentMetric, err error) v1alpha1.Measurement {
+	headerValues := make([]string, 0, len(metric.Headers))
+	for _, header := range metric.Headers {
+		headerValues = append(headerValues, header.Value)
+	}
+	return metricutil.MarkMeasurementResponseError(measurement, err, p.recordResponseBodies, headerValues...)
+}
+
+// affectsService returns whether the service of the incident, or one of its services when it affects several, is the
+// service of the metric
+func affectsService(incident map[string]interface{}, metric *v1alpha1.IncidentMetric) bool {
+	field := metric.ServiceField
+	if field == "" {
+		field = DefaultServiceField
+	}
+	switch services := fieldValue(incident, field).(type) {
+	case []interface{}:
+		for _, service := range services {
+			if fmt.Sprint(service) == metric.Service {
+				return true
+			}
+		}
+		return false
+	case nil:
+		return false
+	default:
+		return fmt.Sprint(services) == metric.Service
+	}
+}
+
+// isActive returns whether the status of the incident is one of the active statuses of the metric, ignoring the case
+func isActive(incident map[string]interface{}, metric *v1alpha1.IncidentMetric) bool {
+	field := metric.StatusField
+	if field == "" {
+		field = DefaultStatusField
+	}
+	statuses := metric.ActiveStatuses
+	if len(statuses) == 0 {
+		statuses = DefaultActiveStatuses
+	}
+	status := fieldString(incident, field)
+	for _, active := range statuses {
+		if strings.EqualFold(status, active) {
+			return true
+		}
+	}
+	return false
+}
+
+func idField(metric *v1alpha1.IncidentMetric) string {
+	if metric.IDField == "" {
+		return DefaultIDField
+	}
+	return metric.IDField
+}
+
+// fieldValue returns the value at the dotted path of the field (e.g. service.summary) in the object, or the object
+// itself when the path is empty
+func fieldValue(object interface{}, path string) interface{} {
+	if path == "" {
+		return object
+	}
+	value := object
+	for _, name := range strings.Split(path, ".") {
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = fields[name]
+	}
+	return value
+}
+
+// fieldString returns the value at the dotted path of the field in the object as a string, or an empty string when
+// the field is not set
+func fieldString(object interface{}, path string) string {
+	value := fieldValue(object, path)
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// Address returns the address of the incident API of the metric
+func Address(metric *v1alpha1.IncidentMetric) string {
+	return metric.Address
+}
+
+// Resume should not be used by the incident provider since all the requests should complete immediately
+func (p *Provider) Resume(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, measurement v1alpha1.Measurement) v1alpha1.Measurement {
+	p.logCtx.Warn("Incident provider should not execute the Resume method")
+	return measurement
+}
+
+// Terminate should not be used by the incident provider since all the requests should complete immediately
+func (p *Provider) Terminate(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, measurement v1alpha1.Measurement) v1alpha1.Measurement {
+	p.logCtx.Warn("Incident provider should not execute the Terminate method")
+	return measurement
+}
+
+// GarbageCollect is a no-op for the incident provider
+func (p *Provider) GarbageCollect(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, limit int) error {
+	return nil
+}
+
+// NewIncidentHttpClient returns a http client using the timeout of the metric
+func NewIncidentHttpClient(metric v1alpha1.Metric) *http.Client {
+	var timeout time.Duration
+
+	// Using a default timeout of 10 seconds
+	if metric.Provider.Incident.TimeoutSeconds <= 0 {
+		timeout = time.Duration(10) * time.Second
+	} else {
+		timeout = time.Duration(metric.Provider.Incident.TimeoutSeconds) * time.Second
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+	}
+}
+
+// NewIncidentProvider creates a new incident provider. When recordResponseBodies is true, the response bodies of the
+// failed requests are recorded in the measurement metadata
+func NewIncidentProvider(logCtx log.Entry, client *http.Client, recordResponseBodies bool) *Provider {
+	return &Provider{
+		logCtx:               logCtx,
+		client:               client,
+		recordResponseBodies: recordResponseBodies,
+	}
+}
@@ -0,0 +1,168 @@
+package incident
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	metricutil "github.com/argoproj/argo-rollouts/utils/metric"
+)
+
+func newMetric(address string) v1alpha1.Metric {
+	return v1alpha1.Metric{
+		Name:             "open-incidents",
+		SuccessCondition: "result == 0",
+		Provider: v1alpha1.MetricProvider{
+			Incident: &v1alpha1.IncidentMetric{
+				Address:      address,
+				ItemsField:   "incidents",
+				Service:      "checkout",
+				ServiceField: "service.summary",
+				Headers: []v1alpha1.WebMetricHeader{
+					{Key: "Authorization", Value: "Token token=my-token"},
+				},
+			},
+		},
+	}
+}
+
+func newTestProvider(metric v1alpha1.Metric) *Provider {
+	return NewIncidentProvider(*log.WithField("", ""), NewIncidentHttpClient(metric), false)
+}
+
+// newServer returns a stub of the incident API, checking the headers of the requests
+func newServer(t *testing.T, status int, response string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "Token token=my-token", req.Header.Get("Authorization"))
+		rw.Header().Set("Content-Type", "application/json")
+		rw.WriteHeader(status)
+		io.WriteString(rw, response)
+	}))
+}
+
+const incidentsResponse = `{
+	"incidents": [
+		{"id": "Q2", "title": "Checkout latency", "status": "acknowledged", "service": {"summary": "checkout"}},
+		{"id": "Q1", "title": "Checkout errors", "status": "triggered", "service": {"summary": "checkout"}},
+		{"id": "Q3", "title": "Checkout outage", "status": "resolved", "service": {"summary": "checkout"}},
+		{"id": "Q4", "title": "Search outage", "status": "triggered", "service": {"summary": "search"}}
+	]
+}`
+
+func TestType(t *testing.T) {
+	p := newTestProvider(newMetric(""))
+	assert.Equal(t, ProviderType, p.Type())
+}
+
+func TestRunIncidentPresent(t *testing.T) {
+	server := newServer(t, 200, incidentsResponse)
+	defer server.Close()
+	metric := newMetric(server.URL)
+	p := newTestProvider(metric)
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, measurement.Phase)
+	assert.Equal(t, "2", measurement.Value)
+	assert.Equal(t, "Q1,Q2", measurement.Metadata[IncidentsMetadataKey])
+	assert.NotNil(t, measurement.StartedAt)
+	assert.NotNil(t, measurement.FinishedAt)
+}
+
+func TestRunIncidentPresentPauses(t *testing.T) {
+	server := newServer(t, 200, incidentsResponse)
+	defer server.Close()
+	metric := newMetric(server.URL)
+	metric.InconclusiveCondition = "result > 0"
+	p := newTestProvider(metric)
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseInconclusive, measurement.Phase)
+}
+
+func TestRunClear(t *testing.T) {
+	server := newServer(t, 200, incidentsResponse)
+	defer server.Close()
+	metric := newMetric(server.URL)
+	// The incidents of the service are resolved, or of other services
+	metric.Provider.Incident.ActiveStatuses = []string{"Investigating"}
+	p := newTestProvider(metric)
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+	assert.Equal(t, "0", measurement.Value)
+	assert.Nil(t, measurement.Metadata)
+
+	metric.Provider.Incident.ActiveStatuses = nil
+	metric.Provider.Incident.Service = "payments"
+	measurement = p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+	assert.Equal(t, "0", measurement.Value)
+}
+
+func TestRunMatchesListOfServices(t *testing.T) {
+	server := newServer(t, 200, `[
+		{"key": "INC-7", "state": "OPEN", "services": ["search", "checkout"]},
+		{"key": "INC-8", "state": "OPEN", "services": ["search"]},
+		{"key": "INC-9", "state": "CLOSED", "services": ["checkout"]}
+	]`)
+	defer server.Close()
+	metric := newMetric(server.URL)
+	metric.Provider.Incident.ItemsField = ""
+	metric.Provider.Incident.ServiceField = "services"
+	metric.Provider.Incident.StatusField = "state"
+	metric.Provider.Incident.IDField = "key"
+	metric.Provider.Incident.ActiveStatuses = []string{"open"}
+	p := newTestProvider(metric)
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, measurement.Phase)
+	assert.Equal(t, "1", measurement.Value)
+	assert.Equal(t, "INC-7", measurement.Metadata[IncidentsMetadataKey])
+}
+
+func TestRunNotAList(t *testing.T) {
+	server := newServer(t, 200, `{"incidents": {"id": "Q1"}}`)
+	defer server.Close()
+	metric := newMetric(server.URL)
+	p := newTestProvider(metric)
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "field 'incidents' of the response is not a list of incidents", measurement.Message)
+}
+
+func TestRunNon2xxResponse(t *testing.T) {
+	server := newServer(t, 401, `{"error":"Token token=my-token is invalid"}`)
+	defer server.Close()
+	metric := newMetric(server.URL)
+	p := NewIncidentProvider(*log.WithField("", ""), NewIncidentHttpClient(metric), true)
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "received non 2xx response code: 401", measurement.Message)
+	assert.NotContains(t, measurement.Metadata[metricutil.ResponseBodyMetadataKey], "my-token")
+}
+
+func TestRunInvalidJSON(t *testing.T) {
+	server := newServer(t, 200, `not json`)
+	defer server.Close()
+	metric := newMetric(server.URL)
+	p := newTestProvider(metric)
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Contains(t, measurement.Message, "Could not parse JSON body")
+}
+
+func TestNewIncidentHttpClient(t *testing.T) {
+	metric := newMetric("")
+	assert.Equal(t, 10*time.Second, NewIncidentHttpClient(metric).Timeout)
+	metric.Provider.Incident.TimeoutSeconds = 30
+	assert.Equal(t, 30*time.Second, NewIncidentHttpClient(metric).Timeout)
+}
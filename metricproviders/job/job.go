@@ -29,6 +29,9 @@ const (
 	// AnalysisRunUIDLabelKey is the job's label key containing the uid of the associated AnalysisRun
 	// Also used to filter the job informer
 	AnalysisRunUIDLabelKey = "analysisrun.argoproj.io/uid"
+	// jobDeadlineExceededReason is the reason set by the k8s job controller on the JobFailed condition
+	// when a job is active longer than its activeDeadlineSeconds
+	jobDeadlineExceededReason = "DeadlineExceeded"
 )
 
 var (
@@ -85,7 +88,13 @@ func newMetricJob(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric) (*batchv1.J
 				AnalysisRunUIDLabelKey: string(run.UID),
 			},
 		},
-		Spec: metric.Provider.Job.Spec,
+		Spec: *metric.Provider.Job.Spec.DeepCopy(),
+	}
+	if metric.Provider.Job.BackoffLimit != nil {
+		job.Spec.BackoffLimit = metric.Provider.Job.BackoffLimit
+	}
+	if metric.Provider.Job.ActiveDeadlineSeconds != nil {
+		job.Spec.ActiveDeadlineSeconds = metric.Provider.Job.ActiveDeadlineSeconds
 	}
 	return &job, nil
 }
@@ -147,6 +156,14 @@ func (p *JobProvider) Resume(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric,
 		case batchv1.JobFailed:
 			measurement.FinishedAt = &now
 			measurement.Phase = v1alpha1.AnalysisPhaseFailed
+			if condition.Reason == jobDeadlineExceededReason {
+				measurement.Message = fmt.Sprintf("job %s exceeded its active deadline", job.Name)
+				if job.Spec.ActiveDeadlineSeconds != nil {
+					measurement.Message = fmt.Sprintf("job %s exceeded its active deadline of %ds", job.Name, *job.Spec.ActiveDeadlineSeconds)
+				}
+			} else {
+				measurement.Message = condition.Message
+			}
 		}
 	}
 	if measurement.Phase.Completed() {
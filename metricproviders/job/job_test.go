@@ -18,6 +18,7 @@ import (
 	k8sfake "k8s.io/client-go/kubernetes/fake"
 	kubetesting "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/pointer"
 
 	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
 )
@@ -200,6 +201,39 @@ func TestResumeFailedJob(t *testing.T) {
 	assert.NotNil(t, measurement.FinishedAt)
 }
 
+func TestResumeDeadlineExceededJob(t *testing.T) {
+	run := newRunWithJobMetric()
+	job := newJob(run, batchv1.JobFailed)
+	job.Spec.ActiveDeadlineSeconds = pointer.Int64Ptr(30)
+	job.Status.Conditions[0].Reason = "DeadlineExceeded"
+	job.Status.Conditions[0].Message = "Job was active longer than specified deadline"
+	p := newTestJobProvider(job)
+	measurement := newRunningMeasurement(job.Name)
+	measurement = p.Resume(run, run.Spec.Metrics[0], measurement)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, measurement.Phase)
+	assert.Equal(t, "job dummyrun-metric-abc123 exceeded its active deadline of 30s", measurement.Message)
+	assert.NotNil(t, measurement.FinishedAt)
+}
+
+func TestRunWithBackoffLimitAndActiveDeadlineSeconds(t *testing.T) {
+	p := newTestJobProvider()
+	run := newRunWithJobMetric()
+	run.Spec.Metrics[0].Provider.Job.Spec.BackoffLimit = pointer.Int32Ptr(6)
+	run.Spec.Metrics[0].Provider.Job.BackoffLimit = pointer.Int32Ptr(2)
+	run.Spec.Metrics[0].Provider.Job.ActiveDeadlineSeconds = pointer.Int64Ptr(60)
+	metric := run.Spec.Metrics[0]
+	measurement := p.Run(run, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseRunning, measurement.Phase)
+
+	jobs, err := p.kubeclientset.BatchV1().Jobs(run.Namespace).List(metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), *jobs.Items[0].Spec.BackoffLimit)
+	assert.Equal(t, int64(60), *jobs.Items[0].Spec.ActiveDeadlineSeconds)
+	// the metric's job spec should not be modified
+	assert.Equal(t, int32(6), *run.Spec.Metrics[0].Provider.Job.Spec.BackoffLimit)
+	assert.Nil(t, run.Spec.Metrics[0].Provider.Job.Spec.ActiveDeadlineSeconds)
+}
+
 func TestResumeErrorJob(t *testing.T) {
 	p := newTestJobProvider()
 	run := newRunWithJobMetric()
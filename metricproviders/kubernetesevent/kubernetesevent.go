@@ -0,0 +1,202 @@
+package kubernetesevent
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/argoproj/argo-rollouts/utils/evaluate"
+	metricutil "github.com/argoproj/argo-rollouts/utils/metric"
+)
+
+const (
+	// ProviderType indicates the provider is Kubernetes Events
+	ProviderType = "KubernetesEvent"
+)
+
+// Provider counts the Kubernetes Events matching the selectors of the metric
+// Implements the Provider Interface
+type Provider struct {
+	logCtx        log.Entry
+	kubeclientset kubernetes.Interface
+}
+
+// Type indicates provider is a Kubernetes Event provider
+func (p *Provider) Type() string {
+	return ProviderType
+}
+
+// Run counts the occurrences of the matching events within the window and evaluates the count
+func (p *Provider) Run(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric) v1alpha1.Measurement {
+	startTime := metav1.Now()
+	measurement := v1alpha1.Measurement{
+		StartedAt: &startTime,
+	}
+
+	eventMetric := metric.Provider.KubernetesEvent
+	since, err := windowStart(run, eventMetric, startTime.Time)
+	if err != nil {
+		return metricutil.MarkMeasurementError(measurement, err)
+	}
+	namespace := eventMetric.Namespace
+	if namespace == "" {
+		namespace = run.Namespace
+	}
+	// The field selector narrows down the events returned by the API server, the events are matched again
+	// afterwards since reasons and name prefixes are not supported by field selectors
+	events, err := p.kubeclientset.CoreV1().Events(namespace).List(metav1.ListOptions{
+		FieldSelector: newFieldSelector(eventMetric).String(),
+	})
+	if err != nil {
+		return metricutil.MarkMeasurementError(measurement, err)
+	}
+
+	count := 0
+	for _, event := range events.Items {
+		if matches(eventMetric, event) && !lastSeen(event).Before(since) {
+			count += occurrences(event)
+		}
+	}
+
+	measurement.Value = strconv.Itoa(count)
+	phase, err := evaluate.EvaluateResult(count, metric, p.logCtx)
+	if err != nil {
+		return metricutil.MarkMeasurementError(measurement, err)
+	}
+	measurement.Phase = phase
+	finishedTime := metav1.Now()
+	measurement.FinishedAt = &finishedTime
+	return measurement
+}
+
+// windowStart returns the time from which the events are counted
+func windowStart(run *v1alpha1.AnalysisRun, metric *v1alpha1.KubernetesEventMetric, now time.Time) (time.Time, error) {
+	if metric.Window != "" {
+		window, err := metric.Window.Duration()
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid window string: %v", err)
+		}
+		return now.Add(-window), nil
+	}
+	if run.Status.StartedAt != nil {
+		return run.Status.StartedAt.Time, nil
+	}
+	return run.CreationTimestamp.Time, nil
+}
+
+// newFieldSelector returns the field selector of the events matching the metric
+func newFieldSelector(metric *v1alpha1.KubernetesEventMetric) fields.Selector {
+	set := fields.Set{}
+	if metric.Type != "" {
+		set["type"] = metric.Type
+	}
+	if len(metric.Reasons) == 1 {
+		set["reason"] = metric.Reasons[0]
+	}
+	if metric.InvolvedObject != nil {
+		if metric.InvolvedObject.Kind != "" {
+			set["involvedObject.kind"] = metric.InvolvedObject.Kind
+		}
+		if metric.InvolvedObject.Name != "" {
+			set["involvedObject.name"] = metric.InvolvedObject.Name
+		}
+	}
+	// the terms are sorted since the selector built from a set follows the order of the map
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	terms := make([]fields.Selector, 0, len(keys))
+	for _, key := range keys {
+		terms = append(terms, fields.OneTermEqualSelector(key, set[key]))
+	}
+	return fields.AndSelectors(terms...)
+}
+
+// matches returns whether the event matches the selectors of the metric
+func matches(metric *v1alpha1.KubernetesEventMetric, event corev1.Event) bool {
+	if metric.Type != "" && event.Type != metric.Type {
+		return false
+	}
+	if len(metric.Reasons) > 0 {
+		found := false
+		for _, reason := range metric.Reasons {
+			if event.Reason == reason {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if selector := metric.InvolvedObject; selector != nil {
+		if selector.Kind != "" && event.InvolvedObject.Kind != selector.Kind {
+			return false
+		}
+		if selector.Name != "" && event.InvolvedObject.Name != selector.Name {
+			return false
+		}
+		if !strings.HasPrefix(event.InvolvedObject.Name, selector.NamePrefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// lastSeen returns the time the event last occurred
+func lastSeen(event corev1.Event) time.Time {
+	if event.Series != nil && !event.Series.LastObservedTime.IsZero() {
+		return event.Series.LastObservedTime.Time
+	}
+	if !event.LastTimestamp.IsZero() {
+		return event.LastTimestamp.Time
+	}
+	return event.EventTime.Time
+}
+
+// occurrences returns the number of times the event occurred, since repeated events are aggregated
+func occurrences(event corev1.Event) int {
+	if event.Series != nil && event.Series.Count > 0 {
+		return int(event.Series.Count)
+	}
+	if event.Count > 0 {
+		return int(event.Count)
+	}
+	return 1
+}
+
+// Resume should not be used the Kubernetes Event provider since all the work should occur in the Run method
+func (p *Provider) Resume(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, measurement v1alpha1.Measurement) v1alpha1.Measurement {
+	p.logCtx.Warn("Kubernetes Event provider should not execute the Resume method")
+	return measurement
+}
+
+// Terminate should not be used the Kubernetes Event provider since all the work should occur in the Run method
+func (p *Provider) Terminate(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, measurement v1alpha1.Measurement) v1alpha1.Measurement {
+	p.logCtx.Warn("Kubernetes Event provider should not execute the Terminate method")
+	return measurement
+}
+
+// GarbageCollect is a no-op for the Kubernetes Event provider
+func (p *Provider) GarbageCollect(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, limit int) error {
+	return nil
+}
+
+// NewKubernetesEventProvider creates a new Kubernetes Event provider
+func NewKubernetesEventProvider(logCtx log.Entry, kubeclientset kubernetes.Interface) *Provider {
+	return &Provider{
+		logCtx:        logCtx,
+		kubeclientset: kubeclientset,
+	}
+}
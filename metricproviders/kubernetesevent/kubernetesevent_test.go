@@ -0,0 +1,222 @@
+package kubernetesevent
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	kubetesting "k8s.io/client-go/testing"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+)
+
+func newTestKubernetesEventProvider(objects ...runtime.Object) (*Provider, *k8sfake.Clientset) {
+	logCtx := log.NewEntry(log.New())
+	kubeclient := k8sfake.NewSimpleClientset(objects...)
+	return NewKubernetesEventProvider(*logCtx, kubeclient), kubeclient
+}
+
+func newRun(startedAt time.Time) *v1alpha1.AnalysisRun {
+	started := metav1.NewTime(startedAt)
+	return &v1alpha1.AnalysisRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "run",
+			Namespace: "default",
+		},
+		Status: v1alpha1.AnalysisRunStatus{
+			StartedAt: &started,
+		},
+	}
+}
+
+func newMetric(eventMetric v1alpha1.KubernetesEventMetric) v1alpha1.Metric {
+	return v1alpha1.Metric{
+		Name:             "events",
+		SuccessCondition: "result == 0",
+		FailureCondition: "result > 0",
+		Provider: v1alpha1.MetricProvider{
+			KubernetesEvent: &eventMetric,
+		},
+	}
+}
+
+func newEvent(name, namespace, eventType, reason, kind, objectName string, count int32, lastSeen time.Time) *corev1.Event {
+	return &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Type:   eventType,
+		Reason: reason,
+		InvolvedObject: corev1.ObjectReference{
+			Kind: kind,
+			Name: objectName,
+		},
+		Count:         count,
+		LastTimestamp: metav1.NewTime(lastSeen),
+	}
+}
+
+func TestType(t *testing.T) {
+	p, _ := newTestKubernetesEventProvider()
+	assert.Equal(t, ProviderType, p.Type())
+}
+
+func TestRunNoMatchingEvents(t *testing.T) {
+	now := time.Now()
+	p, _ := newTestKubernetesEventProvider(
+		newEvent("normal", "default", corev1.EventTypeNormal, "Pulled", "Pod", "guestbook-abc", 1, now),
+	)
+	metric := newMetric(v1alpha1.KubernetesEventMetric{
+		Type:    corev1.EventTypeWarning,
+		Reasons: []string{"BackOff"},
+	})
+	measurement := p.Run(newRun(now.Add(-time.Minute)), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+	assert.Equal(t, "0", measurement.Value)
+	assert.NotNil(t, measurement.StartedAt)
+	assert.NotNil(t, measurement.FinishedAt)
+}
+
+func TestRunCountsMatchingEvents(t *testing.T) {
+	now := time.Now()
+	p, _ := newTestKubernetesEventProvider(
+		newEvent("backoff", "default", corev1.EventTypeWarning, "BackOff", "Pod", "guestbook-abc", 3, now),
+		newEvent("failed", "default", corev1.EventTypeWarning, "Failed", "Pod", "guestbook-def", 0, now),
+		newEvent("other-reason", "default", corev1.EventTypeWarning, "Unhealthy", "Pod", "guestbook-abc", 5, now),
+		newEvent("other-kind", "default", corev1.EventTypeWarning, "BackOff", "ReplicaSet", "guestbook-abc", 5, now),
+		newEvent("other-name", "default", corev1.EventTypeWarning, "BackOff", "Pod", "frontend-abc", 5, now),
+		newEvent("other-type", "default", corev1.EventTypeNormal, "BackOff", "Pod", "guestbook-abc", 5, now),
+		newEvent("other-namespace", "other", corev1.EventTypeWarning, "BackOff", "Pod", "guestbook-abc", 5, now),
+	)
+	metric := newMetric(v1alpha1.KubernetesEventMetric{
+		Type:    corev1.EventTypeWarning,
+		Reasons: []string{"BackOff", "Failed"},
+		InvolvedObject: &v1alpha1.EventInvolvedObjectSelector{
+			Kind:       "Pod",
+			NamePrefix: "guestbook-",
+		},
+	})
+	measurement := p.Run(newRun(now.Add(-time.Minute)), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, measurement.Phase)
+	// Events without a count occurred once
+	assert.Equal(t, "4", measurement.Value)
+}
+
+func TestRunUsesMetricNamespaceAndName(t *testing.T) {
+	now := time.Now()
+	p, _ := newTestKubernetesEventProvider(
+		newEvent("backoff", "other", corev1.EventTypeWarning, "BackOff", "Pod", "guestbook-abc", 2, now),
+		newEvent("backoff-other-pod", "other", corev1.EventTypeWarning, "BackOff", "Pod", "guestbook-def", 2, now),
+	)
+	metric := newMetric(v1alpha1.KubernetesEventMetric{
+		Namespace: "other",
+		InvolvedObject: &v1alpha1.EventInvolvedObjectSelector{
+			Name: "guestbook-abc",
+		},
+	})
+	measurement := p.Run(newRun(now.Add(-time.Minute)), metric)
+	assert.Equal(t, "2", measurement.Value)
+}
+
+func TestRunIgnoresEventsBeforeStartOfRun(t *testing.T) {
+	now := time.Now()
+	p, _ := newTestKubernetesEventProvider(
+		newEvent("before", "default", corev1.EventTypeWarning, "BackOff", "Pod", "guestbook-abc", 3, now.Add(-2*time.Minute)),
+		newEvent("after", "default", corev1.EventTypeWarning, "BackOff", "Pod", "guestbook-abc", 1, now),
+	)
+	metric := newMetric(v1alpha1.KubernetesEventMetric{Reasons: []string{"BackOff"}})
+	measurement := p.Run(newRun(now.Add(-time.Minute)), metric)
+	assert.Equal(t, "1", measurement.Value)
+}
+
+func TestRunWindow(t *testing.T) {
+	now := time.Now()
+	p, _ := newTestKubernetesEventProvider(
+		newEvent("old", "default", corev1.EventTypeWarning, "BackOff", "Pod", "guestbook-abc", 3, now.Add(-10*time.Minute)),
+		newEvent("recent", "default", corev1.EventTypeWarning, "BackOff", "Pod", "guestbook-abc", 1, now.Add(-time.Minute)),
+	)
+	metric := newMetric(v1alpha1.KubernetesEventMetric{
+		Reasons: []string{"BackOff"},
+		Window:  "5m",
+	})
+	measurement := p.Run(newRun(now.Add(-time.Hour)), metric)
+	assert.Equal(t, "1", measurement.Value)
+}
+
+func TestRunEventSeries(t *testing.T) {
+	now := time.Now()
+	event := newEvent("series", "default", corev1.EventTypeWarning, "BackOff", "Pod", "guestbook-abc", 0, time.Time{})
+	event.EventTime = metav1.NewMicroTime(now.Add(-time.Hour))
+	event.Series = &corev1.EventSeries{
+		Count:            7,
+		LastObservedTime: metav1.NewMicroTime(now),
+	}
+	p, _ := newTestKubernetesEventProvider(event)
+	metric := newMetric(v1alpha1.KubernetesEventMetric{Reasons: []string{"BackOff"}})
+	measurement := p.Run(newRun(now.Add(-time.Minute)), metric)
+	assert.Equal(t, "7", measurement.Value)
+}
+
+func TestRunInvalidWindow(t *testing.T) {
+	p, _ := newTestKubernetesEventProvider()
+	metric := newMetric(v1alpha1.KubernetesEventMetric{Window: "invalid"})
+	measurement := p.Run(newRun(time.Now()), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Contains(t, measurement.Message, "invalid window string")
+}
+
+func TestRunListError(t *testing.T) {
+	p, kubeclient := newTestKubernetesEventProvider()
+	kubeclient.PrependReactor("list", "events", func(action kubetesting.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("intentional error")
+	})
+	metric := newMetric(v1alpha1.KubernetesEventMetric{})
+	measurement := p.Run(newRun(time.Now()), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "intentional error", measurement.Message)
+}
+
+func TestNewFieldSelector(t *testing.T) {
+	selector := newFieldSelector(&v1alpha1.KubernetesEventMetric{
+		Type:    corev1.EventTypeWarning,
+		Reasons: []string{"BackOff"},
+		InvolvedObject: &v1alpha1.EventInvolvedObjectSelector{
+			Kind: "Pod",
+			Name: "guestbook-abc",
+		},
+	})
+	assert.Equal(t, "involvedObject.kind=Pod,involvedObject.name=guestbook-abc,reason=BackOff,type=Warning", selector.String())
+
+	// Multiple reasons and name prefixes are only matched after listing the events
+	selector = newFieldSelector(&v1alpha1.KubernetesEventMetric{
+		Reasons: []string{"BackOff", "Failed"},
+		InvolvedObject: &v1alpha1.EventInvolvedObjectSelector{
+			NamePrefix: "guestbook-",
+		},
+	})
+	assert.Equal(t, "", selector.String())
+}
+
+func TestResumeShouldNotBeUsed(t *testing.T) {
+	p, _ := newTestKubernetesEventProvider()
+	measurement := v1alpha1.Measurement{Phase: v1alpha1.AnalysisPhaseRunning}
+	assert.Equal(t, measurement, p.Resume(newRun(time.Now()), newMetric(v1alpha1.KubernetesEventMetric{}), measurement))
+}
+
+func TestTerminateShouldNotBeUsed(t *testing.T) {
+	p, _ := newTestKubernetesEventProvider()
+	measurement := v1alpha1.Measurement{Phase: v1alpha1.AnalysisPhaseRunning}
+	assert.Equal(t, measurement, p.Terminate(newRun(time.Now()), newMetric(v1alpha1.KubernetesEventMetric{}), measurement))
+}
+
+func TestGarbageCollect(t *testing.T) {
+	p, _ := newTestKubernetesEventProvider()
+	assert.NoError(t, p.GarbageCollect(newRun(time.Now()), newMetric(v1alpha1.KubernetesEventMetric{}), 0))
+}
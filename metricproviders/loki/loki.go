@@ -0,0 +1,291 @@
+package loki
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/argoproj/argo-rollouts/utils/evaluate"
+	metricutil "github.com/argoproj/argo-rollouts/utils/metric"
+)
+
+const (
+	// ProviderType indicates the provider is Loki
+	ProviderType = "Loki"
+	// DefaultWindow is the window over which the query is evaluated when the metric does not specify one
+	DefaultWindow = 5 * time.Minute
+	// OrgIDHeader is the header holding the tenant of a multi-tenant Loki
+	OrgIDHeader = "X-Scope-OrgID"
+	// resultTypeMatrix is the result type of a LogQL metric query evaluated over a range
+	resultTypeMatrix = "matrix"
+)
+
+var nowFn = func() time.Time { return time.Now() }
+
+// queryResponse is the body returned by the query_range API of Loki
+type queryResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		ResultType string            `json:"resultType"`
+		Result     []json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+// series is a series of the matrix returned by a LogQL metric query. Each value is a [<unix time>, "<value>"] pair
+type series struct {
+	Metric map[string]string   `json:"metric"`
+	Values [][]json.RawMessage `json:"values"`
+}
+
+// Provider evaluates a LogQL metric query over a window and reduces the returned matrix to a single value
+// Implements the Provider Interface
+type Provider struct {
+	logCtx log.Entry
+	client *http.Client
+	// recordResponseBodies records the response bodies of failed queries in the measurement metadata
+	recordResponseBodies bool
+}
+
+// Type indicates provider is a Loki provider
+func (p *Provider) Type() string {
+	return ProviderType
+}
+
+// Run evaluates the query of the metric over its window and evaluates the reduced value
+func (p *Provider) Run(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric) v1alpha1.Measurement {
+	startTime := metav1.Now()
+	measurement := v1alpha1.Measurement{
+		StartedAt: &startTime,
+	}
+
+	queryURL, err := newQueryRangeURL(metric.Provider.Loki, nowFn())
+	if err != nil {
+		return metricutil.MarkMeasurementError(measurement, err)
+	}
+	request, err := http.NewRequest("GET", queryURL, nil)
+	if err != nil {
+		return metricutil.MarkMeasurementError(measurement, err)
+	}
+	if metric.Provider.Loki.OrgID != "" {
+		request.Header.Set(OrgIDHeader, metric.Provider.Loki.OrgID)
+	}
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		return metricutil.MarkMeasurementError(measurement, err)
+	}
+	defer response.Body.Close()
+
+	bodyBytes, err := metricutil.ReadResponseBody(response.Body)
+	if err != nil {
+		return metricutil.MarkMeasurementError(measurement, err)
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		err = &metricutil.ResponseError{Err: newQueryError(response.StatusCode, bodyBytes), Body: bodyBytes}
+		return metricutil.MarkMeasurementResponseError(measurement, err, p.recordResponseBodies)
+	}
+
+	result, err := parseResponse(bodyBytes, metric.Provider.Loki.Reduce)
+	if err != nil {
+		err = &metricutil.ResponseError{Err: err, Body: bodyBytes}
+		return metricutil.MarkMeasurementResponseError(measurement, err, p.recordResponseBodies)
+	}
+
+	measurement.Value = strconv.FormatFloat(result, 'f', -1, 64)
+	if math.IsNaN(result) && metric.NaNHandling == "" {
+		measurement.Phase = v1alpha1.AnalysisPhaseInconclusive
+	} else {
+		phase, err := evaluate.EvaluateResult(result, metric, p.logCtx)
+		if err != nil {
+			return metricutil.MarkMeasurementError(measurement, err)
+		}
+		measurement.Phase = phase
+	}
+	finishedTime := metav1.Now()
+	measurement.FinishedAt = &finishedTime
+	return measurement
+}
+
+// newQueryRangeURL returns the URL of the query_range API evaluating the query over the window preceding now, or
+// preceding the offset of the metric before now
+func newQueryRangeURL(metric *v1alpha1.LokiMetric, now time.Time) (string, error) {
+	address, err := url.Parse(metric.Address)
+	if err != nil {
+		return "", err
+	}
+	window := DefaultWindow
+	if metric.Window != "" {
+		window, err = metric.Window.Duration()
+		if err != nil {
+			return "", fmt.Errorf("invalid window: %v", err)
+		}
+	}
+	// The window ends at the offset, so that the last samples are not in an incomplete bucket
+	now = now.Add(-time.Duration(metric.OffsetSeconds) * time.Second)
+	address.Path = strings.TrimSuffix(address.Path, "/") + "/loki/api/v1/query_range"
+	query := url.Values{}
+	query.Set("query", metric.Query)
+	query.Set("start", strconv.FormatInt(now.Add(-window).UnixNano(), 10))
+	query.Set("end", strconv.FormatInt(now.UnixNano(), 10))
+	if metric.Step != "" {
+		step, err := metric.Step.Duration()
+		if err != nil {
+			return "", fmt.Errorf("invalid step: %v", err)
+		}
+		query.Set("step", strconv.FormatFloat(step.Seconds(), 'f', -1, 64))
+	}
+	address.RawQuery = query.Encode()
+	return address.String(), nil
+}
+
+// newQueryError returns an error describing a failed query. Loki responds to an invalid query with the plain text
+// parse error
+func newQueryError(statusCode int, bodyBytes []byte) error {
+	var errResponse queryResponse
+	if err := json.Unmarshal(bodyBytes, &errResponse); err == nil && errResponse.Error != "" {
+		return fmt.Errorf("query failed with response code %d: %s", statusCode, errResponse.Error)
+	}
+	if message := strings.TrimSpace(string(bodyBytes)); message != "" {
+		return fmt.Errorf("query failed with response code %d: %s", statusCode, message)
+	}
+	return fmt.Errorf("received non 2xx response code: %v", statusCode)
+}
+
+// parseResponse returns the samples of the matrix returned by the query reduced to a single value. A query without
+// any sample evaluates to 0, since LogQL returns no series when no log line matches
+func parseResponse(bodyBytes []byte, reduce v1alpha1.LokiReduce) (float64, error) {
+	var response queryResponse
+	if err := json.Unmarshal(bodyBytes, &response); err != nil {
+		return 0, fmt.Errorf("Could not parse JSON body: %v", err)
+	}
+	if response.Status != "success" {
+		return 0, fmt.Errorf("query failed with status '%s': %s", response.Status, response.Error)
+	}
+	if response.Data.ResultType != resultTypeMatrix {
+		return 0, fmt.Errorf("query returned a '%s' result instead of a matrix: the query must be a LogQL metric query", response.Data.ResultType)
+	}
+	var all []float64
+	var last []float64
+	for _, rawSeries := range response.Data.Result {
+		var s series
+		if err := json.Unmarshal(rawSeries, &s); err != nil {
+			return 0, fmt.Errorf("Could not parse the series: %v", err)
+		}
+		for i, pair := range s.Values {
+			value, err := parseSample(pair)
+			if err != nil {
+				return 0, err
+			}
+			all = append(all, value)
+			if i == len(s.Values)-1 {
+				last = append(last, value)
+			}
+		}
+	}
+	return reduceSamples(all, last, reduce)
+}
+
+// parseSample returns the value of a [<unix time>, "<value>"] pair
+func parseSample(pair []json.RawMessage) (float64, error) {
+	if len(pair) != 2 {
+		return 0, fmt.Errorf("Could not parse the sample: expected a pair of a time and a value")
+	}
+	var value string
+	if err := json.Unmarshal(pair[1], &value); err != nil {
+		return 0, fmt.Errorf("Could not parse the value of the sample: %v", err)
+	}
+	result, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Could not parse the value '%s' of the sample as a number", value)
+	}
+	return result, nil
+}
+
+// reduceSamples reduces the samples of all the series, or the last samples of every series, to a single value
+func reduceSamples(all, last []float64, reduce v1alpha1.LokiReduce) (float64, error) {
+	if len(all) == 0 {
+		return 0, nil
+	}
+	switch reduce {
+	case "", v1alpha1.LokiReduceLast:
+		return sum(last), nil
+	case v1alpha1.LokiReduceSum:
+		return sum(all), nil
+	case v1alpha1.LokiReduceAvg:
+		return sum(all) / float64(len(all)), nil
+	case v1alpha1.LokiReduceMin:
+		result := all[0]
+		for _, value := range all[1:] {
+			result = math.Min(result, value)
+		}
+		return result, nil
+	case v1alpha1.LokiReduceMax:
+		result := all[0]
+		for _, value := range all[1:] {
+			result = math.Max(result, value)
+		}
+		return result, nil
+	}
+	return 0, fmt.Errorf("invalid reduce '%s'", reduce)
+}
+
+func sum(values []float64) float64 {
+	result := 0.0
+	for _, value := range values {
+		result += value
+	}
+	return result
+}
+
+// Resume should not be used the Loki provider since all the work should occur in the Run method
+func (p *Provider) Resume(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, measurement v1alpha1.Measurement) v1alpha1.Measurement {
+	p.logCtx.Warn("Loki provider should not execute the Resume method")
+	return measurement
+}
+
+// Terminate should not be used the Loki provider since all the work should occur in the Run method
+func (p *Provider) Terminate(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, measurement v1alpha1.Measurement) v1alpha1.Measurement {
+	p.logCtx.Warn("Loki provider should not execute the Terminate method")
+	return measurement
+}
+
+// GarbageCollect is a no-op for the Loki provider
+func (p *Provider) GarbageCollect(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, limit int) error {
+	return nil
+}
+
+// NewLokiHttpClient returns a http client using the timeout of the metric
+func NewLokiHttpClient(metric v1alpha1.Metric) *http.Client {
+	var timeout time.Duration
+
+	// Using a default timeout of 10 seconds
+	if metric.Provider.Loki.TimeoutSeconds <= 0 {
+		timeout = time.Duration(10) * time.Second
+	} else {
+		timeout = time.Duration(metric.Provider.Loki.TimeoutSeconds) * time.Second
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+	}
+}
+
+// NewLokiProvider creates a new Loki provider. When recordResponseBodies is true, the response bodies of failed
+// queries are recorded in the measurement metadata
+func NewLokiProvider(logCtx log.Entry, client *http.Client, recordResponseBodies bool) *Provider {
+	return &Provider{
+		logCtx:               logCtx,
+		client:               client,
+		recordResponseBodies: recordResponseBodies,
+	}
+}
@@ -0,0 +1,268 @@
+package loki
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	metricutil "github.com/argoproj/argo-rollouts/utils/metric"
+)
+
+const (
+	matrixResponse = `{"status": "success", "data": {"resultType": "matrix", "result": [
+		{"metric": {"pod": "guestbook-1"}, "values": [[1600000000, "2"], [1600000060, "4"]]},
+		{"metric": {"pod": "guestbook-2"}, "values": [[1600000000, "1"], [1600000060, "3"]]}
+	]}}`
+	emptyResponse   = `{"status": "success", "data": {"resultType": "matrix", "result": []}}`
+	streamsResponse = `{"status": "success", "data": {"resultType": "streams", "result": [{"stream": {"app": "guestbook"}, "values": [["1600000000000000000", "error"]]}]}}`
+	nanResponse     = `{"status": "success", "data": {"resultType": "matrix", "result": [{"metric": {}, "values": [[1600000000, "NaN"]]}]}}`
+	parseErrMessage = "parse error at line 1, col 5: syntax error: unexpected IDENTIFIER"
+)
+
+func newMetric(address, successCondition string) v1alpha1.Metric {
+	return v1alpha1.Metric{
+		Name:             "error-logs",
+		SuccessCondition: successCondition,
+		Provider: v1alpha1.MetricProvider{
+			Loki: &v1alpha1.LokiMetric{
+				Address: address,
+				Query:   `sum by (pod) (count_over_time({app="guestbook"} |= "error" [1m]))`,
+			},
+		},
+	}
+}
+
+func newServer(status int, response string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(status)
+		io.WriteString(rw, response)
+	}))
+}
+
+func TestType(t *testing.T) {
+	p := NewLokiProvider(*log.WithField("", ""), nil, false)
+	assert.Equal(t, ProviderType, p.Type())
+}
+
+func TestRunSuite(t *testing.T) {
+	tests := []struct {
+		name                 string
+		serverStatus         int
+		serverResponse       string
+		reduce               v1alpha1.LokiReduce
+		successCondition     string
+		expectedValue        string
+		expectedPhase        v1alpha1.AnalysisPhase
+		expectedErrorMessage string
+	}{
+		{
+			name:             "last samples are summed by default",
+			serverStatus:     200,
+			serverResponse:   matrixResponse,
+			successCondition: "result < 10",
+			expectedValue:    "7",
+			expectedPhase:    v1alpha1.AnalysisPhaseSuccessful,
+		},
+		{
+			name:             "sum",
+			serverStatus:     200,
+			serverResponse:   matrixResponse,
+			reduce:           v1alpha1.LokiReduceSum,
+			successCondition: "result < 10",
+			expectedValue:    "10",
+			expectedPhase:    v1alpha1.AnalysisPhaseFailed,
+		},
+		{
+			name:             "avg",
+			serverStatus:     200,
+			serverResponse:   matrixResponse,
+			reduce:           v1alpha1.LokiReduceAvg,
+			successCondition: "result < 3",
+			expectedValue:    "2.5",
+			expectedPhase:    v1alpha1.AnalysisPhaseSuccessful,
+		},
+		{
+			name:             "min",
+			serverStatus:     200,
+			serverResponse:   matrixResponse,
+			reduce:           v1alpha1.LokiReduceMin,
+			successCondition: "result == 1",
+			expectedValue:    "1",
+			expectedPhase:    v1alpha1.AnalysisPhaseSuccessful,
+		},
+		{
+			name:             "max",
+			serverStatus:     200,
+			serverResponse:   matrixResponse,
+			reduce:           v1alpha1.LokiReduceMax,
+			successCondition: "result < 4",
+			expectedValue:    "4",
+			expectedPhase:    v1alpha1.AnalysisPhaseFailed,
+		},
+		{
+			name:             "no matching log lines",
+			serverStatus:     200,
+			serverResponse:   emptyResponse,
+			successCondition: "result == 0",
+			expectedValue:    "0",
+			expectedPhase:    v1alpha1.AnalysisPhaseSuccessful,
+		},
+		{
+			name:             "NaN",
+			serverStatus:     200,
+			serverResponse:   nanResponse,
+			successCondition: "result < 10",
+			expectedValue:    "NaN",
+			expectedPhase:    v1alpha1.AnalysisPhaseInconclusive,
+		},
+		{
+			name:                 "log query",
+			serverStatus:         200,
+			serverResponse:       streamsResponse,
+			successCondition:     "result < 10",
+			expectedPhase:        v1alpha1.AnalysisPhaseError,
+			expectedErrorMessage: "query returned a 'streams' result instead of a matrix: the query must be a LogQL metric query",
+		},
+		{
+			name:                 "invalid query",
+			serverStatus:         400,
+			serverResponse:       parseErrMessage + "\n",
+			successCondition:     "result < 10",
+			expectedPhase:        v1alpha1.AnalysisPhaseError,
+			expectedErrorMessage: "query failed with response code 400: " + parseErrMessage,
+		},
+		{
+			name:                 "error status",
+			serverStatus:         200,
+			serverResponse:       `{"status": "error", "error": "max entries limit exceeded"}`,
+			successCondition:     "result < 10",
+			expectedPhase:        v1alpha1.AnalysisPhaseError,
+			expectedErrorMessage: "query failed with status 'error': max entries limit exceeded",
+		},
+		{
+			name:                 "invalid JSON body",
+			serverStatus:         200,
+			serverResponse:       `matrix`,
+			successCondition:     "result < 10",
+			expectedPhase:        v1alpha1.AnalysisPhaseError,
+			expectedErrorMessage: "Could not parse JSON body: invalid character 'm' looking for beginning of value",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newServer(test.serverStatus, test.serverResponse)
+			defer server.Close()
+			metric := newMetric(server.URL, test.successCondition)
+			metric.Provider.Loki.Reduce = test.reduce
+			p := NewLokiProvider(*log.WithField("", ""), NewLokiHttpClient(metric), false)
+
+			measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+			assert.Equal(t, test.expectedPhase, measurement.Phase)
+			assert.Equal(t, test.expectedValue, measurement.Value)
+			assert.Equal(t, test.expectedErrorMessage, measurement.Message)
+			assert.NotNil(t, measurement.StartedAt)
+			assert.NotNil(t, measurement.FinishedAt)
+		})
+	}
+}
+
+func TestRunQueryRangeRequest(t *testing.T) {
+	now := time.Unix(1600000300, 0)
+	nowFn = func() time.Time { return now }
+	defer func() { nowFn = time.Now }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, http.MethodGet, req.Method)
+		assert.Equal(t, "/loki/api/v1/query_range", req.URL.Path)
+		assert.Equal(t, "team-a", req.Header.Get(OrgIDHeader))
+		query := req.URL.Query()
+		assert.Equal(t, `sum by (pod) (count_over_time({app="guestbook"} |= "error" [1m]))`, query.Get("query"))
+		assert.Equal(t, "1600000000000000000", query.Get("start"))
+		assert.Equal(t, "1600000300000000000", query.Get("end"))
+		assert.Equal(t, "30", query.Get("step"))
+		io.WriteString(rw, emptyResponse)
+	}))
+	defer server.Close()
+	metric := newMetric(server.URL+"/", "result == 0")
+	metric.Provider.Loki.OrgID = "team-a"
+	metric.Provider.Loki.Step = "30s"
+	p := NewLokiProvider(*log.WithField("", ""), NewLokiHttpClient(metric), false)
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+}
+
+func TestNewQueryRangeURLWindow(t *testing.T) {
+	now := time.Unix(1600003600, 0)
+	metric := &v1alpha1.LokiMetric{Address: "http://loki:3100", Query: `count_over_time({app="guestbook"}[1m])`, Window: "1h"}
+	queryURL, err := newQueryRangeURL(metric, now)
+	assert.NoError(t, err)
+	u, err := url.Parse(queryURL)
+	assert.NoError(t, err)
+	assert.Equal(t, "1600000000000000000", u.Query().Get("start"))
+	assert.Equal(t, "", u.Query().Get("step"))
+
+	metric.Window = "an hour"
+	_, err = newQueryRangeURL(metric, now)
+	assert.Error(t, err)
+}
+
+func TestNewQueryRangeURLOffset(t *testing.T) {
+	now := time.Unix(1600000330, 0)
+	metric := &v1alpha1.LokiMetric{Address: "http://loki:3100", Query: `count_over_time({app="guestbook"}[1m])`, OffsetSeconds: 30}
+	queryURL, err := newQueryRangeURL(metric, now)
+	assert.NoError(t, err)
+	u, err := url.Parse(queryURL)
+	assert.NoError(t, err)
+	// the window of 5m ends 30s before the measurement
+	assert.Equal(t, "1600000000000000000", u.Query().Get("start"))
+	assert.Equal(t, "1600000300000000000", u.Query().Get("end"))
+}
+
+func TestRunRecordsResponseBody(t *testing.T) {
+	server := newServer(400, parseErrMessage)
+	defer server.Close()
+	metric := newMetric(server.URL, "result < 10")
+
+	measurement := NewLokiProvider(*log.WithField("", ""), NewLokiHttpClient(metric), false).Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Nil(t, measurement.Metadata)
+
+	measurement = NewLokiProvider(*log.WithField("", ""), NewLokiHttpClient(metric), true).Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, parseErrMessage, measurement.Metadata[metricutil.ResponseBodyMetadataKey])
+}
+
+func TestRunRequestError(t *testing.T) {
+	metric := newMetric("http://", "result < 10")
+	p := NewLokiProvider(*log.WithField("", ""), NewLokiHttpClient(metric), false)
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.NotEmpty(t, measurement.Message)
+}
+
+func TestResumeShouldNotBeUsed(t *testing.T) {
+	p := NewLokiProvider(*log.WithField("", ""), nil, false)
+	measurement := v1alpha1.Measurement{Phase: v1alpha1.AnalysisPhaseRunning}
+	assert.Equal(t, measurement, p.Resume(&v1alpha1.AnalysisRun{}, newMetric("", ""), measurement))
+}
+
+func TestTerminateShouldNotBeUsed(t *testing.T) {
+	p := NewLokiProvider(*log.WithField("", ""), nil, false)
+	measurement := v1alpha1.Measurement{Phase: v1alpha1.AnalysisPhaseRunning}
+	assert.Equal(t, measurement, p.Terminate(&v1alpha1.AnalysisRun{}, newMetric("", ""), measurement))
+}
+
+func TestGarbageCollect(t *testing.T) {
+	p := NewLokiProvider(*log.WithField("", ""), nil, false)
+	assert.NoError(t, p.GarbageCollect(&v1alpha1.AnalysisRun{}, newMetric("", ""), 0))
+}
@@ -3,19 +3,35 @@ package metricproviders
 import (
 	"fmt"
 
+	"github.com/argoproj/argo-rollouts/metricproviders/alertmanager"
+	"github.com/argoproj/argo-rollouts/metricproviders/commitstatus"
+	"github.com/argoproj/argo-rollouts/metricproviders/datadog"
+	"github.com/argoproj/argo-rollouts/metricproviders/decision"
+	"github.com/argoproj/argo-rollouts/metricproviders/elasticsearch"
+	"github.com/argoproj/argo-rollouts/metricproviders/grpcmetric"
+	"github.com/argoproj/argo-rollouts/metricproviders/incident"
+	"github.com/argoproj/argo-rollouts/metricproviders/loki"
+	"github.com/argoproj/argo-rollouts/metricproviders/pingdom"
+	"github.com/argoproj/argo-rollouts/metricproviders/podmetrics"
+	"github.com/argoproj/argo-rollouts/metricproviders/replicasetmetric"
 	"github.com/argoproj/argo-rollouts/metricproviders/wavefront"
+	"github.com/argoproj/argo-rollouts/metricproviders/xray"
 
 	"github.com/argoproj/argo-rollouts/metricproviders/kayenta"
+	"github.com/argoproj/argo-rollouts/metricproviders/kubernetesevent"
+	"github.com/argoproj/argo-rollouts/metricproviders/podexec"
 	"github.com/argoproj/argo-rollouts/metricproviders/webmetric"
 
 	log "github.com/sirupsen/logrus"
 	"k8s.io/client-go/kubernetes"
 	batchlisters "k8s.io/client-go/listers/batch/v1"
+	"k8s.io/client-go/rest"
 
 	"github.com/argoproj/argo-rollouts/metricproviders/job"
 	"github.com/argoproj/argo-rollouts/metricproviders/prometheus"
 
 	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	metricutil "github.com/argoproj/argo-rollouts/utils/metric"
 )
 
 // Provider methods to query a external systems and generate a measurement
@@ -35,14 +51,28 @@ type Provider interface {
 
 type ProviderFactory struct {
 	KubeClient kubernetes.Interface
+	// KubeConfig is the configuration of the Kubernetes client, required to execute commands in pods
+	KubeConfig *rest.Config
 	JobLister  batchlisters.JobLister
+	// AllowedProviders are the provider types which metrics may use. All the provider types are allowed when empty
+	AllowedProviders []string
+	// RecordResponseBodies records the response bodies of the failed calls of the HTTP based providers in the
+	// measurement metadata, truncated and with the secrets redacted
+	RecordResponseBodies bool
+	// MaxResponseBytes is the maximum size of the response bodies read by the HTTP based providers, when their metric
+	// does not set one. Unlimited when 0
+	MaxResponseBytes int64
 }
 
 type ProviderFactoryFunc func(logCtx log.Entry, metric v1alpha1.Metric) (Provider, error)
 
 // NewProvider creates the correct provider based on the provider type of the Metric
 func (f *ProviderFactory) NewProvider(logCtx log.Entry, metric v1alpha1.Metric) (Provider, error) {
-	switch provider := Type(metric); provider {
+	provider := Type(metric)
+	if !IsProviderAllowed(provider, f.AllowedProviders) {
+		return nil, fmt.Errorf("provider '%s' of metric '%s' is not allowed", provider, metric.Name)
+	}
+	switch provider {
 	case prometheus.ProviderType:
 		api, err := prometheus.NewPrometheusAPI(metric)
 		if err != nil {
@@ -53,25 +83,106 @@ func (f *ProviderFactory) NewProvider(logCtx log.Entry, metric v1alpha1.Metric)
 		return job.NewJobProvider(logCtx, f.KubeClient, f.JobLister), nil
 	case kayenta.ProviderType:
 		c := kayenta.NewHttpClient()
-		return kayenta.NewKayentaProvider(logCtx, c), nil
+		return kayenta.NewKayentaProvider(logCtx, *metricutil.LimitResponseBytes(&c, f.MaxResponseBytes)), nil
 	case webmetric.ProviderType:
-		c := webmetric.NewWebMetricHttpClient(metric)
+		c := metricutil.LimitResponseBytes(webmetric.NewWebMetricHttpClient(metric), f.maxResponseBytes(metric))
 		p, err := webmetric.NewWebMetricJsonParser(metric)
 		if err != nil {
 			return nil, err
 		}
-		return webmetric.NewWebMetricProvider(logCtx, c, p), nil
+		return webmetric.NewWebMetricProvider(logCtx, c, p, f.RecordResponseBodies), nil
 	case wavefront.ProviderType:
 		client, err := wavefront.NewWavefrontAPI(metric, f.KubeClient)
 		if err != nil {
 			return nil, err
 		}
 		return wavefront.NewWavefrontProvider(client, logCtx), nil
+	case elasticsearch.ProviderType:
+		c := metricutil.LimitResponseBytes(elasticsearch.NewElasticsearchHttpClient(metric), f.maxResponseBytes(metric))
+		p, err := elasticsearch.NewElasticsearchJsonParser(metric)
+		if err != nil {
+			return nil, err
+		}
+		credentials, err := elasticsearch.NewElasticsearchCredentials(metric, f.KubeClient)
+		if err != nil {
+			return nil, err
+		}
+		return elasticsearch.NewElasticsearchProvider(logCtx, c, p, credentials, f.RecordResponseBodies), nil
+	case kubernetesevent.ProviderType:
+		return kubernetesevent.NewKubernetesEventProvider(logCtx, f.KubeClient), nil
+	case podexec.ProviderType:
+		if f.KubeConfig == nil {
+			return nil, fmt.Errorf("the Kubernetes client configuration required by the PodExec provider is missing")
+		}
+		executor := podexec.NewRemoteExecutor(f.KubeConfig, f.KubeClient)
+		return podexec.NewPodExecProvider(logCtx, f.KubeClient, executor), nil
+	case alertmanager.ProviderType:
+		c := metricutil.LimitResponseBytes(alertmanager.NewAlertmanagerHttpClient(metric), f.maxResponseBytes(metric))
+		return alertmanager.NewAlertmanagerProvider(logCtx, c, f.RecordResponseBodies), nil
+	case decision.ProviderType:
+		c := metricutil.LimitResponseBytes(decision.NewDecisionHttpClient(metric), f.maxResponseBytes(metric))
+		return decision.NewDecisionProvider(logCtx, c, metric, f.RecordResponseBodies)
+	case loki.ProviderType:
+		c := metricutil.LimitResponseBytes(loki.NewLokiHttpClient(metric), f.maxResponseBytes(metric))
+		return loki.NewLokiProvider(logCtx, c, f.RecordResponseBodies), nil
+	case pingdom.ProviderType:
+		c := metricutil.LimitResponseBytes(pingdom.NewPingdomHttpClient(metric), f.maxResponseBytes(metric))
+		return pingdom.NewPingdomProvider(logCtx, c, f.RecordResponseBodies), nil
+	case grpcmetric.ProviderType:
+		return grpcmetric.NewGRPCProvider(logCtx), nil
+	case replicasetmetric.ProviderType:
+		return replicasetmetric.NewReplicaSetProvider(logCtx, f.KubeClient), nil
+	case datadog.ProviderType:
+		c := metricutil.LimitResponseBytes(datadog.NewDatadogHttpClient(metric), f.maxResponseBytes(metric))
+		return datadog.NewDatadogProvider(logCtx, c, f.RecordResponseBodies), nil
+	case podmetrics.ProviderType:
+		return podmetrics.NewPodMetricsProvider(logCtx, podmetrics.NewMetricsServerAPI(f.KubeClient)), nil
+	case incident.ProviderType:
+		c := metricutil.LimitResponseBytes(incident.NewIncidentHttpClient(metric), f.maxResponseBytes(metric))
+		return incident.NewIncidentProvider(logCtx, c, f.RecordResponseBodies), nil
+	case xray.ProviderType:
+		api, err := xray.NewXRayAPI(metric)
+		if err != nil {
+			return nil, err
+		}
+		return xray.NewXRayProvider(api, logCtx), nil
+	case commitstatus.ProviderType:
+		c := metricutil.LimitResponseBytes(commitstatus.NewCommitStatusHttpClient(metric), f.maxResponseBytes(metric))
+		return commitstatus.NewCommitStatusProvider(logCtx, c, f.KubeClient, f.RecordResponseBodies), nil
 	default:
 		return nil, fmt.Errorf("no valid provider in metric '%s'", metric.Name)
 	}
 }
 
+// maxResponseBytes returns the maximum size of the response bodies of the metric, which defaults to the one of the
+// factory
+func (f *ProviderFactory) maxResponseBytes(metric v1alpha1.Metric) int64 {
+	var maxBytes int64
+	if metric.Provider.Web != nil {
+		maxBytes = metric.Provider.Web.MaxResponseBytes
+	} else if metric.Provider.Elasticsearch != nil {
+		maxBytes = metric.Provider.Elasticsearch.MaxResponseBytes
+	} else if metric.Provider.Alertmanager != nil {
+		maxBytes = metric.Provider.Alertmanager.MaxResponseBytes
+	} else if metric.Provider.Decision != nil {
+		maxBytes = metric.Provider.Decision.MaxResponseBytes
+	} else if metric.Provider.Loki != nil {
+		maxBytes = metric.Provider.Loki.MaxResponseBytes
+	} else if metric.Provider.Pingdom != nil {
+		maxBytes = metric.Provider.Pingdom.MaxResponseBytes
+	} else if metric.Provider.Datadog != nil {
+		maxBytes = metric.Provider.Datadog.MaxResponseBytes
+	} else if metric.Provider.Incident != nil {
+		maxBytes = metric.Provider.Incident.MaxResponseBytes
+	} else if metric.Provider.CommitStatus != nil {
+		maxBytes = metric.Provider.CommitStatus.MaxResponseBytes
+	}
+	if maxBytes > 0 {
+		return maxBytes
+	}
+	return f.MaxResponseBytes
+}
+
 func Type(metric v1alpha1.Metric) string {
 	if metric.Provider.Prometheus != nil {
 		return prometheus.ProviderType
@@ -83,6 +194,65 @@ func Type(metric v1alpha1.Metric) string {
 		return webmetric.ProviderType
 	} else if metric.Provider.Wavefront != nil {
 		return wavefront.ProviderType
+	} else if metric.Provider.Elasticsearch != nil {
+		return elasticsearch.ProviderType
+	} else if metric.Provider.KubernetesEvent != nil {
+		return kubernetesevent.ProviderType
+	} else if metric.Provider.PodExec != nil {
+		return podexec.ProviderType
+	} else if metric.Provider.Alertmanager != nil {
+		return alertmanager.ProviderType
+	} else if metric.Provider.Decision != nil {
+		return decision.ProviderType
+	} else if metric.Provider.Loki != nil {
+		return loki.ProviderType
+	} else if metric.Provider.Pingdom != nil {
+		return pingdom.ProviderType
+	} else if metric.Provider.GRPC != nil {
+		return grpcmetric.ProviderType
+	} else if metric.Provider.ReplicaSet != nil {
+		return replicasetmetric.ProviderType
+	} else if metric.Provider.Datadog != nil {
+		return datadog.ProviderType
+	} else if metric.Provider.PodMetrics != nil {
+		return podmetrics.ProviderType
+	} else if metric.Provider.Incident != nil {
+		return incident.ProviderType
+	} else if metric.Provider.XRay != nil {
+		return xray.ProviderType
+	} else if metric.Provider.CommitStatus != nil {
+		return commitstatus.ProviderType
 	}
 	return "Unknown Provider"
 }
+
+// IsProviderAllowed returns whether the provider type is in the allowed provider types. All the provider types are
+// allowed when the list is empty.
+func IsProviderAllowed(provider string, allowedProviders []string) bool {
+	if len(allowedProviders) == 0 {
+		return true
+	}
+	for _, allowed := range allowedProviders {
+		if allowed == provider {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateAllowedProviders returns an error when a metric, or its fallback provider, uses a provider type which is
+// not in the allowed provider types
+func ValidateAllowedProviders(metrics []v1alpha1.Metric, allowedProviders []string) error {
+	for _, metric := range metrics {
+		if provider := Type(metric); !IsProviderAllowed(provider, allowedProviders) {
+			return fmt.Errorf("provider '%s' of metric '%s' is not allowed", provider, metric.Name)
+		}
+		if metric.FallbackProvider != nil {
+			fallback := v1alpha1.Metric{Name: metric.Name, Provider: metric.FallbackProvider.MetricProvider()}
+			if provider := Type(fallback); !IsProviderAllowed(provider, allowedProviders) {
+				return fmt.Errorf("fallback provider '%s' of metric '%s' is not allowed", provider, metric.Name)
+			}
+		}
+	}
+	return nil
+}
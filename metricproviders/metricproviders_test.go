@@ -0,0 +1,67 @@
+package metricproviders
+
+import (
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+)
+
+func TestIsProviderAllowed(t *testing.T) {
+	assert.True(t, IsProviderAllowed("Prometheus", nil))
+	assert.True(t, IsProviderAllowed("Prometheus", []string{"WebMetric", "Prometheus"}))
+	assert.False(t, IsProviderAllowed("job", []string{"WebMetric", "Prometheus"}))
+}
+
+func TestValidateAllowedProviders(t *testing.T) {
+	metric := newPrometheusMetric("http://prometheus:9090")
+	assert.NoError(t, ValidateAllowedProviders([]v1alpha1.Metric{metric}, nil))
+	assert.NoError(t, ValidateAllowedProviders([]v1alpha1.Metric{metric}, []string{"Prometheus"}))
+
+	job := v1alpha1.Metric{
+		Name: "integration-test",
+		Provider: v1alpha1.MetricProvider{
+			Job: &v1alpha1.JobMetric{},
+		},
+	}
+	err := ValidateAllowedProviders([]v1alpha1.Metric{metric, job}, []string{"Prometheus"})
+	assert.EqualError(t, err, "provider 'job' of metric 'integration-test' is not allowed")
+
+	metric.FallbackProvider = &v1alpha1.FallbackMetricProvider{
+		Web: &v1alpha1.WebMetric{},
+	}
+	err = ValidateAllowedProviders([]v1alpha1.Metric{metric}, []string{"Prometheus"})
+	assert.EqualError(t, err, "fallback provider 'WebMetric' of metric 'success-rate' is not allowed")
+}
+
+func TestNewProviderNotAllowed(t *testing.T) {
+	f := ProviderFactory{AllowedProviders: []string{"Prometheus"}}
+	metric := v1alpha1.Metric{
+		Name: "integration-test",
+		Provider: v1alpha1.MetricProvider{
+			Job: &v1alpha1.JobMetric{},
+		},
+	}
+	_, err := f.NewProvider(*log.NewEntry(log.New()), metric)
+	assert.EqualError(t, err, "provider 'job' of metric 'integration-test' is not allowed")
+}
+
+func TestMaxResponseBytes(t *testing.T) {
+	f := ProviderFactory{MaxResponseBytes: 1024}
+	metric := v1alpha1.Metric{
+		Provider: v1alpha1.MetricProvider{
+			Web: &v1alpha1.WebMetric{},
+		},
+	}
+	assert.Equal(t, int64(1024), f.maxResponseBytes(metric))
+
+	metric.Provider.Web.MaxResponseBytes = 2048
+	assert.Equal(t, int64(2048), f.maxResponseBytes(metric))
+
+	metric.Provider = v1alpha1.MetricProvider{
+		Loki: &v1alpha1.LokiMetric{MaxResponseBytes: 512},
+	}
+	assert.Equal(t, int64(512), f.maxResponseBytes(metric))
+}
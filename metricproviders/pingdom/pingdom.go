@@ -0,0 +1,252 @@
+package pingdom
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/argoproj/argo-rollouts/utils/evaluate"
+	metricutil "github.com/argoproj/argo-rollouts/utils/metric"
+)
+
+const (
+	// ProviderType indicates the provider is Pingdom
+	ProviderType = "Pingdom"
+	// DefaultAddress is the address of the Pingdom API used when the metric does not specify one
+	DefaultAddress = "https://api.pingdom.com/api/3.1"
+	// defaultPollInterval is the delay between two polls when the metric does not specify one
+	defaultPollInterval = 30 * time.Second
+	// defaultResultTimeout is how long to wait for a result when the metric does not specify it
+	defaultResultTimeout = 10 * time.Minute
+	// statusUp is the status of a result of a check which passed
+	statusUp = "up"
+	// statusUnknown is the status of a result of a check which could not be determined
+	statusUnknown = "unknown"
+)
+
+// checkResult is a result of a check returned by the results API of Pingdom
+type checkResult struct {
+	Time         int64  `json:"time"`
+	Status       string `json:"status"`
+	ResponseTime int64  `json:"responsetime"`
+	StatusDesc   string `json:"statusdesc"`
+}
+
+// resultsResponse is the response of the results API of Pingdom, listing the most recent results first
+type resultsResponse struct {
+	Results []checkResult `json:"results"`
+}
+
+// Provider evaluates the most recent result of a Pingdom check reported after the measurement started, polling the
+// results of the check until one is reported
+// Implements the Provider Interface
+type Provider struct {
+	logCtx log.Entry
+	client *http.Client
+	// recordResponseBodies records the response bodies of failed requests in the measurement metadata
+	recordResponseBodies bool
+}
+
+// Type indicates provider is a Pingdom provider
+func (p *Provider) Type() string {
+	return ProviderType
+}
+
+// Run starts the measurement and evaluates the result of the check if one was already reported
+func (p *Provider) Run(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric) v1alpha1.Measurement {
+	startTime := metav1.Now()
+	measurement := v1alpha1.Measurement{
+		StartedAt: &startTime,
+	}
+	return p.poll(metric, measurement)
+}
+
+// Resume polls the results of the check again and evaluates the result once one is reported
+func (p *Provider) Resume(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, measurement v1alpha1.Measurement) v1alpha1.Measurement {
+	return p.poll(metric, measurement)
+}
+
+// poll requests the results of the check reported since the measurement started. The measurement keeps running and
+// is resumed after the poll interval when no result is reported yet, until the result timeout expires
+func (p *Provider) poll(metric v1alpha1.Metric, measurement v1alpha1.Measurement) v1alpha1.Measurement {
+	pingdomMetric := metric.Provider.Pingdom
+	result, err := p.latestResult(pingdomMetric, measurement.StartedAt.Time)
+	if err != nil {
+		return p.markError(measurement, pingdomMetric, err)
+	}
+	if result == nil {
+		now := time.Now()
+		if now.Sub(measurement.StartedAt.Time) >= resultTimeout(pingdomMetric) {
+			return metricutil.MarkMeasurementError(measurement, fmt.Errorf("No result of check '%s' reported since %s", pingdomMetric.CheckID, measurement.StartedAt.Format(time.RFC3339)))
+		}
+		resumeTime := metav1.NewTime(now.Add(pollInterval(pingdomMetric)))
+		measurement.Phase = v1alpha1.AnalysisPhaseRunning
+		measurement.ResumeAt = &resumeTime
+		return measurement
+	}
+
+	up := result.Status == statusUp
+	value := map[string]interface{}{
+		"status":       result.Status,
+		"up":           up,
+		"responseTime": result.ResponseTime,
+		"time":         result.Time,
+	}
+	valueBytes, err := json.Marshal(value)
+	if err != nil {
+		return metricutil.MarkMeasurementError(measurement, err)
+	}
+	measurement.Value = string(valueBytes)
+	if !up {
+		measurement.Message = result.StatusDesc
+	}
+	if metric.SuccessCondition == "" && metric.FailureCondition == "" && metric.InconclusiveCondition == "" {
+		measurement.Phase = v1alpha1.AnalysisPhaseFailed
+		if up {
+			measurement.Phase = v1alpha1.AnalysisPhaseSuccessful
+		}
+	} else {
+		phase, err := evaluate.EvaluateResult(value, metric, p.logCtx)
+		if err != nil {
+			return metricutil.MarkMeasurementError(measurement, err)
+		}
+		measurement.Phase = phase
+	}
+	measurement.ResumeAt = nil
+	finishedTime := metav1.Now()
+	measurement.FinishedAt = &finishedTime
+	return measurement
+}
+
+// latestResult returns the most recent result of the check reported at or after the time, or nil if there is none.
+// The results whose status is unknown are ignored
+func (p *Provider) latestResult(metric *v1alpha1.PingdomMetric, from time.Time) (*checkResult, error) {
+	resultsURL, err := newResultsURL(metric, from)
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequest(http.MethodGet, resultsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, header := range metric.Headers {
+		request.Header.Set(header.Key, header.Value)
+	}
+	response, err := p.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	bodyBytes, err := metricutil.ReadResponseBody(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return nil, &metricutil.ResponseError{Err: fmt.Errorf("received non 2xx response code: %v", response.StatusCode), Body: bodyBytes}
+	}
+	var results resultsResponse
+	if err := json.Unmarshal(bodyBytes, &results); err != nil {
+		return nil, &metricutil.ResponseError{Err: fmt.Errorf("Could not parse JSON body: %v", err), Body: bodyBytes}
+	}
+	for i := range results.Results {
+		if results.Results[i].Status != statusUnknown {
+			return &results.Results[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// markError marks the measurement as errored, recording the response body when enabled. The values of the headers
+// are redacted from the recorded body
+func (p *Provider) markError(measurement v1alpha1.Measurement, metric *v1alpha1.PingdomMetric, err error) v1alpha1.Measurement {
+	headerValues := make([]string, 0, len(metric.Headers))
+	for _, header := range metric.Headers {
+		headerValues = append(headerValues, header.Value)
+	}
+	return metricutil.MarkMeasurementResponseError(measurement, err, p.recordResponseBodies, headerValues...)
+}
+
+// newResultsURL returns the URL of the results API listing the results of the check reported at or after the time
+func newResultsURL(metric *v1alpha1.PingdomMetric, from time.Time) (string, error) {
+	address, err := url.Parse(Address(metric))
+	if err != nil {
+		return "", err
+	}
+	address.Path = strings.TrimSuffix(address.Path, "/") + "/results/" + url.PathEscape(metric.CheckID)
+	query := url.Values{}
+	query.Set("from", strconv.FormatInt(from.Unix(), 10))
+	address.RawQuery = query.Encode()
+	return address.String(), nil
+}
+
+// Address returns the address of the Pingdom API of the metric
+func Address(metric *v1alpha1.PingdomMetric) string {
+	if metric.Address == "" {
+		return DefaultAddress
+	}
+	return metric.Address
+}
+
+func pollInterval(metric *v1alpha1.PingdomMetric) time.Duration {
+	if metric.PollIntervalSeconds <= 0 {
+		return defaultPollInterval
+	}
+	return time.Duration(metric.PollIntervalSeconds) * time.Second
+}
+
+func resultTimeout(metric *v1alpha1.PingdomMetric) time.Duration {
+	if metric.ResultTimeoutSeconds <= 0 {
+		return defaultResultTimeout
+	}
+	return time.Duration(metric.ResultTimeoutSeconds) * time.Second
+}
+
+// Terminate stops waiting for a result of the check
+func (p *Provider) Terminate(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, measurement v1alpha1.Measurement) v1alpha1.Measurement {
+	now := metav1.Now()
+	measurement.FinishedAt = &now
+	measurement.Phase = v1alpha1.AnalysisPhaseSuccessful
+	measurement.ResumeAt = nil
+	p.logCtx.Infof("stopped waiting for a result of check '%s'", metric.Provider.Pingdom.CheckID)
+	return measurement
+}
+
+// GarbageCollect is a no-op for the Pingdom provider
+func (p *Provider) GarbageCollect(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, limit int) error {
+	return nil
+}
+
+// NewPingdomHttpClient returns a http client using the timeout of the metric
+func NewPingdomHttpClient(metric v1alpha1.Metric) *http.Client {
+	var timeout time.Duration
+
+	// Using a default timeout of 10 seconds
+	if metric.Provider.Pingdom.TimeoutSeconds <= 0 {
+		timeout = time.Duration(10) * time.Second
+	} else {
+		timeout = time.Duration(metric.Provider.Pingdom.TimeoutSeconds) * time.Second
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+	}
+}
+
+// NewPingdomProvider creates a new Pingdom provider. When recordResponseBodies is true, the response bodies of the
+// failed requests are recorded in the measurement metadata
+func NewPingdomProvider(logCtx log.Entry, client *http.Client, recordResponseBodies bool) *Provider {
+	return &Provider{
+		logCtx:               logCtx,
+		client:               client,
+		recordResponseBodies: recordResponseBodies,
+	}
+}
@@ -0,0 +1,196 @@
+package pingdom
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	metricutil "github.com/argoproj/argo-rollouts/utils/metric"
+)
+
+func newMetric(address string) v1alpha1.Metric {
+	return v1alpha1.Metric{
+		Name: "synthetic-check",
+		Provider: v1alpha1.MetricProvider{
+			Pingdom: &v1alpha1.PingdomMetric{
+				Address: address,
+				CheckID: "85975",
+				Headers: []v1alpha1.WebMetricHeader{{Key: "Authorization", Value: "Bearer my-token"}},
+			},
+		},
+	}
+}
+
+func newTestProvider(metric v1alpha1.Metric) *Provider {
+	return NewPingdomProvider(*log.WithField("", ""), NewPingdomHttpClient(metric), false)
+}
+
+// newServer returns a server responding with the responses in turn, repeating the last one
+func newServer(t *testing.T, status int, responses ...string) (*httptest.Server, *int) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/results/85975", req.URL.Path)
+		assert.NotEmpty(t, req.URL.Query().Get("from"))
+		assert.Equal(t, "Bearer my-token", req.Header.Get("Authorization"))
+		response := responses[len(responses)-1]
+		if requests < len(responses) {
+			response = responses[requests]
+		}
+		requests++
+		rw.Header().Set("Content-Type", "application/json")
+		rw.WriteHeader(status)
+		io.WriteString(rw, response)
+	}))
+	return server, &requests
+}
+
+func TestType(t *testing.T) {
+	p := newTestProvider(newMetric(""))
+	assert.Equal(t, ProviderType, p.Type())
+}
+
+func TestRunUp(t *testing.T) {
+	server, _ := newServer(t, 200, `{"activeprobes":[257],"results":[{"probeid":257,"time":1617035116,"status":"up","responsetime":245,"statusdesc":"OK"}]}`)
+	defer server.Close()
+	metric := newMetric(server.URL)
+	p := newTestProvider(metric)
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+	assert.Equal(t, `{"responseTime":245,"status":"up","time":1617035116,"up":true}`, measurement.Value)
+	assert.Empty(t, measurement.Message)
+	assert.NotNil(t, measurement.StartedAt)
+	assert.NotNil(t, measurement.FinishedAt)
+	assert.Nil(t, measurement.ResumeAt)
+}
+
+func TestRunDown(t *testing.T) {
+	server, _ := newServer(t, 200, `{"results":[{"time":1617035116,"status":"down","responsetime":0,"statusdesc":"Timeout (> 30000 ms)"}]}`)
+	defer server.Close()
+	metric := newMetric(server.URL)
+	p := newTestProvider(metric)
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, measurement.Phase)
+	assert.Equal(t, "Timeout (> 30000 ms)", measurement.Message)
+}
+
+func TestRunEvaluatesConditions(t *testing.T) {
+	server, _ := newServer(t, 200, `{"results":[{"time":1617035116,"status":"up","responsetime":1245}]}`)
+	defer server.Close()
+	metric := newMetric(server.URL)
+	metric.SuccessCondition = "result.up && result.responseTime < 1000"
+	p := newTestProvider(metric)
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, measurement.Phase)
+
+	metric.SuccessCondition = "result.up && result.responseTime < 2000"
+	measurement = p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+}
+
+func TestRunPollsUntilResult(t *testing.T) {
+	server, requests := newServer(t, 200,
+		`{"results":[]}`,
+		`{"results":[{"time":1617035116,"status":"unknown"}]}`,
+		`{"results":[{"time":1617035176,"status":"up","responsetime":245},{"time":1617035116,"status":"unknown"}]}`,
+	)
+	defer server.Close()
+	metric := newMetric(server.URL)
+	metric.Provider.Pingdom.PollIntervalSeconds = 60
+	p := newTestProvider(metric)
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseRunning, measurement.Phase)
+	assert.Nil(t, measurement.FinishedAt)
+	assert.NotNil(t, measurement.ResumeAt)
+	assert.True(t, measurement.ResumeAt.After(time.Now().Add(50*time.Second)))
+
+	// A result whose status is unknown is not a result of the check
+	measurement = p.Resume(&v1alpha1.AnalysisRun{}, metric, measurement)
+	assert.Equal(t, v1alpha1.AnalysisPhaseRunning, measurement.Phase)
+	assert.NotNil(t, measurement.ResumeAt)
+
+	measurement = p.Resume(&v1alpha1.AnalysisRun{}, metric, measurement)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+	assert.Equal(t, `{"responseTime":245,"status":"up","time":1617035176,"up":true}`, measurement.Value)
+	assert.NotNil(t, measurement.FinishedAt)
+	assert.Nil(t, measurement.ResumeAt)
+	assert.Equal(t, 3, *requests)
+}
+
+func TestResumeResultTimeout(t *testing.T) {
+	server, _ := newServer(t, 200, `{"results":[]}`)
+	defer server.Close()
+	metric := newMetric(server.URL)
+	metric.Provider.Pingdom.ResultTimeoutSeconds = 300
+	p := newTestProvider(metric)
+
+	startedAt := metav1.NewTime(time.Now().Add(-5 * time.Minute))
+	measurement := v1alpha1.Measurement{
+		Phase:     v1alpha1.AnalysisPhaseRunning,
+		StartedAt: &startedAt,
+	}
+	measurement = p.Resume(&v1alpha1.AnalysisRun{}, metric, measurement)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "No result of check '85975' reported since "+startedAt.Format(time.RFC3339), measurement.Message)
+}
+
+func TestRunNon2xxResponse(t *testing.T) {
+	server, _ := newServer(t, 403, `{"error":{"statuscode":403,"errormessage":"Bearer my-token is not valid"}}`)
+	defer server.Close()
+	metric := newMetric(server.URL)
+	p := NewPingdomProvider(*log.WithField("", ""), NewPingdomHttpClient(metric), true)
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "received non 2xx response code: 403", measurement.Message)
+	assert.NotContains(t, measurement.Metadata[metricutil.ResponseBodyMetadataKey], "my-token")
+}
+
+func TestRunInvalidJSON(t *testing.T) {
+	server, _ := newServer(t, 200, `not json`)
+	defer server.Close()
+	metric := newMetric(server.URL)
+	p := newTestProvider(metric)
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Contains(t, measurement.Message, "Could not parse JSON body")
+}
+
+func TestTerminate(t *testing.T) {
+	metric := newMetric("")
+	p := newTestProvider(metric)
+	startedAt := metav1.Now()
+	resumeAt := metav1.NewTime(time.Now().Add(time.Minute))
+	measurement := v1alpha1.Measurement{
+		Phase:     v1alpha1.AnalysisPhaseRunning,
+		StartedAt: &startedAt,
+		ResumeAt:  &resumeAt,
+	}
+	measurement = p.Terminate(&v1alpha1.AnalysisRun{}, metric, measurement)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+	assert.NotNil(t, measurement.FinishedAt)
+	assert.Nil(t, measurement.ResumeAt)
+}
+
+func TestNewResultsURL(t *testing.T) {
+	from := time.Unix(1617035116, 0)
+	resultsURL, err := newResultsURL(&v1alpha1.PingdomMetric{CheckID: "85975"}, from)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://api.pingdom.com/api/3.1/results/85975?from=1617035116", resultsURL)
+}
+
+func TestGarbageCollect(t *testing.T) {
+	p := newTestProvider(newMetric(""))
+	assert.NoError(t, p.GarbageCollect(nil, v1alpha1.Metric{}, 0))
+}
@@ -0,0 +1,256 @@
+package podexec
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/argoproj/argo-rollouts/utils/evaluate"
+	metricutil "github.com/argoproj/argo-rollouts/utils/metric"
+)
+
+const (
+	// ProviderType indicates the provider is a command executed in pods
+	ProviderType = "PodExec"
+)
+
+// Executor executes a command in a container of a pod
+type Executor interface {
+	// Exec returns the standard output, the standard error and the exit code of the command
+	Exec(pod *corev1.Pod, container string, command []string) (string, string, int, error)
+}
+
+// Provider executes a command in the selected pods and evaluates its output
+// Implements the Provider Interface
+type Provider struct {
+	logCtx        log.Entry
+	kubeclientset kubernetes.Interface
+	executor      Executor
+}
+
+// Type indicates provider is a PodExec provider
+func (p *Provider) Type() string {
+	return ProviderType
+}
+
+// Run executes the command in the selected pods and evaluates the result
+func (p *Provider) Run(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric) v1alpha1.Measurement {
+	startTime := metav1.Now()
+	measurement := v1alpha1.Measurement{
+		StartedAt: &startTime,
+	}
+
+	execMetric := metric.Provider.PodExec
+	pods, err := p.selectPods(run, execMetric)
+	if err != nil {
+		return metricutil.MarkMeasurementError(measurement, err)
+	}
+	if execMetric.Pods != v1alpha1.PodExecAllPods {
+		pods = pods[:1]
+	}
+
+	results := make([]interface{}, 0, len(pods))
+	values := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		result, value, err := p.exec(pod, execMetric)
+		if err != nil {
+			return metricutil.MarkMeasurementError(measurement, err)
+		}
+		results = append(results, result)
+		values = append(values, value)
+	}
+
+	if execMetric.Pods == v1alpha1.PodExecAllPods {
+		measurement.Value = "[" + strings.Join(values, ",") + "]"
+		phase, err := evaluate.EvaluateResult(results, metric, p.logCtx)
+		if err != nil {
+			return metricutil.MarkMeasurementError(measurement, err)
+		}
+		measurement.Phase = phase
+	} else {
+		measurement.Value = values[0]
+		phase, err := evaluate.EvaluateResult(results[0], metric, p.logCtx)
+		if err != nil {
+			return metricutil.MarkMeasurementError(measurement, err)
+		}
+		measurement.Phase = phase
+	}
+	if measurement.Metadata == nil {
+		measurement.Metadata = map[string]string{}
+	}
+	measurement.Metadata["pods"] = podNames(pods)
+	finishedTime := metav1.Now()
+	measurement.FinishedAt = &finishedTime
+	return measurement
+}
+
+// selectPods returns the running and ready pods matching the pod-template-hash and the selector of the metric,
+// sorted by name so that the same pod is selected across measurements
+func (p *Provider) selectPods(run *v1alpha1.AnalysisRun, metric *v1alpha1.PodExecMetric) ([]*corev1.Pod, error) {
+	podTemplateHash := metric.PodTemplateHash
+	if podTemplateHash == "" {
+		podTemplateHash = run.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+	}
+	if podTemplateHash == "" {
+		return nil, fmt.Errorf("podTemplateHash must be set when the AnalysisRun was not created by a rollout")
+	}
+	selector := labels.NewSelector()
+	if metric.Selector != nil {
+		var err error
+		selector, err = metav1.LabelSelectorAsSelector(metric.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector: %v", err)
+		}
+	}
+	hashRequirement, err := labels.NewRequirement(v1alpha1.DefaultRolloutUniqueLabelKey, "=", []string{podTemplateHash})
+	if err != nil {
+		return nil, fmt.Errorf("invalid podTemplateHash: %v", err)
+	}
+	selector = selector.Add(*hashRequirement)
+
+	podList, err := p.kubeclientset.CoreV1().Pods(run.Namespace).List(metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	pods := []*corev1.Pod{}
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if isRunningAndReady(pod) {
+			pods = append(pods, pod)
+		}
+	}
+	if len(pods) == 0 {
+		return nil, fmt.Errorf("no running and ready pods match the selector '%s'", selector.String())
+	}
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].Name < pods[j].Name
+	})
+	return pods, nil
+}
+
+// isRunningAndReady returns whether the pod is running, ready and not being deleted
+func isRunningAndReady(pod *corev1.Pod) bool {
+	if pod.DeletionTimestamp != nil || pod.Status.Phase != corev1.PodRunning {
+		return false
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// exec executes the command in the pod and returns the result to evaluate along with its string value
+func (p *Provider) exec(pod *corev1.Pod, metric *v1alpha1.PodExecMetric) (interface{}, string, error) {
+	container := metric.Container
+	if container == "" && len(pod.Spec.Containers) > 0 {
+		container = pod.Spec.Containers[0].Name
+	}
+	stdout, stderr, exitCode, err := p.executor.Exec(pod, container, metric.Command)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to execute the command in pod '%s': %v", pod.Name, err)
+	}
+	if metric.Result == v1alpha1.PodExecResultExitCode {
+		return exitCode, strconv.Itoa(exitCode), nil
+	}
+	if exitCode != 0 {
+		return nil, "", fmt.Errorf("command exited with code %d in pod '%s': %s", exitCode, pod.Name, strings.TrimSpace(stderr))
+	}
+	stdout = strings.TrimSpace(stdout)
+	return stdout, stdout, nil
+}
+
+func podNames(pods []*corev1.Pod) string {
+	names := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		names = append(names, pod.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+// Resume should not be used the PodExec provider since all the work should occur in the Run method
+func (p *Provider) Resume(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, measurement v1alpha1.Measurement) v1alpha1.Measurement {
+	p.logCtx.Warn("PodExec provider should not execute the Resume method")
+	return measurement
+}
+
+// Terminate should not be used the PodExec provider since all the work should occur in the Run method
+func (p *Provider) Terminate(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, measurement v1alpha1.Measurement) v1alpha1.Measurement {
+	p.logCtx.Warn("PodExec provider should not execute the Terminate method")
+	return measurement
+}
+
+// GarbageCollect is a no-op for the PodExec provider
+func (p *Provider) GarbageCollect(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, limit int) error {
+	return nil
+}
+
+// NewPodExecProvider creates a new PodExec provider
+func NewPodExecProvider(logCtx log.Entry, kubeclientset kubernetes.Interface, executor Executor) *Provider {
+	return &Provider{
+		logCtx:        logCtx,
+		kubeclientset: kubeclientset,
+		executor:      executor,
+	}
+}
+
+// remoteExecutor executes commands through the exec subresource of the pods
+type remoteExecutor struct {
+	config        *rest.Config
+	kubeclientset kubernetes.Interface
+}
+
+// NewRemoteExecutor returns an executor which executes commands through the Kubernetes exec API
+func NewRemoteExecutor(config *rest.Config, kubeclientset kubernetes.Interface) Executor {
+	return &remoteExecutor{
+		config:        config,
+		kubeclientset: kubeclientset,
+	}
+}
+
+func (e *remoteExecutor) Exec(pod *corev1.Pod, container string, command []string) (string, string, int, error) {
+	req := e.kubeclientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(pod.Namespace).
+		Name(pod.Name).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+	executor, err := remotecommand.NewSPDYExecutor(e.config, http.MethodPost, req.URL())
+	if err != nil {
+		return "", "", 0, err
+	}
+	var stdout, stderr bytes.Buffer
+	err = executor.Stream(remotecommand.StreamOptions{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+	if exitErr, ok := err.(utilexec.ExitError); ok && exitErr.Exited() {
+		return stdout.String(), stderr.String(), exitErr.ExitStatus(), nil
+	}
+	if err != nil {
+		return "", "", 0, err
+	}
+	return stdout.String(), stderr.String(), 0, nil
+}
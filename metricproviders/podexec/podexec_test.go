@@ -0,0 +1,249 @@
+package podexec
+
+import (
+	"fmt"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	kubetesting "k8s.io/client-go/testing"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+)
+
+type execResult struct {
+	stdout   string
+	stderr   string
+	exitCode int
+	err      error
+}
+
+type execCall struct {
+	pod       string
+	container string
+	command   []string
+}
+
+type mockExecutor struct {
+	results map[string]execResult
+	calls   []execCall
+}
+
+func (m *mockExecutor) Exec(pod *corev1.Pod, container string, command []string) (string, string, int, error) {
+	m.calls = append(m.calls, execCall{pod: pod.Name, container: container, command: command})
+	result := m.results[pod.Name]
+	return result.stdout, result.stderr, result.exitCode, result.err
+}
+
+func newTestPodExecProvider(results map[string]execResult, objects ...runtime.Object) (*Provider, *mockExecutor, *k8sfake.Clientset) {
+	logCtx := log.NewEntry(log.New())
+	kubeclient := k8sfake.NewSimpleClientset(objects...)
+	executor := &mockExecutor{results: results}
+	return NewPodExecProvider(*logCtx, kubeclient, executor), executor, kubeclient
+}
+
+func newRun() *v1alpha1.AnalysisRun {
+	return &v1alpha1.AnalysisRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "run",
+			Namespace: metav1.NamespaceDefault,
+			Labels: map[string]string{
+				v1alpha1.DefaultRolloutUniqueLabelKey: "canary",
+			},
+		},
+	}
+}
+
+func newMetric(execMetric v1alpha1.PodExecMetric) v1alpha1.Metric {
+	if execMetric.Command == nil {
+		execMetric.Command = []string{"curl", "-s", "localhost:8080/healthz"}
+	}
+	return v1alpha1.Metric{
+		Name:             "health",
+		SuccessCondition: "result == 'ok'",
+		Provider: v1alpha1.MetricProvider{
+			PodExec: &execMetric,
+		},
+	}
+}
+
+func newPod(name, podTemplateHash string, ready bool) *corev1.Pod {
+	readyStatus := corev1.ConditionFalse
+	if ready {
+		readyStatus = corev1.ConditionTrue
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: metav1.NamespaceDefault,
+			Labels: map[string]string{
+				"app":                                 "guestbook",
+				v1alpha1.DefaultRolloutUniqueLabelKey: podTemplateHash,
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "guestbook"}, {Name: "sidecar"}},
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			Conditions: []corev1.PodCondition{{
+				Type:   corev1.PodReady,
+				Status: readyStatus,
+			}},
+		},
+	}
+}
+
+func TestType(t *testing.T) {
+	p, _, _ := newTestPodExecProvider(nil)
+	assert.Equal(t, ProviderType, p.Type())
+}
+
+func TestRunInCanaryPod(t *testing.T) {
+	p, executor, _ := newTestPodExecProvider(
+		map[string]execResult{"canary-b": {stdout: "ok\n"}},
+		newPod("canary-b", "canary", true),
+		newPod("stable-a", "stable", true),
+		newPod("canary-a", "canary", false),
+	)
+	measurement := p.Run(newRun(), newMetric(v1alpha1.PodExecMetric{}))
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+	assert.Equal(t, "ok", measurement.Value)
+	assert.Equal(t, "canary-b", measurement.Metadata["pods"])
+	assert.NotNil(t, measurement.StartedAt)
+	assert.NotNil(t, measurement.FinishedAt)
+	// The command is executed in the first container by default
+	assert.Equal(t, []execCall{{pod: "canary-b", container: "guestbook", command: []string{"curl", "-s", "localhost:8080/healthz"}}}, executor.calls)
+}
+
+func TestRunInAnyPodSelectsFirstPod(t *testing.T) {
+	p, executor, _ := newTestPodExecProvider(
+		map[string]execResult{"canary-a": {stdout: "ok"}, "canary-b": {stdout: "ok"}},
+		newPod("canary-b", "canary", true),
+		newPod("canary-a", "canary", true),
+	)
+	measurement := p.Run(newRun(), newMetric(v1alpha1.PodExecMetric{Container: "sidecar"}))
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+	assert.Len(t, executor.calls, 1)
+	assert.Equal(t, "canary-a", executor.calls[0].pod)
+	assert.Equal(t, "sidecar", executor.calls[0].container)
+}
+
+func TestRunInAllPods(t *testing.T) {
+	p, executor, _ := newTestPodExecProvider(
+		map[string]execResult{"canary-a": {stdout: "ok"}, "canary-b": {stdout: "degraded"}},
+		newPod("canary-b", "canary", true),
+		newPod("canary-a", "canary", true),
+	)
+	metric := newMetric(v1alpha1.PodExecMetric{Pods: v1alpha1.PodExecAllPods})
+	metric.SuccessCondition = "all(result, {# == 'ok'})"
+	measurement := p.Run(newRun(), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, measurement.Phase)
+	assert.Equal(t, "[ok,degraded]", measurement.Value)
+	assert.Equal(t, "canary-a,canary-b", measurement.Metadata["pods"])
+	assert.Len(t, executor.calls, 2)
+}
+
+func TestRunExitCode(t *testing.T) {
+	p, _, _ := newTestPodExecProvider(
+		map[string]execResult{"canary-a": {stderr: "connection refused", exitCode: 7}},
+		newPod("canary-a", "canary", true),
+	)
+	metric := newMetric(v1alpha1.PodExecMetric{Result: v1alpha1.PodExecResultExitCode})
+	metric.SuccessCondition = "result == 0"
+	measurement := p.Run(newRun(), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, measurement.Phase)
+	assert.Equal(t, "7", measurement.Value)
+}
+
+func TestRunStdoutWithNonZeroExitCode(t *testing.T) {
+	p, _, _ := newTestPodExecProvider(
+		map[string]execResult{"canary-a": {stderr: "connection refused\n", exitCode: 7}},
+		newPod("canary-a", "canary", true),
+	)
+	measurement := p.Run(newRun(), newMetric(v1alpha1.PodExecMetric{}))
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "command exited with code 7 in pod 'canary-a': connection refused", measurement.Message)
+}
+
+func TestRunExecError(t *testing.T) {
+	p, _, _ := newTestPodExecProvider(
+		map[string]execResult{"canary-a": {err: fmt.Errorf("intentional error")}},
+		newPod("canary-a", "canary", true),
+	)
+	measurement := p.Run(newRun(), newMetric(v1alpha1.PodExecMetric{}))
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "failed to execute the command in pod 'canary-a': intentional error", measurement.Message)
+}
+
+func TestRunPodTemplateHashAndSelector(t *testing.T) {
+	other := newPod("other-a", "other", true)
+	other.Labels["app"] = "frontend"
+	p, executor, kubeclient := newTestPodExecProvider(
+		map[string]execResult{"guestbook-a": {stdout: "ok"}},
+		newPod("guestbook-a", "other", true),
+		other,
+	)
+	metric := newMetric(v1alpha1.PodExecMetric{
+		PodTemplateHash: "other",
+		Selector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{"app": "guestbook"},
+		},
+	})
+	measurement := p.Run(newRun(), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+	assert.Equal(t, "guestbook-a", executor.calls[0].pod)
+
+	listAction := kubeclient.Actions()[0].(kubetesting.ListAction)
+	assert.Equal(t, "app=guestbook,rollouts-pod-template-hash=other", listAction.GetListRestrictions().Labels.String())
+}
+
+func TestRunNoReadyPods(t *testing.T) {
+	terminating := newPod("canary-b", "canary", true)
+	now := metav1.Now()
+	terminating.DeletionTimestamp = &now
+	p, _, _ := newTestPodExecProvider(nil, newPod("canary-a", "canary", false), terminating)
+	measurement := p.Run(newRun(), newMetric(v1alpha1.PodExecMetric{}))
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "no running and ready pods match the selector 'rollouts-pod-template-hash=canary'", measurement.Message)
+}
+
+func TestRunWithoutPodTemplateHash(t *testing.T) {
+	p, _, _ := newTestPodExecProvider(nil)
+	run := newRun()
+	run.Labels = nil
+	measurement := p.Run(run, newMetric(v1alpha1.PodExecMetric{}))
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "podTemplateHash must be set when the AnalysisRun was not created by a rollout", measurement.Message)
+}
+
+func TestRunListError(t *testing.T) {
+	p, _, kubeclient := newTestPodExecProvider(nil)
+	kubeclient.PrependReactor("list", "pods", func(action kubetesting.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("intentional error")
+	})
+	measurement := p.Run(newRun(), newMetric(v1alpha1.PodExecMetric{}))
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "intentional error", measurement.Message)
+}
+
+func TestResumeShouldNotBeUsed(t *testing.T) {
+	p, _, _ := newTestPodExecProvider(nil)
+	measurement := v1alpha1.Measurement{Phase: v1alpha1.AnalysisPhaseRunning}
+	assert.Equal(t, measurement, p.Resume(newRun(), newMetric(v1alpha1.PodExecMetric{}), measurement))
+}
+
+func TestTerminateShouldNotBeUsed(t *testing.T) {
+	p, _, _ := newTestPodExecProvider(nil)
+	measurement := v1alpha1.Measurement{Phase: v1alpha1.AnalysisPhaseRunning}
+	assert.Equal(t, measurement, p.Terminate(newRun(), newMetric(v1alpha1.PodExecMetric{}), measurement))
+}
+
+func TestGarbageCollect(t *testing.T) {
+	p, _, _ := newTestPodExecProvider(nil)
+	assert.NoError(t, p.GarbageCollect(newRun(), newMetric(v1alpha1.PodExecMetric{}), 0))
+}
@@ -0,0 +1,202 @@
+package podmetrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/argoproj/argo-rollouts/utils/evaluate"
+	metricutil "github.com/argoproj/argo-rollouts/utils/metric"
+)
+
+const (
+	// ProviderType indicates the provider is the metrics-server
+	ProviderType = "PodMetrics"
+	// podMetricsPath is the path of the pod metrics of a namespace in the metrics.k8s.io API
+	podMetricsPath = "/apis/metrics.k8s.io/v1beta1/namespaces/%s/pods"
+)
+
+// PodMetrics is the resource usage of the containers of a pod served by the metrics.k8s.io API
+type PodMetrics struct {
+	Metadata   metav1.ObjectMeta  `json:"metadata"`
+	Containers []ContainerMetrics `json:"containers"`
+}
+
+// ContainerMetrics is the resource usage of a container of a pod
+type ContainerMetrics struct {
+	Name  string              `json:"name"`
+	Usage corev1.ResourceList `json:"usage"`
+}
+
+// PodMetricsAPI lists the metrics of the pods of a namespace matching a label selector
+type PodMetricsAPI interface {
+	ListPodMetrics(namespace string, selector string) ([]PodMetrics, error)
+}
+
+// metricsServerAPI reads the pod metrics served by the metrics-server through the Kubernetes API server
+type metricsServerAPI struct {
+	client rest.Interface
+}
+
+// ListPodMetrics lists the metrics of the pods of the namespace matching the label selector
+func (a *metricsServerAPI) ListPodMetrics(namespace string, selector string) ([]PodMetrics, error) {
+	body, err := a.client.Get().AbsPath(fmt.Sprintf(podMetricsPath, namespace)).Param("labelSelector", selector).DoRaw()
+	if err != nil {
+		return nil, fmt.Errorf("could not read the pod metrics of the metrics.k8s.io API: %v", err)
+	}
+	var list struct {
+		Items []PodMetrics `json:"items"`
+	}
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, fmt.Errorf("could not parse the pod metrics of the metrics.k8s.io API: %v", err)
+	}
+	return list.Items, nil
+}
+
+// NewMetricsServerAPI returns the API reading the pod metrics served by the metrics-server
+func NewMetricsServerAPI(kubeclientset kubernetes.Interface) PodMetricsAPI {
+	return &metricsServerAPI{
+		client: kubeclientset.Discovery().RESTClient(),
+	}
+}
+
+// Provider reads the CPU and memory usage of the canary and stable pods and evaluates them
+// Implements the Provider Interface
+type Provider struct {
+	logCtx log.Entry
+	api    PodMetricsAPI
+}
+
+// Type indicates provider is a PodMetrics provider
+func (p *Provider) Type() string {
+	return ProviderType
+}
+
+// Run reads the usage of the canary pods, and of the stable pods when their pod-template-hash is set, and evaluates
+// the usage of the canary along with the canary and stable variables
+func (p *Provider) Run(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric) v1alpha1.Measurement {
+	startTime := metav1.Now()
+	measurement := v1alpha1.Measurement{
+		StartedAt: &startTime,
+	}
+
+	podMetricsMetric := metric.Provider.PodMetrics
+	canaryPodTemplateHash := podMetricsMetric.CanaryPodTemplateHash
+	if canaryPodTemplateHash == "" {
+		canaryPodTemplateHash = run.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+	}
+	if canaryPodTemplateHash == "" {
+		return metricutil.MarkMeasurementError(measurement, fmt.Errorf("canaryPodTemplateHash must be set when the AnalysisRun was not created by a rollout"))
+	}
+	canary, err := p.podUsage(run.Namespace, canaryPodTemplateHash)
+	if err != nil {
+		return metricutil.MarkMeasurementError(measurement, err)
+	}
+	vars := map[string]interface{}{
+		"canary": canary,
+	}
+	measurement.Metadata = map[string]string{}
+	if podMetricsMetric.StablePodTemplateHash != "" {
+		stable, err := p.podUsage(run.Namespace, podMetricsMetric.StablePodTemplateHash)
+		if err != nil {
+			return metricutil.MarkMeasurementError(measurement, err)
+		}
+		vars["stable"] = stable
+		stableValue, err := json.Marshal(stable)
+		if err != nil {
+			return metricutil.MarkMeasurementError(measurement, err)
+		}
+		measurement.Metadata["stable"] = string(stableValue)
+	}
+
+	value, err := json.Marshal(canary)
+	if err != nil {
+		return metricutil.MarkMeasurementError(measurement, err)
+	}
+	measurement.Value = string(value)
+	phase, err := evaluate.EvaluateResultWithVars(canary, vars, metric, p.logCtx)
+	if err != nil {
+		return metricutil.MarkMeasurementError(measurement, err)
+	}
+	measurement.Phase = phase
+	finishedTime := metav1.Now()
+	measurement.FinishedAt = &finishedTime
+	return measurement
+}
+
+// podUsage returns the usage of the pods of the pod-template-hash. The metrics-server only serves the metrics of the
+// pods once it scraped them, so the pods which just started are missing
+func (p *Provider) podUsage(namespace string, podTemplateHash string) (map[string]interface{}, error) {
+	selector := labels.SelectorFromSet(labels.Set{v1alpha1.DefaultRolloutUniqueLabelKey: podTemplateHash})
+	pods, err := p.api.ListPodMetrics(namespace, selector.String())
+	if err != nil {
+		return nil, err
+	}
+	if len(pods) == 0 {
+		return nil, fmt.Errorf("no pod metrics match the pod-template-hash '%s'", podTemplateHash)
+	}
+	return usage(pods), nil
+}
+
+// usage returns the number of pods along with the average and the maximum of their cpu in cores and their memory
+// in bytes, summed over their containers
+func usage(pods []PodMetrics) map[string]interface{} {
+	var cpu, memory, maxCPU, maxMemory float64
+	for _, pod := range pods {
+		var podCPU, podMemory float64
+		for _, container := range pod.Containers {
+			if quantity, ok := container.Usage[corev1.ResourceCPU]; ok {
+				// The metrics-server reports the cpu in nanocores
+				podCPU += float64(quantity.ScaledValue(resource.Nano)) / 1e9
+			}
+			if quantity, ok := container.Usage[corev1.ResourceMemory]; ok {
+				podMemory += float64(quantity.Value())
+			}
+		}
+		cpu += podCPU
+		memory += podMemory
+		maxCPU = math.Max(maxCPU, podCPU)
+		maxMemory = math.Max(maxMemory, podMemory)
+	}
+	return map[string]interface{}{
+		"pods":      len(pods),
+		"cpu":       cpu / float64(len(pods)),
+		"memory":    memory / float64(len(pods)),
+		"maxCpu":    maxCPU,
+		"maxMemory": maxMemory,
+	}
+}
+
+// Resume should not be used the PodMetrics provider since all the work should occur in the Run method
+func (p *Provider) Resume(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, measurement v1alpha1.Measurement) v1alpha1.Measurement {
+	p.logCtx.Warn("PodMetrics provider should not execute the Resume method")
+	return measurement
+}
+
+// Terminate should not be used the PodMetrics provider since all the work should occur in the Run method
+func (p *Provider) Terminate(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, measurement v1alpha1.Measurement) v1alpha1.Measurement {
+	p.logCtx.Warn("PodMetrics provider should not execute the Terminate method")
+	return measurement
+}
+
+// GarbageCollect is a no-op for the PodMetrics provider
+func (p *Provider) GarbageCollect(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, limit int) error {
+	return nil
+}
+
+// NewPodMetricsProvider creates a new PodMetrics provider
+func NewPodMetricsProvider(logCtx log.Entry, api PodMetricsAPI) *Provider {
+	return &Provider{
+		logCtx: logCtx,
+		api:    api,
+	}
+}
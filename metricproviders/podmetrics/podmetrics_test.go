@@ -0,0 +1,187 @@
+package podmetrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+)
+
+// fakePodMetricsAPI serves the pod metrics of each label selector
+type fakePodMetricsAPI struct {
+	pods      map[string][]PodMetrics
+	err       error
+	selectors []string
+}
+
+func (f *fakePodMetricsAPI) ListPodMetrics(namespace string, selector string) ([]PodMetrics, error) {
+	f.selectors = append(f.selectors, selector)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.pods[selector], nil
+}
+
+func newPodMetrics(name, cpu, memory string) PodMetrics {
+	return PodMetrics{
+		Metadata: metav1.ObjectMeta{Name: name, Namespace: metav1.NamespaceDefault},
+		Containers: []ContainerMetrics{{
+			Name: "guestbook",
+			Usage: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse(memory),
+			},
+		}},
+	}
+}
+
+func newRun() *v1alpha1.AnalysisRun {
+	return &v1alpha1.AnalysisRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "run",
+			Namespace: metav1.NamespaceDefault,
+			Labels: map[string]string{
+				v1alpha1.DefaultRolloutUniqueLabelKey: "canary",
+			},
+		},
+	}
+}
+
+func newMetric(successCondition, stablePodTemplateHash string) v1alpha1.Metric {
+	return v1alpha1.Metric{
+		Name:             "memory",
+		SuccessCondition: successCondition,
+		Provider: v1alpha1.MetricProvider{
+			PodMetrics: &v1alpha1.PodMetricsMetric{
+				StablePodTemplateHash: stablePodTemplateHash,
+			},
+		},
+	}
+}
+
+func newTestPodMetricsProvider(api PodMetricsAPI) *Provider {
+	logCtx := log.NewEntry(log.New())
+	return NewPodMetricsProvider(*logCtx, api)
+}
+
+func newFakeAPI() *fakePodMetricsAPI {
+	return &fakePodMetricsAPI{
+		pods: map[string][]PodMetrics{
+			v1alpha1.DefaultRolloutUniqueLabelKey + "=canary": {
+				newPodMetrics("canary-1", "250m", "300Mi"),
+				newPodMetrics("canary-2", "150m", "500Mi"),
+			},
+			v1alpha1.DefaultRolloutUniqueLabelKey + "=stable": {
+				newPodMetrics("stable-1", "200m", "250Mi"),
+				newPodMetrics("stable-2", "200m", "250Mi"),
+			},
+		},
+	}
+}
+
+func TestType(t *testing.T) {
+	p := newTestPodMetricsProvider(newFakeAPI())
+	assert.Equal(t, ProviderType, p.Type())
+}
+
+func TestRunComparedToStable(t *testing.T) {
+	api := newFakeAPI()
+	p := newTestPodMetricsProvider(api)
+
+	measurement := p.Run(newRun(), newMetric("canary.memory < 2 * stable.memory", "stable"))
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+	assert.NotNil(t, measurement.FinishedAt)
+	assert.Equal(t, []string{v1alpha1.DefaultRolloutUniqueLabelKey + "=canary", v1alpha1.DefaultRolloutUniqueLabelKey + "=stable"}, api.selectors)
+
+	var canary map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(measurement.Value), &canary))
+	assert.Equal(t, float64(2), canary["pods"])
+	assert.InDelta(t, 0.2, canary["cpu"], 0.0001)
+	assert.Equal(t, float64(400*1024*1024), canary["memory"])
+	assert.Equal(t, float64(500*1024*1024), canary["maxMemory"])
+	assert.Contains(t, measurement.Metadata["stable"], `"memory":262144000`)
+
+	measurement = p.Run(newRun(), newMetric("canary.maxMemory < 2 * stable.maxMemory", "stable"))
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, measurement.Phase)
+}
+
+func TestRunWithoutStable(t *testing.T) {
+	api := newFakeAPI()
+	p := newTestPodMetricsProvider(api)
+
+	measurement := p.Run(newRun(), newMetric("result.cpu < 0.5 && canary.pods == 2", ""))
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+	assert.Len(t, api.selectors, 1)
+	assert.NotContains(t, measurement.Metadata, "stable")
+}
+
+func TestRunWithCanaryPodTemplateHash(t *testing.T) {
+	api := newFakeAPI()
+	p := newTestPodMetricsProvider(api)
+	metric := newMetric("result.pods == 2", "")
+	metric.Provider.PodMetrics.CanaryPodTemplateHash = "stable"
+	run := newRun()
+	run.Labels = nil
+
+	measurement := p.Run(run, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+	assert.Equal(t, []string{v1alpha1.DefaultRolloutUniqueLabelKey + "=stable"}, api.selectors)
+}
+
+func TestRunErrors(t *testing.T) {
+	t.Run("Missing canary pod-template-hash", func(t *testing.T) {
+		p := newTestPodMetricsProvider(newFakeAPI())
+		run := newRun()
+		run.Labels = nil
+		measurement := p.Run(run, newMetric("true", ""))
+		assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+		assert.Contains(t, measurement.Message, "canaryPodTemplateHash must be set")
+	})
+
+	t.Run("No pod metrics", func(t *testing.T) {
+		p := newTestPodMetricsProvider(newFakeAPI())
+		measurement := p.Run(newRun(), newMetric("true", "missing"))
+		assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+		assert.Equal(t, "no pod metrics match the pod-template-hash 'missing'", measurement.Message)
+	})
+
+	t.Run("Metrics API error", func(t *testing.T) {
+		api := newFakeAPI()
+		api.err = fmt.Errorf("the server could not find the requested resource")
+		p := newTestPodMetricsProvider(api)
+		measurement := p.Run(newRun(), newMetric("true", ""))
+		assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+		assert.Equal(t, "the server could not find the requested resource", measurement.Message)
+	})
+}
+
+func TestUsage(t *testing.T) {
+	pod := newPodMetrics("canary-1", "12500000n", "64Mi")
+	pod.Containers = append(pod.Containers, ContainerMetrics{
+		Name: "sidecar",
+		Usage: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("7500000n"),
+			corev1.ResourceMemory: resource.MustParse("16Mi"),
+		},
+	})
+	result := usage([]PodMetrics{pod})
+	assert.Equal(t, 1, result["pods"])
+	assert.InDelta(t, 0.02, result["cpu"], 0.0000001)
+	assert.Equal(t, float64(80*1024*1024), result["memory"])
+	assert.Equal(t, result["cpu"], result["maxCpu"])
+}
+
+func TestResumeTerminateGarbageCollect(t *testing.T) {
+	p := newTestPodMetricsProvider(newFakeAPI())
+	measurement := v1alpha1.Measurement{Phase: v1alpha1.AnalysisPhaseRunning}
+	assert.Equal(t, measurement, p.Resume(newRun(), newMetric("true", ""), measurement))
+	assert.Equal(t, measurement, p.Terminate(newRun(), newMetric("true", ""), measurement))
+	assert.NoError(t, p.GarbageCollect(newRun(), newMetric("true", ""), 10))
+}
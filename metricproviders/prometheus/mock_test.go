@@ -12,6 +12,8 @@ type mockAPI struct {
 	value    model.Value
 	err      error
 	warnings v1.Warnings
+	// values returns a different value for each query, instead of value
+	values map[string]model.Value
 }
 
 // Query performs a query for the given time.
@@ -19,6 +21,9 @@ func (m mockAPI) Query(ctx context.Context, query string, ts time.Time) (model.V
 	if m.err != nil {
 		return nil, m.warnings, m.err
 	}
+	if m.values != nil {
+		return m.values[query], m.warnings, nil
+	}
 	return m.value, m.warnings, nil
 }
 
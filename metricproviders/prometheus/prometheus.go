@@ -2,8 +2,16 @@ package prometheus
 
 import (
 	"context"
+	"crypto/tls"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/api"
@@ -20,8 +28,19 @@ import (
 const (
 	//ProviderType indicates the provider is prometheus
 	ProviderType = "Prometheus"
+	// BaselineMetadataKey is the key of the measurement metadata holding the baseline value of a comparison
+	BaselineMetadataKey = "baseline"
+	// BearerTokenRefreshInterval is the interval at which the bearer token file is re-read
+	BearerTokenRefreshInterval = time.Minute
+	// queryTimeoutGracePeriod is how much longer than the query timeout the client waits for prometheus to respond
+	queryTimeoutGracePeriod = 5 * time.Second
 )
 
+var nowFn = func() time.Time { return time.Now() }
+
+// clientTimeout is how long the client waits for prometheus to respond to a query
+var clientTimeout = 30 * time.Second
+
 // Provider contains all the required components to run a prometheus query
 type Provider struct {
 	api    v1.API
@@ -40,21 +59,153 @@ func (p *Provider) Run(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric) v1alph
 		StartedAt: &startTime,
 	}
 
-	//TODO(dthomson) make timeout configuriable
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), queryClientTimeout(metric.Provider.Prometheus))
 	defer cancel()
 
+	if metric.Provider.Prometheus.BaselineQuery != "" {
+		return p.runComparison(ctx, metric, newMeasurement)
+	}
+
 	response, warnings, err := p.api.Query(ctx, metric.Provider.Prometheus.Query, time.Now())
 	if err != nil {
-		return metricutil.MarkMeasurementError(newMeasurement, err)
+		return metricutil.MarkMeasurementError(newMeasurement, queryError(ctx, metric.Provider.Prometheus, err))
 	}
 
-	newValue, newStatus, err := p.processResponse(metric, response)
+	newValue, newStatus, newMessage, err := p.processResponse(metric, response)
+	newMeasurement.Value = newValue
 	if err != nil {
 		return metricutil.MarkMeasurementError(newMeasurement, err)
 
 	}
-	newMeasurement.Value = newValue
+	newMeasurement.Message = newMessage
+	p.setWarnings(&newMeasurement, warnings)
+
+	newMeasurement.Phase = newStatus
+	finishedTime := metav1.Now()
+	newMeasurement.FinishedAt = &finishedTime
+	return newMeasurement
+}
+
+// runComparison queries prometheus for both the canary and the baseline values, which are evaluated as the canary
+// and baseline variables of the conditions. The baseline captured at the start of the run is reused rather than
+// queried when the metric captures its baseline once
+func (p *Provider) runComparison(ctx context.Context, metric v1alpha1.Metric, newMeasurement v1alpha1.Measurement) v1alpha1.Measurement {
+	now := time.Now()
+	canary, canaryWarnings, err := p.querySingleValue(ctx, metric.Provider.Prometheus.Query, now)
+	if err != nil {
+		return metricutil.MarkMeasurementError(newMeasurement, fmt.Errorf("canary query: %v", queryError(ctx, metric.Provider.Prometheus, err)))
+	}
+	var baseline model.SampleValue
+	var baselineWarnings v1.Warnings
+	if captured := metric.Provider.Prometheus.CapturedBaseline; captured != "" && metric.Provider.Prometheus.BaselineCapture == v1alpha1.BaselineCaptureRunStart {
+		value, err := strconv.ParseFloat(captured, 64)
+		if err != nil {
+			return metricutil.MarkMeasurementError(newMeasurement, fmt.Errorf("invalid captured baseline '%s': %v", captured, err))
+		}
+		baseline = model.SampleValue(value)
+	} else {
+		baseline, baselineWarnings, err = p.querySingleValue(ctx, metric.Provider.Prometheus.BaselineQuery, now)
+		if err != nil {
+			return metricutil.MarkMeasurementError(newMeasurement, fmt.Errorf("baseline query: %v", queryError(ctx, metric.Provider.Prometheus, err)))
+		}
+	}
+
+	newMeasurement.Value = canary.String()
+	p.setWarnings(&newMeasurement, append(canaryWarnings, baselineWarnings...))
+	if newMeasurement.Metadata == nil {
+		newMeasurement.Metadata = map[string]string{}
+	}
+	newMeasurement.Metadata[BaselineMetadataKey] = baseline.String()
+
+	canaryResult := float64(canary)
+	baselineResult := float64(baseline)
+	if (math.IsNaN(canaryResult) || math.IsNaN(baselineResult)) && metric.NaNHandling == "" {
+		newMeasurement.Phase = v1alpha1.AnalysisPhaseInconclusive
+	} else {
+		vars := map[string]interface{}{
+			"canary":   canaryResult,
+			"baseline": baselineResult,
+		}
+		phase, err := evaluate.EvaluateResultWithVars(canaryResult, vars, metric, p.logCtx)
+		if err != nil {
+			return metricutil.MarkMeasurementError(newMeasurement, err)
+		}
+		newMeasurement.Phase = phase
+	}
+	finishedTime := metav1.Now()
+	newMeasurement.FinishedAt = &finishedTime
+	return newMeasurement
+}
+
+// querySingleValue performs a query which must return either a scalar or a vector with a single sample
+func (p *Provider) querySingleValue(ctx context.Context, query string, ts time.Time) (model.SampleValue, v1.Warnings, error) {
+	response, warnings, err := p.api.Query(ctx, query, ts)
+	if err != nil {
+		return 0, warnings, err
+	}
+	switch value := response.(type) {
+	case *model.Scalar:
+		return value.Value, warnings, nil
+	case model.Vector:
+		if len(value) != 1 || value[0] == nil {
+			return 0, warnings, fmt.Errorf("expected a single value, got %d", len(value))
+		}
+		return value[0].Value, warnings, nil
+	default:
+		return 0, warnings, fmt.Errorf("Prometheus metric type not supported")
+	}
+}
+
+// queryClientTimeout returns how long the client waits for prometheus to respond. With a query timeout, the client
+// waits a little longer than the query timeout so that prometheus cancels an expensive query before the client gives
+// up on it
+func queryClientTimeout(metric *v1alpha1.PrometheusMetric) time.Duration {
+	if metric.QueryTimeout == "" {
+		return clientTimeout
+	}
+	queryTimeout, err := metric.QueryTimeout.Duration()
+	if err != nil || queryTimeout+queryTimeoutGracePeriod <= clientTimeout {
+		return clientTimeout
+	}
+	return queryTimeout + queryTimeoutGracePeriod
+}
+
+// queryError returns a clear error when the query timed out, either on the prometheus server or in the client
+func queryError(ctx context.Context, metric *v1alpha1.PrometheusMetric, err error) error {
+	if message, ok := serverTimeoutMessage(err); ok {
+		if metric.QueryTimeout != "" {
+			return fmt.Errorf("Prometheus timed out evaluating the query after the queryTimeout of %s: %s", metric.QueryTimeout, message)
+		}
+		return fmt.Errorf("Prometheus timed out evaluating the query: %s", message)
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("Prometheus did not respond within the client timeout of %s", queryClientTimeout(metric))
+	}
+	return err
+}
+
+// serverTimeoutMessage returns the message of the error reported by prometheus when the evaluation of a query timed out
+func serverTimeoutMessage(err error) (string, bool) {
+	apiErr, ok := err.(*v1.Error)
+	if !ok {
+		return "", false
+	}
+	if apiErr.Type == v1.ErrTimeout {
+		return apiErr.Msg, true
+	}
+	// Prometheus responds to a timed out query with a 503, which the client reports as a server error holding the body
+	var body struct {
+		ErrorType string `json:"errorType"`
+		Error     string `json:"error"`
+	}
+	if json.Unmarshal([]byte(apiErr.Detail), &body) == nil && body.ErrorType == string(v1.ErrTimeout) {
+		return body.Error, true
+	}
+	return "", false
+}
+
+// setWarnings records the warnings returned by prometheus in the metadata of the measurement
+func (p *Provider) setWarnings(newMeasurement *v1alpha1.Measurement, warnings v1.Warnings) {
 	if len(warnings) > 0 {
 		warningMetadata := ""
 		for _, warning := range warnings {
@@ -66,11 +217,6 @@ func (p *Provider) Run(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric) v1alph
 			p.logCtx.Warnf("Prometheus returned the following warnings: %s", warningMetadata)
 		}
 	}
-
-	newMeasurement.Phase = newStatus
-	finishedTime := metav1.Now()
-	newMeasurement.FinishedAt = &finishedTime
-	return newMeasurement
 }
 
 // Resume should not be used the prometheus provider since all the work should occur in the Run method
@@ -90,16 +236,18 @@ func (p *Provider) GarbageCollect(run *v1alpha1.AnalysisRun, metric v1alpha1.Met
 	return nil
 }
 
-func (p *Provider) processResponse(metric v1alpha1.Metric, response model.Value) (string, v1alpha1.AnalysisPhase, error) {
+// processResponse evaluates the conditions with the result of the query, and returns the value of the measurement
+// along with its phase and, for a Failed measurement, its failure message
+func (p *Provider) processResponse(metric v1alpha1.Metric, response model.Value) (string, v1alpha1.AnalysisPhase, string, error) {
 	switch value := response.(type) {
 	case *model.Scalar:
 		valueStr := value.Value.String()
 		result := float64(value.Value)
-		if math.IsNaN(result) {
-			return valueStr, v1alpha1.AnalysisPhaseInconclusive, nil
+		if math.IsNaN(result) && metric.NaNHandling == "" {
+			return valueStr, v1alpha1.AnalysisPhaseInconclusive, "", nil
 		}
-		newStatus := evaluate.EvaluateResult(result, metric, p.logCtx)
-		return valueStr, newStatus, nil
+		newStatus, newMessage, err := p.evaluateResult(result, response, metric)
+		return valueStr, newStatus, newMessage, err
 	case model.Vector:
 		results := make([]float64, 0, len(value))
 		valueStr := "["
@@ -115,16 +263,52 @@ func (p *Provider) processResponse(metric v1alpha1.Metric, response model.Value)
 		}
 		valueStr = valueStr + "]"
 		for _, result := range results {
-			if math.IsNaN(result) {
-				return valueStr, v1alpha1.AnalysisPhaseInconclusive, nil
+			if math.IsNaN(result) && metric.NaNHandling == "" {
+				return valueStr, v1alpha1.AnalysisPhaseInconclusive, "", nil
 			}
 		}
-		newStatus := evaluate.EvaluateResult(results, metric, p.logCtx)
-		return valueStr, newStatus, nil
+		newStatus, newMessage, err := p.evaluateResult(results, response, metric)
+		return valueStr, newStatus, newMessage, err
 	//TODO(dthomson) add other response types
 	default:
-		return "", v1alpha1.AnalysisPhaseError, fmt.Errorf("Prometheus metric type not supported")
+		return "", v1alpha1.AnalysisPhaseError, "", fmt.Errorf("Prometheus metric type not supported")
+	}
+}
+
+// evaluateResult evaluates the conditions with the result and the labels of the response, and evaluates the failure
+// message of the metric when the measurement failed
+func (p *Provider) evaluateResult(result interface{}, response model.Value, metric v1alpha1.Metric) (v1alpha1.AnalysisPhase, string, error) {
+	vars := map[string]interface{}{
+		"labels": sampleLabels(response),
+	}
+	newStatus, err := evaluate.EvaluateResultWithVars(result, vars, metric, p.logCtx)
+	if err != nil {
+		return newStatus, "", err
+	}
+	if newStatus != v1alpha1.AnalysisPhaseFailed || metric.Provider.Prometheus == nil || metric.Provider.Prometheus.FailureMessage == "" {
+		return newStatus, "", nil
+	}
+	message, err := evaluate.EvaluateMessage(result, vars, metric.Provider.Prometheus.FailureMessage)
+	if err != nil {
+		p.logCtx.Warning(err.Error())
+		return newStatus, err.Error(), nil
 	}
+	return newStatus, message, nil
+}
+
+// sampleLabels returns the labels of the sample selected by the query, exposed as the labels variable. A sample is
+// only selected when the query returns a vector with a single sample, such as with topk(1, ...), so the labels are
+// empty for a scalar or a vector with several samples
+func sampleLabels(response model.Value) map[string]interface{} {
+	labels := map[string]interface{}{}
+	vector, ok := response.(model.Vector)
+	if !ok || len(vector) != 1 || vector[0] == nil {
+		return labels
+	}
+	for name, value := range vector[0].Metric {
+		labels[string(name)] = string(value)
+	}
+	return labels
 }
 
 // NewPrometheusProvider Creates a new Prometheus client
@@ -137,8 +321,13 @@ func NewPrometheusProvider(api v1.API, logCtx log.Entry) *Provider {
 
 // NewPrometheusAPI generates a prometheus API from the metric configuration
 func NewPrometheusAPI(metric v1alpha1.Metric) (v1.API, error) {
+	roundTripper, err := newRoundTripper(metric.Provider.Prometheus)
+	if err != nil {
+		return nil, err
+	}
 	client, err := api.NewClient(api.Config{
-		Address: metric.Provider.Prometheus.Address,
+		Address:      metric.Provider.Prometheus.Address,
+		RoundTripper: roundTripper,
 	})
 	if err != nil {
 		return nil, err
@@ -146,3 +335,100 @@ func NewPrometheusAPI(metric v1alpha1.Metric) (v1.API, error) {
 
 	return v1.NewAPI(client), nil
 }
+
+// newRoundTripper returns the round tripper used to query prometheus, which skips the verification of the TLS
+// certificate, attaches the bearer token and sets the query timeout when configured
+func newRoundTripper(metric *v1alpha1.PrometheusMetric) (http.RoundTripper, error) {
+	var roundTripper http.RoundTripper = &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: metric.InsecureSkipVerify,
+		},
+	}
+	if metric.BearerTokenFile != "" {
+		tokenRoundTripper := &bearerTokenFileRoundTripper{
+			path: metric.BearerTokenFile,
+			rt:   roundTripper,
+		}
+		// The token is read upfront so that a missing file is reported when the provider is created
+		if _, err := tokenRoundTripper.getToken(); err != nil {
+			return nil, err
+		}
+		roundTripper = tokenRoundTripper
+	}
+	if metric.QueryTimeout != "" {
+		queryTimeout, err := metric.QueryTimeout.Duration()
+		if err != nil {
+			return nil, fmt.Errorf("invalid queryTimeout '%s': %v", metric.QueryTimeout, err)
+		}
+		roundTripper = &queryTimeoutRoundTripper{
+			// Prometheus accepts the timeout as a number of seconds
+			timeout: strconv.FormatFloat(queryTimeout.Seconds(), 'f', -1, 64),
+			rt:      roundTripper,
+		}
+	}
+	return roundTripper, nil
+}
+
+// queryTimeoutRoundTripper sets the timeout parameter of the queries, after which prometheus cancels their evaluation
+type queryTimeoutRoundTripper struct {
+	timeout string
+	rt      http.RoundTripper
+}
+
+// RoundTrip sets the timeout parameter in the URL of a copy of the request
+func (rt *queryTimeoutRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	query := req.URL.Query()
+	query.Set("timeout", rt.timeout)
+	req.URL.RawQuery = query.Encode()
+	return rt.rt.RoundTrip(req)
+}
+
+// bearerTokenFileRoundTripper attaches the bearer token read from a file to the requests. The file is re-read
+// after BearerTokenRefreshInterval so that rotated tokens are picked up
+type bearerTokenFileRoundTripper struct {
+	path string
+	rt   http.RoundTripper
+
+	mu     sync.Mutex
+	token  string
+	readAt time.Time
+}
+
+// getToken returns the bearer token, re-reading the file if the token was read too long ago
+func (rt *bearerTokenFileRoundTripper) getToken() (string, error) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	now := nowFn()
+	if rt.token != "" && now.Sub(rt.readAt) < BearerTokenRefreshInterval {
+		return rt.token, nil
+	}
+	b, err := ioutil.ReadFile(rt.path)
+	if err != nil {
+		return "", fmt.Errorf("unable to read bearer token file %s: %v", rt.path, err)
+	}
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return "", fmt.Errorf("bearer token file %s is empty", rt.path)
+	}
+	rt.token = token
+	rt.readAt = now
+	return rt.token, nil
+}
+
+// RoundTrip attaches the bearer token to a copy of the request
+func (rt *bearerTokenFileRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := rt.getToken()
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return rt.rt.RoundTrip(req)
+}
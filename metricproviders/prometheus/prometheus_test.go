@@ -1,9 +1,16 @@
 package prometheus
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
 	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
 
@@ -153,6 +160,127 @@ func TestRunWithEvaluationError(t *testing.T) {
 	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
 }
 
+func newComparisonMetric() v1alpha1.Metric {
+	return v1alpha1.Metric{
+		Name:             "error-rate",
+		SuccessCondition: "canary <= baseline * 1.2",
+		Provider: v1alpha1.MetricProvider{
+			Prometheus: &v1alpha1.PrometheusMetric{
+				Query:         "canary",
+				BaselineQuery: "baseline",
+			},
+		},
+	}
+}
+
+func TestRunComparisonSuccessfully(t *testing.T) {
+	e := log.Entry{}
+	mock := mockAPI{
+		values: map[string]model.Value{
+			"canary":   newScalar(1.1),
+			"baseline": model.Vector{{Value: 1}},
+		},
+	}
+	p := NewPrometheusProvider(mock, e)
+	measurement := p.Run(newAnalysisRun(), newComparisonMetric())
+	assert.NotNil(t, measurement.StartedAt)
+	assert.Equal(t, "1.1", measurement.Value)
+	assert.Equal(t, map[string]string{"baseline": "1"}, measurement.Metadata)
+	assert.NotNil(t, measurement.FinishedAt)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+}
+
+func TestRunComparisonFailed(t *testing.T) {
+	e := log.Entry{}
+	mock := mockAPI{
+		values: map[string]model.Value{
+			"canary":   newScalar(1.5),
+			"baseline": newScalar(1),
+		},
+	}
+	p := NewPrometheusProvider(mock, e)
+	measurement := p.Run(newAnalysisRun(), newComparisonMetric())
+	assert.Equal(t, "1.5", measurement.Value)
+	assert.Equal(t, "1", measurement.Metadata["baseline"])
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, measurement.Phase)
+}
+
+func TestRunComparisonNaNBaseline(t *testing.T) {
+	e := log.NewEntry(log.New())
+	mock := mockAPI{
+		values: map[string]model.Value{
+			"canary":   newScalar(1),
+			"baseline": newScalar(math.NaN()),
+		},
+	}
+	p := NewPrometheusProvider(mock, *e)
+	metric := newComparisonMetric()
+	measurement := p.Run(newAnalysisRun(), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseInconclusive, measurement.Phase)
+
+	metric.NaNHandling = v1alpha1.NaNHandlingFail
+	measurement = p.Run(newAnalysisRun(), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, measurement.Phase)
+}
+
+func TestRunComparisonCapturedBaseline(t *testing.T) {
+	e := log.Entry{}
+	// the baseline query is not answered, so that the measurements fail if it is performed
+	mock := mockAPI{
+		values: map[string]model.Value{
+			"canary": newScalar(1.1),
+		},
+	}
+	p := NewPrometheusProvider(mock, e)
+	metric := newComparisonMetric()
+	metric.Provider.Prometheus.BaselineCapture = v1alpha1.BaselineCaptureRunStart
+	metric.Provider.Prometheus.CapturedBaseline = "1"
+	measurement := p.Run(newAnalysisRun(), metric)
+	assert.Equal(t, "1.1", measurement.Value)
+	assert.Equal(t, map[string]string{BaselineMetadataKey: "1"}, measurement.Metadata)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+
+	metric.Provider.Prometheus.CapturedBaseline = "0.5"
+	measurement = p.Run(newAnalysisRun(), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, measurement.Phase)
+
+	// The baseline is queried until it is captured
+	metric.Provider.Prometheus.CapturedBaseline = ""
+	measurement = p.Run(newAnalysisRun(), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Contains(t, measurement.Message, "baseline query:")
+
+	metric.Provider.Prometheus.CapturedBaseline = "not a number"
+	measurement = p.Run(newAnalysisRun(), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Contains(t, measurement.Message, "invalid captured baseline 'not a number'")
+}
+
+func TestRunComparisonMultipleValues(t *testing.T) {
+	e := log.Entry{}
+	mock := mockAPI{
+		values: map[string]model.Value{
+			"canary":   newScalar(1),
+			"baseline": model.Vector{{Value: 1}, {Value: 2}},
+		},
+	}
+	p := NewPrometheusProvider(mock, e)
+	measurement := p.Run(newAnalysisRun(), newComparisonMetric())
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "baseline query: expected a single value, got 2", measurement.Message)
+}
+
+func TestRunComparisonWithQueryError(t *testing.T) {
+	e := log.Entry{}
+	mock := mockAPI{
+		err: fmt.Errorf("bad big bug :("),
+	}
+	p := NewPrometheusProvider(mock, e)
+	measurement := p.Run(newAnalysisRun(), newComparisonMetric())
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "canary query: bad big bug :(", measurement.Message)
+}
+
 func TestResume(t *testing.T) {
 	e := log.WithField("", "")
 	mock := mockAPI{}
@@ -213,7 +341,7 @@ func TestProcessScalarResponse(t *testing.T) {
 		Timestamp: model.Time(0),
 	}
 
-	value, status, err := p.processResponse(metric, response)
+	value, status, _, err := p.processResponse(metric, response)
 	assert.Nil(t, err)
 	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, status)
 	assert.Equal(t, "10", value)
@@ -235,13 +363,66 @@ func TestProcessNaNScalarResponse(t *testing.T) {
 		Timestamp: model.Time(0),
 	}
 
-	value, status, err := p.processResponse(metric, response)
+	value, status, _, err := p.processResponse(metric, response)
 	assert.Nil(t, err)
 	assert.Equal(t, v1alpha1.AnalysisPhaseInconclusive, status)
 	assert.Equal(t, "NaN", value)
 
 }
 
+func TestProcessNaNScalarResponseWithNaNHandling(t *testing.T) {
+	logCtx := log.WithField("test", "test")
+	p := Provider{
+		logCtx: *logCtx,
+	}
+	response := &model.Scalar{
+		Value:     model.SampleValue(math.NaN()),
+		Timestamp: model.Time(0),
+	}
+
+	metric := v1alpha1.Metric{
+		SuccessCondition: "result < 0.05",
+		NaNHandling:      v1alpha1.NaNHandlingFail,
+	}
+	value, status, _, err := p.processResponse(metric, response)
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, status)
+	assert.Equal(t, "NaN", value)
+
+	metric.NaNHandling = v1alpha1.NaNHandlingPass
+	_, status, _, err = p.processResponse(metric, response)
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, status)
+
+	metric.NaNHandling = v1alpha1.NaNHandlingError
+	_, status, _, err = p.processResponse(metric, response)
+	assert.EqualError(t, err, "metric value is NaN or Inf")
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, status)
+}
+
+func TestRunWithNaNHandlingError(t *testing.T) {
+	e := log.NewEntry(log.New())
+	mock := mockAPI{
+		value: newScalar(math.NaN()),
+	}
+	p := NewPrometheusProvider(mock, *e)
+	metric := v1alpha1.Metric{
+		Name:             "foo",
+		SuccessCondition: "result < 0.05",
+		NaNHandling:      v1alpha1.NaNHandlingError,
+		Provider: v1alpha1.MetricProvider{
+			Prometheus: &v1alpha1.PrometheusMetric{
+				Query: "test",
+			},
+		},
+	}
+	measurement := p.Run(newAnalysisRun(), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "metric value is NaN or Inf", measurement.Message)
+	assert.Equal(t, "NaN", measurement.Value)
+	assert.NotNil(t, measurement.FinishedAt)
+}
+
 func TestProcessVectorResponse(t *testing.T) {
 	logCtx := log.WithField("test", "test")
 	p := Provider{
@@ -262,13 +443,83 @@ func TestProcessVectorResponse(t *testing.T) {
 			Timestamp: model.Time(0),
 		},
 	}
-	value, status, err := p.processResponse(metric, response)
+	value, status, _, err := p.processResponse(metric, response)
 	assert.Nil(t, err)
 	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, status)
 	assert.Equal(t, "[10,11]", value)
 
 }
 
+func TestProcessLabeledVectorResponse(t *testing.T) {
+	logCtx := log.WithField("test", "test")
+	p := Provider{
+		logCtx: *logCtx,
+	}
+	metric := v1alpha1.Metric{
+		SuccessCondition: `result[0] < 0.5 || labels.path == "/healthz"`,
+		Provider: v1alpha1.MetricProvider{
+			Prometheus: &v1alpha1.PrometheusMetric{
+				FailureMessage: `"latency of " + labels.path + " exceeded 500ms"`,
+			},
+		},
+	}
+	newResponse := func(path string, latency float64) model.Vector {
+		return model.Vector{
+			{
+				Metric:    model.Metric{"__name__": "http_request_duration_seconds", "path": model.LabelValue(path)},
+				Value:     model.SampleValue(latency),
+				Timestamp: model.Time(0),
+			},
+		}
+	}
+
+	value, status, message, err := p.processResponse(metric, newResponse("/checkout", 0.8))
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, status)
+	assert.Equal(t, "[0.8]", value)
+	assert.Equal(t, "latency of /checkout exceeded 500ms", message)
+
+	_, status, message, err = p.processResponse(metric, newResponse("/healthz", 0.8))
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, status)
+	assert.Equal(t, "", message)
+
+	// without a selected sample, the labels are empty
+	response := append(newResponse("/checkout", 0.8), newResponse("/healthz", 0.1)...)
+	_, status, message, err = p.processResponse(metric, response)
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, status)
+	assert.Contains(t, message, "message")
+}
+
+func TestRunWithFailureMessage(t *testing.T) {
+	e := log.Entry{}
+	mock := mockAPI{
+		value: model.Vector{
+			{
+				Metric:    model.Metric{"path": "/checkout"},
+				Value:     model.SampleValue(0.8),
+				Timestamp: model.Time(0),
+			},
+		},
+	}
+	p := NewPrometheusProvider(mock, e)
+	metric := v1alpha1.Metric{
+		Name:             "foo",
+		FailureCondition: "result[0] >= 0.5",
+		Provider: v1alpha1.MetricProvider{
+			Prometheus: &v1alpha1.PrometheusMetric{
+				Query:          "topk(1, latency)",
+				FailureMessage: `"latency of " + labels.path + " exceeded 500ms"`,
+			},
+		},
+	}
+	measurement := p.Run(newAnalysisRun(), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, measurement.Phase)
+	assert.Equal(t, "[0.8]", measurement.Value)
+	assert.Equal(t, "latency of /checkout exceeded 500ms", measurement.Message)
+}
+
 func TestProcessNanVectorResponse(t *testing.T) {
 	logCtx := log.WithField("test", "test")
 	p := Provider{
@@ -285,13 +536,29 @@ func TestProcessNanVectorResponse(t *testing.T) {
 			Timestamp: model.Time(0),
 		},
 	}
-	value, status, err := p.processResponse(metric, response)
+	value, status, _, err := p.processResponse(metric, response)
 	assert.Nil(t, err)
 	assert.Equal(t, v1alpha1.AnalysisPhaseInconclusive, status)
 	assert.Equal(t, "[NaN]", value)
 
 }
 
+func TestProcessEmptyVectorResponseWithInconclusiveCondition(t *testing.T) {
+	logCtx := log.WithField("test", "test")
+	p := Provider{
+		logCtx: *logCtx,
+	}
+	metric := v1alpha1.Metric{
+		SuccessCondition:      "result[0] >= 0.9",
+		InconclusiveCondition: "len(result) == 0",
+	}
+
+	value, status, _, err := p.processResponse(metric, model.Vector{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha1.AnalysisPhaseInconclusive, status)
+	assert.Equal(t, "[]", value)
+}
+
 func TestProcessInvalidResponse(t *testing.T) {
 	logCtx := log.WithField("test", "test")
 	p := Provider{
@@ -302,7 +569,7 @@ func TestProcessInvalidResponse(t *testing.T) {
 		FailureCondition: "true",
 	}
 
-	value, status, err := p.processResponse(metric, nil)
+	value, status, _, err := p.processResponse(metric, nil)
 	assert.NotNil(t, err)
 	assert.Equal(t, v1alpha1.AnalysisPhaseError, status)
 	assert.Equal(t, "", value)
@@ -324,3 +591,181 @@ func TestNewPrometheusAPI(t *testing.T) {
 	_, err = NewPrometheusAPI(metric)
 	assert.Nil(t, err)
 }
+
+func newPrometheusServer(t *testing.T, expectedToken *string) *httptest.Server {
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if expectedToken != nil {
+			assert.Equal(t, "Bearer "+*expectedToken, r.Header.Get("Authorization"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"status":"success","data":{"resultType":"scalar","result":[0,"10"]}}`)
+	}))
+}
+
+func TestNewPrometheusAPIInsecureSkipVerify(t *testing.T) {
+	server := newPrometheusServer(t, nil)
+	defer server.Close()
+	metric := v1alpha1.Metric{
+		Provider: v1alpha1.MetricProvider{
+			Prometheus: &v1alpha1.PrometheusMetric{
+				Address: server.URL,
+			},
+		},
+	}
+
+	// The certificate of the test server is self-signed
+	api, err := NewPrometheusAPI(metric)
+	assert.NoError(t, err)
+	_, _, err = api.Query(context.Background(), "up", time.Now())
+	assert.Error(t, err)
+
+	metric.Provider.Prometheus.InsecureSkipVerify = true
+	api, err = NewPrometheusAPI(metric)
+	assert.NoError(t, err)
+	value, _, err := api.Query(context.Background(), "up", time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, "10", value.(*model.Scalar).Value.String())
+}
+
+func TestNewPrometheusAPIBearerTokenFile(t *testing.T) {
+	defer func() {
+		nowFn = func() time.Time { return time.Now() }
+	}()
+	now := time.Now()
+	nowFn = func() time.Time { return now }
+
+	dir, err := ioutil.TempDir("", "prometheus")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+	assert.NoError(t, ioutil.WriteFile(tokenFile, []byte("first-token\n"), 0600))
+
+	expectedToken := "first-token"
+	server := newPrometheusServer(t, &expectedToken)
+	defer server.Close()
+	metric := v1alpha1.Metric{
+		Provider: v1alpha1.MetricProvider{
+			Prometheus: &v1alpha1.PrometheusMetric{
+				Address:            server.URL,
+				BearerTokenFile:    tokenFile,
+				InsecureSkipVerify: true,
+			},
+		},
+	}
+	api, err := NewPrometheusAPI(metric)
+	assert.NoError(t, err)
+	_, _, err = api.Query(context.Background(), "up", time.Now())
+	assert.NoError(t, err)
+
+	// The rotated token is only read once the refresh interval elapsed
+	assert.NoError(t, ioutil.WriteFile(tokenFile, []byte("second-token"), 0600))
+	_, _, err = api.Query(context.Background(), "up", time.Now())
+	assert.NoError(t, err)
+
+	now = now.Add(BearerTokenRefreshInterval)
+	expectedToken = "second-token"
+	_, _, err = api.Query(context.Background(), "up", time.Now())
+	assert.NoError(t, err)
+}
+
+func TestNewPrometheusAPIBearerTokenFileErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "prometheus")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	metric := v1alpha1.Metric{
+		Provider: v1alpha1.MetricProvider{
+			Prometheus: &v1alpha1.PrometheusMetric{
+				Address:         "https://www.example.com",
+				BearerTokenFile: filepath.Join(dir, "missing"),
+			},
+		},
+	}
+	_, err = NewPrometheusAPI(metric)
+	assert.EqualError(t, err, fmt.Sprintf("unable to read bearer token file %s: open %s: no such file or directory", metric.Provider.Prometheus.BearerTokenFile, metric.Provider.Prometheus.BearerTokenFile))
+
+	emptyFile := filepath.Join(dir, "empty")
+	assert.NoError(t, ioutil.WriteFile(emptyFile, []byte("\n"), 0600))
+	metric.Provider.Prometheus.BearerTokenFile = emptyFile
+	_, err = NewPrometheusAPI(metric)
+	assert.EqualError(t, err, fmt.Sprintf("bearer token file %s is empty", emptyFile))
+}
+
+func newQueryTimeoutMetric(address string, queryTimeout v1alpha1.DurationString) v1alpha1.Metric {
+	return v1alpha1.Metric{
+		Name:             "foo",
+		SuccessCondition: "result == 10",
+		Provider: v1alpha1.MetricProvider{
+			Prometheus: &v1alpha1.PrometheusMetric{
+				Address:      address,
+				Query:        "test",
+				QueryTimeout: queryTimeout,
+			},
+		},
+	}
+}
+
+func TestRunQueryTimedOutByPrometheus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "test", r.FormValue("query"))
+		assert.Equal(t, "5", r.FormValue("timeout"))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, `{"status":"error","errorType":"timeout","error":"query timed out in query execution"}`)
+	}))
+	defer server.Close()
+	metric := newQueryTimeoutMetric(server.URL, "5s")
+	api, err := NewPrometheusAPI(metric)
+	assert.NoError(t, err)
+	p := NewPrometheusProvider(api, log.Entry{})
+
+	measurement := p.Run(newAnalysisRun(), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "Prometheus timed out evaluating the query after the queryTimeout of 5s: query timed out in query execution", measurement.Message)
+}
+
+func TestRunQueryTimedOutWithoutQueryTimeout(t *testing.T) {
+	mock := mockAPI{
+		err: &v1.Error{Type: v1.ErrTimeout, Msg: "query timed out in expression evaluation"},
+	}
+	p := NewPrometheusProvider(mock, log.Entry{})
+	measurement := p.Run(newAnalysisRun(), newQueryTimeoutMetric("", ""))
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "Prometheus timed out evaluating the query: query timed out in expression evaluation", measurement.Message)
+}
+
+func TestRunQueryTimedOutByClient(t *testing.T) {
+	defer func(timeout time.Duration) {
+		clientTimeout = timeout
+	}(clientTimeout)
+	clientTimeout = 50 * time.Millisecond
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The response is only sent after the client gave up
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+	metric := newQueryTimeoutMetric(server.URL, "")
+	api, err := NewPrometheusAPI(metric)
+	assert.NoError(t, err)
+	p := NewPrometheusProvider(api, log.Entry{})
+
+	measurement := p.Run(newAnalysisRun(), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "Prometheus did not respond within the client timeout of 50ms", measurement.Message)
+}
+
+func TestQueryClientTimeout(t *testing.T) {
+	assert.Equal(t, 30*time.Second, queryClientTimeout(&v1alpha1.PrometheusMetric{}))
+	assert.Equal(t, 30*time.Second, queryClientTimeout(&v1alpha1.PrometheusMetric{QueryTimeout: "10s"}))
+	// The client waits for prometheus to cancel a query running longer than the client timeout
+	assert.Equal(t, 65*time.Second, queryClientTimeout(&v1alpha1.PrometheusMetric{QueryTimeout: "1m"}))
+}
+
+func TestNewPrometheusAPIInvalidQueryTimeout(t *testing.T) {
+	_, err := NewPrometheusAPI(newQueryTimeoutMetric("https://www.example.com", "5"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid queryTimeout '5'")
+}
@@ -0,0 +1,184 @@
+package metricproviders
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"sync"
+
+	"github.com/ghodss/yaml"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/flowcontrol"
+
+	"github.com/argoproj/argo-rollouts/metricproviders/commitstatus"
+	"github.com/argoproj/argo-rollouts/metricproviders/datadog"
+	"github.com/argoproj/argo-rollouts/metricproviders/incident"
+	"github.com/argoproj/argo-rollouts/metricproviders/pingdom"
+	"github.com/argoproj/argo-rollouts/metricproviders/xray"
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/argoproj/argo-rollouts/utils/defaults"
+)
+
+const (
+	// ConfigMapName is the name of the ConfigMap in the controller namespace holding the controller configuration
+	ConfigMapName = defaults.ControllerConfigMapName
+	// RateLimitsConfigMapKey is the key of the ConfigMap holding the metric provider rate limits
+	RateLimitsConfigMapKey = "metricProviderRateLimits"
+)
+
+// RateLimit limits the rate of requests made to a metric provider backend
+type RateLimit struct {
+	// Provider is the provider type the limit applies to (e.g. Prometheus)
+	Provider string `json:"provider"`
+	// Address limits the requests made to a single endpoint of the provider. If omitted, the limit is shared by
+	// all the endpoints of the provider which do not have a limit of their own
+	Address string `json:"address,omitempty"`
+	// RequestsPerSecond is the sustained number of requests per second allowed
+	RequestsPerSecond float32 `json:"requestsPerSecond"`
+	// Burst is the maximum number of requests allowed at once
+	Burst int `json:"burst"`
+}
+
+// RateLimiters holds the rate limiters of the metric provider backends, which are shared across all AnalysisRuns
+type RateLimiters struct {
+	limits   []RateLimit
+	defaults []ProviderDefaults
+	lock     sync.Mutex
+	limiters map[string]flowcontrol.RateLimiter
+}
+
+// NewRateLimiters returns the rate limiters for the limits. The providers without a limit are limited in each namespace
+// by the rate limit of their defaults, and are not rate limited when their defaults do not have one.
+func NewRateLimiters(limits []RateLimit, defaults []ProviderDefaults) *RateLimiters {
+	return &RateLimiters{
+		limits:   limits,
+		defaults: defaults,
+		limiters: map[string]flowcontrol.RateLimiter{},
+	}
+}
+
+// SetConfig replaces the limits and the defaults of the rate limiters, such as when the controller ConfigMap is
+// reloaded. The tokens of the rate limiters are reset only when the configuration changed.
+func (r *RateLimiters) SetConfig(limits []RateLimit, defaults []ProviderDefaults) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if reflect.DeepEqual(r.limits, limits) && reflect.DeepEqual(r.defaults, defaults) {
+		return
+	}
+	r.limits = limits
+	r.defaults = defaults
+	r.limiters = map[string]flowcontrol.RateLimiter{}
+}
+
+// TryAccept returns true if a request to the backend of the metric can be made now, or false if the rate
+// limit of the backend has been reached
+func (r *RateLimiters) TryAccept(namespace string, metric v1alpha1.Metric) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	limit := r.getRateLimit(metric)
+	key := ""
+	if limit != nil {
+		key = limit.Provider + "/" + limit.Address
+	} else {
+		// the default rate limits are shared by the AnalysisRuns of a namespace only
+		limit = defaultRateLimit(r.defaults, namespace, metric)
+		if limit == nil {
+			return true
+		}
+		key = namespace + "/" + limit.Provider
+	}
+	limiter, ok := r.limiters[key]
+	if !ok {
+		limiter = flowcontrol.NewTokenBucketRateLimiter(limit.RequestsPerSecond, limit.Burst)
+		r.limiters[key] = limiter
+	}
+	return limiter.TryAccept()
+}
+
+// getRateLimit returns the limit of the metric's endpoint, falling back to the limit of its provider type. The lock
+// must be held.
+func (r *RateLimiters) getRateLimit(metric v1alpha1.Metric) *RateLimit {
+	providerType := Type(metric)
+	address := Address(metric)
+	var providerLimit *RateLimit
+	for i := range r.limits {
+		limit := r.limits[i]
+		if limit.Provider != providerType {
+			continue
+		}
+		if limit.Address == "" {
+			providerLimit = &limit
+		} else if limit.Address == address {
+			return &limit
+		}
+	}
+	return providerLimit
+}
+
+// Address returns the endpoint of the metric's provider backend
+func Address(metric v1alpha1.Metric) string {
+	if metric.Provider.Prometheus != nil {
+		return metric.Provider.Prometheus.Address
+	} else if metric.Provider.Kayenta != nil {
+		return metric.Provider.Kayenta.Address
+	} else if metric.Provider.Web != nil {
+		if u, err := url.Parse(metric.Provider.Web.URL); err == nil {
+			return u.Host
+		}
+		return metric.Provider.Web.URL
+	} else if metric.Provider.Wavefront != nil {
+		return metric.Provider.Wavefront.Address
+	} else if metric.Provider.Elasticsearch != nil {
+		return metric.Provider.Elasticsearch.Address
+	} else if metric.Provider.Alertmanager != nil {
+		return metric.Provider.Alertmanager.Address
+	} else if metric.Provider.Decision != nil {
+		if u, err := url.Parse(metric.Provider.Decision.URL); err == nil {
+			return u.Host
+		}
+		return metric.Provider.Decision.URL
+	} else if metric.Provider.Loki != nil {
+		return metric.Provider.Loki.Address
+	} else if metric.Provider.Pingdom != nil {
+		return pingdom.Address(metric.Provider.Pingdom)
+	} else if metric.Provider.GRPC != nil {
+		return metric.Provider.GRPC.Address
+	} else if metric.Provider.Datadog != nil {
+		return datadog.Address(metric.Provider.Datadog)
+	} else if metric.Provider.Incident != nil {
+		return incident.Address(metric.Provider.Incident)
+	} else if metric.Provider.XRay != nil {
+		return xray.Address(metric.Provider.XRay)
+	} else if metric.Provider.CommitStatus != nil {
+		return commitstatus.Address(metric.Provider.CommitStatus)
+	}
+	return ""
+}
+
+// GetRateLimits reads the metric provider rate limits from the controller ConfigMap. No limits are returned
+// if the ConfigMap or its key does not exist.
+func GetRateLimits(kubeclientset kubernetes.Interface) ([]RateLimit, error) {
+	cm, err := kubeclientset.CoreV1().ConfigMaps(defaults.Namespace()).Get(ConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	data, ok := cm.Data[RateLimitsConfigMapKey]
+	if !ok {
+		return nil, nil
+	}
+	var limits []RateLimit
+	if err := yaml.Unmarshal([]byte(data), &limits); err != nil {
+		return nil, fmt.Errorf("invalid %s in ConfigMap %s: %v", RateLimitsConfigMapKey, ConfigMapName, err)
+	}
+	for _, limit := range limits {
+		if limit.Provider == "" || limit.RequestsPerSecond <= 0 || limit.Burst <= 0 {
+			return nil, fmt.Errorf("invalid %s in ConfigMap %s: provider, requestsPerSecond and burst are required", RateLimitsConfigMapKey, ConfigMapName)
+		}
+	}
+	return limits, nil
+}
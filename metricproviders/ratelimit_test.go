@@ -0,0 +1,148 @@
+package metricproviders
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/argoproj/argo-rollouts/utils/defaults"
+)
+
+func newPrometheusMetric(address string) v1alpha1.Metric {
+	return v1alpha1.Metric{
+		Name: "success-rate",
+		Provider: v1alpha1.MetricProvider{
+			Prometheus: &v1alpha1.PrometheusMetric{
+				Address: address,
+			},
+		},
+	}
+}
+
+func TestRateLimitersUnlimited(t *testing.T) {
+	r := NewRateLimiters(nil, nil)
+	for i := 0; i < 100; i++ {
+		assert.True(t, r.TryAccept("default", newPrometheusMetric("http://prometheus:9090")))
+	}
+}
+
+func TestRateLimitersPerProviderAndAddress(t *testing.T) {
+	r := NewRateLimiters([]RateLimit{{
+		Provider:          "Prometheus",
+		RequestsPerSecond: 0.001,
+		Burst:             2,
+	}, {
+		Provider:          "Prometheus",
+		Address:           "http://prometheus-b:9090",
+		RequestsPerSecond: 0.001,
+		Burst:             1,
+	}}, nil)
+
+	// endpoints without a limit of their own share the provider limit
+	assert.True(t, r.TryAccept("default", newPrometheusMetric("http://prometheus-a:9090")))
+	assert.True(t, r.TryAccept("default", newPrometheusMetric("http://prometheus-c:9090")))
+	assert.False(t, r.TryAccept("default", newPrometheusMetric("http://prometheus-a:9090")))
+
+	// endpoints with a limit have their own limiter
+	assert.True(t, r.TryAccept("default", newPrometheusMetric("http://prometheus-b:9090")))
+	assert.False(t, r.TryAccept("default", newPrometheusMetric("http://prometheus-b:9090")))
+
+	// other providers are not limited
+	web := v1alpha1.Metric{
+		Name: "web",
+		Provider: v1alpha1.MetricProvider{
+			Web: &v1alpha1.WebMetric{URL: "http://prometheus-a:9090/api"},
+		},
+	}
+	assert.True(t, r.TryAccept("default", web))
+}
+
+func TestRateLimitersSetConfig(t *testing.T) {
+	limits := []RateLimit{{
+		Provider:          "Prometheus",
+		RequestsPerSecond: 0.001,
+		Burst:             1,
+	}}
+	r := NewRateLimiters(limits, nil)
+	metric := newPrometheusMetric("http://prometheus:9090")
+	assert.True(t, r.TryAccept("default", metric))
+	assert.False(t, r.TryAccept("default", metric))
+
+	// an unchanged configuration keeps the rate limiters
+	r.SetConfig([]RateLimit{{
+		Provider:          "Prometheus",
+		RequestsPerSecond: 0.001,
+		Burst:             1,
+	}}, nil)
+	assert.False(t, r.TryAccept("default", metric))
+
+	// a changed configuration applies to the next requests
+	r.SetConfig([]RateLimit{{
+		Provider:          "Prometheus",
+		RequestsPerSecond: 0.001,
+		Burst:             2,
+	}}, nil)
+	assert.True(t, r.TryAccept("default", metric))
+	assert.True(t, r.TryAccept("default", metric))
+	assert.False(t, r.TryAccept("default", metric))
+
+	r.SetConfig(nil, nil)
+	assert.True(t, r.TryAccept("default", metric))
+}
+
+func TestAddress(t *testing.T) {
+	assert.Equal(t, "http://prometheus:9090", Address(newPrometheusMetric("http://prometheus:9090")))
+	web := v1alpha1.Metric{
+		Provider: v1alpha1.MetricProvider{
+			Web: &v1alpha1.WebMetric{URL: "https://metrics.example.com/api/v1?service=foo"},
+		},
+	}
+	assert.Equal(t, "metrics.example.com", Address(web))
+	job := v1alpha1.Metric{
+		Provider: v1alpha1.MetricProvider{
+			Job: &v1alpha1.JobMetric{},
+		},
+	}
+	assert.Equal(t, "", Address(job))
+}
+
+func TestGetRateLimits(t *testing.T) {
+	limits, err := GetRateLimits(k8sfake.NewSimpleClientset())
+	assert.NoError(t, err)
+	assert.Nil(t, limits)
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ConfigMapName,
+			Namespace: defaults.Namespace(),
+		},
+		Data: map[string]string{
+			RateLimitsConfigMapKey: `
+- provider: Prometheus
+  requestsPerSecond: 5
+  burst: 10
+- provider: WebMetric
+  address: metrics.example.com
+  requestsPerSecond: 0.5
+  burst: 1
+`,
+		},
+	}
+	limits, err = GetRateLimits(k8sfake.NewSimpleClientset(cm))
+	assert.NoError(t, err)
+	assert.Equal(t, []RateLimit{
+		{Provider: "Prometheus", RequestsPerSecond: 5, Burst: 10},
+		{Provider: "WebMetric", Address: "metrics.example.com", RequestsPerSecond: 0.5, Burst: 1},
+	}, limits)
+
+	cm.Data[RateLimitsConfigMapKey] = `
+- provider: Prometheus
+  requestsPerSecond: 5
+`
+	_, err = GetRateLimits(k8sfake.NewSimpleClientset(cm))
+	assert.EqualError(t, err, "invalid metricProviderRateLimits in ConfigMap argo-rollouts-config: provider, requestsPerSecond and burst are required")
+}
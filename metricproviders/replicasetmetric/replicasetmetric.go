@@ -0,0 +1,222 @@
+package replicasetmetric
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/jsonpath"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/argoproj/argo-rollouts/utils/evaluate"
+	metricutil "github.com/argoproj/argo-rollouts/utils/metric"
+)
+
+const (
+	// ProviderType indicates the provider is the metadata of a ReplicaSet
+	ProviderType = "ReplicaSet"
+)
+
+// Provider reads the fields of a ReplicaSet and of its pods and evaluates them
+// Implements the Provider Interface
+type Provider struct {
+	logCtx        log.Entry
+	kubeclientset kubernetes.Interface
+}
+
+// Type indicates provider is a ReplicaSet provider
+func (p *Provider) Type() string {
+	return ProviderType
+}
+
+// Run reads the fields of the metric and evaluates the map of their values
+func (p *Provider) Run(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric) v1alpha1.Measurement {
+	startTime := metav1.Now()
+	measurement := v1alpha1.Measurement{
+		StartedAt: &startTime,
+	}
+
+	rsMetric := metric.Provider.ReplicaSet
+	rs, err := p.getReplicaSet(run, rsMetric)
+	if err != nil {
+		return metricutil.MarkMeasurementError(measurement, err)
+	}
+	var pods []corev1.Pod
+	if readsPods(rsMetric) {
+		pods, err = p.getPods(rs)
+		if err != nil {
+			return metricutil.MarkMeasurementError(measurement, err)
+		}
+	}
+
+	result := map[string]interface{}{}
+	for _, field := range rsMetric.Fields {
+		if field.Source == v1alpha1.ReplicaSetMetricFieldSourcePods {
+			values := make([]interface{}, 0, len(pods))
+			for i := range pods {
+				value, err := fieldValue(&pods[i], field)
+				if err != nil {
+					return metricutil.MarkMeasurementError(measurement, err)
+				}
+				values = append(values, value)
+			}
+			result[field.Name] = values
+			continue
+		}
+		value, err := fieldValue(rs, field)
+		if err != nil {
+			return metricutil.MarkMeasurementError(measurement, err)
+		}
+		result[field.Name] = value
+	}
+
+	value, err := json.Marshal(result)
+	if err != nil {
+		return metricutil.MarkMeasurementError(measurement, err)
+	}
+	measurement.Value = string(value)
+	phase, err := evaluate.EvaluateResult(result, metric, p.logCtx)
+	if err != nil {
+		return metricutil.MarkMeasurementError(measurement, err)
+	}
+	measurement.Phase = phase
+	if measurement.Metadata == nil {
+		measurement.Metadata = map[string]string{}
+	}
+	measurement.Metadata["replicaSet"] = rs.Name
+	finishedTime := metav1.Now()
+	measurement.FinishedAt = &finishedTime
+	return measurement
+}
+
+// getReplicaSet returns the ReplicaSet of the pod-template-hash of the metric. When several ReplicaSets have the
+// same pod-template-hash, the one controlled by the controller of the AnalysisRun (e.g. its rollout) is returned
+func (p *Provider) getReplicaSet(run *v1alpha1.AnalysisRun, metric *v1alpha1.ReplicaSetMetric) (*appsv1.ReplicaSet, error) {
+	podTemplateHash := metric.PodTemplateHash
+	if podTemplateHash == "" {
+		podTemplateHash = run.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+	}
+	if podTemplateHash == "" {
+		return nil, fmt.Errorf("podTemplateHash must be set when the AnalysisRun was not created by a rollout")
+	}
+	selector := labels.SelectorFromSet(labels.Set{v1alpha1.DefaultRolloutUniqueLabelKey: podTemplateHash})
+	rsList, err := p.kubeclientset.AppsV1().ReplicaSets(run.Namespace).List(metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	replicaSets := rsList.Items
+	if len(replicaSets) > 1 {
+		if runOwner := metav1.GetControllerOf(run); runOwner != nil {
+			owned := []appsv1.ReplicaSet{}
+			for _, rs := range replicaSets {
+				if rsOwner := metav1.GetControllerOf(&rs); rsOwner != nil && rsOwner.UID == runOwner.UID {
+					owned = append(owned, rs)
+				}
+			}
+			replicaSets = owned
+		}
+	}
+	switch len(replicaSets) {
+	case 0:
+		return nil, fmt.Errorf("no ReplicaSet matches the pod-template-hash '%s'", podTemplateHash)
+	case 1:
+		return &replicaSets[0], nil
+	default:
+		return nil, fmt.Errorf("%d ReplicaSets match the pod-template-hash '%s'", len(replicaSets), podTemplateHash)
+	}
+}
+
+// getPods returns the pods of the ReplicaSet which are not being deleted, sorted by name so that the values of the
+// pod fields are listed in the same order across measurements
+func (p *Provider) getPods(rs *appsv1.ReplicaSet) ([]corev1.Pod, error) {
+	podList, err := p.kubeclientset.CoreV1().Pods(rs.Namespace).List(metav1.ListOptions{
+		LabelSelector: metav1.FormatLabelSelector(rs.Spec.Selector),
+	})
+	if err != nil {
+		return nil, err
+	}
+	pods := []corev1.Pod{}
+	for _, pod := range podList.Items {
+		if metav1.IsControlledBy(&pod, rs) && pod.DeletionTimestamp == nil {
+			pods = append(pods, pod)
+		}
+	}
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].Name < pods[j].Name
+	})
+	return pods, nil
+}
+
+func readsPods(metric *v1alpha1.ReplicaSetMetric) bool {
+	for _, field := range metric.Fields {
+		if field.Source == v1alpha1.ReplicaSetMetricFieldSourcePods {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldValue returns the value of the field in the object: nil when the field is missing, the value when the path
+// matches a single value, or the list of the values otherwise
+func fieldValue(obj interface{}, field v1alpha1.ReplicaSetMetricField) (interface{}, error) {
+	data, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	parser := jsonpath.New(field.Name).AllowMissingKeys(true)
+	if err := parser.Parse(field.JSONPath); err != nil {
+		return nil, fmt.Errorf("invalid jsonPath of field '%s': %v", field.Name, err)
+	}
+	results, err := parser.FindResults(data)
+	if err != nil {
+		return nil, fmt.Errorf("could not read field '%s': %v", field.Name, err)
+	}
+	values := []interface{}{}
+	for _, result := range results {
+		for _, value := range result {
+			values = append(values, value.Interface())
+		}
+	}
+	switch len(values) {
+	case 0:
+		return nil, nil
+	case 1:
+		return values[0], nil
+	default:
+		return values, nil
+	}
+}
+
+// Resume should not be used the ReplicaSet provider since all the work should occur in the Run method
+func (p *Provider) Resume(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, measurement v1alpha1.Measurement) v1alpha1.Measurement {
+	p.logCtx.Warn("ReplicaSet provider should not execute the Resume method")
+	return measurement
+}
+
+// Terminate should not be used the ReplicaSet provider since all the work should occur in the Run method
+func (p *Provider) Terminate(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, measurement v1alpha1.Measurement) v1alpha1.Measurement {
+	p.logCtx.Warn("ReplicaSet provider should not execute the Terminate method")
+	return measurement
+}
+
+// GarbageCollect is a no-op for the ReplicaSet provider
+func (p *Provider) GarbageCollect(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, limit int) error {
+	return nil
+}
+
+// NewReplicaSetProvider creates a new ReplicaSet provider
+func NewReplicaSetProvider(logCtx log.Entry, kubeclientset kubernetes.Interface) *Provider {
+	return &Provider{
+		logCtx:        logCtx,
+		kubeclientset: kubeclientset,
+	}
+}
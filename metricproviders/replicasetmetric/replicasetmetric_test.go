@@ -0,0 +1,197 @@
+package replicasetmetric
+
+import (
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+)
+
+func newTestReplicaSetProvider(objects ...runtime.Object) *Provider {
+	logCtx := log.NewEntry(log.New())
+	kubeclient := k8sfake.NewSimpleClientset(objects...)
+	return NewReplicaSetProvider(*logCtx, kubeclient)
+}
+
+func newRun() *v1alpha1.AnalysisRun {
+	return &v1alpha1.AnalysisRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "run",
+			Namespace: metav1.NamespaceDefault,
+			Labels: map[string]string{
+				v1alpha1.DefaultRolloutUniqueLabelKey: "canary",
+			},
+		},
+	}
+}
+
+func newMetric(successCondition string, fields ...v1alpha1.ReplicaSetMetricField) v1alpha1.Metric {
+	return v1alpha1.Metric{
+		Name:             "metadata",
+		SuccessCondition: successCondition,
+		Provider: v1alpha1.MetricProvider{
+			ReplicaSet: &v1alpha1.ReplicaSetMetric{
+				Fields: fields,
+			},
+		},
+	}
+}
+
+func newReplicaSet(name, podTemplateHash string, annotations map[string]string) *appsv1.ReplicaSet {
+	labels := map[string]string{
+		"app":                                 "guestbook",
+		v1alpha1.DefaultRolloutUniqueLabelKey: podTemplateHash,
+	}
+	replicas := int32(2)
+	return &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   metav1.NamespaceDefault,
+			UID:         types.UID(name),
+			Labels:      labels,
+			Annotations: annotations,
+		},
+		Spec: appsv1.ReplicaSetSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+		},
+	}
+}
+
+func newPod(name string, rs *appsv1.ReplicaSet, image string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       rs.Namespace,
+			Labels:          rs.Spec.Selector.MatchLabels,
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(rs, appsv1.SchemeGroupVersion.WithKind("ReplicaSet"))},
+		},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "guestbook", ImageID: image}},
+		},
+	}
+}
+
+func TestType(t *testing.T) {
+	p := newTestReplicaSetProvider()
+	assert.Equal(t, ProviderType, p.Type())
+}
+
+func TestRunReplicaSetFields(t *testing.T) {
+	canary := newReplicaSet("guestbook-canary", "canary", map[string]string{"example.com/digest": "sha256:abc"})
+	stable := newReplicaSet("guestbook-stable", "stable", map[string]string{"example.com/digest": "sha256:def"})
+	p := newTestReplicaSetProvider(canary, stable)
+
+	metric := newMetric("result.digest == 'sha256:abc' && result.replicas == 2",
+		v1alpha1.ReplicaSetMetricField{Name: "digest", JSONPath: `{.metadata.annotations.example\.com/digest}`},
+		v1alpha1.ReplicaSetMetricField{Name: "replicas", JSONPath: "{.spec.replicas}"},
+	)
+	measurement := p.Run(newRun(), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+	assert.Equal(t, `{"digest":"sha256:abc","replicas":2}`, measurement.Value)
+	assert.Equal(t, "guestbook-canary", measurement.Metadata["replicaSet"])
+	assert.NotNil(t, measurement.StartedAt)
+	assert.NotNil(t, measurement.FinishedAt)
+}
+
+func TestRunMissingField(t *testing.T) {
+	p := newTestReplicaSetProvider(newReplicaSet("guestbook-canary", "canary", nil))
+	metric := newMetric("result.digest == 'sha256:abc'",
+		v1alpha1.ReplicaSetMetricField{Name: "digest", JSONPath: "{.metadata.annotations.digest}"},
+	)
+	measurement := p.Run(newRun(), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, measurement.Phase)
+	assert.Equal(t, `{"digest":null}`, measurement.Value)
+}
+
+func TestRunPodFields(t *testing.T) {
+	canary := newReplicaSet("guestbook-canary", "canary", nil)
+	p := newTestReplicaSetProvider(
+		canary,
+		newPod("guestbook-canary-b", canary, "sha256:def"),
+		newPod("guestbook-canary-a", canary, "sha256:abc"),
+	)
+	metric := newMetric("all(result.images, {# == 'sha256:abc'})",
+		v1alpha1.ReplicaSetMetricField{Name: "images", JSONPath: "{.status.containerStatuses[0].imageID}", Source: v1alpha1.ReplicaSetMetricFieldSourcePods},
+	)
+	measurement := p.Run(newRun(), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, measurement.Phase)
+	// The values are listed in the order of the names of the pods
+	assert.Equal(t, `{"images":["sha256:abc","sha256:def"]}`, measurement.Value)
+}
+
+func TestRunPodTemplateHash(t *testing.T) {
+	p := newTestReplicaSetProvider(
+		newReplicaSet("guestbook-canary", "canary", nil),
+		newReplicaSet("guestbook-stable", "stable", nil),
+	)
+	metric := newMetric("result.name == 'guestbook-stable'",
+		v1alpha1.ReplicaSetMetricField{Name: "name", JSONPath: "{.metadata.name}"},
+	)
+	metric.Provider.ReplicaSet.PodTemplateHash = "stable"
+	measurement := p.Run(newRun(), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+}
+
+func TestRunReplicaSetOfRunController(t *testing.T) {
+	owned := newReplicaSet("guestbook-canary", "canary", nil)
+	owned.OwnerReferences = []metav1.OwnerReference{{Kind: "Rollout", Name: "guestbook", UID: "guestbook", Controller: boolPtr(true)}}
+	other := newReplicaSet("frontend-canary", "canary", nil)
+	other.OwnerReferences = []metav1.OwnerReference{{Kind: "Rollout", Name: "frontend", UID: "frontend", Controller: boolPtr(true)}}
+	p := newTestReplicaSetProvider(owned, other)
+
+	metric := newMetric("result.name == 'guestbook-canary'",
+		v1alpha1.ReplicaSetMetricField{Name: "name", JSONPath: "{.metadata.name}"},
+	)
+	run := newRun()
+	run.OwnerReferences = []metav1.OwnerReference{{Kind: "Rollout", Name: "guestbook", UID: "guestbook", Controller: boolPtr(true)}}
+	measurement := p.Run(run, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+
+	// Without a controller, the ReplicaSet of the run is ambiguous
+	measurement = p.Run(newRun(), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "2 ReplicaSets match the pod-template-hash 'canary'", measurement.Message)
+}
+
+func TestRunErrors(t *testing.T) {
+	p := newTestReplicaSetProvider(newReplicaSet("guestbook-stable", "stable", nil))
+	field := v1alpha1.ReplicaSetMetricField{Name: "name", JSONPath: "{.metadata.name}"}
+
+	measurement := p.Run(newRun(), newMetric("result.name != ''", field))
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "no ReplicaSet matches the pod-template-hash 'canary'", measurement.Message)
+
+	run := newRun()
+	run.Labels = nil
+	measurement = p.Run(run, newMetric("result.name != ''", field))
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "podTemplateHash must be set when the AnalysisRun was not created by a rollout", measurement.Message)
+
+	metric := newMetric("result.name != ''", v1alpha1.ReplicaSetMetricField{Name: "name", JSONPath: "{.metadata.name"})
+	metric.Provider.ReplicaSet.PodTemplateHash = "stable"
+	measurement = p.Run(newRun(), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Contains(t, measurement.Message, "invalid jsonPath of field 'name'")
+}
+
+func TestResumeTerminateGarbageCollect(t *testing.T) {
+	p := newTestReplicaSetProvider()
+	metric := newMetric("result.name != ''")
+	measurement := v1alpha1.Measurement{Phase: v1alpha1.AnalysisPhaseRunning}
+	assert.Equal(t, measurement, p.Resume(newRun(), metric, measurement))
+	assert.Equal(t, measurement, p.Terminate(newRun(), metric, measurement))
+	assert.NoError(t, p.GarbageCollect(newRun(), metric, 0))
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
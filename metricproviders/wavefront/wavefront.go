@@ -3,11 +3,9 @@ package wavefront
 import (
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"math"
-	"os"
 	"strconv"
-	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	wavefrontapi "github.com/spaceapegames/go-wavefront"
@@ -15,6 +13,7 @@ import (
 	"k8s.io/client-go/kubernetes"
 
 	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/argoproj/argo-rollouts/utils/defaults"
 	"github.com/argoproj/argo-rollouts/utils/evaluate"
 	metricutil "github.com/argoproj/argo-rollouts/utils/metric"
 )
@@ -81,6 +80,14 @@ func (p *Provider) Run(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric) v1alph
 		Granularity:             "s",
 		SeriesOutsideTimeWindow: false,
 	}
+	evaluationTime := startTime
+	if offset := metric.Provider.Wavefront.OffsetSeconds; offset > 0 {
+		// Query the datapoints of the offset preceding the evaluation time, to select the last one at or before it
+		evaluationTime = metav1.NewTime(startTime.Add(-time.Duration(offset) * time.Second))
+		queryParams.StartTime = strconv.FormatInt(evaluationTime.Add(-time.Duration(offset)*time.Second).Unix()*1000, 10)
+		queryParams.EndTime = strconv.FormatInt(evaluationTime.Unix()*1000, 10)
+		queryParams.MaxPoints = ""
+	}
 
 	response, err := p.api.NewQuery(queryParams).Execute()
 	if response != nil && response.Warnings != "" {
@@ -89,7 +96,7 @@ func (p *Provider) Run(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric) v1alph
 	if err != nil {
 		return metricutil.MarkMeasurementError(newMeasurement, err)
 	}
-	result, err := p.processResponse(metric, response, startTime)
+	result, err := p.processResponse(metric, response, evaluationTime)
 	if err != nil {
 		return metricutil.MarkMeasurementError(newMeasurement, err)
 
@@ -136,26 +143,62 @@ func (p *Provider) findDataPointValue(datapoints []wavefrontapi.DataPoint, start
 	return currentValue, fmt.Sprintf("%.0f", currentTime)
 }
 
+// findLastDataPointValue returns the value and the timestamp of the last datapoint at or before the evaluation time
+func findLastDataPointValue(datapoints []wavefrontapi.DataPoint, evaluationTime metav1.Time) (float64, string, error) {
+	found := false
+	currentValue := float64(0)
+	currentTime := float64(0)
+	evaluationTimeEpoch := float64(evaluationTime.Unix())
+	for _, dp := range datapoints {
+		if dp[0] <= evaluationTimeEpoch && (!found || dp[0] > currentTime) {
+			currentValue = dp[1]
+			currentTime = dp[0]
+			found = true
+		}
+	}
+	if !found {
+		return 0, "", fmt.Errorf("No datapoint found at or before %d in response from Wavefront", evaluationTime.Unix())
+	}
+	return currentValue, fmt.Sprintf("%.0f", currentTime), nil
+}
+
+// selectDataPointValue returns the value and the timestamp of the datapoint of a series to evaluate: the last one at
+// or before the evaluation time when the metric has an offset, otherwise the closest one
+func (p *Provider) selectDataPointValue(metric v1alpha1.Metric, datapoints []wavefrontapi.DataPoint, evaluationTime metav1.Time) (float64, string, error) {
+	if metric.Provider.Wavefront != nil && metric.Provider.Wavefront.OffsetSeconds > 0 {
+		return findLastDataPointValue(datapoints, evaluationTime)
+	}
+	value, epoch := p.findDataPointValue(datapoints, evaluationTime)
+	return value, epoch, nil
+}
+
 func (p *Provider) processResponse(metric v1alpha1.Metric, response *wavefrontapi.QueryResponse, startTime metav1.Time) (wavefrontResponse, error) {
 	wavefrontResponse := wavefrontResponse{}
 	if len(response.TimeSeries) == 1 {
 		series := response.TimeSeries[0]
-		value, time := p.findDataPointValue(series.DataPoints, startTime) // Wavefront DataPoint struct is of type []float{<timestamp>, <value>}
+		value, time, err := p.selectDataPointValue(metric, series.DataPoints, startTime) // Wavefront DataPoint struct is of type []float{<timestamp>, <value>}
+		if err != nil {
+			return wavefrontResponse, err
+		}
 		wavefrontResponse.newValue = fmt.Sprintf("%.2f", value)
 		wavefrontResponse.epochsUsed = time
-		if math.IsNaN(value) {
+		if math.IsNaN(value) && metric.NaNHandling == "" {
 			wavefrontResponse.newStatus = v1alpha1.AnalysisPhaseInconclusive
 			return wavefrontResponse, nil
 		}
-		wavefrontResponse.newStatus = evaluate.EvaluateResult(value, metric, p.logCtx)
-		return wavefrontResponse, nil
+		newStatus, err := evaluate.EvaluateResult(value, metric, p.logCtx)
+		wavefrontResponse.newStatus = newStatus
+		return wavefrontResponse, err
 
 	} else if len(response.TimeSeries) > 1 {
 		results := make([]float64, 0, len(response.TimeSeries))
 		valueStr := "["
 		epochsStr := "["
 		for _, series := range response.TimeSeries {
-			value, epoch := p.findDataPointValue(series.DataPoints, startTime) // Wavefront DataPoint struct is of type []float{<timestamp>, <value>}
+			value, epoch, err := p.selectDataPointValue(metric, series.DataPoints, startTime) // Wavefront DataPoint struct is of type []float{<timestamp>, <value>}
+			if err != nil {
+				return wavefrontResponse, err
+			}
 			valueStr = valueStr + fmt.Sprintf("%.2f", value) + ","
 			epochsStr = epochsStr + epoch + ","
 			results = append(results, value)
@@ -171,13 +214,14 @@ func (p *Provider) processResponse(metric v1alpha1.Metric, response *wavefrontap
 		wavefrontResponse.newValue = valueStr
 		wavefrontResponse.epochsUsed = epochsStr
 		for _, result := range results {
-			if math.IsNaN(result) {
+			if math.IsNaN(result) && metric.NaNHandling == "" {
 				wavefrontResponse.newStatus = v1alpha1.AnalysisPhaseInconclusive
 				return wavefrontResponse, nil
 			}
 		}
-		wavefrontResponse.newStatus = evaluate.EvaluateResult(results, metric, p.logCtx)
-		return wavefrontResponse, nil
+		newStatus, err := evaluate.EvaluateResult(results, metric, p.logCtx)
+		wavefrontResponse.newStatus = newStatus
+		return wavefrontResponse, err
 
 	} else {
 		wavefrontResponse.newStatus = v1alpha1.AnalysisPhaseFailed
@@ -195,7 +239,7 @@ func NewWavefrontProvider(api WavefrontClientAPI, logCtx log.Entry) *Provider {
 
 // NewWavefrontAPI generates a Wavefront API client from the metric configuration
 func NewWavefrontAPI(metric v1alpha1.Metric, kubeclientset kubernetes.Interface) (WavefrontClientAPI, error) {
-	ns := Namespace()
+	ns := defaults.Namespace()
 	secret, err := kubeclientset.CoreV1().Secrets(ns).Get(WavefrontTokensSecretName, metav1.GetOptions{})
 	if err != nil {
 		return nil, err
@@ -215,18 +259,3 @@ func NewWavefrontAPI(metric v1alpha1.Metric, kubeclientset kubernetes.Interface)
 		return nil, errors.New("API token not found")
 	}
 }
-
-func Namespace() string {
-	// This way assumes you've set the POD_NAMESPACE environment variable using the downward API.
-	// This check has to be done first for backwards compatibility with the way InClusterConfig was originally set up
-	if ns, ok := os.LookupEnv("POD_NAMESPACE"); ok {
-		return ns
-	}
-	// Fall back to the namespace associated with the service account token, if available
-	if data, err := ioutil.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace"); err == nil {
-		if ns := strings.TrimSpace(string(data)); len(ns) > 0 {
-			return ns
-		}
-	}
-	return "argo-rollouts"
-}
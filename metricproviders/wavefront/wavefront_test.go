@@ -287,3 +287,64 @@ func TestFindDataPointValue(t *testing.T) {
 		assert.Equal(t, "0", epoch)
 	})
 }
+
+func TestFindLastDataPointValue(t *testing.T) {
+	dp := func(time, value float64) []float64 {
+		return []float64{time, value}
+	}
+	dataPoints := []wavefrontapi.DataPoint{
+		dp(10, 3),
+		dp(0, 1),
+		dp(5, 2),
+	}
+	t.Run("Choose last point before the evaluation time", func(t *testing.T) {
+		value, epoch, err := findLastDataPointValue(dataPoints, metav1.Unix(9, 0))
+		assert.NoError(t, err)
+		assert.Equal(t, float64(2), value)
+		assert.Equal(t, "5", epoch)
+	})
+
+	t.Run("Choose point at the evaluation time", func(t *testing.T) {
+		value, epoch, err := findLastDataPointValue(dataPoints, metav1.Unix(10, 0))
+		assert.NoError(t, err)
+		assert.Equal(t, float64(3), value)
+		assert.Equal(t, "10", epoch)
+	})
+
+	t.Run("No point before the evaluation time", func(t *testing.T) {
+		_, _, err := findLastDataPointValue(dataPoints[:1], metav1.Unix(9, 0))
+		assert.EqualError(t, err, "No datapoint found at or before 9 in response from Wavefront")
+	})
+}
+
+func TestProcessResponseWithOffset(t *testing.T) {
+	logCtx := log.WithField("test", "test")
+	p := Provider{
+		logCtx: *logCtx,
+	}
+	metric := v1alpha1.Metric{
+		SuccessCondition: "result == 10",
+		Provider: v1alpha1.MetricProvider{
+			Wavefront: &v1alpha1.WavefrontMetric{
+				OffsetSeconds: 60,
+			},
+		},
+	}
+
+	mockSeries := wavefrontapi.TimeSeries{
+		DataPoints: []wavefrontapi.DataPoint{
+			[]float64{11900, 9},
+			[]float64{11940, 10},
+			[]float64{11950, 11},
+		},
+	}
+	response := &wavefrontapi.QueryResponse{
+		TimeSeries: []wavefrontapi.TimeSeries{mockSeries},
+	}
+	// The closest point to the evaluation time is after it, so the last one before it is evaluated instead
+	result, err := p.processResponse(metric, response, metav1.Unix(11948, 0))
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, result.newStatus)
+	assert.Equal(t, "10.00", result.newValue)
+	assert.Equal(t, "11940", result.epochsUsed)
+}
@@ -7,6 +7,9 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	metricutil "github.com/argoproj/argo-rollouts/utils/metric"
@@ -21,6 +24,13 @@ import (
 const (
 	//ProviderType indicates the provider is prometheus
 	ProviderType = "WebMetric"
+	// DefaultPageLimit is the maximum number of pages requested when the metric does not specify a page limit
+	DefaultPageLimit = 10
+	// MaxPageLimit caps the number of pages requested by a single measurement
+	MaxPageLimit = 100
+	// ValuesMetadataKey is the key of the measurement metadata holding the named values selected by valuesFrom, when
+	// the result is the value selected by the jsonPath
+	ValuesMetadataKey = "values"
 )
 
 // Provider contains all the required components to run a WebMetric query
@@ -29,6 +39,8 @@ type Provider struct {
 	logCtx     log.Entry
 	client     *http.Client
 	jsonParser *jsonpath.JSONPath
+	// recordResponseBodies records the response bodies of failed requests in the measurement metadata
+	recordResponseBodies bool
 }
 
 // Type incidates provider is a WebMetric provider
@@ -44,67 +56,258 @@ func (p *Provider) Run(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric) v1alph
 		StartedAt: &startTime,
 	}
 
-	// Create request
-	request := &http.Request{
-		Method: "GET", // TODO maybe make this configurable....also implies we will need body templates
-	}
-
 	url, err := url.Parse(metric.Provider.Web.URL)
 	if err != nil {
 		return metricutil.MarkMeasurementError(measurement, err)
 	}
 
-	request.URL = url
-
-	request.Header = make(http.Header)
-
-	for _, header := range metric.Provider.Web.Headers {
-		request.Header.Set(header.Key, header.Value)
+	var value string
+	var values map[string]string
+	if metric.Provider.Web.NextPageJSONPath == "" {
+		value, values, err = p.query(metric.Provider.Web, url)
+	} else {
+		value, err = p.queryPages(metric.Provider.Web, url)
 	}
-
-	// Send Request
-	response, err := p.client.Do(request)
 	if err != nil {
-		return metricutil.MarkMeasurementError(measurement, err)
-	} else if response.StatusCode < 200 || response.StatusCode >= 300 {
-		return metricutil.MarkMeasurementError(measurement, fmt.Errorf("received non 2xx response code: %v", response.StatusCode))
+		return metricutil.MarkMeasurementResponseError(measurement, err, p.recordResponseBodies, headerValues(metric.Provider.Web)...)
 	}
 
-	value, status, err := p.parseResponse(metric, response)
+	var result interface{} = value
+	var vars map[string]interface{}
+	if len(values) > 0 {
+		vars = make(map[string]interface{}, len(values))
+		for name, v := range values {
+			vars[name] = v
+		}
+		valuesBytes, err := json.Marshal(values)
+		if err != nil {
+			return metricutil.MarkMeasurementError(measurement, err)
+		}
+		if metric.Provider.Web.JSONPath == "" {
+			// the named values are the result. Its values are typed as strings, so the conditions can convert them with
+			// the asInt and asFloat functions
+			result = values
+			value = string(valuesBytes)
+		} else {
+			measurement.Metadata = map[string]string{ValuesMetadataKey: string(valuesBytes)}
+		}
+	}
+	measurement.Value = value
+	phase, err := evaluate.EvaluateResultWithVars(result, vars, metric, p.logCtx)
 	if err != nil {
 		return metricutil.MarkMeasurementError(measurement, err)
 	}
-
-	measurement.Value = value
-	measurement.Phase = status
+	measurement.Phase = phase
 	finishedTime := metav1.Now()
 	measurement.FinishedAt = &finishedTime
 
 	return measurement
 }
 
-func (p *Provider) parseResponse(metric v1alpha1.Metric, response *http.Response) (string, v1alpha1.AnalysisPhase, error) {
-	var data interface{}
+// query requests the url and returns the value selected by the JSONPath, along with the named values selected by the
+// JSONPaths of valuesFrom
+func (p *Provider) query(webMetric *v1alpha1.WebMetric, url *url.URL) (string, map[string]string, error) {
+	data, body, err := p.get(webMetric, url)
+	if err != nil {
+		return "", nil, err
+	}
+	var value string
+	if webMetric.JSONPath != "" || len(webMetric.ValuesFrom) == 0 {
+		value, err = p.parseValue(data)
+		if err != nil {
+			return "", nil, &metricutil.ResponseError{Err: err, Body: body}
+		}
+	}
+	values, err := parseNamedValues(webMetric.ValuesFrom, data)
+	if err != nil {
+		return "", nil, &metricutil.ResponseError{Err: err, Body: body}
+	}
+	return value, values, nil
+}
+
+// parseNamedValues returns the values selected by the JSONPath of each name of valuesFrom, or nil without valuesFrom
+func parseNamedValues(valuesFrom map[string]string, data interface{}) (map[string]string, error) {
+	if len(valuesFrom) == 0 {
+		return nil, nil
+	}
+	names := make([]string, 0, len(valuesFrom))
+	for name := range valuesFrom {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	values := make(map[string]string, len(valuesFrom))
+	for _, name := range names {
+		parser := jsonpath.New(name)
+		if err := parser.Parse(valuesFrom[name]); err != nil {
+			return nil, fmt.Errorf("Could not parse JSONPath of value '%s': %v", name, err)
+		}
+		buf := new(bytes.Buffer)
+		if err := parser.Execute(buf, data); err != nil {
+			return nil, fmt.Errorf("Could not find JSONPath of value '%s' in body: %s", name, err)
+		}
+		values[name] = buf.String()
+	}
+	return values, nil
+}
 
-	bodyBytes, err := ioutil.ReadAll(response.Body)
+// queryPages follows the next pages from the url and returns the sum of the values selected by the JSONPath on
+// every page
+func (p *Provider) queryPages(webMetric *v1alpha1.WebMetric, pageURL *url.URL) (string, error) {
+	nextPageParser := jsonpath.New("nextPage").AllowMissingKeys(true)
+	err := nextPageParser.Parse(webMetric.NextPageJSONPath)
 	if err != nil {
-		return "", v1alpha1.AnalysisPhaseError, fmt.Errorf("Received no bytes in response: %v", err)
+		return "", fmt.Errorf("Could not parse nextPageJsonPath: %v", err)
+	}
+	pageLimit := getPageLimit(webMetric)
+	requested := map[string]bool{}
+	sum := float64(0)
+	for page := 1; ; page++ {
+		requested[pageURL.String()] = true
+		data, body, err := p.get(webMetric, pageURL)
+		if err != nil {
+			return "", err
+		}
+		value, err := p.parseValue(data)
+		if err != nil {
+			return "", &metricutil.ResponseError{Err: err, Body: body}
+		}
+		for _, field := range strings.Fields(value) {
+			f, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				return "", &metricutil.ResponseError{Err: fmt.Errorf("Could not sum non numeric value '%s' of page %d", field, page), Body: body}
+			}
+			sum += f
+		}
+
+		next, err := findNextPage(nextPageParser, data)
+		if err != nil {
+			return "", &metricutil.ResponseError{Err: err, Body: body}
+		}
+		if next == "" {
+			break
+		}
+		// Relative URLs are resolved against the URL of the current page
+		nextURL, err := pageURL.Parse(next)
+		if err != nil {
+			return "", fmt.Errorf("Could not parse next page URL '%s': %v", next, err)
+		}
+		if requested[nextURL.String()] {
+			return "", fmt.Errorf("next page URL '%s' was already requested", nextURL)
+		}
+		if page >= pageLimit {
+			return "", fmt.Errorf("page limit (%d) reached before the last page", pageLimit)
+		}
+		pageURL = nextURL
 	}
+	return strconv.FormatFloat(sum, 'f', -1, 64), nil
+}
 
+// findNextPage returns the URL of the next page selected by the parser, or an empty string on the last page
+func findNextPage(parser *jsonpath.JSONPath, data interface{}) (string, error) {
+	results, err := parser.FindResults(data)
+	if err != nil {
+		return "", fmt.Errorf("Could not find nextPageJsonPath in body: %v", err)
+	}
+	for _, result := range results {
+		for _, value := range result {
+			if !value.IsValid() {
+				continue
+			}
+			switch next := value.Interface().(type) {
+			case nil:
+				continue
+			case string:
+				return next, nil
+			default:
+				return "", fmt.Errorf("nextPageJsonPath selected a non string value: %v", next)
+			}
+		}
+	}
+	return "", nil
+}
+
+// getPageLimit returns the maximum number of pages requested, capped to MaxPageLimit
+func getPageLimit(webMetric *v1alpha1.WebMetric) int {
+	if webMetric.PageLimit <= 0 {
+		return DefaultPageLimit
+	}
+	if webMetric.PageLimit > MaxPageLimit {
+		return MaxPageLimit
+	}
+	return webMetric.PageLimit
+}
+
+// get sends a GET request to the url and returns the decoded JSON body along with the raw body
+func (p *Provider) get(webMetric *v1alpha1.WebMetric, url *url.URL) (interface{}, []byte, error) {
+	request := &http.Request{
+		Method: "GET", // TODO maybe make this configurable....also implies we will need body templates
+		URL:    url,
+		Header: make(http.Header),
+	}
+
+	for _, header := range webMetric.Headers {
+		request.Header.Set(header.Key, header.Value)
+	}
+
+	// Send Request
+	response, err := p.client.Do(request)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer response.Body.Close()
+	if !isSuccessStatus(webMetric, response.StatusCode) {
+		// The body is only read to be recorded, it is not part of the error message
+		bodyBytes, _ := ioutil.ReadAll(response.Body)
+		err := fmt.Errorf("received non 2xx response code: %v", response.StatusCode)
+		if len(webMetric.SuccessStatusRanges) > 0 {
+			err = fmt.Errorf("received response code %v outside of the successStatusRanges", response.StatusCode)
+		}
+		return nil, nil, &metricutil.ResponseError{Err: err, Body: bodyBytes}
+	}
+
+	bodyBytes, err := metricutil.ReadResponseBody(response.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var data interface{}
 	err = json.Unmarshal(bodyBytes, &data)
 	if err != nil {
-		return "", v1alpha1.AnalysisPhaseError, fmt.Errorf("Could not parse JSON body: %v", err)
+		return nil, nil, &metricutil.ResponseError{Err: fmt.Errorf("Could not parse JSON body: %v", err), Body: bodyBytes}
+	}
+	return data, bodyBytes, nil
+}
+
+// isSuccessStatus returns whether the response with the status code is parsed, which are the 2xx responses unless the
+// metric has successStatusRanges
+func isSuccessStatus(webMetric *v1alpha1.WebMetric, statusCode int) bool {
+	if len(webMetric.SuccessStatusRanges) == 0 {
+		return statusCode >= 200 && statusCode < 300
+	}
+	for _, statusRange := range webMetric.SuccessStatusRanges {
+		if statusRange.Contains(statusCode) {
+			return true
+		}
+	}
+	return false
+}
+
+// headerValues returns the values of the headers of the metric, which are redacted from the recorded response bodies
+func headerValues(webMetric *v1alpha1.WebMetric) []string {
+	values := make([]string, 0, len(webMetric.Headers))
+	for _, header := range webMetric.Headers {
+		values = append(values, header.Value)
 	}
+	return values
+}
 
+func (p *Provider) parseValue(data interface{}) (string, error) {
 	buf := new(bytes.Buffer)
-	err = p.jsonParser.Execute(buf, data)
+	err := p.jsonParser.Execute(buf, data)
 	if err != nil {
-		return "", v1alpha1.AnalysisPhaseError, fmt.Errorf("Could not find JSONPath in body: %s", err)
+		return "", fmt.Errorf("Could not find JSONPath in body: %s", err)
 	}
-	out := buf.String()
-
-	status := evaluate.EvaluateResult(out, metric, p.logCtx)
-	return out, status, nil
+	return buf.String(), nil
 }
 
 // Resume should not be used the WebMetric provider since all the work should occur in the Run method
@@ -148,10 +351,13 @@ func NewWebMetricJsonParser(metric v1alpha1.Metric) (*jsonpath.JSONPath, error)
 	return jsonParser, err
 }
 
-func NewWebMetricProvider(logCtx log.Entry, client *http.Client, jsonParser *jsonpath.JSONPath) *Provider {
+// NewWebMetricProvider creates a new WebMetric provider. When recordResponseBodies is true, the response bodies of
+// failed requests are recorded in the measurement metadata
+func NewWebMetricProvider(logCtx log.Entry, client *http.Client, jsonParser *jsonpath.JSONPath, recordResponseBodies bool) *Provider {
 	return &Provider{
-		logCtx:     logCtx,
-		client:     client,
-		jsonParser: jsonParser,
+		logCtx:               logCtx,
+		client:               client,
+		jsonParser:           jsonParser,
+		recordResponseBodies: recordResponseBodies,
 	}
 }
@@ -1,12 +1,15 @@
 package webmetric
 
 import (
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	metricutil "github.com/argoproj/argo-rollouts/utils/metric"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 )
@@ -248,7 +251,7 @@ func TestRunSuite(t *testing.T) {
 
 		jsonparser, err := NewWebMetricJsonParser(test.metric)
 		assert.NoError(t, err)
-		provider := NewWebMetricProvider(*logCtx, server.Client(), jsonparser)
+		provider := NewWebMetricProvider(*logCtx, server.Client(), jsonparser, false)
 
 		// Get our result
 		measurement := provider.Run(newAnalysisRun(), test.metric)
@@ -277,3 +280,358 @@ func TestRunSuite(t *testing.T) {
 func newAnalysisRun() *v1alpha1.AnalysisRun {
 	return &v1alpha1.AnalysisRun{}
 }
+
+func newPaginatedServer(pages map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		page, ok := pages[req.URL.RequestURI()]
+		if !ok {
+			http.Error(rw, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		io.WriteString(rw, page)
+	}))
+}
+
+func newPaginatedMetric(url string, pageLimit int) v1alpha1.Metric {
+	return v1alpha1.Metric{
+		Name:             "foo",
+		SuccessCondition: "asFloat(result) < 10",
+		FailureCondition: "asFloat(result) >= 10",
+		Provider: v1alpha1.MetricProvider{
+			Web: &v1alpha1.WebMetric{
+				URL:              url,
+				JSONPath:         "{$.items[*].errors}",
+				NextPageJSONPath: "{$.next}",
+				PageLimit:        pageLimit,
+			},
+		},
+	}
+}
+
+func runPaginatedMetric(t *testing.T, server *httptest.Server, metric v1alpha1.Metric) v1alpha1.Measurement {
+	logCtx := log.WithField("test", "test")
+	jsonparser, err := NewWebMetricJsonParser(metric)
+	assert.NoError(t, err)
+	provider := NewWebMetricProvider(*logCtx, server.Client(), jsonparser, false)
+	return provider.Run(newAnalysisRun(), metric)
+}
+
+func TestRunPaginatedSumsPages(t *testing.T) {
+	server := newPaginatedServer(map[string]string{
+		"/errors":        `{"items": [{"errors": 1}, {"errors": 2}], "next": "/errors?page=2"}`,
+		"/errors?page=2": `{"items": [{"errors": 3}], "next": "?page=3"}`,
+		"/errors?page=3": `{"items": [{"errors": 4.5}], "next": null}`,
+	})
+	defer server.Close()
+
+	measurement := runPaginatedMetric(t, server, newPaginatedMetric(server.URL+"/errors", 0))
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, measurement.Phase)
+	assert.Equal(t, "10.5", measurement.Value)
+	assert.NotNil(t, measurement.FinishedAt)
+}
+
+func TestRunPaginatedStopsWithoutNextPage(t *testing.T) {
+	server := newPaginatedServer(map[string]string{
+		"/errors": `{"items": [{"errors": 1}]}`,
+	})
+	defer server.Close()
+
+	measurement := runPaginatedMetric(t, server, newPaginatedMetric(server.URL+"/errors", 0))
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+	assert.Equal(t, "1", measurement.Value)
+}
+
+func TestRunPaginatedPageLimitReached(t *testing.T) {
+	server := newPaginatedServer(map[string]string{
+		"/errors":        `{"items": [{"errors": 1}], "next": "/errors?page=2"}`,
+		"/errors?page=2": `{"items": [{"errors": 1}], "next": "/errors?page=3"}`,
+		"/errors?page=3": `{"items": [{"errors": 1}]}`,
+	})
+	defer server.Close()
+
+	measurement := runPaginatedMetric(t, server, newPaginatedMetric(server.URL+"/errors", 2))
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "page limit (2) reached before the last page", measurement.Message)
+}
+
+func TestRunPaginatedLoop(t *testing.T) {
+	server := newPaginatedServer(map[string]string{
+		"/errors":        `{"items": [{"errors": 1}], "next": "/errors?page=2"}`,
+		"/errors?page=2": `{"items": [{"errors": 1}], "next": "/errors"}`,
+	})
+	defer server.Close()
+
+	measurement := runPaginatedMetric(t, server, newPaginatedMetric(server.URL+"/errors", 0))
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Contains(t, measurement.Message, "was already requested")
+}
+
+func TestRunPaginatedNonNumericValue(t *testing.T) {
+	server := newPaginatedServer(map[string]string{
+		"/errors": `{"items": [{"errors": "many"}]}`,
+	})
+	defer server.Close()
+
+	measurement := runPaginatedMetric(t, server, newPaginatedMetric(server.URL+"/errors", 0))
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "Could not sum non numeric value 'many' of page 1", measurement.Message)
+}
+
+func TestRunPaginatedPageError(t *testing.T) {
+	server := newPaginatedServer(map[string]string{
+		"/errors": `{"items": [{"errors": 1}], "next": "/missing"}`,
+	})
+	defer server.Close()
+
+	measurement := runPaginatedMetric(t, server, newPaginatedMetric(server.URL+"/errors", 0))
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "received non 2xx response code: 404", measurement.Message)
+}
+
+func TestRunSuccessStatusRanges(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		switch req.URL.Path {
+		case "/degraded":
+			rw.WriteHeader(http.StatusNotFound)
+			io.WriteString(rw, `{"status": "degraded"}`)
+		case "/unavailable":
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			io.WriteString(rw, `{"status": "unavailable"}`)
+		default:
+			io.WriteString(rw, `{"status": "healthy"}`)
+		}
+	}))
+	defer server.Close()
+	newMetric := func(path string, ranges ...v1alpha1.HTTPStatusRange) v1alpha1.Metric {
+		return v1alpha1.Metric{
+			Name:             "foo",
+			SuccessCondition: "result == 'healthy'",
+			FailureCondition: "result == 'degraded'",
+			Provider: v1alpha1.MetricProvider{
+				Web: &v1alpha1.WebMetric{
+					URL:                 server.URL + path,
+					JSONPath:            "{$.status}",
+					SuccessStatusRanges: ranges,
+				},
+			},
+		}
+	}
+	run := func(metric v1alpha1.Metric) v1alpha1.Measurement {
+		jsonparser, err := NewWebMetricJsonParser(metric)
+		assert.NoError(t, err)
+		return NewWebMetricProvider(*log.WithField("test", "test"), server.Client(), jsonparser, false).Run(newAnalysisRun(), metric)
+	}
+
+	// Only the 2xx responses are parsed by default
+	measurement := run(newMetric("/degraded"))
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "received non 2xx response code: 404", measurement.Message)
+
+	// The 404 response is evaluated by the conditions
+	ranges := []v1alpha1.HTTPStatusRange{{Min: 200, Max: 299}, {Min: 404}}
+	measurement = run(newMetric("/degraded", ranges...))
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, measurement.Phase)
+	assert.Equal(t, "degraded", measurement.Value)
+
+	measurement = run(newMetric("/healthy", ranges...))
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+	assert.Equal(t, "healthy", measurement.Value)
+
+	measurement = run(newMetric("/unavailable", ranges...))
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "received response code 503 outside of the successStatusRanges", measurement.Message)
+
+	// The 2xx responses are errors when they are not listed
+	measurement = run(newMetric("/healthy", v1alpha1.HTTPStatusRange{Min: 404}))
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+}
+
+func newHealthServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		io.WriteString(rw, `{"status": "healthy", "latency": {"p99": 250}, "errorRate": 0.02}`)
+	}))
+}
+
+func runWebMetric(t *testing.T, metric v1alpha1.Metric) v1alpha1.Measurement {
+	jsonparser, err := NewWebMetricJsonParser(metric)
+	assert.NoError(t, err)
+	return NewWebMetricProvider(*log.WithField("test", "test"), http.DefaultClient, jsonparser, false).Run(newAnalysisRun(), metric)
+}
+
+func TestRunValuesFrom(t *testing.T) {
+	server := newHealthServer()
+	defer server.Close()
+	metric := v1alpha1.Metric{
+		Name:             "health",
+		SuccessCondition: "asFloat(latency) < 300 && asFloat(errorRate) < 0.05",
+		Provider: v1alpha1.MetricProvider{
+			Web: &v1alpha1.WebMetric{
+				URL: server.URL,
+				ValuesFrom: map[string]string{
+					"latency":   "{$.latency.p99}",
+					"errorRate": "{$.errorRate}",
+				},
+			},
+		},
+	}
+
+	// a single request populates both variables, and the result is the map of the values
+	measurement := runWebMetric(t, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+	assert.Equal(t, `{"errorRate":"0.02","latency":"250"}`, measurement.Value)
+
+	metric.SuccessCondition = "asFloat(latency) < 200 && asFloat(errorRate) < 0.05"
+	measurement = runWebMetric(t, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, measurement.Phase)
+
+	metric.SuccessCondition = "asFloat(result.latency) < 300"
+	measurement = runWebMetric(t, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+}
+
+func TestRunValuesFromWithJSONPath(t *testing.T) {
+	server := newHealthServer()
+	defer server.Close()
+	metric := v1alpha1.Metric{
+		Name:             "health",
+		SuccessCondition: "result == 'healthy' && asFloat(errorRate) < 0.01",
+		Provider: v1alpha1.MetricProvider{
+			Web: &v1alpha1.WebMetric{
+				URL:      server.URL,
+				JSONPath: "{$.status}",
+				ValuesFrom: map[string]string{
+					"errorRate": "{$.errorRate}",
+				},
+			},
+		},
+	}
+
+	// the result is the value of the jsonPath, and the named values are recorded in the metadata
+	measurement := runWebMetric(t, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, measurement.Phase)
+	assert.Equal(t, "healthy", measurement.Value)
+	assert.Equal(t, `{"errorRate":"0.02"}`, measurement.Metadata[ValuesMetadataKey])
+}
+
+func TestRunValuesFromMissing(t *testing.T) {
+	server := newHealthServer()
+	defer server.Close()
+	metric := v1alpha1.Metric{
+		Name: "health",
+		Provider: v1alpha1.MetricProvider{
+			Web: &v1alpha1.WebMetric{
+				URL: server.URL,
+				ValuesFrom: map[string]string{
+					"saturation": "{$.saturation}",
+				},
+			},
+		},
+	}
+
+	measurement := runWebMetric(t, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "Could not find JSONPath of value 'saturation' in body: saturation is not found", measurement.Message)
+}
+
+func TestHTTPStatusRangeContains(t *testing.T) {
+	assert.True(t, v1alpha1.HTTPStatusRange{Min: 404}.Contains(404))
+	assert.False(t, v1alpha1.HTTPStatusRange{Min: 404}.Contains(405))
+	assert.True(t, v1alpha1.HTTPStatusRange{Min: 400, Max: 499}.Contains(499))
+	assert.False(t, v1alpha1.HTTPStatusRange{Min: 400, Max: 499}.Contains(500))
+}
+
+func TestGetPageLimit(t *testing.T) {
+	assert.Equal(t, DefaultPageLimit, getPageLimit(&v1alpha1.WebMetric{}))
+	assert.Equal(t, 5, getPageLimit(&v1alpha1.WebMetric{PageLimit: 5}))
+	assert.Equal(t, MaxPageLimit, getPageLimit(&v1alpha1.WebMetric{PageLimit: 1000}))
+}
+
+func TestRunRecordsResponseBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusBadGateway)
+		io.WriteString(rw, `{"error": "upstream unavailable", "echo": "`+req.Header.Get("Authorization")+`", "token": "abc"}`)
+	}))
+	defer server.Close()
+	metric := v1alpha1.Metric{
+		Name: "foo",
+		Provider: v1alpha1.MetricProvider{
+			Web: &v1alpha1.WebMetric{
+				URL:      server.URL,
+				JSONPath: "{$.value}",
+				Headers:  []v1alpha1.WebMetricHeader{{Key: "Authorization", Value: "Bearer s3cr3t"}},
+			},
+		},
+	}
+	jsonparser, err := NewWebMetricJsonParser(metric)
+	assert.NoError(t, err)
+
+	// The response bodies are not recorded by default
+	measurement := NewWebMetricProvider(*log.WithField("test", "test"), server.Client(), jsonparser, false).Run(newAnalysisRun(), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Nil(t, measurement.Metadata)
+
+	measurement = NewWebMetricProvider(*log.WithField("test", "test"), server.Client(), jsonparser, true).Run(newAnalysisRun(), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "received non 2xx response code: 502", measurement.Message)
+	assert.Equal(t, `{"error": "upstream unavailable", "echo": "<redacted>", "token": "<redacted>"}`, measurement.Metadata[metricutil.ResponseBodyMetadataKey])
+}
+
+func TestRunRecordsTruncatedResponseBodyOnMissingJSONPath(t *testing.T) {
+	body := `{"items": ["` + strings.Repeat("x", 2*metricutil.MaxResponseBodyLength) + `"]}`
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		io.WriteString(rw, body)
+	}))
+	defer server.Close()
+	metric := v1alpha1.Metric{
+		Name: "foo",
+		Provider: v1alpha1.MetricProvider{
+			Web: &v1alpha1.WebMetric{
+				URL:      server.URL,
+				JSONPath: "{$.value}",
+			},
+		},
+	}
+	jsonparser, err := NewWebMetricJsonParser(metric)
+	assert.NoError(t, err)
+
+	measurement := NewWebMetricProvider(*log.WithField("test", "test"), server.Client(), jsonparser, true).Run(newAnalysisRun(), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "Could not find JSONPath in body: value is not found", measurement.Message)
+	recorded := measurement.Metadata[metricutil.ResponseBodyMetadataKey]
+	assert.True(t, strings.HasPrefix(recorded, body[:metricutil.MaxResponseBodyLength]))
+	assert.True(t, strings.HasSuffix(recorded, fmt.Sprintf("... (%d bytes truncated)", len(body)-metricutil.MaxResponseBodyLength)))
+}
+
+func TestRunResponseTooLarge(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		io.WriteString(rw, `{"value": 1, "padding": "`+strings.Repeat("x", 1024)+`"}`)
+	}))
+	defer server.Close()
+	metric := v1alpha1.Metric{
+		Name:             "foo",
+		SuccessCondition: "asInt(result) == 1",
+		Provider: v1alpha1.MetricProvider{
+			Web: &v1alpha1.WebMetric{
+				URL:      server.URL,
+				JSONPath: "{$.value}",
+			},
+		},
+	}
+	jsonparser, err := NewWebMetricJsonParser(metric)
+	assert.NoError(t, err)
+
+	client := metricutil.LimitResponseBytes(server.Client(), 512)
+	measurement := NewWebMetricProvider(*log.WithField("test", "test"), client, jsonparser, false).Run(newAnalysisRun(), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "response body exceeds the maximum size of 512 bytes", measurement.Message)
+
+	client = metricutil.LimitResponseBytes(server.Client(), 2048)
+	measurement = NewWebMetricProvider(*log.WithField("test", "test"), client, jsonparser, false).Run(newAnalysisRun(), metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+	assert.Equal(t, "1", measurement.Value)
+}
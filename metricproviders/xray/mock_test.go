@@ -0,0 +1,33 @@
+package xray
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	awsxray "github.com/aws/aws-sdk-go/service/xray"
+)
+
+// mockAPI returns the pages of the responses in turn, recording the inputs of the calls
+type mockAPI struct {
+	servicePages []*awsxray.GetServiceGraphOutput
+	tracePages   []*awsxray.GetTraceSummariesOutput
+	err          error
+
+	serviceInputs []awsxray.GetServiceGraphInput
+	traceInputs   []awsxray.GetTraceSummariesInput
+}
+
+func (m *mockAPI) GetServiceGraphWithContext(ctx aws.Context, input *awsxray.GetServiceGraphInput, opts ...request.Option) (*awsxray.GetServiceGraphOutput, error) {
+	m.serviceInputs = append(m.serviceInputs, *input)
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.servicePages[len(m.serviceInputs)-1], nil
+}
+
+func (m *mockAPI) GetTraceSummariesWithContext(ctx aws.Context, input *awsxray.GetTraceSummariesInput, opts ...request.Option) (*awsxray.GetTraceSummariesOutput, error) {
+	m.traceInputs = append(m.traceInputs, *input)
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.tracePages[len(m.traceInputs)-1], nil
+}
@@ -0,0 +1,289 @@
+package xray
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	awsxray "github.com/aws/aws-sdk-go/service/xray"
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/argoproj/argo-rollouts/utils/evaluate"
+	metricutil "github.com/argoproj/argo-rollouts/utils/metric"
+)
+
+const (
+	// ProviderType indicates the provider is AWS X-Ray
+	ProviderType = "XRay"
+	// DefaultInterval is the duration over which the requests are evaluated when the metric does not specify one
+	DefaultInterval = 5 * time.Minute
+)
+
+var nowFn = time.Now
+
+// XRayAPI is the subset of the X-Ray API used by the provider
+type XRayAPI interface {
+	GetServiceGraphWithContext(aws.Context, *awsxray.GetServiceGraphInput, ...request.Option) (*awsxray.GetServiceGraphOutput, error)
+	GetTraceSummariesWithContext(aws.Context, *awsxray.GetTraceSummariesInput, ...request.Option) (*awsxray.GetTraceSummariesOutput, error)
+}
+
+// requestCounts are the counts of the requests, and of the requests which errored, faulted or were throttled
+type requestCounts struct {
+	total    int64
+	errors   int64
+	faults   int64
+	throttle int64
+}
+
+// Provider evaluates the error and fault rates of the requests of a service, or of traces, recorded by X-Ray
+// Implements the Provider Interface
+type Provider struct {
+	api    XRayAPI
+	logCtx log.Entry
+}
+
+// Type indicates provider is an X-Ray provider
+func (p *Provider) Type() string {
+	return ProviderType
+}
+
+// Run counts the requests over the interval of the metric and evaluates their error and fault rates
+func (p *Provider) Run(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric) v1alpha1.Measurement {
+	startTime := metav1.Now()
+	measurement := v1alpha1.Measurement{
+		StartedAt: &startTime,
+	}
+	xrayMetric := metric.Provider.XRay
+	interval, err := Interval(xrayMetric)
+	if err != nil {
+		return metricutil.MarkMeasurementError(measurement, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout(xrayMetric))
+	defer cancel()
+
+	end := nowFn()
+	start := end.Add(-interval)
+	var counts *requestCounts
+	if xrayMetric.FilterExpression != "" {
+		counts, err = p.traceCounts(ctx, xrayMetric, start, end)
+	} else {
+		counts, err = p.serviceCounts(ctx, xrayMetric, start, end)
+	}
+	if err != nil {
+		return metricutil.MarkMeasurementError(measurement, apiError(err))
+	}
+
+	value := map[string]interface{}{
+		"requestCount":  counts.total,
+		"errorCount":    counts.errors,
+		"faultCount":    counts.faults,
+		"throttleCount": counts.throttle,
+	}
+	if counts.total > 0 {
+		value["errorRate"] = float64(counts.errors) / float64(counts.total)
+		value["faultRate"] = float64(counts.faults) / float64(counts.total)
+		value["throttleRate"] = float64(counts.throttle) / float64(counts.total)
+	}
+	valueBytes, err := json.Marshal(value)
+	if err != nil {
+		return metricutil.MarkMeasurementError(measurement, err)
+	}
+	measurement.Value = string(valueBytes)
+	if counts.total == 0 {
+		// the rates of a canary without requests are unknown
+		measurement.Phase = v1alpha1.AnalysisPhaseInconclusive
+		measurement.Message = fmt.Sprintf("no requests recorded by X-Ray over the interval of %s", interval)
+	} else {
+		phase, err := evaluate.EvaluateResult(value, metric, p.logCtx)
+		if err != nil {
+			return metricutil.MarkMeasurementError(measurement, err)
+		}
+		measurement.Phase = phase
+	}
+	finishedTime := metav1.Now()
+	measurement.FinishedAt = &finishedTime
+	return measurement
+}
+
+// serviceCounts sums the statistics of the services of the service graph matching the service of the metric
+func (p *Provider) serviceCounts(ctx context.Context, metric *v1alpha1.XRayMetric, start, end time.Time) (*requestCounts, error) {
+	input := &awsxray.GetServiceGraphInput{
+		StartTime: aws.Time(start),
+		EndTime:   aws.Time(end),
+	}
+	if metric.GroupName != "" {
+		input.GroupName = aws.String(metric.GroupName)
+	}
+	counts := &requestCounts{}
+	found := false
+	for {
+		output, err := p.api.GetServiceGraphWithContext(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		for _, service := range output.Services {
+			if !matchesService(service, metric) {
+				continue
+			}
+			found = true
+			addServiceStatistics(counts, service.SummaryStatistics)
+		}
+		if aws.StringValue(output.NextToken) == "" {
+			break
+		}
+		input.NextToken = output.NextToken
+	}
+	if !found {
+		return nil, fmt.Errorf("service '%s' not found in the X-Ray service graph", metric.ServiceName)
+	}
+	return counts, nil
+}
+
+// traceCounts counts the summaries of the traces matching the filter expression of the metric
+func (p *Provider) traceCounts(ctx context.Context, metric *v1alpha1.XRayMetric, start, end time.Time) (*requestCounts, error) {
+	input := &awsxray.GetTraceSummariesInput{
+		StartTime:        aws.Time(start),
+		EndTime:          aws.Time(end),
+		FilterExpression: aws.String(metric.FilterExpression),
+	}
+	counts := &requestCounts{}
+	for {
+		output, err := p.api.GetTraceSummariesWithContext(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		for _, summary := range output.TraceSummaries {
+			counts.total++
+			if aws.BoolValue(summary.HasError) {
+				counts.errors++
+			}
+			if aws.BoolValue(summary.HasFault) {
+				counts.faults++
+			}
+			if aws.BoolValue(summary.HasThrottle) {
+				counts.throttle++
+			}
+		}
+		if aws.StringValue(output.NextToken) == "" {
+			break
+		}
+		input.NextToken = output.NextToken
+	}
+	return counts, nil
+}
+
+// matchesService returns whether one of the names of the service is the service of the metric, and its type the
+// type of the metric when set
+func matchesService(service *awsxray.Service, metric *v1alpha1.XRayMetric) bool {
+	if metric.ServiceType != "" && aws.StringValue(service.Type) != metric.ServiceType {
+		return false
+	}
+	if aws.StringValue(service.Name) == metric.ServiceName {
+		return true
+	}
+	for _, name := range service.Names {
+		if aws.StringValue(name) == metric.ServiceName {
+			return true
+		}
+	}
+	return false
+}
+
+func addServiceStatistics(counts *requestCounts, statistics *awsxray.ServiceStatistics) {
+	if statistics == nil {
+		return
+	}
+	counts.total += aws.Int64Value(statistics.TotalCount)
+	if statistics.ErrorStatistics != nil {
+		counts.errors += aws.Int64Value(statistics.ErrorStatistics.TotalCount)
+		counts.throttle += aws.Int64Value(statistics.ErrorStatistics.ThrottleCount)
+	}
+	if statistics.FaultStatistics != nil {
+		counts.faults += aws.Int64Value(statistics.FaultStatistics.TotalCount)
+	}
+}
+
+// apiError returns the error of a call to X-Ray with its error code
+func apiError(err error) error {
+	if awsErr, ok := err.(awserr.Error); ok {
+		return fmt.Errorf("X-Ray returned %s: %s", awsErr.Code(), awsErr.Message())
+	}
+	return err
+}
+
+// Interval returns the duration over which the requests of the metric are evaluated
+func Interval(metric *v1alpha1.XRayMetric) (time.Duration, error) {
+	if metric.Interval == "" {
+		return DefaultInterval, nil
+	}
+	interval, err := metric.Interval.Duration()
+	if err != nil {
+		return 0, fmt.Errorf("invalid interval '%s': %v", metric.Interval, err)
+	}
+	return interval, nil
+}
+
+func timeout(metric *v1alpha1.XRayMetric) time.Duration {
+	// Using a default timeout of 10 seconds
+	if metric.TimeoutSeconds <= 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(metric.TimeoutSeconds) * time.Second
+}
+
+// Address returns the region of X-Ray queried by the metric
+func Address(metric *v1alpha1.XRayMetric) string {
+	return metric.Region
+}
+
+// Resume should not be used by the X-Ray provider since all the requests should complete immediately
+func (p *Provider) Resume(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, measurement v1alpha1.Measurement) v1alpha1.Measurement {
+	p.logCtx.Warn("X-Ray provider should not execute the Resume method")
+	return measurement
+}
+
+// Terminate should not be used by the X-Ray provider since all the requests should complete immediately
+func (p *Provider) Terminate(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, measurement v1alpha1.Measurement) v1alpha1.Measurement {
+	p.logCtx.Warn("X-Ray provider should not execute the Terminate method")
+	return measurement
+}
+
+// GarbageCollect is a no-op for the X-Ray provider
+func (p *Provider) GarbageCollect(run *v1alpha1.AnalysisRun, metric v1alpha1.Metric, limit int) error {
+	return nil
+}
+
+// NewXRayAPI returns a client of X-Ray using the credentials of the controller, or the role of the metric when set
+func NewXRayAPI(metric v1alpha1.Metric) (XRayAPI, error) {
+	config := aws.NewConfig()
+	if metric.Provider.XRay.Region != "" {
+		config = config.WithRegion(metric.Provider.XRay.Region)
+	}
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            *config,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if metric.Provider.XRay.RoleARN != "" {
+		return awsxray.New(sess, aws.NewConfig().WithCredentials(stscreds.NewCredentials(sess, metric.Provider.XRay.RoleARN))), nil
+	}
+	return awsxray.New(sess), nil
+}
+
+// NewXRayProvider creates a new X-Ray provider
+func NewXRayProvider(api XRayAPI, logCtx log.Entry) *Provider {
+	return &Provider{
+		api:    api,
+		logCtx: logCtx,
+	}
+}
@@ -0,0 +1,234 @@
+package xray
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	awsxray "github.com/aws/aws-sdk-go/service/xray"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+)
+
+var testNow = time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+
+func init() {
+	nowFn = func() time.Time { return testNow }
+}
+
+func newServiceMetric() v1alpha1.Metric {
+	return v1alpha1.Metric{
+		Name:             "checkout-faults",
+		SuccessCondition: "result.faultRate < 0.05",
+		Provider: v1alpha1.MetricProvider{
+			XRay: &v1alpha1.XRayMetric{
+				Region:      "us-west-2",
+				ServiceName: "checkout-canary",
+				GroupName:   "checkout",
+			},
+		},
+	}
+}
+
+func newService(name, serviceType string, total, errors, throttles, faults int64) *awsxray.Service {
+	return &awsxray.Service{
+		Name: aws.String(name),
+		Type: aws.String(serviceType),
+		SummaryStatistics: &awsxray.ServiceStatistics{
+			TotalCount: aws.Int64(total),
+			OkCount:    aws.Int64(total - errors - faults),
+			ErrorStatistics: &awsxray.ErrorStatistics{
+				TotalCount:    aws.Int64(errors),
+				ThrottleCount: aws.Int64(throttles),
+			},
+			FaultStatistics: &awsxray.FaultStatistics{
+				TotalCount: aws.Int64(faults),
+			},
+		},
+	}
+}
+
+func resultOf(t *testing.T, measurement v1alpha1.Measurement) map[string]interface{} {
+	var result map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(measurement.Value), &result))
+	return result
+}
+
+func TestType(t *testing.T) {
+	p := NewXRayProvider(&mockAPI{}, *log.WithField("", ""))
+	assert.Equal(t, ProviderType, p.Type())
+}
+
+func TestRunServiceGraph(t *testing.T) {
+	api := &mockAPI{
+		servicePages: []*awsxray.GetServiceGraphOutput{{
+			Services: []*awsxray.Service{
+				newService("checkout-canary", "AWS::EKS::Container", 150, 6, 3, 3),
+				newService("checkout-stable", "AWS::EKS::Container", 900, 9, 0, 0),
+			},
+			NextToken: aws.String("page-2"),
+		}, {
+			Services: []*awsxray.Service{
+				newService("checkout-canary", "AWS::EKS::Container", 50, 2, 0, 1),
+			},
+		}},
+	}
+	p := NewXRayProvider(api, *log.WithField("", ""))
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, newServiceMetric())
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, measurement.Phase)
+	result := resultOf(t, measurement)
+	assert.Equal(t, float64(200), result["requestCount"])
+	assert.Equal(t, float64(8), result["errorCount"])
+	assert.Equal(t, float64(4), result["faultCount"])
+	assert.Equal(t, float64(3), result["throttleCount"])
+	assert.InDelta(t, 0.04, result["errorRate"], 0.0001)
+	assert.InDelta(t, 0.02, result["faultRate"], 0.0001)
+	assert.InDelta(t, 0.015, result["throttleRate"], 0.0001)
+	assert.NotNil(t, measurement.FinishedAt)
+
+	// the service graph is requested over the default interval, following the pages
+	assert.Len(t, api.serviceInputs, 2)
+	assert.Equal(t, testNow.Add(-DefaultInterval), *api.serviceInputs[0].StartTime)
+	assert.Equal(t, testNow, *api.serviceInputs[0].EndTime)
+	assert.Equal(t, "checkout", *api.serviceInputs[0].GroupName)
+	assert.Nil(t, api.serviceInputs[0].NextToken)
+	assert.Equal(t, "page-2", *api.serviceInputs[1].NextToken)
+}
+
+func TestRunServiceGraphFailed(t *testing.T) {
+	api := &mockAPI{
+		servicePages: []*awsxray.GetServiceGraphOutput{{
+			Services: []*awsxray.Service{
+				newService("checkout-canary", "AWS::EKS::Container", 100, 10, 0, 10),
+			},
+		}},
+	}
+	p := NewXRayProvider(api, *log.WithField("", ""))
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, newServiceMetric())
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, measurement.Phase)
+}
+
+func TestRunServiceGraphMatchesNameAndType(t *testing.T) {
+	alias := newService("checkout", "AWS::EKS::Container", 100, 0, 0, 10)
+	alias.Names = []*string{aws.String("checkout"), aws.String("checkout-canary")}
+	api := &mockAPI{
+		servicePages: []*awsxray.GetServiceGraphOutput{{
+			Services: []*awsxray.Service{
+				alias,
+				newService("checkout-canary", "remote", 100, 0, 0, 100),
+			},
+		}},
+	}
+	metric := newServiceMetric()
+	metric.Provider.XRay.ServiceType = "AWS::EKS::Container"
+	p := NewXRayProvider(api, *log.WithField("", ""))
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, measurement.Phase)
+	assert.Equal(t, float64(100), resultOf(t, measurement)["requestCount"])
+}
+
+func TestRunServiceNotFound(t *testing.T) {
+	api := &mockAPI{
+		servicePages: []*awsxray.GetServiceGraphOutput{{
+			Services: []*awsxray.Service{
+				newService("checkout-stable", "AWS::EKS::Container", 900, 9, 0, 0),
+			},
+		}},
+	}
+	p := NewXRayProvider(api, *log.WithField("", ""))
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, newServiceMetric())
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "service 'checkout-canary' not found in the X-Ray service graph", measurement.Message)
+}
+
+func TestRunNoRequests(t *testing.T) {
+	api := &mockAPI{
+		servicePages: []*awsxray.GetServiceGraphOutput{{
+			Services: []*awsxray.Service{
+				newService("checkout-canary", "AWS::EKS::Container", 0, 0, 0, 0),
+			},
+		}},
+	}
+	p := NewXRayProvider(api, *log.WithField("", ""))
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, newServiceMetric())
+	assert.Equal(t, v1alpha1.AnalysisPhaseInconclusive, measurement.Phase)
+	assert.Equal(t, "no requests recorded by X-Ray over the interval of 5m0s", measurement.Message)
+	assert.Equal(t, float64(0), resultOf(t, measurement)["requestCount"])
+}
+
+func TestRunTraceSummaries(t *testing.T) {
+	api := &mockAPI{
+		tracePages: []*awsxray.GetTraceSummariesOutput{{
+			TraceSummaries: []*awsxray.TraceSummary{
+				{Id: aws.String("1"), HasFault: aws.Bool(true)},
+				{Id: aws.String("2"), HasError: aws.Bool(true), HasThrottle: aws.Bool(true)},
+			},
+			NextToken: aws.String("page-2"),
+		}, {
+			TraceSummaries: []*awsxray.TraceSummary{
+				{Id: aws.String("3")},
+				{Id: aws.String("4")},
+			},
+		}},
+	}
+	metric := newServiceMetric()
+	metric.SuccessCondition = "result.faultRate < 0.2"
+	metric.Provider.XRay.FilterExpression = `service("checkout") AND annotation.version = "canary"`
+	metric.Provider.XRay.Interval = "10m"
+	p := NewXRayProvider(api, *log.WithField("", ""))
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, measurement.Phase)
+	result := resultOf(t, measurement)
+	assert.Equal(t, float64(4), result["requestCount"])
+	assert.Equal(t, float64(1), result["errorCount"])
+	assert.Equal(t, float64(1), result["faultCount"])
+	assert.Equal(t, float64(1), result["throttleCount"])
+	assert.InDelta(t, 0.25, result["faultRate"], 0.0001)
+
+	// the service graph is not requested
+	assert.Len(t, api.serviceInputs, 0)
+	assert.Len(t, api.traceInputs, 2)
+	assert.Equal(t, testNow.Add(-10*time.Minute), *api.traceInputs[0].StartTime)
+	assert.Equal(t, metric.Provider.XRay.FilterExpression, *api.traceInputs[0].FilterExpression)
+	assert.Equal(t, "page-2", *api.traceInputs[1].NextToken)
+}
+
+func TestRunAPIError(t *testing.T) {
+	api := &mockAPI{
+		err: awserr.New(awsxray.ErrCodeThrottledException, "Rate exceeded", nil),
+	}
+	p := NewXRayProvider(api, *log.WithField("", ""))
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, newServiceMetric())
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Equal(t, "X-Ray returned ThrottledException: Rate exceeded", measurement.Message)
+}
+
+func TestRunInvalidInterval(t *testing.T) {
+	metric := newServiceMetric()
+	metric.Provider.XRay.Interval = "5x"
+	p := NewXRayProvider(&mockAPI{}, *log.WithField("", ""))
+
+	measurement := p.Run(&v1alpha1.AnalysisRun{}, metric)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, measurement.Phase)
+	assert.Contains(t, measurement.Message, "invalid interval '5x'")
+}
+
+func TestNewXRayAPI(t *testing.T) {
+	metric := newServiceMetric()
+	_, err := NewXRayAPI(metric)
+	assert.NoError(t, err)
+	metric.Provider.XRay.RoleARN = "arn:aws:iam::123456789012:role/xray-reader"
+	_, err = NewXRayAPI(metric)
+	assert.NoError(t, err)
+}
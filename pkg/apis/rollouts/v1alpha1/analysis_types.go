@@ -57,6 +57,26 @@ type AnalysisTemplateSpec struct {
 	// +patchStrategy=merge
 	// +optional
 	Args []Argument `json:"args,omitempty" patchStrategy:"merge" patchMergeKey:"name"`
+	// MetricMixins include metrics defined in ClusterAnalysisTemplates in the metrics of the template. The mixins
+	// are expanded when an AnalysisRun is created from the template
+	// +optional
+	MetricMixins []MetricMixin `json:"metricMixins,omitempty"`
+}
+
+// MetricMixin includes a metric of a ClusterAnalysisTemplate, overriding some of the arguments it references
+type MetricMixin struct {
+	// ClusterTemplateName is the name of the ClusterAnalysisTemplate defining the metric
+	ClusterTemplateName string `json:"clusterTemplateName"`
+	// Metric is the name of the included metric in the ClusterAnalysisTemplate
+	Metric string `json:"metric"`
+	// Name is the name of the metric once included, allowing to include the same metric more than once. Defaults
+	// to the name of the included metric
+	// +optional
+	Name string `json:"name,omitempty"`
+	// Args override the values of arguments of the ClusterAnalysisTemplate. The arguments which are not
+	// overridden become arguments of the including template
+	// +optional
+	Args []Argument `json:"args,omitempty"`
 }
 
 // DurationString is a string representing a duration (e.g. 30s, 5m, 1h)
@@ -74,12 +94,47 @@ type Metric struct {
 	// Interval defines an interval string (e.g. 30s, 5m, 1h) between each measurement.
 	// If omitted, will perform a single measurement
 	Interval DurationString `json:"interval,omitempty"`
+	// DecisionInterval is a shorter interval between the measurements of a metric which a single measurement may
+	// fail, i.e. whose failures, inconclusive measurements or consecutive errors are at their limit. It reaches the
+	// decision, and rolls back a failing canary, faster at the cost of extra queries. It is bounded by the Interval
+	// and by a minimum of 5s. If omitted, the measurements are always taken at the Interval
+	// +optional
+	DecisionInterval DurationString `json:"decisionInterval,omitempty"`
+	// Schedule is a cron expression in UTC (e.g. "0 */2 * * *") of the times of the measurements, as an alternative
+	// to Interval. The first measurement is taken at the first time of the schedule after the run started and the
+	// InitialDelay elapsed. If Count is omitted, the metric runs indefinitely.
+	// +optional
+	Schedule string `json:"schedule,omitempty"`
 	// InitialDelay how long the AnalysisRun should wait before starting this metric
 	InitialDelay DurationString `json:"initialDelay,omitempty"`
-	// Count is the number of times to run the measurement. If both interval and count are omitted,
-	// the effective count is 1. If only interval is specified, metric runs indefinitely.
-	// If count > 1, interval must be specified.
+	// Count is the number of times to run the measurement. If interval, schedule and count are omitted,
+	// the effective count is 1. If only interval or schedule is specified, metric runs indefinitely.
+	// If count > 1, interval or schedule must be specified.
 	Count int32 `json:"count,omitempty"`
+	// MaxMeasurements is a hard cap on the number of measurements taken by a metric which runs indefinitely (e.g.
+	// a background analysis). Once the cap is reached, the metric stops measuring and completes with the
+	// MaxMeasurementsPhase. Unlike count, the cap bounds the resource usage of long-lived runs rather than
+	// deciding when the analysis is done.
+	// +optional
+	MaxMeasurements int32 `json:"maxMeasurements,omitempty"`
+	// MaxMeasurementsPhase is the phase of the metric once MaxMeasurements is reached (Successful, Failed or
+	// Inconclusive). Defaults to Successful
+	// +optional
+	MaxMeasurementsPhase AnalysisPhase `json:"maxMeasurementsPhase,omitempty"`
+	// Transform is an expression applied to the result before the success and failure conditions are evaluated,
+	// so the conditions operate on the transformed value. Examples:
+	//   asFloat(result) / 1024 / 1024
+	//   result * 100
+	// +optional
+	Transform string `json:"transform,omitempty"`
+	// RecentResultsWindow is the number of recent results exposed to the conditions as the `recentResults` list:
+	// the values of the most recent successful measurements followed by the current result, oldest first. This
+	// allows conditions to aggregate over a sliding window, such as avg(recentResults) < 0.05
+	// +optional
+	RecentResultsWindow int32 `json:"recentResultsWindow,omitempty"`
+	// RecentValues are the values of the most recent successful measurements, which are set by the controller
+	// before a measurement is taken when RecentResultsWindow is set
+	RecentValues []string `json:"-"`
 	// SuccessCondition is an expression which determines if a measurement is considered successful
 	// Expression is a goevaluate expression. The keyword `result` is a variable reference to the
 	// value of measurement. Results can be both structured data or primitive.
@@ -91,6 +146,18 @@ type Metric struct {
 	// If both success and failure conditions are specified, and the measurement does not fall into
 	// either condition, the measurement is considered Inconclusive
 	FailureCondition string `json:"failureCondition,omitempty"`
+	// InconclusiveCondition is an expression which determines if a measurement is considered Inconclusive
+	// regardless of the success and failure conditions, such as when there is not enough data to assess
+	// the measurement. It is evaluated before the success and failure conditions.
+	// Examples:
+	//   len(result) == 0
+	// +optional
+	InconclusiveCondition string `json:"inconclusiveCondition,omitempty"`
+	// MarginalBand is a range of results, between two thresholds, which are neither a clear pass nor a clear
+	// failure. A measurement whose result is within the band is considered Inconclusive, which pauses the
+	// rollout for an operator to promote or abort it. It is evaluated before the success and failure conditions.
+	// +optional
+	MarginalBand *MarginalBand `json:"marginalBand,omitempty"`
 	// FailureLimit is the maximum number of times the measurement is allowed to fail, before the
 	// entire metric is considered Failed (default: 0)
 	FailureLimit int32 `json:"failureLimit,omitempty"`
@@ -100,17 +167,31 @@ type Metric struct {
 	// ConsecutiveErrorLimit is the maximum number of times the measurement is allowed to error in
 	// succession, before the metric is considered error (default: 4)
 	ConsecutiveErrorLimit *int32 `json:"consecutiveErrorLimit,omitempty"`
+	// InitialErrorGrace is the number of errors of the first measurements, taken before any measurement completed
+	// without error, which are not counted towards the consecutiveErrorLimit. It tolerates the errors of a provider
+	// whose metrics are not populated yet when the canary starts (default: 0)
+	// +optional
+	InitialErrorGrace int32 `json:"initialErrorGrace,omitempty"`
+	// NaNHandling determines how a NaN or Inf measurement result is assessed (error, fail, pass).
+	// If omitted, the provider's default behavior is used (e.g. Inconclusive for Prometheus)
+	// +optional
+	NaNHandling NaNHandling `json:"nanHandling,omitempty"`
 	// Provider configuration to the external system to use to verify the analysis
 	Provider MetricProvider `json:"provider"`
+	// FallbackProvider is queried when the measurement of the provider errors (e.g. the metrics backend is
+	// unreachable). The result of the fallback provider is used for the measurement. A measurement which does not
+	// meet the success or failure condition does not query the fallback provider.
+	// +optional
+	FallbackProvider *FallbackMetricProvider `json:"fallbackProvider,omitempty"`
 }
 
-// EffectiveCount is the effective count based on whether or not count/interval is specified
-// If neither count or interval is specified, the effective count is 1
-// If only interval is specified, metric runs indefinitely and there is no effective count (nil)
+// EffectiveCount is the effective count based on whether or not count/interval/schedule is specified
+// If neither count, interval or schedule is specified, the effective count is 1
+// If only interval or schedule is specified, metric runs indefinitely and there is no effective count (nil)
 // Otherwise, it is the user specified value
 func (m *Metric) EffectiveCount() *int32 {
 	if m.Count == 0 {
-		if m.Interval == "" {
+		if m.Interval == "" && m.Schedule == "" {
 			one := int32(1)
 			return &one
 		}
@@ -119,6 +200,28 @@ func (m *Metric) EffectiveCount() *int32 {
 	return &m.Count
 }
 
+// NaNHandling defines how a NaN or Inf measurement result should be assessed
+type NaNHandling string
+
+// Possible NaNHandling values
+const (
+	// NaNHandlingError assesses a NaN or Inf result as an Error
+	NaNHandlingError NaNHandling = "error"
+	// NaNHandlingFail assesses a NaN or Inf result as Failed
+	NaNHandlingFail NaNHandling = "fail"
+	// NaNHandlingPass assesses a NaN or Inf result as Successful
+	NaNHandlingPass NaNHandling = "pass"
+)
+
+// MarginalBand defines the thresholds of a band of results which are considered marginal. The thresholds are
+// inclusive and hold numbers as strings (e.g. "0.90")
+type MarginalBand struct {
+	// Lower is the lower threshold of the band
+	Lower string `json:"lower"`
+	// Upper is the upper threshold of the band
+	Upper string `json:"upper"`
+}
+
 // MetricProvider which external system to use to verify the analysis
 // Only one of the fields in this struct should be non-nil
 type MetricProvider struct {
@@ -130,6 +233,97 @@ type MetricProvider struct {
 	Wavefront *WavefrontMetric `json:"wavefront,omitempty"`
 	// Job specifies the job metric run
 	Job *JobMetric `json:"job,omitempty"`
+	// Elasticsearch specifies the Elasticsearch or OpenSearch search to perform
+	Elasticsearch *ElasticsearchMetric `json:"elasticsearch,omitempty"`
+	// KubernetesEvent specifies the Kubernetes Events to count
+	KubernetesEvent *KubernetesEventMetric `json:"kubernetesEvent,omitempty"`
+	// PodExec specifies the command to execute in the canary pods
+	PodExec *PodExecMetric `json:"podExec,omitempty"`
+	// Alertmanager specifies the Prometheus Alertmanager alerts to count
+	Alertmanager *AlertmanagerMetric `json:"alertmanager,omitempty"`
+	// Decision specifies the external decision API returning whether the analysis passes
+	Decision *DecisionMetric `json:"decision,omitempty"`
+	// Loki specifies the LogQL metric query to perform
+	Loki *LokiMetric `json:"loki,omitempty"`
+	// Pingdom specifies the Pingdom synthetic check whose results to evaluate
+	Pingdom *PingdomMetric `json:"pingdom,omitempty"`
+	// GRPC specifies the gRPC method to invoke and the numeric field of its response to evaluate
+	GRPC *GRPCMetric `json:"grpc,omitempty"`
+	// ReplicaSet specifies the fields of a ReplicaSet and of its pods to evaluate
+	ReplicaSet *ReplicaSetMetric `json:"replicaSet,omitempty"`
+	// Datadog specifies the Datadog SLO whose status to evaluate, or the Datadog logs to count
+	Datadog *DatadogMetric `json:"datadog,omitempty"`
+	// PodMetrics specifies the pods whose CPU and memory usage to read from the metrics-server
+	PodMetrics *PodMetricsMetric `json:"podMetrics,omitempty"`
+	// Incident specifies the incident-management API whose active incidents affecting a service to count
+	Incident *IncidentMetric `json:"incident,omitempty"`
+	// XRay specifies the service, or the traces, of AWS X-Ray whose error and fault rates to evaluate
+	XRay *XRayMetric `json:"xray,omitempty"`
+	// CommitStatus specifies the commit of a GitHub or GitLab repository whose checks to aggregate
+	CommitStatus *CommitStatusMetric `json:"commitStatus,omitempty"`
+}
+
+// FallbackMetricProvider is the provider queried when the measurement of the provider of a metric errors. It supports
+// all the providers but the job provider, whose pod template would double the size of the CRDs.
+type FallbackMetricProvider struct {
+	// Prometheus specifies the prometheus metric to query
+	Prometheus *PrometheusMetric `json:"prometheus,omitempty"`
+	Kayenta    *KayentaMetric    `json:"kayenta,omitempty"`
+	Web        *WebMetric        `json:"web,omitempty"`
+	// Wavefront specifies the wavefront metric to query
+	Wavefront *WavefrontMetric `json:"wavefront,omitempty"`
+	// Elasticsearch specifies the Elasticsearch or OpenSearch search to perform
+	Elasticsearch *ElasticsearchMetric `json:"elasticsearch,omitempty"`
+	// KubernetesEvent specifies the Kubernetes Events to count
+	KubernetesEvent *KubernetesEventMetric `json:"kubernetesEvent,omitempty"`
+	// PodExec specifies the command to execute in the canary pods
+	PodExec *PodExecMetric `json:"podExec,omitempty"`
+	// Alertmanager specifies the Prometheus Alertmanager alerts to count
+	Alertmanager *AlertmanagerMetric `json:"alertmanager,omitempty"`
+	// Decision specifies the external decision API returning whether the analysis passes
+	Decision *DecisionMetric `json:"decision,omitempty"`
+	// Loki specifies the LogQL metric query to perform
+	Loki *LokiMetric `json:"loki,omitempty"`
+	// Pingdom specifies the Pingdom synthetic check whose results to evaluate
+	Pingdom *PingdomMetric `json:"pingdom,omitempty"`
+	// GRPC specifies the gRPC method to invoke and the numeric field of its response to evaluate
+	GRPC *GRPCMetric `json:"grpc,omitempty"`
+	// ReplicaSet specifies the fields of a ReplicaSet and of its pods to evaluate
+	ReplicaSet *ReplicaSetMetric `json:"replicaSet,omitempty"`
+	// Datadog specifies the Datadog SLO whose status to evaluate, or the Datadog logs to count
+	Datadog *DatadogMetric `json:"datadog,omitempty"`
+	// PodMetrics specifies the pods whose CPU and memory usage to read from the metrics-server
+	PodMetrics *PodMetricsMetric `json:"podMetrics,omitempty"`
+	// Incident specifies the incident-management API whose active incidents affecting a service to count
+	Incident *IncidentMetric `json:"incident,omitempty"`
+	// XRay specifies the service, or the traces, of AWS X-Ray whose error and fault rates to evaluate
+	XRay *XRayMetric `json:"xray,omitempty"`
+	// CommitStatus specifies the commit of a GitHub or GitLab repository whose checks to aggregate
+	CommitStatus *CommitStatusMetric `json:"commitStatus,omitempty"`
+}
+
+// MetricProvider returns the fallback provider as a metric provider
+func (p FallbackMetricProvider) MetricProvider() MetricProvider {
+	return MetricProvider{
+		Prometheus:      p.Prometheus,
+		Kayenta:         p.Kayenta,
+		Web:             p.Web,
+		Wavefront:       p.Wavefront,
+		Elasticsearch:   p.Elasticsearch,
+		KubernetesEvent: p.KubernetesEvent,
+		PodExec:         p.PodExec,
+		Alertmanager:    p.Alertmanager,
+		Decision:        p.Decision,
+		Loki:            p.Loki,
+		Pingdom:         p.Pingdom,
+		GRPC:            p.GRPC,
+		ReplicaSet:      p.ReplicaSet,
+		Datadog:         p.Datadog,
+		PodMetrics:      p.PodMetrics,
+		Incident:        p.Incident,
+		XRay:            p.XRay,
+		CommitStatus:    p.CommitStatus,
+	}
 }
 
 // AnalysisPhase is the overall phase of an AnalysisRun, MetricResult, or Measurement
@@ -154,12 +348,47 @@ func (as AnalysisPhase) Completed() bool {
 	return false
 }
 
+// BaselineCapture is when the baseline of a comparison is queried
+type BaselineCapture string
+
+// Possible BaselineCapture values
+const (
+	// BaselineCaptureEveryMeasurement queries the baseline with each measurement
+	BaselineCaptureEveryMeasurement BaselineCapture = "EveryMeasurement"
+	// BaselineCaptureRunStart queries the baseline once, with the first measurement of the run, and reuses it
+	BaselineCaptureRunStart BaselineCapture = "RunStart"
+)
+
 // PrometheusMetric defines the prometheus query to perform canary analysis
 type PrometheusMetric struct {
 	// Address is the HTTP address and port of the prometheus server
 	Address string `json:"address,omitempty"`
 	// Query is a raw prometheus query to perform
 	Query string `json:"query,omitempty"`
+	// BaselineQuery is a raw prometheus query for the baseline of the comparison, such as the error rate of the stable
+	// over the prior hour. When set, the conditions compare the `canary` value of the query to the `baseline` value
+	BaselineQuery string `json:"baselineQuery,omitempty"`
+	// BaselineCapture is when the baselineQuery is performed. EveryMeasurement (default) compares each measurement to
+	// the baseline of its time, while RunStart captures the baseline once, with the first measurement of the run, and
+	// compares every measurement to that snapshot, which keeps the comparison stable when the baseline drifts
+	// +optional
+	BaselineCapture BaselineCapture `json:"baselineCapture,omitempty"`
+	// CapturedBaseline is the baseline captured at the start of the run, which is set by the controller before a
+	// measurement is taken when BaselineCapture is RunStart
+	CapturedBaseline string `json:"-"`
+	// BearerTokenFile is the path of a file containing the bearer token sent with the queries, such as the token
+	// of the ServiceAccount mounted in the controller pod. The file is re-read periodically since tokens are rotated
+	BearerTokenFile string `json:"bearerTokenFile,omitempty"`
+	// InsecureSkipVerify skips the verification of the TLS certificate of the prometheus server
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+	// QueryTimeout is the timeout of the evaluation of the query by the prometheus server (e.g. 10s), after which
+	// prometheus cancels the query. Unlike the timeout of the client, it stops expensive queries on the server side
+	QueryTimeout DurationString `json:"queryTimeout,omitempty"`
+	// FailureMessage is an expression evaluated like the conditions, whose output is the message of a Failed
+	// measurement (e.g. "latency exceeded on " + labels.path). The labels variable holds the labels of the sample when
+	// the query returns a vector with a single sample
+	// +optional
+	FailureMessage string `json:"failureMessage,omitempty"`
 }
 
 // WavefrontMetric defines the wavefront query to perform canary analysis
@@ -168,12 +397,219 @@ type WavefrontMetric struct {
 	Address string `json:"address,omitempty"`
 	// Query is a raw wavefront query to perform
 	Query string `json:"query,omitempty"`
+	// OffsetSeconds evaluates the last datapoint at or before the given number of seconds ago, rather than the
+	// datapoint closest to the measurement, to avoid evaluating an incomplete bucket
+	// +optional
+	OffsetSeconds int64 `json:"offsetSeconds,omitempty"`
+}
+
+// ElasticsearchMetric defines the Elasticsearch or OpenSearch search to perform canary analysis
+type ElasticsearchMetric struct {
+	// Address is the HTTP address and port of the Elasticsearch server
+	Address string `json:"address"`
+	// Index is the index, alias or index pattern to search
+	Index string `json:"index"`
+	// Body is the query DSL request body of the search
+	Body string `json:"body"`
+	// JSONPath selects the numeric value of the search response used as the result (e.g. {$.hits.total.value})
+	JSONPath string `json:"jsonPath"`
+	// SecretName is the name of a secret in the controller namespace holding either the username and password
+	// keys for basic authentication, or the apiKey key for API key authentication
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+	// TimeoutSeconds is the timeout of the search request. Defaults to 10 seconds
+	// +optional
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+	// MaxResponseBytes is the maximum size of a response body, above which the measurement errors. Defaults to the
+	// --max-provider-response-bytes of the controller
+	// +optional
+	MaxResponseBytes int64 `json:"maxResponseBytes,omitempty"`
+}
+
+// KubernetesEventMetric defines the Kubernetes Events to count. The result is the number of occurrences of the
+// matching events which were last seen within the window
+type KubernetesEventMetric struct {
+	// Namespace is the namespace of the events. Defaults to the namespace of the AnalysisRun
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// Reasons are the reasons of the events to count (e.g. Unhealthy, BackOff). Events of any reason are counted
+	// if omitted
+	// +optional
+	Reasons []string `json:"reasons,omitempty"`
+	// Type is the type of the events to count (Normal or Warning). Events of any type are counted if omitted
+	// +optional
+	Type string `json:"type,omitempty"`
+	// InvolvedObject selects the objects the events are about. Events of any object are counted if omitted
+	// +optional
+	InvolvedObject *EventInvolvedObjectSelector `json:"involvedObject,omitempty"`
+	// Window is the duration (e.g. 5m) before the measurement in which the events are counted. Defaults to the
+	// time since the AnalysisRun started
+	// +optional
+	Window DurationString `json:"window,omitempty"`
+}
+
+// EventInvolvedObjectSelector selects the objects Kubernetes Events are about
+type EventInvolvedObjectSelector struct {
+	// Kind is the kind of the objects (e.g. Pod)
+	// +optional
+	Kind string `json:"kind,omitempty"`
+	// Name is the name of the object
+	// +optional
+	Name string `json:"name,omitempty"`
+	// NamePrefix is the prefix of the names of the objects (e.g. the name of a ReplicaSet to select its pods)
+	// +optional
+	NamePrefix string `json:"namePrefix,omitempty"`
+}
+
+// PodExecResultSource is the output of the command which is evaluated as the result of a PodExec metric
+type PodExecResultSource string
+
+const (
+	// PodExecResultStdout evaluates the standard output of the command, the command must exit successfully
+	PodExecResultStdout PodExecResultSource = "Stdout"
+	// PodExecResultExitCode evaluates the exit code of the command
+	PodExecResultExitCode PodExecResultSource = "ExitCode"
+)
+
+// PodExecPodSelection indicates in which of the selected pods the command is executed
+type PodExecPodSelection string
+
+const (
+	// PodExecAnyPod executes the command in one of the selected pods
+	PodExecAnyPod PodExecPodSelection = "Any"
+	// PodExecAllPods executes the command in every selected pod, the result is the list of the results of each pod
+	PodExecAllPods PodExecPodSelection = "All"
+)
+
+// PodExecMetric defines a command executed in existing canary pods which acts as a metric
+type PodExecMetric struct {
+	// Command is the command to execute in the container
+	Command []string `json:"command"`
+	// Container is the name of the container in which the command is executed. Defaults to the first container
+	// of the pod
+	// +optional
+	Container string `json:"container,omitempty"`
+	// PodTemplateHash is the pod-template-hash of the pods in which the command is executed. Defaults to the
+	// pod-template-hash of the revision which created the AnalysisRun, which is the canary during a rollout
+	// +optional
+	PodTemplateHash string `json:"podTemplateHash,omitempty"`
+	// Selector further restricts the pods in which the command is executed
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+	// Pods indicates whether the command is executed in any (default) or all of the running and ready pods
+	// +optional
+	Pods PodExecPodSelection `json:"pods,omitempty"`
+	// Result is the output evaluated as the result: the standard output (default) or the exit code
+	// +optional
+	Result PodExecResultSource `json:"result,omitempty"`
+}
+
+// AlertmanagerMatchType is the operator used to match the value of an alert label
+type AlertmanagerMatchType string
+
+const (
+	// AlertmanagerMatchEqual matches the alerts with a label equal to the value
+	AlertmanagerMatchEqual AlertmanagerMatchType = "Equal"
+	// AlertmanagerMatchNotEqual matches the alerts with a label different from the value
+	AlertmanagerMatchNotEqual AlertmanagerMatchType = "NotEqual"
+	// AlertmanagerMatchRegex matches the alerts with a label matching the regular expression of the value
+	AlertmanagerMatchRegex AlertmanagerMatchType = "Regex"
+	// AlertmanagerMatchNotRegex matches the alerts with a label not matching the regular expression of the value
+	AlertmanagerMatchNotRegex AlertmanagerMatchType = "NotRegex"
+)
+
+// AlertmanagerMatcher matches the value of a label of the alerts
+type AlertmanagerMatcher struct {
+	// Name is the name of the label (e.g. alertname)
+	Name string `json:"name"`
+	// Value is the value or regular expression the label is matched against
+	Value string `json:"value"`
+	// Type is the operator used to match the label: Equal (default), NotEqual, Regex or NotRegex
+	// +optional
+	Type AlertmanagerMatchType `json:"type,omitempty"`
+}
+
+// AlertmanagerMetric defines the Prometheus Alertmanager alerts to count. The result is the number of active alerts
+// matching all the matchers
+type AlertmanagerMetric struct {
+	// Address is the HTTP address and port of the Alertmanager server
+	Address string `json:"address"`
+	// Matchers are the label matchers the alerts must satisfy
+	Matchers []AlertmanagerMatcher `json:"matchers"`
+	// Silenced counts the alerts which are silenced
+	// +optional
+	Silenced bool `json:"silenced,omitempty"`
+	// Inhibited counts the alerts which are inhibited by another alert
+	// +optional
+	Inhibited bool `json:"inhibited,omitempty"`
+	// TimeoutSeconds is the timeout of the request. Defaults to 10 seconds
+	// +optional
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+	// MaxResponseBytes is the maximum size of a response body, above which the measurement errors. Defaults to the
+	// --max-provider-response-bytes of the controller
+	// +optional
+	MaxResponseBytes int64 `json:"maxResponseBytes,omitempty"`
+}
+
+// LokiReduce is the function reducing the samples of the series returned by a LogQL query to the result
+type LokiReduce string
+
+const (
+	// LokiReduceLast reduces the samples to the sum of the last sample of every series
+	LokiReduceLast LokiReduce = "Last"
+	// LokiReduceSum reduces the samples to their sum
+	LokiReduceSum LokiReduce = "Sum"
+	// LokiReduceAvg reduces the samples to their average
+	LokiReduceAvg LokiReduce = "Avg"
+	// LokiReduceMin reduces the samples to their minimum
+	LokiReduceMin LokiReduce = "Min"
+	// LokiReduceMax reduces the samples to their maximum
+	LokiReduceMax LokiReduce = "Max"
+)
+
+// LokiMetric defines the LogQL metric query to perform canary analysis. The query is evaluated over the window
+// preceding the measurement with the query_range API, and the samples of the returned series are reduced to the result
+type LokiMetric struct {
+	// Address is the HTTP address and port of the Loki server
+	Address string `json:"address"`
+	// Query is the LogQL metric query to perform (e.g. sum(count_over_time({app="guestbook"} |= "error" [1m])))
+	Query string `json:"query"`
+	// Window is the duration preceding the measurement over which the query is evaluated (e.g. 5m). Defaults to 5m
+	// +optional
+	Window DurationString `json:"window,omitempty"`
+	// Step is the resolution of the query (e.g. 30s). Defaults to the resolution chosen by Loki for the window
+	// +optional
+	Step DurationString `json:"step,omitempty"`
+	// Reduce is the function reducing the samples to the result, either Last (default), Sum, Avg, Min or Max
+	// +optional
+	Reduce LokiReduce `json:"reduce,omitempty"`
+	// OrgID is the tenant of a multi-tenant Loki, sent in the X-Scope-OrgID header
+	// +optional
+	OrgID string `json:"orgId,omitempty"`
+	// OffsetSeconds ends the window the given number of seconds before the measurement, so that the last samples are
+	// the ones at or before that time, to avoid evaluating an incomplete bucket
+	// +optional
+	OffsetSeconds int64 `json:"offsetSeconds,omitempty"`
+	// TimeoutSeconds is the timeout of the request. Defaults to 10 seconds
+	// +optional
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+	// MaxResponseBytes is the maximum size of a response body, above which the measurement errors. Defaults to the
+	// --max-provider-response-bytes of the controller
+	// +optional
+	MaxResponseBytes int64 `json:"maxResponseBytes,omitempty"`
 }
 
 // JobMetric defines a job to run which acts as a metric
 type JobMetric struct {
 	Metadata metav1.ObjectMeta `json:"metadata,omitempty"`
 	Spec     batchv1.JobSpec   `json:"spec"`
+	// BackoffLimit specifies the number of retries before marking the job failed. Overrides spec.backoffLimit
+	// +optional
+	BackoffLimit *int32 `json:"backoffLimit,omitempty"`
+	// ActiveDeadlineSeconds specifies the duration in seconds the job may be active before it is terminated
+	// and the measurement is marked failed. Overrides spec.activeDeadlineSeconds
+	// +optional
+	ActiveDeadlineSeconds *int64 `json:"activeDeadlineSeconds,omitempty"`
 }
 
 // AnalysisRun is an instantiation of an AnalysisTemplate
@@ -209,6 +645,48 @@ type AnalysisRunSpec struct {
 	Args []Argument `json:"args,omitempty" patchStrategy:"merge" patchMergeKey:"name"`
 	// Terminate is used to prematurely stop the run (e.g. rollout completed and analysis is no longer desired)
 	Terminate bool `json:"terminate,omitempty"`
+	// Suspend is used to temporarily stop the run from taking measurements without completing it. The measurements
+	// which are due are taken once the run is resumed.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+	// Hooks are the webhooks notified of the outcome of the run once it completes
+	// +optional
+	Hooks *AnalysisHooks `json:"hooks,omitempty"`
+	// MetricErrorPolicy is how the metrics which complete Error, such as the metrics of an unavailable provider, are
+	// aggregated into the phase of the run, either Error or Inconclusive. Defaults to Error
+	// +optional
+	MetricErrorPolicy MetricErrorPolicy `json:"metricErrorPolicy,omitempty"`
+}
+
+// MetricErrorPolicy is how the metrics of an AnalysisRun which complete Error are aggregated into the phase of the run
+type MetricErrorPolicy string
+
+const (
+	// MetricErrorPolicyError completes the run Error as soon as one of its metrics completes Error
+	MetricErrorPolicyError MetricErrorPolicy = "Error"
+	// MetricErrorPolicyInconclusive keeps measuring the other metrics when a metric completes Error, and completes the
+	// run Inconclusive unless another metric fails. The run completes Error when all of its metrics complete Error.
+	MetricErrorPolicyInconclusive MetricErrorPolicy = "Inconclusive"
+)
+
+// AnalysisHooks are the webhooks notified of the outcome of an AnalysisRun. Runs which are terminated do not notify
+// the webhooks.
+type AnalysisHooks struct {
+	// OnSuccess is notified when the run completes Successful
+	// +optional
+	OnSuccess *AnalysisWebhook `json:"onSuccess,omitempty"`
+	// OnFailure is notified when the run completes Failed or Error
+	// +optional
+	OnFailure *AnalysisWebhook `json:"onFailure,omitempty"`
+}
+
+// AnalysisWebhook is an endpoint receiving the outcome of an AnalysisRun as a JSON POST request
+type AnalysisWebhook struct {
+	// URL is the http or https URL of the endpoint
+	URL string `json:"url"`
+	// Headers are added to the requests to the endpoint (e.g. an authorization header)
+	// +optional
+	Headers []WebMetricHeader `json:"headers,omitempty"`
 }
 
 // Argument is an argument to an AnalysisRun
@@ -246,6 +724,36 @@ type AnalysisRunStatus struct {
 	MetricResults []MetricResult `json:"metricResults,omitempty"`
 	// StartedAt indicates when the analysisRun first started
 	StartedAt *metav1.Time `json:"startedAt,omitempty"`
+	// Summary holds the aggregate statistics of the measurements of each metric, computed when the run completes
+	// +optional
+	Summary []MetricSummary `json:"summary,omitempty"`
+}
+
+// MetricSummary holds the aggregate statistics of the measurements of a metric of a completed AnalysisRun
+type MetricSummary struct {
+	// Name is the name of the metric
+	Name string `json:"name"`
+	// Phase is the final status of the metric
+	Phase AnalysisPhase `json:"phase"`
+	// Measurements is the total number of measurements of the metric, including the errors
+	Measurements int32 `json:"measurements"`
+	// Successful is the number of Successful measurements
+	Successful int32 `json:"successful"`
+	// Failed is the number of Failed measurements
+	Failed int32 `json:"failed"`
+	// Inconclusive is the number of Inconclusive measurements
+	Inconclusive int32 `json:"inconclusive"`
+	// Error is the number of measurements which errored
+	Error int32 `json:"error"`
+	// Min is the minimum of the numeric values of the retained measurements. Omitted when no value is numeric
+	// +optional
+	Min string `json:"min,omitempty"`
+	// Max is the maximum of the numeric values of the retained measurements. Omitted when no value is numeric
+	// +optional
+	Max string `json:"max,omitempty"`
+	// Avg is the average of the numeric values of the retained measurements. Omitted when no value is numeric
+	// +optional
+	Avg string `json:"avg,omitempty"`
 }
 
 // MetricResult contain a list of the most recent measurements for a single metric along with
@@ -273,6 +781,9 @@ type MetricResult struct {
 	// ConsecutiveError is the number of times an error was encountered during measurement in succession
 	// Resets to zero when non-errors are encountered
 	ConsecutiveError int32 `json:"consecutiveError,omitempty"`
+	// Baseline is the baseline value captured at the start of the run, to which the measurements are compared when
+	// the baselineCapture of the metric is RunStart
+	Baseline string `json:"baseline,omitempty"`
 }
 
 // Measurement is a point in time result value of a single metric, and the time it was measured
@@ -336,6 +847,367 @@ type WebMetric struct {
 	Headers        []WebMetricHeader `json:"headers,omitempty" patchStrategy:"merge" patchMergeKey:"key"`
 	TimeoutSeconds int               `json:"timeoutSeconds,omitempty"`
 	JSONPath       string            `json:"jsonPath"`
+	// ValuesFrom maps the names of variables to the JSONPaths of the values they select from the response (e.g.
+	// latency: "{$.latency.p99}"), so that a single request populates several variables of the conditions. When
+	// JSONPath is omitted, the result is the map of the names to their values. Not supported with NextPageJSONPath
+	// +optional
+	ValuesFrom map[string]string `json:"valuesFrom,omitempty"`
+	// NextPageJSONPath selects the URL of the next page of a paginated response. When set, the next pages are
+	// requested until no URL is selected, and the numeric values selected by JSONPath on every page are summed
+	NextPageJSONPath string `json:"nextPageJsonPath,omitempty"`
+	// PageLimit is the maximum number of pages requested when following the next pages (default: 10, max: 100)
+	PageLimit int `json:"pageLimit,omitempty"`
+	// SuccessStatusRanges are the HTTP status codes of the responses which are parsed and evaluated by the
+	// conditions. The responses with other status codes are errors. Defaults to the 2xx status codes.
+	// +optional
+	SuccessStatusRanges []HTTPStatusRange `json:"successStatusRanges,omitempty"`
+	// MaxResponseBytes is the maximum size of a response body, above which the measurement errors. Defaults to the
+	// --max-provider-response-bytes of the controller
+	// +optional
+	MaxResponseBytes int64 `json:"maxResponseBytes,omitempty"`
+}
+
+// HTTPStatusRange is an inclusive range of HTTP status codes
+type HTTPStatusRange struct {
+	// Min is the lowest status code of the range
+	Min int `json:"min"`
+	// Max is the highest status code of the range. Defaults to Min, which makes the range a single status code.
+	// +optional
+	Max int `json:"max,omitempty"`
+}
+
+// Contains returns whether the status code is within the range
+func (r HTTPStatusRange) Contains(statusCode int) bool {
+	max := r.Max
+	if max == 0 {
+		max = r.Min
+	}
+	return statusCode >= r.Min && statusCode <= max
+}
+
+// DecisionMetric defines an external decision API returning a boolean pass or fail along with its reason
+type DecisionMetric struct {
+	// URL is the address of the decision API
+	URL string `json:"url"`
+	// Method is the HTTP method of the request, either GET (default) or POST
+	// +optional
+	Method string `json:"method,omitempty"`
+	// Body is the body of a POST request
+	// +optional
+	Body string `json:"body,omitempty"`
+	// Headers are the headers of the request
+	// +patchMergeKey=key
+	// +patchStrategy=merge
+	// +optional
+	Headers []WebMetricHeader `json:"headers,omitempty" patchStrategy:"merge" patchMergeKey:"key"`
+	// TimeoutSeconds is the timeout of the request. Defaults to 10 seconds
+	// +optional
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+	// MaxResponseBytes is the maximum size of a response body, above which the measurement errors. Defaults to the
+	// --max-provider-response-bytes of the controller
+	// +optional
+	MaxResponseBytes int64 `json:"maxResponseBytes,omitempty"`
+	// PassJSONPath selects the boolean decision of the response (default: {$.pass})
+	// +optional
+	PassJSONPath string `json:"passJsonPath,omitempty"`
+	// ReasonJSONPath selects the reason of the decision, surfaced as the message of the measurement
+	// (default: {$.reason})
+	// +optional
+	ReasonJSONPath string `json:"reasonJsonPath,omitempty"`
+}
+
+// PingdomMetric defines the Pingdom synthetic check to evaluate. The result is the first result of the check reported
+// after the measurement started, exposing whether the check is up and its response time. The measurement waits for
+// that result, polling the results of the check until it is reported
+type PingdomMetric struct {
+	// Address is the HTTP address of the Pingdom API. Defaults to https://api.pingdom.com/api/3.1
+	// +optional
+	Address string `json:"address,omitempty"`
+	// CheckID is the identifier of the check
+	CheckID string `json:"checkId"`
+	// Headers are the headers of the requests, such as the Authorization header holding the API token
+	// +patchMergeKey=key
+	// +patchStrategy=merge
+	// +optional
+	Headers []WebMetricHeader `json:"headers,omitempty" patchStrategy:"merge" patchMergeKey:"key"`
+	// PollIntervalSeconds is the delay between two polls of the results of the check. Defaults to 30 seconds
+	// +optional
+	PollIntervalSeconds int `json:"pollIntervalSeconds,omitempty"`
+	// ResultTimeoutSeconds is how long to wait for a result of the check before the measurement errors. Defaults to
+	// 10 minutes
+	// +optional
+	ResultTimeoutSeconds int `json:"resultTimeoutSeconds,omitempty"`
+	// TimeoutSeconds is the timeout of each request. Defaults to 10 seconds
+	// +optional
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+	// MaxResponseBytes is the maximum size of a response body, above which the measurement errors. Defaults to the
+	// --max-provider-response-bytes of the controller
+	// +optional
+	MaxResponseBytes int64 `json:"maxResponseBytes,omitempty"`
+}
+
+// PodMetricsMetric defines the pods whose CPU and memory usage is read from the metrics.k8s.io API served by the
+// metrics-server, which gates an analysis on the resource usage of the canary without a metrics backend. The result
+// is the usage of the canary pods, also exposed as the canary variable along with the usage of the stable pods as the
+// stable variable (e.g. canary.memory < 2 * stable.memory). The usage holds the average cpu in cores and memory in
+// bytes of the pods, their maxCpu and maxMemory, and the number of pods
+type PodMetricsMetric struct {
+	// CanaryPodTemplateHash is the pod-template-hash of the canary pods. Defaults to the pod-template-hash of the
+	// revision which created the AnalysisRun
+	// +optional
+	CanaryPodTemplateHash string `json:"canaryPodTemplateHash,omitempty"`
+	// StablePodTemplateHash is the pod-template-hash of the stable pods, usually supplied by an argument valued from
+	// the Stable podTemplateHashValue. The stable variable is not set when empty
+	// +optional
+	StablePodTemplateHash string `json:"stablePodTemplateHash,omitempty"`
+}
+
+// IncidentMetric defines the incident-management API listing the incidents, and the service whose active incidents
+// to count. The result is the number of active incidents affecting the service, which gates a promotion on the
+// absence of an ongoing outage
+type IncidentMetric struct {
+	// Address is the URL of the API listing the incidents
+	Address string `json:"address"`
+	// Headers are the headers of the request, such as the Authorization header holding the token of the API
+	// +patchMergeKey=key
+	// +patchStrategy=merge
+	// +optional
+	Headers []WebMetricHeader `json:"headers,omitempty" patchStrategy:"merge" patchMergeKey:"key"`
+	// ItemsField is the dotted path of the list of incidents in the response (e.g. incidents). Defaults to the
+	// response itself, which must then be a list
+	// +optional
+	ItemsField string `json:"itemsField,omitempty"`
+	// Service is the service affected by the incidents to count
+	Service string `json:"service"`
+	// ServiceField is the dotted path of the service of an incident (e.g. service.summary), which may also hold a list
+	// of services. Defaults to service
+	// +optional
+	ServiceField string `json:"serviceField,omitempty"`
+	// StatusField is the dotted path of the status of an incident. Defaults to status
+	// +optional
+	StatusField string `json:"statusField,omitempty"`
+	// ActiveStatuses are the statuses of the active incidents, compared without case. Defaults to triggered and
+	// acknowledged
+	// +optional
+	ActiveStatuses []string `json:"activeStatuses,omitempty"`
+	// IDField is the dotted path of the identifier of an incident, listed in the measurement metadata. Defaults to id
+	// +optional
+	IDField string `json:"idField,omitempty"`
+	// TimeoutSeconds is the timeout of the request. Defaults to 10 seconds
+	// +optional
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+	// MaxResponseBytes is the maximum size of a response body, above which the measurement errors. Defaults to the
+	// --max-provider-response-bytes of the controller
+	// +optional
+	MaxResponseBytes int64 `json:"maxResponseBytes,omitempty"`
+}
+
+// CommitStatusVCS is the version control system hosting the repository of a commit status metric
+type CommitStatusVCS string
+
+// Possible CommitStatusVCS values
+const (
+	// CommitStatusVCSGitHub aggregates the commit statuses and the check runs of a GitHub commit
+	CommitStatusVCSGitHub CommitStatusVCS = "GitHub"
+	// CommitStatusVCSGitLab aggregates the commit statuses of a GitLab commit, which include its pipeline jobs
+	CommitStatusVCSGitLab CommitStatusVCS = "GitLab"
+)
+
+// CommitStatusMetric defines the commit of a GitHub or GitLab repository whose checks gate the analysis, which ties
+// the checks of the code, such as the CI pipeline or the security scans, to the promotion of the traffic. The result
+// is the aggregate state of the checks, which is success, pending or failure. The measurement waits for the pending
+// checks, polling the checks of the commit until none is pending
+type CommitStatusMetric struct {
+	// VCS is the version control system hosting the repository, either GitHub or GitLab
+	VCS CommitStatusVCS `json:"vcs"`
+	// Address is the HTTP address of the API. Defaults to https://api.github.com for GitHub and
+	// https://gitlab.com/api/v4 for GitLab
+	// +optional
+	Address string `json:"address,omitempty"`
+	// Repository is the owner/name of a GitHub repository, or the ID or the path of a GitLab project
+	Repository string `json:"repository"`
+	// SHA is the commit whose checks to aggregate, usually supplied by an argument valued from an annotation of the
+	// rollout
+	SHA string `json:"sha"`
+	// RequiredChecks are the names of the checks which must succeed, the other checks being ignored. A required check
+	// which is not reported yet is pending. Defaults to all the checks of the commit
+	// +optional
+	RequiredChecks []string `json:"requiredChecks,omitempty"`
+	// TokenSecretRef references the key of a secret in the namespace of the AnalysisRun holding the token of the API
+	// +optional
+	TokenSecretRef *SecretKeyRef `json:"tokenSecretRef,omitempty"`
+	// PollIntervalSeconds is the delay between two polls of the pending checks. Defaults to 30 seconds
+	// +optional
+	PollIntervalSeconds int `json:"pollIntervalSeconds,omitempty"`
+	// ResultTimeoutSeconds is how long to wait for the pending checks before the measurement errors. Defaults to 30
+	// minutes
+	// +optional
+	ResultTimeoutSeconds int `json:"resultTimeoutSeconds,omitempty"`
+	// TimeoutSeconds is the timeout of each request. Defaults to 10 seconds
+	// +optional
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+	// MaxResponseBytes is the maximum size of a response body, above which the measurement errors. Defaults to the
+	// --max-provider-response-bytes of the controller
+	// +optional
+	MaxResponseBytes int64 `json:"maxResponseBytes,omitempty"`
+}
+
+// XRayMetric defines the service of the AWS X-Ray service graph, or the traces, whose requests are evaluated, which
+// gates the analysis on traces without a separate metrics pipeline. The result is a map of the counts and the rates
+// of the errors, faults and throttles of the requests over the interval (e.g. result.faultRate)
+type XRayMetric struct {
+	// Region is the AWS region of X-Ray. Defaults to the region of the controller
+	// +optional
+	Region string `json:"region,omitempty"`
+	// RoleARN is the ARN of the IAM role assumed to query X-Ray. Defaults to the credentials of the controller
+	// +optional
+	RoleARN string `json:"roleArn,omitempty"`
+	// ServiceName is the name of the service of the service graph whose statistics are evaluated
+	// +optional
+	ServiceName string `json:"serviceName,omitempty"`
+	// ServiceType is the type of the service (e.g. AWS::EKS::Container), which distinguishes the services with the
+	// same name
+	// +optional
+	ServiceType string `json:"serviceType,omitempty"`
+	// GroupName is the X-Ray group whose service graph is queried. Defaults to the Default group
+	// +optional
+	GroupName string `json:"groupName,omitempty"`
+	// FilterExpression selects the traces of the canary (e.g. annotation.version = "canary"). When set, the summaries
+	// of the traces are counted instead of the statistics of the service graph
+	// +optional
+	FilterExpression string `json:"filterExpression,omitempty"`
+	// Interval is the duration before the measurement over which the requests are evaluated. Defaults to 5m
+	// +optional
+	Interval DurationString `json:"interval,omitempty"`
+	// TimeoutSeconds is the timeout of the calls to X-Ray. Defaults to 10 seconds
+	// +optional
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+}
+
+// DatadogMetric defines the Datadog SLO to evaluate, which gates the analysis on an existing SLO rather than on a query
+// reconstructing it. The result exposes the SLI value and the remaining error budget of the SLO over its timeframe.
+// Alternatively, the metric counts the logs matching a query, in which case the result is the count
+type DatadogMetric struct {
+	// Address is the HTTP address of the Datadog API. Defaults to https://api.datadoghq.com
+	// +optional
+	Address string `json:"address,omitempty"`
+	// SLOID is the identifier of the SLO. Exactly one of sloId or logs must be specified
+	// +optional
+	SLOID string `json:"sloId,omitempty"`
+	// Logs is the query of the logs to count with the logs aggregate API, instead of evaluating an SLO
+	// +optional
+	Logs *DatadogLogsQuery `json:"logs,omitempty"`
+	// Timeframe is the timeframe of the SLO to evaluate, which is one of 7d, 30d or 90d. Defaults to 30d
+	// +optional
+	Timeframe string `json:"timeframe,omitempty"`
+	// Headers are the headers of the requests, such as the DD-API-KEY and DD-APPLICATION-KEY headers holding the keys
+	// +patchMergeKey=key
+	// +patchStrategy=merge
+	// +optional
+	Headers []WebMetricHeader `json:"headers,omitempty" patchStrategy:"merge" patchMergeKey:"key"`
+	// TimeoutSeconds is the timeout of each request. Defaults to 10 seconds
+	// +optional
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+	// MaxResponseBytes is the maximum size of a response body, above which the measurement errors. Defaults to the
+	// --max-provider-response-bytes of the controller
+	// +optional
+	MaxResponseBytes int64 `json:"maxResponseBytes,omitempty"`
+}
+
+// DatadogLogsQuery defines the logs counted by a Datadog metric, which gates the analysis on log-derived signals (e.g.
+// the error logs of the canary) without defining a log-based metric in Datadog
+type DatadogLogsQuery struct {
+	// Query is the search query of the logs to count (e.g. service:checkout status:error version:canary)
+	Query string `json:"query"`
+	// Timeframe is the duration before the measurement over which the logs are counted. Defaults to 5m
+	// +optional
+	Timeframe DurationString `json:"timeframe,omitempty"`
+}
+
+// ReplicaSetMetricFieldSource is the object a field of a ReplicaSet metric is read from
+type ReplicaSetMetricFieldSource string
+
+const (
+	// ReplicaSetMetricFieldSourceReplicaSet reads the field from the ReplicaSet
+	ReplicaSetMetricFieldSourceReplicaSet ReplicaSetMetricFieldSource = "ReplicaSet"
+	// ReplicaSetMetricFieldSourcePods reads the field from every pod of the ReplicaSet, the value is the list of the
+	// values of each pod
+	ReplicaSetMetricFieldSourcePods ReplicaSetMetricFieldSource = "Pods"
+)
+
+// ReplicaSetMetric defines the fields of a ReplicaSet and of its pods evaluated by the conditions, which gates an
+// analysis on deployment metadata (e.g. an image digest) without a metrics backend. The result is a map of the names
+// of the fields to their values (e.g. result.digest)
+type ReplicaSetMetric struct {
+	// PodTemplateHash is the pod-template-hash of the ReplicaSet. Defaults to the pod-template-hash of the revision
+	// which created the AnalysisRun, which is the canary during a rollout
+	// +optional
+	PodTemplateHash string `json:"podTemplateHash,omitempty"`
+	// Fields are the fields exposed to the conditions
+	Fields []ReplicaSetMetricField `json:"fields"`
+}
+
+// ReplicaSetMetricField selects a field of a ReplicaSet or of its pods
+type ReplicaSetMetricField struct {
+	// Name is the key of the value of the field in the result
+	Name string `json:"name"`
+	// JSONPath is the path of the field (e.g. {.metadata.annotations.example\.com/digest}, the dots of a key being
+	// escaped). A missing field has a nil value
+	JSONPath string `json:"jsonPath"`
+	// Source is the object the field is read from: the ReplicaSet (default) or its pods
+	// +optional
+	Source ReplicaSetMetricFieldSource `json:"source,omitempty"`
+}
+
+// GRPCMetric defines the gRPC method to invoke. The result is the numeric field of the response of the method, which
+// lets gRPC services expose metrics without an HTTP endpoint
+type GRPCMetric struct {
+	// Address is the host and port of the gRPC server
+	Address string `json:"address"`
+	// Method is the full name of the method to invoke, such as /metrics.v1.Metrics/GetErrorRate
+	Method string `json:"method"`
+	// Request is the request message of the method, encoded in the protobuf binary format and then in base64. An
+	// empty message is sent when it is not set
+	// +optional
+	Request string `json:"request,omitempty"`
+	// Field is the path of the numeric field of the response holding the result, made of the field numbers of the
+	// nested messages separated by dots (e.g. 2.1 for the first field of the message in the second field)
+	Field string `json:"field"`
+	// FieldType is the protobuf type of the field. Defaults to double
+	// +optional
+	FieldType GRPCFieldType `json:"fieldType,omitempty"`
+	// TLS connects to the server over TLS rather than in plaintext
+	// +optional
+	TLS *GRPCMetricTLS `json:"tls,omitempty"`
+	// TimeoutSeconds is the timeout of the call. Defaults to 10 seconds
+	// +optional
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+}
+
+// GRPCFieldType is the protobuf type of the field of a gRPC response holding the result
+type GRPCFieldType string
+
+const (
+	GRPCFieldTypeDouble GRPCFieldType = "double"
+	GRPCFieldTypeFloat  GRPCFieldType = "float"
+	GRPCFieldTypeInt64  GRPCFieldType = "int64"
+	GRPCFieldTypeUint64 GRPCFieldType = "uint64"
+	GRPCFieldTypeInt32  GRPCFieldType = "int32"
+	GRPCFieldTypeUint32 GRPCFieldType = "uint32"
+	GRPCFieldTypeSint64 GRPCFieldType = "sint64"
+	GRPCFieldTypeSint32 GRPCFieldType = "sint32"
+	GRPCFieldTypeBool   GRPCFieldType = "bool"
+)
+
+// GRPCMetricTLS configures the TLS connection to a gRPC server
+type GRPCMetricTLS struct {
+	// ServerName is the name of the server verified against its certificate, when it differs from the host of the
+	// address
+	// +optional
+	ServerName string `json:"serverName,omitempty"`
+	// InsecureSkipVerify skips the verification of the certificate of the server
+	// +optional
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
 }
 
 type WebMetricHeader struct {
@@ -47,6 +47,13 @@ type ExperimentSpec struct {
 	// +patchMergeKey=name
 	// +patchStrategy=merge
 	Analyses []ExperimentAnalysisTemplateRef `json:"analyses,omitempty" patchStrategy:"merge" patchMergeKey:"name"`
+	// Args are the arguments that will be added to the AnalysisRuns of all the analyses. The args of an analysis take
+	// precedence over the args of the experiment, and the args are only added to the AnalysisRuns whose templates
+	// declare them.
+	// +optional
+	// +patchMergeKey=name
+	// +patchStrategy=merge
+	Args []Argument `json:"args,omitempty" patchStrategy:"merge" patchMergeKey:"name"`
 }
 
 type TemplateSpec struct {
@@ -61,6 +61,11 @@ type RolloutSpec struct {
 	ProgressDeadlineSeconds *int32 `json:"progressDeadlineSeconds,omitempty"`
 	// RestartAt indicates when all the pods of a Rollout should be restarted
 	RestartAt *metav1.Time `json:"restartAt,omitempty"`
+	// MaxConcurrentAnalysisRuns limits the number of AnalysisRuns of the rollout running at the same time, not counting
+	// the background AnalysisRun. The creation of further AnalysisRuns is queued until a running AnalysisRun completes.
+	// Defaults to no limit.
+	// +optional
+	MaxConcurrentAnalysisRuns *int32 `json:"maxConcurrentAnalysisRuns,omitempty"`
 }
 
 const (
@@ -79,6 +84,9 @@ const (
 	// LabelKeyControllerInstanceID is the label the controller uses for the rollout, experiment, analysis segregation
 	// between controllers. Controllers will only operate on objects with the same instanceID as the controller.
 	LabelKeyControllerInstanceID = "argo-rollouts.argoproj.io/controller-instance-id"
+	// RolloutQuarantineLabelKey is the label set to true on the canary ReplicaSet and its pods while they are kept in
+	// quarantine after the rollout was aborted
+	RolloutQuarantineLabelKey = "argo-rollouts.argoproj.io/quarantined"
 )
 
 // RolloutStrategy defines strategy to apply during next rollout
@@ -122,11 +130,55 @@ type BlueGreenStrategy struct {
 	ScaleDownDelayRevisionLimit *int32 `json:"scaleDownDelayRevisionLimit,omitempty"`
 	// PrePromotionAnalysis configuration to run analysis before a selector switch
 	PrePromotionAnalysis *RolloutAnalysis `json:"prePromotionAnalysis,omitempty"`
+	// RequireManualApproval keeps the rollout paused after the PrePromotionAnalysis succeeds until the
+	// rollout is manually promoted. A failed PrePromotionAnalysis still aborts the rollout.
+	// +optional
+	RequireManualApproval bool `json:"requireManualApproval,omitempty"`
 	// AntiAffinity enables anti-affinity rules for Blue Green deployment
 	// +optional
 	AntiAffinity *AntiAffinity `json:"antiAffinity,omitempty"`
 	// PostPromotionAnalysis configuration to run analysis after a selector switch
 	PostPromotionAnalysis *RolloutAnalysis `json:"postPromotionAnalysis,omitempty"`
+	// TrafficRouting shifts the weight of the PreviewTrafficRamp from the active service to the preview service
+	// +optional
+	TrafficRouting *RolloutTrafficRouting `json:"trafficRouting,omitempty"`
+	// PreviewTrafficRamp progressively shifts traffic to the preview service through the TrafficRouting before the
+	// active service is promoted
+	// +optional
+	PreviewTrafficRamp []PreviewTrafficRampStep `json:"previewTrafficRamp,omitempty"`
+	// PreviewTrafficRampAnalysis configuration to run analysis during the PreviewTrafficRamp. A failed analysis
+	// aborts the rollout and shifts the traffic back to the active service.
+	// +optional
+	PreviewTrafficRampAnalysis *RolloutAnalysis `json:"previewTrafficRampAnalysis,omitempty"`
+	// ScaleDownDrain keeps the previous active ReplicaSet scaled up after a promotion until an analysis finds its
+	// connections drained, rather than for the fixed ScaleDownDelaySeconds
+	// +optional
+	ScaleDownDrain *ScaleDownDrain `json:"scaleDownDrain,omitempty"`
+}
+
+// ScaleDownDrain defines the analysis measuring the connections, or in-flight requests, of the previous active
+// ReplicaSet. The ReplicaSet is scaled down as soon as the latest measurement of each metric of the analysis is
+// successful, and at the latest after MaxDelaySeconds. The pod template hash of the ReplicaSet is supplied to the
+// analysis as the `draining-pod-template-hash` argument.
+type ScaleDownDrain struct {
+	RolloutAnalysis `json:",inline"`
+	// MaxDelaySeconds is the maximum delay after the promotion before the previous active ReplicaSet is scaled down,
+	// whether or not its connections drained. Defaults to 3600 seconds
+	// +optional
+	MaxDelaySeconds *int32 `json:"maxDelaySeconds,omitempty"`
+}
+
+// PreviewTrafficRampStep defines a weight of the traffic sent to the preview service during the PreviewTrafficRamp
+type PreviewTrafficRampStep struct {
+	// Weight is the percentage of the traffic sent to the preview service
+	Weight int32 `json:"weight"`
+	// Duration the amount of time to send the weight before moving to the next step
+	Duration *intstr.IntOrString `json:"duration"`
+}
+
+// DurationSeconds converts the duration of the step to seconds
+func (s PreviewTrafficRampStep) DurationSeconds() int32 {
+	return RolloutPause{Duration: s.Duration}.DurationSeconds()
 }
 
 // AntiAffinity defines which inter-pod scheduling rule to use for anti-affinity injection
@@ -190,6 +242,136 @@ type CanaryStrategy struct {
 	// AntiAffinity enables anti-affinity rules for Canary deployment
 	// +optional
 	AntiAffinity *AntiAffinity `json:"antiAffinity,omitempty"`
+	// MaxWeightSchedule caps the weight of the setWeight steps during daily time windows
+	// +optional
+	MaxWeightSchedule *MaxWeightSchedule `json:"maxWeightSchedule,omitempty"`
+	// BakeTime is the amount of time to hold the rollout at 100% weight with the background analysis running after
+	// all the steps complete, before the new ReplicaSet is marked as stable. The rollout is aborted if the analysis
+	// fails while baking.
+	// +optional
+	BakeTime *intstr.IntOrString `json:"bakeTime,omitempty"`
+	// AbortPolicy defines how the rollout falls back when an analysis fails. By default, the rollout is aborted.
+	// +optional
+	AbortPolicy *CanaryAbortPolicy `json:"abortPolicy,omitempty"`
+	// WarmupWeight is the weight the canary is held at before the first step until all the canary pods are ready
+	// and have passed the WarmupReadyCheck. The steps start once the warmup completes.
+	// +optional
+	WarmupWeight *int32 `json:"warmupWeight,omitempty"`
+	// WarmupReadyCheck defines the check the canary pods need to pass at the WarmupWeight to complete the warmup
+	// +optional
+	WarmupReadyCheck *WarmupReadyCheck `json:"warmupReadyCheck,omitempty"`
+	// ReadinessGate holds the traffic of the canary at 0 until all the canary pods satisfy a custom pod condition,
+	// set by an external controller (e.g. a service mesh). Requires TrafficRouting.
+	// +optional
+	ReadinessGate *CanaryReadinessGate `json:"readinessGate,omitempty"`
+	// DependsOnRollout is the name of a rollout in the same namespace which needs to be Healthy before the canary
+	// receives traffic and the analyses start. The canary is held at a weight of 0 until then.
+	// +optional
+	DependsOnRollout string `json:"dependsOnRollout,omitempty"`
+	// QuarantineOnFailure keeps the canary pods of an aborted rollout for inspection, at a minimal count, without
+	// traffic and labeled as quarantined, until the quarantine expires. Requires TrafficRouting.
+	// +optional
+	QuarantineOnFailure *CanaryQuarantine `json:"quarantineOnFailure,omitempty"`
+	// RebalanceWeightOnReplicaLoss reduces the weight of the canary proportionally to its available pods when canary
+	// pods are lost after the current step scaled up the canary, so that the remaining pods do not receive more traffic
+	// than intended. The weight of the step is restored once the canary ReplicaSet recovers. Requires TrafficRouting.
+	// +optional
+	RebalanceWeightOnReplicaLoss bool `json:"rebalanceWeightOnReplicaLoss,omitempty"`
+}
+
+// CanaryQuarantine defines how the canary pods of an aborted rollout are kept for inspection
+type CanaryQuarantine struct {
+	// Replicas is the number of canary pods kept in quarantine. Defaults to 1.
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+	// TTLSeconds is the number of seconds after the abort the canary pods are kept in quarantine, before they are
+	// scaled down. Defaults to 3600.
+	// +optional
+	TTLSeconds *int32 `json:"ttlSeconds,omitempty"`
+}
+
+// CanaryReadinessGate defines the pod condition the canary pods need to satisfy before receiving traffic
+type CanaryReadinessGate struct {
+	// ConditionType is the type of the pod condition which needs to be True on all the canary pods
+	ConditionType corev1.PodConditionType `json:"conditionType"`
+}
+
+// WarmupReadyCheck defines the check the canary pods need to pass to complete the warmup of a canary rollout
+type WarmupReadyCheck struct {
+	// ReadySeconds is the number of seconds all the canary pods need to stay ready at the WarmupWeight. Defaults to 0,
+	// which completes the warmup as soon as all the canary pods are ready.
+	// +optional
+	ReadySeconds int32 `json:"readySeconds,omitempty"`
+}
+
+// CanaryAbortPolicy defines how a canary rollout falls back when an analysis fails
+type CanaryAbortPolicy struct {
+	// RollbackToStep is the index of an earlier step the rollout returns to when an analysis of a later step fails.
+	// The rollout is paused at that step instead of being aborted, keeping the traffic of the step on the canary.
+	// The rollout is still aborted if the analysis fails at or before that step.
+	// +optional
+	RollbackToStep *int32 `json:"rollbackToStep,omitempty"`
+	// AutoRetry retries the rollout after it is aborted by a failed analysis, running the analysis again
+	// +optional
+	AutoRetry *AnalysisAutoRetry `json:"autoRetry,omitempty"`
+}
+
+// AnalysisAutoRetry defines how a rollout aborted by a failed analysis is retried, for example after a transient
+// failure of the metric provider
+type AnalysisAutoRetry struct {
+	// CooldownSeconds is the number of seconds the rollout stays aborted before it is retried. Defaults to 0
+	// +optional
+	CooldownSeconds int32 `json:"cooldownSeconds,omitempty"`
+	// Limit is the maximum number of times the rollout of a revision is retried
+	Limit int32 `json:"limit"`
+}
+
+// BakeTimeSeconds converts the bake time to seconds
+// If BakeTime is nil 0 is returned
+// if BakeTime values is string and does not contain a valid unit -1 is returned
+func (c CanaryStrategy) BakeTimeSeconds() int32 {
+	return RolloutPause{Duration: c.BakeTime}.DurationSeconds()
+}
+
+// MaxWeightSchedule defines the daily time windows during which the canary weight is capped
+type MaxWeightSchedule struct {
+	// TimeZone is the IANA time zone name (e.g. America/New_York) of the windows. Defaults to UTC
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+	// Windows are the non-overlapping time windows during which the canary weight is capped
+	Windows []MaxWeightWindow `json:"windows"`
+}
+
+// MaxWeightWindow caps the canary weight between a start and end time of day
+type MaxWeightWindow struct {
+	// StartTime is the time of day the window starts in 24-hour HH:MM format
+	StartTime string `json:"startTime"`
+	// EndTime is the time of day the window ends in 24-hour HH:MM format. A window which ends before it
+	// starts spans midnight
+	EndTime string `json:"endTime"`
+	// MaxWeight is the maximum canary weight applied while the window is active
+	MaxWeight int32 `json:"maxWeight"`
+}
+
+// Location returns the location of the schedule's time zone
+func (s MaxWeightSchedule) Location() (*time.Location, error) {
+	if s.TimeZone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(s.TimeZone)
+}
+
+// Minutes returns the start and end time of the window as the number of minutes since midnight
+func (w MaxWeightWindow) Minutes() (int, int, error) {
+	start, err := time.Parse("15:04", w.StartTime)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := time.Parse("15:04", w.EndTime)
+	if err != nil {
+		return 0, 0, err
+	}
+	return start.Hour()*60 + start.Minute(), end.Hour()*60 + end.Minute(), nil
 }
 
 // ALBTrafficRouting configuration for ALB ingress controller to control traffic routing
@@ -203,6 +385,38 @@ type ALBTrafficRouting struct {
 	// AnnotationPrefix has to match the configured annotation prefix on the alb ingress controller
 	// +optional
 	AnnotationPrefix string `json:"annotationPrefix,omitempty"`
+	// ListenerRules references additional rules of the Ingress, such as the rules of another listener, whose actions
+	// are updated along with the action of the RootService
+	// +optional
+	ListenerRules []ALBListenerRule `json:"listenerRules,omitempty"`
+	// StickinessConfig configures the target group stickiness of the actions of the Ingress
+	// +optional
+	StickinessConfig *ALBStickinessConfig `json:"stickinessConfig,omitempty"`
+}
+
+// ALBStickinessConfig configures the target group stickiness of the ALB actions. Since sticky sessions keep the clients
+// on the target group they were routed to, the stickiness can be reduced or disabled while the canary receives traffic
+// so that the weight changes take effect promptly.
+type ALBStickinessConfig struct {
+	// Enabled enables the target group stickiness
+	Enabled bool `json:"enabled"`
+	// DurationSeconds is the duration of the target group stickiness, between 1 and 604800 seconds
+	DurationSeconds int64 `json:"durationSeconds"`
+	// CanaryDurationSeconds is the duration of the target group stickiness while the canary receives traffic, after
+	// which the DurationSeconds is restored (e.g. on promotion). 0 disables the stickiness while the canary receives
+	// traffic. Defaults to the DurationSeconds
+	// +optional
+	CanaryDurationSeconds *int64 `json:"canaryDurationSeconds,omitempty"`
+}
+
+// ALBListenerRule references a rule of the Ingress through the service of its action
+type ALBListenerRule struct {
+	// RootService references the service in the ingress to the controller should add the action to
+	RootService string `json:"rootService"`
+	// ServicePort refers to the port that the Ingress action should route traffic to. Defaults to the ServicePort of
+	// the ALB traffic routing
+	// +optional
+	ServicePort int32 `json:"servicePort,omitempty"`
 }
 
 // RolloutTrafficRouting hosts all the different configuration for supported service meshes to enable more fine-grained traffic routing
@@ -215,6 +429,22 @@ type RolloutTrafficRouting struct {
 	ALB *ALBTrafficRouting `json:"alb,omitempty"`
 	// SMI holds TrafficSplit specific configuration to route traffic
 	SMI *SMITrafficRouting `json:"smi,omitempty"`
+	// Traefik holds Traefik IngressRoute specific configuration to route traffic
+	Traefik *TraefikTrafficRouting `json:"traefik,omitempty"`
+	// Plugin holds the configuration of a traffic router plugin registered in the controller, to route traffic with a
+	// traffic controller which is not supported natively
+	// +optional
+	Plugin *PluginTrafficRouting `json:"plugin,omitempty"`
+	// AbortRampDownSeconds is the number of seconds over which the traffic is gradually shifted back to the stable
+	// version when the rollout is aborted. The traffic is shifted back instantly if omitted.
+	// +optional
+	AbortRampDownSeconds *int32 `json:"abortRampDownSeconds,omitempty"`
+	// MaxTrafficWeight is the total weight of the traffic split between the stable and canary versions, which the
+	// weights of the steps are relative to. A MaxTrafficWeight of 1000 sets the weights in permille, so that a
+	// setWeight of 1 sends 0.1% of the traffic to the canary. A MaxTrafficWeight other than 100 is only supported by
+	// the Istio, SMI and Traefik traffic routers, which split the traffic by relative weights. Defaults to 100.
+	// +optional
+	MaxTrafficWeight *int32 `json:"maxTrafficWeight,omitempty"`
 }
 
 // SMITrafficRouting configuration for TrafficSplit Custom Resource to control traffic routing
@@ -227,6 +457,22 @@ type SMITrafficRouting struct {
 	TrafficSplitName string `json:"trafficSplitName,omitempty"`
 }
 
+// TraefikTrafficRouting configuration for Traefik IngressRoute to control traffic routing
+type TraefikTrafficRouting struct {
+	// IngressRoute refers to the name of an `IngressRoute` resource in the same namespace as the `Rollout`. The weights
+	// of the stable and canary services are set on every route forwarding to both of them.
+	IngressRoute string `json:"ingressRoute"`
+}
+
+// PluginTrafficRouting configuration for a traffic router plugin to control traffic routing
+type PluginTrafficRouting struct {
+	// Name is the name under which the router of the plugin is registered in the controller
+	Name string `json:"name"`
+	// Config is the configuration given to the router of the plugin, whose keys depend on the plugin
+	// +optional
+	Config map[string]string `json:"config,omitempty"`
+}
+
 // NginxTrafficRouting configuration for Nginx ingress controller to control traffic routing
 type NginxTrafficRouting struct {
 	// AnnotationPrefix has to match the configured annotation prefix on the nginx ingress controller
@@ -279,6 +525,10 @@ type RolloutExperimentStepAnalysisTemplateRef struct {
 	// +patchMergeKey=name
 	// +patchStrategy=merge
 	Args []AnalysisRunArgument `json:"args,omitempty" patchStrategy:"merge" patchMergeKey:"name"`
+	// RequiredForCompletion indicates that the experiment, and so the experiment step, completes once the analysis
+	// completes. The step waits on the result of the analysis even after the duration of the experiment passed
+	// +optional
+	RequiredForCompletion bool `json:"requiredForCompletion,omitempty"`
 }
 
 // RolloutExperimentTemplate defines the template used to create experiments for the Rollout's experiment canary step
@@ -355,6 +605,11 @@ type RolloutAnalysisBackground struct {
 	// StartingStep indicates which step the background analysis should start on
 	// If not listed, controller defaults to 0
 	StartingStep *int32 `json:"startingStep,omitempty"`
+	// TerminateAnalysisOnPromote indicates if the background analysis is terminated once the rollout is fully promoted.
+	// When false, the AnalysisRun keeps running after the promotion until it completes on its own, and its result no
+	// longer affects the rollout. If omitted, the controller defaults to true.
+	// +optional
+	TerminateAnalysisOnPromote *bool `json:"terminateAnalysisOnPromote,omitempty"`
 }
 
 // RolloutAnalysis defines a template that is used to create a analysisRun
@@ -372,8 +627,65 @@ type RolloutAnalysis struct {
 	// +patchMergeKey=name
 	// +patchStrategy=merge
 	Args []AnalysisRunArgument `json:"args,omitempty" patchStrategy:"merge" patchMergeKey:"name"`
+	// InheritArgsFromStep the index of a previous canary analysis step whose resolved arguments are
+	// added to the AnalysisRun. The arguments of this analysis take precedence over the inherited ones.
+	// Only supported by canary analysis steps
+	// +optional
+	InheritArgsFromStep *int32 `json:"inheritArgsFromStep,omitempty"`
+	// Aggregation creates an AnalysisRun for each of the templates rather than a single AnalysisRun combining them,
+	// or for each of the Regions when they are set, and aggregates the results of the AnalysisRuns into the decision
+	// of the step, either All, Any or Quorum.
+	// Only supported by canary analysis steps
+	// +optional
+	Aggregation AnalysisAggregation `json:"aggregation,omitempty"`
+	// Regions creates an AnalysisRun combining the templates for each of the regions, rather than for each of the
+	// templates. The region is supplied to the AnalysisRun as the `region` argument and the `analysis-region` label.
+	// Requires Aggregation
+	// +optional
+	Regions []string `json:"regions,omitempty"`
+	// Quorum is the number of successful AnalysisRuns required by the Quorum aggregation
+	// +optional
+	Quorum int32 `json:"quorum,omitempty"`
+	// TemplateChangePolicy is what happens to a running AnalysisRun when its templates change, either Ignore, which
+	// keeps running the templates snapshotted at the creation of the AnalysisRun, or Restart, which replaces the
+	// AnalysisRun with one created from the updated templates. Defaults to Ignore
+	// +optional
+	TemplateChangePolicy AnalysisTemplateChangePolicy `json:"templateChangePolicy,omitempty"`
+	// Hooks are the webhooks notified of the outcome of the AnalysisRuns once they complete
+	// +optional
+	Hooks *AnalysisHooks `json:"hooks,omitempty"`
+	// MetricErrorPolicy is how the metrics which complete Error, such as the metrics of an unavailable provider, are
+	// aggregated into the phase of the AnalysisRuns, either Error or Inconclusive. Defaults to Error
+	// +optional
+	MetricErrorPolicy MetricErrorPolicy `json:"metricErrorPolicy,omitempty"`
 }
 
+// AnalysisTemplateChangePolicy is what happens to a running AnalysisRun when its templates change
+type AnalysisTemplateChangePolicy string
+
+const (
+	// AnalysisTemplateChangePolicyIgnore keeps running the templates snapshotted at the creation of the AnalysisRun
+	AnalysisTemplateChangePolicyIgnore AnalysisTemplateChangePolicy = "Ignore"
+	// AnalysisTemplateChangePolicyRestart replaces the AnalysisRun with one created from the updated templates
+	AnalysisTemplateChangePolicyRestart AnalysisTemplateChangePolicy = "Restart"
+)
+
+// AnalysisAggregation is the policy aggregating the AnalysisRuns of the templates of an analysis step into the
+// decision of the step
+type AnalysisAggregation string
+
+const (
+	// AnalysisAggregationAll completes the step once all the AnalysisRuns are successful, and aborts the rollout as
+	// soon as one of them fails
+	AnalysisAggregationAll AnalysisAggregation = "All"
+	// AnalysisAggregationAny completes the step as soon as one of the AnalysisRuns is successful, and aborts the
+	// rollout once all of them failed
+	AnalysisAggregationAny AnalysisAggregation = "Any"
+	// AnalysisAggregationQuorum completes the step as soon as a quorum of the AnalysisRuns is successful, and aborts
+	// the rollout once too many of them failed for the quorum to be met
+	AnalysisAggregationQuorum AnalysisAggregation = "Quorum"
+)
+
 type RolloutAnalysisTemplate struct {
 	//TemplateName name of template to use in AnalysisRun
 	// +optional
@@ -397,6 +709,15 @@ type AnalysisRunArgument struct {
 type ArgumentValueFrom struct {
 	// PodTemplateHashValue gets the value from one of the children ReplicaSet's Pod Template Hash
 	PodTemplateHashValue *ValueFromPodTemplateHash `json:"podTemplateHashValue,omitempty"`
+	// FieldRef gets the value from a field of the rollout (e.g. metadata.labels['team'])
+	FieldRef *FieldRef `json:"fieldRef,omitempty"`
+}
+
+// FieldRef selects a field of the rollout
+type FieldRef struct {
+	// FieldPath is the path of the field to select. The metadata fields are selected with the paths supported by the
+	// downward API (e.g. metadata.labels['team']), and the spec fields with a dotted path (e.g. spec.strategy.canary.stableService)
+	FieldPath string `json:"fieldPath"`
 }
 
 // ValueFromPodTemplateHash indicates which ReplicaSet pod template pod hash to use
@@ -420,8 +741,16 @@ const (
 	RolloutTypePrePromotionLabel = "PrePromotion"
 	// RolloutTypePostPromotionLabel indicates that the analysisRun was created after the active service promotion
 	RolloutTypePostPromotionLabel = "PostPromotion"
+	// RolloutTypePreviewTrafficRampLabel indicates that the analysisRun was created during the preview traffic ramp
+	RolloutTypePreviewTrafficRampLabel = "PreviewTrafficRamp"
+	// RolloutTypeScaleDownDrainLabel indicates that the analysisRun was created to drain the previous active replicaset
+	RolloutTypeScaleDownDrainLabel = "ScaleDownDrain"
 	// RolloutCanaryStepIndexLabel indicates which step created this analysisRun
 	RolloutCanaryStepIndexLabel = "step-index"
+	// RolloutAnalysisTemplateIndexLabel indicates which template of an aggregated analysis step created this analysisRun
+	RolloutAnalysisTemplateIndexLabel = "analysis-template-index"
+	// RolloutAnalysisRegionLabel indicates which region of an aggregated analysis step created this analysisRun
+	RolloutAnalysisRegionLabel = "analysis-region"
 )
 
 // RolloutPause defines a pause stage for a rollout
@@ -429,6 +758,10 @@ type RolloutPause struct {
 	// Duration the amount of time to wait before moving to the next step.
 	// +optional
 	Duration *intstr.IntOrString `json:"duration,omitempty"`
+	// RequireHealthyAnalysis only resumes the rollout at the end of the duration if the background analysis is
+	// currently passing. Otherwise, the rollout stays paused until the analysis passes or the step is skipped.
+	// +optional
+	RequireHealthyAnalysis bool `json:"requireHealthyAnalysis,omitempty"`
 }
 
 // DurationSeconds converts the pause duration to seconds
@@ -478,6 +811,8 @@ const (
 	PauseReasonCanaryPauseStep PauseReason = "CanaryPauseStep"
 	// PauseReasonBlueGreenPause pause rollout before promoting rollout
 	PauseReasonBlueGreenPause PauseReason = "BlueGreenPause"
+	// PauseReasonRollbackToStep pauses rollout after a failed analysis rolled it back to an earlier step
+	PauseReasonRollbackToStep PauseReason = "RollbackToStep"
 )
 
 // PauseCondition the reason for a pause and when it started
@@ -497,7 +832,8 @@ type RolloutStatus struct {
 	// AbortedAt indicates the controller reconciled an aborted rollout. The controller uses this to understand if
 	// the controller needs to do some specific work when a Rollout is aborted. For example, the reconcileAbort is used
 	// to indicate if the Rollout should enter an aborted state when the latest AnalysisRun is a failure, or the controller
-	// has already put the Rollout into an aborted and should create a new AnalysisRun.
+	// has already put the Rollout into an aborted and should create a new AnalysisRun. It is also used as the start
+	// of the traffic ramp down of an aborted canary.
 	AbortedAt *metav1.Time `json:"abortedAt,omitempty"`
 	// CurrentPodHash the hash of the current pod template
 	// +optional
@@ -582,6 +918,19 @@ type BlueGreenStatus struct {
 	PostPromotionAnalysisRun string `json:"postPromotionAnalysisRun,omitempty"`
 	// PostPromotionAnalysisRunStatus indicates the status of the current post promotion analysis run
 	PostPromotionAnalysisRunStatus *RolloutAnalysisRunStatus `json:"postPromotionAnalysisRunStatus,omitempty"`
+	// PreviewTrafficRampStepIndex indicates the current step of the PreviewTrafficRamp
+	// +optional
+	PreviewTrafficRampStepIndex *int32 `json:"previewTrafficRampStepIndex,omitempty"`
+	// PreviewTrafficRampStepStartedAt indicates when the current step of the PreviewTrafficRamp started
+	// +optional
+	PreviewTrafficRampStepStartedAt *metav1.Time `json:"previewTrafficRampStepStartedAt,omitempty"`
+	// PreviewTrafficRampAnalysisRunStatus indicates the status of the current analysis run of the PreviewTrafficRamp
+	// +optional
+	PreviewTrafficRampAnalysisRunStatus *RolloutAnalysisRunStatus `json:"previewTrafficRampAnalysisRunStatus,omitempty"`
+	// ScaleDownDrainAnalysisRunStatus indicates the status of the current analysis run draining the previous active
+	// replicaset
+	// +optional
+	ScaleDownDrainAnalysisRunStatus *RolloutAnalysisRunStatus `json:"scaleDownDrainAnalysisRunStatus,omitempty"`
 }
 
 // CanaryStatus status fields that only pertain to the canary rollout
@@ -594,6 +943,10 @@ type CanaryStatus struct {
 	CurrentStepAnalysisRun string `json:"currentStepAnalysisRun,omitempty"`
 	// CurrentStepAnalysisRunStatus indicates the status of the current step analysis run
 	CurrentStepAnalysisRunStatus *RolloutAnalysisRunStatus `json:"currentStepAnalysisRunStatus,omitempty"`
+	// CurrentStepAnalysisRuns indicates the statuses of the analysis runs of the current step when the step aggregates
+	// an analysis run for each of its templates
+	// +optional
+	CurrentStepAnalysisRuns []RolloutAnalysisRunStatus `json:"currentStepAnalysisRuns,omitempty"`
 	// CurrentBackgroundAnalysisRun indicates the analysisRun for the Background step
 	// TODO(Deprecated): Remove in v0.10
 	CurrentBackgroundAnalysisRun string `json:"currentBackgroundAnalysisRun,omitempty"`
@@ -601,12 +954,47 @@ type CanaryStatus struct {
 	CurrentBackgroundAnalysisRunStatus *RolloutAnalysisRunStatus `json:"currentBackgroundAnalysisRunStatus,omitempty"`
 	// CurrentExperiment indicates the running experiment
 	CurrentExperiment string `json:"currentExperiment,omitempty"`
+	// AbortedWeight indicates the canary weight when the rollout was aborted, which is ramped down to zero when
+	// the trafficRouting has an AbortRampDownSeconds
+	// +optional
+	AbortedWeight int32 `json:"abortedWeight,omitempty"`
+	// BakeStartedAt indicates when the rollout started baking the new ReplicaSet at 100% weight
+	// +optional
+	BakeStartedAt *metav1.Time `json:"bakeStartedAt,omitempty"`
+	// WarmupReadySince indicates since when all the canary pods are ready at the warmup weight
+	// +optional
+	WarmupReadySince *metav1.Time `json:"warmupReadySince,omitempty"`
+	// WarmupCompleted indicates the canary pods passed the warmup ready check and the rollout started the steps
+	// +optional
+	WarmupCompleted bool `json:"warmupCompleted,omitempty"`
+	// ReadinessGateSatisfied indicates all the canary pods satisfied the readiness gate and the canary started
+	// receiving traffic
+	// +optional
+	ReadinessGateSatisfied bool `json:"readinessGateSatisfied,omitempty"`
+	// DependencySatisfied indicates the rollout referenced by DependsOnRollout was Healthy and the canary started
+	// receiving traffic
+	// +optional
+	DependencySatisfied bool `json:"dependencySatisfied,omitempty"`
+	// AnalysisRetries indicates the number of times the rollout was retried by the autoRetry of the abortPolicy
+	// after a failed analysis
+	// +optional
+	AnalysisRetries int32 `json:"analysisRetries,omitempty"`
+	// ReplicasReachedStepIndex indicates the index of the step at which the ReplicaSets reached their desired replica
+	// counts. A later shortfall of canary replicas at that step is a loss of canary pods.
+	// +optional
+	ReplicasReachedStepIndex *int32 `json:"replicasReachedStepIndex,omitempty"`
 }
 
 type RolloutAnalysisRunStatus struct {
 	Name    string        `json:"name"`
 	Status  AnalysisPhase `json:"status"`
 	Message string        `json:"message,omitempty"`
+	// Region is the region of the AnalysisRun of an aggregated analysis step running the analysis in each region
+	// +optional
+	Region string `json:"region,omitempty"`
+	// TemplateChangePolicy is the template change policy of the analysis the AnalysisRun was created for
+	// +optional
+	TemplateChangePolicy AnalysisTemplateChangePolicy `json:"templateChangePolicy,omitempty"`
 }
 
 // RolloutConditionType defines the conditions of Rollout
@@ -26,9 +26,56 @@ import (
 	intstr "k8s.io/apimachinery/pkg/util/intstr"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ALBListenerRule) DeepCopyInto(out *ALBListenerRule) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ALBListenerRule.
+func (in *ALBListenerRule) DeepCopy() *ALBListenerRule {
+	if in == nil {
+		return nil
+	}
+	out := new(ALBListenerRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ALBStickinessConfig) DeepCopyInto(out *ALBStickinessConfig) {
+	*out = *in
+	if in.CanaryDurationSeconds != nil {
+		in, out := &in.CanaryDurationSeconds, &out.CanaryDurationSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ALBStickinessConfig.
+func (in *ALBStickinessConfig) DeepCopy() *ALBStickinessConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ALBStickinessConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ALBTrafficRouting) DeepCopyInto(out *ALBTrafficRouting) {
 	*out = *in
+	if in.ListenerRules != nil {
+		in, out := &in.ListenerRules, &out.ListenerRules
+		*out = make([]ALBListenerRule, len(*in))
+		copy(*out, *in)
+	}
+	if in.StickinessConfig != nil {
+		in, out := &in.StickinessConfig, &out.StickinessConfig
+		*out = new(ALBStickinessConfig)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -42,6 +89,85 @@ func (in *ALBTrafficRouting) DeepCopy() *ALBTrafficRouting {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AlertmanagerMatcher) DeepCopyInto(out *AlertmanagerMatcher) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AlertmanagerMatcher.
+func (in *AlertmanagerMatcher) DeepCopy() *AlertmanagerMatcher {
+	if in == nil {
+		return nil
+	}
+	out := new(AlertmanagerMatcher)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AlertmanagerMetric) DeepCopyInto(out *AlertmanagerMetric) {
+	*out = *in
+	if in.Matchers != nil {
+		in, out := &in.Matchers, &out.Matchers
+		*out = make([]AlertmanagerMatcher, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AlertmanagerMetric.
+func (in *AlertmanagerMetric) DeepCopy() *AlertmanagerMetric {
+	if in == nil {
+		return nil
+	}
+	out := new(AlertmanagerMetric)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AnalysisAutoRetry) DeepCopyInto(out *AnalysisAutoRetry) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AnalysisAutoRetry.
+func (in *AnalysisAutoRetry) DeepCopy() *AnalysisAutoRetry {
+	if in == nil {
+		return nil
+	}
+	out := new(AnalysisAutoRetry)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AnalysisHooks) DeepCopyInto(out *AnalysisHooks) {
+	*out = *in
+	if in.OnSuccess != nil {
+		in, out := &in.OnSuccess, &out.OnSuccess
+		*out = new(AnalysisWebhook)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.OnFailure != nil {
+		in, out := &in.OnFailure, &out.OnFailure
+		*out = new(AnalysisWebhook)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AnalysisHooks.
+func (in *AnalysisHooks) DeepCopy() *AnalysisHooks {
+	if in == nil {
+		return nil
+	}
+	out := new(AnalysisHooks)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AnalysisRun) DeepCopyInto(out *AnalysisRun) {
 	*out = *in
@@ -141,6 +267,11 @@ func (in *AnalysisRunSpec) DeepCopyInto(out *AnalysisRunSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Hooks != nil {
+		in, out := &in.Hooks, &out.Hooks
+		*out = new(AnalysisHooks)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -168,6 +299,11 @@ func (in *AnalysisRunStatus) DeepCopyInto(out *AnalysisRunStatus) {
 		in, out := &in.StartedAt, &out.StartedAt
 		*out = (*in).DeepCopy()
 	}
+	if in.Summary != nil {
+		in, out := &in.Summary, &out.Summary
+		*out = make([]MetricSummary, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -258,6 +394,13 @@ func (in *AnalysisTemplateSpec) DeepCopyInto(out *AnalysisTemplateSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.MetricMixins != nil {
+		in, out := &in.MetricMixins, &out.MetricMixins
+		*out = make([]MetricMixin, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -271,6 +414,27 @@ func (in *AnalysisTemplateSpec) DeepCopy() *AnalysisTemplateSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AnalysisWebhook) DeepCopyInto(out *AnalysisWebhook) {
+	*out = *in
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make([]WebMetricHeader, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AnalysisWebhook.
+func (in *AnalysisWebhook) DeepCopy() *AnalysisWebhook {
+	if in == nil {
+		return nil
+	}
+	out := new(AnalysisWebhook)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AntiAffinity) DeepCopyInto(out *AntiAffinity) {
 	*out = *in
@@ -331,6 +495,11 @@ func (in *ArgumentValueFrom) DeepCopyInto(out *ArgumentValueFrom) {
 		*out = new(ValueFromPodTemplateHash)
 		**out = **in
 	}
+	if in.FieldRef != nil {
+		in, out := &in.FieldRef, &out.FieldRef
+		*out = new(FieldRef)
+		**out = **in
+	}
 	return
 }
 
@@ -361,6 +530,25 @@ func (in *BlueGreenStatus) DeepCopyInto(out *BlueGreenStatus) {
 		*out = new(RolloutAnalysisRunStatus)
 		**out = **in
 	}
+	if in.PreviewTrafficRampStepIndex != nil {
+		in, out := &in.PreviewTrafficRampStepIndex, &out.PreviewTrafficRampStepIndex
+		*out = new(int32)
+		**out = **in
+	}
+	if in.PreviewTrafficRampStepStartedAt != nil {
+		in, out := &in.PreviewTrafficRampStepStartedAt, &out.PreviewTrafficRampStepStartedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.PreviewTrafficRampAnalysisRunStatus != nil {
+		in, out := &in.PreviewTrafficRampAnalysisRunStatus, &out.PreviewTrafficRampAnalysisRunStatus
+		*out = new(RolloutAnalysisRunStatus)
+		**out = **in
+	}
+	if in.ScaleDownDrainAnalysisRunStatus != nil {
+		in, out := &in.ScaleDownDrainAnalysisRunStatus, &out.ScaleDownDrainAnalysisRunStatus
+		*out = new(RolloutAnalysisRunStatus)
+		**out = **in
+	}
 	return
 }
 
@@ -417,6 +605,28 @@ func (in *BlueGreenStrategy) DeepCopyInto(out *BlueGreenStrategy) {
 		*out = new(RolloutAnalysis)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.TrafficRouting != nil {
+		in, out := &in.TrafficRouting, &out.TrafficRouting
+		*out = new(RolloutTrafficRouting)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PreviewTrafficRamp != nil {
+		in, out := &in.PreviewTrafficRamp, &out.PreviewTrafficRamp
+		*out = make([]PreviewTrafficRampStep, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PreviewTrafficRampAnalysis != nil {
+		in, out := &in.PreviewTrafficRampAnalysis, &out.PreviewTrafficRampAnalysis
+		*out = new(RolloutAnalysis)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ScaleDownDrain != nil {
+		in, out := &in.ScaleDownDrain, &out.ScaleDownDrain
+		*out = new(ScaleDownDrain)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -430,6 +640,74 @@ func (in *BlueGreenStrategy) DeepCopy() *BlueGreenStrategy {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CanaryAbortPolicy) DeepCopyInto(out *CanaryAbortPolicy) {
+	*out = *in
+	if in.RollbackToStep != nil {
+		in, out := &in.RollbackToStep, &out.RollbackToStep
+		*out = new(int32)
+		**out = **in
+	}
+	if in.AutoRetry != nil {
+		in, out := &in.AutoRetry, &out.AutoRetry
+		*out = new(AnalysisAutoRetry)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CanaryAbortPolicy.
+func (in *CanaryAbortPolicy) DeepCopy() *CanaryAbortPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(CanaryAbortPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CanaryQuarantine) DeepCopyInto(out *CanaryQuarantine) {
+	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.TTLSeconds != nil {
+		in, out := &in.TTLSeconds, &out.TTLSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CanaryQuarantine.
+func (in *CanaryQuarantine) DeepCopy() *CanaryQuarantine {
+	if in == nil {
+		return nil
+	}
+	out := new(CanaryQuarantine)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CanaryReadinessGate) DeepCopyInto(out *CanaryReadinessGate) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CanaryReadinessGate.
+func (in *CanaryReadinessGate) DeepCopy() *CanaryReadinessGate {
+	if in == nil {
+		return nil
+	}
+	out := new(CanaryReadinessGate)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CanaryStatus) DeepCopyInto(out *CanaryStatus) {
 	*out = *in
@@ -438,11 +716,29 @@ func (in *CanaryStatus) DeepCopyInto(out *CanaryStatus) {
 		*out = new(RolloutAnalysisRunStatus)
 		**out = **in
 	}
+	if in.CurrentStepAnalysisRuns != nil {
+		in, out := &in.CurrentStepAnalysisRuns, &out.CurrentStepAnalysisRuns
+		*out = make([]RolloutAnalysisRunStatus, len(*in))
+		copy(*out, *in)
+	}
 	if in.CurrentBackgroundAnalysisRunStatus != nil {
 		in, out := &in.CurrentBackgroundAnalysisRunStatus, &out.CurrentBackgroundAnalysisRunStatus
 		*out = new(RolloutAnalysisRunStatus)
 		**out = **in
 	}
+	if in.BakeStartedAt != nil {
+		in, out := &in.BakeStartedAt, &out.BakeStartedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.WarmupReadySince != nil {
+		in, out := &in.WarmupReadySince, &out.WarmupReadySince
+		*out = (*in).DeepCopy()
+	}
+	if in.ReplicasReachedStepIndex != nil {
+		in, out := &in.ReplicasReachedStepIndex, &out.ReplicasReachedStepIndex
+		*out = new(int32)
+		**out = **in
+	}
 	return
 }
 
@@ -532,6 +828,41 @@ func (in *CanaryStrategy) DeepCopyInto(out *CanaryStrategy) {
 		*out = new(AntiAffinity)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.MaxWeightSchedule != nil {
+		in, out := &in.MaxWeightSchedule, &out.MaxWeightSchedule
+		*out = new(MaxWeightSchedule)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BakeTime != nil {
+		in, out := &in.BakeTime, &out.BakeTime
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.AbortPolicy != nil {
+		in, out := &in.AbortPolicy, &out.AbortPolicy
+		*out = new(CanaryAbortPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.WarmupWeight != nil {
+		in, out := &in.WarmupWeight, &out.WarmupWeight
+		*out = new(int32)
+		**out = **in
+	}
+	if in.WarmupReadyCheck != nil {
+		in, out := &in.WarmupReadyCheck, &out.WarmupReadyCheck
+		*out = new(WarmupReadyCheck)
+		**out = **in
+	}
+	if in.ReadinessGate != nil {
+		in, out := &in.ReadinessGate, &out.ReadinessGate
+		*out = new(CanaryReadinessGate)
+		**out = **in
+	}
+	if in.QuarantineOnFailure != nil {
+		in, out := &in.QuarantineOnFailure, &out.QuarantineOnFailure
+		*out = new(CanaryQuarantine)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -606,78 +937,199 @@ func (in *ClusterAnalysisTemplateList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Experiment) DeepCopyInto(out *Experiment) {
+func (in *CommitStatusMetric) DeepCopyInto(out *CommitStatusMetric) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
-	in.Status.DeepCopyInto(&out.Status)
+	if in.RequiredChecks != nil {
+		in, out := &in.RequiredChecks, &out.RequiredChecks
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TokenSecretRef != nil {
+		in, out := &in.TokenSecretRef, &out.TokenSecretRef
+		*out = new(SecretKeyRef)
+		**out = **in
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Experiment.
-func (in *Experiment) DeepCopy() *Experiment {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CommitStatusMetric.
+func (in *CommitStatusMetric) DeepCopy() *CommitStatusMetric {
 	if in == nil {
 		return nil
 	}
-	out := new(Experiment)
+	out := new(CommitStatusMetric)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *Experiment) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ExperimentAnalysisRunStatus) DeepCopyInto(out *ExperimentAnalysisRunStatus) {
+func (in *DatadogLogsQuery) DeepCopyInto(out *DatadogLogsQuery) {
 	*out = *in
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExperimentAnalysisRunStatus.
-func (in *ExperimentAnalysisRunStatus) DeepCopy() *ExperimentAnalysisRunStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatadogLogsQuery.
+func (in *DatadogLogsQuery) DeepCopy() *DatadogLogsQuery {
 	if in == nil {
 		return nil
 	}
-	out := new(ExperimentAnalysisRunStatus)
+	out := new(DatadogLogsQuery)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ExperimentAnalysisTemplateRef) DeepCopyInto(out *ExperimentAnalysisTemplateRef) {
+func (in *DatadogMetric) DeepCopyInto(out *DatadogMetric) {
 	*out = *in
-	if in.Args != nil {
-		in, out := &in.Args, &out.Args
-		*out = make([]Argument, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+	if in.Logs != nil {
+		in, out := &in.Logs, &out.Logs
+		*out = new(DatadogLogsQuery)
+		**out = **in
+	}
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make([]WebMetricHeader, len(*in))
+		copy(*out, *in)
 	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExperimentAnalysisTemplateRef.
-func (in *ExperimentAnalysisTemplateRef) DeepCopy() *ExperimentAnalysisTemplateRef {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatadogMetric.
+func (in *DatadogMetric) DeepCopy() *DatadogMetric {
 	if in == nil {
 		return nil
 	}
-	out := new(ExperimentAnalysisTemplateRef)
+	out := new(DatadogMetric)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ExperimentCondition) DeepCopyInto(out *ExperimentCondition) {
+func (in *DecisionMetric) DeepCopyInto(out *DecisionMetric) {
 	*out = *in
-	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
-	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
-	return
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make([]WebMetricHeader, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DecisionMetric.
+func (in *DecisionMetric) DeepCopy() *DecisionMetric {
+	if in == nil {
+		return nil
+	}
+	out := new(DecisionMetric)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ElasticsearchMetric) DeepCopyInto(out *ElasticsearchMetric) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ElasticsearchMetric.
+func (in *ElasticsearchMetric) DeepCopy() *ElasticsearchMetric {
+	if in == nil {
+		return nil
+	}
+	out := new(ElasticsearchMetric)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EventInvolvedObjectSelector) DeepCopyInto(out *EventInvolvedObjectSelector) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EventInvolvedObjectSelector.
+func (in *EventInvolvedObjectSelector) DeepCopy() *EventInvolvedObjectSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(EventInvolvedObjectSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Experiment) DeepCopyInto(out *Experiment) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Experiment.
+func (in *Experiment) DeepCopy() *Experiment {
+	if in == nil {
+		return nil
+	}
+	out := new(Experiment)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Experiment) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExperimentAnalysisRunStatus) DeepCopyInto(out *ExperimentAnalysisRunStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExperimentAnalysisRunStatus.
+func (in *ExperimentAnalysisRunStatus) DeepCopy() *ExperimentAnalysisRunStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ExperimentAnalysisRunStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExperimentAnalysisTemplateRef) DeepCopyInto(out *ExperimentAnalysisTemplateRef) {
+	*out = *in
+	if in.Args != nil {
+		in, out := &in.Args, &out.Args
+		*out = make([]Argument, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExperimentAnalysisTemplateRef.
+func (in *ExperimentAnalysisTemplateRef) DeepCopy() *ExperimentAnalysisTemplateRef {
+	if in == nil {
+		return nil
+	}
+	out := new(ExperimentAnalysisTemplateRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExperimentCondition) DeepCopyInto(out *ExperimentCondition) {
+	*out = *in
+	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	return
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExperimentCondition.
@@ -745,6 +1197,13 @@ func (in *ExperimentSpec) DeepCopyInto(out *ExperimentSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Args != nil {
+		in, out := &in.Args, &out.Args
+		*out = make([]Argument, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -797,6 +1256,207 @@ func (in *ExperimentStatus) DeepCopy() *ExperimentStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FallbackMetricProvider) DeepCopyInto(out *FallbackMetricProvider) {
+	*out = *in
+	if in.Prometheus != nil {
+		in, out := &in.Prometheus, &out.Prometheus
+		*out = new(PrometheusMetric)
+		**out = **in
+	}
+	if in.Kayenta != nil {
+		in, out := &in.Kayenta, &out.Kayenta
+		*out = new(KayentaMetric)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Web != nil {
+		in, out := &in.Web, &out.Web
+		*out = new(WebMetric)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Wavefront != nil {
+		in, out := &in.Wavefront, &out.Wavefront
+		*out = new(WavefrontMetric)
+		**out = **in
+	}
+	if in.Elasticsearch != nil {
+		in, out := &in.Elasticsearch, &out.Elasticsearch
+		*out = new(ElasticsearchMetric)
+		**out = **in
+	}
+	if in.KubernetesEvent != nil {
+		in, out := &in.KubernetesEvent, &out.KubernetesEvent
+		*out = new(KubernetesEventMetric)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PodExec != nil {
+		in, out := &in.PodExec, &out.PodExec
+		*out = new(PodExecMetric)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Alertmanager != nil {
+		in, out := &in.Alertmanager, &out.Alertmanager
+		*out = new(AlertmanagerMetric)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Decision != nil {
+		in, out := &in.Decision, &out.Decision
+		*out = new(DecisionMetric)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Loki != nil {
+		in, out := &in.Loki, &out.Loki
+		*out = new(LokiMetric)
+		**out = **in
+	}
+	if in.Pingdom != nil {
+		in, out := &in.Pingdom, &out.Pingdom
+		*out = new(PingdomMetric)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GRPC != nil {
+		in, out := &in.GRPC, &out.GRPC
+		*out = new(GRPCMetric)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ReplicaSet != nil {
+		in, out := &in.ReplicaSet, &out.ReplicaSet
+		*out = new(ReplicaSetMetric)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Datadog != nil {
+		in, out := &in.Datadog, &out.Datadog
+		*out = new(DatadogMetric)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PodMetrics != nil {
+		in, out := &in.PodMetrics, &out.PodMetrics
+		*out = new(PodMetricsMetric)
+		**out = **in
+	}
+	if in.Incident != nil {
+		in, out := &in.Incident, &out.Incident
+		*out = new(IncidentMetric)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.XRay != nil {
+		in, out := &in.XRay, &out.XRay
+		*out = new(XRayMetric)
+		**out = **in
+	}
+	if in.CommitStatus != nil {
+		in, out := &in.CommitStatus, &out.CommitStatus
+		*out = new(CommitStatusMetric)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FallbackMetricProvider.
+func (in *FallbackMetricProvider) DeepCopy() *FallbackMetricProvider {
+	if in == nil {
+		return nil
+	}
+	out := new(FallbackMetricProvider)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FieldRef) DeepCopyInto(out *FieldRef) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FieldRef.
+func (in *FieldRef) DeepCopy() *FieldRef {
+	if in == nil {
+		return nil
+	}
+	out := new(FieldRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GRPCMetric) DeepCopyInto(out *GRPCMetric) {
+	*out = *in
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(GRPCMetricTLS)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GRPCMetric.
+func (in *GRPCMetric) DeepCopy() *GRPCMetric {
+	if in == nil {
+		return nil
+	}
+	out := new(GRPCMetric)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GRPCMetricTLS) DeepCopyInto(out *GRPCMetricTLS) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GRPCMetricTLS.
+func (in *GRPCMetricTLS) DeepCopy() *GRPCMetricTLS {
+	if in == nil {
+		return nil
+	}
+	out := new(GRPCMetricTLS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HTTPStatusRange) DeepCopyInto(out *HTTPStatusRange) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPStatusRange.
+func (in *HTTPStatusRange) DeepCopy() *HTTPStatusRange {
+	if in == nil {
+		return nil
+	}
+	out := new(HTTPStatusRange)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IncidentMetric) DeepCopyInto(out *IncidentMetric) {
+	*out = *in
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make([]WebMetricHeader, len(*in))
+		copy(*out, *in)
+	}
+	if in.ActiveStatuses != nil {
+		in, out := &in.ActiveStatuses, &out.ActiveStatuses
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IncidentMetric.
+func (in *IncidentMetric) DeepCopy() *IncidentMetric {
+	if in == nil {
+		return nil
+	}
+	out := new(IncidentMetric)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IstioTrafficRouting) DeepCopyInto(out *IstioTrafficRouting) {
 	*out = *in
@@ -840,6 +1500,16 @@ func (in *JobMetric) DeepCopyInto(out *JobMetric) {
 	*out = *in
 	in.Metadata.DeepCopyInto(&out.Metadata)
 	in.Spec.DeepCopyInto(&out.Spec)
+	if in.BackoffLimit != nil {
+		in, out := &in.BackoffLimit, &out.BackoffLimit
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ActiveDeadlineSeconds != nil {
+		in, out := &in.ActiveDeadlineSeconds, &out.ActiveDeadlineSeconds
+		*out = new(int64)
+		**out = **in
+	}
 	return
 }
 
@@ -909,6 +1579,101 @@ func (in *KayentaThreshold) DeepCopy() *KayentaThreshold {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubernetesEventMetric) DeepCopyInto(out *KubernetesEventMetric) {
+	*out = *in
+	if in.Reasons != nil {
+		in, out := &in.Reasons, &out.Reasons
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.InvolvedObject != nil {
+		in, out := &in.InvolvedObject, &out.InvolvedObject
+		*out = new(EventInvolvedObjectSelector)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubernetesEventMetric.
+func (in *KubernetesEventMetric) DeepCopy() *KubernetesEventMetric {
+	if in == nil {
+		return nil
+	}
+	out := new(KubernetesEventMetric)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LokiMetric) DeepCopyInto(out *LokiMetric) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LokiMetric.
+func (in *LokiMetric) DeepCopy() *LokiMetric {
+	if in == nil {
+		return nil
+	}
+	out := new(LokiMetric)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MarginalBand) DeepCopyInto(out *MarginalBand) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MarginalBand.
+func (in *MarginalBand) DeepCopy() *MarginalBand {
+	if in == nil {
+		return nil
+	}
+	out := new(MarginalBand)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaxWeightSchedule) DeepCopyInto(out *MaxWeightSchedule) {
+	*out = *in
+	if in.Windows != nil {
+		in, out := &in.Windows, &out.Windows
+		*out = make([]MaxWeightWindow, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaxWeightSchedule.
+func (in *MaxWeightSchedule) DeepCopy() *MaxWeightSchedule {
+	if in == nil {
+		return nil
+	}
+	out := new(MaxWeightSchedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaxWeightWindow) DeepCopyInto(out *MaxWeightWindow) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaxWeightWindow.
+func (in *MaxWeightWindow) DeepCopy() *MaxWeightWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(MaxWeightWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Measurement) DeepCopyInto(out *Measurement) {
 	*out = *in
@@ -934,34 +1699,72 @@ func (in *Measurement) DeepCopyInto(out *Measurement) {
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Measurement.
-func (in *Measurement) DeepCopy() *Measurement {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Measurement.
+func (in *Measurement) DeepCopy() *Measurement {
+	if in == nil {
+		return nil
+	}
+	out := new(Measurement)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Metric) DeepCopyInto(out *Metric) {
+	*out = *in
+	if in.RecentValues != nil {
+		in, out := &in.RecentValues, &out.RecentValues
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MarginalBand != nil {
+		in, out := &in.MarginalBand, &out.MarginalBand
+		*out = new(MarginalBand)
+		**out = **in
+	}
+	if in.ConsecutiveErrorLimit != nil {
+		in, out := &in.ConsecutiveErrorLimit, &out.ConsecutiveErrorLimit
+		*out = new(int32)
+		**out = **in
+	}
+	in.Provider.DeepCopyInto(&out.Provider)
+	if in.FallbackProvider != nil {
+		in, out := &in.FallbackProvider, &out.FallbackProvider
+		*out = new(FallbackMetricProvider)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Metric.
+func (in *Metric) DeepCopy() *Metric {
 	if in == nil {
 		return nil
 	}
-	out := new(Measurement)
+	out := new(Metric)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Metric) DeepCopyInto(out *Metric) {
+func (in *MetricMixin) DeepCopyInto(out *MetricMixin) {
 	*out = *in
-	if in.ConsecutiveErrorLimit != nil {
-		in, out := &in.ConsecutiveErrorLimit, &out.ConsecutiveErrorLimit
-		*out = new(int32)
-		**out = **in
+	if in.Args != nil {
+		in, out := &in.Args, &out.Args
+		*out = make([]Argument, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
-	in.Provider.DeepCopyInto(&out.Provider)
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Metric.
-func (in *Metric) DeepCopy() *Metric {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetricMixin.
+func (in *MetricMixin) DeepCopy() *MetricMixin {
 	if in == nil {
 		return nil
 	}
-	out := new(Metric)
+	out := new(MetricMixin)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -994,6 +1797,76 @@ func (in *MetricProvider) DeepCopyInto(out *MetricProvider) {
 		*out = new(JobMetric)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Elasticsearch != nil {
+		in, out := &in.Elasticsearch, &out.Elasticsearch
+		*out = new(ElasticsearchMetric)
+		**out = **in
+	}
+	if in.KubernetesEvent != nil {
+		in, out := &in.KubernetesEvent, &out.KubernetesEvent
+		*out = new(KubernetesEventMetric)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PodExec != nil {
+		in, out := &in.PodExec, &out.PodExec
+		*out = new(PodExecMetric)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Alertmanager != nil {
+		in, out := &in.Alertmanager, &out.Alertmanager
+		*out = new(AlertmanagerMetric)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Decision != nil {
+		in, out := &in.Decision, &out.Decision
+		*out = new(DecisionMetric)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Loki != nil {
+		in, out := &in.Loki, &out.Loki
+		*out = new(LokiMetric)
+		**out = **in
+	}
+	if in.Pingdom != nil {
+		in, out := &in.Pingdom, &out.Pingdom
+		*out = new(PingdomMetric)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GRPC != nil {
+		in, out := &in.GRPC, &out.GRPC
+		*out = new(GRPCMetric)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ReplicaSet != nil {
+		in, out := &in.ReplicaSet, &out.ReplicaSet
+		*out = new(ReplicaSetMetric)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Datadog != nil {
+		in, out := &in.Datadog, &out.Datadog
+		*out = new(DatadogMetric)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PodMetrics != nil {
+		in, out := &in.PodMetrics, &out.PodMetrics
+		*out = new(PodMetricsMetric)
+		**out = **in
+	}
+	if in.Incident != nil {
+		in, out := &in.Incident, &out.Incident
+		*out = new(IncidentMetric)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.XRay != nil {
+		in, out := &in.XRay, &out.XRay
+		*out = new(XRayMetric)
+		**out = **in
+	}
+	if in.CommitStatus != nil {
+		in, out := &in.CommitStatus, &out.CommitStatus
+		*out = new(CommitStatusMetric)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -1030,6 +1903,22 @@ func (in *MetricResult) DeepCopy() *MetricResult {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MetricSummary) DeepCopyInto(out *MetricSummary) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetricSummary.
+func (in *MetricSummary) DeepCopy() *MetricSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(MetricSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NginxTrafficRouting) DeepCopyInto(out *NginxTrafficRouting) {
 	*out = *in
@@ -1070,6 +1959,92 @@ func (in *PauseCondition) DeepCopy() *PauseCondition {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PingdomMetric) DeepCopyInto(out *PingdomMetric) {
+	*out = *in
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make([]WebMetricHeader, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PingdomMetric.
+func (in *PingdomMetric) DeepCopy() *PingdomMetric {
+	if in == nil {
+		return nil
+	}
+	out := new(PingdomMetric)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PluginTrafficRouting) DeepCopyInto(out *PluginTrafficRouting) {
+	*out = *in
+	if in.Config != nil {
+		in, out := &in.Config, &out.Config
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PluginTrafficRouting.
+func (in *PluginTrafficRouting) DeepCopy() *PluginTrafficRouting {
+	if in == nil {
+		return nil
+	}
+	out := new(PluginTrafficRouting)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodExecMetric) DeepCopyInto(out *PodExecMetric) {
+	*out = *in
+	if in.Command != nil {
+		in, out := &in.Command, &out.Command
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodExecMetric.
+func (in *PodExecMetric) DeepCopy() *PodExecMetric {
+	if in == nil {
+		return nil
+	}
+	out := new(PodExecMetric)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodMetricsMetric) DeepCopyInto(out *PodMetricsMetric) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodMetricsMetric.
+func (in *PodMetricsMetric) DeepCopy() *PodMetricsMetric {
+	if in == nil {
+		return nil
+	}
+	out := new(PodMetricsMetric)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PodTemplateMetadata) DeepCopyInto(out *PodTemplateMetadata) {
 	*out = *in
@@ -1116,6 +2091,27 @@ func (in *PreferredDuringSchedulingIgnoredDuringExecution) DeepCopy() *Preferred
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreviewTrafficRampStep) DeepCopyInto(out *PreviewTrafficRampStep) {
+	*out = *in
+	if in.Duration != nil {
+		in, out := &in.Duration, &out.Duration
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreviewTrafficRampStep.
+func (in *PreviewTrafficRampStep) DeepCopy() *PreviewTrafficRampStep {
+	if in == nil {
+		return nil
+	}
+	out := new(PreviewTrafficRampStep)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PrometheusMetric) DeepCopyInto(out *PrometheusMetric) {
 	*out = *in
@@ -1132,6 +2128,43 @@ func (in *PrometheusMetric) DeepCopy() *PrometheusMetric {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplicaSetMetric) DeepCopyInto(out *ReplicaSetMetric) {
+	*out = *in
+	if in.Fields != nil {
+		in, out := &in.Fields, &out.Fields
+		*out = make([]ReplicaSetMetricField, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplicaSetMetric.
+func (in *ReplicaSetMetric) DeepCopy() *ReplicaSetMetric {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplicaSetMetric)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplicaSetMetricField) DeepCopyInto(out *ReplicaSetMetricField) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplicaSetMetricField.
+func (in *ReplicaSetMetricField) DeepCopy() *ReplicaSetMetricField {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplicaSetMetricField)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RequiredDuringSchedulingIgnoredDuringExecution) DeepCopyInto(out *RequiredDuringSchedulingIgnoredDuringExecution) {
 	*out = *in
@@ -1191,6 +2224,21 @@ func (in *RolloutAnalysis) DeepCopyInto(out *RolloutAnalysis) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.InheritArgsFromStep != nil {
+		in, out := &in.InheritArgsFromStep, &out.InheritArgsFromStep
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Regions != nil {
+		in, out := &in.Regions, &out.Regions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Hooks != nil {
+		in, out := &in.Hooks, &out.Hooks
+		*out = new(AnalysisHooks)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -1213,6 +2261,11 @@ func (in *RolloutAnalysisBackground) DeepCopyInto(out *RolloutAnalysisBackground
 		*out = new(int32)
 		**out = **in
 	}
+	if in.TerminateAnalysisOnPromote != nil {
+		in, out := &in.TerminateAnalysisOnPromote, &out.TerminateAnalysisOnPromote
+		*out = new(bool)
+		**out = **in
+	}
 	return
 }
 
@@ -1439,6 +2492,11 @@ func (in *RolloutSpec) DeepCopyInto(out *RolloutSpec) {
 		in, out := &in.RestartAt, &out.RestartAt
 		*out = (*in).DeepCopy()
 	}
+	if in.MaxConcurrentAnalysisRuns != nil {
+		in, out := &in.MaxConcurrentAnalysisRuns, &out.MaxConcurrentAnalysisRuns
+		*out = new(int32)
+		**out = **in
+	}
 	return
 }
 
@@ -1544,13 +2602,33 @@ func (in *RolloutTrafficRouting) DeepCopyInto(out *RolloutTrafficRouting) {
 	if in.ALB != nil {
 		in, out := &in.ALB, &out.ALB
 		*out = new(ALBTrafficRouting)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.SMI != nil {
 		in, out := &in.SMI, &out.SMI
 		*out = new(SMITrafficRouting)
 		**out = **in
 	}
+	if in.Traefik != nil {
+		in, out := &in.Traefik, &out.Traefik
+		*out = new(TraefikTrafficRouting)
+		**out = **in
+	}
+	if in.Plugin != nil {
+		in, out := &in.Plugin, &out.Plugin
+		*out = new(PluginTrafficRouting)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AbortRampDownSeconds != nil {
+		in, out := &in.AbortRampDownSeconds, &out.AbortRampDownSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxTrafficWeight != nil {
+		in, out := &in.MaxTrafficWeight, &out.MaxTrafficWeight
+		*out = new(int32)
+		**out = **in
+	}
 	return
 }
 
@@ -1580,6 +2658,28 @@ func (in *SMITrafficRouting) DeepCopy() *SMITrafficRouting {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScaleDownDrain) DeepCopyInto(out *ScaleDownDrain) {
+	*out = *in
+	in.RolloutAnalysis.DeepCopyInto(&out.RolloutAnalysis)
+	if in.MaxDelaySeconds != nil {
+		in, out := &in.MaxDelaySeconds, &out.MaxDelaySeconds
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScaleDownDrain.
+func (in *ScaleDownDrain) DeepCopy() *ScaleDownDrain {
+	if in == nil {
+		return nil
+	}
+	out := new(ScaleDownDrain)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ScopeDetail) DeepCopyInto(out *ScopeDetail) {
 	*out = *in
@@ -1690,6 +2790,22 @@ func (in *TemplateStatus) DeepCopy() *TemplateStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TraefikTrafficRouting) DeepCopyInto(out *TraefikTrafficRouting) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TraefikTrafficRouting.
+func (in *TraefikTrafficRouting) DeepCopy() *TraefikTrafficRouting {
+	if in == nil {
+		return nil
+	}
+	out := new(TraefikTrafficRouting)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ValueFrom) DeepCopyInto(out *ValueFrom) {
 	*out = *in
@@ -1711,6 +2827,22 @@ func (in *ValueFrom) DeepCopy() *ValueFrom {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WarmupReadyCheck) DeepCopyInto(out *WarmupReadyCheck) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WarmupReadyCheck.
+func (in *WarmupReadyCheck) DeepCopy() *WarmupReadyCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(WarmupReadyCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WavefrontMetric) DeepCopyInto(out *WavefrontMetric) {
 	*out = *in
@@ -1735,6 +2867,18 @@ func (in *WebMetric) DeepCopyInto(out *WebMetric) {
 		*out = make([]WebMetricHeader, len(*in))
 		copy(*out, *in)
 	}
+	if in.ValuesFrom != nil {
+		in, out := &in.ValuesFrom, &out.ValuesFrom
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.SuccessStatusRanges != nil {
+		in, out := &in.SuccessStatusRanges, &out.SuccessStatusRanges
+		*out = make([]HTTPStatusRange, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -1763,3 +2907,19 @@ func (in *WebMetricHeader) DeepCopy() *WebMetricHeader {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *XRayMetric) DeepCopyInto(out *XRayMetric) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new XRayMetric.
+func (in *XRayMetric) DeepCopy() *XRayMetric {
+	if in == nil {
+		return nil
+	}
+	out := new(XRayMetric)
+	in.DeepCopyInto(out)
+	return out
+}
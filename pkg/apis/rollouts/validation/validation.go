@@ -3,6 +3,7 @@ package validation
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"strconv"
 
 	corev1 "k8s.io/api/core/v1"
@@ -20,6 +21,7 @@ import (
 	apivalidation "k8s.io/kubernetes/pkg/apis/core/validation"
 
 	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/argoproj/argo-rollouts/utils/annotations"
 )
 
 const (
@@ -27,8 +29,8 @@ const (
 
 	// MissingFieldMessage the message to indicate rollout is missing a field
 	MissingFieldMessage = "Rollout has missing field '%s'"
-	// InvalidSetWeightMessage indicates the setweight value needs to be between 0 and 100
-	InvalidSetWeightMessage = "SetWeight needs to be between 0 and 100"
+	// InvalidSetWeightMessage indicates the setweight value needs to be between 0 and the maxTrafficWeight
+	InvalidSetWeightMessage = "SetWeight needs to be between 0 and the maxTrafficWeight (default 100)"
 	// InvalidSetCanaryScaleTrafficPolicy indicates that TrafficRouting, required for SetCanaryScale, is missing
 	InvalidSetCanaryScaleTrafficPolicy = "SetCanaryScale requires TrafficRouting to be set"
 	// InvalidDurationMessage indicates the Duration value needs to be greater than 0
@@ -53,14 +55,118 @@ const (
 	InvalidTrafficRoutingMessage = "Canary service and Stable service must to be set to use Traffic Routing"
 	// InvalidIstioRoutesMessage indicates that rollout does not have a route specified for the istio Traffic Routing
 	InvalidIstioRoutesMessage = "Istio virtual service must have at least 1 route specified"
+	// InvalidTraefikIngressRouteMessage indicates that rollout does not specify the IngressRoute of the Traefik Traffic Routing
+	InvalidTraefikIngressRouteMessage = "Traefik IngressRoute must be specified"
+	// MissingTrafficRouterPluginNameMessage indicates that rollout does not specify the name of the traffic router plugin
+	MissingTrafficRouterPluginNameMessage = "Traffic router plugin name must be specified"
+	// InvalidMaxTrafficWeightMessage indicates that the maxTrafficWeight is lower than the default of 100
+	InvalidMaxTrafficWeightMessage = "MaxTrafficWeight must be at least 100"
+	// UnsupportedMaxTrafficWeightMessage indicates that the traffic router only supports weights in percent
+	UnsupportedMaxTrafficWeightMessage = "MaxTrafficWeight other than 100 is only supported by the Istio, SMI and Traefik traffic routers"
+	// InvalidALBStickinessDurationMessage indicates that the duration of the ALB target group stickiness is out of range
+	InvalidALBStickinessDurationMessage = "ALB stickiness DurationSeconds must be between 1 and 604800"
+	// InvalidALBStickinessCanaryDurationMessage indicates that the canary duration of the ALB target group stickiness is out of range
+	InvalidALBStickinessCanaryDurationMessage = "ALB stickiness CanaryDurationSeconds must be between 0 and 604800"
+	// InvalidRequireManualApprovalMessage indicates that requireManualApproval needs a prePromotionAnalysis to gate on
+	InvalidRequireManualApprovalMessage = "RequireManualApproval requires PrePromotionAnalysis to be set"
+	// InvalidRequireManualApprovalAutoPromotionMessage indicates that requireManualApproval can not be combined with autoPromotionSeconds
+	InvalidRequireManualApprovalAutoPromotionMessage = "RequireManualApproval can not be used with AutoPromotionSeconds"
+	// InvalidMaxWeightScheduleTimeZoneMessage indicates that the maxWeightSchedule time zone is not a valid IANA time zone
+	InvalidMaxWeightScheduleTimeZoneMessage = "MaxWeightSchedule TimeZone must be a valid IANA time zone name"
+	// InvalidMaxWeightWindowTimeMessage indicates that a maxWeightSchedule window time is not in HH:MM format
+	InvalidMaxWeightWindowTimeMessage = "MaxWeightSchedule window StartTime and EndTime must be in 24-hour HH:MM format and must not be equal"
+	// InvalidMaxWeightWindowWeightMessage indicates that a maxWeightSchedule window max weight is out of range
+	InvalidMaxWeightWindowWeightMessage = "MaxWeightSchedule window MaxWeight must be between 0 and the maxTrafficWeight (default 100)"
+	// OverlappingMaxWeightWindowsMessage indicates that two maxWeightSchedule windows overlap
+	OverlappingMaxWeightWindowsMessage = "MaxWeightSchedule windows must not overlap"
+	// InvalidInheritArgsFromStepMessage indicates that inheritArgsFromStep does not reference a previous analysis step
+	InvalidInheritArgsFromStepMessage = "InheritArgsFromStep must reference a previous step with an analysis"
+	// InvalidInheritArgsFromStepScopeMessage indicates that inheritArgsFromStep is set outside of a canary analysis step
+	InvalidInheritArgsFromStepScopeMessage = "InheritArgsFromStep is only supported by canary analysis steps"
+	// InvalidAnalysisAggregationMessage indicates that the aggregation of an analysis step is not a supported policy
+	InvalidAnalysisAggregationMessage = "Aggregation must be either All, Any or Quorum"
+	// InvalidAnalysisAggregationTemplatesMessage indicates that the aggregation of an analysis step requires templates to aggregate
+	InvalidAnalysisAggregationTemplatesMessage = "Aggregation requires the Templates of the analysis to be set"
+	// InvalidAnalysisAggregationScopeMessage indicates that aggregation is set outside of a canary analysis step
+	InvalidAnalysisAggregationScopeMessage = "Aggregation is only supported by canary analysis steps"
+	// InvalidAnalysisQuorumMessage indicates that the quorum of an analysis step exceeds the number of its AnalysisRuns
+	InvalidAnalysisQuorumMessage = "Quorum must be between 1 and the number of Regions, or Templates when Regions are not set"
+	// InvalidAnalysisQuorumAggregationMessage indicates that quorum is set without the Quorum aggregation
+	InvalidAnalysisQuorumAggregationMessage = "Quorum requires the Quorum aggregation"
+	// InvalidAnalysisRegionsMessage indicates that regions are set without aggregation
+	InvalidAnalysisRegionsMessage = "Regions requires Aggregation to be set"
+	// InvalidAnalysisRegionMessage indicates that a region of an analysis step is empty or listed more than once
+	InvalidAnalysisRegionMessage = "Regions must be unique and must not be empty"
+	// InvalidTemplateChangePolicyMessage indicates that the template change policy of an analysis is not a supported policy
+	InvalidTemplateChangePolicyMessage = "TemplateChangePolicy must be either Ignore or Restart"
+	// InvalidAnalysisWebhookURLMessage indicates that the URL of an analysis hook is not an absolute http or https URL
+	InvalidAnalysisWebhookURLMessage = "Hook URL must be an absolute http or https URL"
+	// InvalidRequireHealthyAnalysisMessage indicates that requireHealthyAnalysis needs a pause duration and a background analysis
+	InvalidRequireHealthyAnalysisMessage = "RequireHealthyAnalysis requires the pause Duration and the canary background Analysis to be set"
+	// InvalidBlueGreenTrafficRoutingMessage indicates that the preview service must be set to use Traffic Routing with a blue-green strategy
+	InvalidBlueGreenTrafficRoutingMessage = "Preview service must be set to use Traffic Routing"
+	// InvalidPreviewTrafficRampMessage indicates that TrafficRouting, required for PreviewTrafficRamp, is missing
+	InvalidPreviewTrafficRampMessage = "PreviewTrafficRamp requires TrafficRouting to be set"
+	// InvalidPreviewTrafficRampWeightMessage indicates the weight of a preview traffic ramp step needs to be between 0 and the maxTrafficWeight
+	InvalidPreviewTrafficRampWeightMessage = "PreviewTrafficRamp weight needs to be between 0 and the maxTrafficWeight (default 100)"
+	// InvalidPreviewTrafficRampAnalysisMessage indicates that PreviewTrafficRampAnalysis requires a PreviewTrafficRamp to run during
+	InvalidPreviewTrafficRampAnalysisMessage = "PreviewTrafficRampAnalysis requires PreviewTrafficRamp to be set"
+	// InvalidScaleDownDrainMaxDelayMessage indicates the maximum delay of the scale down drain needs to be positive
+	InvalidScaleDownDrainMaxDelayMessage = "ScaleDownDrain MaxDelaySeconds needs to be greater than 0"
+	// InvalidScaleDownDrainDelayMessage indicates that a fixed scale down delay is set along with the scale down drain
+	InvalidScaleDownDrainDelayMessage = "ScaleDownDelaySeconds cannot be set with ScaleDownDrain, whose MaxDelaySeconds bounds the scale down delay"
+	// InvalidRollbackToStepMessage indicates that the rollbackToStep of the abort policy is not the index of a step
+	InvalidRollbackToStepMessage = "AbortPolicy RollbackToStep must be the index of one of the canary steps"
+	// InvalidAutoRetryLimitMessage indicates the limit of the auto retry of the abort policy needs to be positive
+	InvalidAutoRetryLimitMessage = "AbortPolicy AutoRetry Limit needs to be greater than 0"
+	// InvalidWarmupWeightMessage indicates the warmup weight needs to be between 1 and the maxTrafficWeight
+	InvalidWarmupWeightMessage = "WarmupWeight needs to be between 1 and the maxTrafficWeight (default 100)"
+	// InvalidWarmupWeightStepsMessage indicates that the steps, which start after the warmup, are missing
+	InvalidWarmupWeightStepsMessage = "WarmupWeight requires Steps to be set"
+	// InvalidWarmupReadyCheckMessage indicates that WarmupWeight, required for WarmupReadyCheck, is missing
+	InvalidWarmupReadyCheckMessage = "WarmupReadyCheck requires WarmupWeight to be set"
+	// InvalidReadinessGateTrafficRoutingMessage indicates that TrafficRouting, required for ReadinessGate, is missing
+	InvalidReadinessGateTrafficRoutingMessage = "ReadinessGate requires TrafficRouting to be set"
+	// MissingReadinessGateConditionTypeMessage indicates that the condition type of the readiness gate is missing
+	MissingReadinessGateConditionTypeMessage = "ReadinessGate requires a ConditionType"
+	// InvalidDependsOnRolloutMessage indicates that a rollout cannot depend on itself
+	InvalidDependsOnRolloutMessage = "DependsOnRollout must not reference the rollout itself"
+	// InvalidDependsOnRolloutStepsMessage indicates that the steps, which start once the dependency is Healthy, are missing
+	InvalidDependsOnRolloutStepsMessage = "DependsOnRollout requires Steps to be set"
+	// InvalidQuarantineTrafficRoutingMessage indicates that TrafficRouting, required for QuarantineOnFailure, is missing
+	InvalidQuarantineTrafficRoutingMessage = "QuarantineOnFailure requires TrafficRouting to be set"
+	// InvalidQuarantineReplicasMessage indicates the number of quarantined canary pods needs to be positive
+	InvalidQuarantineReplicasMessage = "QuarantineOnFailure Replicas needs to be greater than 0"
+	// InvalidQuarantineTTLSecondsMessage indicates the TTL of the quarantine needs to be positive
+	InvalidQuarantineTTLSecondsMessage = "QuarantineOnFailure TTLSeconds needs to be greater than 0"
+	// InvalidRebalanceWeightTrafficRoutingMessage indicates that TrafficRouting, required for RebalanceWeightOnReplicaLoss,
+	// is missing
+	InvalidRebalanceWeightTrafficRoutingMessage = "RebalanceWeightOnReplicaLoss requires TrafficRouting to be set"
+	// InvalidAnalysisArgAnnotationMessage indicates that an annotation overriding an analysis argument does not name
+	// the argument
+	InvalidAnalysisArgAnnotationMessage = "Annotation overriding an analysis argument must name the argument after the prefix " + annotations.AnalysisArgAnnotationPrefix
+	// InvalidMetricErrorPolicyMessage indicates that the metric error policy of an analysis is not a supported policy
+	InvalidMetricErrorPolicyMessage = "MetricErrorPolicy must be either Error or Inconclusive"
 )
 
 func ValidateRollout(rollout *v1alpha1.Rollout) field.ErrorList {
 	allErrs := field.ErrorList{}
+	allErrs = append(allErrs, ValidateAnalysisArgAnnotations(rollout, field.NewPath("metadata", "annotations"))...)
 	allErrs = append(allErrs, ValidateRolloutSpec(rollout, field.NewPath("spec"))...)
 	return allErrs
 }
 
+// ValidateAnalysisArgAnnotations checks that the annotations overriding the analysis arguments name the arguments
+func ValidateAnalysisArgAnnotations(rollout *v1alpha1.Rollout, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for key := range rollout.Annotations {
+		if key == annotations.AnalysisArgAnnotationPrefix {
+			allErrs = append(allErrs, field.Invalid(fldPath.Key(key), rollout.Annotations[key], InvalidAnalysisArgAnnotationMessage))
+		}
+	}
+	return allErrs
+}
+
 // ValidateRolloutSpec checks for a valid spec otherwise returns a list of errors.
 func ValidateRolloutSpec(rollout *v1alpha1.Rollout, fldPath *field.Path) field.ErrorList {
 	spec := rollout.Spec
@@ -119,6 +225,10 @@ func ValidateRolloutSpec(rollout *v1alpha1.Rollout, fldPath *field.Path) field.E
 		allErrs = append(allErrs, field.Invalid(fldPath.Child("progressDeadlineSeconds"), progressDeadlineSeconds, "must be greater than minReadySeconds"))
 	}
 
+	if spec.MaxConcurrentAnalysisRuns != nil && *spec.MaxConcurrentAnalysisRuns <= 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxConcurrentAnalysisRuns"), *spec.MaxConcurrentAnalysisRuns, "must be greater than 0"))
+	}
+
 	allErrs = append(allErrs, ValidateRolloutStrategy(rollout, fldPath.Child("strategy"))...)
 
 	return allErrs
@@ -151,10 +261,193 @@ func ValidateRolloutStrategyBlueGreen(rollout *v1alpha1.Rollout, fldPath *field.
 	if blueGreen.ScaleDownDelayRevisionLimit != nil && revisionHistoryLimit < *blueGreen.ScaleDownDelayRevisionLimit {
 		allErrs = append(allErrs, field.Invalid(fldPath.Child("scaleDownDelayRevisionLimit"), *blueGreen.ScaleDownDelayRevisionLimit, ScaleDownLimitLargerThanRevisionLimit))
 	}
+	if blueGreen.RequireManualApproval {
+		if blueGreen.PrePromotionAnalysis == nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("requireManualApproval"), blueGreen.RequireManualApproval, InvalidRequireManualApprovalMessage))
+		}
+		if blueGreen.AutoPromotionSeconds != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("requireManualApproval"), blueGreen.RequireManualApproval, InvalidRequireManualApprovalAutoPromotionMessage))
+		}
+	}
+	allErrs = append(allErrs, invalidStepOnlyAnalysisFields(blueGreen.PrePromotionAnalysis, fldPath.Child("prePromotionAnalysis"))...)
+	allErrs = append(allErrs, invalidStepOnlyAnalysisFields(blueGreen.PostPromotionAnalysis, fldPath.Child("postPromotionAnalysis"))...)
+	if blueGreen.TrafficRouting != nil {
+		if blueGreen.PreviewService == "" {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("previewService"), blueGreen.PreviewService, InvalidBlueGreenTrafficRoutingMessage))
+		}
+		if blueGreen.TrafficRouting.Istio != nil && len(blueGreen.TrafficRouting.Istio.VirtualService.Routes) == 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("trafficRouting").Child("istio").Child("virtualService").Child("routes"), "[]", InvalidIstioRoutesMessage))
+		}
+		if blueGreen.TrafficRouting.Traefik != nil && blueGreen.TrafficRouting.Traefik.IngressRoute == "" {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("trafficRouting").Child("traefik").Child("ingressRoute"), blueGreen.TrafficRouting.Traefik.IngressRoute, InvalidTraefikIngressRouteMessage))
+		}
+		if blueGreen.TrafficRouting.Plugin != nil && blueGreen.TrafficRouting.Plugin.Name == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("trafficRouting").Child("plugin").Child("name"), MissingTrafficRouterPluginNameMessage))
+		}
+		allErrs = append(allErrs, invalidMaxTrafficWeight(blueGreen.TrafficRouting, fldPath.Child("trafficRouting"))...)
+	}
+	maxTrafficWeight := defaults.GetMaxTrafficWeightOrDefault(rollout)
+	if len(blueGreen.PreviewTrafficRamp) > 0 && blueGreen.TrafficRouting == nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("previewTrafficRamp"), len(blueGreen.PreviewTrafficRamp), InvalidPreviewTrafficRampMessage))
+	}
+	for i, step := range blueGreen.PreviewTrafficRamp {
+		stepFldPath := fldPath.Child("previewTrafficRamp").Index(i)
+		if step.Weight < 0 || step.Weight > maxTrafficWeight {
+			allErrs = append(allErrs, field.Invalid(stepFldPath.Child("weight"), step.Weight, InvalidPreviewTrafficRampWeightMessage))
+		}
+		if step.DurationSeconds() < 0 {
+			allErrs = append(allErrs, field.Invalid(stepFldPath.Child("duration"), step.DurationSeconds(), InvalidDurationMessage))
+		}
+	}
+	if blueGreen.PreviewTrafficRampAnalysis != nil && len(blueGreen.PreviewTrafficRamp) == 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("previewTrafficRampAnalysis"), blueGreen.PreviewTrafficRampAnalysis, InvalidPreviewTrafficRampAnalysisMessage))
+	}
+	allErrs = append(allErrs, invalidStepOnlyAnalysisFields(blueGreen.PreviewTrafficRampAnalysis, fldPath.Child("previewTrafficRampAnalysis"))...)
+	if blueGreen.ScaleDownDrain != nil {
+		drainFldPath := fldPath.Child("scaleDownDrain")
+		if blueGreen.ScaleDownDrain.MaxDelaySeconds != nil && *blueGreen.ScaleDownDrain.MaxDelaySeconds <= 0 {
+			allErrs = append(allErrs, field.Invalid(drainFldPath.Child("maxDelaySeconds"), *blueGreen.ScaleDownDrain.MaxDelaySeconds, InvalidScaleDownDrainMaxDelayMessage))
+		}
+		if blueGreen.ScaleDownDelaySeconds != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("scaleDownDelaySeconds"), *blueGreen.ScaleDownDelaySeconds, InvalidScaleDownDrainDelayMessage))
+		}
+		allErrs = append(allErrs, invalidStepOnlyAnalysisFields(&blueGreen.ScaleDownDrain.RolloutAnalysis, drainFldPath)...)
+	}
 	allErrs = append(allErrs, ValidateRolloutStrategyAntiAffinity(blueGreen.AntiAffinity, fldPath.Child("antiAffinity"))...)
 	return allErrs
 }
 
+// invalidStepOnlyAnalysisFields rejects inheritArgsFromStep and aggregation in analyses which are not canary analysis
+// steps
+func invalidStepOnlyAnalysisFields(rolloutAnalysis *v1alpha1.RolloutAnalysis, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if rolloutAnalysis == nil {
+		return allErrs
+	}
+	allErrs = append(allErrs, invalidTemplateChangePolicy(rolloutAnalysis, fldPath)...)
+	allErrs = append(allErrs, invalidAnalysisHooks(rolloutAnalysis.Hooks, fldPath.Child("hooks"))...)
+	allErrs = append(allErrs, invalidMetricErrorPolicy(rolloutAnalysis, fldPath)...)
+	if rolloutAnalysis.InheritArgsFromStep != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("inheritArgsFromStep"), *rolloutAnalysis.InheritArgsFromStep, InvalidInheritArgsFromStepScopeMessage))
+	}
+	if rolloutAnalysis.Aggregation != "" {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("aggregation"), rolloutAnalysis.Aggregation, InvalidAnalysisAggregationScopeMessage))
+	}
+	if len(rolloutAnalysis.Regions) > 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("regions"), rolloutAnalysis.Regions, InvalidAnalysisRegionsMessage))
+	}
+	if rolloutAnalysis.Quorum != 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("quorum"), rolloutAnalysis.Quorum, InvalidAnalysisQuorumAggregationMessage))
+	}
+	return allErrs
+}
+
+// invalidTemplateChangePolicy validates the policy applied to a running AnalysisRun when its templates change
+func invalidTemplateChangePolicy(rolloutAnalysis *v1alpha1.RolloutAnalysis, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	switch rolloutAnalysis.TemplateChangePolicy {
+	case "", v1alpha1.AnalysisTemplateChangePolicyIgnore, v1alpha1.AnalysisTemplateChangePolicyRestart:
+	default:
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("templateChangePolicy"), rolloutAnalysis.TemplateChangePolicy, InvalidTemplateChangePolicyMessage))
+	}
+	return allErrs
+}
+
+// invalidMetricErrorPolicy validates the policy aggregating the errored metrics into the phase of the AnalysisRuns
+func invalidMetricErrorPolicy(rolloutAnalysis *v1alpha1.RolloutAnalysis, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	switch rolloutAnalysis.MetricErrorPolicy {
+	case "", v1alpha1.MetricErrorPolicyError, v1alpha1.MetricErrorPolicyInconclusive:
+	default:
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("metricErrorPolicy"), rolloutAnalysis.MetricErrorPolicy, InvalidMetricErrorPolicyMessage))
+	}
+	return allErrs
+}
+
+// invalidAnalysisHooks validates the URLs of the webhooks notified of the outcome of the AnalysisRuns
+func invalidAnalysisHooks(hooks *v1alpha1.AnalysisHooks, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if hooks == nil {
+		return allErrs
+	}
+	allErrs = append(allErrs, invalidAnalysisWebhook(hooks.OnSuccess, fldPath.Child("onSuccess"))...)
+	allErrs = append(allErrs, invalidAnalysisWebhook(hooks.OnFailure, fldPath.Child("onFailure"))...)
+	return allErrs
+}
+
+func invalidAnalysisWebhook(webhook *v1alpha1.AnalysisWebhook, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if webhook == nil {
+		return allErrs
+	}
+	u, err := url.Parse(webhook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("url"), webhook.URL, InvalidAnalysisWebhookURLMessage))
+	}
+	return allErrs
+}
+
+// invalidAnalysisAggregation validates the aggregation policy of a canary analysis step
+func invalidAnalysisAggregation(rolloutAnalysis *v1alpha1.RolloutAnalysis, fldPath *field.Path) field.ErrorList {
+	allErrs := invalidTemplateChangePolicy(rolloutAnalysis, fldPath)
+	allErrs = append(allErrs, invalidAnalysisHooks(rolloutAnalysis.Hooks, fldPath.Child("hooks"))...)
+	allErrs = append(allErrs, invalidMetricErrorPolicy(rolloutAnalysis, fldPath)...)
+	if rolloutAnalysis.Quorum != 0 && rolloutAnalysis.Aggregation != v1alpha1.AnalysisAggregationQuorum {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("quorum"), rolloutAnalysis.Quorum, InvalidAnalysisQuorumAggregationMessage))
+	}
+	switch rolloutAnalysis.Aggregation {
+	case "":
+		if len(rolloutAnalysis.Regions) > 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("regions"), rolloutAnalysis.Regions, InvalidAnalysisRegionsMessage))
+		}
+		return allErrs
+	case v1alpha1.AnalysisAggregationAll, v1alpha1.AnalysisAggregationAny:
+	case v1alpha1.AnalysisAggregationQuorum:
+		runCount := len(rolloutAnalysis.Regions)
+		if runCount == 0 {
+			runCount = len(rolloutAnalysis.Templates)
+		}
+		if rolloutAnalysis.Quorum < 1 || int(rolloutAnalysis.Quorum) > runCount {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("quorum"), rolloutAnalysis.Quorum, InvalidAnalysisQuorumMessage))
+		}
+	default:
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("aggregation"), rolloutAnalysis.Aggregation, InvalidAnalysisAggregationMessage))
+	}
+	if len(rolloutAnalysis.Templates) == 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("templates"), rolloutAnalysis.Templates, InvalidAnalysisAggregationTemplatesMessage))
+	}
+	regions := map[string]bool{}
+	for i, region := range rolloutAnalysis.Regions {
+		// The region is the value of a label of the AnalysisRun
+		for _, msg := range validationutil.IsValidLabelValue(region) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("regions").Index(i), region, msg))
+		}
+		if region == "" || regions[region] {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("regions").Index(i), region, InvalidAnalysisRegionMessage))
+		}
+		regions[region] = true
+	}
+	return allErrs
+}
+
+// maxALBStickinessDurationSeconds is the maximum duration of the ALB target group stickiness (7 days)
+const maxALBStickinessDurationSeconds = 604800
+
+// invalidALBStickinessConfig validates the durations of an enabled ALB target group stickiness
+func invalidALBStickinessConfig(config *v1alpha1.ALBStickinessConfig, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if !config.Enabled {
+		return allErrs
+	}
+	if config.DurationSeconds < 1 || config.DurationSeconds > maxALBStickinessDurationSeconds {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("durationSeconds"), config.DurationSeconds, InvalidALBStickinessDurationMessage))
+	}
+	if config.CanaryDurationSeconds != nil && (*config.CanaryDurationSeconds < 0 || *config.CanaryDurationSeconds > maxALBStickinessDurationSeconds) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("canaryDurationSeconds"), *config.CanaryDurationSeconds, InvalidALBStickinessCanaryDurationMessage))
+	}
+	return allErrs
+}
+
 func ValidateRolloutStrategyCanary(rollout *v1alpha1.Rollout, fldPath *field.Path) field.ErrorList {
 	canary := rollout.Spec.Strategy.Canary
 	allErrs := field.ErrorList{}
@@ -169,11 +462,25 @@ func ValidateRolloutStrategyCanary(rollout *v1alpha1.Rollout, fldPath *field.Pat
 		if canary.CanaryService == "" {
 			allErrs = append(allErrs, field.Invalid(fldPath.Child("canaryService"), canary.CanaryService, InvalidTrafficRoutingMessage))
 		}
+		if canary.TrafficRouting.AbortRampDownSeconds != nil {
+			allErrs = append(allErrs, apivalidation.ValidateNonnegativeField(int64(*canary.TrafficRouting.AbortRampDownSeconds), fldPath.Child("trafficRouting").Child("abortRampDownSeconds"))...)
+		}
+		allErrs = append(allErrs, invalidMaxTrafficWeight(canary.TrafficRouting, fldPath.Child("trafficRouting"))...)
 	}
+	maxTrafficWeight := defaults.GetMaxTrafficWeightOrDefault(rollout)
 	if canary.TrafficRouting != nil && canary.TrafficRouting.Istio != nil && len(canary.TrafficRouting.Istio.VirtualService.Routes) == 0 {
 		allErrs = append(allErrs, field.Invalid(fldPath.Child("trafficRouting").Child("istio").Child("virtualService").Child("routes"), "[]", InvalidIstioRoutesMessage))
 
 	}
+	if canary.TrafficRouting != nil && canary.TrafficRouting.Traefik != nil && canary.TrafficRouting.Traefik.IngressRoute == "" {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("trafficRouting").Child("traefik").Child("ingressRoute"), canary.TrafficRouting.Traefik.IngressRoute, InvalidTraefikIngressRouteMessage))
+	}
+	if canary.TrafficRouting != nil && canary.TrafficRouting.Plugin != nil && canary.TrafficRouting.Plugin.Name == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("trafficRouting").Child("plugin").Child("name"), MissingTrafficRouterPluginNameMessage))
+	}
+	if canary.TrafficRouting != nil && canary.TrafficRouting.ALB != nil && canary.TrafficRouting.ALB.StickinessConfig != nil {
+		allErrs = append(allErrs, invalidALBStickinessConfig(canary.TrafficRouting.ALB.StickinessConfig, fldPath.Child("trafficRouting").Child("alb").Child("stickinessConfig"))...)
+	}
 	for i, step := range canary.Steps {
 		stepFldPath := fldPath.Child("steps").Index(i)
 		allErrs = append(allErrs, hasMultipleStepsType(step, stepFldPath)...)
@@ -182,20 +489,166 @@ func ValidateRolloutStrategyCanary(rollout *v1alpha1.Rollout, fldPath *field.Pat
 				step.Experiment == nil, step.Pause == nil, step.SetWeight == nil, step.Analysis == nil, step.SetCanaryScale == nil)
 			allErrs = append(allErrs, field.Invalid(stepFldPath, errVal, InvalidStepMessage))
 		}
-		if step.SetWeight != nil && (*step.SetWeight < 0 || *step.SetWeight > 100) {
+		if step.SetWeight != nil && (*step.SetWeight < 0 || *step.SetWeight > maxTrafficWeight) {
 			allErrs = append(allErrs, field.Invalid(stepFldPath.Child("setWeight"), *canary.Steps[i].SetWeight, InvalidSetWeightMessage))
 		}
 		if step.Pause != nil && step.Pause.DurationSeconds() < 0 {
 			allErrs = append(allErrs, field.Invalid(stepFldPath.Child("pause").Child("duration"), step.Pause.DurationSeconds(), InvalidDurationMessage))
 		}
+		if step.Pause != nil && step.Pause.RequireHealthyAnalysis && (step.Pause.Duration == nil || canary.Analysis == nil) {
+			allErrs = append(allErrs, field.Invalid(stepFldPath.Child("pause").Child("requireHealthyAnalysis"), step.Pause.RequireHealthyAnalysis, InvalidRequireHealthyAnalysisMessage))
+		}
 		if rollout.Spec.Strategy.Canary != nil && rollout.Spec.Strategy.Canary.TrafficRouting == nil && step.SetCanaryScale != nil {
 			allErrs = append(allErrs, field.Invalid(stepFldPath.Child("setCanaryScale"), step.SetCanaryScale, InvalidSetCanaryScaleTrafficPolicy))
 		}
+		if step.Analysis != nil && step.Analysis.InheritArgsFromStep != nil {
+			inheritFrom := *step.Analysis.InheritArgsFromStep
+			if inheritFrom < 0 || int(inheritFrom) >= i || canary.Steps[inheritFrom].Analysis == nil {
+				allErrs = append(allErrs, field.Invalid(stepFldPath.Child("analysis").Child("inheritArgsFromStep"), inheritFrom, InvalidInheritArgsFromStepMessage))
+			}
+		}
+		if step.Analysis != nil {
+			allErrs = append(allErrs, invalidAnalysisAggregation(step.Analysis, stepFldPath.Child("analysis"))...)
+		}
+	}
+	if canary.Analysis != nil {
+		allErrs = append(allErrs, invalidStepOnlyAnalysisFields(&canary.Analysis.RolloutAnalysis, fldPath.Child("analysis"))...)
+	}
+	if canary.AbortPolicy != nil && canary.AbortPolicy.RollbackToStep != nil {
+		rollbackToStep := *canary.AbortPolicy.RollbackToStep
+		if rollbackToStep < 0 || int(rollbackToStep) >= len(canary.Steps) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("abortPolicy").Child("rollbackToStep"), rollbackToStep, InvalidRollbackToStepMessage))
+		}
+	}
+	if canary.AbortPolicy != nil && canary.AbortPolicy.AutoRetry != nil {
+		autoRetry := canary.AbortPolicy.AutoRetry
+		autoRetryFldPath := fldPath.Child("abortPolicy").Child("autoRetry")
+		if autoRetry.Limit < 1 {
+			allErrs = append(allErrs, field.Invalid(autoRetryFldPath.Child("limit"), autoRetry.Limit, InvalidAutoRetryLimitMessage))
+		}
+		allErrs = append(allErrs, apivalidation.ValidateNonnegativeField(int64(autoRetry.CooldownSeconds), autoRetryFldPath.Child("cooldownSeconds"))...)
+	}
+	if canary.BakeTimeSeconds() < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("bakeTime"), canary.BakeTimeSeconds(), InvalidDurationMessage))
+	}
+	if canary.WarmupWeight != nil {
+		if *canary.WarmupWeight < 1 || *canary.WarmupWeight > maxTrafficWeight {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("warmupWeight"), *canary.WarmupWeight, InvalidWarmupWeightMessage))
+		}
+		if len(canary.Steps) == 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("warmupWeight"), *canary.WarmupWeight, InvalidWarmupWeightStepsMessage))
+		}
+	}
+	if canary.WarmupReadyCheck != nil {
+		if canary.WarmupWeight == nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("warmupReadyCheck"), canary.WarmupReadyCheck, InvalidWarmupReadyCheckMessage))
+		}
+		allErrs = append(allErrs, apivalidation.ValidateNonnegativeField(int64(canary.WarmupReadyCheck.ReadySeconds), fldPath.Child("warmupReadyCheck").Child("readySeconds"))...)
+	}
+	if canary.ReadinessGate != nil {
+		if canary.TrafficRouting == nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("readinessGate"), canary.ReadinessGate, InvalidReadinessGateTrafficRoutingMessage))
+		}
+		if canary.ReadinessGate.ConditionType == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("readinessGate").Child("conditionType"), MissingReadinessGateConditionTypeMessage))
+		}
+	}
+	if canary.DependsOnRollout != "" {
+		for _, msg := range validationutil.IsDNS1123Subdomain(canary.DependsOnRollout) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("dependsOnRollout"), canary.DependsOnRollout, msg))
+		}
+		if canary.DependsOnRollout == rollout.Name {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("dependsOnRollout"), canary.DependsOnRollout, InvalidDependsOnRolloutMessage))
+		}
+		if len(canary.Steps) == 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("dependsOnRollout"), canary.DependsOnRollout, InvalidDependsOnRolloutStepsMessage))
+		}
+	}
+	if quarantine := canary.QuarantineOnFailure; quarantine != nil {
+		if canary.TrafficRouting == nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("quarantineOnFailure"), quarantine, InvalidQuarantineTrafficRoutingMessage))
+		}
+		if quarantine.Replicas != nil && *quarantine.Replicas < 1 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("quarantineOnFailure").Child("replicas"), *quarantine.Replicas, InvalidQuarantineReplicasMessage))
+		}
+		if quarantine.TTLSeconds != nil && *quarantine.TTLSeconds < 1 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("quarantineOnFailure").Child("ttlSeconds"), *quarantine.TTLSeconds, InvalidQuarantineTTLSecondsMessage))
+		}
+	}
+	if canary.RebalanceWeightOnReplicaLoss && canary.TrafficRouting == nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("rebalanceWeightOnReplicaLoss"), canary.RebalanceWeightOnReplicaLoss, InvalidRebalanceWeightTrafficRoutingMessage))
 	}
 	allErrs = append(allErrs, ValidateRolloutStrategyAntiAffinity(canary.AntiAffinity, fldPath.Child("antiAffinity"))...)
+	allErrs = append(allErrs, ValidateMaxWeightSchedule(canary.MaxWeightSchedule, maxTrafficWeight, fldPath.Child("maxWeightSchedule"))...)
+	return allErrs
+}
+
+// invalidMaxTrafficWeight validates that the maxTrafficWeight of the traffic routing only sets weights finer than
+// percentages with the traffic routers splitting the traffic by relative weights
+func invalidMaxTrafficWeight(trafficRouting *v1alpha1.RolloutTrafficRouting, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if trafficRouting.MaxTrafficWeight == nil {
+		return allErrs
+	}
+	maxTrafficWeight := *trafficRouting.MaxTrafficWeight
+	if maxTrafficWeight < defaults.DefaultMaxTrafficWeight {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxTrafficWeight"), maxTrafficWeight, InvalidMaxTrafficWeightMessage))
+	} else if maxTrafficWeight != defaults.DefaultMaxTrafficWeight && (trafficRouting.Nginx != nil || trafficRouting.ALB != nil || trafficRouting.Plugin != nil) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxTrafficWeight"), maxTrafficWeight, UnsupportedMaxTrafficWeightMessage))
+	}
+	return allErrs
+}
+
+func ValidateMaxWeightSchedule(schedule *v1alpha1.MaxWeightSchedule, maxTrafficWeight int32, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if schedule == nil {
+		return allErrs
+	}
+	if _, err := schedule.Location(); err != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("timeZone"), schedule.TimeZone, InvalidMaxWeightScheduleTimeZoneMessage))
+	}
+	// Each window is split into the minute ranges of a day it covers to detect overlaps
+	ranges := make([][]minuteRange, len(schedule.Windows))
+	for i, window := range schedule.Windows {
+		windowFldPath := fldPath.Child("windows").Index(i)
+		if window.MaxWeight < 0 || window.MaxWeight > maxTrafficWeight {
+			allErrs = append(allErrs, field.Invalid(windowFldPath.Child("maxWeight"), window.MaxWeight, InvalidMaxWeightWindowWeightMessage))
+		}
+		start, end, err := window.Minutes()
+		if err != nil || start == end {
+			allErrs = append(allErrs, field.Invalid(windowFldPath, fmt.Sprintf("%s-%s", window.StartTime, window.EndTime), InvalidMaxWeightWindowTimeMessage))
+			continue
+		}
+		if start < end {
+			ranges[i] = []minuteRange{{start, end}}
+		} else {
+			ranges[i] = []minuteRange{{start, 24 * 60}, {0, end}}
+		}
+		for j := 0; j < i; j++ {
+			if minuteRangesOverlap(ranges[i], ranges[j]) {
+				allErrs = append(allErrs, field.Invalid(windowFldPath, fmt.Sprintf("%s-%s", window.StartTime, window.EndTime), OverlappingMaxWeightWindowsMessage))
+				break
+			}
+		}
+	}
 	return allErrs
 }
 
+type minuteRange struct {
+	start, end int
+}
+
+func minuteRangesOverlap(a, b []minuteRange) bool {
+	for _, r1 := range a {
+		for _, r2 := range b {
+			if r1.start < r2.end && r2.start < r1.end {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func ValidateRolloutStrategyAntiAffinity(antiAffinity *v1alpha1.AntiAffinity, fldPath *field.Path) field.ErrorList {
 	allErrs := field.ErrorList{}
 	if antiAffinity != nil {
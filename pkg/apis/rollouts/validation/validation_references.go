@@ -138,6 +138,15 @@ func ValidateIngress(rollout *v1alpha1.Rollout, ingress v1beta1.Ingress) field.E
 		msg := fmt.Sprintf("ingress `%s` has no rules using service %s backend", ingress.Name, serviceName)
 		allErrs = append(allErrs, field.Invalid(fldPath, ingressName, msg))
 	}
+	if alb := rollout.Spec.Strategy.Canary.TrafficRouting.ALB; alb != nil {
+		rulesPath := field.NewPath("spec", "strategy", "canary", "trafficRouting", "alb", "listenerRules")
+		for i, rule := range alb.ListenerRules {
+			if !ingressutil.HasRuleWithService(&ingress, rule.RootService) {
+				msg := fmt.Sprintf("ingress `%s` has no rules using service %s backend", ingress.Name, rule.RootService)
+				allErrs = append(allErrs, field.Invalid(rulesPath.Index(i).Child("rootService"), rule.RootService, msg))
+			}
+		}
+	}
 	return allErrs
 }
 
@@ -186,6 +186,19 @@ func TestValidateIngress(t *testing.T) {
 		expectedErr := field.Invalid(field.NewPath("spec", "strategy", "canary", "trafficRouting", "alb", "ingress"), ingress.Name, "ingress `alb-ingress` has no rules using service stable-service-name backend")
 		assert.Equal(t, expectedErr.Error(), allErrs[0].Error())
 	})
+
+	t.Run("validate ingress - listener rule failure", func(t *testing.T) {
+		ingress := getIngress()
+		rollout := getRollout()
+		rollout.Spec.Strategy.Canary.TrafficRouting.ALB.ListenerRules = []v1alpha1.ALBListenerRule{
+			{RootService: "stable-service-name"},
+			{RootService: "https-service-name"},
+		}
+		allErrs := ValidateIngress(rollout, ingress)
+		assert.Len(t, allErrs, 1)
+		expectedErr := field.Invalid(field.NewPath("spec", "strategy", "canary", "trafficRouting", "alb", "listenerRules").Index(1).Child("rootService"), "https-service-name", "ingress `alb-ingress` has no rules using service https-service-name backend")
+		assert.Equal(t, expectedErr.Error(), allErrs[0].Error())
+	})
 }
 
 func TestValidateService(t *testing.T) {
@@ -11,6 +11,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/pointer"
 )
 
 func TestValidateRollout(t *testing.T) {
@@ -55,6 +56,27 @@ func TestValidateRollout(t *testing.T) {
 
 	})
 
+	t.Run("invalid maxConcurrentAnalysisRuns", func(t *testing.T) {
+		invalidRo := ro.DeepCopy()
+		maxConcurrentAnalysisRuns := int32(0)
+		invalidRo.Spec.MaxConcurrentAnalysisRuns = &maxConcurrentAnalysisRuns
+		allErrs := ValidateRollout(invalidRo)
+		assert.Equal(t, "spec.maxConcurrentAnalysisRuns", allErrs[0].Field)
+		assert.Equal(t, "must be greater than 0", allErrs[0].Detail)
+	})
+
+	t.Run("invalid analysis arg annotation", func(t *testing.T) {
+		invalidRo := ro.DeepCopy()
+		invalidRo.Annotations = map[string]string{
+			"rollout.argoproj.io/arg-error-threshold": "0.01",
+			"rollout.argoproj.io/arg-":                "0.01",
+		}
+		allErrs := ValidateRollout(invalidRo)
+		assert.Len(t, allErrs, 1)
+		assert.Equal(t, "metadata.annotations[rollout.argoproj.io/arg-]", allErrs[0].Field)
+		assert.Equal(t, InvalidAnalysisArgAnnotationMessage, allErrs[0].Detail)
+	})
+
 	t.Run("successful run", func(t *testing.T) {
 		invalidRo := ro.DeepCopy()
 		invalidRo.Spec.Strategy.Canary = nil
@@ -107,6 +129,158 @@ func TestValidateRolloutStrategyBlueGreen(t *testing.T) {
 	assert.Equal(t, ScaleDownLimitLargerThanRevisionLimit, allErrs[1].Detail)
 }
 
+func TestValidateRolloutStrategyBlueGreenRequireManualApproval(t *testing.T) {
+	autoPromotionSeconds := int32(30)
+	rollout := v1alpha1.Rollout{
+		Spec: v1alpha1.RolloutSpec{
+			Strategy: v1alpha1.RolloutStrategy{
+				BlueGreen: &v1alpha1.BlueGreenStrategy{
+					PreviewService:        "preview",
+					ActiveService:         "active",
+					AutoPromotionSeconds:  &autoPromotionSeconds,
+					RequireManualApproval: true,
+				},
+			},
+		},
+	}
+
+	allErrs := ValidateRolloutStrategyBlueGreen(&rollout, field.NewPath("spec", "strategy", "blueGreen"))
+	assert.Len(t, allErrs, 2)
+	assert.Equal(t, InvalidRequireManualApprovalMessage, allErrs[0].Detail)
+	assert.Equal(t, InvalidRequireManualApprovalAutoPromotionMessage, allErrs[1].Detail)
+
+	rollout.Spec.Strategy.BlueGreen.AutoPromotionSeconds = nil
+	rollout.Spec.Strategy.BlueGreen.PrePromotionAnalysis = &v1alpha1.RolloutAnalysis{
+		Templates: []v1alpha1.RolloutAnalysisTemplate{{TemplateName: "smoke-tests"}},
+	}
+	allErrs = ValidateRolloutStrategyBlueGreen(&rollout, field.NewPath("spec", "strategy", "blueGreen"))
+	assert.Empty(t, allErrs)
+}
+
+func TestValidateRolloutStrategyBlueGreenInheritArgsFromStep(t *testing.T) {
+	inheritArgsFromStep := int32(0)
+	rollout := v1alpha1.Rollout{
+		Spec: v1alpha1.RolloutSpec{
+			Strategy: v1alpha1.RolloutStrategy{
+				BlueGreen: &v1alpha1.BlueGreenStrategy{
+					PreviewService: "preview",
+					ActiveService:  "active",
+					PostPromotionAnalysis: &v1alpha1.RolloutAnalysis{
+						Templates:           []v1alpha1.RolloutAnalysisTemplate{{TemplateName: "smoke-tests"}},
+						InheritArgsFromStep: &inheritArgsFromStep,
+					},
+				},
+			},
+		},
+	}
+
+	allErrs := ValidateRolloutStrategyBlueGreen(&rollout, field.NewPath("spec", "strategy", "blueGreen"))
+	assert.Len(t, allErrs, 1)
+	assert.Equal(t, InvalidInheritArgsFromStepScopeMessage, allErrs[0].Detail)
+	assert.Equal(t, "spec.strategy.blueGreen.postPromotionAnalysis.inheritArgsFromStep", allErrs[0].Field)
+}
+
+func TestValidateRolloutStrategyBlueGreenPreviewTrafficRamp(t *testing.T) {
+	duration := intstr.FromString("2m")
+	invalidDuration := intstr.FromString("2 minutes")
+	rollout := v1alpha1.Rollout{
+		Spec: v1alpha1.RolloutSpec{
+			Strategy: v1alpha1.RolloutStrategy{
+				BlueGreen: &v1alpha1.BlueGreenStrategy{
+					ActiveService: "active",
+					PreviewTrafficRamp: []v1alpha1.PreviewTrafficRampStep{
+						{Weight: 10, Duration: &duration},
+						{Weight: 110, Duration: &invalidDuration},
+					},
+				},
+			},
+		},
+	}
+
+	allErrs := ValidateRolloutStrategyBlueGreen(&rollout, field.NewPath("spec", "strategy", "blueGreen"))
+	assert.Len(t, allErrs, 3)
+	assert.Equal(t, InvalidPreviewTrafficRampMessage, allErrs[0].Detail)
+	assert.Equal(t, InvalidPreviewTrafficRampWeightMessage, allErrs[1].Detail)
+	assert.Equal(t, "spec.strategy.blueGreen.previewTrafficRamp[1].weight", allErrs[1].Field)
+	assert.Equal(t, InvalidDurationMessage, allErrs[2].Detail)
+	assert.Equal(t, "spec.strategy.blueGreen.previewTrafficRamp[1].duration", allErrs[2].Field)
+
+	rollout.Spec.Strategy.BlueGreen.PreviewTrafficRamp = rollout.Spec.Strategy.BlueGreen.PreviewTrafficRamp[:1]
+	rollout.Spec.Strategy.BlueGreen.TrafficRouting = &v1alpha1.RolloutTrafficRouting{
+		SMI: &v1alpha1.SMITrafficRouting{},
+	}
+	allErrs = ValidateRolloutStrategyBlueGreen(&rollout, field.NewPath("spec", "strategy", "blueGreen"))
+	assert.Len(t, allErrs, 1)
+	assert.Equal(t, InvalidBlueGreenTrafficRoutingMessage, allErrs[0].Detail)
+	assert.Equal(t, "spec.strategy.blueGreen.previewService", allErrs[0].Field)
+
+	rollout.Spec.Strategy.BlueGreen.PreviewService = "preview"
+	allErrs = ValidateRolloutStrategyBlueGreen(&rollout, field.NewPath("spec", "strategy", "blueGreen"))
+	assert.Empty(t, allErrs)
+
+	// the weights are relative to the maxTrafficWeight
+	maxTrafficWeight := int32(1000)
+	rollout.Spec.Strategy.BlueGreen.TrafficRouting.MaxTrafficWeight = &maxTrafficWeight
+	rollout.Spec.Strategy.BlueGreen.PreviewTrafficRamp[0].Weight = 110
+	allErrs = ValidateRolloutStrategyBlueGreen(&rollout, field.NewPath("spec", "strategy", "blueGreen"))
+	assert.Empty(t, allErrs)
+}
+
+func TestValidateRolloutStrategyBlueGreenPreviewTrafficRampAnalysis(t *testing.T) {
+	rollout := v1alpha1.Rollout{
+		Spec: v1alpha1.RolloutSpec{
+			Strategy: v1alpha1.RolloutStrategy{
+				BlueGreen: &v1alpha1.BlueGreenStrategy{
+					PreviewService: "preview",
+					ActiveService:  "active",
+					PreviewTrafficRampAnalysis: &v1alpha1.RolloutAnalysis{
+						Templates: []v1alpha1.RolloutAnalysisTemplate{{TemplateName: "success-rate"}},
+					},
+				},
+			},
+		},
+	}
+
+	allErrs := ValidateRolloutStrategyBlueGreen(&rollout, field.NewPath("spec", "strategy", "blueGreen"))
+	assert.Len(t, allErrs, 1)
+	assert.Equal(t, InvalidPreviewTrafficRampAnalysisMessage, allErrs[0].Detail)
+	assert.Equal(t, "spec.strategy.blueGreen.previewTrafficRampAnalysis", allErrs[0].Field)
+}
+
+func TestValidateRolloutStrategyBlueGreenScaleDownDrain(t *testing.T) {
+	maxDelaySeconds := int32(0)
+	scaleDownDelaySeconds := int32(30)
+	rollout := v1alpha1.Rollout{
+		Spec: v1alpha1.RolloutSpec{
+			Strategy: v1alpha1.RolloutStrategy{
+				BlueGreen: &v1alpha1.BlueGreenStrategy{
+					PreviewService: "preview",
+					ActiveService:  "active",
+					ScaleDownDrain: &v1alpha1.ScaleDownDrain{
+						RolloutAnalysis: v1alpha1.RolloutAnalysis{
+							Templates: []v1alpha1.RolloutAnalysisTemplate{{TemplateName: "active-connections"}},
+						},
+						MaxDelaySeconds: &maxDelaySeconds,
+					},
+					ScaleDownDelaySeconds: &scaleDownDelaySeconds,
+				},
+			},
+		},
+	}
+
+	allErrs := ValidateRolloutStrategyBlueGreen(&rollout, field.NewPath("spec", "strategy", "blueGreen"))
+	assert.Len(t, allErrs, 2)
+	assert.Equal(t, InvalidScaleDownDrainMaxDelayMessage, allErrs[0].Detail)
+	assert.Equal(t, "spec.strategy.blueGreen.scaleDownDrain.maxDelaySeconds", allErrs[0].Field)
+	assert.Equal(t, InvalidScaleDownDrainDelayMessage, allErrs[1].Detail)
+	assert.Equal(t, "spec.strategy.blueGreen.scaleDownDelaySeconds", allErrs[1].Field)
+
+	maxDelaySeconds = 1800
+	rollout.Spec.Strategy.BlueGreen.ScaleDownDelaySeconds = nil
+	allErrs = ValidateRolloutStrategyBlueGreen(&rollout, field.NewPath("spec", "strategy", "blueGreen"))
+	assert.Empty(t, allErrs)
+}
+
 func TestValidateRolloutStrategyCanary(t *testing.T) {
 	canaryStrategy := &v1alpha1.CanaryStrategy{
 		CanaryService: "canary",
@@ -133,6 +307,63 @@ func TestValidateRolloutStrategyCanary(t *testing.T) {
 		assert.Equal(t, InvalidTrafficRoutingMessage, allErrs[0].Detail)
 	})
 
+	t.Run("invalid abort ramp down seconds", func(t *testing.T) {
+		invalidRo := ro.DeepCopy()
+		rampDownSeconds := int32(-1)
+		invalidRo.Spec.Strategy.Canary.TrafficRouting.AbortRampDownSeconds = &rampDownSeconds
+		allErrs := ValidateRolloutStrategyCanary(invalidRo, field.NewPath(""))
+		assert.Equal(t, "must be greater than or equal to 0", allErrs[0].Detail)
+	})
+
+	t.Run("missing traefik ingress route", func(t *testing.T) {
+		invalidRo := ro.DeepCopy()
+		invalidRo.Spec.Strategy.Canary.TrafficRouting = &v1alpha1.RolloutTrafficRouting{
+			Traefik: &v1alpha1.TraefikTrafficRouting{},
+		}
+		allErrs := ValidateRolloutStrategyCanary(invalidRo, field.NewPath(""))
+		assert.Equal(t, InvalidTraefikIngressRouteMessage, allErrs[0].Detail)
+	})
+
+	t.Run("missing traffic router plugin name", func(t *testing.T) {
+		invalidRo := ro.DeepCopy()
+		invalidRo.Spec.Strategy.Canary.TrafficRouting = &v1alpha1.RolloutTrafficRouting{
+			Plugin: &v1alpha1.PluginTrafficRouting{},
+		}
+		allErrs := ValidateRolloutStrategyCanary(invalidRo, field.NewPath(""))
+		assert.Equal(t, MissingTrafficRouterPluginNameMessage, allErrs[0].Detail)
+	})
+
+	t.Run("invalid alb stickiness durations", func(t *testing.T) {
+		invalidRo := ro.DeepCopy()
+		invalidRo.Spec.Strategy.Canary.TrafficRouting = &v1alpha1.RolloutTrafficRouting{
+			ALB: &v1alpha1.ALBTrafficRouting{
+				StickinessConfig: &v1alpha1.ALBStickinessConfig{
+					Enabled:               true,
+					CanaryDurationSeconds: pointer.Int64Ptr(604801),
+				},
+			},
+		}
+		allErrs := ValidateRolloutStrategyCanary(invalidRo, field.NewPath(""))
+		assert.Equal(t, InvalidALBStickinessDurationMessage, allErrs[0].Detail)
+		assert.Equal(t, InvalidALBStickinessCanaryDurationMessage, allErrs[1].Detail)
+
+		// the durations are ignored while the stickiness is disabled
+		invalidRo.Spec.Strategy.Canary.TrafficRouting.ALB.StickinessConfig.Enabled = false
+		allErrs = ValidateRolloutStrategyCanary(invalidRo, field.NewPath(""))
+		for _, err := range allErrs {
+			assert.NotContains(t, err.Field, "stickinessConfig")
+		}
+	})
+
+	t.Run("invalid bake time", func(t *testing.T) {
+		invalidRo := ro.DeepCopy()
+		invalidRo.Spec.Strategy.Canary.Steps = []v1alpha1.CanaryStep{{SetWeight: pointer.Int32Ptr(10)}}
+		invalidRo.Spec.Strategy.Canary.BakeTime = v1alpha1.DurationFromString("1z")
+		allErrs := ValidateRolloutStrategyCanary(invalidRo, field.NewPath(""))
+		assert.Len(t, allErrs, 1)
+		assert.Equal(t, InvalidDurationMessage, allErrs[0].Detail)
+	})
+
 	t.Run("invalid setCanaryScale without trafficRouting", func(t *testing.T) {
 		invalidRo := ro.DeepCopy()
 		invalidRo.Spec.Strategy.Canary.Steps[0].SetCanaryScale = &v1alpha1.SetCanaryScale{}
@@ -155,6 +386,57 @@ func TestValidateRolloutStrategyCanary(t *testing.T) {
 		assert.Equal(t, InvalidSetWeightMessage, allErrs[0].Detail)
 	})
 
+	t.Run("set weight relative to max traffic weight", func(t *testing.T) {
+		maxTrafficWeight := int32(1000)
+		setWeight := int32(101)
+		newRo := ro.DeepCopy()
+		newRo.Spec.Strategy.Canary.TrafficRouting.MaxTrafficWeight = &maxTrafficWeight
+		newRo.Spec.Strategy.Canary.Steps[0].SetWeight = &setWeight
+		assert.Empty(t, ValidateRolloutStrategyCanary(newRo, field.NewPath("")))
+
+		setWeight = int32(1001)
+		allErrs := ValidateRolloutStrategyCanary(newRo, field.NewPath(""))
+		assert.Len(t, allErrs, 1)
+		assert.Equal(t, InvalidSetWeightMessage, allErrs[0].Detail)
+	})
+
+	t.Run("invalid max traffic weight", func(t *testing.T) {
+		maxTrafficWeight := int32(50)
+		invalidRo := ro.DeepCopy()
+		invalidRo.Spec.Strategy.Canary.TrafficRouting.MaxTrafficWeight = &maxTrafficWeight
+		allErrs := ValidateRolloutStrategyCanary(invalidRo, field.NewPath(""))
+		assert.Equal(t, InvalidMaxTrafficWeightMessage, allErrs[0].Detail)
+		assert.Equal(t, "[].trafficRouting.maxTrafficWeight", allErrs[0].Field)
+	})
+
+	t.Run("max traffic weight supported by router", func(t *testing.T) {
+		maxTrafficWeight := int32(1000)
+		for _, trafficRouting := range []v1alpha1.RolloutTrafficRouting{
+			{Istio: &v1alpha1.IstioTrafficRouting{VirtualService: v1alpha1.IstioVirtualService{Name: "vsvc", Routes: []string{"primary"}}}},
+			{SMI: &v1alpha1.SMITrafficRouting{}},
+			{Traefik: &v1alpha1.TraefikTrafficRouting{IngressRoute: "ingress-route"}},
+		} {
+			newRo := ro.DeepCopy()
+			newRo.Spec.Strategy.Canary.Steps[0].SetWeight = &maxTrafficWeight
+			newRo.Spec.Strategy.Canary.TrafficRouting = trafficRouting.DeepCopy()
+			newRo.Spec.Strategy.Canary.TrafficRouting.MaxTrafficWeight = &maxTrafficWeight
+			assert.Empty(t, ValidateRolloutStrategyCanary(newRo, field.NewPath("")))
+		}
+		for _, trafficRouting := range []v1alpha1.RolloutTrafficRouting{
+			{Nginx: &v1alpha1.NginxTrafficRouting{StableIngress: "stable-ingress"}},
+			{ALB: &v1alpha1.ALBTrafficRouting{Ingress: "ingress"}},
+			{Plugin: &v1alpha1.PluginTrafficRouting{Name: "router"}},
+		} {
+			invalidRo := ro.DeepCopy()
+			invalidRo.Spec.Strategy.Canary.Steps[0].SetWeight = &maxTrafficWeight
+			invalidRo.Spec.Strategy.Canary.TrafficRouting = trafficRouting.DeepCopy()
+			invalidRo.Spec.Strategy.Canary.TrafficRouting.MaxTrafficWeight = &maxTrafficWeight
+			allErrs := ValidateRolloutStrategyCanary(invalidRo, field.NewPath(""))
+			assert.Len(t, allErrs, 1)
+			assert.Equal(t, UnsupportedMaxTrafficWeightMessage, allErrs[0].Detail)
+		}
+	})
+
 	t.Run("invalid duration set in paused step", func(t *testing.T) {
 		pauseDuration := intstr.FromInt(-1)
 		invalidRo := ro.DeepCopy()
@@ -164,6 +446,412 @@ func TestValidateRolloutStrategyCanary(t *testing.T) {
 		allErrs := ValidateRolloutStrategyCanary(invalidRo, field.NewPath(""))
 		assert.Equal(t, InvalidDurationMessage, allErrs[0].Detail)
 	})
+
+	t.Run("require healthy analysis", func(t *testing.T) {
+		newRo := func(duration *intstr.IntOrString, analysis *v1alpha1.RolloutAnalysisBackground) *v1alpha1.Rollout {
+			r := ro.DeepCopy()
+			r.Spec.Strategy.Canary.Steps[0].Pause = &v1alpha1.RolloutPause{
+				Duration:               duration,
+				RequireHealthyAnalysis: true,
+			}
+			r.Spec.Strategy.Canary.Analysis = analysis
+			return r
+		}
+		analysis := &v1alpha1.RolloutAnalysisBackground{
+			RolloutAnalysis: v1alpha1.RolloutAnalysis{
+				Templates: []v1alpha1.RolloutAnalysisTemplate{{TemplateName: "success-rate"}},
+			},
+		}
+		assert.Empty(t, ValidateRolloutStrategyCanary(newRo(v1alpha1.DurationFromString("10m"), analysis), field.NewPath("")))
+
+		allErrs := ValidateRolloutStrategyCanary(newRo(nil, analysis), field.NewPath(""))
+		assert.Len(t, allErrs, 1)
+		assert.Equal(t, InvalidRequireHealthyAnalysisMessage, allErrs[0].Detail)
+		assert.Equal(t, "[].steps[0].pause.requireHealthyAnalysis", allErrs[0].Field)
+
+		allErrs = ValidateRolloutStrategyCanary(newRo(v1alpha1.DurationFromString("10m"), nil), field.NewPath(""))
+		assert.Len(t, allErrs, 1)
+		assert.Equal(t, InvalidRequireHealthyAnalysisMessage, allErrs[0].Detail)
+	})
+
+	t.Run("abort policy rollback to step", func(t *testing.T) {
+		newRo := func(rollbackToStep int32) *v1alpha1.Rollout {
+			r := ro.DeepCopy()
+			r.Spec.Strategy.Canary.Steps = []v1alpha1.CanaryStep{{SetWeight: pointer.Int32Ptr(10)}, {SetWeight: pointer.Int32Ptr(50)}}
+			r.Spec.Strategy.Canary.AbortPolicy = &v1alpha1.CanaryAbortPolicy{RollbackToStep: &rollbackToStep}
+			return r
+		}
+		assert.Empty(t, ValidateRolloutStrategyCanary(newRo(1), field.NewPath("")))
+
+		for _, rollbackToStep := range []int32{-1, 2} {
+			allErrs := ValidateRolloutStrategyCanary(newRo(rollbackToStep), field.NewPath(""))
+			assert.Len(t, allErrs, 1)
+			assert.Equal(t, InvalidRollbackToStepMessage, allErrs[0].Detail)
+			assert.Equal(t, "[].abortPolicy.rollbackToStep", allErrs[0].Field)
+		}
+	})
+
+	t.Run("abort policy auto retry", func(t *testing.T) {
+		newRo := func(autoRetry v1alpha1.AnalysisAutoRetry) *v1alpha1.Rollout {
+			r := ro.DeepCopy()
+			r.Spec.Strategy.Canary.Steps = []v1alpha1.CanaryStep{{SetWeight: pointer.Int32Ptr(10)}}
+			r.Spec.Strategy.Canary.AbortPolicy = &v1alpha1.CanaryAbortPolicy{AutoRetry: &autoRetry}
+			return r
+		}
+		assert.Empty(t, ValidateRolloutStrategyCanary(newRo(v1alpha1.AnalysisAutoRetry{CooldownSeconds: 300, Limit: 3}), field.NewPath("")))
+
+		allErrs := ValidateRolloutStrategyCanary(newRo(v1alpha1.AnalysisAutoRetry{Limit: 0}), field.NewPath(""))
+		assert.Len(t, allErrs, 1)
+		assert.Equal(t, InvalidAutoRetryLimitMessage, allErrs[0].Detail)
+		assert.Equal(t, "[].abortPolicy.autoRetry.limit", allErrs[0].Field)
+
+		allErrs = ValidateRolloutStrategyCanary(newRo(v1alpha1.AnalysisAutoRetry{CooldownSeconds: -1, Limit: 1}), field.NewPath(""))
+		assert.Len(t, allErrs, 1)
+		assert.Equal(t, "[].abortPolicy.autoRetry.cooldownSeconds", allErrs[0].Field)
+	})
+
+	t.Run("warmup weight", func(t *testing.T) {
+		newRo := func(warmupWeight *int32, readyCheck *v1alpha1.WarmupReadyCheck) *v1alpha1.Rollout {
+			r := ro.DeepCopy()
+			r.Spec.Strategy.Canary.Steps = []v1alpha1.CanaryStep{{SetWeight: pointer.Int32Ptr(20)}}
+			r.Spec.Strategy.Canary.WarmupWeight = warmupWeight
+			r.Spec.Strategy.Canary.WarmupReadyCheck = readyCheck
+			return r
+		}
+		assert.Empty(t, ValidateRolloutStrategyCanary(newRo(pointer.Int32Ptr(1), &v1alpha1.WarmupReadyCheck{ReadySeconds: 30}), field.NewPath("")))
+
+		for _, warmupWeight := range []int32{0, 101} {
+			allErrs := ValidateRolloutStrategyCanary(newRo(pointer.Int32Ptr(warmupWeight), nil), field.NewPath(""))
+			assert.Len(t, allErrs, 1)
+			assert.Equal(t, InvalidWarmupWeightMessage, allErrs[0].Detail)
+			assert.Equal(t, "[].warmupWeight", allErrs[0].Field)
+		}
+
+		noSteps := newRo(pointer.Int32Ptr(1), nil)
+		noSteps.Spec.Strategy.Canary.Steps = nil
+		allErrs := ValidateRolloutStrategyCanary(noSteps, field.NewPath(""))
+		assert.Len(t, allErrs, 1)
+		assert.Equal(t, InvalidWarmupWeightStepsMessage, allErrs[0].Detail)
+
+		allErrs = ValidateRolloutStrategyCanary(newRo(nil, &v1alpha1.WarmupReadyCheck{ReadySeconds: 30}), field.NewPath(""))
+		assert.Len(t, allErrs, 1)
+		assert.Equal(t, InvalidWarmupReadyCheckMessage, allErrs[0].Detail)
+		assert.Equal(t, "[].warmupReadyCheck", allErrs[0].Field)
+
+		allErrs = ValidateRolloutStrategyCanary(newRo(pointer.Int32Ptr(1), &v1alpha1.WarmupReadyCheck{ReadySeconds: -1}), field.NewPath(""))
+		assert.Len(t, allErrs, 1)
+		assert.Equal(t, "[].warmupReadyCheck.readySeconds", allErrs[0].Field)
+	})
+
+	t.Run("readiness gate", func(t *testing.T) {
+		validRo := ro.DeepCopy()
+		validRo.Spec.Strategy.Canary.Steps = []v1alpha1.CanaryStep{{SetWeight: pointer.Int32Ptr(20)}}
+		validRo.Spec.Strategy.Canary.ReadinessGate = &v1alpha1.CanaryReadinessGate{ConditionType: "MeshConfigured"}
+		assert.Empty(t, ValidateRolloutStrategyCanary(validRo, field.NewPath("")))
+
+		noTrafficRouting := validRo.DeepCopy()
+		noTrafficRouting.Spec.Strategy.Canary.TrafficRouting = nil
+		allErrs := ValidateRolloutStrategyCanary(noTrafficRouting, field.NewPath(""))
+		assert.Len(t, allErrs, 1)
+		assert.Equal(t, InvalidReadinessGateTrafficRoutingMessage, allErrs[0].Detail)
+		assert.Equal(t, "[].readinessGate", allErrs[0].Field)
+
+		noConditionType := validRo.DeepCopy()
+		noConditionType.Spec.Strategy.Canary.ReadinessGate.ConditionType = ""
+		allErrs = ValidateRolloutStrategyCanary(noConditionType, field.NewPath(""))
+		assert.Len(t, allErrs, 1)
+		assert.Equal(t, MissingReadinessGateConditionTypeMessage, allErrs[0].Detail)
+		assert.Equal(t, "[].readinessGate.conditionType", allErrs[0].Field)
+	})
+
+	t.Run("depends on rollout", func(t *testing.T) {
+		validRo := ro.DeepCopy()
+		validRo.Name = "checkout"
+		validRo.Spec.Strategy.Canary.Steps = []v1alpha1.CanaryStep{{SetWeight: pointer.Int32Ptr(20)}}
+		validRo.Spec.Strategy.Canary.DependsOnRollout = "payments"
+		assert.Empty(t, ValidateRolloutStrategyCanary(validRo, field.NewPath("")))
+
+		itself := validRo.DeepCopy()
+		itself.Spec.Strategy.Canary.DependsOnRollout = "checkout"
+		allErrs := ValidateRolloutStrategyCanary(itself, field.NewPath(""))
+		assert.Len(t, allErrs, 1)
+		assert.Equal(t, InvalidDependsOnRolloutMessage, allErrs[0].Detail)
+		assert.Equal(t, "[].dependsOnRollout", allErrs[0].Field)
+
+		noSteps := validRo.DeepCopy()
+		noSteps.Spec.Strategy.Canary.Steps = nil
+		allErrs = ValidateRolloutStrategyCanary(noSteps, field.NewPath(""))
+		assert.Len(t, allErrs, 1)
+		assert.Equal(t, InvalidDependsOnRolloutStepsMessage, allErrs[0].Detail)
+
+		invalidName := validRo.DeepCopy()
+		invalidName.Spec.Strategy.Canary.DependsOnRollout = "Payments_API"
+		allErrs = ValidateRolloutStrategyCanary(invalidName, field.NewPath(""))
+		assert.Len(t, allErrs, 1)
+		assert.Equal(t, "[].dependsOnRollout", allErrs[0].Field)
+	})
+
+	t.Run("rebalance weight on replica loss", func(t *testing.T) {
+		validRo := ro.DeepCopy()
+		validRo.Spec.Strategy.Canary.Steps = []v1alpha1.CanaryStep{{SetWeight: pointer.Int32Ptr(20)}}
+		validRo.Spec.Strategy.Canary.RebalanceWeightOnReplicaLoss = true
+		assert.Empty(t, ValidateRolloutStrategyCanary(validRo, field.NewPath("")))
+
+		noTrafficRouting := validRo.DeepCopy()
+		noTrafficRouting.Spec.Strategy.Canary.TrafficRouting = nil
+		allErrs := ValidateRolloutStrategyCanary(noTrafficRouting, field.NewPath(""))
+		assert.Len(t, allErrs, 1)
+		assert.Equal(t, InvalidRebalanceWeightTrafficRoutingMessage, allErrs[0].Detail)
+		assert.Equal(t, "[].rebalanceWeightOnReplicaLoss", allErrs[0].Field)
+	})
+
+	t.Run("quarantine on failure", func(t *testing.T) {
+		validRo := ro.DeepCopy()
+		validRo.Spec.Strategy.Canary.Steps = []v1alpha1.CanaryStep{{SetWeight: pointer.Int32Ptr(20)}}
+		validRo.Spec.Strategy.Canary.QuarantineOnFailure = &v1alpha1.CanaryQuarantine{Replicas: pointer.Int32Ptr(1), TTLSeconds: pointer.Int32Ptr(600)}
+		assert.Empty(t, ValidateRolloutStrategyCanary(validRo, field.NewPath("")))
+
+		noTrafficRouting := validRo.DeepCopy()
+		noTrafficRouting.Spec.Strategy.Canary.TrafficRouting = nil
+		allErrs := ValidateRolloutStrategyCanary(noTrafficRouting, field.NewPath(""))
+		assert.Len(t, allErrs, 1)
+		assert.Equal(t, InvalidQuarantineTrafficRoutingMessage, allErrs[0].Detail)
+		assert.Equal(t, "[].quarantineOnFailure", allErrs[0].Field)
+
+		invalidValues := validRo.DeepCopy()
+		invalidValues.Spec.Strategy.Canary.QuarantineOnFailure = &v1alpha1.CanaryQuarantine{Replicas: pointer.Int32Ptr(0), TTLSeconds: pointer.Int32Ptr(0)}
+		allErrs = ValidateRolloutStrategyCanary(invalidValues, field.NewPath(""))
+		assert.Len(t, allErrs, 2)
+		assert.Equal(t, InvalidQuarantineReplicasMessage, allErrs[0].Detail)
+		assert.Equal(t, "[].quarantineOnFailure.replicas", allErrs[0].Field)
+		assert.Equal(t, InvalidQuarantineTTLSecondsMessage, allErrs[1].Detail)
+		assert.Equal(t, "[].quarantineOnFailure.ttlSeconds", allErrs[1].Field)
+	})
+
+	t.Run("inherit args from step", func(t *testing.T) {
+		newRo := func(inheritArgsFromStep int32) *v1alpha1.Rollout {
+			r := ro.DeepCopy()
+			r.Spec.Strategy.Canary.Steps = []v1alpha1.CanaryStep{
+				{Analysis: &v1alpha1.RolloutAnalysis{Templates: []v1alpha1.RolloutAnalysisTemplate{{TemplateName: "smoke-tests"}}}},
+				{Pause: &v1alpha1.RolloutPause{}},
+				{Analysis: &v1alpha1.RolloutAnalysis{Templates: []v1alpha1.RolloutAnalysisTemplate{{TemplateName: "load-tests"}}}},
+			}
+			r.Spec.Strategy.Canary.Steps[2].Analysis.InheritArgsFromStep = &inheritArgsFromStep
+			return r
+		}
+		assert.Empty(t, ValidateRolloutStrategyCanary(newRo(0), field.NewPath("")))
+		for _, inheritArgsFromStep := range []int32{-1, 1, 2, 3} {
+			allErrs := ValidateRolloutStrategyCanary(newRo(inheritArgsFromStep), field.NewPath(""))
+			assert.Len(t, allErrs, 1)
+			assert.Equal(t, InvalidInheritArgsFromStepMessage, allErrs[0].Detail)
+			assert.Equal(t, "[].steps[2].analysis.inheritArgsFromStep", allErrs[0].Field)
+		}
+	})
+
+	t.Run("inherit args in background analysis", func(t *testing.T) {
+		inheritArgsFromStep := int32(0)
+		invalidRo := ro.DeepCopy()
+		invalidRo.Spec.Strategy.Canary.Steps[0].Pause = &v1alpha1.RolloutPause{}
+		invalidRo.Spec.Strategy.Canary.Analysis = &v1alpha1.RolloutAnalysisBackground{
+			RolloutAnalysis: v1alpha1.RolloutAnalysis{InheritArgsFromStep: &inheritArgsFromStep},
+		}
+		allErrs := ValidateRolloutStrategyCanary(invalidRo, field.NewPath(""))
+		assert.Len(t, allErrs, 1)
+		assert.Equal(t, InvalidInheritArgsFromStepScopeMessage, allErrs[0].Detail)
+	})
+
+	t.Run("analysis aggregation", func(t *testing.T) {
+		newRo := func(aggregation v1alpha1.AnalysisAggregation, templates ...string) *v1alpha1.Rollout {
+			r := ro.DeepCopy()
+			analysis := &v1alpha1.RolloutAnalysis{Aggregation: aggregation}
+			for _, template := range templates {
+				analysis.Templates = append(analysis.Templates, v1alpha1.RolloutAnalysisTemplate{TemplateName: template})
+			}
+			r.Spec.Strategy.Canary.Steps = []v1alpha1.CanaryStep{{Analysis: analysis}}
+			return r
+		}
+		assert.Empty(t, ValidateRolloutStrategyCanary(newRo(v1alpha1.AnalysisAggregationAll, "smoke-tests", "load-tests"), field.NewPath("")))
+		assert.Empty(t, ValidateRolloutStrategyCanary(newRo(v1alpha1.AnalysisAggregationAny, "smoke-tests", "load-tests"), field.NewPath("")))
+
+		allErrs := ValidateRolloutStrategyCanary(newRo("Majority", "smoke-tests", "load-tests"), field.NewPath(""))
+		assert.Len(t, allErrs, 1)
+		assert.Equal(t, InvalidAnalysisAggregationMessage, allErrs[0].Detail)
+		assert.Equal(t, "[].steps[0].analysis.aggregation", allErrs[0].Field)
+
+		allErrs = ValidateRolloutStrategyCanary(newRo(v1alpha1.AnalysisAggregationAll), field.NewPath(""))
+		assert.Len(t, allErrs, 1)
+		assert.Equal(t, InvalidAnalysisAggregationTemplatesMessage, allErrs[0].Detail)
+		assert.Equal(t, "[].steps[0].analysis.templates", allErrs[0].Field)
+	})
+
+	t.Run("template change policy", func(t *testing.T) {
+		newRo := func(policy v1alpha1.AnalysisTemplateChangePolicy) *v1alpha1.Rollout {
+			r := ro.DeepCopy()
+			analysis := v1alpha1.RolloutAnalysis{
+				Templates:            []v1alpha1.RolloutAnalysisTemplate{{TemplateName: "success-rate"}},
+				TemplateChangePolicy: policy,
+			}
+			r.Spec.Strategy.Canary.Steps = []v1alpha1.CanaryStep{{Analysis: analysis.DeepCopy()}}
+			r.Spec.Strategy.Canary.Analysis = &v1alpha1.RolloutAnalysisBackground{RolloutAnalysis: analysis}
+			return r
+		}
+		assert.Empty(t, ValidateRolloutStrategyCanary(newRo(v1alpha1.AnalysisTemplateChangePolicyIgnore), field.NewPath("")))
+		assert.Empty(t, ValidateRolloutStrategyCanary(newRo(v1alpha1.AnalysisTemplateChangePolicyRestart), field.NewPath("")))
+
+		allErrs := ValidateRolloutStrategyCanary(newRo("Resume"), field.NewPath(""))
+		assert.Len(t, allErrs, 2)
+		assert.Equal(t, InvalidTemplateChangePolicyMessage, allErrs[0].Detail)
+		assert.Equal(t, "[].steps[0].analysis.templateChangePolicy", allErrs[0].Field)
+		assert.Equal(t, InvalidTemplateChangePolicyMessage, allErrs[1].Detail)
+		assert.Equal(t, "[].analysis.templateChangePolicy", allErrs[1].Field)
+	})
+
+	t.Run("analysis hooks", func(t *testing.T) {
+		newRo := func(onSuccessURL, onFailureURL string) *v1alpha1.Rollout {
+			r := ro.DeepCopy()
+			analysis := v1alpha1.RolloutAnalysis{
+				Templates: []v1alpha1.RolloutAnalysisTemplate{{TemplateName: "success-rate"}},
+				Hooks: &v1alpha1.AnalysisHooks{
+					OnSuccess: &v1alpha1.AnalysisWebhook{URL: onSuccessURL},
+					OnFailure: &v1alpha1.AnalysisWebhook{URL: onFailureURL},
+				},
+			}
+			r.Spec.Strategy.Canary.Steps = []v1alpha1.CanaryStep{{Analysis: analysis.DeepCopy()}}
+			r.Spec.Strategy.Canary.Analysis = &v1alpha1.RolloutAnalysisBackground{RolloutAnalysis: analysis}
+			return r
+		}
+		assert.Empty(t, ValidateRolloutStrategyCanary(newRo("https://ci.example.com/success", "http://ci.example.com/failure"), field.NewPath("")))
+
+		allErrs := ValidateRolloutStrategyCanary(newRo("ci.example.com/success", "ftp://ci.example.com/failure"), field.NewPath(""))
+		assert.Len(t, allErrs, 4)
+		assert.Equal(t, InvalidAnalysisWebhookURLMessage, allErrs[0].Detail)
+		assert.Equal(t, "[].steps[0].analysis.hooks.onSuccess.url", allErrs[0].Field)
+		assert.Equal(t, "[].steps[0].analysis.hooks.onFailure.url", allErrs[1].Field)
+		assert.Equal(t, "[].analysis.hooks.onSuccess.url", allErrs[2].Field)
+		assert.Equal(t, "[].analysis.hooks.onFailure.url", allErrs[3].Field)
+	})
+
+	t.Run("analysis metric error policy", func(t *testing.T) {
+		newRo := func(policy v1alpha1.MetricErrorPolicy) *v1alpha1.Rollout {
+			r := ro.DeepCopy()
+			analysis := v1alpha1.RolloutAnalysis{
+				Templates:         []v1alpha1.RolloutAnalysisTemplate{{TemplateName: "success-rate"}},
+				MetricErrorPolicy: policy,
+			}
+			r.Spec.Strategy.Canary.Steps = []v1alpha1.CanaryStep{{Analysis: analysis.DeepCopy()}}
+			r.Spec.Strategy.Canary.Analysis = &v1alpha1.RolloutAnalysisBackground{RolloutAnalysis: analysis}
+			return r
+		}
+		assert.Empty(t, ValidateRolloutStrategyCanary(newRo(v1alpha1.MetricErrorPolicyInconclusive), field.NewPath("")))
+		assert.Empty(t, ValidateRolloutStrategyCanary(newRo(v1alpha1.MetricErrorPolicyError), field.NewPath("")))
+
+		allErrs := ValidateRolloutStrategyCanary(newRo("Ignore"), field.NewPath(""))
+		assert.Len(t, allErrs, 2)
+		assert.Equal(t, InvalidMetricErrorPolicyMessage, allErrs[0].Detail)
+		assert.Equal(t, "[].steps[0].analysis.metricErrorPolicy", allErrs[0].Field)
+		assert.Equal(t, "[].analysis.metricErrorPolicy", allErrs[1].Field)
+	})
+
+	t.Run("analysis quorum", func(t *testing.T) {
+		newRo := func(aggregation v1alpha1.AnalysisAggregation, quorum int32, regions ...string) *v1alpha1.Rollout {
+			r := ro.DeepCopy()
+			r.Spec.Strategy.Canary.Steps = []v1alpha1.CanaryStep{{Analysis: &v1alpha1.RolloutAnalysis{
+				Templates:   []v1alpha1.RolloutAnalysisTemplate{{TemplateName: "success-rate"}},
+				Aggregation: aggregation,
+				Quorum:      quorum,
+				Regions:     regions,
+			}}}
+			return r
+		}
+		assert.Empty(t, ValidateRolloutStrategyCanary(newRo(v1alpha1.AnalysisAggregationQuorum, 2, "us-east-1", "us-west-2", "eu-west-1"), field.NewPath("")))
+		assert.Empty(t, ValidateRolloutStrategyCanary(newRo(v1alpha1.AnalysisAggregationAll, 0, "us-east-1", "us-west-2"), field.NewPath("")))
+		// Without regions, the quorum is bound by the number of templates
+		assert.Empty(t, ValidateRolloutStrategyCanary(newRo(v1alpha1.AnalysisAggregationQuorum, 1), field.NewPath("")))
+
+		for _, quorum := range []int32{0, 4} {
+			allErrs := ValidateRolloutStrategyCanary(newRo(v1alpha1.AnalysisAggregationQuorum, quorum, "us-east-1", "us-west-2", "eu-west-1"), field.NewPath(""))
+			assert.Len(t, allErrs, 1)
+			assert.Equal(t, InvalidAnalysisQuorumMessage, allErrs[0].Detail)
+			assert.Equal(t, "[].steps[0].analysis.quorum", allErrs[0].Field)
+		}
+
+		allErrs := ValidateRolloutStrategyCanary(newRo(v1alpha1.AnalysisAggregationAll, 2, "us-east-1", "us-west-2"), field.NewPath(""))
+		assert.Len(t, allErrs, 1)
+		assert.Equal(t, InvalidAnalysisQuorumAggregationMessage, allErrs[0].Detail)
+
+		allErrs = ValidateRolloutStrategyCanary(newRo("", 0, "us-east-1", "us-west-2"), field.NewPath(""))
+		assert.Len(t, allErrs, 1)
+		assert.Equal(t, InvalidAnalysisRegionsMessage, allErrs[0].Detail)
+		assert.Equal(t, "[].steps[0].analysis.regions", allErrs[0].Field)
+
+		allErrs = ValidateRolloutStrategyCanary(newRo(v1alpha1.AnalysisAggregationAll, 0, "us-east-1", "us-east-1", ""), field.NewPath(""))
+		assert.Len(t, allErrs, 2)
+		assert.Equal(t, InvalidAnalysisRegionMessage, allErrs[0].Detail)
+		assert.Equal(t, "[].steps[0].analysis.regions[1]", allErrs[0].Field)
+		assert.Equal(t, "[].steps[0].analysis.regions[2]", allErrs[1].Field)
+
+		allErrs = ValidateRolloutStrategyCanary(newRo(v1alpha1.AnalysisAggregationAll, 0, "us east"), field.NewPath(""))
+		assert.Len(t, allErrs, 1)
+		assert.Equal(t, "[].steps[0].analysis.regions[0]", allErrs[0].Field)
+	})
+
+	t.Run("aggregation in background analysis", func(t *testing.T) {
+		invalidRo := ro.DeepCopy()
+		invalidRo.Spec.Strategy.Canary.Steps[0].Pause = &v1alpha1.RolloutPause{}
+		invalidRo.Spec.Strategy.Canary.Analysis = &v1alpha1.RolloutAnalysisBackground{
+			RolloutAnalysis: v1alpha1.RolloutAnalysis{
+				Templates:   []v1alpha1.RolloutAnalysisTemplate{{TemplateName: "smoke-tests"}},
+				Aggregation: v1alpha1.AnalysisAggregationAll,
+			},
+		}
+		allErrs := ValidateRolloutStrategyCanary(invalidRo, field.NewPath(""))
+		assert.Len(t, allErrs, 1)
+		assert.Equal(t, InvalidAnalysisAggregationScopeMessage, allErrs[0].Detail)
+		assert.Equal(t, "[].analysis.aggregation", allErrs[0].Field)
+	})
+}
+
+func TestValidateMaxWeightSchedule(t *testing.T) {
+	fldPath := field.NewPath("spec", "strategy", "canary", "maxWeightSchedule")
+	schedule := &v1alpha1.MaxWeightSchedule{
+		TimeZone: "America/New_York",
+		Windows: []v1alpha1.MaxWeightWindow{
+			{StartTime: "09:00", EndTime: "17:00", MaxWeight: 10},
+			{StartTime: "22:00", EndTime: "02:00", MaxWeight: 30},
+		},
+	}
+	assert.Empty(t, ValidateMaxWeightSchedule(schedule, 100, fldPath))
+	assert.Empty(t, ValidateMaxWeightSchedule(nil, 100, fldPath))
+
+	schedule.TimeZone = "Nowhere/Invalid"
+	allErrs := ValidateMaxWeightSchedule(schedule, 100, fldPath)
+	assert.Len(t, allErrs, 1)
+	assert.Equal(t, InvalidMaxWeightScheduleTimeZoneMessage, allErrs[0].Detail)
+	schedule.TimeZone = ""
+
+	schedule.Windows = []v1alpha1.MaxWeightWindow{
+		{StartTime: "9am", EndTime: "17:00", MaxWeight: 10},
+		{StartTime: "10:00", EndTime: "10:00", MaxWeight: 10},
+		{StartTime: "18:00", EndTime: "19:00", MaxWeight: 101},
+	}
+	allErrs = ValidateMaxWeightSchedule(schedule, 100, fldPath)
+	assert.Len(t, allErrs, 3)
+	assert.Equal(t, InvalidMaxWeightWindowTimeMessage, allErrs[0].Detail)
+	assert.Equal(t, InvalidMaxWeightWindowTimeMessage, allErrs[1].Detail)
+	assert.Equal(t, InvalidMaxWeightWindowWeightMessage, allErrs[2].Detail)
+
+	schedule.Windows = []v1alpha1.MaxWeightWindow{
+		{StartTime: "09:00", EndTime: "17:00", MaxWeight: 10},
+		{StartTime: "16:00", EndTime: "18:00", MaxWeight: 20},
+		{StartTime: "23:00", EndTime: "10:00", MaxWeight: 20},
+		{StartTime: "18:00", EndTime: "20:00", MaxWeight: 20},
+	}
+	allErrs = ValidateMaxWeightSchedule(schedule, 100, fldPath)
+	assert.Len(t, allErrs, 2)
+	assert.Equal(t, OverlappingMaxWeightWindowsMessage, allErrs[0].Detail)
+	assert.Equal(t, "spec.strategy.canary.maxWeightSchedule.windows[1]", allErrs[0].Field)
+	assert.Equal(t, OverlappingMaxWeightWindowsMessage, allErrs[1].Detail)
+	assert.Equal(t, "spec.strategy.canary.maxWeightSchedule.windows[2]", allErrs[1].Field)
 }
 
 func TestValidateRolloutStrategyAntiAffinity(t *testing.T) {
@@ -1,33 +1,51 @@
 package abort
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	types "k8s.io/apimachinery/pkg/types"
 
 	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
 	clientset "github.com/argoproj/argo-rollouts/pkg/client/clientset/versioned/typed/rollouts/v1alpha1"
 	"github.com/argoproj/argo-rollouts/pkg/kubectl-argo-rollouts/options"
+	replicasetutil "github.com/argoproj/argo-rollouts/utils/replicaset"
 )
 
 const (
 	abortExample = `
   # Abort a rollout
-  %[1]s abort guestbook`
+  %[1]s abort guestbook
+
+  # Roll a canary rollout back to its second step and pause it
+  %[1]s abort guestbook --rollback-to-step 1`
 
 	abortUsage = `This command stops progressing the current rollout and reverts all steps. The previous ReplicaSet will be active.
 
 Note the 'spec.template' still represents the new rollout version. If the Rollout leaves the aborted state, it will try to go to the new version. 
-Updating the 'spec.template' back to the previous version will fully revert the rollout.`
+Updating the 'spec.template' back to the previous version will fully revert the rollout.
+
+Use '--rollback-to-step' to only revert the steps of a canary rollout after the given step index, as the rollbackToStep of
+an abort policy does. The rollout is paused at that step until it is promoted.`
 )
 
 const (
 	abortPatch = `{"status":{"abort":true}}`
+
+	rollbackToStepBlueGreenError   = "Cannot roll back a bluegreen rollout to a step"
+	rollbackToStepNoStepsError     = "Cannot roll back a rollout without steps to a step"
+	rollbackToStepCompletedError   = "Cannot roll back a rollout which is not updating to a step"
+	rollbackToStepUnreachableError = "Cannot roll back to step %d: the step needs to be before the current step %d"
 )
 
 // NewCmdAbort returns a new instance of an `rollouts abort` command
 func NewCmdAbort(o *options.ArgoRolloutsOptions) *cobra.Command {
+	var (
+		rollbackToStep = int32(-1)
+	)
 	var cmd = &cobra.Command{
 		Use:          "abort ROLLOUT_NAME",
 		Short:        "Abort a rollout",
@@ -41,6 +59,15 @@ func NewCmdAbort(o *options.ArgoRolloutsOptions) *cobra.Command {
 			ns := o.Namespace()
 			rolloutIf := o.RolloutsClientset().ArgoprojV1alpha1().Rollouts(ns)
 			for _, name := range args {
+				if c.Flags().Changed("rollback-to-step") {
+					ro, err := RollbackRolloutToStep(rolloutIf, name, rollbackToStep)
+					if err != nil {
+						return err
+					}
+					fmt.Fprintf(o.Out, "rollout '%s' rolled back to step %d\n", ro.Name, rollbackToStep)
+					fmt.Fprintf(o.Out, "rollout '%s' paused: %s\n", ro.Name, pauseReasons(ro))
+					continue
+				}
 				ro, err := AbortRollout(rolloutIf, name)
 				if err != nil {
 					return err
@@ -50,6 +77,7 @@ func NewCmdAbort(o *options.ArgoRolloutsOptions) *cobra.Command {
 			return nil
 		},
 	}
+	cmd.Flags().Int32Var(&rollbackToStep, "rollback-to-step", -1, "Roll a canary rollout back to an earlier step index and pause it, instead of aborting it")
 	return cmd
 }
 
@@ -57,3 +85,57 @@ func NewCmdAbort(o *options.ArgoRolloutsOptions) *cobra.Command {
 func AbortRollout(rolloutIf clientset.RolloutInterface, name string) (*v1alpha1.Rollout, error) {
 	return rolloutIf.Patch(name, types.MergePatchType, []byte(abortPatch))
 }
+
+// RollbackRolloutToStep returns a canary rollout to an earlier step and pauses it there, like the rollbackToStep of
+// an abort policy after a failed analysis. The analysis runs of the rolled back steps are created again once the
+// rollout is promoted.
+func RollbackRolloutToStep(rolloutIf clientset.RolloutInterface, name string, stepIndex int32) (*v1alpha1.Rollout, error) {
+	ro, err := rolloutIf.Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if ro.Spec.Strategy.Canary == nil {
+		return nil, fmt.Errorf(rollbackToStepBlueGreenError)
+	}
+	if len(ro.Spec.Strategy.Canary.Steps) == 0 {
+		return nil, fmt.Errorf(rollbackToStepNoStepsError)
+	}
+	if ro.Status.StableRS == "" || ro.Status.StableRS == ro.Status.CurrentPodHash {
+		return nil, fmt.Errorf(rollbackToStepCompletedError)
+	}
+	_, currentStepIndex := replicasetutil.GetCurrentCanaryStep(ro)
+	// At this point, the rollout is a canary with steps and GetCurrentCanaryStep returns 0 if the index is not set
+	if stepIndex < 0 || stepIndex >= *currentStepIndex {
+		return nil, fmt.Errorf(rollbackToStepUnreachableError, stepIndex, *currentStepIndex)
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"status": map[string]interface{}{
+			"currentStepIndex": stepIndex,
+			"controllerPause":  true,
+			"pauseConditions": []v1alpha1.PauseCondition{{
+				Reason:    v1alpha1.PauseReasonRollbackToStep,
+				StartTime: metav1.Now(),
+			}},
+			"canary": map[string]interface{}{
+				"currentStepAnalysisRun":             nil,
+				"currentStepAnalysisRunStatus":       nil,
+				"currentStepAnalysisRuns":            nil,
+				"currentBackgroundAnalysisRun":       nil,
+				"currentBackgroundAnalysisRunStatus": nil,
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rolloutIf.Patch(name, types.MergePatchType, patch)
+}
+
+// pauseReasons returns the reasons of the pause conditions of the rollout
+func pauseReasons(ro *v1alpha1.Rollout) string {
+	reasons := make([]string, 0, len(ro.Status.PauseConditions))
+	for _, cond := range ro.Status.PauseConditions {
+		reasons = append(reasons, string(cond.Reason))
+	}
+	return strings.Join(reasons, ", ")
+}
@@ -8,6 +8,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	kubetesting "k8s.io/client-go/testing"
+	"k8s.io/utils/pointer"
 
 	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
 	fakeroclient "github.com/argoproj/argo-rollouts/pkg/client/clientset/versioned/fake"
@@ -78,3 +79,95 @@ func TestAbortCmdError(t *testing.T) {
 	assert.Empty(t, stdout)
 	assert.Equal(t, "Error: rollouts.argoproj.io \"doesnotexist\" not found\n", stderr)
 }
+
+func newCanaryRollout(currentStepIndex int32) *v1alpha1.Rollout {
+	return &v1alpha1.Rollout{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "guestbook",
+			Namespace: "test",
+		},
+		Spec: v1alpha1.RolloutSpec{
+			Strategy: v1alpha1.RolloutStrategy{
+				Canary: &v1alpha1.CanaryStrategy{
+					Steps: []v1alpha1.CanaryStep{
+						{SetWeight: pointer.Int32Ptr(20)},
+						{Pause: &v1alpha1.RolloutPause{}},
+						{SetWeight: pointer.Int32Ptr(50)},
+						{Pause: &v1alpha1.RolloutPause{}},
+					},
+				},
+			},
+		},
+		Status: v1alpha1.RolloutStatus{
+			CurrentStepIndex: pointer.Int32Ptr(currentStepIndex),
+			StableRS:         "stable",
+			CurrentPodHash:   "canary",
+			Canary: v1alpha1.CanaryStatus{
+				CurrentStepAnalysisRun:       "guestbook-canary-2",
+				CurrentBackgroundAnalysisRun: "guestbook-canary",
+			},
+		},
+	}
+}
+
+func TestAbortCmdRollbackToStep(t *testing.T) {
+	tf, o := options.NewFakeArgoRolloutsOptions(newCanaryRollout(2))
+	defer tf.Cleanup()
+	o.RESTClientGetter = tf.WithNamespace("test")
+	cmd := NewCmdAbort(o)
+	o.AddKubectlFlags(cmd)
+	cmd.PersistentPreRunE = o.PersistentPreRunE
+	cmd.SetArgs([]string{"guestbook", "--rollback-to-step", "1", "-n", "test"})
+	err := cmd.Execute()
+	assert.Nil(t, err)
+
+	stdout := o.Out.(*bytes.Buffer).String()
+	stderr := o.ErrOut.(*bytes.Buffer).String()
+	assert.Equal(t, "rollout 'guestbook' rolled back to step 1\nrollout 'guestbook' paused: RollbackToStep\n", stdout)
+	assert.Empty(t, stderr)
+
+	ro, err := o.RolloutsClient.ArgoprojV1alpha1().Rollouts("test").Get("guestbook", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.False(t, ro.Status.Abort)
+	assert.Equal(t, pointer.Int32Ptr(1), ro.Status.CurrentStepIndex)
+	assert.True(t, ro.Status.ControllerPause)
+	assert.Len(t, ro.Status.PauseConditions, 1)
+	assert.Equal(t, v1alpha1.PauseReasonRollbackToStep, ro.Status.PauseConditions[0].Reason)
+	assert.Empty(t, ro.Status.Canary.CurrentStepAnalysisRun)
+	assert.Empty(t, ro.Status.Canary.CurrentBackgroundAnalysisRun)
+}
+
+func TestAbortCmdRollbackToStepError(t *testing.T) {
+	blueGreen := newCanaryRollout(2)
+	blueGreen.Spec.Strategy.Canary = nil
+	blueGreen.Spec.Strategy.BlueGreen = &v1alpha1.BlueGreenStrategy{}
+	noSteps := newCanaryRollout(2)
+	noSteps.Spec.Strategy.Canary.Steps = nil
+	completed := newCanaryRollout(4)
+	completed.Status.StableRS = "canary"
+
+	tests := []struct {
+		ro            *v1alpha1.Rollout
+		step          string
+		expectedError string
+	}{
+		{blueGreen, "1", rollbackToStepBlueGreenError},
+		{noSteps, "1", rollbackToStepNoStepsError},
+		{completed, "1", rollbackToStepCompletedError},
+		{newCanaryRollout(2), "2", "Cannot roll back to step 2: the step needs to be before the current step 2"},
+		{newCanaryRollout(2), "-1", "Cannot roll back to step -1: the step needs to be before the current step 2"},
+	}
+	for _, test := range tests {
+		tf, o := options.NewFakeArgoRolloutsOptions(test.ro)
+		o.RESTClientGetter = tf.WithNamespace("test")
+		cmd := NewCmdAbort(o)
+		o.AddKubectlFlags(cmd)
+		cmd.PersistentPreRunE = o.PersistentPreRunE
+		cmd.SetArgs([]string{"guestbook", "--rollback-to-step", test.step, "-n", "test"})
+		err := cmd.Execute()
+		assert.EqualError(t, err, test.expectedError)
+		stdout := o.Out.(*bytes.Buffer).String()
+		assert.Empty(t, stdout)
+		tf.Cleanup()
+	}
+}
@@ -271,11 +271,19 @@ func NewCmdCreateAnalysisRun(o *options.ArgoRolloutsOptions) *cobra.Command {
 			var run *v1alpha1.AnalysisRun
 
 			if clusterTemplate != nil {
+				clusterTemplate.Spec, err = analysisutil.ExpandMetricMixins(clusterTemplate.Spec, createOptions.getMixinClusterAnalysisTemplate)
+				if err != nil {
+					return err
+				}
 				run, err = analysisutil.NewAnalysisRunFromClusterTemplate(clusterTemplate, templateArgs, name, generateName, ns)
 				if err != nil {
 					return err
 				}
 			} else {
+				template.Spec, err = analysisutil.ExpandMetricMixins(template.Spec, createOptions.getMixinClusterAnalysisTemplate)
+				if err != nil {
+					return err
+				}
 				run, err = analysisutil.NewAnalysisRunFromTemplate(template, templateArgs, name, generateName, ns)
 				if err != nil {
 					return err
@@ -339,6 +347,11 @@ func (c *CreateAnalysisRunOptions) getClusterAnalysisTemplate() (*v1alpha1.Clust
 	}
 }
 
+// getMixinClusterAnalysisTemplate returns the ClusterAnalysisTemplate defining the metric of a mixin
+func (c *CreateAnalysisRunOptions) getMixinClusterAnalysisTemplate(name string) (*v1alpha1.ClusterAnalysisTemplate, error) {
+	return c.RolloutsClientset().ArgoprojV1alpha1().ClusterAnalysisTemplates().Get(name, metav1.GetOptions{})
+}
+
 func (c *CreateAnalysisRunOptions) ParseArgFlags() ([]v1alpha1.Argument, error) {
 	var args []v1alpha1.Argument
 	for _, argFlag := range c.ArgFlags {
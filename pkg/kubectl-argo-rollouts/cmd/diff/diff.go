@@ -0,0 +1,29 @@
+package diff
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/argoproj/argo-rollouts/pkg/kubectl-argo-rollouts/options"
+)
+
+const (
+	diffExample = `
+	# Diff two AnalysisTemplates
+	%[1]s diff analysistemplate success-rate success-rate-v2`
+)
+
+// NewCmdDiff returns a new instance of an `rollouts diff` command
+func NewCmdDiff(o *options.ArgoRolloutsOptions) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:          "diff <analysistemplate> RESOURCE_NAME RESOURCE_NAME",
+		Short:        "Diff two resources",
+		Long:         "This command consists of multiple subcommands which can be used to show the differences between the specs of two resources.",
+		Example:      o.Example(diffExample),
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			return o.UsageErr(c)
+		},
+	}
+	cmd.AddCommand(NewCmdDiffAnalysisTemplate(o))
+	return cmd
+}
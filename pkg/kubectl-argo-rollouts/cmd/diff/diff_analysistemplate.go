@@ -0,0 +1,259 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/argoproj/argo-rollouts/pkg/kubectl-argo-rollouts/options"
+)
+
+const (
+	diffAnalysisTemplateExample = `
+	# Diff two AnalysisTemplates
+	%[1]s diff analysistemplate success-rate success-rate-v2`
+
+	// noValue is displayed in place of a field which is not set
+	noValue = "<none>"
+)
+
+// NewCmdDiffAnalysisTemplate returns a new instance of an `rollouts diff analysistemplate` command
+func NewCmdDiffAnalysisTemplate(o *options.ArgoRolloutsOptions) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:          "analysistemplate TEMPLATE_NAME TEMPLATE_NAME",
+		Aliases:      []string{"at", "analysistemplates"},
+		Short:        "Diff two AnalysisTemplates",
+		Long:         "This command shows the differences between the specs of two AnalysisTemplates, argument by argument and metric by metric.",
+		Example:      o.Example(diffAnalysisTemplateExample),
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return o.UsageErr(c)
+			}
+			templateIf := o.RolloutsClientset().ArgoprojV1alpha1().AnalysisTemplates(o.Namespace())
+			from, err := templateIf.Get(args[0], metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			to, err := templateIf.Get(args[1], metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(o.Out, "--- analysistemplate/%s\n+++ analysistemplate/%s\n", from.Name, to.Name)
+			different, err := PrintAnalysisTemplateSpecDiff(o.Out, from.Spec, to.Spec)
+			if err != nil {
+				return err
+			}
+			if !different {
+				fmt.Fprintf(o.Out, "AnalysisTemplates \"%s\" and \"%s\" are identical\n", from.Name, to.Name)
+			}
+			return nil
+		},
+	}
+	return cmd
+}
+
+// PrintAnalysisTemplateSpecDiff prints the differences between two AnalysisTemplate specs and returns whether they
+// are different. The args and the metrics are matched by name, and the fields of the ones found in both specs are
+// compared one by one.
+func PrintAnalysisTemplateSpecDiff(w io.Writer, from, to v1alpha1.AnalysisTemplateSpec) (bool, error) {
+	different := false
+	argLines, err := diffNamedEntries(from.Args, to.Args)
+	if err != nil {
+		return false, err
+	}
+	metricLines, err := diffNamedEntries(from.Metrics, to.Metrics)
+	if err != nil {
+		return false, err
+	}
+	mixinLines, err := diffEntries(from.MetricMixins, to.MetricMixins)
+	if err != nil {
+		return false, err
+	}
+	for _, section := range []struct {
+		title string
+		lines []string
+	}{
+		{"args", argLines},
+		{"metrics", metricLines},
+		{"metricMixins", mixinLines},
+	} {
+		if len(section.lines) == 0 {
+			continue
+		}
+		different = true
+		fmt.Fprintf(w, "%s:\n", section.title)
+		for _, line := range section.lines {
+			fmt.Fprintln(w, line)
+		}
+	}
+	return different, nil
+}
+
+// namedEntry holds the flattened fields of an entry of a list keyed by name (e.g. a metric)
+type namedEntry struct {
+	name   string
+	fields map[string]string
+}
+
+// diffNamedEntries returns the lines describing the entries removed from, changed in and added to a list keyed by
+// name. The fields of a changed entry are listed with their old and new values, and the fields of an added entry
+// with their values.
+func diffNamedEntries(from, to interface{}) ([]string, error) {
+	fromEntries, err := toNamedEntries(from)
+	if err != nil {
+		return nil, err
+	}
+	toEntries, err := toNamedEntries(to)
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	for _, fromEntry := range fromEntries {
+		toEntry := findNamedEntry(toEntries, fromEntry.name)
+		if toEntry == nil {
+			lines = append(lines, fmt.Sprintf("  - %s", fromEntry.name))
+			continue
+		}
+		fieldLines := diffFields(fromEntry.fields, toEntry.fields)
+		if len(fieldLines) > 0 {
+			lines = append(lines, fmt.Sprintf("  ~ %s", fromEntry.name))
+			lines = append(lines, fieldLines...)
+		}
+	}
+	for _, toEntry := range toEntries {
+		if findNamedEntry(fromEntries, toEntry.name) != nil {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("  + %s", toEntry.name))
+		for _, key := range sortedKeys(toEntry.fields) {
+			lines = append(lines, fmt.Sprintf("      %s: %s", key, toEntry.fields[key]))
+		}
+	}
+	return lines, nil
+}
+
+// diffEntries returns the lines describing the changed fields of a list whose entries have no name, comparing the
+// entries by index
+func diffEntries(from, to interface{}) ([]string, error) {
+	fromFields, err := flattenObject(from)
+	if err != nil {
+		return nil, err
+	}
+	toFields, err := flattenObject(to)
+	if err != nil {
+		return nil, err
+	}
+	return diffFields(fromFields, toFields), nil
+}
+
+// diffFields returns a line for each field whose value differs, sorted by field
+func diffFields(from, to map[string]string) []string {
+	allKeys := make(map[string]string, len(from)+len(to))
+	for key := range from {
+		allKeys[key] = ""
+	}
+	for key := range to {
+		allKeys[key] = ""
+	}
+	var lines []string
+	for _, key := range sortedKeys(allKeys) {
+		fromValue, fromOK := from[key]
+		toValue, toOK := to[key]
+		if fromOK == toOK && fromValue == toValue {
+			continue
+		}
+		if !fromOK {
+			fromValue = noValue
+		}
+		if !toOK {
+			toValue = noValue
+		}
+		lines = append(lines, fmt.Sprintf("      %s: %s -> %s", key, fromValue, toValue))
+	}
+	return lines
+}
+
+// toNamedEntries flattens the fields of each entry of a list keyed by name, other than the name
+func toNamedEntries(list interface{}) ([]namedEntry, error) {
+	data, err := json.Marshal(list)
+	if err != nil {
+		return nil, err
+	}
+	var objs []map[string]interface{}
+	if err := json.Unmarshal(data, &objs); err != nil {
+		return nil, err
+	}
+	entries := make([]namedEntry, 0, len(objs))
+	for _, obj := range objs {
+		name, _ := obj["name"].(string)
+		delete(obj, "name")
+		fields := map[string]string{}
+		flatten("", obj, fields)
+		entries = append(entries, namedEntry{name: name, fields: fields})
+	}
+	return entries, nil
+}
+
+func findNamedEntry(entries []namedEntry, name string) *namedEntry {
+	for i := range entries {
+		if entries[i].name == name {
+			return &entries[i]
+		}
+	}
+	return nil
+}
+
+// flattenObject returns the values of the leaves of an object keyed by their path
+func flattenObject(obj interface{}) (map[string]string, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	fields := map[string]string{}
+	flatten("", value, fields)
+	return fields, nil
+}
+
+// flatten adds the values of the leaves of a value to the fields, keyed by their path (e.g.
+// provider.web.headers[0].key)
+func flatten(path string, value interface{}, fields map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+			flatten(childPath, child, fields)
+		}
+	case []interface{}:
+		for i, child := range v {
+			flatten(fmt.Sprintf("%s[%d]", path, i), child, fields)
+		}
+	case string:
+		fields[path] = strconv.Quote(v)
+	case nil:
+	default:
+		fields[path] = fmt.Sprint(v)
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,179 @@
+package diff
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	options "github.com/argoproj/argo-rollouts/pkg/kubectl-argo-rollouts/options/fake"
+)
+
+func newAnalysisTemplate(name string, spec v1alpha1.AnalysisTemplateSpec) *v1alpha1.AnalysisTemplate {
+	return &v1alpha1.AnalysisTemplate{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "test",
+		},
+		Spec: spec,
+	}
+}
+
+func successRateSpec() v1alpha1.AnalysisTemplateSpec {
+	return v1alpha1.AnalysisTemplateSpec{
+		Args: []v1alpha1.Argument{
+			{Name: "service-name"},
+			{Name: "threshold", Value: pointer.StringPtr("0.95")},
+		},
+		Metrics: []v1alpha1.Metric{
+			{
+				Name:             "success-rate",
+				Interval:         "5m",
+				SuccessCondition: "result[0] >= {{args.threshold}}",
+				Provider: v1alpha1.MetricProvider{
+					Prometheus: &v1alpha1.PrometheusMetric{
+						Address: "http://prometheus.example.com:9090",
+						Query:   `sum(rate(http_requests_total{service="{{args.service-name}}",code!~"5.*"}[5m]))`,
+					},
+				},
+			},
+			{
+				Name:             "latency",
+				SuccessCondition: "result[0] < 500",
+				Provider: v1alpha1.MetricProvider{
+					Prometheus: &v1alpha1.PrometheusMetric{
+						Address: "http://prometheus.example.com:9090",
+						Query:   `histogram_quantile(0.99, http_request_duration_seconds_bucket{service="{{args.service-name}}"})`,
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestDiffCmdUsage(t *testing.T) {
+	tf, o := options.NewFakeArgoRolloutsOptions()
+	defer tf.Cleanup()
+	cmd := NewCmdDiff(o)
+	cmd.PersistentPreRunE = o.PersistentPreRunE
+	cmd.SetArgs([]string{})
+	err := cmd.Execute()
+	assert.Error(t, err)
+	stdout := o.Out.(*bytes.Buffer).String()
+	stderr := o.ErrOut.(*bytes.Buffer).String()
+	assert.Empty(t, stdout)
+	assert.Contains(t, stderr, "Usage:\n  diff <analysistemplate> RESOURCE_NAME RESOURCE_NAME")
+}
+
+func TestDiffAnalysisTemplateCmdUsage(t *testing.T) {
+	tf, o := options.NewFakeArgoRolloutsOptions()
+	defer tf.Cleanup()
+	cmd := NewCmdDiffAnalysisTemplate(o)
+	cmd.PersistentPreRunE = o.PersistentPreRunE
+	cmd.SetArgs([]string{"success-rate"})
+	err := cmd.Execute()
+	assert.Error(t, err)
+	stdout := o.Out.(*bytes.Buffer).String()
+	stderr := o.ErrOut.(*bytes.Buffer).String()
+	assert.Empty(t, stdout)
+	assert.Contains(t, stderr, "Usage:\n  analysistemplate TEMPLATE_NAME TEMPLATE_NAME")
+	assert.Contains(t, stderr, "Aliases:\n  analysistemplate, at, analysistemplates")
+}
+
+func TestDiffAnalysisTemplateCmd(t *testing.T) {
+	newSpec := successRateSpec()
+	newSpec.Args = []v1alpha1.Argument{
+		{Name: "service-name"},
+		{Name: "threshold", Value: pointer.StringPtr("0.99")},
+		{Name: "namespace", Value: pointer.StringPtr("default")},
+	}
+	newSpec.Metrics[0].FailureLimit = 2
+	newSpec.Metrics[0].SuccessCondition = "result[0] > {{args.threshold}}"
+	newSpec.Metrics = append(newSpec.Metrics[:1], v1alpha1.Metric{
+		Name: "error-logs",
+		Provider: v1alpha1.MetricProvider{
+			Web: &v1alpha1.WebMetric{URL: "http://logs.example.com/errors", JSONPath: "{$.count}"},
+		},
+	})
+
+	tf, o := options.NewFakeArgoRolloutsOptions(newAnalysisTemplate("success-rate", successRateSpec()), newAnalysisTemplate("success-rate-v2", newSpec))
+	defer tf.Cleanup()
+	o.RESTClientGetter = tf.WithNamespace("test")
+	cmd := NewCmdDiffAnalysisTemplate(o)
+	o.AddKubectlFlags(cmd)
+	cmd.PersistentPreRunE = o.PersistentPreRunE
+	cmd.SetArgs([]string{"success-rate", "success-rate-v2", "-n", "test"})
+	err := cmd.Execute()
+	assert.NoError(t, err)
+	stdout := o.Out.(*bytes.Buffer).String()
+	stderr := o.ErrOut.(*bytes.Buffer).String()
+	assert.Equal(t, `--- analysistemplate/success-rate
++++ analysistemplate/success-rate-v2
+args:
+  ~ threshold
+      value: "0.95" -> "0.99"
+  + namespace
+      value: "default"
+metrics:
+  ~ success-rate
+      failureLimit: <none> -> 2
+      successCondition: "result[0] >= {{args.threshold}}" -> "result[0] > {{args.threshold}}"
+  - latency
+  + error-logs
+      provider.web.jsonPath: "{$.count}"
+      provider.web.url: "http://logs.example.com/errors"
+`, stdout)
+	assert.Empty(t, stderr)
+}
+
+func TestDiffAnalysisTemplateCmdIdentical(t *testing.T) {
+	tf, o := options.NewFakeArgoRolloutsOptions(newAnalysisTemplate("success-rate", successRateSpec()), newAnalysisTemplate("success-rate-copy", successRateSpec()))
+	defer tf.Cleanup()
+	o.RESTClientGetter = tf.WithNamespace("test")
+	cmd := NewCmdDiffAnalysisTemplate(o)
+	o.AddKubectlFlags(cmd)
+	cmd.PersistentPreRunE = o.PersistentPreRunE
+	cmd.SetArgs([]string{"success-rate", "success-rate-copy", "-n", "test"})
+	err := cmd.Execute()
+	assert.NoError(t, err)
+	stdout := o.Out.(*bytes.Buffer).String()
+	assert.Equal(t, `--- analysistemplate/success-rate
++++ analysistemplate/success-rate-copy
+AnalysisTemplates "success-rate" and "success-rate-copy" are identical
+`, stdout)
+}
+
+func TestDiffAnalysisTemplateCmdNotFound(t *testing.T) {
+	tf, o := options.NewFakeArgoRolloutsOptions(newAnalysisTemplate("success-rate", successRateSpec()))
+	defer tf.Cleanup()
+	o.RESTClientGetter = tf.WithNamespace("test")
+	cmd := NewCmdDiffAnalysisTemplate(o)
+	o.AddKubectlFlags(cmd)
+	cmd.PersistentPreRunE = o.PersistentPreRunE
+	cmd.SetArgs([]string{"success-rate", "doesnotexist", "-n", "test"})
+	err := cmd.Execute()
+	assert.Error(t, err)
+	stdout := o.Out.(*bytes.Buffer).String()
+	stderr := o.ErrOut.(*bytes.Buffer).String()
+	assert.Empty(t, stdout)
+	assert.Equal(t, "Error: analysistemplates.argoproj.io \"doesnotexist\" not found\n", stderr)
+}
+
+func TestPrintAnalysisTemplateSpecDiffMetricMixins(t *testing.T) {
+	from := v1alpha1.AnalysisTemplateSpec{
+		MetricMixins: []v1alpha1.MetricMixin{{ClusterTemplateName: "common", Metric: "error-rate"}},
+	}
+	to := v1alpha1.AnalysisTemplateSpec{
+		MetricMixins: []v1alpha1.MetricMixin{{ClusterTemplateName: "common-v2", Metric: "error-rate"}},
+	}
+	out := &bytes.Buffer{}
+	different, err := PrintAnalysisTemplateSpecDiff(out, from, to)
+	assert.NoError(t, err)
+	assert.True(t, different)
+	assert.Equal(t, `metricMixins:
+      [0].clusterTemplateName: "common" -> "common-v2"
+`, out.String())
+}
@@ -0,0 +1,195 @@
+package status
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/argoproj/argo-rollouts/pkg/kubectl-argo-rollouts/info"
+	"github.com/argoproj/argo-rollouts/pkg/kubectl-argo-rollouts/options"
+	"github.com/argoproj/argo-rollouts/utils/conditions"
+)
+
+const (
+	statusExample = `
+  # Show the status of a rollout
+  %[1]s status guestbook
+
+  # Wait for the rollout to become Healthy or Degraded, giving up after 10 minutes
+  %[1]s status guestbook --watch --timeout 10m`
+
+	statusUsage = `This command shows the status of a rollout: Progressing, Paused, Healthy, Degraded or InvalidSpec.
+
+With --watch, the command waits until the rollout is either Healthy, Degraded or has an invalid spec, printing every
+change of its status. The exit code is 0 when the rollout is Healthy, 2 when it is Degraded (e.g. aborted after a
+failed analysis) or has an invalid spec, and 3 when the timeout is reached.`
+)
+
+const (
+	// ExitCodeDegraded is the exit code of a watched rollout which is Degraded or has an invalid spec
+	ExitCodeDegraded = 2
+	// ExitCodeTimeout is the exit code of a watched rollout which did not complete before the timeout
+	ExitCodeTimeout = 3
+
+	statusHealthy     = "Healthy"
+	statusDegraded    = "Degraded"
+	statusInvalidSpec = "InvalidSpec"
+	statusProgressing = "Progressing"
+)
+
+// ExitError is returned by the status command to exit with a specific code
+type ExitError struct {
+	Code    int
+	Message string
+}
+
+func (e *ExitError) Error() string {
+	return e.Message
+}
+
+// StatusOptions are the options of the status command
+type StatusOptions struct {
+	Watch   bool
+	Timeout time.Duration
+	// PollInterval is the interval at which the rollout is requested while watching
+	PollInterval time.Duration
+
+	options.ArgoRolloutsOptions
+}
+
+// NewCmdStatus returns a new instance of an `rollouts status` command
+func NewCmdStatus(o *options.ArgoRolloutsOptions) *cobra.Command {
+	statusOptions := StatusOptions{
+		PollInterval:        time.Second,
+		ArgoRolloutsOptions: *o,
+	}
+	var cmd = &cobra.Command{
+		Use:          "status ROLLOUT_NAME",
+		Short:        "Show the status of a rollout",
+		Long:         statusUsage,
+		Example:      o.Example(statusExample),
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return o.UsageErr(c)
+			}
+			name := args[0]
+			if !statusOptions.Watch {
+				ro, err := statusOptions.getRollout(name)
+				if err != nil {
+					return err
+				}
+				statusOptions.printStatus(ro, rolloutStatus(ro))
+				return nil
+			}
+			return statusOptions.WatchStatus(name)
+		},
+	}
+	cmd.Flags().BoolVarP(&statusOptions.Watch, "watch", "w", false, "Wait until the rollout is Healthy or Degraded, exiting with a distinct code for each outcome")
+	cmd.Flags().DurationVarP(&statusOptions.Timeout, "timeout", "t", 0, "The length of time to watch before giving up (e.g. 30s, 5m). Zero means wait forever")
+	return cmd
+}
+
+func (o *StatusOptions) getRollout(name string) (*v1alpha1.Rollout, error) {
+	return o.RolloutsClientset().ArgoprojV1alpha1().Rollouts(o.Namespace()).Get(name, metav1.GetOptions{})
+}
+
+// WatchStatus prints the status of the rollout every time it changes, until the rollout is Healthy or Degraded or
+// the timeout is reached
+func (o *StatusOptions) WatchStatus(name string) error {
+	var ro *v1alpha1.Rollout
+	lastStatus := ""
+	condition := func() (bool, error) {
+		var err error
+		ro, err = o.getRollout(name)
+		if err != nil {
+			return false, err
+		}
+		status := rolloutStatus(ro)
+		if status != lastStatus {
+			o.printStatus(ro, status)
+			lastStatus = status
+		}
+		switch status {
+		case statusHealthy, statusDegraded, statusInvalidSpec:
+			return true, nil
+		}
+		return false, nil
+	}
+	var err error
+	if o.Timeout > 0 {
+		err = wait.PollImmediate(o.PollInterval, o.Timeout, condition)
+	} else {
+		err = wait.PollImmediateInfinite(o.PollInterval, condition)
+	}
+	if err == wait.ErrWaitTimeout {
+		return &ExitError{
+			Code:    ExitCodeTimeout,
+			Message: fmt.Sprintf("rollout '%s' is still %s after %s", name, lastStatus, o.Timeout),
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if lastStatus != statusHealthy {
+		return &ExitError{
+			Code:    ExitCodeDegraded,
+			Message: fmt.Sprintf("rollout '%s' is %s", name, lastStatus),
+		}
+	}
+	return nil
+}
+
+// rolloutStatus returns the status of the rollout, which is Progressing until the controller observed its spec
+func rolloutStatus(ro *v1alpha1.Rollout) string {
+	if ro.Status.ObservedGeneration != conditions.ComputeGenerationHash(ro.Spec) {
+		return statusProgressing
+	}
+	return info.RolloutStatusString(ro)
+}
+
+// printStatus prints the status of the rollout, followed by the reasons of a Degraded rollout or an invalid spec
+func (o *StatusOptions) printStatus(ro *v1alpha1.Rollout, status string) {
+	fmt.Fprintln(o.Out, status)
+	if status != statusDegraded && status != statusInvalidSpec {
+		return
+	}
+	for _, cond := range ro.Status.Conditions {
+		switch {
+		case cond.Type == v1alpha1.InvalidSpec,
+			cond.Reason == conditions.RolloutAbortedReason,
+			cond.Reason == conditions.TimedOutReason:
+			fmt.Fprintf(o.Out, "  %s: %s\n", cond.Reason, cond.Message)
+		}
+	}
+	for _, message := range o.analysisFailures(ro) {
+		fmt.Fprintf(o.Out, "  %s\n", message)
+	}
+}
+
+// analysisFailures returns the messages of the unsuccessful AnalysisRuns of the current revision of the rollout
+func (o *StatusOptions) analysisFailures(ro *v1alpha1.Rollout) []string {
+	runs, err := o.RolloutsClientset().ArgoprojV1alpha1().AnalysisRuns(ro.Namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return []string{fmt.Sprintf("failed to list the AnalysisRuns: %v", err)}
+	}
+	var messages []string
+	for _, run := range runs.Items {
+		controllerRef := metav1.GetControllerOf(&run)
+		if controllerRef == nil || controllerRef.UID != ro.UID {
+			continue
+		}
+		if run.Labels[v1alpha1.DefaultRolloutUniqueLabelKey] != ro.Status.CurrentPodHash {
+			continue
+		}
+		switch run.Status.Phase {
+		case v1alpha1.AnalysisPhaseFailed, v1alpha1.AnalysisPhaseError, v1alpha1.AnalysisPhaseInconclusive:
+			messages = append(messages, fmt.Sprintf("AnalysisRun '%s' %s: %s", run.Name, run.Status.Phase, run.Status.Message))
+		}
+	}
+	return messages
+}
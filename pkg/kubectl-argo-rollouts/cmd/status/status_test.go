@@ -0,0 +1,222 @@
+package status
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubetesting "k8s.io/client-go/testing"
+	"k8s.io/utils/pointer"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	fakeroclient "github.com/argoproj/argo-rollouts/pkg/client/clientset/versioned/fake"
+	options "github.com/argoproj/argo-rollouts/pkg/kubectl-argo-rollouts/options/fake"
+	"github.com/argoproj/argo-rollouts/utils/conditions"
+)
+
+func newRollout(status string) *v1alpha1.Rollout {
+	ro := &v1alpha1.Rollout{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "guestbook",
+			Namespace: "test",
+			UID:       "guestbook-uid",
+		},
+		Spec: v1alpha1.RolloutSpec{
+			Replicas: pointer.Int32Ptr(1),
+			Strategy: v1alpha1.RolloutStrategy{
+				Canary: &v1alpha1.CanaryStrategy{},
+			},
+		},
+		Status: v1alpha1.RolloutStatus{
+			Replicas:          1,
+			UpdatedReplicas:   1,
+			AvailableReplicas: 1,
+			CurrentPodHash:    "6c54544bf9",
+			StableRS:          "6c54544bf9",
+		},
+	}
+	ro.Status.ObservedGeneration = conditions.ComputeGenerationHash(ro.Spec)
+	switch status {
+	case statusProgressing:
+		ro.Status.StableRS = "5d9c8f9c77"
+	case statusDegraded:
+		ro.Status.StableRS = "5d9c8f9c77"
+		ro.Status.Abort = true
+		ro.Status.Conditions = []v1alpha1.RolloutCondition{{
+			Type:    v1alpha1.RolloutProgressing,
+			Status:  corev1.ConditionFalse,
+			Reason:  conditions.RolloutAbortedReason,
+			Message: conditions.RolloutAbortedMessage,
+		}}
+	}
+	return ro
+}
+
+func newAnalysisRun(ro *v1alpha1.Rollout, name, podHash string, phase v1alpha1.AnalysisPhase, message string) *v1alpha1.AnalysisRun {
+	return &v1alpha1.AnalysisRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ro.Namespace,
+			Labels:    map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: podHash},
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(ro, v1alpha1.SchemeGroupVersion.WithKind("Rollout")),
+			},
+		},
+		Status: v1alpha1.AnalysisRunStatus{
+			Phase:   phase,
+			Message: message,
+		},
+	}
+}
+
+func TestStatusCmdUsage(t *testing.T) {
+	tf, o := options.NewFakeArgoRolloutsOptions()
+	defer tf.Cleanup()
+	cmd := NewCmdStatus(o)
+	cmd.PersistentPreRunE = o.PersistentPreRunE
+	cmd.SetArgs([]string{})
+	err := cmd.Execute()
+	assert.Error(t, err)
+	stderr := o.ErrOut.(*bytes.Buffer).String()
+	assert.Contains(t, stderr, "Usage:")
+	assert.Contains(t, stderr, "status ROLLOUT_NAME")
+}
+
+func TestStatusCmd(t *testing.T) {
+	tf, o := options.NewFakeArgoRolloutsOptions(newRollout(statusProgressing))
+	defer tf.Cleanup()
+	o.RESTClientGetter = tf.WithNamespace("test")
+	cmd := NewCmdStatus(o)
+	o.AddKubectlFlags(cmd)
+	cmd.PersistentPreRunE = o.PersistentPreRunE
+	cmd.SetArgs([]string{"guestbook", "-n", "test"})
+	err := cmd.Execute()
+	assert.NoError(t, err)
+	assert.Equal(t, "Progressing\n", o.Out.(*bytes.Buffer).String())
+}
+
+func TestStatusCmdNotFound(t *testing.T) {
+	tf, o := options.NewFakeArgoRolloutsOptions()
+	defer tf.Cleanup()
+	cmd := NewCmdStatus(o)
+	o.AddKubectlFlags(cmd)
+	cmd.PersistentPreRunE = o.PersistentPreRunE
+	cmd.SetArgs([]string{"guestbook", "-n", "test"})
+	err := cmd.Execute()
+	assert.Error(t, err)
+	_, isExitErr := err.(*ExitError)
+	assert.False(t, isExitErr)
+}
+
+func TestStatusCmdWatchHealthy(t *testing.T) {
+	tf, o := options.NewFakeArgoRolloutsOptions(newRollout(statusHealthy))
+	defer tf.Cleanup()
+	o.RESTClientGetter = tf.WithNamespace("test")
+	cmd := NewCmdStatus(o)
+	o.AddKubectlFlags(cmd)
+	cmd.PersistentPreRunE = o.PersistentPreRunE
+	cmd.SetArgs([]string{"guestbook", "-n", "test", "--watch", "--timeout", "1m"})
+	err := cmd.Execute()
+	assert.NoError(t, err)
+	assert.Equal(t, "Healthy\n", o.Out.(*bytes.Buffer).String())
+}
+
+func TestStatusCmdWatchDegraded(t *testing.T) {
+	ro := newRollout(statusDegraded)
+	failedRun := newAnalysisRun(ro, "guestbook-6c54544bf9-2-1", "6c54544bf9", v1alpha1.AnalysisPhaseFailed, `metric "success-rate" assessed Failed due to failed (1) > failureLimit (0)`)
+	successfulRun := newAnalysisRun(ro, "guestbook-6c54544bf9-2-0", "6c54544bf9", v1alpha1.AnalysisPhaseSuccessful, "")
+	previousRun := newAnalysisRun(ro, "guestbook-5d9c8f9c77-1-1", "5d9c8f9c77", v1alpha1.AnalysisPhaseFailed, "metric \"success-rate\" assessed Failed")
+	tf, o := options.NewFakeArgoRolloutsOptions(ro, failedRun, successfulRun, previousRun)
+	defer tf.Cleanup()
+	o.RESTClientGetter = tf.WithNamespace("test")
+	cmd := NewCmdStatus(o)
+	o.AddKubectlFlags(cmd)
+	cmd.PersistentPreRunE = o.PersistentPreRunE
+	cmd.SetArgs([]string{"guestbook", "-n", "test", "--watch"})
+	err := cmd.Execute()
+	assert.Error(t, err)
+	exitErr, ok := err.(*ExitError)
+	assert.True(t, ok)
+	assert.Equal(t, ExitCodeDegraded, exitErr.Code)
+	assert.Equal(t, "rollout 'guestbook' is Degraded", exitErr.Error())
+	expectedOut := `Degraded
+  RolloutAborted: Rollout is aborted
+  AnalysisRun 'guestbook-6c54544bf9-2-1' Failed: metric "success-rate" assessed Failed due to failed (1) > failureLimit (0)
+`
+	assert.Equal(t, expectedOut, o.Out.(*bytes.Buffer).String())
+}
+
+func TestWatchStatusUntilHealthy(t *testing.T) {
+	tf, o := options.NewFakeArgoRolloutsOptions()
+	defer tf.Cleanup()
+	// The controller has not observed the spec yet, then the canary progresses and completes
+	unobserved := newRollout(statusHealthy)
+	unobserved.Status.ObservedGeneration = "outdated"
+	updates := []*v1alpha1.Rollout{unobserved, newRollout(statusProgressing), newRollout(statusProgressing), newRollout(statusHealthy)}
+	gets := 0
+	fakeClient := o.RolloutsClient.(*fakeroclient.Clientset)
+	fakeClient.PrependReactor("get", "rollouts", func(action kubetesting.Action) (bool, runtime.Object, error) {
+		ro := updates[gets]
+		if gets < len(updates)-1 {
+			gets++
+		}
+		return true, ro, nil
+	})
+	o.RESTClientGetter = tf.WithNamespace("test")
+
+	statusOptions := StatusOptions{
+		Watch:               true,
+		Timeout:             time.Minute,
+		PollInterval:        time.Millisecond,
+		ArgoRolloutsOptions: *o,
+	}
+	err := statusOptions.WatchStatus("guestbook")
+	assert.NoError(t, err)
+	assert.Equal(t, "Progressing\nHealthy\n", o.Out.(*bytes.Buffer).String())
+}
+
+func TestWatchStatusTimeout(t *testing.T) {
+	tf, o := options.NewFakeArgoRolloutsOptions(newRollout(statusProgressing))
+	defer tf.Cleanup()
+	o.RESTClientGetter = tf.WithNamespace("test")
+	statusOptions := StatusOptions{
+		Watch:               true,
+		Timeout:             20 * time.Millisecond,
+		PollInterval:        time.Millisecond,
+		ArgoRolloutsOptions: *o,
+	}
+	err := statusOptions.WatchStatus("guestbook")
+	assert.Error(t, err)
+	exitErr, ok := err.(*ExitError)
+	assert.True(t, ok)
+	assert.Equal(t, ExitCodeTimeout, exitErr.Code)
+	assert.Equal(t, "rollout 'guestbook' is still Progressing after 20ms", exitErr.Error())
+	assert.Equal(t, "Progressing\n", o.Out.(*bytes.Buffer).String())
+}
+
+func TestWatchStatusInvalidSpec(t *testing.T) {
+	ro := newRollout(statusProgressing)
+	ro.Status.Conditions = []v1alpha1.RolloutCondition{{
+		Type:    v1alpha1.InvalidSpec,
+		Status:  corev1.ConditionTrue,
+		Reason:  conditions.InvalidSpecReason,
+		Message: "The Rollout \"guestbook\" is invalid: spec.strategy.canary.steps: Required value",
+	}}
+	tf, o := options.NewFakeArgoRolloutsOptions(ro)
+	defer tf.Cleanup()
+	o.RESTClientGetter = tf.WithNamespace("test")
+	statusOptions := StatusOptions{
+		Watch:               true,
+		PollInterval:        time.Millisecond,
+		ArgoRolloutsOptions: *o,
+	}
+	err := statusOptions.WatchStatus("guestbook")
+	exitErr, ok := err.(*ExitError)
+	assert.True(t, ok)
+	assert.Equal(t, ExitCodeDegraded, exitErr.Code)
+	assert.Equal(t, "InvalidSpec\n  InvalidSpec: The Rollout \"guestbook\" is invalid: spec.strategy.canary.steps: Required value\n", o.Out.(*bytes.Buffer).String())
+}
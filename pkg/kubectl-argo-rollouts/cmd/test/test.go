@@ -0,0 +1,29 @@
+package test
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/argoproj/argo-rollouts/pkg/kubectl-argo-rollouts/options"
+)
+
+const (
+	testExample = `
+	# Take a single measurement of each metric of an AnalysisTemplate
+	%[1]s test analysistemplate success-rate --argument service-name=guestbook`
+)
+
+// NewCmdTest returns a new instance of an `rollouts test` command
+func NewCmdTest(o *options.ArgoRolloutsOptions) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:          "test <analysistemplate> RESOURCE_NAME",
+		Short:        "Test a resource against the live metric providers",
+		Long:         "This command consists of multiple subcommands which can be used to try out a resource before it is relied upon by a rollout.",
+		Example:      o.Example(testExample),
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			return o.UsageErr(c)
+		},
+	}
+	cmd.AddCommand(NewCmdTestAnalysisTemplate(o))
+	return cmd
+}
@@ -0,0 +1,266 @@
+package test
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/utils/pointer"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/argoproj/argo-rollouts/pkg/kubectl-argo-rollouts/cmd/create"
+	"github.com/argoproj/argo-rollouts/pkg/kubectl-argo-rollouts/options"
+	analysisutil "github.com/argoproj/argo-rollouts/utils/analysis"
+)
+
+const (
+	testAnalysisTemplateExample = `
+	# Take a single measurement of each metric of an AnalysisTemplate
+	%[1]s test analysistemplate success-rate --argument service-name=guestbook
+
+	# Take a single measurement of each metric of a ClusterAnalysisTemplate, giving up after a minute
+	%[1]s test analysistemplate success-rate --global --argument service-name=guestbook --timeout 1m`
+
+	testAnalysisTemplateUsage = `This command smoke tests an AnalysisTemplate against the live metric providers, without affecting any rollout.
+
+It creates a throwaway AnalysisRun from the template which takes a single measurement of each metric right away,
+ignoring the interval, count and limits of the metrics. Once the run completes, the command prints the outcome, value
+and message of each metric and deletes the run. The exit code is non-zero when a metric is not Successful.`
+
+	// noValue is displayed in place of the value of a metric without measurement
+	noValue = "-"
+)
+
+// TestAnalysisTemplateOptions are the options of the test analysistemplate command
+type TestAnalysisTemplateOptions struct {
+	ArgFlags   []string
+	Global     bool
+	InstanceID string
+	Timeout    time.Duration
+	// PollInterval is the interval at which the AnalysisRun is requested until it completes
+	PollInterval time.Duration
+
+	options.ArgoRolloutsOptions
+}
+
+// NewCmdTestAnalysisTemplate returns a new instance of an `rollouts test analysistemplate` command
+func NewCmdTestAnalysisTemplate(o *options.ArgoRolloutsOptions) *cobra.Command {
+	testOptions := TestAnalysisTemplateOptions{
+		PollInterval:        time.Second,
+		ArgoRolloutsOptions: *o,
+	}
+	var cmd = &cobra.Command{
+		Use:          "analysistemplate TEMPLATE_NAME",
+		Aliases:      []string{"at", "analysistemplates"},
+		Short:        "Take a single measurement of each metric of an AnalysisTemplate",
+		Long:         testAnalysisTemplateUsage,
+		Example:      o.Example(testAnalysisTemplateExample),
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return o.UsageErr(c)
+			}
+			return testOptions.TestAnalysisTemplate(args[0])
+		},
+	}
+	cmd.Flags().StringArrayVarP(&testOptions.ArgFlags, "argument", "a", []string{}, "Arguments to the parameter template")
+	cmd.Flags().BoolVar(&testOptions.Global, "global", false, "Use a ClusterAnalysisTemplate instead of a AnalysisTemplate")
+	cmd.Flags().StringVar(&testOptions.InstanceID, "instance-id", "", "Instance-ID for the AnalysisRun")
+	cmd.Flags().DurationVarP(&testOptions.Timeout, "timeout", "t", 5*time.Minute, "The length of time to wait for the measurements before giving up (e.g. 30s, 5m). Zero means wait forever")
+	return cmd
+}
+
+// TestAnalysisTemplate creates an AnalysisRun taking a single measurement of each metric of the template, prints the
+// outcome of the metrics once it completes, and deletes it
+func (o *TestAnalysisTemplateOptions) TestAnalysisTemplate(name string) error {
+	run, err := o.newAnalysisRun(name)
+	if err != nil {
+		return err
+	}
+	ns := o.Namespace()
+	runIf := o.RolloutsClientset().ArgoprojV1alpha1().AnalysisRuns(ns)
+	created, err := runIf.Create(run)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "analysisrun.argoproj.io/%s created\n", created.Name)
+	defer func() {
+		// the Jobs of the job metrics are deleted along with the run
+		backgroundDelete := metav1.DeletePropagationBackground
+		err := runIf.Delete(created.Name, &metav1.DeleteOptions{PropagationPolicy: &backgroundDelete})
+		if err != nil {
+			fmt.Fprintf(o.ErrOut, "failed to delete analysisrun '%s': %v\n", created.Name, err)
+			return
+		}
+		fmt.Fprintf(o.Out, "analysisrun.argoproj.io/%s deleted\n", created.Name)
+	}()
+
+	run = created
+	condition := func() (bool, error) {
+		run, err = runIf.Get(created.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return run.Status.Phase.Completed(), nil
+	}
+	if o.Timeout > 0 {
+		err = wait.PollImmediate(o.PollInterval, o.Timeout, condition)
+	} else {
+		err = wait.PollImmediateInfinite(o.PollInterval, condition)
+	}
+	if err != nil && err != wait.ErrWaitTimeout {
+		return err
+	}
+	o.printMetricResults(run)
+	if err == wait.ErrWaitTimeout {
+		return fmt.Errorf("analysisrun '%s' did not complete within %s", created.Name, o.Timeout)
+	}
+	if run.Status.Phase != v1alpha1.AnalysisPhaseSuccessful {
+		return fmt.Errorf("analysistemplate '%s' completed %s", name, run.Status.Phase)
+	}
+	return nil
+}
+
+// newAnalysisRun returns an AnalysisRun from the template, whose metrics take a single measurement
+func (o *TestAnalysisTemplateOptions) newAnalysisRun(name string) (*v1alpha1.AnalysisRun, error) {
+	args, err := (&create.CreateAnalysisRunOptions{ArgFlags: o.ArgFlags}).ParseArgFlags()
+	if err != nil {
+		return nil, err
+	}
+	ns := o.Namespace()
+	generateName := name + "-test-"
+	var run *v1alpha1.AnalysisRun
+	if o.Global {
+		template, err := o.RolloutsClientset().ArgoprojV1alpha1().ClusterAnalysisTemplates().Get(name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		template.Spec, err = analysisutil.ExpandMetricMixins(template.Spec, o.getMixinClusterAnalysisTemplate)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkArgs(template.Spec.Args, args); err != nil {
+			return nil, err
+		}
+		run, err = analysisutil.NewAnalysisRunFromClusterTemplate(template, args, "", generateName, ns)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		template, err := o.RolloutsClientset().ArgoprojV1alpha1().AnalysisTemplates(ns).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		template.Spec, err = analysisutil.ExpandMetricMixins(template.Spec, o.getMixinClusterAnalysisTemplate)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkArgs(template.Spec.Args, args); err != nil {
+			return nil, err
+		}
+		run, err = analysisutil.NewAnalysisRunFromTemplate(template, args, "", generateName, ns)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for i := range run.Spec.Metrics {
+		run.Spec.Metrics[i] = singleMeasurement(run.Spec.Metrics[i])
+	}
+	if o.InstanceID != "" {
+		run.Labels = map[string]string{
+			v1alpha1.LabelKeyControllerInstanceID: o.InstanceID,
+		}
+	}
+	return run, nil
+}
+
+// getMixinClusterAnalysisTemplate returns the ClusterAnalysisTemplate defining the metric of a mixin
+func (o *TestAnalysisTemplateOptions) getMixinClusterAnalysisTemplate(name string) (*v1alpha1.ClusterAnalysisTemplate, error) {
+	return o.RolloutsClientset().ArgoprojV1alpha1().ClusterAnalysisTemplates().Get(name, metav1.GetOptions{})
+}
+
+// checkArgs returns an error listing the args of the template which are neither set by the template nor by the
+// arguments, or the arguments which are not args of the template
+func checkArgs(templateArgs, args []v1alpha1.Argument) error {
+	passed := map[string]bool{}
+	for _, arg := range args {
+		passed[arg.Name] = true
+	}
+	declared := map[string]bool{}
+	var missing []string
+	for _, arg := range templateArgs {
+		declared[arg.Name] = true
+		if arg.Value == nil && arg.ValueFrom == nil && !passed[arg.Name] {
+			missing = append(missing, arg.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required args: %s (set them with --argument NAME=VALUE)", strings.Join(missing, ", "))
+	}
+	var unknown []string
+	for name := range passed {
+		if !declared[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown args: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// singleMeasurement returns the metric taking a single measurement right away, which decides the outcome of the
+// metric
+func singleMeasurement(metric v1alpha1.Metric) v1alpha1.Metric {
+	metric.Interval = ""
+	metric.DecisionInterval = ""
+	metric.Schedule = ""
+	metric.InitialDelay = ""
+	metric.Count = 1
+	metric.MaxMeasurements = 0
+	metric.MaxMeasurementsPhase = ""
+	metric.FailureLimit = 0
+	metric.InconclusiveLimit = 0
+	metric.ConsecutiveErrorLimit = pointer.Int32Ptr(0)
+	metric.InitialErrorGrace = 0
+	return metric
+}
+
+// printMetricResults prints the phase, the value and the message of the measurement of each metric of the run
+func (o *TestAnalysisTemplateOptions) printMetricResults(run *v1alpha1.AnalysisRun) {
+	w := tabwriter.NewWriter(o.Out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "METRIC\tSTATUS\tVALUE\tMESSAGE\n")
+	for _, metric := range run.Spec.Metrics {
+		status := string(v1alpha1.AnalysisPhasePending)
+		value := noValue
+		message := ""
+		if result := analysisutil.GetResult(run, metric.Name); result != nil {
+			status = string(result.Phase)
+			message = result.Message
+		}
+		if measurement := analysisutil.LastMeasurement(run, metric.Name); measurement != nil {
+			if measurement.Value != "" {
+				value = measurement.Value
+			}
+			if measurement.Message != "" {
+				message = measurement.Message
+			}
+		}
+		// the rows without message do not end with an empty cell, which would be padded with trailing spaces
+		if message == "" {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", metric.Name, status, value)
+		} else {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", metric.Name, status, value, message)
+		}
+	}
+	if run.Status.Message != "" {
+		fmt.Fprintf(w, "\nMessage: %s\n", run.Status.Message)
+	}
+	_ = w.Flush()
+}
@@ -0,0 +1,234 @@
+package test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubetesting "k8s.io/client-go/testing"
+	"k8s.io/utils/pointer"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	fakeroclient "github.com/argoproj/argo-rollouts/pkg/client/clientset/versioned/fake"
+	options "github.com/argoproj/argo-rollouts/pkg/kubectl-argo-rollouts/options/fake"
+)
+
+func newAnalysisTemplate() *v1alpha1.AnalysisTemplate {
+	return &v1alpha1.AnalysisTemplate{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "success-rate",
+			Namespace: "test",
+		},
+		Spec: v1alpha1.AnalysisTemplateSpec{
+			Args: []v1alpha1.Argument{
+				{Name: "service-name"},
+				{Name: "threshold", Value: pointer.StringPtr("0.95")},
+			},
+			Metrics: []v1alpha1.Metric{
+				{
+					Name:             "success-rate",
+					Interval:         "5m",
+					Count:            10,
+					FailureLimit:     3,
+					InitialDelay:     "1m",
+					SuccessCondition: "result[0] >= {{args.threshold}}",
+					Provider: v1alpha1.MetricProvider{
+						Prometheus: &v1alpha1.PrometheusMetric{
+							Address: "http://prometheus.example.com:9090",
+							Query:   `sum(rate(http_requests_total{service="{{args.service-name}}",code!~"5.*"}[5m]))`,
+						},
+					},
+				},
+				{
+					Name:             "error-logs",
+					SuccessCondition: "result < 10",
+					Provider: v1alpha1.MetricProvider{
+						Web: &v1alpha1.WebMetric{URL: "http://logs.example.com/errors", JSONPath: "{$.count}"},
+					},
+				},
+			},
+		},
+	}
+}
+
+// completeOnCreate names the created AnalysisRuns and completes them with the status, recording the created runs
+func completeOnCreate(fakeClient *fakeroclient.Clientset, status *v1alpha1.AnalysisRunStatus, created *[]*v1alpha1.AnalysisRun) {
+	fakeClient.PrependReactor("create", "analysisruns", func(action kubetesting.Action) (handled bool, ret runtime.Object, err error) {
+		run := action.(kubetesting.CreateAction).GetObject().(*v1alpha1.AnalysisRun)
+		run.Name = run.GenerateName + "abcde"
+		if status != nil {
+			run.Status = *status
+		}
+		*created = append(*created, run.DeepCopy())
+		return false, nil, nil
+	})
+}
+
+func TestTestCmdUsage(t *testing.T) {
+	tf, o := options.NewFakeArgoRolloutsOptions()
+	defer tf.Cleanup()
+	cmd := NewCmdTest(o)
+	cmd.PersistentPreRunE = o.PersistentPreRunE
+	cmd.SetArgs([]string{})
+	err := cmd.Execute()
+	assert.Error(t, err)
+	stdout := o.Out.(*bytes.Buffer).String()
+	stderr := o.ErrOut.(*bytes.Buffer).String()
+	assert.Empty(t, stdout)
+	assert.Contains(t, stderr, "Usage:\n  test <analysistemplate> RESOURCE_NAME")
+}
+
+func TestTestAnalysisTemplateCmdUsage(t *testing.T) {
+	tf, o := options.NewFakeArgoRolloutsOptions()
+	defer tf.Cleanup()
+	cmd := NewCmdTestAnalysisTemplate(o)
+	cmd.PersistentPreRunE = o.PersistentPreRunE
+	cmd.SetArgs([]string{})
+	err := cmd.Execute()
+	assert.Error(t, err)
+	stderr := o.ErrOut.(*bytes.Buffer).String()
+	assert.Contains(t, stderr, "Usage:\n  analysistemplate TEMPLATE_NAME")
+}
+
+func TestTestAnalysisTemplateCmd(t *testing.T) {
+	tf, o := options.NewFakeArgoRolloutsOptions(newAnalysisTemplate())
+	defer tf.Cleanup()
+	o.RESTClientGetter = tf.WithNamespace("test")
+	fakeClient := o.RolloutsClient.(*fakeroclient.Clientset)
+	var created []*v1alpha1.AnalysisRun
+	completeOnCreate(fakeClient, &v1alpha1.AnalysisRunStatus{
+		Phase: v1alpha1.AnalysisPhaseSuccessful,
+		MetricResults: []v1alpha1.MetricResult{{
+			Name:         "success-rate",
+			Phase:        v1alpha1.AnalysisPhaseSuccessful,
+			Measurements: []v1alpha1.Measurement{{Phase: v1alpha1.AnalysisPhaseSuccessful, Value: "[0.99]"}},
+		}, {
+			Name:         "error-logs",
+			Phase:        v1alpha1.AnalysisPhaseSuccessful,
+			Measurements: []v1alpha1.Measurement{{Phase: v1alpha1.AnalysisPhaseSuccessful, Value: "2"}},
+		}},
+	}, &created)
+
+	cmd := NewCmdTestAnalysisTemplate(o)
+	o.AddKubectlFlags(cmd)
+	cmd.PersistentPreRunE = o.PersistentPreRunE
+	cmd.SetArgs([]string{"success-rate", "-a", "service-name=guestbook", "-n", "test"})
+	err := cmd.Execute()
+	assert.NoError(t, err)
+	stdout := o.Out.(*bytes.Buffer).String()
+	stderr := o.ErrOut.(*bytes.Buffer).String()
+	assert.Equal(t, `analysisrun.argoproj.io/success-rate-test-abcde created
+METRIC        STATUS      VALUE  MESSAGE
+success-rate  Successful  [0.99]
+error-logs    Successful  2
+analysisrun.argoproj.io/success-rate-test-abcde deleted
+`, stdout)
+	assert.Empty(t, stderr)
+
+	// the metrics take a single measurement right away
+	assert.Len(t, created, 1)
+	metric := created[0].Spec.Metrics[0]
+	assert.Equal(t, int32(1), metric.Count)
+	assert.Empty(t, metric.Interval)
+	assert.Empty(t, metric.InitialDelay)
+	assert.Equal(t, int32(0), metric.FailureLimit)
+	assert.Equal(t, int32(0), *metric.ConsecutiveErrorLimit)
+	assert.Equal(t, "guestbook", *created[0].Spec.Args[0].Value)
+
+	// the run is deleted
+	_, err = fakeClient.ArgoprojV1alpha1().AnalysisRuns("test").Get("success-rate-test-abcde", metav1.GetOptions{})
+	assert.True(t, k8serrors.IsNotFound(err))
+}
+
+func TestTestAnalysisTemplateCmdProviderError(t *testing.T) {
+	tf, o := options.NewFakeArgoRolloutsOptions(newAnalysisTemplate())
+	defer tf.Cleanup()
+	o.RESTClientGetter = tf.WithNamespace("test")
+	fakeClient := o.RolloutsClient.(*fakeroclient.Clientset)
+	var created []*v1alpha1.AnalysisRun
+	completeOnCreate(fakeClient, &v1alpha1.AnalysisRunStatus{
+		Phase:   v1alpha1.AnalysisPhaseError,
+		Message: "metric \"error-logs\" assessed Error due to consecutiveErrors (1) > consecutiveErrorLimit (0)",
+		MetricResults: []v1alpha1.MetricResult{{
+			Name:         "success-rate",
+			Phase:        v1alpha1.AnalysisPhaseSuccessful,
+			Measurements: []v1alpha1.Measurement{{Phase: v1alpha1.AnalysisPhaseSuccessful, Value: "[0.99]"}},
+		}, {
+			Name:         "error-logs",
+			Phase:        v1alpha1.AnalysisPhaseError,
+			Message:      "consecutiveErrors (1) > consecutiveErrorLimit (0)",
+			Measurements: []v1alpha1.Measurement{{Phase: v1alpha1.AnalysisPhaseError, Message: "connection refused"}},
+		}},
+	}, &created)
+
+	cmd := NewCmdTestAnalysisTemplate(o)
+	o.AddKubectlFlags(cmd)
+	cmd.PersistentPreRunE = o.PersistentPreRunE
+	cmd.SetArgs([]string{"success-rate", "-a", "service-name=guestbook", "-n", "test"})
+	err := cmd.Execute()
+	assert.EqualError(t, err, "analysistemplate 'success-rate' completed Error")
+	stdout := o.Out.(*bytes.Buffer).String()
+	assert.Contains(t, stdout, "error-logs    Error       -  connection refused\n")
+	assert.Contains(t, stdout, "Message: metric \"error-logs\" assessed Error")
+	assert.Contains(t, stdout, "analysisrun.argoproj.io/success-rate-test-abcde deleted\n")
+}
+
+func TestTestAnalysisTemplateCmdTimeout(t *testing.T) {
+	tf, o := options.NewFakeArgoRolloutsOptions(newAnalysisTemplate())
+	defer tf.Cleanup()
+	o.RESTClientGetter = tf.WithNamespace("test")
+	fakeClient := o.RolloutsClient.(*fakeroclient.Clientset)
+	var created []*v1alpha1.AnalysisRun
+	completeOnCreate(fakeClient, nil, &created)
+
+	cmd := NewCmdTestAnalysisTemplate(o)
+	o.AddKubectlFlags(cmd)
+	cmd.PersistentPreRunE = o.PersistentPreRunE
+	cmd.SetArgs([]string{"success-rate", "-a", "service-name=guestbook", "-n", "test", "--timeout", "10ms"})
+	err := cmd.Execute()
+	assert.EqualError(t, err, "analysisrun 'success-rate-test-abcde' did not complete within 10ms")
+	stdout := o.Out.(*bytes.Buffer).String()
+	assert.Contains(t, stdout, "success-rate  Pending  -")
+	assert.Contains(t, stdout, "analysisrun.argoproj.io/success-rate-test-abcde deleted\n")
+}
+
+func TestTestAnalysisTemplateCmdArgs(t *testing.T) {
+	tf, o := options.NewFakeArgoRolloutsOptions(newAnalysisTemplate())
+	defer tf.Cleanup()
+	o.RESTClientGetter = tf.WithNamespace("test")
+	fakeClient := o.RolloutsClient.(*fakeroclient.Clientset)
+	var created []*v1alpha1.AnalysisRun
+	completeOnCreate(fakeClient, nil, &created)
+
+	for _, test := range []struct {
+		args []string
+		err  string
+	}{
+		{nil, "missing required args: service-name (set them with --argument NAME=VALUE)"},
+		{[]string{"-a", "service-name=guestbook", "-a", "namespace=default"}, "unknown args: namespace"},
+		{[]string{"-a", "service-name"}, "arguments must be in the form NAME=VALUE"},
+	} {
+		cmd := NewCmdTestAnalysisTemplate(o)
+		o.AddKubectlFlags(cmd)
+		cmd.PersistentPreRunE = o.PersistentPreRunE
+		cmd.SetArgs(append([]string{"success-rate", "-n", "test"}, test.args...))
+		err := cmd.Execute()
+		assert.EqualError(t, err, test.err)
+	}
+	// no run is created
+	assert.Empty(t, created)
+}
+
+func TestTestAnalysisTemplateCmdNotFound(t *testing.T) {
+	tf, o := options.NewFakeArgoRolloutsOptions()
+	defer tf.Cleanup()
+	cmd := NewCmdTestAnalysisTemplate(o)
+	o.AddKubectlFlags(cmd)
+	cmd.PersistentPreRunE = o.PersistentPreRunE
+	cmd.SetArgs([]string{"success-rate", "--global"})
+	err := cmd.Execute()
+	assert.EqualError(t, err, `clusteranalysistemplates.argoproj.io "success-rate" not found`)
+}
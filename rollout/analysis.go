@@ -2,6 +2,7 @@ package rollout
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -16,6 +17,7 @@ import (
 	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
 	analysisutil "github.com/argoproj/argo-rollouts/utils/analysis"
 	"github.com/argoproj/argo-rollouts/utils/annotations"
+	"github.com/argoproj/argo-rollouts/utils/defaults"
 	logutil "github.com/argoproj/argo-rollouts/utils/log"
 	replicasetutil "github.com/argoproj/argo-rollouts/utils/replicaset"
 )
@@ -55,12 +57,21 @@ func (c *Controller) reconcileAnalysisRuns(roCtx rolloutContext) error {
 
 	newCurrentAnalysisRuns := analysisutil.CurrentAnalysisRuns{}
 	rollout := roCtx.Rollout()
+	limiter := newAnalysisRunLimiter(roCtx)
 	if rollout.Spec.Strategy.Canary != nil {
-		stepAnalysisRun, err := c.reconcileStepBasedAnalysisRun(roCtx)
-		if err != nil {
-			return err
+		if step, _ := replicasetutil.GetCurrentCanaryStep(rollout); step != nil && step.Analysis != nil && step.Analysis.Aggregation != "" {
+			aggregatedStepAnalysisRuns, err := c.reconcileAggregatedStepAnalysisRuns(roCtx, limiter)
+			if err != nil {
+				return err
+			}
+			newCurrentAnalysisRuns.CanaryAggregatedStep = aggregatedStepAnalysisRuns
+		} else {
+			stepAnalysisRun, err := c.reconcileStepBasedAnalysisRun(roCtx, limiter)
+			if err != nil {
+				return err
+			}
+			newCurrentAnalysisRuns.CanaryStep = stepAnalysisRun
 		}
-		newCurrentAnalysisRuns.CanaryStep = stepAnalysisRun
 
 		backgroundAnalysisRun, err := c.reconcileBackgroundAnalysisRun(roCtx)
 		if err != nil {
@@ -70,17 +81,29 @@ func (c *Controller) reconcileAnalysisRuns(roCtx rolloutContext) error {
 
 	}
 	if rollout.Spec.Strategy.BlueGreen != nil {
-		prePromotionAr, err := c.reconcilePrePromotionAnalysisRun(roCtx)
+		prePromotionAr, err := c.reconcilePrePromotionAnalysisRun(roCtx, limiter)
 		if err != nil {
 			return err
 		}
 		newCurrentAnalysisRuns.BlueGreenPrePromotion = prePromotionAr
 
-		postPromotionAr, err := c.reconcilePostPromotionAnalysisRun(roCtx)
+		postPromotionAr, err := c.reconcilePostPromotionAnalysisRun(roCtx, limiter)
 		if err != nil {
 			return err
 		}
 		newCurrentAnalysisRuns.BlueGreenPostPromotion = postPromotionAr
+
+		previewTrafficRampAr, err := c.reconcilePreviewTrafficRampAnalysisRun(roCtx, limiter)
+		if err != nil {
+			return err
+		}
+		newCurrentAnalysisRuns.BlueGreenPreviewTrafficRamp = previewTrafficRampAr
+
+		scaleDownDrainAr, err := c.reconcileScaleDownDrainAnalysisRun(roCtx, limiter)
+		if err != nil {
+			return err
+		}
+		newCurrentAnalysisRuns.BlueGreenScaleDownDrain = scaleDownDrainAr
 	}
 	roCtx.SetCurrentAnalysisRuns(newCurrentAnalysisRuns)
 
@@ -170,6 +193,20 @@ func (c *Controller) reconcileAnalysisRunStatusChanges(ctx rolloutContext, currA
 		v1alpha1.RolloutTypePrePromotionLabel,
 	)
 
+	c.emitAnalysisRunStatusChanges(
+		rollout,
+		rollout.Status.BlueGreen.PreviewTrafficRampAnalysisRunStatus,
+		currARs.BlueGreenPreviewTrafficRamp,
+		v1alpha1.RolloutTypePreviewTrafficRampLabel,
+	)
+
+	c.emitAnalysisRunStatusChanges(
+		rollout,
+		rollout.Status.BlueGreen.ScaleDownDrainAnalysisRunStatus,
+		currARs.BlueGreenScaleDownDrain,
+		v1alpha1.RolloutTypeScaleDownDrainLabel,
+	)
+
 	c.emitAnalysisRunStatusChanges(
 		rollout,
 		rollout.Status.Canary.CurrentStepAnalysisRunStatus,
@@ -183,9 +220,19 @@ func (c *Controller) reconcileAnalysisRunStatusChanges(ctx rolloutContext, currA
 		currARs.CanaryBackground,
 		v1alpha1.RolloutTypeBackgroundRunLabel,
 	)
+
+	for _, ar := range currARs.CanaryAggregatedStep {
+		var prevStatus *v1alpha1.RolloutAnalysisRunStatus
+		for i := range rollout.Status.Canary.CurrentStepAnalysisRuns {
+			if rollout.Status.Canary.CurrentStepAnalysisRuns[i].Name == ar.Name {
+				prevStatus = &rollout.Status.Canary.CurrentStepAnalysisRuns[i]
+			}
+		}
+		c.emitAnalysisRunStatusChanges(rollout, prevStatus, ar, v1alpha1.RolloutTypeStepLabel)
+	}
 }
 
-func (c *Controller) reconcilePrePromotionAnalysisRun(roCtx rolloutContext) (*v1alpha1.AnalysisRun, error) {
+func (c *Controller) reconcilePrePromotionAnalysisRun(roCtx rolloutContext, limiter *analysisRunLimiter) (*v1alpha1.AnalysisRun, error) {
 	rollout := roCtx.Rollout()
 	newRS := roCtx.NewRS()
 	currentArs := roCtx.CurrentAnalysisRuns()
@@ -208,7 +255,15 @@ func (c *Controller) reconcilePrePromotionAnalysisRun(roCtx rolloutContext) (*v1
 		return currentAr, nil
 	}
 
+	currentAr, err := c.restartOnTemplateChange(roCtx, rollout.Spec.Strategy.BlueGreen.PrePromotionAnalysis, currentAr)
+	if err != nil {
+		return nil, err
+	}
 	if needsNewAnalysisRun(currentAr, rollout) {
+		if !limiter.tryAcquire() {
+			c.queueAnalysisRun(roCtx, "Pre Promotion AnalysisRun", limiter)
+			return nil, nil
+		}
 		podHash := replicasetutil.GetPodTemplateHash(newRS)
 		instanceID := analysisutil.GetInstanceID(rollout)
 		prePromotionLabels := analysisutil.PrePromotionLabels(podHash, instanceID)
@@ -236,7 +291,7 @@ func needPostPromotionAnalysisRun(rollout *v1alpha1.Rollout, newRS *appsv1.Repli
 	return rollout.Status.StableRS == currentPodHash || activeSelector != currentPodHash || currentPodHash == "" || !annotations.IsSaturated(rollout, newRS)
 }
 
-func (c *Controller) reconcilePostPromotionAnalysisRun(roCtx rolloutContext) (*v1alpha1.AnalysisRun, error) {
+func (c *Controller) reconcilePostPromotionAnalysisRun(roCtx rolloutContext, limiter *analysisRunLimiter) (*v1alpha1.AnalysisRun, error) {
 	rollout := roCtx.Rollout()
 	newRS := roCtx.NewRS()
 	currentArs := roCtx.CurrentAnalysisRuns()
@@ -256,7 +311,15 @@ func (c *Controller) reconcilePostPromotionAnalysisRun(roCtx rolloutContext) (*v
 		return currentAr, nil
 	}
 
+	currentAr, err := c.restartOnTemplateChange(roCtx, rollout.Spec.Strategy.BlueGreen.PostPromotionAnalysis, currentAr)
+	if err != nil {
+		return nil, err
+	}
 	if needsNewAnalysisRun(currentAr, rollout) {
+		if !limiter.tryAcquire() {
+			c.queueAnalysisRun(roCtx, "Post Promotion AnalysisRun", limiter)
+			return nil, nil
+		}
 		podHash := replicasetutil.GetPodTemplateHash(newRS)
 		instanceID := analysisutil.GetInstanceID(rollout)
 		postPromotionLabels := analysisutil.PostPromotionLabels(podHash, instanceID)
@@ -275,6 +338,153 @@ func (c *Controller) reconcilePostPromotionAnalysisRun(roCtx rolloutContext) (*v
 	return currentAr, nil
 }
 
+// reconcilePreviewTrafficRampAnalysisRun runs the analysis of the preview traffic ramp while the traffic is shifted
+// to the preview service. A failed analysis aborts the rollout.
+func (c *Controller) reconcilePreviewTrafficRampAnalysisRun(roCtx rolloutContext, limiter *analysisRunLimiter) (*v1alpha1.AnalysisRun, error) {
+	rollout := roCtx.Rollout()
+	newRS := roCtx.NewRS()
+	currentAr := roCtx.CurrentAnalysisRuns().BlueGreenPreviewTrafficRamp
+	if rollout.Spec.Strategy.BlueGreen.PreviewTrafficRampAnalysis == nil {
+		err := c.cancelAnalysisRuns(roCtx, []*v1alpha1.AnalysisRun{currentAr})
+		return nil, err
+	}
+	roCtx.Log().Info("Reconciling Preview Traffic Ramp Analysis")
+
+	// Only run the analysis while the ramp is in progress
+	index := roCtx.NewStatus().BlueGreen.PreviewTrafficRampStepIndex
+	if index == nil || *index >= int32(len(rollout.Spec.Strategy.BlueGreen.PreviewTrafficRamp)) {
+		err := c.cancelAnalysisRuns(roCtx, []*v1alpha1.AnalysisRun{currentAr})
+		return nil, err
+	}
+
+	if getPauseCondition(rollout, v1alpha1.PauseReasonInconclusiveAnalysis) != nil {
+		return currentAr, nil
+	}
+
+	currentAr, err := c.restartOnTemplateChange(roCtx, rollout.Spec.Strategy.BlueGreen.PreviewTrafficRampAnalysis, currentAr)
+	if err != nil {
+		return nil, err
+	}
+	if needsNewAnalysisRun(currentAr, rollout) {
+		if !limiter.tryAcquire() {
+			c.queueAnalysisRun(roCtx, "Preview Traffic Ramp AnalysisRun", limiter)
+			return nil, nil
+		}
+		podHash := replicasetutil.GetPodTemplateHash(newRS)
+		instanceID := analysisutil.GetInstanceID(rollout)
+		previewTrafficRampLabels := analysisutil.PreviewTrafficRampLabels(podHash, instanceID)
+		currentAr, err := c.createAnalysisRun(roCtx, rollout.Spec.Strategy.BlueGreen.PreviewTrafficRampAnalysis, nil, previewTrafficRampLabels)
+		if err == nil {
+			roCtx.Log().WithField(logutil.AnalysisRunKey, currentAr.Name).Info("Created Preview Traffic Ramp AnalysisRun")
+		}
+		return currentAr, err
+	}
+	switch currentAr.Status.Phase {
+	case v1alpha1.AnalysisPhaseInconclusive:
+		roCtx.PauseContext().AddPauseCondition(v1alpha1.PauseReasonInconclusiveAnalysis)
+	case v1alpha1.AnalysisPhaseError, v1alpha1.AnalysisPhaseFailed:
+		roCtx.PauseContext().AddAbort(currentAr.Status.Message)
+	}
+	return currentAr, nil
+}
+
+// reconcileScaleDownDrainAnalysisRun runs the analysis of the connections of the previous active ReplicaSet while it
+// waits to be scaled down. The result of the analysis does not pause or abort the rollout: the ReplicaSet is scaled
+// down once the analysis finds it drained, or once its scale down deadline passes.
+func (c *Controller) reconcileScaleDownDrainAnalysisRun(roCtx rolloutContext, limiter *analysisRunLimiter) (*v1alpha1.AnalysisRun, error) {
+	rollout := roCtx.Rollout()
+	currentAr := roCtx.CurrentAnalysisRuns().BlueGreenScaleDownDrain
+	scaleDownDrain := rollout.Spec.Strategy.BlueGreen.ScaleDownDrain
+	if scaleDownDrain == nil {
+		err := c.cancelAnalysisRuns(roCtx, []*v1alpha1.AnalysisRun{currentAr})
+		return nil, err
+	}
+	roCtx.Log().Info("Reconciling Scale Down Drain Analysis")
+
+	drainingRS := scaleDownDrainReplicaSet(roCtx.AllRSs(), rollout)
+	if drainingRS == nil {
+		err := c.cancelAnalysisRuns(roCtx, []*v1alpha1.AnalysisRun{currentAr})
+		return nil, err
+	}
+	podHash := replicasetutil.GetPodTemplateHash(drainingRS)
+	if currentAr != nil && currentAr.Labels[v1alpha1.DefaultRolloutUniqueLabelKey] != podHash {
+		// A later promotion replaced the ReplicaSet draining its connections
+		err := c.cancelAnalysisRuns(roCtx, []*v1alpha1.AnalysisRun{currentAr})
+		if err != nil {
+			return nil, err
+		}
+		currentAr = nil
+	}
+
+	currentAr, err := c.restartOnTemplateChange(roCtx, &scaleDownDrain.RolloutAnalysis, currentAr)
+	if err != nil {
+		return nil, err
+	}
+	if currentAr == nil {
+		if !limiter.tryAcquire() {
+			c.queueAnalysisRun(roCtx, "Scale Down Drain AnalysisRun", limiter)
+			return nil, nil
+		}
+		drainAnalysis := scaleDownDrain.RolloutAnalysis.DeepCopy()
+		// The pod template hash of the draining ReplicaSet takes precedence over an argument of the same name
+		drainAnalysis.Args = append(drainAnalysis.Args, v1alpha1.AnalysisRunArgument{Name: analysisutil.DrainingPodTemplateHashArgName, Value: podHash})
+		instanceID := analysisutil.GetInstanceID(rollout)
+		scaleDownDrainLabels := analysisutil.ScaleDownDrainLabels(podHash, instanceID)
+		currentAr, err := c.createAnalysisRun(roCtx, drainAnalysis, nil, scaleDownDrainLabels)
+		if err == nil {
+			roCtx.Log().WithField(logutil.AnalysisRunKey, currentAr.Name).Infof("Created Scale Down Drain AnalysisRun for RS '%s'", drainingRS.Name)
+		}
+		return currentAr, err
+	}
+	return currentAr, nil
+}
+
+// scaleDownDrainReplicaSet returns the previous active ReplicaSet waiting to be scaled down, which is the latest
+// revision of the scaled up ReplicaSets with a scale down deadline other than the active and the new one, or nil if
+// there is none
+func scaleDownDrainReplicaSet(allRSs []*appsv1.ReplicaSet, rollout *v1alpha1.Rollout) *appsv1.ReplicaSet {
+	var waitingRSs []*appsv1.ReplicaSet
+	for _, rs := range allRSs {
+		podHash := replicasetutil.GetPodTemplateHash(rs)
+		if podHash == rollout.Status.BlueGreen.ActiveSelector || podHash == rollout.Status.CurrentPodHash {
+			continue
+		}
+		if _, ok := rs.Annotations[v1alpha1.DefaultReplicaSetScaleDownDeadlineAnnotationKey]; !ok {
+			continue
+		}
+		if rs.Spec.Replicas == nil || *rs.Spec.Replicas == 0 {
+			continue
+		}
+		waitingRSs = append(waitingRSs, rs)
+	}
+	if len(waitingRSs) == 0 {
+		return nil
+	}
+	sort.Sort(sort.Reverse(replicasetutil.ReplicaSetsByRevisionNumber(waitingRSs)))
+	return waitingRSs[0]
+}
+
+// isDrained returns whether the analysis of the scale down drain found the ReplicaSet drained, which is once the
+// AnalysisRun is successful or the latest measurement of each of its metrics is successful
+func isDrained(ar *v1alpha1.AnalysisRun, rs *appsv1.ReplicaSet) bool {
+	if ar == nil || ar.Labels[v1alpha1.DefaultRolloutUniqueLabelKey] != replicasetutil.GetPodTemplateHash(rs) {
+		return false
+	}
+	if ar.Status.Phase.Completed() {
+		return ar.Status.Phase == v1alpha1.AnalysisPhaseSuccessful
+	}
+	if len(ar.Spec.Metrics) == 0 {
+		return false
+	}
+	for _, metric := range ar.Spec.Metrics {
+		measurement := analysisutil.LastMeasurement(ar, metric.Name)
+		if measurement == nil || measurement.Phase != v1alpha1.AnalysisPhaseSuccessful {
+			return false
+		}
+	}
+	return true
+}
+
 func (c *Controller) reconcileBackgroundAnalysisRun(roCtx rolloutContext) (*v1alpha1.AnalysisRun, error) {
 	rollout := roCtx.Rollout()
 	newRS := roCtx.NewRS()
@@ -285,15 +495,35 @@ func (c *Controller) reconcileBackgroundAnalysisRun(roCtx rolloutContext) (*v1al
 		return nil, err
 	}
 
+	// Let the background run of a promoted rollout continue until it completes on its own. Since the rollout is already
+	// fully promoted, the result of the run no longer pauses or aborts the rollout.
+	if currentAr != nil && rollout.Status.StableRS == rollout.Status.CurrentPodHash && !defaults.GetTerminateAnalysisOnPromoteOrDefault(rollout) {
+		return currentAr, nil
+	}
+
 	// Do not create a background run if the rollout is completely rolled out, just created, before the starting step
 	if rollout.Status.StableRS == rollout.Status.CurrentPodHash || rollout.Status.CurrentPodHash == "" || replicasetutil.BeforeStartingStep(rollout) {
 		return nil, nil
 	}
 
+	// Do not create a background run until the rollout the canary depends on is Healthy
+	if replicasetutil.WaitingForDependentRollout(rollout) {
+		return currentAr, nil
+	}
+
 	if getPauseCondition(rollout, v1alpha1.PauseReasonInconclusiveAnalysis) != nil {
 		return currentAr, nil
 	}
 
+	// A new background run is only created once a rollout rolled back to an earlier step is resumed
+	if getPauseCondition(rollout, v1alpha1.PauseReasonRollbackToStep) != nil {
+		return currentAr, nil
+	}
+
+	currentAr, err := c.restartOnTemplateChange(roCtx, &rollout.Spec.Strategy.Canary.Analysis.RolloutAnalysis, currentAr)
+	if err != nil {
+		return nil, err
+	}
 	if needsNewAnalysisRun(currentAr, rollout) {
 		podHash := replicasetutil.GetPodTemplateHash(newRS)
 		instanceID := analysisutil.GetInstanceID(rollout)
@@ -308,19 +538,105 @@ func (c *Controller) reconcileBackgroundAnalysisRun(roCtx rolloutContext) (*v1al
 	case v1alpha1.AnalysisPhaseInconclusive:
 		roCtx.PauseContext().AddPauseCondition(v1alpha1.PauseReasonInconclusiveAnalysis)
 	case v1alpha1.AnalysisPhaseError, v1alpha1.AnalysisPhaseFailed:
-		roCtx.PauseContext().AddAbort(currentAr.Status.Message)
+		abortOnFailedAnalysis(roCtx, currentAr.Status.Message)
 	}
 	return currentAr, nil
 }
 
+// abortOnFailedAnalysis aborts the canary rollout after a failed analysis, unless the abort policy of the rollout
+// rolls it back to an earlier step
+func abortOnFailedAnalysis(roCtx rolloutContext, message string) {
+	if roCtx.PauseContext().rollbackToStep != nil {
+		// Another analysis already failed during this reconciliation
+		return
+	}
+	if stepIndex := rollbackToStepIndex(roCtx.Rollout()); stepIndex != nil {
+		roCtx.PauseContext().RollbackToStep(*stepIndex, message)
+		return
+	}
+	roCtx.PauseContext().AddAbort(message)
+}
+
+// rollbackToStepIndex returns the step the abort policy of the rollout rolls back to, or nil if the rollout is not
+// past that step yet and needs to be aborted
+func rollbackToStepIndex(rollout *v1alpha1.Rollout) *int32 {
+	abortPolicy := rollout.Spec.Strategy.Canary.AbortPolicy
+	if abortPolicy == nil || abortPolicy.RollbackToStep == nil {
+		return nil
+	}
+	_, currentStepIndex := replicasetutil.GetCurrentCanaryStep(rollout)
+	if currentStepIndex == nil || *currentStepIndex <= *abortPolicy.RollbackToStep {
+		return nil
+	}
+	return abortPolicy.RollbackToStep
+}
+
+// analysisRunLimiter limits the number of AnalysisRuns of a rollout running at the same time to the
+// MaxConcurrentAnalysisRuns of the rollout. AnalysisRuns which can not be created are queued until the rollout is
+// reconciled again after a running AnalysisRun completes. The background AnalysisRun is not limited, since it can run
+// until the rollout completes and would otherwise hold a slot which the step AnalysisRuns wait for.
+type analysisRunLimiter struct {
+	max     *int32
+	running int32
+}
+
+// newAnalysisRunLimiter returns a limiter counting the current AnalysisRuns of the rollout, other than the background
+// AnalysisRun, which are running
+func newAnalysisRunLimiter(roCtx rolloutContext) *analysisRunLimiter {
+	limiter := &analysisRunLimiter{
+		max: roCtx.Rollout().Spec.MaxConcurrentAnalysisRuns,
+	}
+	currentArs := roCtx.CurrentAnalysisRuns()
+	for _, ar := range currentArs.ToArray() {
+		if ar != currentArs.CanaryBackground && !ar.Spec.Terminate && !ar.Status.Phase.Completed() {
+			limiter.running++
+		}
+	}
+	return limiter
+}
+
+// tryAcquire returns true and counts the AnalysisRun as running if a new AnalysisRun can be created, or false if
+// the creation of the AnalysisRun needs to be queued
+func (l *analysisRunLimiter) tryAcquire() bool {
+	if l.max != nil && l.running >= *l.max {
+		return false
+	}
+	l.running++
+	return true
+}
+
+// queueAnalysisRun logs and records an event that the creation of the AnalysisRun is queued until a running
+// AnalysisRun completes
+func (c *Controller) queueAnalysisRun(roCtx rolloutContext, description string, limiter *analysisRunLimiter) {
+	msg := fmt.Sprintf("Queueing %s: %d AnalysisRuns are already running", description, limiter.running)
+	roCtx.Log().Info(msg)
+	c.recorder.Event(roCtx.Rollout(), corev1.EventTypeNormal, "AnalysisRunQueued", msg)
+}
+
 func (c *Controller) createAnalysisRun(roCtx rolloutContext, rolloutAnalysis *v1alpha1.RolloutAnalysis, stepIdx *int32, labels map[string]string) (*v1alpha1.AnalysisRun, error) {
 	newRS := roCtx.NewRS()
 	stableRS := roCtx.StableRS()
-	args := analysisutil.BuildArgumentsForRolloutAnalysisRun(rolloutAnalysis.Args, stableRS, newRS)
+	args, err := analysisutil.BuildArgumentsForRolloutAnalysisRun(rolloutAnalysis.Args, stableRS, newRS, roCtx.Rollout())
+	if err != nil {
+		return nil, err
+	}
+	if roCtx.Rollout().Spec.Strategy.Canary != nil && stepIdx != nil {
+		// The weight of the canary is only supplied to the analysis steps: the background analysis is created once,
+		// so its weight would not follow the steps. The arguments of the rollout take precedence over the weight.
+		weight := replicasetutil.GetCurrentSetWeight(roCtx.Rollout())
+		args = append([]v1alpha1.Argument{analysisutil.CanaryWeightArg(weight)}, args...)
+	}
 	podHash := replicasetutil.GetPodTemplateHash(newRS)
 	if podHash == "" {
 		return nil, fmt.Errorf("Latest ReplicaSet '%s' has no pod hash in the labels", newRS.Name)
 	}
+	if rolloutAnalysis.InheritArgsFromStep != nil {
+		inheritedArgs, err := getInheritedArgs(roCtx, *rolloutAnalysis.InheritArgsFromStep, podHash)
+		if err != nil {
+			return nil, err
+		}
+		args = analysisutil.InheritArgs(inheritedArgs, args)
+	}
 	ar, err := c.newAnalysisRunFromRollout(roCtx, rolloutAnalysis, args, podHash, stepIdx, labels)
 	if err != nil {
 		return nil, err
@@ -329,14 +645,40 @@ func (c *Controller) createAnalysisRun(roCtx rolloutContext, rolloutAnalysis *v1
 	return analysisutil.CreateWithCollisionCounter(roCtx.Log(), analysisRunIf, *ar)
 }
 
-func (c *Controller) reconcileStepBasedAnalysisRun(roCtx rolloutContext) (*v1alpha1.AnalysisRun, error) {
+// getInheritedArgs returns the resolved arguments of the AnalysisRun created by a previous analysis step for
+// the same revision of the rollout
+func getInheritedArgs(roCtx rolloutContext, stepIdx int32, podHash string) ([]v1alpha1.Argument, error) {
+	allArs := append(roCtx.CurrentAnalysisRuns().ToArray(), roCtx.OtherAnalysisRuns()...)
+	stepIdxStr := strconv.Itoa(int(stepIdx))
+	var inheritFrom *v1alpha1.AnalysisRun
+	for i := range allArs {
+		ar := allArs[i]
+		if ar.Labels[v1alpha1.RolloutTypeLabel] != v1alpha1.RolloutTypeStepLabel ||
+			ar.Labels[v1alpha1.RolloutCanaryStepIndexLabel] != stepIdxStr ||
+			ar.Labels[v1alpha1.DefaultRolloutUniqueLabelKey] != podHash {
+			continue
+		}
+		// A step can be retried, in which case the arguments of its latest AnalysisRun are inherited
+		if inheritFrom == nil || inheritFrom.CreationTimestamp.Before(&ar.CreationTimestamp) {
+			inheritFrom = ar
+		}
+	}
+	if inheritFrom == nil {
+		return nil, fmt.Errorf("AnalysisRun of step %d not found to inherit the arguments from", stepIdx)
+	}
+	return inheritFrom.Spec.Args, nil
+}
+
+func (c *Controller) reconcileStepBasedAnalysisRun(roCtx rolloutContext, limiter *analysisRunLimiter) (*v1alpha1.AnalysisRun, error) {
 	rollout := roCtx.Rollout()
 	currentArs := roCtx.CurrentAnalysisRuns()
 	newRS := roCtx.NewRS()
 	step, index := replicasetutil.GetCurrentCanaryStep(rollout)
 	currentAr := currentArs.CanaryStep
 
-	if len(rollout.Status.PauseConditions) > 0 || rollout.Status.Abort {
+	// A rollout retried by the autoRetry of its abortPolicy runs the analysis of the step again right away
+	aborted := rollout.Status.Abort && !roCtx.PauseContext().retryAbort
+	if len(rollout.Status.PauseConditions) > 0 || aborted || replicasetutil.InCanaryWarmup(rollout) || replicasetutil.WaitingForDependentRollout(rollout) {
 		return currentAr, nil
 	}
 
@@ -344,7 +686,19 @@ func (c *Controller) reconcileStepBasedAnalysisRun(roCtx rolloutContext) (*v1alp
 		err := c.cancelAnalysisRuns(roCtx, []*v1alpha1.AnalysisRun{currentAr})
 		return nil, err
 	}
+	currentAr, err := c.restartOnTemplateChange(roCtx, step.Analysis, currentAr)
+	if err != nil {
+		return nil, err
+	}
 	if needsNewAnalysisRun(currentAr, rollout) {
+		if replicasetutil.IsPreTrafficAnalysisStep(rollout) && (newRS == nil || newRS.Status.AvailableReplicas == 0) {
+			roCtx.Log().Info("Waiting for the canary to be available before creating the pre-traffic AnalysisRun")
+			return nil, nil
+		}
+		if !limiter.tryAcquire() {
+			c.queueAnalysisRun(roCtx, "step AnalysisRun", limiter)
+			return nil, nil
+		}
 		podHash := replicasetutil.GetPodTemplateHash(newRS)
 		instanceID := analysisutil.GetInstanceID(rollout)
 		stepLabels := analysisutil.StepLabels(*index, podHash, instanceID)
@@ -359,12 +713,196 @@ func (c *Controller) reconcileStepBasedAnalysisRun(roCtx rolloutContext) (*v1alp
 	case v1alpha1.AnalysisPhaseInconclusive:
 		roCtx.PauseContext().AddPauseCondition(v1alpha1.PauseReasonInconclusiveAnalysis)
 	case v1alpha1.AnalysisPhaseError, v1alpha1.AnalysisPhaseFailed:
-		roCtx.PauseContext().AddAbort(currentAr.Status.Message)
+		abortOnFailedAnalysis(roCtx, currentAr.Status.Message)
 	}
 
 	return currentAr, nil
 }
 
+// reconcileAggregatedStepAnalysisRuns runs an AnalysisRun for each of the templates, or each of the regions, of the
+// current canary analysis step, and pauses or aborts the rollout according to the result of the AnalysisRuns
+// aggregated by the aggregation policy of the step
+func (c *Controller) reconcileAggregatedStepAnalysisRuns(roCtx rolloutContext, limiter *analysisRunLimiter) ([]*v1alpha1.AnalysisRun, error) {
+	rollout := roCtx.Rollout()
+	currentArs := roCtx.CurrentAnalysisRuns().CanaryAggregatedStep
+	newRS := roCtx.NewRS()
+	step, index := replicasetutil.GetCurrentCanaryStep(rollout)
+
+	aborted := rollout.Status.Abort && !roCtx.PauseContext().retryAbort
+	if len(rollout.Status.PauseConditions) > 0 || aborted || replicasetutil.InCanaryWarmup(rollout) || replicasetutil.WaitingForDependentRollout(rollout) {
+		return currentArs, nil
+	}
+
+	var ars []*v1alpha1.AnalysisRun
+	for _, stepRun := range aggregatedStepRuns(step.Analysis) {
+		currentAr := findAggregatedStepAnalysisRun(currentArs, *index, stepRun.label, stepRun.value)
+		currentAr, err := c.restartOnTemplateChange(roCtx, stepRun.analysis, currentAr)
+		if err != nil {
+			return ars, err
+		}
+		if !needsNewAnalysisRun(currentAr, rollout) {
+			ars = append(ars, currentAr)
+			continue
+		}
+		if replicasetutil.IsPreTrafficAnalysisStep(rollout) && (newRS == nil || newRS.Status.AvailableReplicas == 0) {
+			roCtx.Log().Info("Waiting for the canary to be available before creating the pre-traffic AnalysisRuns")
+			return ars, nil
+		}
+		if !limiter.tryAcquire() {
+			c.queueAnalysisRun(roCtx, fmt.Sprintf("step AnalysisRun of %s", stepRun.description), limiter)
+			continue
+		}
+		podHash := replicasetutil.GetPodTemplateHash(newRS)
+		instanceID := analysisutil.GetInstanceID(rollout)
+		stepLabels := analysisutil.StepLabels(*index, podHash, instanceID)
+		stepLabels[stepRun.label] = stepRun.value
+		currentAr, err = c.createAnalysisRun(roCtx, stepRun.analysis, index, stepLabels)
+		if err != nil {
+			return ars, err
+		}
+		roCtx.Log().WithField(logutil.AnalysisRunKey, currentAr.Name).Infof("Created AnalysisRun of %s for step '%d'", stepRun.description, *index)
+		ars = append(ars, currentAr)
+	}
+
+	phase, message := aggregateStepAnalysisRunPhase(step.Analysis, ars)
+	switch phase {
+	case v1alpha1.AnalysisPhaseInconclusive:
+		roCtx.PauseContext().AddPauseCondition(v1alpha1.PauseReasonInconclusiveAnalysis)
+	case v1alpha1.AnalysisPhaseError, v1alpha1.AnalysisPhaseFailed:
+		abortOnFailedAnalysis(roCtx, message)
+	}
+	return ars, nil
+}
+
+// aggregatedStepRun is an AnalysisRun of an aggregated analysis step, running either one of the templates of the step
+// or all of them in one of the regions of the step
+type aggregatedStepRun struct {
+	// label and value identify the AnalysisRun among the AnalysisRuns of the step
+	label string
+	value string
+	// description describes the AnalysisRun in the logs
+	description string
+	analysis    *v1alpha1.RolloutAnalysis
+}
+
+// aggregatedStepRuns returns the AnalysisRuns of an aggregated analysis step, which are the AnalysisRuns of its regions
+// when it has any, and the AnalysisRuns of its templates otherwise
+func aggregatedStepRuns(analysis *v1alpha1.RolloutAnalysis) []aggregatedStepRun {
+	var stepRuns []aggregatedStepRun
+	if len(analysis.Regions) > 0 {
+		for _, region := range analysis.Regions {
+			regionAnalysis := analysis.DeepCopy()
+			// The region takes precedence over an argument of the same name
+			regionAnalysis.Args = append(regionAnalysis.Args, v1alpha1.AnalysisRunArgument{Name: analysisutil.RegionArgName, Value: region})
+			stepRuns = append(stepRuns, aggregatedStepRun{
+				label:       v1alpha1.RolloutAnalysisRegionLabel,
+				value:       region,
+				description: fmt.Sprintf("region '%s'", region),
+				analysis:    regionAnalysis,
+			})
+		}
+		return stepRuns
+	}
+	for i, template := range analysis.Templates {
+		templateAnalysis := analysis.DeepCopy()
+		templateAnalysis.Templates = []v1alpha1.RolloutAnalysisTemplate{template}
+		stepRuns = append(stepRuns, aggregatedStepRun{
+			label:       v1alpha1.RolloutAnalysisTemplateIndexLabel,
+			value:       strconv.Itoa(i),
+			description: fmt.Sprintf("template '%s'", template.TemplateName),
+			analysis:    templateAnalysis,
+		})
+	}
+	return stepRuns
+}
+
+// findAggregatedStepAnalysisRun returns the AnalysisRun of the aggregated analysis step with the value of the label
+func findAggregatedStepAnalysisRun(ars []*v1alpha1.AnalysisRun, stepIdx int32, label, value string) *v1alpha1.AnalysisRun {
+	for _, ar := range ars {
+		if ar.Labels[v1alpha1.RolloutCanaryStepIndexLabel] == strconv.Itoa(int(stepIdx)) && ar.Labels[label] == value {
+			return ar
+		}
+	}
+	return nil
+}
+
+// aggregateStepAnalysisRunPhase returns the phase and the message of the decision of an aggregated analysis step from
+// its AnalysisRuns, according to the aggregation policy of the step
+func aggregateStepAnalysisRunPhase(analysis *v1alpha1.RolloutAnalysis, ars []*v1alpha1.AnalysisRun) (v1alpha1.AnalysisPhase, string) {
+	runCount := len(aggregatedStepRuns(analysis))
+	if analysis.Aggregation == v1alpha1.AnalysisAggregationQuorum {
+		return aggregateQuorumAnalysisRunPhase(analysis.Quorum, ars, runCount)
+	}
+	return aggregateAnalysisRunPhase(analysis.Aggregation, ars, runCount)
+}
+
+// aggregateQuorumAnalysisRunPhase returns the phase and the message of the decision of an aggregated analysis step
+// with the Quorum policy. The step is successful as soon as the quorum of AnalysisRuns is successful, and fails once
+// too many AnalysisRuns completed without success for the quorum to be met, in which case the worst phase of these
+// AnalysisRuns is returned along with the outcome of each of them.
+func aggregateQuorumAnalysisRunPhase(quorum int32, ars []*v1alpha1.AnalysisRun, runCount int) (v1alpha1.AnalysisPhase, string) {
+	successful := 0
+	var unsuccessful []*v1alpha1.AnalysisRun
+	var worst *v1alpha1.AnalysisRun
+	for _, ar := range ars {
+		if ar == nil || !ar.Status.Phase.Completed() {
+			continue
+		}
+		if ar.Status.Phase == v1alpha1.AnalysisPhaseSuccessful {
+			successful++
+			continue
+		}
+		unsuccessful = append(unsuccessful, ar)
+		if worst == nil || analysisutil.IsWorse(worst.Status.Phase, ar.Status.Phase) {
+			worst = ar
+		}
+	}
+	if successful >= int(quorum) {
+		return v1alpha1.AnalysisPhaseSuccessful, ""
+	}
+	if runCount-len(unsuccessful) >= int(quorum) {
+		return v1alpha1.AnalysisPhaseRunning, ""
+	}
+	outcomes := make([]string, 0, len(unsuccessful))
+	for _, ar := range unsuccessful {
+		name := ar.Labels[v1alpha1.RolloutAnalysisRegionLabel]
+		if name == "" {
+			name = ar.Name
+		}
+		outcomes = append(outcomes, fmt.Sprintf("%s %s", name, ar.Status.Phase))
+	}
+	return worst.Status.Phase, fmt.Sprintf("quorum of %d successful AnalysisRuns cannot be met: %s", quorum, strings.Join(outcomes, ", "))
+}
+
+// aggregateAnalysisRunPhase returns the phase and the message of the decision of an aggregated analysis step from the
+// AnalysisRuns of its templates or regions. With the All policy, the step is successful once every template or region
+// has a successful AnalysisRun and fails as soon as one of them fails. With the Any policy, the step is successful as
+// soon as one of the AnalysisRuns is successful and fails once all of them failed. The worst phase of the completed
+// AnalysisRuns is returned when the step is not successful.
+func aggregateAnalysisRunPhase(aggregation v1alpha1.AnalysisAggregation, ars []*v1alpha1.AnalysisRun, runCount int) (v1alpha1.AnalysisPhase, string) {
+	completed := 0
+	var worst *v1alpha1.AnalysisRun
+	for _, ar := range ars {
+		if ar == nil || !ar.Status.Phase.Completed() {
+			continue
+		}
+		if aggregation == v1alpha1.AnalysisAggregationAny && ar.Status.Phase == v1alpha1.AnalysisPhaseSuccessful {
+			return v1alpha1.AnalysisPhaseSuccessful, ""
+		}
+		completed++
+		if worst == nil || analysisutil.IsWorse(worst.Status.Phase, ar.Status.Phase) {
+			worst = ar
+		}
+	}
+	if aggregation == v1alpha1.AnalysisAggregationAll && worst != nil && worst.Status.Phase != v1alpha1.AnalysisPhaseSuccessful {
+		return worst.Status.Phase, worst.Status.Message
+	}
+	if worst == nil || completed < runCount {
+		return v1alpha1.AnalysisPhaseRunning, ""
+	}
+	return worst.Status.Phase, worst.Status.Message
+}
+
 func (c *Controller) cancelAnalysisRuns(roCtx rolloutContext, analysisRuns []*v1alpha1.AnalysisRun) error {
 	logctx := roCtx.Log()
 	for i := range analysisRuns {
@@ -388,19 +926,58 @@ func (c *Controller) cancelAnalysisRuns(roCtx rolloutContext, analysisRuns []*v1
 // newAnalysisRunFromRollout generates an AnalysisRun from the rollouts, the AnalysisRun Step, the new/stable ReplicaSet, and any extra objects.
 func (c *Controller) newAnalysisRunFromRollout(roCtx rolloutContext, rolloutAnalysis *v1alpha1.RolloutAnalysis, args []v1alpha1.Argument, podHash string, stepIdx *int32, labels map[string]string) (*v1alpha1.AnalysisRun, error) {
 	r := roCtx.Rollout()
-	logctx := roCtx.Log()
 	revision := r.Annotations[annotations.RevisionAnnotation]
 	nameParts := []string{r.Name, podHash, revision}
 	if stepIdx != nil {
 		nameParts = append(nameParts, strconv.Itoa(int(*stepIdx)))
 	}
+	if region := labels[v1alpha1.RolloutAnalysisRegionLabel]; region != "" {
+		// Each region of an aggregated analysis step has its own AnalysisRun
+		nameParts = append(nameParts, region)
+	} else if rolloutAnalysis.Aggregation != "" {
+		// Each template of an aggregated analysis step has its own AnalysisRun
+		nameParts = append(nameParts, rolloutAnalysis.Templates[0].TemplateName)
+	}
 	if rolloutAnalysis.TemplateName != "" {
 		//TODO(dthomson) remove this code block in v0.9.0
 		nameParts = append(nameParts, rolloutAnalysis.TemplateName)
 	}
 	name := strings.Join(nameParts, "-")
+	templates, clusterTemplates, err := c.getAnalysisTemplates(roCtx, rolloutAnalysis)
+	if err != nil {
+		return nil, err
+	}
 	var run *v1alpha1.AnalysisRun
-	var err error
+	if rolloutAnalysis.TemplateName != "" {
+		//TODO(dthomson) remove this code block in v0.9.0
+		run, err = analysisutil.NewAnalysisRunFromTemplate(templates[0], args, name, "", r.Namespace)
+	} else {
+		run, err = analysisutil.NewAnalysisRunFromTemplates(templates, clusterTemplates, args, name, "", r.Namespace)
+	}
+	if err != nil {
+		return nil, err
+	}
+	run.Labels = labels
+	run.Annotations = map[string]string{
+		annotations.RevisionAnnotation: revision,
+	}
+	if rolloutAnalysis.TemplateChangePolicy != "" {
+		// The hash of the templates snapshotted in the AnalysisRun detects a change of the templates during the run
+		run.Annotations[annotations.AnalysisTemplateChangePolicyAnnotation] = string(rolloutAnalysis.TemplateChangePolicy)
+		run.Annotations[annotations.AnalysisTemplateHashAnnotation] = analysisutil.ComputeTemplatesHash(templates, clusterTemplates)
+	}
+	run.Spec.Hooks = rolloutAnalysis.Hooks.DeepCopy()
+	run.Spec.MetricErrorPolicy = rolloutAnalysis.MetricErrorPolicy
+	run.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(r, controllerKind)}
+	return run, nil
+}
+
+// getAnalysisTemplates returns the templates referenced by the analysis, with their metric mixins expanded
+func (c *Controller) getAnalysisTemplates(roCtx rolloutContext, rolloutAnalysis *v1alpha1.RolloutAnalysis) ([]*v1alpha1.AnalysisTemplate, []*v1alpha1.ClusterAnalysisTemplate, error) {
+	r := roCtx.Rollout()
+	logctx := roCtx.Log()
+	templates := make([]*v1alpha1.AnalysisTemplate, 0)
+	clusterTemplates := make([]*v1alpha1.ClusterAnalysisTemplate, 0)
 	if rolloutAnalysis.TemplateName != "" {
 		//TODO(dthomson) remove this code block in v0.9.0
 		template, err := c.analysisTemplateLister.AnalysisTemplates(r.Namespace).Get(rolloutAnalysis.TemplateName)
@@ -408,49 +985,98 @@ func (c *Controller) newAnalysisRunFromRollout(roCtx rolloutContext, rolloutAnal
 			if k8serrors.IsNotFound(err) {
 				logctx.Warnf("AnalysisTemplate '%s' not found", rolloutAnalysis.TemplateName)
 			}
-			return nil, err
+			return nil, nil, err
 		}
-		run, err = analysisutil.NewAnalysisRunFromTemplate(template, args, name, "", r.Namespace)
+		template, err = c.expandAnalysisTemplateMixins(template)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
-	} else {
-		templates := make([]*v1alpha1.AnalysisTemplate, 0)
-		clusterTemplates := make([]*v1alpha1.ClusterAnalysisTemplate, 0)
-		for _, templateRef := range rolloutAnalysis.Templates {
-
-			if templateRef.ClusterScope {
-				template, err := c.clusterAnalysisTemplateLister.Get(templateRef.TemplateName)
-				if err != nil {
-					if k8serrors.IsNotFound(err) {
-						logctx.Warnf("ClusterAnalysisTemplate '%s' not found", rolloutAnalysis.TemplateName)
-					}
-					return nil, err
+		return append(templates, template), clusterTemplates, nil
+	}
+	for _, templateRef := range rolloutAnalysis.Templates {
+
+		if templateRef.ClusterScope {
+			template, err := c.clusterAnalysisTemplateLister.Get(templateRef.TemplateName)
+			if err != nil {
+				if k8serrors.IsNotFound(err) {
+					logctx.Warnf("ClusterAnalysisTemplate '%s' not found", rolloutAnalysis.TemplateName)
 				}
-				clusterTemplates = append(clusterTemplates, template)
-			} else {
-				template, err := c.analysisTemplateLister.AnalysisTemplates(r.Namespace).Get(templateRef.TemplateName)
-				if err != nil {
-					if k8serrors.IsNotFound(err) {
-						logctx.Warnf("AnalysisTemplate '%s' not found", rolloutAnalysis.TemplateName)
-					}
-					return nil, err
+				return nil, nil, err
+			}
+			template, err = c.expandClusterAnalysisTemplateMixins(template)
+			if err != nil {
+				return nil, nil, err
+			}
+			clusterTemplates = append(clusterTemplates, template)
+		} else {
+			template, err := c.analysisTemplateLister.AnalysisTemplates(r.Namespace).Get(templateRef.TemplateName)
+			if err != nil {
+				if k8serrors.IsNotFound(err) {
+					logctx.Warnf("AnalysisTemplate '%s' not found", rolloutAnalysis.TemplateName)
 				}
-				templates = append(templates, template)
+				return nil, nil, err
 			}
-
+			template, err = c.expandAnalysisTemplateMixins(template)
+			if err != nil {
+				return nil, nil, err
+			}
+			templates = append(templates, template)
 		}
-		run, err = analysisutil.NewAnalysisRunFromTemplates(templates, clusterTemplates, args, name, "", r.Namespace)
-		if err != nil {
-			return nil, err
+
+	}
+	return templates, clusterTemplates, nil
+}
+
+// restartOnTemplateChange cancels the current AnalysisRun when the analysis restarts on template change and the
+// templates changed since the AnalysisRun was created, so that a new AnalysisRun is created from the updated
+// templates. With the Ignore policy, the AnalysisRun keeps running the templates snapshotted at its creation
+func (c *Controller) restartOnTemplateChange(roCtx rolloutContext, rolloutAnalysis *v1alpha1.RolloutAnalysis, currentAr *v1alpha1.AnalysisRun) (*v1alpha1.AnalysisRun, error) {
+	if currentAr == nil || currentAr.Status.Phase.Completed() || rolloutAnalysis.TemplateChangePolicy != v1alpha1.AnalysisTemplateChangePolicyRestart {
+		return currentAr, nil
+	}
+	templateHash, ok := currentAr.Annotations[annotations.AnalysisTemplateHashAnnotation]
+	if !ok {
+		// The AnalysisRun was created before the policy was set
+		return currentAr, nil
+	}
+	templates, clusterTemplates, err := c.getAnalysisTemplates(roCtx, rolloutAnalysis)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			// The AnalysisRun keeps running its snapshot until the template is restored
+			return currentAr, nil
 		}
+		return nil, err
 	}
-	run.Labels = labels
-	run.Annotations = map[string]string{
-		annotations.RevisionAnnotation: revision,
+	if analysisutil.ComputeTemplatesHash(templates, clusterTemplates) == templateHash {
+		return currentAr, nil
 	}
-	run.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(r, controllerKind)}
-	return run, nil
+	msg := fmt.Sprintf("Restarting AnalysisRun '%s' since its templates changed", currentAr.Name)
+	roCtx.Log().WithField(logutil.AnalysisRunKey, currentAr.Name).Info(msg)
+	c.recorder.Event(roCtx.Rollout(), corev1.EventTypeNormal, "AnalysisRunRestarted", msg)
+	err = c.cancelAnalysisRuns(roCtx, []*v1alpha1.AnalysisRun{currentAr})
+	return nil, err
+}
+
+// expandAnalysisTemplateMixins returns a copy of the template with its metric mixins expanded
+func (c *Controller) expandAnalysisTemplateMixins(template *v1alpha1.AnalysisTemplate) (*v1alpha1.AnalysisTemplate, error) {
+	spec, err := analysisutil.ExpandMetricMixins(template.Spec, c.clusterAnalysisTemplateLister.Get)
+	if err != nil {
+		return nil, err
+	}
+	template = template.DeepCopy()
+	template.Spec = spec
+	return template, nil
+}
+
+// expandClusterAnalysisTemplateMixins returns a copy of the cluster template with its metric mixins expanded
+func (c *Controller) expandClusterAnalysisTemplateMixins(template *v1alpha1.ClusterAnalysisTemplate) (*v1alpha1.ClusterAnalysisTemplate, error) {
+	spec, err := analysisutil.ExpandMetricMixins(template.Spec, c.clusterAnalysisTemplateLister.Get)
+	if err != nil {
+		return nil, err
+	}
+	template = template.DeepCopy()
+	template.Spec = spec
+	return template, nil
 }
 
 func (c *Controller) deleteAnalysisRuns(roCtx rolloutContext, ars []*v1alpha1.AnalysisRun) error {
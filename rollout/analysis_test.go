@@ -1,20 +1,25 @@
 package rollout
 
 import (
+	"encoding/json"
 	"fmt"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 
+	appsv1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/kubernetes/pkg/controller"
 	"k8s.io/utils/pointer"
 
 	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
 	analysisutil "github.com/argoproj/argo-rollouts/utils/analysis"
+	"github.com/argoproj/argo-rollouts/utils/annotations"
 	"github.com/argoproj/argo-rollouts/utils/conditions"
+	replicasetutil "github.com/argoproj/argo-rollouts/utils/replicaset"
 )
 
 func analysisTemplate(name string) *v1alpha1.AnalysisTemplate {
@@ -91,6 +96,9 @@ func analysisRun(at *v1alpha1.AnalysisTemplate, analysisRunType string, r *v1alp
 	} else if analysisRunType == v1alpha1.RolloutTypePostPromotionLabel {
 		labels = analysisutil.PostPromotionLabels(podHash, "")
 		name = fmt.Sprintf("%s-%s-%s", r.Name, podHash, "2")
+	} else if analysisRunType == v1alpha1.RolloutTypePreviewTrafficRampLabel {
+		labels = analysisutil.PreviewTrafficRampLabels(podHash, "")
+		name = fmt.Sprintf("%s-%s-%s", r.Name, podHash, "2")
 	}
 	return &v1alpha1.AnalysisRun{
 		ObjectMeta: metav1.ObjectMeta{
@@ -402,6 +410,70 @@ func TestCreateBackgroundAnalysisRunWithClusterTemplatesAndTemplate(t *testing.T
 
 // TestCreateAnalysisRunWithCollision ensures we will create an new analysis run with a new name
 // when there is a conflict (e.g. such as when there is a retry)
+func TestCreateBackgroundAnalysisRunWithMetricMixins(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	steps := []v1alpha1.CanaryStep{{
+		SetWeight: int32Ptr(10),
+	}}
+	cat := clusterAnalysisTemplate("standard-latency")
+	cat.Spec.Metrics[0].SuccessCondition = "result < {{args.threshold}}"
+	cat.Spec.Args = []v1alpha1.Argument{{Name: "threshold"}}
+	at := analysisTemplate("bar")
+	at.Spec.MetricMixins = []v1alpha1.MetricMixin{{
+		ClusterTemplateName: cat.Name,
+		Metric:              "clusterexample",
+		Name:                "latency",
+		Args:                []v1alpha1.Argument{{Name: "threshold", Value: pointer.StringPtr("0.5")}},
+	}}
+	r1 := newCanaryRollout("foo", 10, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(0), intstr.FromInt(1))
+	r2 := bumpVersion(r1)
+	ar := analysisRun(at, v1alpha1.RolloutTypeBackgroundRunLabel, r2)
+	r2.Spec.Strategy.Canary.Analysis = &v1alpha1.RolloutAnalysisBackground{
+		RolloutAnalysis: v1alpha1.RolloutAnalysis{
+			Templates: []v1alpha1.RolloutAnalysisTemplate{{
+				TemplateName: at.Name,
+			}},
+		},
+	}
+
+	rs1 := newReplicaSetWithStatus(r1, 10, 10)
+	rs2 := newReplicaSetWithStatus(r2, 0, 0)
+	f.kubeobjects = append(f.kubeobjects, rs1, rs2)
+	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+
+	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 10, 0, 10, false)
+	progressingCondition, _ := newProgressingCondition(conditions.ReplicaSetUpdatedReason, rs2, "")
+	conditions.SetRolloutCondition(&r2.Status, progressingCondition)
+	availableCondition, _ := newAvailableCondition(true)
+	conditions.SetRolloutCondition(&r2.Status, availableCondition)
+
+	f.rolloutLister = append(f.rolloutLister, r2)
+	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
+	f.clusterAnalysisTemplateLister = append(f.clusterAnalysisTemplateLister, cat)
+	f.objects = append(f.objects, r2, at, cat)
+
+	createdIndex := f.expectCreateAnalysisRunAction(ar)
+	f.expectUpdateReplicaSetAction(rs2)
+	f.expectPatchRolloutAction(r1)
+
+	f.run(getKey(r2, t))
+	createdAr := f.getCreatedAnalysisRun(createdIndex)
+	// The metrics of the templates are flattened in no particular order
+	metrics := map[string]v1alpha1.Metric{}
+	for _, metric := range createdAr.Spec.Metrics {
+		metrics[metric.Name] = metric
+	}
+	assert.Len(t, metrics, 2)
+	assert.Contains(t, metrics, "example")
+	assert.Equal(t, "result < 0.5", metrics["latency"].SuccessCondition)
+	assert.Empty(t, createdAr.Spec.Args)
+	// The metric of the ClusterAnalysisTemplate is left untouched in the informer cache
+	assert.Equal(t, "result < {{args.threshold}}", cat.Spec.Metrics[0].SuccessCondition)
+}
+
 func TestCreateAnalysisRunWithCollision(t *testing.T) {
 	f := newFixture(t)
 	defer f.Close()
@@ -580,140 +652,194 @@ func TestCreateAnalysisRunOnAnalysisStep(t *testing.T) {
 	assert.Equal(t, calculatePatch(r2, fmt.Sprintf(expectedPatch, expectedArName, expectedArName)), patch)
 }
 
-func TestFailCreateStepAnalysisRunIfInvalidTemplateRef(t *testing.T) {
+func TestCreateAnalysisRunOnAnalysisStepWithCanaryWeight(t *testing.T) {
 	f := newFixture(t)
 	defer f.Close()
 
+	at := analysisTemplate("bar")
+	at.Spec.Args = []v1alpha1.Argument{{Name: "canary-weight"}}
 	steps := []v1alpha1.CanaryStep{{
+		SetWeight: pointer.Int32Ptr(20),
+	}, {
 		Analysis: &v1alpha1.RolloutAnalysis{
-			TemplateName: "invalid-template-ref",
+			TemplateName: at.Name,
 		},
 	}}
 
-	r1 := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(0), intstr.FromInt(1))
+	r1 := newCanaryRollout("foo", 5, nil, steps, pointer.Int32Ptr(1), intstr.FromInt(0), intstr.FromInt(1))
 	r2 := bumpVersion(r1)
+	ar := analysisRun(at, v1alpha1.RolloutTypeStepLabel, r2)
 
-	rs1 := newReplicaSetWithStatus(r1, 1, 1)
-	rs2 := newReplicaSetWithStatus(r2, 0, 0)
+	rs1 := newReplicaSetWithStatus(r1, 4, 4)
+	rs2 := newReplicaSetWithStatus(r2, 1, 1)
 	f.kubeobjects = append(f.kubeobjects, rs1, rs2)
 	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
 	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
 
-	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 1, 0, 1, false)
+	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 5, 1, 5, false)
 	progressingCondition, _ := newProgressingCondition(conditions.ReplicaSetUpdatedReason, rs2, "")
 	conditions.SetRolloutCondition(&r2.Status, progressingCondition)
 	availableCondition, _ := newAvailableCondition(true)
 	conditions.SetRolloutCondition(&r2.Status, availableCondition)
 
 	f.rolloutLister = append(f.rolloutLister, r2)
-	f.objects = append(f.objects, r2)
+	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
+	f.objects = append(f.objects, r2, at)
 
-	f.runExpectError(getKey(r2, t), true)
+	createdIndex := f.expectCreateAnalysisRunAction(ar)
+	f.expectPatchRolloutAction(r1)
+
+	f.run(getKey(r2, t))
+	createdAr := f.getCreatedAnalysisRun(createdIndex)
+	// The weight of the canary is supplied to the templates declaring it
+	assert.Equal(t, []v1alpha1.Argument{
+		{Name: "canary-weight", Value: pointer.StringPtr("20")},
+	}, createdAr.Spec.Args)
 }
 
-func TestFailCreateBackgroundAnalysisRunIfInvalidTemplateRef(t *testing.T) {
+// createStepAnalysisRunWithInterval reconciles a rollout whose analysis steps supply the interval of the metric of
+// the template, and returns the AnalysisRun created for the given step
+func createStepAnalysisRunWithInterval(t *testing.T, stepIndex int32, stableReplicas, canaryReplicas int) *v1alpha1.AnalysisRun {
 	f := newFixture(t)
 	defer f.Close()
 
+	at := analysisTemplate("bar")
+	at.Spec.Args = []v1alpha1.Argument{{Name: "interval"}}
+	at.Spec.Metrics[0].Interval = "{{args.interval}}"
 	steps := []v1alpha1.CanaryStep{{
-		SetWeight: pointer.Int32Ptr(10),
+		SetWeight: pointer.Int32Ptr(20),
+	}, {
+		Analysis: &v1alpha1.RolloutAnalysis{
+			TemplateName: at.Name,
+			Args:         []v1alpha1.AnalysisRunArgument{{Name: "interval", Value: "5m"}},
+		},
+	}, {
+		SetWeight: pointer.Int32Ptr(80),
+	}, {
+		Analysis: &v1alpha1.RolloutAnalysis{
+			TemplateName: at.Name,
+			Args:         []v1alpha1.AnalysisRunArgument{{Name: "interval", Value: "30s"}},
+		},
 	}}
 
-	r1 := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(0), intstr.FromInt(1))
+	r1 := newCanaryRollout("foo", 5, nil, steps, pointer.Int32Ptr(stepIndex), intstr.FromInt(0), intstr.FromInt(1))
 	r2 := bumpVersion(r1)
-	r2.Spec.Strategy.Canary.Analysis = &v1alpha1.RolloutAnalysisBackground{
-		RolloutAnalysis: v1alpha1.RolloutAnalysis{
-			TemplateName: "invalid-template-ref",
-		},
-	}
+	ar := analysisRun(at, v1alpha1.RolloutTypeStepLabel, r2)
 
-	rs1 := newReplicaSetWithStatus(r1, 1, 1)
-	rs2 := newReplicaSetWithStatus(r2, 0, 0)
+	rs1 := newReplicaSetWithStatus(r1, stableReplicas, stableReplicas)
+	rs2 := newReplicaSetWithStatus(r2, canaryReplicas, canaryReplicas)
 	f.kubeobjects = append(f.kubeobjects, rs1, rs2)
 	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
 	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
 
-	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 1, 0, 1, false)
-
+	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 5, int32(canaryReplicas), 5, false)
 	progressingCondition, _ := newProgressingCondition(conditions.ReplicaSetUpdatedReason, rs2, "")
 	conditions.SetRolloutCondition(&r2.Status, progressingCondition)
 	availableCondition, _ := newAvailableCondition(true)
 	conditions.SetRolloutCondition(&r2.Status, availableCondition)
 
 	f.rolloutLister = append(f.rolloutLister, r2)
-	f.objects = append(f.objects, r2)
+	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
+	f.objects = append(f.objects, r2, at)
 
-	f.runExpectError(getKey(r2, t), true)
+	createdIndex := f.expectCreateAnalysisRunAction(ar)
+	f.expectPatchRolloutAction(r1)
+
+	f.run(getKey(r2, t))
+	return f.getCreatedAnalysisRun(createdIndex)
 }
 
-func TestFailCreateBackgroundAnalysisRunIfInvalidTemplateRefWithTemplates(t *testing.T) {
+func TestCreateAnalysisRunOnAnalysisStepWithIntervalFromArgs(t *testing.T) {
+	// The first analysis measures at a coarse interval while the canary receives little traffic
+	createdAr := createStepAnalysisRunWithInterval(t, 1, 4, 1)
+	assert.Equal(t, v1alpha1.DurationString("5m"), createdAr.Spec.Metrics[0].Interval)
+
+	// The last analysis measures at a fine interval
+	createdAr = createStepAnalysisRunWithInterval(t, 3, 1, 4)
+	assert.Equal(t, v1alpha1.DurationString("30s"), createdAr.Spec.Metrics[0].Interval)
+}
+
+func TestCreateAnalysisRunOnAnalysisStepWithCanaryWeightOfMaxTrafficWeight(t *testing.T) {
 	f := newFixture(t)
 	defer f.Close()
 
+	at := analysisTemplate("bar")
+	at.Spec.Args = []v1alpha1.Argument{{Name: "canary-weight"}}
 	steps := []v1alpha1.CanaryStep{{
-		SetWeight: pointer.Int32Ptr(10),
+		SetWeight: pointer.Int32Ptr(200),
+	}, {
+		Analysis: &v1alpha1.RolloutAnalysis{
+			TemplateName: at.Name,
+		},
 	}}
 
-	r1 := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(0), intstr.FromInt(1))
-	r2 := bumpVersion(r1)
-	r2.Spec.Strategy.Canary.Analysis = &v1alpha1.RolloutAnalysisBackground{
-		RolloutAnalysis: v1alpha1.RolloutAnalysis{
-			Templates: []v1alpha1.RolloutAnalysisTemplate{{
-				TemplateName: "invalid-template-ref",
-			}},
-		},
+	r1 := newCanaryRollout("foo", 5, nil, steps, pointer.Int32Ptr(1), intstr.FromInt(0), intstr.FromInt(1))
+	r1.Spec.Strategy.Canary.TrafficRouting = &v1alpha1.RolloutTrafficRouting{
+		MaxTrafficWeight: pointer.Int32Ptr(1000),
 	}
+	r1.Spec.Strategy.Canary.CanaryService = "canary"
+	r1.Spec.Strategy.Canary.StableService = "stable"
+	r2 := bumpVersion(r1)
+	ar := analysisRun(at, v1alpha1.RolloutTypeStepLabel, r2)
 
-	rs1 := newReplicaSetWithStatus(r1, 1, 1)
-	rs2 := newReplicaSetWithStatus(r2, 0, 0)
-	f.kubeobjects = append(f.kubeobjects, rs1, rs2)
-	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+	rs1 := newReplicaSetWithStatus(r1, 4, 4)
+	rs2 := newReplicaSetWithStatus(r2, 1, 1)
 	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+	rs2PodHash := rs2.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+	canarySvc := newService("canary", 80, map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: rs2PodHash}, r2)
+	stableSvc := newService("stable", 80, map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: rs1PodHash}, r2)
+	f.kubeobjects = append(f.kubeobjects, rs1, rs2, canarySvc, stableSvc)
+	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+	f.serviceLister = append(f.serviceLister, canarySvc, stableSvc)
 
-	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 1, 0, 1, false)
-
+	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 5, 1, 5, false)
 	progressingCondition, _ := newProgressingCondition(conditions.ReplicaSetUpdatedReason, rs2, "")
 	conditions.SetRolloutCondition(&r2.Status, progressingCondition)
 	availableCondition, _ := newAvailableCondition(true)
 	conditions.SetRolloutCondition(&r2.Status, availableCondition)
 
 	f.rolloutLister = append(f.rolloutLister, r2)
-	f.objects = append(f.objects, r2)
+	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
+	f.objects = append(f.objects, r2, at)
 
-	f.runExpectError(getKey(r2, t), true)
+	createdIndex := f.expectCreateAnalysisRunAction(ar)
+	// with traffic routing, the stable RS is kept at the full count
+	f.expectUpdateReplicaSetAction(rs1)
+	f.expectPatchRolloutAction(r1)
+
+	f.run(getKey(r2, t))
+	createdAr := f.getCreatedAnalysisRun(createdIndex)
+	// The weight is the setWeight of the step, out of the maxTrafficWeight, rather than a percentage
+	assert.Equal(t, []v1alpha1.Argument{
+		{Name: "canary-weight", Value: pointer.StringPtr("200")},
+	}, createdAr.Spec.Args)
 }
 
-func TestFailCreateBackgroundAnalysisRunIfMetricRepeated(t *testing.T) {
+func TestCreateBackgroundAnalysisRunWithoutCanaryWeight(t *testing.T) {
 	f := newFixture(t)
 	defer f.Close()
 
 	steps := []v1alpha1.CanaryStep{{
-		SetWeight: pointer.Int32Ptr(10),
+		SetWeight: int32Ptr(10),
 	}}
-
-	r1 := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(0), intstr.FromInt(1))
-	r2 := bumpVersion(r1)
 	at := analysisTemplate("bar")
+	at.Spec.Args = []v1alpha1.Argument{{Name: "canary-weight", Value: pointer.StringPtr("100")}}
+	r1 := newCanaryRollout("foo", 10, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(0), intstr.FromInt(1))
+	r2 := bumpVersion(r1)
+	ar := analysisRun(at, v1alpha1.RolloutTypeBackgroundRunLabel, r2)
 	r2.Spec.Strategy.Canary.Analysis = &v1alpha1.RolloutAnalysisBackground{
 		RolloutAnalysis: v1alpha1.RolloutAnalysis{
-			Templates: []v1alpha1.RolloutAnalysisTemplate{
-				{
-					TemplateName: at.Name,
-				}, {
-					TemplateName: at.Name,
-				},
-			},
+			TemplateName: at.Name,
 		},
 	}
 
-	rs1 := newReplicaSetWithStatus(r1, 1, 1)
+	rs1 := newReplicaSetWithStatus(r1, 10, 10)
 	rs2 := newReplicaSetWithStatus(r2, 0, 0)
 	f.kubeobjects = append(f.kubeobjects, rs1, rs2)
 	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
 	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
 
-	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 1, 0, 1, false)
-
+	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 10, 0, 10, false)
 	progressingCondition, _ := newProgressingCondition(conditions.ReplicaSetUpdatedReason, rs2, "")
 	conditions.SetRolloutCondition(&r2.Status, progressingCondition)
 	availableCondition, _ := newAvailableCondition(true)
@@ -723,72 +849,106 @@ func TestFailCreateBackgroundAnalysisRunIfMetricRepeated(t *testing.T) {
 	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
 	f.objects = append(f.objects, r2, at)
 
-	f.runExpectError(getKey(r2, t), true)
+	createdIndex := f.expectCreateAnalysisRunAction(ar)
+	f.expectUpdateReplicaSetAction(rs2)
+	f.expectPatchRolloutAction(r1)
+
+	f.run(getKey(r2, t))
+	createdAr := f.getCreatedAnalysisRun(createdIndex)
+	// The background analysis is not supplied with the weight of the step it starts in
+	assert.Equal(t, []v1alpha1.Argument{
+		{Name: "canary-weight", Value: pointer.StringPtr("100")},
+	}, createdAr.Spec.Args)
 }
 
-func TestDoNothingWithAnalysisRunsWhileBackgroundAnalysisRunRunning(t *testing.T) {
+func TestCreateAnalysisRunOnAnalysisStepWithInheritedArgs(t *testing.T) {
 	f := newFixture(t)
 	defer f.Close()
 
-	at := analysisTemplate("bar")
+	at1 := analysisTemplate("smoke-tests")
+	at2 := analysisTemplate("load-tests")
+	at2.Spec.Args = []v1alpha1.Argument{{Name: "service-name"}, {Name: "canary-hash"}}
+	latest := v1alpha1.Latest
 	steps := []v1alpha1.CanaryStep{{
-		SetWeight: pointer.Int32Ptr(10),
+		Analysis: &v1alpha1.RolloutAnalysis{
+			TemplateName: at1.Name,
+		},
+	}, {
+		Analysis: &v1alpha1.RolloutAnalysis{
+			TemplateName: at2.Name,
+			Args: []v1alpha1.AnalysisRunArgument{{
+				Name:      "canary-hash",
+				ValueFrom: &v1alpha1.ArgumentValueFrom{PodTemplateHashValue: &latest},
+			}},
+			InheritArgsFromStep: pointer.Int32Ptr(0),
+		},
 	}}
 
-	r1 := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(0), intstr.FromInt(1))
+	r1 := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(1), intstr.FromInt(0), intstr.FromInt(1))
 	r2 := bumpVersion(r1)
-	r2.Spec.Strategy.Canary.Analysis = &v1alpha1.RolloutAnalysisBackground{
-		RolloutAnalysis: v1alpha1.RolloutAnalysis{
-			TemplateName: at.Name,
-		},
-	}
-	ar := analysisRun(at, v1alpha1.RolloutTypeBackgroundRunLabel, r2)
-	ar.Status.Phase = v1alpha1.AnalysisPhaseRunning
+	ar := analysisRun(at2, v1alpha1.RolloutTypeStepLabel, r2)
 
 	rs1 := newReplicaSetWithStatus(r1, 1, 1)
 	rs2 := newReplicaSetWithStatus(r2, 0, 0)
 	f.kubeobjects = append(f.kubeobjects, rs1, rs2)
 	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
 	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+	rs2PodHash := rs2.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+
+	// The AnalysisRun of the first step resolved the arguments of its template
+	previousAr := analysisRun(at1, v1alpha1.RolloutTypeStepLabel, r2)
+	previousAr.Name = fmt.Sprintf("%s-%s-%s-%s-%s", r2.Name, rs2PodHash, "2", "0", at1.Name)
+	previousAr.Labels = analysisutil.StepLabels(0, rs2PodHash, "")
+	previousAr.Spec.Args = []v1alpha1.Argument{
+		{Name: "service-name", Value: pointer.StringPtr("guestbook")},
+		{Name: "canary-hash", Value: pointer.StringPtr("abcdef")},
+	}
+	previousAr.Status.Phase = v1alpha1.AnalysisPhaseSuccessful
 
 	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 1, 0, 1, false)
 	progressingCondition, _ := newProgressingCondition(conditions.ReplicaSetUpdatedReason, rs2, "")
 	conditions.SetRolloutCondition(&r2.Status, progressingCondition)
 	availableCondition, _ := newAvailableCondition(true)
 	conditions.SetRolloutCondition(&r2.Status, availableCondition)
-	r2.Status.Canary.CurrentBackgroundAnalysisRun = ar.Name
-	r2.Status.Canary.CurrentBackgroundAnalysisRunStatus = &v1alpha1.RolloutAnalysisRunStatus{
-		Name:   ar.Name,
-		Status: v1alpha1.AnalysisPhaseRunning,
-	}
 
 	f.rolloutLister = append(f.rolloutLister, r2)
-	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
-	f.analysisRunLister = append(f.analysisRunLister, ar)
-	f.objects = append(f.objects, r2, at, ar)
+	f.analysisTemplateLister = append(f.analysisTemplateLister, at1, at2)
+	f.analysisRunLister = append(f.analysisRunLister, previousAr)
+	f.objects = append(f.objects, r2, at1, at2, previousAr)
+
+	createdIndex := f.expectCreateAnalysisRunAction(ar)
+	f.expectPatchRolloutAction(r1)
 
-	f.expectUpdateReplicaSetAction(rs2)
-	patchIndex := f.expectPatchRolloutAction(r2)
 	f.run(getKey(r2, t))
-	patch := f.getPatchedRollout(patchIndex)
-	assert.Equal(t, calculatePatch(r2, OnlyObservedGenerationPatch), patch)
+	createdAr := f.getCreatedAnalysisRun(createdIndex)
+	expectedArName := fmt.Sprintf("%s-%s-%s-%s-%s", r2.Name, rs2PodHash, "2", "1", at2.Name)
+	assert.Equal(t, expectedArName, createdAr.Name)
+	// The arguments of the step take precedence over the inherited ones
+	assert.Equal(t, []v1alpha1.Argument{
+		{Name: "service-name", Value: pointer.StringPtr("guestbook")},
+		{Name: "canary-hash", Value: pointer.StringPtr(rs2PodHash)},
+	}, createdAr.Spec.Args)
 }
 
-func TestDoNothingWhileStepBasedAnalysisRunRunning(t *testing.T) {
+func TestFailCreateStepAnalysisRunIfInheritedAnalysisRunMissing(t *testing.T) {
 	f := newFixture(t)
 	defer f.Close()
 
-	at := analysisTemplate("bar")
+	at1 := analysisTemplate("smoke-tests")
+	at2 := analysisTemplate("load-tests")
 	steps := []v1alpha1.CanaryStep{{
 		Analysis: &v1alpha1.RolloutAnalysis{
-			TemplateName: at.Name,
+			TemplateName: at1.Name,
+		},
+	}, {
+		Analysis: &v1alpha1.RolloutAnalysis{
+			TemplateName:        at2.Name,
+			InheritArgsFromStep: pointer.Int32Ptr(0),
 		},
 	}}
 
-	r1 := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(0), intstr.FromInt(1))
+	r1 := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(1), intstr.FromInt(0), intstr.FromInt(1))
 	r2 := bumpVersion(r1)
-	ar := analysisRun(at, v1alpha1.RolloutTypeStepLabel, r2)
-	ar.Status.Phase = v1alpha1.AnalysisPhaseRunning
 
 	rs1 := newReplicaSetWithStatus(r1, 1, 1)
 	rs2 := newReplicaSetWithStatus(r2, 0, 0)
@@ -796,46 +956,37 @@ func TestDoNothingWhileStepBasedAnalysisRunRunning(t *testing.T) {
 	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
 	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
 
+	// The AnalysisRun of the first step belongs to the previous revision and is not inherited
+	previousAr := analysisRun(at1, v1alpha1.RolloutTypeStepLabel, r1)
+	previousAr.Labels = analysisutil.StepLabels(0, rs1PodHash, "")
+	previousAr.Status.Phase = v1alpha1.AnalysisPhaseSuccessful
+
 	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 1, 0, 1, false)
 	progressingCondition, _ := newProgressingCondition(conditions.ReplicaSetUpdatedReason, rs2, "")
 	conditions.SetRolloutCondition(&r2.Status, progressingCondition)
 	availableCondition, _ := newAvailableCondition(true)
 	conditions.SetRolloutCondition(&r2.Status, availableCondition)
-	r2.Status.Canary.CurrentStepAnalysisRun = ar.Name
-	r2.Status.Canary.CurrentStepAnalysisRunStatus = &v1alpha1.RolloutAnalysisRunStatus{
-		Name:   ar.Name,
-		Status: v1alpha1.AnalysisPhaseRunning,
-	}
 
 	f.rolloutLister = append(f.rolloutLister, r2)
-	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
-	f.analysisRunLister = append(f.analysisRunLister, ar)
-	f.objects = append(f.objects, r2, at, ar)
+	f.analysisTemplateLister = append(f.analysisTemplateLister, at1, at2)
+	f.analysisRunLister = append(f.analysisRunLister, previousAr)
+	f.objects = append(f.objects, r2, at1, at2, previousAr)
 
-	patchIndex := f.expectPatchRolloutAction(r2)
-	f.run(getKey(r2, t))
-	patch := f.getPatchedRollout(patchIndex)
-	assert.Equal(t, calculatePatch(r2, OnlyObservedGenerationPatch), patch)
+	f.runExpectError(getKey(r2, t), true)
 }
 
-func TestCancelOlderAnalysisRuns(t *testing.T) {
+func TestFailCreateStepAnalysisRunIfInvalidTemplateRef(t *testing.T) {
 	f := newFixture(t)
 	defer f.Close()
 
-	at := analysisTemplate("bar")
 	steps := []v1alpha1.CanaryStep{{
 		Analysis: &v1alpha1.RolloutAnalysis{
-			TemplateName: at.Name,
+			TemplateName: "invalid-template-ref",
 		},
 	}}
 
 	r1 := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(0), intstr.FromInt(1))
 	r2 := bumpVersion(r1)
-	ar := analysisRun(at, v1alpha1.RolloutTypeStepLabel, r2)
-	olderAr := ar.DeepCopy()
-	olderAr.Name = "older-analysis-run"
-	oldBackgroundAr := analysisRun(at, v1alpha1.RolloutTypeBackgroundRunLabel, r2)
-	oldBackgroundAr.Name = "old-background-run"
 
 	rs1 := newReplicaSetWithStatus(r1, 1, 1)
 	rs2 := newReplicaSetWithStatus(r2, 0, 0)
@@ -848,61 +999,29 @@ func TestCancelOlderAnalysisRuns(t *testing.T) {
 	conditions.SetRolloutCondition(&r2.Status, progressingCondition)
 	availableCondition, _ := newAvailableCondition(true)
 	conditions.SetRolloutCondition(&r2.Status, availableCondition)
-	r2.Status.Canary.CurrentStepAnalysisRun = ar.Name
-	r2.Status.Canary.CurrentStepAnalysisRunStatus = &v1alpha1.RolloutAnalysisRunStatus{
-		Name:   ar.Name,
-		Status: "",
-	}
-	r2.Status.Canary.CurrentBackgroundAnalysisRun = oldBackgroundAr.Name
-	r2.Status.Canary.CurrentBackgroundAnalysisRunStatus = &v1alpha1.RolloutAnalysisRunStatus{
-		Name:   oldBackgroundAr.Name,
-		Status: "",
-	}
 
 	f.rolloutLister = append(f.rolloutLister, r2)
-	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
-	f.analysisRunLister = append(f.analysisRunLister, ar, olderAr, oldBackgroundAr)
-	f.objects = append(f.objects, r2, at, ar, olderAr, oldBackgroundAr)
+	f.objects = append(f.objects, r2)
 
-	cancelBackgroundAr := f.expectPatchAnalysisRunAction(oldBackgroundAr)
-	cancelOldAr := f.expectPatchAnalysisRunAction(olderAr)
-	patchIndex := f.expectPatchRolloutAction(r2)
-	f.run(getKey(r2, t))
+	f.runExpectError(getKey(r2, t), true)
+}
 
-	assert.True(t, f.verifyPatchedAnalysisRun(cancelBackgroundAr, oldBackgroundAr))
-	assert.True(t, f.verifyPatchedAnalysisRun(cancelOldAr, olderAr))
-	patch := f.getPatchedRollout(patchIndex)
-	expectedPatch := `{
-		"status": {
-			"canary": {
-				"currentBackgroundAnalysisRun": null,
-				"currentBackgroundAnalysisRunStatus":null
-			}
-		}
-	}`
-	assert.Equal(t, calculatePatch(r2, expectedPatch), patch)
-}
-
-func TestDeleteAnalysisRunsWithNoMatchingRS(t *testing.T) {
+func TestFailCreateBackgroundAnalysisRunIfInvalidTemplateRef(t *testing.T) {
 	f := newFixture(t)
 	defer f.Close()
 
-	at := analysisTemplate("bar")
 	steps := []v1alpha1.CanaryStep{{
-		Analysis: &v1alpha1.RolloutAnalysis{
-			TemplateName: at.Name,
-		},
+		SetWeight: pointer.Int32Ptr(10),
 	}}
 
 	r1 := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(0), intstr.FromInt(1))
 	r2 := bumpVersion(r1)
-	ar := analysisRun(at, v1alpha1.RolloutTypeStepLabel, r2)
-	arWithDiffPodHash := ar.DeepCopy()
-	arWithDiffPodHash.Name = "older-analysis-run"
-	arWithDiffPodHash.Labels[v1alpha1.DefaultRolloutUniqueLabelKey] = "abc123"
-	arWithDiffPodHash.Status = v1alpha1.AnalysisRunStatus{
-		Phase: v1alpha1.AnalysisPhaseSuccessful,
+	r2.Spec.Strategy.Canary.Analysis = &v1alpha1.RolloutAnalysisBackground{
+		RolloutAnalysis: v1alpha1.RolloutAnalysis{
+			TemplateName: "invalid-template-ref",
+		},
 	}
+
 	rs1 := newReplicaSetWithStatus(r1, 1, 1)
 	rs2 := newReplicaSetWithStatus(r2, 0, 0)
 	f.kubeobjects = append(f.kubeobjects, rs1, rs2)
@@ -910,97 +1029,76 @@ func TestDeleteAnalysisRunsWithNoMatchingRS(t *testing.T) {
 	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
 
 	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 1, 0, 1, false)
+
 	progressingCondition, _ := newProgressingCondition(conditions.ReplicaSetUpdatedReason, rs2, "")
 	conditions.SetRolloutCondition(&r2.Status, progressingCondition)
 	availableCondition, _ := newAvailableCondition(true)
 	conditions.SetRolloutCondition(&r2.Status, availableCondition)
-	r2.Status.Canary.CurrentStepAnalysisRun = ar.Name
-	r2.Status.Canary.CurrentStepAnalysisRunStatus = &v1alpha1.RolloutAnalysisRunStatus{
-		Name: ar.Name,
-	}
 
 	f.rolloutLister = append(f.rolloutLister, r2)
-	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
-	f.analysisRunLister = append(f.analysisRunLister, ar, arWithDiffPodHash)
-	f.objects = append(f.objects, r2, at, ar, arWithDiffPodHash)
-
-	deletedIndex := f.expectDeleteAnalysisRunAction(arWithDiffPodHash)
-	patchIndex := f.expectPatchRolloutAction(r2)
-	f.run(getKey(r2, t))
+	f.objects = append(f.objects, r2)
 
-	deletedAr := f.getDeletedAnalysisRun(deletedIndex)
-	assert.Equal(t, deletedAr, arWithDiffPodHash.Name)
-	patch := f.getPatchedRollout(patchIndex)
-	assert.Equal(t, calculatePatch(r2, OnlyObservedGenerationPatch), patch)
+	f.runExpectError(getKey(r2, t), true)
 }
 
-func TestDeleteAnalysisRunsAfterRSDelete(t *testing.T) {
+func TestFailCreateBackgroundAnalysisRunIfInvalidTemplateRefWithTemplates(t *testing.T) {
 	f := newFixture(t)
 	defer f.Close()
 
-	at := analysisTemplate("bar")
 	steps := []v1alpha1.CanaryStep{{
-		Analysis: &v1alpha1.RolloutAnalysis{
-			TemplateName: at.Name,
-		},
+		SetWeight: pointer.Int32Ptr(10),
 	}}
 
 	r1 := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(0), intstr.FromInt(1))
 	r2 := bumpVersion(r1)
-	r3 := bumpVersion(r2)
-	r3.Spec.RevisionHistoryLimit = pointer.Int32Ptr(0)
-	ar := analysisRun(at, v1alpha1.RolloutTypeStepLabel, r3)
+	r2.Spec.Strategy.Canary.Analysis = &v1alpha1.RolloutAnalysisBackground{
+		RolloutAnalysis: v1alpha1.RolloutAnalysis{
+			Templates: []v1alpha1.RolloutAnalysisTemplate{{
+				TemplateName: "invalid-template-ref",
+			}},
+		},
+	}
 
-	rs1 := newReplicaSetWithStatus(r1, 0, 0)
-	rs2 := newReplicaSetWithStatus(r2, 1, 1)
-	rs3 := newReplicaSetWithStatus(r3, 0, 0)
-	f.kubeobjects = append(f.kubeobjects, rs1, rs2, rs3)
-	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2, rs3)
+	rs1 := newReplicaSetWithStatus(r1, 1, 1)
+	rs2 := newReplicaSetWithStatus(r2, 0, 0)
+	f.kubeobjects = append(f.kubeobjects, rs1, rs2)
+	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
 	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
-	rs2PodHash := rs2.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
-
-	arToDelete := ar.DeepCopy()
-	arToDelete.Name = "older-analysis-run"
-	arToDelete.Labels[v1alpha1.DefaultRolloutUniqueLabelKey] = rs1PodHash
-	arToDelete.Spec.Terminate = true
-	arAlreadyDeleted := arToDelete.DeepCopy()
-	arAlreadyDeleted.Name = "already-deleted-analysis-run"
-	now := metav1.Now()
-	arAlreadyDeleted.DeletionTimestamp = &now
 
-	r3 = updateCanaryRolloutStatus(r3, rs2PodHash, 1, 0, 1, false)
-	r3.Status.Canary.CurrentStepAnalysisRun = ar.Name
+	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 1, 0, 1, false)
 
-	f.rolloutLister = append(f.rolloutLister, r3)
-	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
-	f.analysisRunLister = append(f.analysisRunLister, ar, arToDelete, arAlreadyDeleted)
-	f.objects = append(f.objects, r3, at, ar, arToDelete, arAlreadyDeleted)
+	progressingCondition, _ := newProgressingCondition(conditions.ReplicaSetUpdatedReason, rs2, "")
+	conditions.SetRolloutCondition(&r2.Status, progressingCondition)
+	availableCondition, _ := newAvailableCondition(true)
+	conditions.SetRolloutCondition(&r2.Status, availableCondition)
 
-	f.expectDeleteReplicaSetAction(rs1)
-	deletedIndex := f.expectDeleteAnalysisRunAction(arToDelete)
-	f.expectPatchRolloutAction(r3)
-	f.run(getKey(r3, t))
+	f.rolloutLister = append(f.rolloutLister, r2)
+	f.objects = append(f.objects, r2)
 
-	deletedAr := f.getDeletedAnalysisRun(deletedIndex)
-	assert.Equal(t, deletedAr, arToDelete.Name)
+	f.runExpectError(getKey(r2, t), true)
 }
 
-func TestIncrementStepAfterSuccessfulAnalysisRun(t *testing.T) {
+func TestFailCreateBackgroundAnalysisRunIfMetricRepeated(t *testing.T) {
 	f := newFixture(t)
 	defer f.Close()
 
-	at := analysisTemplate("bar")
 	steps := []v1alpha1.CanaryStep{{
-		Analysis: &v1alpha1.RolloutAnalysis{
-			TemplateName: at.Name,
-		},
+		SetWeight: pointer.Int32Ptr(10),
 	}}
 
 	r1 := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(0), intstr.FromInt(1))
 	r2 := bumpVersion(r1)
-	ar := analysisRun(at, v1alpha1.RolloutTypeStepLabel, r2)
-	ar.Status = v1alpha1.AnalysisRunStatus{
-		Phase: v1alpha1.AnalysisPhaseSuccessful,
+	at := analysisTemplate("bar")
+	r2.Spec.Strategy.Canary.Analysis = &v1alpha1.RolloutAnalysisBackground{
+		RolloutAnalysis: v1alpha1.RolloutAnalysis{
+			Templates: []v1alpha1.RolloutAnalysisTemplate{
+				{
+					TemplateName: at.Name,
+				}, {
+					TemplateName: at.Name,
+				},
+			},
+		},
 	}
 
 	rs1 := newReplicaSetWithStatus(r1, 1, 1)
@@ -1010,52 +1108,37 @@ func TestIncrementStepAfterSuccessfulAnalysisRun(t *testing.T) {
 	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
 
 	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 1, 0, 1, false)
-	r2.Status.Canary.CurrentStepAnalysisRun = ar.Name
+
+	progressingCondition, _ := newProgressingCondition(conditions.ReplicaSetUpdatedReason, rs2, "")
+	conditions.SetRolloutCondition(&r2.Status, progressingCondition)
+	availableCondition, _ := newAvailableCondition(true)
+	conditions.SetRolloutCondition(&r2.Status, availableCondition)
 
 	f.rolloutLister = append(f.rolloutLister, r2)
 	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
-	f.analysisRunLister = append(f.analysisRunLister, ar)
-	f.objects = append(f.objects, r2, at, ar)
-
-	patchIndex := f.expectPatchRolloutAction(r2)
-	f.run(getKey(r2, t))
-	patch := f.getPatchedRollout(patchIndex)
-	expectedPatch := `{
-		"status": {
-			"canary": {
-				"currentStepAnalysisRun": null
-			},
-			"currentStepIndex": 1,
-			"conditions": %s
-		}
-	}`
-	condition := generateConditionsPatch(true, conditions.ReplicaSetUpdatedReason, rs2, false, "")
+	f.objects = append(f.objects, r2, at)
 
-	assert.Equal(t, calculatePatch(r2, fmt.Sprintf(expectedPatch, condition)), patch)
+	f.runExpectError(getKey(r2, t), true)
 }
 
-func TestPausedOnInconclusiveBackgroundAnalysisRun(t *testing.T) {
+func TestDoNothingWithAnalysisRunsWhileBackgroundAnalysisRunRunning(t *testing.T) {
 	f := newFixture(t)
 	defer f.Close()
 
 	at := analysisTemplate("bar")
-	steps := []v1alpha1.CanaryStep{
-		{SetWeight: pointer.Int32Ptr(10)},
-		{SetWeight: pointer.Int32Ptr(20)},
-		{SetWeight: pointer.Int32Ptr(30)},
-	}
+	steps := []v1alpha1.CanaryStep{{
+		SetWeight: pointer.Int32Ptr(10),
+	}}
 
 	r1 := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(0), intstr.FromInt(1))
 	r2 := bumpVersion(r1)
-	ar := analysisRun(at, v1alpha1.RolloutTypeBackgroundRunLabel, r2)
 	r2.Spec.Strategy.Canary.Analysis = &v1alpha1.RolloutAnalysisBackground{
 		RolloutAnalysis: v1alpha1.RolloutAnalysis{
 			TemplateName: at.Name,
 		},
 	}
-	ar.Status = v1alpha1.AnalysisRunStatus{
-		Phase: v1alpha1.AnalysisPhaseInconclusive,
-	}
+	ar := analysisRun(at, v1alpha1.RolloutTypeBackgroundRunLabel, r2)
+	ar.Status.Phase = v1alpha1.AnalysisPhaseRunning
 
 	rs1 := newReplicaSetWithStatus(r1, 1, 1)
 	rs2 := newReplicaSetWithStatus(r2, 0, 0)
@@ -1064,39 +1147,29 @@ func TestPausedOnInconclusiveBackgroundAnalysisRun(t *testing.T) {
 	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
 
 	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 1, 0, 1, false)
+	progressingCondition, _ := newProgressingCondition(conditions.ReplicaSetUpdatedReason, rs2, "")
+	conditions.SetRolloutCondition(&r2.Status, progressingCondition)
+	availableCondition, _ := newAvailableCondition(true)
+	conditions.SetRolloutCondition(&r2.Status, availableCondition)
 	r2.Status.Canary.CurrentBackgroundAnalysisRun = ar.Name
+	r2.Status.Canary.CurrentBackgroundAnalysisRunStatus = &v1alpha1.RolloutAnalysisRunStatus{
+		Name:   ar.Name,
+		Status: v1alpha1.AnalysisPhaseRunning,
+	}
 
 	f.rolloutLister = append(f.rolloutLister, r2)
 	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
 	f.analysisRunLister = append(f.analysisRunLister, ar)
 	f.objects = append(f.objects, r2, at, ar)
 
+	f.expectUpdateReplicaSetAction(rs2)
 	patchIndex := f.expectPatchRolloutAction(r2)
 	f.run(getKey(r2, t))
 	patch := f.getPatchedRollout(patchIndex)
-	now := metav1.Now().UTC().Format(time.RFC3339)
-	expectedPatch := `{
-		"status": {
-			"conditions": %s,
-			"canary": {
-				"currentBackgroundAnalysisRunStatus": {
-					"name": "%s",
-					"status": "Inconclusive"
-				}
-			},
-			"pauseConditions": [{
-					"reason": "%s",
-					"startTime": "%s"
-			}],
-			"controllerPause": true
-		}
-	}`
-	condition := generateConditionsPatch(true, conditions.ReplicaSetUpdatedReason, r2, false, "")
-
-	assert.Equal(t, calculatePatch(r2, fmt.Sprintf(expectedPatch, condition, ar.Name, v1alpha1.PauseReasonInconclusiveAnalysis, now)), patch)
+	assert.Equal(t, calculatePatch(r2, OnlyObservedGenerationPatch), patch)
 }
 
-func TestPausedStepAfterInconclusiveAnalysisRun(t *testing.T) {
+func TestDoNothingWhileStepBasedAnalysisRunRunning(t *testing.T) {
 	f := newFixture(t)
 	defer f.Close()
 
@@ -1110,9 +1183,7 @@ func TestPausedStepAfterInconclusiveAnalysisRun(t *testing.T) {
 	r1 := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(0), intstr.FromInt(1))
 	r2 := bumpVersion(r1)
 	ar := analysisRun(at, v1alpha1.RolloutTypeStepLabel, r2)
-	ar.Status = v1alpha1.AnalysisRunStatus{
-		Phase: v1alpha1.AnalysisPhaseInconclusive,
-	}
+	ar.Status.Phase = v1alpha1.AnalysisPhaseRunning
 
 	rs1 := newReplicaSetWithStatus(r1, 1, 1)
 	rs2 := newReplicaSetWithStatus(r2, 0, 0)
@@ -1121,7 +1192,15 @@ func TestPausedStepAfterInconclusiveAnalysisRun(t *testing.T) {
 	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
 
 	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 1, 0, 1, false)
+	progressingCondition, _ := newProgressingCondition(conditions.ReplicaSetUpdatedReason, rs2, "")
+	conditions.SetRolloutCondition(&r2.Status, progressingCondition)
+	availableCondition, _ := newAvailableCondition(true)
+	conditions.SetRolloutCondition(&r2.Status, availableCondition)
 	r2.Status.Canary.CurrentStepAnalysisRun = ar.Name
+	r2.Status.Canary.CurrentStepAnalysisRunStatus = &v1alpha1.RolloutAnalysisRunStatus{
+		Name:   ar.Name,
+		Status: v1alpha1.AnalysisPhaseRunning,
+	}
 
 	f.rolloutLister = append(f.rolloutLister, r2)
 	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
@@ -1131,28 +1210,117 @@ func TestPausedStepAfterInconclusiveAnalysisRun(t *testing.T) {
 	patchIndex := f.expectPatchRolloutAction(r2)
 	f.run(getKey(r2, t))
 	patch := f.getPatchedRollout(patchIndex)
-	now := metav1.Now().UTC().Format(time.RFC3339)
-	expectedPatch := `{
-		"status": {
-			"conditions": %s,
-			"canary": {
-				"currentStepAnalysisRunStatus": {
-					"name": "%s",
-					"status": "Inconclusive"
-				}
+	assert.Equal(t, calculatePatch(r2, OnlyObservedGenerationPatch), patch)
+}
+
+func TestAnalysisTemplateChangePolicy(t *testing.T) {
+	// newTemplateChangeRollout returns a rollout running the AnalysisRun of its analysis step, which snapshotted the
+	// template before it was updated when changed is set
+	newTemplateChangeRollout := func(f *fixture, policy v1alpha1.AnalysisTemplateChangePolicy, changed bool) (*v1alpha1.Rollout, *v1alpha1.AnalysisRun, *v1alpha1.AnalysisTemplate) {
+		at := analysisTemplate("bar")
+		steps := []v1alpha1.CanaryStep{{
+			Analysis: &v1alpha1.RolloutAnalysis{
+				Templates:            []v1alpha1.RolloutAnalysisTemplate{{TemplateName: at.Name}},
+				TemplateChangePolicy: policy,
 			},
-			"pauseConditions": [{
-					"reason": "%s",
-					"startTime": "%s"
-			}],
-			"controllerPause": true
+		}}
+
+		r1 := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(0), intstr.FromInt(1))
+		r2 := bumpVersion(r1)
+		ar := analysisRun(at, v1alpha1.RolloutTypeStepLabel, r2)
+		ar.Status.Phase = v1alpha1.AnalysisPhaseRunning
+		ar.Annotations = map[string]string{
+			annotations.AnalysisTemplateChangePolicyAnnotation: string(policy),
+			annotations.AnalysisTemplateHashAnnotation:         analysisutil.ComputeTemplatesHash([]*v1alpha1.AnalysisTemplate{at}, nil),
 		}
-	}`
-	condition := generateConditionsPatch(true, conditions.ReplicaSetUpdatedReason, r2, false, "")
-	assert.Equal(t, calculatePatch(r2, fmt.Sprintf(expectedPatch, condition, ar.Name, v1alpha1.PauseReasonInconclusiveAnalysis, now)), patch)
+		if changed {
+			at = at.DeepCopy()
+			at.Spec.Metrics[0].SuccessCondition = "result > 0.9"
+		}
+
+		rs1 := newReplicaSetWithStatus(r1, 1, 1)
+		rs2 := newReplicaSetWithStatus(r2, 0, 0)
+		f.kubeobjects = append(f.kubeobjects, rs1, rs2)
+		f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+		rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+
+		r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 1, 0, 1, false)
+		progressingCondition, _ := newProgressingCondition(conditions.ReplicaSetUpdatedReason, rs2, "")
+		conditions.SetRolloutCondition(&r2.Status, progressingCondition)
+		availableCondition, _ := newAvailableCondition(true)
+		conditions.SetRolloutCondition(&r2.Status, availableCondition)
+		r2.Status.Canary.CurrentStepAnalysisRun = ar.Name
+		r2.Status.Canary.CurrentStepAnalysisRunStatus = &v1alpha1.RolloutAnalysisRunStatus{
+			Name:                 ar.Name,
+			Status:               v1alpha1.AnalysisPhaseRunning,
+			TemplateChangePolicy: policy,
+		}
+
+		f.rolloutLister = append(f.rolloutLister, r2)
+		f.analysisTemplateLister = append(f.analysisTemplateLister, at)
+		f.analysisRunLister = append(f.analysisRunLister, ar)
+		f.objects = append(f.objects, r2, at, ar)
+		return r2, ar, at
+	}
+
+	t.Run("Keep the snapshot with the Ignore policy", func(t *testing.T) {
+		f := newFixture(t)
+		defer f.Close()
+
+		r2, _, _ := newTemplateChangeRollout(f, v1alpha1.AnalysisTemplateChangePolicyIgnore, true)
+
+		patchIndex := f.expectPatchRolloutAction(r2)
+		f.run(getKey(r2, t))
+		patch := f.getPatchedRollout(patchIndex)
+		assert.Equal(t, calculatePatch(r2, OnlyObservedGenerationPatch), patch)
+	})
+
+	t.Run("Keep the AnalysisRun of unchanged templates with the Restart policy", func(t *testing.T) {
+		f := newFixture(t)
+		defer f.Close()
+
+		r2, _, _ := newTemplateChangeRollout(f, v1alpha1.AnalysisTemplateChangePolicyRestart, false)
+
+		patchIndex := f.expectPatchRolloutAction(r2)
+		f.run(getKey(r2, t))
+		patch := f.getPatchedRollout(patchIndex)
+		assert.Equal(t, calculatePatch(r2, OnlyObservedGenerationPatch), patch)
+	})
+
+	t.Run("Restart the AnalysisRun of changed templates with the Restart policy", func(t *testing.T) {
+		f := newFixture(t)
+		defer f.Close()
+
+		r2, ar, at := newTemplateChangeRollout(f, v1alpha1.AnalysisTemplateChangePolicyRestart, true)
+
+		cancelIndex := f.expectPatchAnalysisRunAction(ar)
+		createdIndex := f.expectCreateAnalysisRunAction(ar)
+		patchIndex := f.expectPatchRolloutAction(r2)
+		f.run(getKey(r2, t))
+
+		assert.True(t, f.verifyPatchedAnalysisRun(cancelIndex, ar))
+		createdAr := f.getCreatedAnalysisRun(createdIndex)
+		assert.Equal(t, "result > 0.9", createdAr.Spec.Metrics[0].SuccessCondition)
+		assert.Equal(t, string(v1alpha1.AnalysisTemplateChangePolicyRestart), createdAr.Annotations[annotations.AnalysisTemplateChangePolicyAnnotation])
+		assert.Equal(t, analysisutil.ComputeTemplatesHash([]*v1alpha1.AnalysisTemplate{at}, nil), createdAr.Annotations[annotations.AnalysisTemplateHashAnnotation])
+
+		patch := f.getPatchedRollout(patchIndex)
+		expectedPatch := `{
+			"status": {
+				"canary": {
+					"currentStepAnalysisRun": "%s",
+					"currentStepAnalysisRunStatus": {
+						"name": "%s",
+						"status": ""
+					}
+				}
+			}
+		}`
+		assert.Equal(t, calculatePatch(r2, fmt.Sprintf(expectedPatch, createdAr.Name, createdAr.Name)), patch)
+	})
 }
 
-func TestErrorConditionAfterErrorAnalysisRunStep(t *testing.T) {
+func TestCancelOlderAnalysisRuns(t *testing.T) {
 	f := newFixture(t)
 	defer f.Close()
 
@@ -1166,13 +1334,10 @@ func TestErrorConditionAfterErrorAnalysisRunStep(t *testing.T) {
 	r1 := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(0), intstr.FromInt(1))
 	r2 := bumpVersion(r1)
 	ar := analysisRun(at, v1alpha1.RolloutTypeStepLabel, r2)
-	ar.Status = v1alpha1.AnalysisRunStatus{
-		Phase:   v1alpha1.AnalysisPhaseError,
-		Message: "Error",
-		MetricResults: []v1alpha1.MetricResult{{
-			Phase: v1alpha1.AnalysisPhaseError,
-		}},
-	}
+	olderAr := ar.DeepCopy()
+	olderAr.Name = "older-analysis-run"
+	oldBackgroundAr := analysisRun(at, v1alpha1.RolloutTypeBackgroundRunLabel, r2)
+	oldBackgroundAr.Name = "old-background-run"
 
 	rs1 := newReplicaSetWithStatus(r1, 1, 1)
 	rs2 := newReplicaSetWithStatus(r2, 0, 0)
@@ -1181,104 +1346,46 @@ func TestErrorConditionAfterErrorAnalysisRunStep(t *testing.T) {
 	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
 
 	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 1, 0, 1, false)
+	progressingCondition, _ := newProgressingCondition(conditions.ReplicaSetUpdatedReason, rs2, "")
+	conditions.SetRolloutCondition(&r2.Status, progressingCondition)
+	availableCondition, _ := newAvailableCondition(true)
+	conditions.SetRolloutCondition(&r2.Status, availableCondition)
 	r2.Status.Canary.CurrentStepAnalysisRun = ar.Name
-
-	f.rolloutLister = append(f.rolloutLister, r2)
-	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
-	f.analysisRunLister = append(f.analysisRunLister, ar)
-	f.objects = append(f.objects, r2, at, ar)
-
-	patchIndex := f.expectPatchRolloutAction(r2)
-	f.run(getKey(r2, t))
-	patch := f.getPatchedRollout(patchIndex)
-	expectedPatch := `{
-		"status": {
-			"canary":{
-				"currentStepAnalysisRunStatus": {
-					"name": "%s",
-					"status": "Error",
-					"message": "Error"
-				}
-			},
-			"conditions": %s,
-			"abort": true,
-			"abortedAt": "%s"
-		}
-	}`
-	now := metav1.Now().UTC().Format(time.RFC3339)
-	condition := generateConditionsPatch(true, conditions.RolloutAbortedReason, r2, false, ar.Status.Message)
-
-	assert.Equal(t, calculatePatch(r2, fmt.Sprintf(expectedPatch, ar.Name, condition, now)), patch)
-}
-
-func TestErrorConditionAfterErrorAnalysisRunBackground(t *testing.T) {
-	f := newFixture(t)
-	defer f.Close()
-
-	at := analysisTemplate("bar")
-	steps := []v1alpha1.CanaryStep{
-		{SetWeight: pointer.Int32Ptr(10)},
-		{SetWeight: pointer.Int32Ptr(20)},
-		{SetWeight: pointer.Int32Ptr(40)},
-	}
-
-	r1 := newCanaryRollout("foo", 10, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(0), intstr.FromInt(1))
-	r2 := bumpVersion(r1)
-	r2.Spec.Strategy.Canary.Analysis = &v1alpha1.RolloutAnalysisBackground{
-		RolloutAnalysis: v1alpha1.RolloutAnalysis{
-			TemplateName: at.Name,
-		},
-	}
-
-	ar := analysisRun(at, v1alpha1.RolloutTypeBackgroundRunLabel, r2)
-	ar.Status = v1alpha1.AnalysisRunStatus{
-		Phase: v1alpha1.AnalysisPhaseError,
-		MetricResults: []v1alpha1.MetricResult{{
-			Phase: v1alpha1.AnalysisPhaseError,
-		}},
+	r2.Status.Canary.CurrentStepAnalysisRunStatus = &v1alpha1.RolloutAnalysisRunStatus{
+		Name:   ar.Name,
+		Status: "",
 	}
-
+	r2.Status.Canary.CurrentBackgroundAnalysisRun = oldBackgroundAr.Name
 	r2.Status.Canary.CurrentBackgroundAnalysisRunStatus = &v1alpha1.RolloutAnalysisRunStatus{
-		Name:   ar.Name,
-		Status: v1alpha1.AnalysisPhaseRunning,
+		Name:   oldBackgroundAr.Name,
+		Status: "",
 	}
 
-	rs1 := newReplicaSetWithStatus(r1, 9, 9)
-	rs2 := newReplicaSetWithStatus(r2, 1, 1)
-	f.kubeobjects = append(f.kubeobjects, rs1, rs2)
-	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
-	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
-
-	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 10, 1, 10, false)
-	r2.Status.Canary.CurrentBackgroundAnalysisRun = ar.Name
-
 	f.rolloutLister = append(f.rolloutLister, r2)
 	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
-	f.analysisRunLister = append(f.analysisRunLister, ar)
-	f.objects = append(f.objects, r2, at, ar)
+	f.analysisRunLister = append(f.analysisRunLister, ar, olderAr, oldBackgroundAr)
+	f.objects = append(f.objects, r2, at, ar, olderAr, oldBackgroundAr)
 
+	cancelBackgroundAr := f.expectPatchAnalysisRunAction(oldBackgroundAr)
+	cancelOldAr := f.expectPatchAnalysisRunAction(olderAr)
 	patchIndex := f.expectPatchRolloutAction(r2)
 	f.run(getKey(r2, t))
+
+	assert.True(t, f.verifyPatchedAnalysisRun(cancelBackgroundAr, oldBackgroundAr))
+	assert.True(t, f.verifyPatchedAnalysisRun(cancelOldAr, olderAr))
 	patch := f.getPatchedRollout(patchIndex)
 	expectedPatch := `{
 		"status": {
-			"canary":{
-				"currentBackgroundAnalysisRunStatus": {
-					"status": "Error"
-				}
-			},
-			"conditions": %s,
-			"abortedAt": "%s",
-			"abort": true
+			"canary": {
+				"currentBackgroundAnalysisRun": null,
+				"currentBackgroundAnalysisRunStatus":null
+			}
 		}
 	}`
-	condition := generateConditionsPatch(true, conditions.RolloutAbortedReason, r2, false, "")
-
-	now := metav1.Now().UTC().Format(time.RFC3339)
-	assert.Equal(t, calculatePatch(r2, fmt.Sprintf(expectedPatch, condition, now)), patch)
+	assert.Equal(t, calculatePatch(r2, expectedPatch), patch)
 }
 
-func TestCancelAnalysisRunsWhenAborted(t *testing.T) {
+func TestDeleteAnalysisRunsWithNoMatchingRS(t *testing.T) {
 	f := newFixture(t)
 	defer f.Close()
 
@@ -1292,9 +1399,12 @@ func TestCancelAnalysisRunsWhenAborted(t *testing.T) {
 	r1 := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(0), intstr.FromInt(1))
 	r2 := bumpVersion(r1)
 	ar := analysisRun(at, v1alpha1.RolloutTypeStepLabel, r2)
-	olderAr := ar.DeepCopy()
-	olderAr.Name = "older-analysis-run"
-
+	arWithDiffPodHash := ar.DeepCopy()
+	arWithDiffPodHash.Name = "older-analysis-run"
+	arWithDiffPodHash.Labels[v1alpha1.DefaultRolloutUniqueLabelKey] = "abc123"
+	arWithDiffPodHash.Status = v1alpha1.AnalysisRunStatus{
+		Phase: v1alpha1.AnalysisPhaseSuccessful,
+	}
 	rs1 := newReplicaSetWithStatus(r1, 1, 1)
 	rs2 := newReplicaSetWithStatus(r2, 0, 0)
 	f.kubeobjects = append(f.kubeobjects, rs1, rs2)
@@ -1302,143 +1412,983 @@ func TestCancelAnalysisRunsWhenAborted(t *testing.T) {
 	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
 
 	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 1, 0, 1, false)
-	r2.Status.Abort = true
+	progressingCondition, _ := newProgressingCondition(conditions.ReplicaSetUpdatedReason, rs2, "")
+	conditions.SetRolloutCondition(&r2.Status, progressingCondition)
+	availableCondition, _ := newAvailableCondition(true)
+	conditions.SetRolloutCondition(&r2.Status, availableCondition)
 	r2.Status.Canary.CurrentStepAnalysisRun = ar.Name
 	r2.Status.Canary.CurrentStepAnalysisRunStatus = &v1alpha1.RolloutAnalysisRunStatus{
-		Name:   ar.Name,
-		Status: "",
+		Name: ar.Name,
 	}
 
 	f.rolloutLister = append(f.rolloutLister, r2)
 	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
-	f.analysisRunLister = append(f.analysisRunLister, ar, olderAr)
-	f.objects = append(f.objects, r2, at, ar, olderAr)
+	f.analysisRunLister = append(f.analysisRunLister, ar, arWithDiffPodHash)
+	f.objects = append(f.objects, r2, at, ar, arWithDiffPodHash)
 
-	cancelCurrentAr := f.expectPatchAnalysisRunAction(ar)
-	cancelOldAr := f.expectPatchAnalysisRunAction(olderAr)
+	deletedIndex := f.expectDeleteAnalysisRunAction(arWithDiffPodHash)
 	patchIndex := f.expectPatchRolloutAction(r2)
 	f.run(getKey(r2, t))
 
-	assert.True(t, f.verifyPatchedAnalysisRun(cancelOldAr, olderAr))
-	assert.True(t, f.verifyPatchedAnalysisRun(cancelCurrentAr, ar))
+	deletedAr := f.getDeletedAnalysisRun(deletedIndex)
+	assert.Equal(t, deletedAr, arWithDiffPodHash.Name)
 	patch := f.getPatchedRollout(patchIndex)
-	newConditions := generateConditionsPatch(true, conditions.RolloutAbortedReason, r2, false, "")
-	expectedPatch := `{
-		"status": {
-			"conditions": %s,
-			"abortedAt": "%s"
-		}
-	}`
-	now := metav1.Now().UTC().Format(time.RFC3339)
-	assert.Equal(t, calculatePatch(r2, fmt.Sprintf(expectedPatch, newConditions, now)), patch)
+	assert.Equal(t, calculatePatch(r2, OnlyObservedGenerationPatch), patch)
 }
 
-func TestCancelBackgroundAnalysisRunWhenRolloutIsCompleted(t *testing.T) {
+func TestDeleteAnalysisRunsAfterRSDelete(t *testing.T) {
 	f := newFixture(t)
 	defer f.Close()
 
 	at := analysisTemplate("bar")
-	steps := []v1alpha1.CanaryStep{
-		{SetWeight: pointer.Int32Ptr(10)},
-	}
-
-	r1 := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(1), intstr.FromInt(0), intstr.FromInt(1))
-	r2 := bumpVersion(r1)
-	r2.Spec.Strategy.Canary.Analysis = &v1alpha1.RolloutAnalysisBackground{
-		RolloutAnalysis: v1alpha1.RolloutAnalysis{
+	steps := []v1alpha1.CanaryStep{{
+		Analysis: &v1alpha1.RolloutAnalysis{
 			TemplateName: at.Name,
 		},
-	}
-	ar := analysisRun(at, v1alpha1.RolloutTypeStepLabel, r2)
+	}}
+
+	r1 := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(0), intstr.FromInt(1))
+	r2 := bumpVersion(r1)
+	r3 := bumpVersion(r2)
+	r3.Spec.RevisionHistoryLimit = pointer.Int32Ptr(0)
+	ar := analysisRun(at, v1alpha1.RolloutTypeStepLabel, r3)
 
 	rs1 := newReplicaSetWithStatus(r1, 0, 0)
 	rs2 := newReplicaSetWithStatus(r2, 1, 1)
-	f.kubeobjects = append(f.kubeobjects, rs1, rs2)
-	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+	rs3 := newReplicaSetWithStatus(r3, 0, 0)
+	f.kubeobjects = append(f.kubeobjects, rs1, rs2, rs3)
+	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2, rs3)
+	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
 	rs2PodHash := rs2.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
 
-	r2 = updateCanaryRolloutStatus(r2, rs2PodHash, 1, 1, 1, false)
-	r2.Status.ObservedGeneration = conditions.ComputeGenerationHash(r2.Spec)
-	r2.Status.Canary.CurrentBackgroundAnalysisRun = ar.Name
+	arToDelete := ar.DeepCopy()
+	arToDelete.Name = "older-analysis-run"
+	arToDelete.Labels[v1alpha1.DefaultRolloutUniqueLabelKey] = rs1PodHash
+	arToDelete.Spec.Terminate = true
+	arAlreadyDeleted := arToDelete.DeepCopy()
+	arAlreadyDeleted.Name = "already-deleted-analysis-run"
+	now := metav1.Now()
+	arAlreadyDeleted.DeletionTimestamp = &now
 
-	f.rolloutLister = append(f.rolloutLister, r2)
+	r3 = updateCanaryRolloutStatus(r3, rs2PodHash, 1, 0, 1, false)
+	r3.Status.Canary.CurrentStepAnalysisRun = ar.Name
+
+	f.rolloutLister = append(f.rolloutLister, r3)
 	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
-	f.analysisRunLister = append(f.analysisRunLister, ar)
-	f.objects = append(f.objects, r2, at, ar)
+	f.analysisRunLister = append(f.analysisRunLister, ar, arToDelete, arAlreadyDeleted)
+	f.objects = append(f.objects, r3, at, ar, arToDelete, arAlreadyDeleted)
 
-	patchIndex := f.expectPatchRolloutAction(r2)
-	f.run(getKey(r2, t))
+	f.expectDeleteReplicaSetAction(rs1)
+	deletedIndex := f.expectDeleteAnalysisRunAction(arToDelete)
+	f.expectPatchRolloutAction(r3)
+	f.run(getKey(r3, t))
 
-	patch := f.getPatchedRollout(patchIndex)
-	assert.Contains(t, patch, `"currentBackgroundAnalysisRun":null`)
+	deletedAr := f.getDeletedAnalysisRun(deletedIndex)
+	assert.Equal(t, deletedAr, arToDelete.Name)
 }
 
-func TestDoNotCreateBackgroundAnalysisRunAfterInconclusiveRun(t *testing.T) {
+func TestIncrementStepAfterSuccessfulAnalysisRun(t *testing.T) {
 	f := newFixture(t)
 	defer f.Close()
 
 	at := analysisTemplate("bar")
-	steps := []v1alpha1.CanaryStep{
-		{SetWeight: pointer.Int32Ptr(10)},
-	}
+	steps := []v1alpha1.CanaryStep{{
+		Analysis: &v1alpha1.RolloutAnalysis{
+			TemplateName: at.Name,
+		},
+	}}
 
 	r1 := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(0), intstr.FromInt(1))
 	r2 := bumpVersion(r1)
-	r2.Spec.Strategy.Canary.Analysis = &v1alpha1.RolloutAnalysisBackground{
-		RolloutAnalysis: v1alpha1.RolloutAnalysis{
-			TemplateName: at.Name,
-		},
+	ar := analysisRun(at, v1alpha1.RolloutTypeStepLabel, r2)
+	ar.Status = v1alpha1.AnalysisRunStatus{
+		Phase: v1alpha1.AnalysisPhaseSuccessful,
 	}
 
 	rs1 := newReplicaSetWithStatus(r1, 1, 1)
 	rs2 := newReplicaSetWithStatus(r2, 0, 0)
 	f.kubeobjects = append(f.kubeobjects, rs1, rs2)
 	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
-	rs2PodHash := rs2.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
 
-	r2 = updateCanaryRolloutStatus(r2, rs2PodHash, 1, 0, 1, false)
-	r2.Status.PauseConditions = []v1alpha1.PauseCondition{{
-		Reason:    v1alpha1.PauseReasonInconclusiveAnalysis,
-		StartTime: metav1.Now(),
-	}}
-	pausedCondition, _ := newProgressingCondition(conditions.PausedRolloutReason, r2, "")
-	conditions.SetRolloutCondition(&r2.Status, pausedCondition)
+	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 1, 0, 1, false)
+	r2.Status.Canary.CurrentStepAnalysisRun = ar.Name
 
 	f.rolloutLister = append(f.rolloutLister, r2)
 	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
-	f.objects = append(f.objects, r2, at)
+	f.analysisRunLister = append(f.analysisRunLister, ar)
+	f.objects = append(f.objects, r2, at, ar)
 
 	patchIndex := f.expectPatchRolloutAction(r2)
 	f.run(getKey(r2, t))
-
 	patch := f.getPatchedRollout(patchIndex)
-	assert.Equal(t, calculatePatch(r2, OnlyObservedGenerationPatch), patch)
+	expectedPatch := `{
+		"status": {
+			"canary": {
+				"currentStepAnalysisRun": null
+			},
+			"currentStepIndex": 1,
+			"conditions": %s
+		}
+	}`
+	condition := generateConditionsPatch(true, conditions.ReplicaSetUpdatedReason, rs2, false, "")
+
+	assert.Equal(t, calculatePatch(r2, fmt.Sprintf(expectedPatch, condition)), patch)
 }
 
-func TestDoNotCreateBackgroundAnalysisRunOnNewCanaryRollout(t *testing.T) {
+func TestPausedOnInconclusiveBackgroundAnalysisRun(t *testing.T) {
 	f := newFixture(t)
 	defer f.Close()
 
 	at := analysisTemplate("bar")
 	steps := []v1alpha1.CanaryStep{
 		{SetWeight: pointer.Int32Ptr(10)},
+		{SetWeight: pointer.Int32Ptr(20)},
+		{SetWeight: pointer.Int32Ptr(30)},
 	}
 
 	r1 := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(0), intstr.FromInt(1))
-	r1.Spec.Strategy.Canary.Analysis = &v1alpha1.RolloutAnalysisBackground{
+	r2 := bumpVersion(r1)
+	ar := analysisRun(at, v1alpha1.RolloutTypeBackgroundRunLabel, r2)
+	r2.Spec.Strategy.Canary.Analysis = &v1alpha1.RolloutAnalysisBackground{
 		RolloutAnalysis: v1alpha1.RolloutAnalysis{
 			TemplateName: at.Name,
 		},
 	}
-	r1.Status.CurrentPodHash = ""
-	rs1 := newReplicaSet(r1, 1)
+	ar.Status = v1alpha1.AnalysisRunStatus{
+		Phase: v1alpha1.AnalysisPhaseInconclusive,
+	}
 
-	f.rolloutLister = append(f.rolloutLister, r1)
-	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
-	f.objects = append(f.objects, r1, at)
+	rs1 := newReplicaSetWithStatus(r1, 1, 1)
+	rs2 := newReplicaSetWithStatus(r2, 0, 0)
+	f.kubeobjects = append(f.kubeobjects, rs1, rs2)
+	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
 
-	f.expectCreateReplicaSetAction(rs1)
-	// Update the revision
+	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 1, 0, 1, false)
+	r2.Status.Canary.CurrentBackgroundAnalysisRun = ar.Name
+
+	f.rolloutLister = append(f.rolloutLister, r2)
+	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
+	f.analysisRunLister = append(f.analysisRunLister, ar)
+	f.objects = append(f.objects, r2, at, ar)
+
+	patchIndex := f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+	patch := f.getPatchedRollout(patchIndex)
+	now := metav1.Now().UTC().Format(time.RFC3339)
+	expectedPatch := `{
+		"status": {
+			"conditions": %s,
+			"canary": {
+				"currentBackgroundAnalysisRunStatus": {
+					"name": "%s",
+					"status": "Inconclusive"
+				}
+			},
+			"pauseConditions": [{
+					"reason": "%s",
+					"startTime": "%s"
+			}],
+			"controllerPause": true
+		}
+	}`
+	condition := generateConditionsPatch(true, conditions.ReplicaSetUpdatedReason, r2, false, "")
+
+	assert.Equal(t, calculatePatch(r2, fmt.Sprintf(expectedPatch, condition, ar.Name, v1alpha1.PauseReasonInconclusiveAnalysis, now)), patch)
+}
+
+func TestPausedStepAfterInconclusiveAnalysisRun(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	at := analysisTemplate("bar")
+	steps := []v1alpha1.CanaryStep{{
+		Analysis: &v1alpha1.RolloutAnalysis{
+			TemplateName: at.Name,
+		},
+	}}
+
+	r1 := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(0), intstr.FromInt(1))
+	r2 := bumpVersion(r1)
+	ar := analysisRun(at, v1alpha1.RolloutTypeStepLabel, r2)
+	ar.Status = v1alpha1.AnalysisRunStatus{
+		Phase: v1alpha1.AnalysisPhaseInconclusive,
+	}
+
+	rs1 := newReplicaSetWithStatus(r1, 1, 1)
+	rs2 := newReplicaSetWithStatus(r2, 0, 0)
+	f.kubeobjects = append(f.kubeobjects, rs1, rs2)
+	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+
+	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 1, 0, 1, false)
+	r2.Status.Canary.CurrentStepAnalysisRun = ar.Name
+
+	f.rolloutLister = append(f.rolloutLister, r2)
+	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
+	f.analysisRunLister = append(f.analysisRunLister, ar)
+	f.objects = append(f.objects, r2, at, ar)
+
+	patchIndex := f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+	patch := f.getPatchedRollout(patchIndex)
+	now := metav1.Now().UTC().Format(time.RFC3339)
+	expectedPatch := `{
+		"status": {
+			"conditions": %s,
+			"canary": {
+				"currentStepAnalysisRunStatus": {
+					"name": "%s",
+					"status": "Inconclusive"
+				}
+			},
+			"pauseConditions": [{
+					"reason": "%s",
+					"startTime": "%s"
+			}],
+			"controllerPause": true
+		}
+	}`
+	condition := generateConditionsPatch(true, conditions.ReplicaSetUpdatedReason, r2, false, "")
+	assert.Equal(t, calculatePatch(r2, fmt.Sprintf(expectedPatch, condition, ar.Name, v1alpha1.PauseReasonInconclusiveAnalysis, now)), patch)
+}
+
+func TestRollbackToStepAfterFailedAnalysisRun(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	at := analysisTemplate("bar")
+	steps := []v1alpha1.CanaryStep{
+		{Pause: &v1alpha1.RolloutPause{}},
+		{Analysis: &v1alpha1.RolloutAnalysis{TemplateName: at.Name}},
+	}
+
+	r1 := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(1), intstr.FromInt(0), intstr.FromInt(1))
+	r1.Spec.Strategy.Canary.AbortPolicy = &v1alpha1.CanaryAbortPolicy{RollbackToStep: pointer.Int32Ptr(0)}
+	r2 := bumpVersion(r1)
+	ar := analysisRun(at, v1alpha1.RolloutTypeStepLabel, r2)
+	ar.Status = v1alpha1.AnalysisRunStatus{
+		Phase:   v1alpha1.AnalysisPhaseFailed,
+		Message: "metric failed",
+	}
+
+	rs1 := newReplicaSetWithStatus(r1, 1, 1)
+	rs2 := newReplicaSetWithStatus(r2, 0, 0)
+	f.kubeobjects = append(f.kubeobjects, rs1, rs2)
+	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+
+	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 1, 0, 1, false)
+	r2.Status.Canary.CurrentStepAnalysisRun = ar.Name
+
+	f.rolloutLister = append(f.rolloutLister, r2)
+	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
+	f.analysisRunLister = append(f.analysisRunLister, ar)
+	f.objects = append(f.objects, r2, at, ar)
+
+	patchIndex := f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+	patch := f.getPatchedRollout(patchIndex)
+	var patchObj map[string]interface{}
+	err := json.Unmarshal([]byte(patch), &patchObj)
+	assert.NoError(t, err)
+
+	// The rollout is paused at the step of the abort policy instead of being aborted
+	status := patchObj["status"].(map[string]interface{})
+	_, ok := status["abort"]
+	assert.False(t, ok)
+	assert.Equal(t, float64(0), status["currentStepIndex"])
+	assert.Equal(t, true, status["controllerPause"])
+	pauseConditions := status["pauseConditions"].([]interface{})
+	assert.Len(t, pauseConditions, 1)
+	assert.Equal(t, string(v1alpha1.PauseReasonRollbackToStep), pauseConditions[0].(map[string]interface{})["reason"])
+	canaryStatus := status["canary"].(map[string]interface{})
+	currentStepAnalysisRun, ok := canaryStatus["currentStepAnalysisRun"]
+	assert.True(t, ok)
+	assert.Nil(t, currentStepAnalysisRun)
+}
+
+func TestAbortOnFailedAnalysis(t *testing.T) {
+	steps := []v1alpha1.CanaryStep{
+		{SetWeight: pointer.Int32Ptr(10)},
+		{Pause: &v1alpha1.RolloutPause{}},
+		{SetWeight: pointer.Int32Ptr(50)},
+		{Analysis: &v1alpha1.RolloutAnalysis{TemplateName: "bar"}},
+	}
+	newRollout := func(stepIndex int32, rollbackToStep *int32) *v1alpha1.Rollout {
+		r := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(stepIndex), intstr.FromInt(0), intstr.FromInt(1))
+		if rollbackToStep != nil {
+			r.Spec.Strategy.Canary.AbortPolicy = &v1alpha1.CanaryAbortPolicy{RollbackToStep: rollbackToStep}
+		}
+		return r
+	}
+
+	t.Run("abort without an abort policy", func(t *testing.T) {
+		roCtx := newCanaryCtx(newRollout(3, nil), nil, nil, nil, nil)
+		abortOnFailedAnalysis(roCtx, "metric failed")
+		assert.True(t, roCtx.PauseContext().IsAborted())
+		assert.Nil(t, roCtx.PauseContext().rollbackToStep)
+	})
+
+	t.Run("roll back to an earlier step", func(t *testing.T) {
+		roCtx := newCanaryCtx(newRollout(3, pointer.Int32Ptr(1)), nil, nil, nil, nil)
+		abortOnFailedAnalysis(roCtx, "metric failed")
+		// A failure of another analysis during the same reconciliation is ignored
+		abortOnFailedAnalysis(roCtx, "another metric failed")
+		assert.False(t, roCtx.PauseContext().IsAborted())
+		assert.Equal(t, pointer.Int32Ptr(1), roCtx.PauseContext().rollbackToStep)
+		assert.Equal(t, "metric failed", roCtx.PauseContext().rollbackMessage)
+		assert.Equal(t, []v1alpha1.PauseReason{v1alpha1.PauseReasonRollbackToStep}, roCtx.PauseContext().addPauseReasons)
+	})
+
+	t.Run("abort at or before the step of the abort policy", func(t *testing.T) {
+		for _, stepIndex := range []int32{0, 1} {
+			roCtx := newCanaryCtx(newRollout(stepIndex, pointer.Int32Ptr(1)), nil, nil, nil, nil)
+			abortOnFailedAnalysis(roCtx, "metric failed")
+			assert.True(t, roCtx.PauseContext().IsAborted())
+			assert.Nil(t, roCtx.PauseContext().rollbackToStep)
+		}
+	})
+}
+
+// newAutoRetryFixture returns a fixture of a rollout aborted by the failed analysis of its first step 10 minutes ago,
+// with an abort policy retrying it after a 5 minute cooldown up to twice
+func newAutoRetryFixture(t *testing.T, analysisRetries int32) (*fixture, *v1alpha1.Rollout, *v1alpha1.AnalysisRun) {
+	f := newFixture(t)
+
+	at := analysisTemplate("bar")
+	steps := []v1alpha1.CanaryStep{{
+		Analysis: &v1alpha1.RolloutAnalysis{
+			TemplateName: at.Name,
+		},
+	}}
+
+	r1 := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(0), intstr.FromInt(1))
+	r1.Spec.Strategy.Canary.AbortPolicy = &v1alpha1.CanaryAbortPolicy{
+		AutoRetry: &v1alpha1.AnalysisAutoRetry{CooldownSeconds: 300, Limit: 2},
+	}
+	r2 := bumpVersion(r1)
+	ar := analysisRun(at, v1alpha1.RolloutTypeStepLabel, r2)
+	ar.Name = "failed-analysis-run"
+	ar.Status = v1alpha1.AnalysisRunStatus{
+		Phase:   v1alpha1.AnalysisPhaseFailed,
+		Message: "metric failed",
+	}
+
+	rs1 := newReplicaSetWithStatus(r1, 1, 1)
+	rs2 := newReplicaSetWithStatus(r2, 0, 0)
+	f.kubeobjects = append(f.kubeobjects, rs1, rs2)
+	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+
+	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 1, 0, 1, false)
+	r2.Status.Abort = true
+	abortedAt := metav1.NewTime(time.Now().Add(-10 * time.Minute))
+	r2.Status.AbortedAt = &abortedAt
+	r2.Status.Canary.AnalysisRetries = analysisRetries
+	r2.Status.Canary.CurrentStepAnalysisRun = ar.Name
+	r2.Status.Canary.CurrentStepAnalysisRunStatus = &v1alpha1.RolloutAnalysisRunStatus{
+		Name:    ar.Name,
+		Status:  v1alpha1.AnalysisPhaseFailed,
+		Message: "metric failed",
+	}
+
+	f.rolloutLister = append(f.rolloutLister, r2)
+	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
+	f.analysisRunLister = append(f.analysisRunLister, ar)
+	f.objects = append(f.objects, r2, at, ar)
+	return f, r2, ar
+}
+
+func TestAutoRetryAfterFailedAnalysisRun(t *testing.T) {
+	f, r2, failedAr := newAutoRetryFixture(t, 0)
+	defer f.Close()
+
+	createdIndex := f.expectCreateAnalysisRunAction(failedAr)
+	patchIndex := f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+
+	// The analysis runs again, as if the rollout was retried by the user
+	createdAr := f.getCreatedAnalysisRun(createdIndex)
+	assert.NotEqual(t, failedAr.Name, createdAr.Name)
+	patch := f.getPatchedRollout(patchIndex)
+	var patchObj map[string]interface{}
+	err := json.Unmarshal([]byte(patch), &patchObj)
+	assert.NoError(t, err)
+	status := patchObj["status"].(map[string]interface{})
+	abort, ok := status["abort"]
+	assert.True(t, ok)
+	assert.Nil(t, abort)
+	canaryStatus := status["canary"].(map[string]interface{})
+	assert.Equal(t, float64(1), canaryStatus["analysisRetries"])
+	assert.Equal(t, createdAr.Name, canaryStatus["currentStepAnalysisRunStatus"].(map[string]interface{})["name"])
+}
+
+func TestIncrementStepAfterSuccessfulAutoRetry(t *testing.T) {
+	f, r2, failedAr := newAutoRetryFixture(t, 1)
+	defer f.Close()
+
+	// The analysis run created by the retry succeeded
+	ar := failedAr.DeepCopy()
+	ar.Name = "retried-analysis-run"
+	ar.Status = v1alpha1.AnalysisRunStatus{
+		Phase: v1alpha1.AnalysisPhaseSuccessful,
+	}
+	r2 = r2.DeepCopy()
+	r2.Status.Abort = false
+	r2.Status.AbortedAt = nil
+	r2.Status.Canary.CurrentStepAnalysisRun = ar.Name
+	r2.Status.Canary.CurrentStepAnalysisRunStatus = nil
+	f.rolloutLister = []*v1alpha1.Rollout{r2}
+	f.analysisRunLister = append(f.analysisRunLister, ar)
+	f.objects = append(f.objects[1:], r2, ar)
+
+	patchIndex := f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+	patch := f.getPatchedRollout(patchIndex)
+	var patchObj map[string]interface{}
+	err := json.Unmarshal([]byte(patch), &patchObj)
+	assert.NoError(t, err)
+	status := patchObj["status"].(map[string]interface{})
+	_, ok := status["abort"]
+	assert.False(t, ok)
+	assert.Equal(t, float64(1), status["currentStepIndex"])
+	// The retries of the revision are kept
+	_, ok = status["canary"].(map[string]interface{})["analysisRetries"]
+	assert.False(t, ok)
+}
+
+func TestNoAutoRetryAfterRetriesExhausted(t *testing.T) {
+	f, r2, _ := newAutoRetryFixture(t, 2)
+	defer f.Close()
+
+	patchIndex := f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+
+	// The rollout stays aborted and no analysis run is created
+	patch := f.getPatchedRollout(patchIndex)
+	var patchObj map[string]interface{}
+	err := json.Unmarshal([]byte(patch), &patchObj)
+	assert.NoError(t, err)
+	status := patchObj["status"].(map[string]interface{})
+	_, ok := status["abort"]
+	assert.False(t, ok)
+	_, ok = status["abortedAt"]
+	assert.False(t, ok)
+	_, ok = status["canary"]
+	assert.False(t, ok)
+}
+
+func TestNoAutoRetryDuringCooldownOrAfterUserAbort(t *testing.T) {
+	f, r2, _ := newAutoRetryFixture(t, 0)
+	defer f.Close()
+
+	r2.Spec.Strategy.Canary.AbortPolicy.AutoRetry.CooldownSeconds = 3600
+	roCtx := newCanaryCtx(r2, nil, nil, nil, nil)
+	c, _, _ := f.newController(noResyncPeriodFunc)
+	c.reconcileAnalysisAutoRetry(roCtx)
+	assert.True(t, roCtx.PauseContext().IsAborted())
+	assert.False(t, roCtx.PauseContext().retryAbort)
+
+	// A rollout aborted by the user is not retried
+	r2.Spec.Strategy.Canary.AbortPolicy.AutoRetry.CooldownSeconds = 0
+	r2.Status.Canary.CurrentStepAnalysisRunStatus = nil
+	roCtx = newCanaryCtx(r2, nil, nil, nil, nil)
+	c.reconcileAnalysisAutoRetry(roCtx)
+	assert.True(t, roCtx.PauseContext().IsAborted())
+}
+
+// TestRemainPausedOnStepAfterInconclusiveAnalysisRun verifies the rollout awaits a promote or abort after pausing on an
+// inconclusive step analysis, without advancing the step or retrying the analysis
+func TestRemainPausedOnStepAfterInconclusiveAnalysisRun(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	at := analysisTemplate("bar")
+	steps := []v1alpha1.CanaryStep{{
+		Analysis: &v1alpha1.RolloutAnalysis{
+			TemplateName: at.Name,
+		},
+	}}
+
+	r1 := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(0), intstr.FromInt(1))
+	r2 := bumpVersion(r1)
+	ar := analysisRun(at, v1alpha1.RolloutTypeStepLabel, r2)
+	ar.Status = v1alpha1.AnalysisRunStatus{
+		Phase: v1alpha1.AnalysisPhaseInconclusive,
+	}
+
+	rs1 := newReplicaSetWithStatus(r1, 1, 1)
+	rs2 := newReplicaSetWithStatus(r2, 0, 0)
+	f.kubeobjects = append(f.kubeobjects, rs1, rs2)
+	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+
+	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 1, 0, 1, false)
+	r2.Status.Canary.CurrentStepAnalysisRun = ar.Name
+	r2.Status.Canary.CurrentStepAnalysisRunStatus = &v1alpha1.RolloutAnalysisRunStatus{
+		Name:   ar.Name,
+		Status: v1alpha1.AnalysisPhaseInconclusive,
+	}
+	r2.Status.ControllerPause = true
+	r2.Status.PauseConditions = []v1alpha1.PauseCondition{{
+		Reason:    v1alpha1.PauseReasonInconclusiveAnalysis,
+		StartTime: metav1.Now(),
+	}}
+	pausedCondition, _ := newProgressingCondition(conditions.PausedRolloutReason, r2, "")
+	conditions.SetRolloutCondition(&r2.Status, pausedCondition)
+	availableCondition, _ := newAvailableCondition(true)
+	conditions.SetRolloutCondition(&r2.Status, availableCondition)
+
+	f.rolloutLister = append(f.rolloutLister, r2)
+	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
+	f.analysisRunLister = append(f.analysisRunLister, ar)
+	f.objects = append(f.objects, r2, at, ar)
+
+	patchIndex := f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+	patch := f.getPatchedRollout(patchIndex)
+	assert.Equal(t, calculatePatch(r2, OnlyObservedGenerationPatch), patch)
+}
+
+func TestErrorConditionAfterErrorAnalysisRunStep(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	at := analysisTemplate("bar")
+	steps := []v1alpha1.CanaryStep{{
+		Analysis: &v1alpha1.RolloutAnalysis{
+			TemplateName: at.Name,
+		},
+	}}
+
+	r1 := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(0), intstr.FromInt(1))
+	r2 := bumpVersion(r1)
+	ar := analysisRun(at, v1alpha1.RolloutTypeStepLabel, r2)
+	ar.Status = v1alpha1.AnalysisRunStatus{
+		Phase:   v1alpha1.AnalysisPhaseError,
+		Message: "Error",
+		MetricResults: []v1alpha1.MetricResult{{
+			Phase: v1alpha1.AnalysisPhaseError,
+		}},
+	}
+
+	rs1 := newReplicaSetWithStatus(r1, 1, 1)
+	rs2 := newReplicaSetWithStatus(r2, 0, 0)
+	f.kubeobjects = append(f.kubeobjects, rs1, rs2)
+	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+
+	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 1, 0, 1, false)
+	r2.Status.Canary.CurrentStepAnalysisRun = ar.Name
+
+	f.rolloutLister = append(f.rolloutLister, r2)
+	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
+	f.analysisRunLister = append(f.analysisRunLister, ar)
+	f.objects = append(f.objects, r2, at, ar)
+
+	patchIndex := f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+	patch := f.getPatchedRollout(patchIndex)
+	expectedPatch := `{
+		"status": {
+			"canary":{
+				"currentStepAnalysisRunStatus": {
+					"name": "%s",
+					"status": "Error",
+					"message": "Error"
+				}
+			},
+			"conditions": %s,
+			"abort": true,
+			"abortedAt": "%s"
+		}
+	}`
+	now := metav1.Now().UTC().Format(time.RFC3339)
+	condition := generateConditionsPatch(true, conditions.RolloutAbortedReason, r2, false, ar.Status.Message)
+
+	assert.Equal(t, calculatePatch(r2, fmt.Sprintf(expectedPatch, ar.Name, condition, now)), patch)
+}
+
+func TestErrorConditionAfterErrorAnalysisRunBackground(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	at := analysisTemplate("bar")
+	steps := []v1alpha1.CanaryStep{
+		{SetWeight: pointer.Int32Ptr(10)},
+		{SetWeight: pointer.Int32Ptr(20)},
+		{SetWeight: pointer.Int32Ptr(40)},
+	}
+
+	r1 := newCanaryRollout("foo", 10, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(0), intstr.FromInt(1))
+	r2 := bumpVersion(r1)
+	r2.Spec.Strategy.Canary.Analysis = &v1alpha1.RolloutAnalysisBackground{
+		RolloutAnalysis: v1alpha1.RolloutAnalysis{
+			TemplateName: at.Name,
+		},
+	}
+
+	ar := analysisRun(at, v1alpha1.RolloutTypeBackgroundRunLabel, r2)
+	ar.Status = v1alpha1.AnalysisRunStatus{
+		Phase: v1alpha1.AnalysisPhaseError,
+		MetricResults: []v1alpha1.MetricResult{{
+			Phase: v1alpha1.AnalysisPhaseError,
+		}},
+	}
+
+	r2.Status.Canary.CurrentBackgroundAnalysisRunStatus = &v1alpha1.RolloutAnalysisRunStatus{
+		Name:   ar.Name,
+		Status: v1alpha1.AnalysisPhaseRunning,
+	}
+
+	rs1 := newReplicaSetWithStatus(r1, 9, 9)
+	rs2 := newReplicaSetWithStatus(r2, 1, 1)
+	f.kubeobjects = append(f.kubeobjects, rs1, rs2)
+	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+
+	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 10, 1, 10, false)
+	r2.Status.Canary.CurrentBackgroundAnalysisRun = ar.Name
+
+	f.rolloutLister = append(f.rolloutLister, r2)
+	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
+	f.analysisRunLister = append(f.analysisRunLister, ar)
+	f.objects = append(f.objects, r2, at, ar)
+
+	patchIndex := f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+	patch := f.getPatchedRollout(patchIndex)
+	expectedPatch := `{
+		"status": {
+			"canary":{
+				"currentBackgroundAnalysisRunStatus": {
+					"status": "Error"
+				}
+			},
+			"conditions": %s,
+			"abortedAt": "%s",
+			"abort": true
+		}
+	}`
+	condition := generateConditionsPatch(true, conditions.RolloutAbortedReason, r2, false, "")
+
+	now := metav1.Now().UTC().Format(time.RFC3339)
+	assert.Equal(t, calculatePatch(r2, fmt.Sprintf(expectedPatch, condition, now)), patch)
+}
+
+func TestAbortRolloutAfterFailedAnalysisRunWhileBaking(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	at := analysisTemplate("bar")
+	steps := []v1alpha1.CanaryStep{
+		{SetWeight: pointer.Int32Ptr(10)},
+	}
+
+	r1 := newCanaryRollout("foo", 10, nil, steps, pointer.Int32Ptr(1), intstr.FromInt(1), intstr.FromInt(0))
+	r1.Spec.Strategy.Canary.BakeTime = v1alpha1.DurationFromInt(60)
+	r2 := bumpVersion(r1)
+	r2.Spec.Strategy.Canary.Analysis = &v1alpha1.RolloutAnalysisBackground{
+		RolloutAnalysis: v1alpha1.RolloutAnalysis{
+			TemplateName: at.Name,
+		},
+	}
+
+	ar := analysisRun(at, v1alpha1.RolloutTypeBackgroundRunLabel, r2)
+	ar.Status = v1alpha1.AnalysisRunStatus{
+		Phase:   v1alpha1.AnalysisPhaseFailed,
+		Message: "metric \"example\" assessed Failed due to failed (1) > failureLimit (0)",
+		MetricResults: []v1alpha1.MetricResult{{
+			Phase: v1alpha1.AnalysisPhaseFailed,
+		}},
+	}
+
+	rs1 := newReplicaSetWithStatus(r1, 0, 0)
+	rs2 := newReplicaSetWithStatus(r2, 10, 10)
+	f.kubeobjects = append(f.kubeobjects, rs1, rs2)
+	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+
+	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 10, 10, 10, false)
+	r2.Status.Canary.CurrentBackgroundAnalysisRun = ar.Name
+	r2.Status.Canary.CurrentBackgroundAnalysisRunStatus = &v1alpha1.RolloutAnalysisRunStatus{
+		Name:   ar.Name,
+		Status: v1alpha1.AnalysisPhaseRunning,
+	}
+	bakeStartedAt := metav1.NewTime(time.Now().Add(-30 * time.Second))
+	r2.Status.Canary.BakeStartedAt = &bakeStartedAt
+
+	f.rolloutLister = append(f.rolloutLister, r2)
+	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
+	f.analysisRunLister = append(f.analysisRunLister, ar)
+	f.objects = append(f.objects, r2, at, ar)
+
+	patchIndex := f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+	patch := f.getPatchedRollout(patchIndex)
+	var patchObj map[string]interface{}
+	err := json.Unmarshal([]byte(patch), &patchObj)
+	assert.NoError(t, err)
+
+	status := patchObj["status"].(map[string]interface{})
+	assert.Equal(t, true, status["abort"])
+	assert.Equal(t, float64(0), status["currentStepIndex"])
+	_, ok := status["stableRS"]
+	assert.False(t, ok)
+	canaryStatus := status["canary"].(map[string]interface{})
+	bakeStartedAtPatch, ok := canaryStatus["bakeStartedAt"]
+	assert.True(t, ok)
+	assert.Nil(t, bakeStartedAtPatch)
+}
+
+func TestCancelAnalysisRunsWhenAborted(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	at := analysisTemplate("bar")
+	steps := []v1alpha1.CanaryStep{{
+		Analysis: &v1alpha1.RolloutAnalysis{
+			TemplateName: at.Name,
+		},
+	}}
+
+	r1 := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(0), intstr.FromInt(1))
+	r2 := bumpVersion(r1)
+	ar := analysisRun(at, v1alpha1.RolloutTypeStepLabel, r2)
+	olderAr := ar.DeepCopy()
+	olderAr.Name = "older-analysis-run"
+
+	rs1 := newReplicaSetWithStatus(r1, 1, 1)
+	rs2 := newReplicaSetWithStatus(r2, 0, 0)
+	f.kubeobjects = append(f.kubeobjects, rs1, rs2)
+	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+
+	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 1, 0, 1, false)
+	r2.Status.Abort = true
+	r2.Status.Canary.CurrentStepAnalysisRun = ar.Name
+	r2.Status.Canary.CurrentStepAnalysisRunStatus = &v1alpha1.RolloutAnalysisRunStatus{
+		Name:   ar.Name,
+		Status: "",
+	}
+
+	f.rolloutLister = append(f.rolloutLister, r2)
+	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
+	f.analysisRunLister = append(f.analysisRunLister, ar, olderAr)
+	f.objects = append(f.objects, r2, at, ar, olderAr)
+
+	cancelCurrentAr := f.expectPatchAnalysisRunAction(ar)
+	cancelOldAr := f.expectPatchAnalysisRunAction(olderAr)
+	patchIndex := f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+
+	assert.True(t, f.verifyPatchedAnalysisRun(cancelOldAr, olderAr))
+	assert.True(t, f.verifyPatchedAnalysisRun(cancelCurrentAr, ar))
+	patch := f.getPatchedRollout(patchIndex)
+	newConditions := generateConditionsPatch(true, conditions.RolloutAbortedReason, r2, false, "")
+	expectedPatch := `{
+		"status": {
+			"conditions": %s,
+			"abortedAt": "%s"
+		}
+	}`
+	now := metav1.Now().UTC().Format(time.RFC3339)
+	assert.Equal(t, calculatePatch(r2, fmt.Sprintf(expectedPatch, newConditions, now)), patch)
+}
+
+func TestCancelBackgroundAnalysisRunWhenRolloutIsCompleted(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	at := analysisTemplate("bar")
+	steps := []v1alpha1.CanaryStep{
+		{SetWeight: pointer.Int32Ptr(10)},
+	}
+
+	r1 := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(1), intstr.FromInt(0), intstr.FromInt(1))
+	r2 := bumpVersion(r1)
+	r2.Spec.Strategy.Canary.Analysis = &v1alpha1.RolloutAnalysisBackground{
+		RolloutAnalysis: v1alpha1.RolloutAnalysis{
+			TemplateName: at.Name,
+		},
+	}
+	ar := analysisRun(at, v1alpha1.RolloutTypeStepLabel, r2)
+
+	rs1 := newReplicaSetWithStatus(r1, 0, 0)
+	rs2 := newReplicaSetWithStatus(r2, 1, 1)
+	f.kubeobjects = append(f.kubeobjects, rs1, rs2)
+	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+	rs2PodHash := rs2.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+
+	r2 = updateCanaryRolloutStatus(r2, rs2PodHash, 1, 1, 1, false)
+	r2.Status.ObservedGeneration = conditions.ComputeGenerationHash(r2.Spec)
+	r2.Status.Canary.CurrentBackgroundAnalysisRun = ar.Name
+
+	f.rolloutLister = append(f.rolloutLister, r2)
+	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
+	f.analysisRunLister = append(f.analysisRunLister, ar)
+	f.objects = append(f.objects, r2, at, ar)
+
+	patchIndex := f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+
+	patch := f.getPatchedRollout(patchIndex)
+	assert.Contains(t, patch, `"currentBackgroundAnalysisRun":null`)
+}
+
+func newPromotedRolloutWithBackgroundAnalysisRun(f *fixture, terminateAnalysisOnPromote *bool) (*v1alpha1.Rollout, *v1alpha1.AnalysisRun) {
+	at := analysisTemplate("bar")
+	steps := []v1alpha1.CanaryStep{
+		{SetWeight: pointer.Int32Ptr(10)},
+		{Pause: &v1alpha1.RolloutPause{}},
+	}
+
+	r1 := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(2), intstr.FromInt(0), intstr.FromInt(1))
+	r2 := bumpVersion(r1)
+	r2.Spec.Strategy.Canary.Analysis = &v1alpha1.RolloutAnalysisBackground{
+		RolloutAnalysis: v1alpha1.RolloutAnalysis{
+			TemplateName: at.Name,
+		},
+		TerminateAnalysisOnPromote: terminateAnalysisOnPromote,
+	}
+	ar := analysisRun(at, v1alpha1.RolloutTypeBackgroundRunLabel, r2)
+	ar.Status.Phase = v1alpha1.AnalysisPhaseRunning
+
+	rs1 := newReplicaSetWithStatus(r1, 0, 0)
+	rs2 := newReplicaSetWithStatus(r2, 1, 1)
+	f.kubeobjects = append(f.kubeobjects, rs1, rs2)
+	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+	rs2PodHash := rs2.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+
+	// The rollout was promoted past the indefinite pause, so the new ReplicaSet became the stable ReplicaSet
+	r2 = updateCanaryRolloutStatus(r2, rs2PodHash, 1, 1, 1, false)
+	r2.Status.ObservedGeneration = conditions.ComputeGenerationHash(r2.Spec)
+	r2.Status.Canary.CurrentBackgroundAnalysisRun = ar.Name
+	r2.Status.Canary.CurrentBackgroundAnalysisRunStatus = &v1alpha1.RolloutAnalysisRunStatus{
+		Name:   ar.Name,
+		Status: v1alpha1.AnalysisPhaseRunning,
+	}
+
+	f.rolloutLister = append(f.rolloutLister, r2)
+	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
+	f.analysisRunLister = append(f.analysisRunLister, ar)
+	f.objects = append(f.objects, r2, at, ar)
+	return r2, ar
+}
+
+func TestCancelBackgroundAnalysisRunOnPromote(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	r2, _ := newPromotedRolloutWithBackgroundAnalysisRun(f, nil)
+
+	// Releasing the run from the rollout status makes the next sync cancel it along with the other old runs
+	patchIndex := f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+
+	patch := f.getPatchedRollout(patchIndex)
+	assert.Contains(t, patch, `"currentBackgroundAnalysisRun":null`)
+	assert.Contains(t, patch, `"currentBackgroundAnalysisRunStatus":null`)
+}
+
+func TestKeepBackgroundAnalysisRunRunningOnPromote(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	r2, _ := newPromotedRolloutWithBackgroundAnalysisRun(f, pointer.BoolPtr(false))
+
+	patchIndex := f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+
+	patch := f.getPatchedRollout(patchIndex)
+	assert.NotContains(t, patch, `currentBackgroundAnalysisRun`)
+}
+
+func TestIgnoreFailedBackgroundAnalysisRunAfterPromote(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	r2, ar := newPromotedRolloutWithBackgroundAnalysisRun(f, pointer.BoolPtr(false))
+	ar.Status.Phase = v1alpha1.AnalysisPhaseFailed
+	ar.Status.Message = "metric failed"
+
+	patchIndex := f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+
+	// The failed run is reported, but does not abort the promoted rollout
+	patch := f.getPatchedRollout(patchIndex)
+	assert.NotContains(t, patch, `"abort"`)
+	assert.Contains(t, patch, `"currentBackgroundAnalysisRunStatus":{"message":"metric failed","status":"Failed"}`)
+}
+
+func TestDoNotCreateBackgroundAnalysisRunAfterInconclusiveRun(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	at := analysisTemplate("bar")
+	steps := []v1alpha1.CanaryStep{
+		{SetWeight: pointer.Int32Ptr(10)},
+	}
+
+	r1 := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(0), intstr.FromInt(1))
+	r2 := bumpVersion(r1)
+	r2.Spec.Strategy.Canary.Analysis = &v1alpha1.RolloutAnalysisBackground{
+		RolloutAnalysis: v1alpha1.RolloutAnalysis{
+			TemplateName: at.Name,
+		},
+	}
+
+	rs1 := newReplicaSetWithStatus(r1, 1, 1)
+	rs2 := newReplicaSetWithStatus(r2, 0, 0)
+	f.kubeobjects = append(f.kubeobjects, rs1, rs2)
+	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+	rs2PodHash := rs2.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+
+	r2 = updateCanaryRolloutStatus(r2, rs2PodHash, 1, 0, 1, false)
+	r2.Status.PauseConditions = []v1alpha1.PauseCondition{{
+		Reason:    v1alpha1.PauseReasonInconclusiveAnalysis,
+		StartTime: metav1.Now(),
+	}}
+	pausedCondition, _ := newProgressingCondition(conditions.PausedRolloutReason, r2, "")
+	conditions.SetRolloutCondition(&r2.Status, pausedCondition)
+
+	f.rolloutLister = append(f.rolloutLister, r2)
+	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
+	f.objects = append(f.objects, r2, at)
+
+	patchIndex := f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+
+	patch := f.getPatchedRollout(patchIndex)
+	assert.Equal(t, calculatePatch(r2, OnlyObservedGenerationPatch), patch)
+}
+
+func TestDoNotCreateBackgroundAnalysisRunOnNewCanaryRollout(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	at := analysisTemplate("bar")
+	steps := []v1alpha1.CanaryStep{
+		{SetWeight: pointer.Int32Ptr(10)},
+	}
+
+	r1 := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(0), intstr.FromInt(1))
+	r1.Spec.Strategy.Canary.Analysis = &v1alpha1.RolloutAnalysisBackground{
+		RolloutAnalysis: v1alpha1.RolloutAnalysis{
+			TemplateName: at.Name,
+		},
+	}
+	r1.Status.CurrentPodHash = ""
+	rs1 := newReplicaSet(r1, 1)
+
+	f.rolloutLister = append(f.rolloutLister, r1)
+	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
+	f.objects = append(f.objects, r1, at)
+
+	f.expectCreateReplicaSetAction(rs1)
+	// Update the revision
 	f.expectUpdateRolloutAction(r1)
 	f.expectPatchRolloutAction(r1)
 	f.run(getKey(r1, t))
@@ -1768,18 +2718,277 @@ func TestRolloutPrePromotionAnalysisHonorAutoPromotionSeconds(t *testing.T) {
 		Status: v1alpha1.AnalysisPhaseRunning,
 	}
 
-	rs1 := newReplicaSetWithStatus(r1, 1, 1)
+	rs1 := newReplicaSetWithStatus(r1, 1, 1)
+	rs2 := newReplicaSetWithStatus(r2, 1, 1)
+	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+	rs2PodHash := rs2.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+
+	r2 = updateBlueGreenRolloutStatus(r2, "", rs1PodHash, rs1PodHash, 1, 1, 2, 1, true, true)
+	now := metav1.NewTime(metav1.Now().Add(-10 * time.Second))
+	r2.Status.PauseConditions[0].StartTime = now
+	pausedCondition, _ := newProgressingCondition(conditions.PausedRolloutReason, r2, "")
+	conditions.SetRolloutCondition(&r2.Status, pausedCondition)
+
+	activeSelector := map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: rs1PodHash}
+	activeSvc := newService("active", 80, activeSelector, r2)
+
+	f.objects = append(f.objects, r2, at, ar)
+	f.kubeobjects = append(f.kubeobjects, activeSvc, rs1, rs2)
+	f.rolloutLister = append(f.rolloutLister, r2)
+	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
+	f.analysisRunLister = append(f.analysisRunLister, ar)
+	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+	f.serviceLister = append(f.serviceLister, activeSvc)
+
+	f.expectPatchServiceAction(activeSvc, rs2PodHash)
+	f.expectPatchReplicaSetAction(rs1)
+	patchIndex := f.expectPatchRolloutActionWithPatch(r2, OnlyObservedGenerationPatch)
+	f.run(getKey(r2, t))
+	patch := f.getPatchedRollout(patchIndex)
+	expectedPatch := fmt.Sprintf(`{
+		"status": {
+			"blueGreen": {
+				"activeSelector": "%s"
+			},
+			"pauseConditions": null,
+			"controllerPause": null,
+			"selector":"foo=bar,rollouts-pod-template-hash=%s"
+		}
+	}`, rs2PodHash, rs2PodHash)
+	assert.Equal(t, calculatePatch(r2, expectedPatch), patch)
+}
+
+func TestRolloutPrePromotionAnalysisDoNothingOnInconclusiveAnalysis(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	at := analysisTemplate("bar")
+	r1 := newBlueGreenRollout("foo", 1, nil, "active", "")
+	r1.Spec.Strategy.BlueGreen.AutoPromotionEnabled = pointer.BoolPtr(false)
+	r2 := bumpVersion(r1)
+	r2.Spec.Strategy.BlueGreen.PrePromotionAnalysis = &v1alpha1.RolloutAnalysis{
+		Templates: []v1alpha1.RolloutAnalysisTemplate{{
+			TemplateName: at.Name,
+		}},
+	}
+	ar := analysisRun(at, v1alpha1.RolloutTypePrePromotionLabel, r2)
+	ar.Status.Phase = v1alpha1.AnalysisPhaseInconclusive
+	r2.Status.BlueGreen.PrePromotionAnalysisRun = ar.Name
+
+	rs1 := newReplicaSetWithStatus(r1, 1, 1)
+	rs2 := newReplicaSetWithStatus(r2, 1, 1)
+	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+
+	r2 = updateBlueGreenRolloutStatus(r2, "", rs1PodHash, rs1PodHash, 1, 1, 2, 1, true, true)
+	inconclusivePauseCondition := v1alpha1.PauseCondition{
+		Reason:    v1alpha1.PauseReasonInconclusiveAnalysis,
+		StartTime: metav1.Now(),
+	}
+	r2.Status.PauseConditions = append(r2.Status.PauseConditions, inconclusivePauseCondition)
+	r2.Status.ObservedGeneration = conditions.ComputeGenerationHash(r2.Spec)
+	pausedCondition, _ := newProgressingCondition(conditions.PausedRolloutReason, r2, "")
+	conditions.SetRolloutCondition(&r2.Status, pausedCondition)
+
+	activeSelector := map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: rs1PodHash}
+	activeSvc := newService("active", 80, activeSelector, r2)
+
+	f.objects = append(f.objects, r2, at, ar)
+	f.kubeobjects = append(f.kubeobjects, activeSvc, rs1, rs2)
+	f.rolloutLister = append(f.rolloutLister, r2)
+	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
+	f.analysisRunLister = append(f.analysisRunLister, ar)
+	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+	f.serviceLister = append(f.serviceLister, activeSvc)
+
+	f.expectPatchRolloutActionWithPatch(r2, OnlyObservedGenerationPatch)
+	f.run(getKey(r2, t))
+}
+
+func TestRolloutPrePromotionAnalysisRequireManualApprovalAfterSuccess(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	at := analysisTemplate("bar")
+	r1 := newBlueGreenRollout("foo", 1, nil, "active", "")
+	r1.Spec.Strategy.BlueGreen.AutoPromotionEnabled = pointer.BoolPtr(true)
+	r1.Spec.Strategy.BlueGreen.RequireManualApproval = true
+	r2 := bumpVersion(r1)
+	r2.Spec.Strategy.BlueGreen.PrePromotionAnalysis = &v1alpha1.RolloutAnalysis{
+		Templates: []v1alpha1.RolloutAnalysisTemplate{{
+			TemplateName: at.Name,
+		}},
+	}
+	ar := analysisRun(at, v1alpha1.RolloutTypePrePromotionLabel, r2)
+	ar.Status.Phase = v1alpha1.AnalysisPhaseSuccessful
+	r2.Status.BlueGreen.PrePromotionAnalysisRun = ar.Name
+
+	rs1 := newReplicaSetWithStatus(r1, 1, 1)
+	rs2 := newReplicaSetWithStatus(r2, 1, 1)
+	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+
+	r2 = updateBlueGreenRolloutStatus(r2, "", rs1PodHash, rs1PodHash, 1, 1, 2, 1, true, true)
+	r2.Status.BlueGreen.PrePromotionAnalysisRunStatus = &v1alpha1.RolloutAnalysisRunStatus{
+		Name:   ar.Name,
+		Status: v1alpha1.AnalysisPhaseSuccessful,
+	}
+	r2.Status.ObservedGeneration = conditions.ComputeGenerationHash(r2.Spec)
+	pausedCondition, _ := newProgressingCondition(conditions.PausedRolloutReason, r2, "")
+	conditions.SetRolloutCondition(&r2.Status, pausedCondition)
+
+	activeSelector := map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: rs1PodHash}
+	activeSvc := newService("active", 80, activeSelector, r2)
+
+	f.objects = append(f.objects, r2, at, ar)
+	f.kubeobjects = append(f.kubeobjects, activeSvc, rs1, rs2)
+	f.rolloutLister = append(f.rolloutLister, r2)
+	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
+	f.analysisRunLister = append(f.analysisRunLister, ar)
+	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+	f.serviceLister = append(f.serviceLister, activeSvc)
+
+	// The active service is not switched until the rollout is manually promoted
+	f.expectPatchRolloutActionWithPatch(r2, OnlyObservedGenerationPatch)
+	f.run(getKey(r2, t))
+}
+
+func TestAbortRolloutOnErrorPrePromotionAnalysis(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	at := analysisTemplate("bar")
+	r1 := newBlueGreenRollout("foo", 1, nil, "active", "")
+	r1.Spec.Strategy.BlueGreen.AutoPromotionEnabled = pointer.BoolPtr(false)
+	r2 := bumpVersion(r1)
+	r2.Spec.Strategy.BlueGreen.PrePromotionAnalysis = &v1alpha1.RolloutAnalysis{
+		Templates: []v1alpha1.RolloutAnalysisTemplate{{
+			TemplateName: at.Name,
+		}},
+	}
+	ar := analysisRun(at, v1alpha1.RolloutTypePrePromotionLabel, r2)
+	ar.Status.Phase = v1alpha1.AnalysisPhaseError
+	r2.Status.BlueGreen.PrePromotionAnalysisRun = ar.Name
+	r2.Status.BlueGreen.PrePromotionAnalysisRunStatus = &v1alpha1.RolloutAnalysisRunStatus{
+		Name:   ar.Name,
+		Status: v1alpha1.AnalysisPhaseRunning,
+	}
+
+	rs1 := newReplicaSetWithStatus(r1, 1, 1)
+	rs2 := newReplicaSetWithStatus(r2, 1, 1)
+	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+
+	r2 = updateBlueGreenRolloutStatus(r2, "", rs1PodHash, rs1PodHash, 1, 1, 2, 1, true, true)
+	pausedCondition, _ := newProgressingCondition(conditions.PausedRolloutReason, r2, "")
+	conditions.SetRolloutCondition(&r2.Status, pausedCondition)
+
+	activeSelector := map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: rs1PodHash}
+	activeSvc := newService("active", 80, activeSelector, r2)
+
+	f.objects = append(f.objects, r2, at, ar)
+	f.kubeobjects = append(f.kubeobjects, activeSvc, rs1, rs2)
+	f.rolloutLister = append(f.rolloutLister, r2)
+	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
+	f.analysisRunLister = append(f.analysisRunLister, ar)
+	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+	f.serviceLister = append(f.serviceLister, activeSvc)
+
+	patchIndex := f.expectPatchRolloutActionWithPatch(r2, OnlyObservedGenerationPatch)
+	f.run(getKey(r2, t))
+	patch := f.getPatchedRollout(patchIndex)
+	// is wrong
+	expectedPatch := `{
+		"status": {
+			"abort": true,
+			"abortedAt": "%s",
+			"pauseConditions": null,
+			"controllerPause":null,
+			"blueGreen": {
+				"prePromotionAnalysisRunStatus": {
+					"status": "Error"
+				}
+			}
+		}
+	}`
+	now := metav1.Now().UTC().Format(time.RFC3339)
+	assert.Equal(t, calculatePatch(r2, fmt.Sprintf(expectedPatch, now)), patch)
+}
+
+func TestCreatePostPromotionAnalysisRun(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	at := analysisTemplate("bar")
+	r1 := newBlueGreenRollout("foo", 1, nil, "active", "")
+	r2 := bumpVersion(r1)
+	r2.Spec.Strategy.BlueGreen.PostPromotionAnalysis = &v1alpha1.RolloutAnalysis{
+		Templates: []v1alpha1.RolloutAnalysisTemplate{{
+			TemplateName: at.Name,
+		}},
+	}
+	ar := analysisRun(at, v1alpha1.RolloutTypePostPromotionLabel, r2)
+	rs1 := newReplicaSetWithStatus(r1, 1, 1)
+	rs1.Annotations[v1alpha1.DefaultReplicaSetScaleDownDeadlineAnnotationKey] = nowFn().Add(10 * time.Second).Format(time.RFC3339)
+	rs2 := newReplicaSetWithStatus(r2, 1, 1)
+	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+	rs2PodHash := rs2.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+
+	r2 = updateBlueGreenRolloutStatus(r2, "", rs2PodHash, rs1PodHash, 1, 1, 2, 1, false, true)
+
+	activeSelector := map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: rs2PodHash}
+	activeSvc := newService("active", 80, activeSelector, r2)
+
+	f.objects = append(f.objects, r2, at)
+	f.kubeobjects = append(f.kubeobjects, activeSvc, rs1, rs2)
+	f.rolloutLister = append(f.rolloutLister, r2)
+	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
+	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+	f.serviceLister = append(f.serviceLister, activeSvc)
+
+	f.expectCreateAnalysisRunAction(ar)
+	patchIndex := f.expectPatchRolloutActionWithPatch(r2, OnlyObservedGenerationPatch)
+	f.run(getKey(r2, t))
+	patch := f.getPatchedRollout(patchIndex)
+	expectedPatch := fmt.Sprintf(`{
+		"status": {
+			"blueGreen": {
+				"postPromotionAnalysisRun": "%s",
+				"postPromotionAnalysisRunStatus":{
+					"name": "%s", 
+					"status": ""
+				}
+			}
+		}
+	}`, ar.Name, ar.Name)
+	assert.Equal(t, calculatePatch(r2, expectedPatch), patch)
+}
+
+func TestRolloutPostPromotionAnalysisSuccess(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	at := analysisTemplate("bar")
+	r1 := newBlueGreenRollout("foo", 1, nil, "active", "")
+	r2 := bumpVersion(r1)
+	r2.Spec.Strategy.BlueGreen.PostPromotionAnalysis = &v1alpha1.RolloutAnalysis{
+		Templates: []v1alpha1.RolloutAnalysisTemplate{{
+			TemplateName: at.Name,
+		}},
+	}
+	ar := analysisRun(at, v1alpha1.RolloutTypePostPromotionLabel, r2)
+	ar.Status.Phase = v1alpha1.AnalysisPhaseSuccessful
+	r2.Status.BlueGreen.PostPromotionAnalysisRun = ar.Name
+	r2.Status.BlueGreen.PostPromotionAnalysisRunStatus = &v1alpha1.RolloutAnalysisRunStatus{
+		Name:   ar.Name,
+		Status: v1alpha1.AnalysisPhaseRunning,
+	}
+
+	rs1 := newReplicaSetWithStatus(r1, 0, 0)
 	rs2 := newReplicaSetWithStatus(r2, 1, 1)
 	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
 	rs2PodHash := rs2.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
 
-	r2 = updateBlueGreenRolloutStatus(r2, "", rs1PodHash, rs1PodHash, 1, 1, 2, 1, true, true)
-	now := metav1.NewTime(metav1.Now().Add(-10 * time.Second))
-	r2.Status.PauseConditions[0].StartTime = now
-	pausedCondition, _ := newProgressingCondition(conditions.PausedRolloutReason, r2, "")
-	conditions.SetRolloutCondition(&r2.Status, pausedCondition)
+	r2 = updateBlueGreenRolloutStatus(r2, "", rs2PodHash, rs1PodHash, 1, 1, 1, 1, false, true)
 
-	activeSelector := map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: rs1PodHash}
+	activeSelector := map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: rs2PodHash}
 	activeSvc := newService("active", 80, activeSelector, r2)
 
 	f.objects = append(f.objects, r2, at, ar)
@@ -1790,100 +2999,107 @@ func TestRolloutPrePromotionAnalysisHonorAutoPromotionSeconds(t *testing.T) {
 	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
 	f.serviceLister = append(f.serviceLister, activeSvc)
 
-	f.expectPatchServiceAction(activeSvc, rs2PodHash)
-	f.expectPatchReplicaSetAction(rs1)
-	patchIndex := f.expectPatchRolloutActionWithPatch(r2, OnlyObservedGenerationPatch)
+	patchIndex := f.expectPatchRolloutAction(r2)
 	f.run(getKey(r2, t))
 	patch := f.getPatchedRollout(patchIndex)
 	expectedPatch := fmt.Sprintf(`{
 		"status": {
+			"stableRS": "%s",
 			"blueGreen": {
-				"activeSelector": "%s"
-			},
-			"pauseConditions": null,
-			"controllerPause": null,
-			"selector":"foo=bar,rollouts-pod-template-hash=%s"
+				"postPromotionAnalysisRunStatus": null,
+				"postPromotionAnalysisRun": null
+			}
 		}
-	}`, rs2PodHash, rs2PodHash)
+	}`, rs2PodHash)
 	assert.Equal(t, calculatePatch(r2, expectedPatch), patch)
 }
 
-func TestRolloutPrePromotionAnalysisDoNothingOnInconclusiveAnalysis(t *testing.T) {
+// TestPostPromotionAnalysisRunHandleInconclusive ensures that the Rollout does not scale down a old ReplicaSet if
+// it's paused for a inconclusive analysis run
+func TestPostPromotionAnalysisRunHandleInconclusive(t *testing.T) {
 	f := newFixture(t)
 	defer f.Close()
 
 	at := analysisTemplate("bar")
 	r1 := newBlueGreenRollout("foo", 1, nil, "active", "")
-	r1.Spec.Strategy.BlueGreen.AutoPromotionEnabled = pointer.BoolPtr(false)
 	r2 := bumpVersion(r1)
-	r2.Spec.Strategy.BlueGreen.PrePromotionAnalysis = &v1alpha1.RolloutAnalysis{
+	r2.Spec.Strategy.BlueGreen.PostPromotionAnalysis = &v1alpha1.RolloutAnalysis{
 		Templates: []v1alpha1.RolloutAnalysisTemplate{{
 			TemplateName: at.Name,
 		}},
 	}
-	ar := analysisRun(at, v1alpha1.RolloutTypePrePromotionLabel, r2)
+	ar := analysisRun(at, v1alpha1.RolloutTypePostPromotionLabel, r2)
 	ar.Status.Phase = v1alpha1.AnalysisPhaseInconclusive
-	r2.Status.BlueGreen.PrePromotionAnalysisRun = ar.Name
+	r2.Status.BlueGreen.PostPromotionAnalysisRun = ar.Name
 
 	rs1 := newReplicaSetWithStatus(r1, 1, 1)
+	rs1.Annotations[v1alpha1.DefaultReplicaSetScaleDownDeadlineAnnotationKey] = nowFn().Add(-10 * time.Second).Format(time.RFC3339)
 	rs2 := newReplicaSetWithStatus(r2, 1, 1)
 	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+	rs2PodHash := rs2.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
 
-	r2 = updateBlueGreenRolloutStatus(r2, "", rs1PodHash, rs1PodHash, 1, 1, 2, 1, true, true)
-	inconclusivePauseCondition := v1alpha1.PauseCondition{
+	r2 = updateBlueGreenRolloutStatus(r2, "", rs2PodHash, rs1PodHash, 1, 1, 2, 1, false, true)
+	r2.Status.PauseConditions = []v1alpha1.PauseCondition{{
 		Reason:    v1alpha1.PauseReasonInconclusiveAnalysis,
 		StartTime: metav1.Now(),
-	}
-	r2.Status.PauseConditions = append(r2.Status.PauseConditions, inconclusivePauseCondition)
-	r2.Status.ObservedGeneration = conditions.ComputeGenerationHash(r2.Spec)
+	}}
 	pausedCondition, _ := newProgressingCondition(conditions.PausedRolloutReason, r2, "")
 	conditions.SetRolloutCondition(&r2.Status, pausedCondition)
 
-	activeSelector := map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: rs1PodHash}
+	activeSelector := map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: rs2PodHash}
 	activeSvc := newService("active", 80, activeSelector, r2)
 
-	f.objects = append(f.objects, r2, at, ar)
+	f.objects = append(f.objects, r2, at)
 	f.kubeobjects = append(f.kubeobjects, activeSvc, rs1, rs2)
 	f.rolloutLister = append(f.rolloutLister, r2)
 	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
-	f.analysisRunLister = append(f.analysisRunLister, ar)
 	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
 	f.serviceLister = append(f.serviceLister, activeSvc)
 
-	f.expectPatchRolloutActionWithPatch(r2, OnlyObservedGenerationPatch)
+	patchIndex := f.expectPatchRolloutActionWithPatch(r2, OnlyObservedGenerationPatch)
 	f.run(getKey(r2, t))
+	patch := f.getPatchedRollout(patchIndex)
+	expectedPatch := fmt.Sprint(`{
+		"status": {
+			"blueGreen": {
+				"postPromotionAnalysisRun": null
+			}
+		}
+	}`)
+	assert.Equal(t, calculatePatch(r2, expectedPatch), patch)
 }
 
-func TestAbortRolloutOnErrorPrePromotionAnalysis(t *testing.T) {
+func TestAbortRolloutOnErrorPostPromotionAnalysis(t *testing.T) {
 	f := newFixture(t)
 	defer f.Close()
 
 	at := analysisTemplate("bar")
 	r1 := newBlueGreenRollout("foo", 1, nil, "active", "")
-	r1.Spec.Strategy.BlueGreen.AutoPromotionEnabled = pointer.BoolPtr(false)
 	r2 := bumpVersion(r1)
-	r2.Spec.Strategy.BlueGreen.PrePromotionAnalysis = &v1alpha1.RolloutAnalysis{
+	r2.Spec.Strategy.BlueGreen.PostPromotionAnalysis = &v1alpha1.RolloutAnalysis{
 		Templates: []v1alpha1.RolloutAnalysisTemplate{{
 			TemplateName: at.Name,
 		}},
 	}
-	ar := analysisRun(at, v1alpha1.RolloutTypePrePromotionLabel, r2)
+	ar := analysisRun(at, v1alpha1.RolloutTypePostPromotionLabel, r2)
 	ar.Status.Phase = v1alpha1.AnalysisPhaseError
-	r2.Status.BlueGreen.PrePromotionAnalysisRun = ar.Name
-	r2.Status.BlueGreen.PrePromotionAnalysisRunStatus = &v1alpha1.RolloutAnalysisRunStatus{
+	r2.Status.BlueGreen.PostPromotionAnalysisRun = ar.Name
+	r2.Status.BlueGreen.PostPromotionAnalysisRunStatus = &v1alpha1.RolloutAnalysisRunStatus{
 		Name:   ar.Name,
 		Status: v1alpha1.AnalysisPhaseRunning,
 	}
 
 	rs1 := newReplicaSetWithStatus(r1, 1, 1)
+	rs1.Annotations[v1alpha1.DefaultReplicaSetScaleDownDeadlineAnnotationKey] = nowFn().Add(10 * time.Second).Format(time.RFC3339)
 	rs2 := newReplicaSetWithStatus(r2, 1, 1)
 	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+	rs2PodHash := rs2.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
 
-	r2 = updateBlueGreenRolloutStatus(r2, "", rs1PodHash, rs1PodHash, 1, 1, 2, 1, true, true)
+	r2 = updateBlueGreenRolloutStatus(r2, "", rs2PodHash, rs1PodHash, 1, 1, 2, 1, true, true)
 	pausedCondition, _ := newProgressingCondition(conditions.PausedRolloutReason, r2, "")
 	conditions.SetRolloutCondition(&r2.Status, pausedCondition)
 
-	activeSelector := map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: rs1PodHash}
+	activeSelector := map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: rs2PodHash}
 	activeSvc := newService("active", 80, activeSelector, r2)
 
 	f.objects = append(f.objects, r2, at, ar)
@@ -1896,213 +3112,768 @@ func TestAbortRolloutOnErrorPrePromotionAnalysis(t *testing.T) {
 
 	patchIndex := f.expectPatchRolloutActionWithPatch(r2, OnlyObservedGenerationPatch)
 	f.run(getKey(r2, t))
-	patch := f.getPatchedRollout(patchIndex)
-	// is wrong
+	patch := f.getPatchedRollout(patchIndex)
+	expectedPatch := `{
+		"status": {
+			"abort": true,
+			"abortedAt": "%s",
+			"pauseConditions": null,
+			"controllerPause":null,
+			"blueGreen": {
+				"postPromotionAnalysisRunStatus": {
+					"status": "Error"
+				}
+			}
+		}
+	}`
+	now := metav1.Now().UTC().Format(time.RFC3339)
+	assert.Equal(t, calculatePatch(r2, fmt.Sprintf(expectedPatch, now)), patch)
+}
+
+// newMaxConcurrentAnalysisRunsFixture returns a fixture with a rollout limited to one running AnalysisRun, which is at
+// an analysis step and has a background AnalysisRun of the given phase
+func newMaxConcurrentAnalysisRunsFixture(t *testing.T, at *v1alpha1.AnalysisTemplate, backgroundPhase v1alpha1.AnalysisPhase) (*fixture, *v1alpha1.Rollout, string) {
+	f := newFixture(t)
+	steps := []v1alpha1.CanaryStep{{
+		Analysis: &v1alpha1.RolloutAnalysis{
+			TemplateName: at.Name,
+		},
+	}}
+
+	r1 := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(0), intstr.FromInt(1))
+	r1.Spec.MaxConcurrentAnalysisRuns = pointer.Int32Ptr(1)
+	r2 := bumpVersion(r1)
+	r2.Spec.Strategy.Canary.Analysis = &v1alpha1.RolloutAnalysisBackground{
+		RolloutAnalysis: v1alpha1.RolloutAnalysis{
+			TemplateName: at.Name,
+		},
+	}
+	backgroundAr := analysisRun(at, v1alpha1.RolloutTypeBackgroundRunLabel, r2)
+	backgroundAr.Status.Phase = backgroundPhase
+
+	rs1 := newReplicaSetWithStatus(r1, 1, 1)
+	rs2 := newReplicaSetWithStatus(r2, 0, 0)
+	f.kubeobjects = append(f.kubeobjects, rs1, rs2)
+	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+	rs2PodHash := rs2.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+
+	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 1, 0, 1, false)
+	progressingCondition, _ := newProgressingCondition(conditions.ReplicaSetUpdatedReason, rs2, "")
+	conditions.SetRolloutCondition(&r2.Status, progressingCondition)
+	availableCondition, _ := newAvailableCondition(true)
+	conditions.SetRolloutCondition(&r2.Status, availableCondition)
+	r2.Status.Canary.CurrentBackgroundAnalysisRun = backgroundAr.Name
+	r2.Status.Canary.CurrentBackgroundAnalysisRunStatus = &v1alpha1.RolloutAnalysisRunStatus{
+		Name:   backgroundAr.Name,
+		Status: backgroundPhase,
+	}
+
+	f.rolloutLister = append(f.rolloutLister, r2)
+	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
+	f.analysisRunLister = append(f.analysisRunLister, backgroundAr)
+	f.objects = append(f.objects, r2, at, backgroundAr)
+	return f, r2, rs2PodHash
+}
+
+func TestCreateStepAnalysisRunWhileBackgroundAnalysisRunRunning(t *testing.T) {
+	at := analysisTemplate("bar")
+	f, r2, rs2PodHash := newMaxConcurrentAnalysisRunsFixture(t, at, v1alpha1.AnalysisPhaseRunning)
+	defer f.Close()
+
+	ar := analysisRun(at, v1alpha1.RolloutTypeStepLabel, r2)
+	createdIndex := f.expectCreateAnalysisRunAction(ar)
+	patchIndex := f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+
+	// the background AnalysisRun, which can run until the rollout completes, does not hold a slot
+	createdAr := f.getCreatedAnalysisRun(createdIndex)
+	expectedArName := fmt.Sprintf("%s-%s-%s-%s-%s", r2.Name, rs2PodHash, "2", "0", at.Name)
+	assert.Equal(t, expectedArName, createdAr.Name)
+	patch := f.getPatchedRollout(patchIndex)
+	assert.Contains(t, patch, fmt.Sprintf(`"currentStepAnalysisRun":"%s"`, expectedArName))
+}
+
+// newMaxConcurrentAggregatedStepCtx returns the context of a rollout limited to one running AnalysisRun, which is at
+// an aggregated analysis step of two templates whose first AnalysisRun has the given phase
+func newMaxConcurrentAggregatedStepCtx(phase v1alpha1.AnalysisPhase) *canaryContext {
+	steps := []v1alpha1.CanaryStep{{
+		Analysis: &v1alpha1.RolloutAnalysis{
+			Templates:   []v1alpha1.RolloutAnalysisTemplate{{TemplateName: "template-0"}, {TemplateName: "template-1"}},
+			Aggregation: v1alpha1.AnalysisAggregationAll,
+		},
+	}}
+	r := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(0), intstr.FromInt(1))
+	r.Spec.MaxConcurrentAnalysisRuns = pointer.Int32Ptr(1)
+	ar := aggregatedStepAnalysisRun(r, 0, phase)
+	r.Status.Canary.CurrentStepAnalysisRuns = []v1alpha1.RolloutAnalysisRunStatus{{Name: ar.Name}}
+	return newCanaryCtx(r, nil, nil, nil, []*v1alpha1.AnalysisRun{ar})
+}
+
+func TestQueueAggregatedStepAnalysisRunWhileMaxConcurrentAnalysisRunsRunning(t *testing.T) {
+	roCtx := newMaxConcurrentAggregatedStepCtx(v1alpha1.AnalysisPhaseRunning)
+	recorder := record.NewFakeRecorder(1)
+	c := &Controller{recorder: recorder}
+
+	ars, err := c.reconcileAggregatedStepAnalysisRuns(roCtx, newAnalysisRunLimiter(roCtx))
+	assert.NoError(t, err)
+	// the AnalysisRun of the second template is not created while the first one is running
+	assert.Len(t, ars, 1)
+	assert.Equal(t, "Normal AnalysisRunQueued Queueing step AnalysisRun of template 'template-1': 1 AnalysisRuns are already running", <-recorder.Events)
+}
+
+func TestCreateQueuedAggregatedStepAnalysisRunAfterAnalysisRunCompleted(t *testing.T) {
+	roCtx := newMaxConcurrentAggregatedStepCtx(v1alpha1.AnalysisPhaseSuccessful)
+	limiter := newAnalysisRunLimiter(roCtx)
+	// the slot of the completed AnalysisRun is free for the queued AnalysisRun
+	assert.Equal(t, int32(0), limiter.running)
+	assert.True(t, limiter.tryAcquire())
+}
+
+func TestAnalysisRunLimiterExcludesBackgroundAnalysisRun(t *testing.T) {
+	roCtx := newMaxConcurrentAggregatedStepCtx(v1alpha1.AnalysisPhaseRunning)
+	currentArs := roCtx.CurrentAnalysisRuns()
+	currentArs.CanaryBackground = &v1alpha1.AnalysisRun{
+		Status: v1alpha1.AnalysisRunStatus{Phase: v1alpha1.AnalysisPhaseRunning},
+	}
+	roCtx.SetCurrentAnalysisRuns(currentArs)
+
+	limiter := newAnalysisRunLimiter(roCtx)
+	assert.Equal(t, int32(1), limiter.running)
+}
+
+func TestAnalysisRunLimiter(t *testing.T) {
+	limiter := &analysisRunLimiter{}
+	assert.True(t, limiter.tryAcquire())
+	assert.True(t, limiter.tryAcquire())
+
+	limiter = &analysisRunLimiter{max: pointer.Int32Ptr(2), running: 1}
+	assert.True(t, limiter.tryAcquire())
+	assert.False(t, limiter.tryAcquire())
+	assert.Equal(t, int32(2), limiter.running)
+}
+
+func newPreTrafficAnalysisFixture(t *testing.T, at *v1alpha1.AnalysisTemplate, canaryReplicas int, stepPhase v1alpha1.AnalysisPhase) (*fixture, *v1alpha1.Rollout, *appsv1.ReplicaSet) {
+	f := newFixture(t)
+	steps := []v1alpha1.CanaryStep{
+		{
+			Analysis: &v1alpha1.RolloutAnalysis{
+				TemplateName: at.Name,
+			},
+		},
+		{SetWeight: pointer.Int32Ptr(20)},
+	}
+
+	r1 := newCanaryRollout("foo", 10, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(1), intstr.FromInt(0))
+	r2 := bumpVersion(r1)
+	r2.Spec.Strategy.Canary.TrafficRouting = &v1alpha1.RolloutTrafficRouting{}
+	r2.Spec.Strategy.Canary.CanaryService = "canary"
+	r2.Spec.Strategy.Canary.StableService = "stable"
+
+	rs1 := newReplicaSetWithStatus(r1, 10, 10)
+	rs2 := newReplicaSetWithStatus(r2, canaryReplicas, canaryReplicas)
+	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+	rs2PodHash := rs2.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+	canarySvc := newService("canary", 80, map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: rs2PodHash}, r2)
+	stableSvc := newService("stable", 80, map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: rs1PodHash}, r2)
+	f.kubeobjects = append(f.kubeobjects, rs1, rs2, canarySvc, stableSvc)
+	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+
+	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 10+int32(canaryReplicas), int32(canaryReplicas), 10+int32(canaryReplicas), false)
+	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
+	f.objects = append(f.objects, at)
+	if stepPhase != "" {
+		ar := analysisRun(at, v1alpha1.RolloutTypeStepLabel, r2)
+		ar.Status.Phase = stepPhase
+		r2.Status.Canary.CurrentStepAnalysisRun = ar.Name
+		r2.Status.Canary.CurrentStepAnalysisRunStatus = &v1alpha1.RolloutAnalysisRunStatus{
+			Name:   ar.Name,
+			Status: stepPhase,
+		}
+		f.analysisRunLister = append(f.analysisRunLister, ar)
+		f.objects = append(f.objects, ar)
+	}
+	f.rolloutLister = append(f.rolloutLister, r2)
+	f.objects = append(f.objects, r2)
+	return f, r2, rs2
+}
+
+func TestScaleUpCanaryBeforePreTrafficAnalysisRun(t *testing.T) {
+	at := analysisTemplate("bar")
+	f, r2, rs2 := newPreTrafficAnalysisFixture(t, at, 0, "")
+	defer f.Close()
+
+	// the canary is scaled up while the traffic stays on the stable, and the AnalysisRun is not created until the
+	// canary is available
+	updatedRSIndex := f.expectUpdateReplicaSetAction(rs2)
+	f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+
+	updatedRS := f.getUpdatedReplicaSet(updatedRSIndex)
+	assert.Equal(t, replicasetutil.PreTrafficAnalysisReplicas, *updatedRS.Spec.Replicas)
+	assert.Equal(t, int32(0), f.fakeTrafficRouting.controllerSetDesiredWeight)
+}
+
+func TestCreatePreTrafficAnalysisRunWhenCanaryAvailable(t *testing.T) {
+	at := analysisTemplate("bar")
+	f, r2, rs2 := newPreTrafficAnalysisFixture(t, at, 1, "")
+	defer f.Close()
+
+	ar := analysisRun(at, v1alpha1.RolloutTypeStepLabel, r2)
+	createdIndex := f.expectCreateAnalysisRunAction(ar)
+	f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+
+	createdAr := f.getCreatedAnalysisRun(createdIndex)
+	rs2PodHash := rs2.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+	expectedArName := fmt.Sprintf("%s-%s-%s-%s-%s", r2.Name, rs2PodHash, "2", "0", at.Name)
+	assert.Equal(t, expectedArName, createdAr.Name)
+	assert.Equal(t, int32(0), f.fakeTrafficRouting.controllerSetDesiredWeight)
+}
+
+func TestPinTrafficToStableWhilePreTrafficAnalysisRunRunning(t *testing.T) {
+	at := analysisTemplate("bar")
+	f, r2, _ := newPreTrafficAnalysisFixture(t, at, 1, v1alpha1.AnalysisPhaseRunning)
+	defer f.Close()
+
+	// the canary stays scaled up without any traffic while the AnalysisRun is running
+	patchIndex := f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+
+	patch := f.getPatchedRollout(patchIndex)
+	var patchObj map[string]interface{}
+	err := json.Unmarshal([]byte(patch), &patchObj)
+	assert.NoError(t, err)
+	status := patchObj["status"].(map[string]interface{})
+	_, ok := status["currentStepIndex"]
+	assert.False(t, ok)
+	assert.Equal(t, int32(0), f.fakeTrafficRouting.controllerSetDesiredWeight)
+}
+
+func TestSetWeightAfterSuccessfulPreTrafficAnalysisRun(t *testing.T) {
+	at := analysisTemplate("bar")
+	f, r2, _ := newPreTrafficAnalysisFixture(t, at, 1, v1alpha1.AnalysisPhaseSuccessful)
+	defer f.Close()
+
+	patchIndex := f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+
+	// the rollout proceeds to the setWeight step once the analysis passes
+	patch := f.getPatchedRollout(patchIndex)
+	var patchObj map[string]interface{}
+	err := json.Unmarshal([]byte(patch), &patchObj)
+	assert.NoError(t, err)
+	status := patchObj["status"].(map[string]interface{})
+	assert.Equal(t, float64(1), status["currentStepIndex"])
+	assert.Equal(t, int32(0), f.fakeTrafficRouting.controllerSetDesiredWeight)
+
+	r2.Status.CurrentStepIndex = pointer.Int32Ptr(1)
+	assert.False(t, replicasetutil.IsPreTrafficAnalysisStep(r2))
+	assert.Equal(t, int32(20), replicasetutil.GetCurrentSetWeight(r2))
+}
+
+func TestCreateAnalysisRunsOnAggregatedAnalysisStep(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	at1 := analysisTemplate("bar")
+	at2 := analysisTemplate("baz")
+	steps := []v1alpha1.CanaryStep{{
+		Analysis: &v1alpha1.RolloutAnalysis{
+			Templates:   []v1alpha1.RolloutAnalysisTemplate{{TemplateName: at1.Name}, {TemplateName: at2.Name}},
+			Aggregation: v1alpha1.AnalysisAggregationAll,
+		},
+	}}
+
+	r1 := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(0), intstr.FromInt(1))
+	r2 := bumpVersion(r1)
+	ar1 := analysisRun(at1, v1alpha1.RolloutTypeStepLabel, r2)
+	ar2 := analysisRun(at2, v1alpha1.RolloutTypeStepLabel, r2)
+
+	rs1 := newReplicaSetWithStatus(r1, 1, 1)
+	rs2 := newReplicaSetWithStatus(r2, 0, 0)
+	f.kubeobjects = append(f.kubeobjects, rs1, rs2)
+	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+	rs2PodHash := rs2.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+
+	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 1, 0, 1, false)
+	progressingCondition, _ := newProgressingCondition(conditions.ReplicaSetUpdatedReason, rs2, "")
+	conditions.SetRolloutCondition(&r2.Status, progressingCondition)
+	availableCondition, _ := newAvailableCondition(true)
+	conditions.SetRolloutCondition(&r2.Status, availableCondition)
+
+	f.rolloutLister = append(f.rolloutLister, r2)
+	f.analysisTemplateLister = append(f.analysisTemplateLister, at1, at2)
+	f.objects = append(f.objects, r2, at1, at2)
+
+	createdIndex1 := f.expectCreateAnalysisRunAction(ar1)
+	createdIndex2 := f.expectCreateAnalysisRunAction(ar2)
+	index := f.expectPatchRolloutAction(r1)
+
+	f.run(getKey(r2, t))
+	createdAr1 := f.getCreatedAnalysisRun(createdIndex1)
+	expectedArName1 := fmt.Sprintf("%s-%s-%s-%s-%s", r2.Name, rs2PodHash, "2", "0", at1.Name)
+	assert.Equal(t, expectedArName1, createdAr1.Name)
+	assert.Equal(t, "0", createdAr1.Labels[v1alpha1.RolloutAnalysisTemplateIndexLabel])
+	assert.Equal(t, "0", createdAr1.Labels[v1alpha1.RolloutCanaryStepIndexLabel])
+	createdAr2 := f.getCreatedAnalysisRun(createdIndex2)
+	expectedArName2 := fmt.Sprintf("%s-%s-%s-%s-%s", r2.Name, rs2PodHash, "2", "0", at2.Name)
+	assert.Equal(t, expectedArName2, createdAr2.Name)
+	assert.Equal(t, "1", createdAr2.Labels[v1alpha1.RolloutAnalysisTemplateIndexLabel])
+
+	patch := f.getPatchedRollout(index)
 	expectedPatch := `{
 		"status": {
-			"abort": true,
-			"abortedAt": "%s",
-			"pauseConditions": null,
-			"controllerPause":null,
-			"blueGreen": {
-				"prePromotionAnalysisRunStatus": {
-					"status": "Error"
-				}
+			"canary": {
+				"currentStepAnalysisRuns": [
+					{"name": "%s", "status": ""},
+					{"name": "%s", "status": ""}
+				]
 			}
 		}
 	}`
-	now := metav1.Now().UTC().Format(time.RFC3339)
-	assert.Equal(t, calculatePatch(r2, fmt.Sprintf(expectedPatch, now)), patch)
+	assert.Equal(t, calculatePatch(r2, fmt.Sprintf(expectedPatch, expectedArName1, expectedArName2)), patch)
 }
 
-func TestCreatePostPromotionAnalysisRun(t *testing.T) {
+func TestCreateAnalysisRunsOnRegionAnalysisStep(t *testing.T) {
 	f := newFixture(t)
 	defer f.Close()
 
-	at := analysisTemplate("bar")
-	r1 := newBlueGreenRollout("foo", 1, nil, "active", "")
+	at := analysisTemplate("success-rate")
+	at.Spec.Args = []v1alpha1.Argument{{Name: analysisutil.RegionArgName}}
+	steps := []v1alpha1.CanaryStep{{
+		Analysis: &v1alpha1.RolloutAnalysis{
+			Templates:   []v1alpha1.RolloutAnalysisTemplate{{TemplateName: at.Name}},
+			Aggregation: v1alpha1.AnalysisAggregationQuorum,
+			Quorum:      1,
+			Regions:     []string{"us-east-1", "eu-west-1"},
+		},
+	}}
+
+	r1 := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(0), intstr.FromInt(1))
 	r2 := bumpVersion(r1)
-	r2.Spec.Strategy.BlueGreen.PostPromotionAnalysis = &v1alpha1.RolloutAnalysis{
-		Templates: []v1alpha1.RolloutAnalysisTemplate{{
-			TemplateName: at.Name,
-		}},
-	}
-	ar := analysisRun(at, v1alpha1.RolloutTypePostPromotionLabel, r2)
+	ar1 := analysisRun(at, v1alpha1.RolloutTypeStepLabel, r2)
+	ar2 := analysisRun(at, v1alpha1.RolloutTypeStepLabel, r2)
+
 	rs1 := newReplicaSetWithStatus(r1, 1, 1)
-	rs1.Annotations[v1alpha1.DefaultReplicaSetScaleDownDeadlineAnnotationKey] = nowFn().Add(10 * time.Second).Format(time.RFC3339)
-	rs2 := newReplicaSetWithStatus(r2, 1, 1)
+	rs2 := newReplicaSetWithStatus(r2, 0, 0)
+	f.kubeobjects = append(f.kubeobjects, rs1, rs2)
+	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
 	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
 	rs2PodHash := rs2.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
 
-	r2 = updateBlueGreenRolloutStatus(r2, "", rs2PodHash, rs1PodHash, 1, 1, 2, 1, false, true)
-
-	activeSelector := map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: rs2PodHash}
-	activeSvc := newService("active", 80, activeSelector, r2)
+	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 1, 0, 1, false)
+	progressingCondition, _ := newProgressingCondition(conditions.ReplicaSetUpdatedReason, rs2, "")
+	conditions.SetRolloutCondition(&r2.Status, progressingCondition)
+	availableCondition, _ := newAvailableCondition(true)
+	conditions.SetRolloutCondition(&r2.Status, availableCondition)
 
-	f.objects = append(f.objects, r2, at)
-	f.kubeobjects = append(f.kubeobjects, activeSvc, rs1, rs2)
 	f.rolloutLister = append(f.rolloutLister, r2)
 	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
-	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
-	f.serviceLister = append(f.serviceLister, activeSvc)
+	f.objects = append(f.objects, r2, at)
+
+	createdIndex1 := f.expectCreateAnalysisRunAction(ar1)
+	createdIndex2 := f.expectCreateAnalysisRunAction(ar2)
+	index := f.expectPatchRolloutAction(r1)
 
-	f.expectCreateAnalysisRunAction(ar)
-	patchIndex := f.expectPatchRolloutActionWithPatch(r2, OnlyObservedGenerationPatch)
 	f.run(getKey(r2, t))
-	patch := f.getPatchedRollout(patchIndex)
-	expectedPatch := fmt.Sprintf(`{
+	// Each region has an AnalysisRun of the templates receiving the region as an argument
+	for i, region := range []string{"us-east-1", "eu-west-1"} {
+		createdAr := f.getCreatedAnalysisRun([]int{createdIndex1, createdIndex2}[i])
+		assert.Equal(t, fmt.Sprintf("%s-%s-%s-%s-%s", r2.Name, rs2PodHash, "2", "0", region), createdAr.Name)
+		assert.Equal(t, region, createdAr.Labels[v1alpha1.RolloutAnalysisRegionLabel])
+		assert.Equal(t, "0", createdAr.Labels[v1alpha1.RolloutCanaryStepIndexLabel])
+		assert.Equal(t, analysisutil.RegionArgName, createdAr.Spec.Args[0].Name)
+		assert.Equal(t, region, *createdAr.Spec.Args[0].Value)
+	}
+
+	patch := f.getPatchedRollout(index)
+	expectedPatch := `{
 		"status": {
-			"blueGreen": {
-				"postPromotionAnalysisRun": "%s",
-				"postPromotionAnalysisRunStatus":{
-					"name": "%s", 
-					"status": ""
-				}
+			"canary": {
+				"currentStepAnalysisRuns": [
+					{"name": "%s-%s-2-0-us-east-1", "status": "", "region": "us-east-1"},
+					{"name": "%s-%s-2-0-eu-west-1", "status": "", "region": "eu-west-1"}
+				]
 			}
 		}
-	}`, ar.Name, ar.Name)
-	assert.Equal(t, calculatePatch(r2, expectedPatch), patch)
+	}`
+	assert.Equal(t, calculatePatch(r2, fmt.Sprintf(expectedPatch, r2.Name, rs2PodHash, r2.Name, rs2PodHash)), patch)
 }
 
-func TestRolloutPostPromotionAnalysisSuccess(t *testing.T) {
+// aggregatedStepAnalysisRun returns the AnalysisRun of a template of the current aggregated analysis step of the rollout
+func aggregatedStepAnalysisRun(r *v1alpha1.Rollout, templateIdx int, phase v1alpha1.AnalysisPhase) *v1alpha1.AnalysisRun {
+	podHash := controller.ComputeHash(&r.Spec.Template, r.Status.CollisionCount)
+	labels := analysisutil.StepLabels(*r.Status.CurrentStepIndex, podHash, "")
+	labels[v1alpha1.RolloutAnalysisTemplateIndexLabel] = fmt.Sprintf("%d", templateIdx)
+	return &v1alpha1.AnalysisRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%s-2-%d-template-%d", r.Name, podHash, *r.Status.CurrentStepIndex, templateIdx),
+			Namespace: metav1.NamespaceDefault,
+			Labels:    labels,
+		},
+		Status: v1alpha1.AnalysisRunStatus{
+			Phase:   phase,
+			Message: fmt.Sprintf("template %d %s", templateIdx, phase),
+		},
+	}
+}
+
+func TestReconcileAggregatedStepAnalysisRuns(t *testing.T) {
+	newCtx := func(aggregation v1alpha1.AnalysisAggregation, phases ...v1alpha1.AnalysisPhase) *canaryContext {
+		analysis := &v1alpha1.RolloutAnalysis{Aggregation: aggregation}
+		for i := range phases {
+			analysis.Templates = append(analysis.Templates, v1alpha1.RolloutAnalysisTemplate{TemplateName: fmt.Sprintf("template-%d", i)})
+		}
+		steps := []v1alpha1.CanaryStep{{Analysis: analysis}, {Pause: &v1alpha1.RolloutPause{}}}
+		r := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(0), intstr.FromInt(1))
+		var ars []*v1alpha1.AnalysisRun
+		for i, phase := range phases {
+			ar := aggregatedStepAnalysisRun(r, i, phase)
+			ars = append(ars, ar)
+			r.Status.Canary.CurrentStepAnalysisRuns = append(r.Status.Canary.CurrentStepAnalysisRuns, v1alpha1.RolloutAnalysisRunStatus{Name: ar.Name})
+		}
+		return newCanaryCtx(r, nil, nil, nil, ars)
+	}
+	reconcile := func(t *testing.T, roCtx *canaryContext) {
+		c := &Controller{}
+		ars, err := c.reconcileAggregatedStepAnalysisRuns(roCtx, newAnalysisRunLimiter(roCtx))
+		assert.NoError(t, err)
+		assert.Len(t, ars, len(roCtx.Rollout().Spec.Strategy.Canary.Steps[0].Analysis.Templates))
+		roCtx.SetCurrentAnalysisRuns(analysisutil.CurrentAnalysisRuns{CanaryAggregatedStep: ars})
+	}
+
+	t.Run("all with a failed and a running analysis aborts", func(t *testing.T) {
+		roCtx := newCtx(v1alpha1.AnalysisAggregationAll, v1alpha1.AnalysisPhaseFailed, v1alpha1.AnalysisPhaseRunning)
+		reconcile(t, roCtx)
+		assert.True(t, roCtx.PauseContext().IsAborted())
+		assert.Equal(t, "template 0 Failed", roCtx.PauseContext().abortMessage)
+		assert.Len(t, roCtx.NewStatus().Canary.CurrentStepAnalysisRuns, 2)
+	})
+
+	t.Run("all with a successful and a running analysis waits", func(t *testing.T) {
+		roCtx := newCtx(v1alpha1.AnalysisAggregationAll, v1alpha1.AnalysisPhaseSuccessful, v1alpha1.AnalysisPhaseRunning)
+		reconcile(t, roCtx)
+		assert.False(t, roCtx.PauseContext().IsAborted())
+		assert.False(t, completedCurrentCanaryStep(roCtx))
+		// The successful analysis stays current until the step completes
+		assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, roCtx.NewStatus().Canary.CurrentStepAnalysisRuns[0].Status)
+	})
+
+	t.Run("all with successful analyses completes the step", func(t *testing.T) {
+		roCtx := newCtx(v1alpha1.AnalysisAggregationAll, v1alpha1.AnalysisPhaseSuccessful, v1alpha1.AnalysisPhaseSuccessful)
+		reconcile(t, roCtx)
+		assert.False(t, roCtx.PauseContext().IsAborted())
+		assert.True(t, completedCurrentCanaryStep(roCtx))
+	})
+
+	t.Run("all with an inconclusive analysis pauses", func(t *testing.T) {
+		roCtx := newCtx(v1alpha1.AnalysisAggregationAll, v1alpha1.AnalysisPhaseInconclusive, v1alpha1.AnalysisPhaseSuccessful)
+		reconcile(t, roCtx)
+		assert.False(t, roCtx.PauseContext().IsAborted())
+		assert.Equal(t, []v1alpha1.PauseReason{v1alpha1.PauseReasonInconclusiveAnalysis}, roCtx.PauseContext().addPauseReasons)
+	})
+
+	t.Run("any with a successful and a running analysis completes the step", func(t *testing.T) {
+		roCtx := newCtx(v1alpha1.AnalysisAggregationAny, v1alpha1.AnalysisPhaseRunning, v1alpha1.AnalysisPhaseSuccessful)
+		reconcile(t, roCtx)
+		assert.False(t, roCtx.PauseContext().IsAborted())
+		assert.True(t, completedCurrentCanaryStep(roCtx))
+	})
+
+	t.Run("any with a failed and a running analysis waits", func(t *testing.T) {
+		roCtx := newCtx(v1alpha1.AnalysisAggregationAny, v1alpha1.AnalysisPhaseFailed, v1alpha1.AnalysisPhaseRunning)
+		reconcile(t, roCtx)
+		assert.False(t, roCtx.PauseContext().IsAborted())
+		assert.False(t, completedCurrentCanaryStep(roCtx))
+	})
+
+	t.Run("any with failed analyses aborts", func(t *testing.T) {
+		roCtx := newCtx(v1alpha1.AnalysisAggregationAny, v1alpha1.AnalysisPhaseError, v1alpha1.AnalysisPhaseFailed)
+		reconcile(t, roCtx)
+		assert.True(t, roCtx.PauseContext().IsAborted())
+		assert.Equal(t, "template 1 Failed", roCtx.PauseContext().abortMessage)
+	})
+}
+
+// regionStepAnalysisRun returns the AnalysisRun of a region of the current aggregated analysis step of the rollout
+func regionStepAnalysisRun(r *v1alpha1.Rollout, region string, phase v1alpha1.AnalysisPhase) *v1alpha1.AnalysisRun {
+	podHash := controller.ComputeHash(&r.Spec.Template, r.Status.CollisionCount)
+	labels := analysisutil.StepLabels(*r.Status.CurrentStepIndex, podHash, "")
+	labels[v1alpha1.RolloutAnalysisRegionLabel] = region
+	return &v1alpha1.AnalysisRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%s-2-%d-%s", r.Name, podHash, *r.Status.CurrentStepIndex, region),
+			Namespace: metav1.NamespaceDefault,
+			Labels:    labels,
+		},
+		Status: v1alpha1.AnalysisRunStatus{
+			Phase:   phase,
+			Message: fmt.Sprintf("region %s %s", region, phase),
+		},
+	}
+}
+
+func TestReconcileQuorumStepAnalysisRuns(t *testing.T) {
+	regions := []string{"us-east-1", "us-west-2", "eu-west-1"}
+	newCtx := func(phases ...v1alpha1.AnalysisPhase) *canaryContext {
+		analysis := &v1alpha1.RolloutAnalysis{
+			Templates:   []v1alpha1.RolloutAnalysisTemplate{{TemplateName: "success-rate"}},
+			Aggregation: v1alpha1.AnalysisAggregationQuorum,
+			Quorum:      2,
+			Regions:     regions,
+		}
+		steps := []v1alpha1.CanaryStep{{Analysis: analysis}, {Pause: &v1alpha1.RolloutPause{}}}
+		r := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(0), intstr.FromInt(1))
+		var ars []*v1alpha1.AnalysisRun
+		for i, phase := range phases {
+			ar := regionStepAnalysisRun(r, regions[i], phase)
+			ars = append(ars, ar)
+			r.Status.Canary.CurrentStepAnalysisRuns = append(r.Status.Canary.CurrentStepAnalysisRuns, v1alpha1.RolloutAnalysisRunStatus{Name: ar.Name})
+		}
+		return newCanaryCtx(r, nil, nil, nil, ars)
+	}
+	reconcile := func(t *testing.T, roCtx *canaryContext) {
+		c := &Controller{}
+		ars, err := c.reconcileAggregatedStepAnalysisRuns(roCtx, newAnalysisRunLimiter(roCtx))
+		assert.NoError(t, err)
+		assert.Len(t, ars, len(regions))
+		roCtx.SetCurrentAnalysisRuns(analysisutil.CurrentAnalysisRuns{CanaryAggregatedStep: ars})
+	}
+
+	t.Run("quorum met completes the step", func(t *testing.T) {
+		roCtx := newCtx(v1alpha1.AnalysisPhaseSuccessful, v1alpha1.AnalysisPhaseFailed, v1alpha1.AnalysisPhaseSuccessful)
+		reconcile(t, roCtx)
+		assert.False(t, roCtx.PauseContext().IsAborted())
+		assert.True(t, completedCurrentCanaryStep(roCtx))
+		// The outcome of each region is tracked in the status
+		statuses := roCtx.NewStatus().Canary.CurrentStepAnalysisRuns
+		assert.Len(t, statuses, 3)
+		for i, region := range regions {
+			assert.Equal(t, region, statuses[i].Region)
+		}
+		assert.Equal(t, v1alpha1.AnalysisPhaseFailed, statuses[1].Status)
+	})
+
+	t.Run("quorum which can still be met waits", func(t *testing.T) {
+		roCtx := newCtx(v1alpha1.AnalysisPhaseSuccessful, v1alpha1.AnalysisPhaseFailed, v1alpha1.AnalysisPhaseRunning)
+		reconcile(t, roCtx)
+		assert.False(t, roCtx.PauseContext().IsAborted())
+		assert.False(t, completedCurrentCanaryStep(roCtx))
+	})
+
+	t.Run("quorum failed aborts", func(t *testing.T) {
+		roCtx := newCtx(v1alpha1.AnalysisPhaseError, v1alpha1.AnalysisPhaseRunning, v1alpha1.AnalysisPhaseFailed)
+		reconcile(t, roCtx)
+		assert.True(t, roCtx.PauseContext().IsAborted())
+		assert.Equal(t, "quorum of 2 successful AnalysisRuns cannot be met: us-east-1 Error, eu-west-1 Failed", roCtx.PauseContext().abortMessage)
+		assert.False(t, completedCurrentCanaryStep(roCtx))
+	})
+
+	t.Run("quorum failed with an inconclusive analysis pauses", func(t *testing.T) {
+		roCtx := newCtx(v1alpha1.AnalysisPhaseInconclusive, v1alpha1.AnalysisPhaseInconclusive, v1alpha1.AnalysisPhaseSuccessful)
+		reconcile(t, roCtx)
+		assert.False(t, roCtx.PauseContext().IsAborted())
+		assert.Equal(t, []v1alpha1.PauseReason{v1alpha1.PauseReasonInconclusiveAnalysis}, roCtx.PauseContext().addPauseReasons)
+	})
+}
+
+func TestAggregateQuorumAnalysisRunPhase(t *testing.T) {
+	newAr := func(phase v1alpha1.AnalysisPhase) *v1alpha1.AnalysisRun {
+		return &v1alpha1.AnalysisRun{
+			ObjectMeta: metav1.ObjectMeta{Name: string(phase)},
+			Status:     v1alpha1.AnalysisRunStatus{Phase: phase},
+		}
+	}
+	tests := []struct {
+		quorum   int32
+		phases   []v1alpha1.AnalysisPhase
+		expected v1alpha1.AnalysisPhase
+	}{
+		{2, []v1alpha1.AnalysisPhase{v1alpha1.AnalysisPhaseSuccessful, v1alpha1.AnalysisPhaseSuccessful, v1alpha1.AnalysisPhaseRunning}, v1alpha1.AnalysisPhaseSuccessful},
+		{2, []v1alpha1.AnalysisPhase{v1alpha1.AnalysisPhaseSuccessful, v1alpha1.AnalysisPhaseRunning}, v1alpha1.AnalysisPhaseRunning},
+		{2, []v1alpha1.AnalysisPhase{v1alpha1.AnalysisPhaseSuccessful, v1alpha1.AnalysisPhaseFailed, v1alpha1.AnalysisPhaseRunning}, v1alpha1.AnalysisPhaseRunning},
+		{2, []v1alpha1.AnalysisPhase{v1alpha1.AnalysisPhaseFailed, v1alpha1.AnalysisPhaseError, v1alpha1.AnalysisPhaseRunning}, v1alpha1.AnalysisPhaseFailed},
+		{3, []v1alpha1.AnalysisPhase{v1alpha1.AnalysisPhaseSuccessful, v1alpha1.AnalysisPhaseInconclusive, v1alpha1.AnalysisPhaseRunning}, v1alpha1.AnalysisPhaseInconclusive},
+		{1, nil, v1alpha1.AnalysisPhaseRunning},
+	}
+	for _, test := range tests {
+		var ars []*v1alpha1.AnalysisRun
+		for _, phase := range test.phases {
+			ars = append(ars, newAr(phase))
+		}
+		phase, _ := aggregateQuorumAnalysisRunPhase(test.quorum, ars, 3)
+		assert.Equal(t, test.expected, phase, "%d %v", test.quorum, test.phases)
+	}
+}
+
+func TestAggregateAnalysisRunPhase(t *testing.T) {
+	newAr := func(phase v1alpha1.AnalysisPhase) *v1alpha1.AnalysisRun {
+		return &v1alpha1.AnalysisRun{Status: v1alpha1.AnalysisRunStatus{Phase: phase, Message: string(phase)}}
+	}
+	tests := []struct {
+		aggregation   v1alpha1.AnalysisAggregation
+		phases        []v1alpha1.AnalysisPhase
+		templateCount int
+		expected      v1alpha1.AnalysisPhase
+	}{
+		{v1alpha1.AnalysisAggregationAll, []v1alpha1.AnalysisPhase{v1alpha1.AnalysisPhaseSuccessful, v1alpha1.AnalysisPhaseSuccessful}, 2, v1alpha1.AnalysisPhaseSuccessful},
+		{v1alpha1.AnalysisAggregationAll, []v1alpha1.AnalysisPhase{v1alpha1.AnalysisPhaseSuccessful, v1alpha1.AnalysisPhaseRunning}, 2, v1alpha1.AnalysisPhaseRunning},
+		{v1alpha1.AnalysisAggregationAll, []v1alpha1.AnalysisPhase{v1alpha1.AnalysisPhaseSuccessful}, 2, v1alpha1.AnalysisPhaseRunning},
+		{v1alpha1.AnalysisAggregationAll, []v1alpha1.AnalysisPhase{v1alpha1.AnalysisPhaseRunning, v1alpha1.AnalysisPhaseFailed}, 2, v1alpha1.AnalysisPhaseFailed},
+		{v1alpha1.AnalysisAggregationAll, []v1alpha1.AnalysisPhase{v1alpha1.AnalysisPhaseInconclusive, v1alpha1.AnalysisPhaseError}, 2, v1alpha1.AnalysisPhaseError},
+		{v1alpha1.AnalysisAggregationAll, []v1alpha1.AnalysisPhase{v1alpha1.AnalysisPhaseInconclusive, v1alpha1.AnalysisPhaseRunning}, 2, v1alpha1.AnalysisPhaseInconclusive},
+		{v1alpha1.AnalysisAggregationAny, []v1alpha1.AnalysisPhase{v1alpha1.AnalysisPhaseFailed, v1alpha1.AnalysisPhaseSuccessful}, 2, v1alpha1.AnalysisPhaseSuccessful},
+		{v1alpha1.AnalysisAggregationAny, []v1alpha1.AnalysisPhase{v1alpha1.AnalysisPhaseRunning, v1alpha1.AnalysisPhaseSuccessful}, 2, v1alpha1.AnalysisPhaseSuccessful},
+		{v1alpha1.AnalysisAggregationAny, []v1alpha1.AnalysisPhase{v1alpha1.AnalysisPhaseFailed, v1alpha1.AnalysisPhaseRunning}, 2, v1alpha1.AnalysisPhaseRunning},
+		{v1alpha1.AnalysisAggregationAny, []v1alpha1.AnalysisPhase{v1alpha1.AnalysisPhaseFailed}, 2, v1alpha1.AnalysisPhaseRunning},
+		{v1alpha1.AnalysisAggregationAny, []v1alpha1.AnalysisPhase{v1alpha1.AnalysisPhaseInconclusive, v1alpha1.AnalysisPhaseFailed}, 2, v1alpha1.AnalysisPhaseFailed},
+		{v1alpha1.AnalysisAggregationAny, nil, 2, v1alpha1.AnalysisPhaseRunning},
+	}
+	for _, test := range tests {
+		var ars []*v1alpha1.AnalysisRun
+		for _, phase := range test.phases {
+			ars = append(ars, newAr(phase))
+		}
+		phase, message := aggregateAnalysisRunPhase(test.aggregation, ars, test.templateCount)
+		assert.Equal(t, test.expected, phase, "%s %v", test.aggregation, test.phases)
+		if phase.Completed() && phase != v1alpha1.AnalysisPhaseSuccessful {
+			assert.Equal(t, string(phase), message)
+		}
+	}
+}
+
+func TestCreateScaleDownDrainAnalysisRun(t *testing.T) {
 	f := newFixture(t)
 	defer f.Close()
 
 	at := analysisTemplate("bar")
+	at.Spec.Args = []v1alpha1.Argument{{Name: analysisutil.DrainingPodTemplateHashArgName}}
 	r1 := newBlueGreenRollout("foo", 1, nil, "active", "")
 	r2 := bumpVersion(r1)
-	r2.Spec.Strategy.BlueGreen.PostPromotionAnalysis = &v1alpha1.RolloutAnalysis{
-		Templates: []v1alpha1.RolloutAnalysisTemplate{{
-			TemplateName: at.Name,
-		}},
-	}
-	ar := analysisRun(at, v1alpha1.RolloutTypePostPromotionLabel, r2)
-	ar.Status.Phase = v1alpha1.AnalysisPhaseSuccessful
-	r2.Status.BlueGreen.PostPromotionAnalysisRun = ar.Name
-	r2.Status.BlueGreen.PostPromotionAnalysisRunStatus = &v1alpha1.RolloutAnalysisRunStatus{
-		Name:   ar.Name,
-		Status: v1alpha1.AnalysisPhaseRunning,
+	r2.Spec.Strategy.BlueGreen.ScaleDownDrain = &v1alpha1.ScaleDownDrain{
+		RolloutAnalysis: v1alpha1.RolloutAnalysis{
+			Templates: []v1alpha1.RolloutAnalysisTemplate{{
+				TemplateName: at.Name,
+			}},
+		},
 	}
-
-	rs1 := newReplicaSetWithStatus(r1, 0, 0)
+	rs1 := newReplicaSetWithStatus(r1, 1, 1)
+	rs1.Annotations[v1alpha1.DefaultReplicaSetScaleDownDeadlineAnnotationKey] = metav1.Now().Add(10 * time.Second).UTC().Format(time.RFC3339)
 	rs2 := newReplicaSetWithStatus(r2, 1, 1)
 	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
 	rs2PodHash := rs2.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
 
-	r2 = updateBlueGreenRolloutStatus(r2, "", rs2PodHash, rs1PodHash, 1, 1, 1, 1, false, true)
+	r2 = updateBlueGreenRolloutStatus(r2, "", rs2PodHash, rs2PodHash, 1, 1, 2, 1, false, true)
+	ar := analysisRun(at, v1alpha1.RolloutTypePostPromotionLabel, r2)
 
 	activeSelector := map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: rs2PodHash}
 	activeSvc := newService("active", 80, activeSelector, r2)
 
-	f.objects = append(f.objects, r2, at, ar)
+	f.objects = append(f.objects, r2, at)
 	f.kubeobjects = append(f.kubeobjects, activeSvc, rs1, rs2)
 	f.rolloutLister = append(f.rolloutLister, r2)
 	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
-	f.analysisRunLister = append(f.analysisRunLister, ar)
 	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
 	f.serviceLister = append(f.serviceLister, activeSvc)
 
+	createdIndex := f.expectCreateAnalysisRunAction(ar)
 	patchIndex := f.expectPatchRolloutAction(r2)
 	f.run(getKey(r2, t))
+
+	// the run analyzes the previous active ReplicaSet
+	createdAr := f.getCreatedAnalysisRun(createdIndex)
+	assert.Equal(t, analysisutil.ScaleDownDrainLabels(rs1PodHash, ""), createdAr.Labels)
+	assert.Contains(t, createdAr.Spec.Args, v1alpha1.Argument{Name: analysisutil.DrainingPodTemplateHashArgName, Value: &rs1PodHash})
+
 	patch := f.getPatchedRollout(patchIndex)
 	expectedPatch := fmt.Sprintf(`{
 		"status": {
-			"stableRS": "%s",
 			"blueGreen": {
-				"postPromotionAnalysisRunStatus": null,
-				"postPromotionAnalysisRun": null
+				"scaleDownDrainAnalysisRunStatus":{
+					"name": "%s",
+					"status": ""
+				}
 			}
 		}
-	}`, rs2PodHash)
+	}`, createdAr.Name)
 	assert.Equal(t, calculatePatch(r2, expectedPatch), patch)
 }
 
-// TestPostPromotionAnalysisRunHandleInconclusive ensures that the Rollout does not scale down a old ReplicaSet if
-// it's paused for a inconclusive analysis run
-func TestPostPromotionAnalysisRunHandleInconclusive(t *testing.T) {
-	f := newFixture(t)
-	defer f.Close()
-
-	at := analysisTemplate("bar")
+func newScaleDownDrainRollout(at *v1alpha1.AnalysisTemplate, lastMeasurementPhase v1alpha1.AnalysisPhase) (*v1alpha1.Rollout, *appsv1.ReplicaSet, *appsv1.ReplicaSet, *v1alpha1.AnalysisRun) {
 	r1 := newBlueGreenRollout("foo", 1, nil, "active", "")
 	r2 := bumpVersion(r1)
-	r2.Spec.Strategy.BlueGreen.PostPromotionAnalysis = &v1alpha1.RolloutAnalysis{
-		Templates: []v1alpha1.RolloutAnalysisTemplate{{
-			TemplateName: at.Name,
-		}},
+	r2.Spec.Strategy.BlueGreen.ScaleDownDrain = &v1alpha1.ScaleDownDrain{
+		RolloutAnalysis: v1alpha1.RolloutAnalysis{
+			Templates: []v1alpha1.RolloutAnalysisTemplate{{
+				TemplateName: at.Name,
+			}},
+		},
 	}
-	ar := analysisRun(at, v1alpha1.RolloutTypePostPromotionLabel, r2)
-	ar.Status.Phase = v1alpha1.AnalysisPhaseInconclusive
-	r2.Status.BlueGreen.PostPromotionAnalysisRun = ar.Name
-
 	rs1 := newReplicaSetWithStatus(r1, 1, 1)
-	rs1.Annotations[v1alpha1.DefaultReplicaSetScaleDownDeadlineAnnotationKey] = nowFn().Add(-10 * time.Second).Format(time.RFC3339)
+	rs1.Annotations[v1alpha1.DefaultReplicaSetScaleDownDeadlineAnnotationKey] = metav1.Now().Add(10 * time.Second).UTC().Format(time.RFC3339)
 	rs2 := newReplicaSetWithStatus(r2, 1, 1)
 	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
 	rs2PodHash := rs2.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
 
-	r2 = updateBlueGreenRolloutStatus(r2, "", rs2PodHash, rs1PodHash, 1, 1, 2, 1, false, true)
-	r2.Status.PauseConditions = []v1alpha1.PauseCondition{{
-		Reason:    v1alpha1.PauseReasonInconclusiveAnalysis,
-		StartTime: metav1.Now(),
-	}}
-	pausedCondition, _ := newProgressingCondition(conditions.PausedRolloutReason, r2, "")
-	conditions.SetRolloutCondition(&r2.Status, pausedCondition)
+	r2 = updateBlueGreenRolloutStatus(r2, "", rs2PodHash, rs2PodHash, 1, 1, 2, 1, false, true)
+	ar := analysisRun(at, v1alpha1.RolloutTypePostPromotionLabel, r2)
+	ar.Labels = analysisutil.ScaleDownDrainLabels(rs1PodHash, "")
+	ar.Status = v1alpha1.AnalysisRunStatus{
+		Phase: v1alpha1.AnalysisPhaseRunning,
+		MetricResults: []v1alpha1.MetricResult{{
+			Name:  "example",
+			Phase: v1alpha1.AnalysisPhaseRunning,
+			Measurements: []v1alpha1.Measurement{
+				{Phase: v1alpha1.AnalysisPhaseFailed},
+				{Phase: lastMeasurementPhase},
+			},
+		}},
+	}
+	r2.Status.BlueGreen.ScaleDownDrainAnalysisRunStatus = &v1alpha1.RolloutAnalysisRunStatus{
+		Name:   ar.Name,
+		Status: v1alpha1.AnalysisPhaseRunning,
+	}
+	return r2, rs1, rs2, ar
+}
 
-	activeSelector := map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: rs2PodHash}
+func TestScaleDownDrainedReplicaSet(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	at := analysisTemplate("bar")
+	r2, rs1, rs2, ar := newScaleDownDrainRollout(at, v1alpha1.AnalysisPhaseSuccessful)
+	activeSelector := map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: r2.Status.BlueGreen.ActiveSelector}
 	activeSvc := newService("active", 80, activeSelector, r2)
 
-	f.objects = append(f.objects, r2, at)
+	f.objects = append(f.objects, r2, at, ar)
 	f.kubeobjects = append(f.kubeobjects, activeSvc, rs1, rs2)
 	f.rolloutLister = append(f.rolloutLister, r2)
 	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
+	f.analysisRunLister = append(f.analysisRunLister, ar)
 	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
 	f.serviceLister = append(f.serviceLister, activeSvc)
 
-	patchIndex := f.expectPatchRolloutActionWithPatch(r2, OnlyObservedGenerationPatch)
+	// the previous active ReplicaSet is scaled down before its deadline
+	updatedRSIndex := f.expectUpdateReplicaSetAction(rs1)
+	patchIndex := f.expectPatchRolloutAction(r2)
 	f.run(getKey(r2, t))
+	updatedRS := f.getUpdatedReplicaSet(updatedRSIndex)
+	assert.Equal(t, rs1.Name, updatedRS.Name)
+	assert.Equal(t, int32(0), *updatedRS.Spec.Replicas)
+
 	patch := f.getPatchedRollout(patchIndex)
-	expectedPatch := fmt.Sprint(`{
-		"status": {
-			"blueGreen": {
-				"postPromotionAnalysisRun": null
-			}
-		}
-	}`)
-	assert.Equal(t, calculatePatch(r2, expectedPatch), patch)
+	assert.Equal(t, calculatePatch(r2, OnlyObservedGenerationPatch), patch)
 }
 
-func TestAbortRolloutOnErrorPostPromotionAnalysis(t *testing.T) {
+func TestScaleDownDrainNotDrainedReplicaSet(t *testing.T) {
 	f := newFixture(t)
 	defer f.Close()
 
 	at := analysisTemplate("bar")
-	r1 := newBlueGreenRollout("foo", 1, nil, "active", "")
-	r2 := bumpVersion(r1)
-	r2.Spec.Strategy.BlueGreen.PostPromotionAnalysis = &v1alpha1.RolloutAnalysis{
-		Templates: []v1alpha1.RolloutAnalysisTemplate{{
-			TemplateName: at.Name,
-		}},
-	}
-	ar := analysisRun(at, v1alpha1.RolloutTypePostPromotionLabel, r2)
-	ar.Status.Phase = v1alpha1.AnalysisPhaseError
-	r2.Status.BlueGreen.PostPromotionAnalysisRun = ar.Name
-	r2.Status.BlueGreen.PostPromotionAnalysisRunStatus = &v1alpha1.RolloutAnalysisRunStatus{
-		Name:   ar.Name,
-		Status: v1alpha1.AnalysisPhaseRunning,
-	}
-
-	rs1 := newReplicaSetWithStatus(r1, 1, 1)
-	rs1.Annotations[v1alpha1.DefaultReplicaSetScaleDownDeadlineAnnotationKey] = nowFn().Add(10 * time.Second).Format(time.RFC3339)
-	rs2 := newReplicaSetWithStatus(r2, 1, 1)
-	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
-	rs2PodHash := rs2.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
-
-	r2 = updateBlueGreenRolloutStatus(r2, "", rs2PodHash, rs1PodHash, 1, 1, 2, 1, true, true)
-	pausedCondition, _ := newProgressingCondition(conditions.PausedRolloutReason, r2, "")
-	conditions.SetRolloutCondition(&r2.Status, pausedCondition)
-
-	activeSelector := map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: rs2PodHash}
+	r2, rs1, rs2, ar := newScaleDownDrainRollout(at, v1alpha1.AnalysisPhaseFailed)
+	activeSelector := map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: r2.Status.BlueGreen.ActiveSelector}
 	activeSvc := newService("active", 80, activeSelector, r2)
 
 	f.objects = append(f.objects, r2, at, ar)
@@ -2113,22 +3884,9 @@ func TestAbortRolloutOnErrorPostPromotionAnalysis(t *testing.T) {
 	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
 	f.serviceLister = append(f.serviceLister, activeSvc)
 
-	patchIndex := f.expectPatchRolloutActionWithPatch(r2, OnlyObservedGenerationPatch)
+	// the previous active ReplicaSet waits for its deadline
+	patchIndex := f.expectPatchRolloutAction(r2)
 	f.run(getKey(r2, t))
 	patch := f.getPatchedRollout(patchIndex)
-	expectedPatch := `{
-		"status": {
-			"abort": true,
-			"abortedAt": "%s",
-			"pauseConditions": null,
-			"controllerPause":null,
-			"blueGreen": {
-				"postPromotionAnalysisRunStatus": {
-					"status": "Error"
-				}
-			}
-		}
-	}`
-	now := metav1.Now().UTC().Format(time.RFC3339)
-	assert.Equal(t, calculatePatch(r2, fmt.Sprintf(expectedPatch, now)), patch)
+	assert.Equal(t, calculatePatch(r2, OnlyObservedGenerationPatch), patch)
 }
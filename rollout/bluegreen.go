@@ -39,7 +39,12 @@ func (c *Controller) rolloutBlueGreen(r *v1alpha1.Rollout, rsList []*appsv1.Repl
 		roCtx.PauseContext().ClearPauseConditions()
 		roCtx.PauseContext().RemoveAbort()
 		roCtx.SetRestartedAt()
+		roCtx.ClearPreviewTrafficRamp()
 		logCtx.Infof("New pod template or template change detected")
+		err = c.reconcileBlueGreenTrafficRouting(roCtx)
+		if err != nil {
+			return err
+		}
 		return c.syncRolloutStatusBlueGreen(previewSvc, activeSvc, roCtx)
 	}
 
@@ -70,6 +75,11 @@ func (c *Controller) rolloutBlueGreen(r *v1alpha1.Rollout, rsList []*appsv1.Repl
 	if err != nil {
 		return err
 	}
+
+	err = c.reconcileBlueGreenTrafficRouting(roCtx)
+	if err != nil {
+		return err
+	}
 	return c.syncRolloutStatusBlueGreen(previewSvc, activeSvc, roCtx)
 }
 
@@ -142,8 +152,14 @@ func skipPause(roCtx *blueGreenContext, activeSvc *corev1.Service) bool {
 		return true
 	}
 
+	// A rollout in the preview traffic ramp was already promoted
+	if rollout.Status.BlueGreen.PreviewTrafficRampStepIndex != nil {
+		return true
+	}
+
 	// If a rollout has a PrePromotionAnalysis, the controller only skips the pause after the analysis passes
-	if defaults.GetAutoPromotionEnabledOrDefault(rollout) && completedPrePromotionAnalysis(roCtx) {
+	// unless a manual approval is required on top of the analysis
+	if defaults.GetAutoPromotionEnabledOrDefault(rollout) && !rollout.Spec.Strategy.BlueGreen.RequireManualApproval && completedPrePromotionAnalysis(roCtx) {
 		return true
 	}
 
@@ -180,6 +196,12 @@ func (c *Controller) reconcileBlueGreenPause(activeSvc, previewSvc *corev1.Servi
 
 	newRSPodHash := newRS.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
 	cond := getPauseCondition(rollout, v1alpha1.PauseReasonBlueGreenPause)
+	// If a manual approval is required, the rollout is only paused once the PrePromotionAnalysis succeeds so
+	// that the promotion is always issued with the analysis results available.
+	if rollout.Spec.Strategy.BlueGreen.RequireManualApproval && cond == nil && !completedPrePromotionAnalysis(roCtx) {
+		roCtx.log.Info("Waiting for PrePromotionAnalysis to succeed before pausing for manual approval")
+		return
+	}
 	// If the rollout is not paused and the active service is not point at the newRS, we should pause the rollout.
 	if cond == nil && !rollout.Status.ControllerPause && !rollout.Status.BlueGreen.ScaleUpPreviewCheckPoint && activeSvc.Spec.Selector[v1alpha1.DefaultRolloutUniqueLabelKey] != newRSPodHash {
 		roCtx.PauseContext().AddPauseCondition(v1alpha1.PauseReasonBlueGreenPause)
@@ -227,6 +249,8 @@ func (c *Controller) scaleDownOldReplicaSetsForBlueGreen(oldRSs []*appsv1.Replic
 				logCtx.Warnf("Unable to read scaleDownAt label on rs '%s'", targetRS.Name)
 			} else if rollout.Spec.Strategy.BlueGreen.ScaleDownDelayRevisionLimit != nil && annotationedRSs == *rollout.Spec.Strategy.BlueGreen.ScaleDownDelayRevisionLimit {
 				logCtx.Info("At ScaleDownDelayRevisionLimit and scaling down the rest")
+			} else if rollout.Spec.Strategy.BlueGreen.ScaleDownDrain != nil && isDrained(roCtx.CurrentAnalysisRuns().BlueGreenScaleDownDrain, targetRS) {
+				logCtx.Infof("RS '%s' drained before the scaleDownTime", targetRS.Name)
 			} else {
 				now := metav1.Now()
 				scaleDownAt := metav1.NewTime(scaleDownAtTime)
@@ -282,6 +306,11 @@ func (c *Controller) syncRolloutStatusBlueGreen(previewSvc *corev1.Service, acti
 	}
 
 	newStatus.BlueGreen.ActiveSelector = activeSelector
+	// The preview traffic ramp ends with the promotion of the active service or the abort of the rollout
+	if newStatus.BlueGreen.ActiveSelector == newStatus.CurrentPodHash || roCtx.PauseContext().IsAborted() {
+		newStatus.BlueGreen.PreviewTrafficRampStepIndex = nil
+		newStatus.BlueGreen.PreviewTrafficRampStepStartedAt = nil
+	}
 	if newStatus.BlueGreen.ActiveSelector != r.Status.BlueGreen.ActiveSelector {
 		previousActiveRS, _ := replicasetutil.GetReplicaSetByTemplateHash(oldRSs, r.Status.BlueGreen.ActiveSelector)
 		if replicasetutil.GetReplicaCountForReplicaSets([]*appsv1.ReplicaSet{previousActiveRS}) > 0 {
@@ -3,9 +3,11 @@ package rollout
 import (
 	"fmt"
 	"sort"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/kubernetes/pkg/controller"
 	"k8s.io/utils/pointer"
@@ -17,6 +19,15 @@ import (
 	replicasetutil "github.com/argoproj/argo-rollouts/utils/replicaset"
 )
 
+const (
+	// readinessGateCheckTime is the interval at which a rollout is requeued while its canary pods do not satisfy the
+	// readiness gate of the rollout
+	readinessGateCheckTime = 10 * time.Second
+	// dependencyCheckTime is the interval at which a rollout is requeued while the rollout it depends on is not
+	// Healthy
+	dependencyCheckTime = 10 * time.Second
+)
+
 func (c *Controller) rolloutCanary(rollout *v1alpha1.Rollout, rsList []*appsv1.ReplicaSet) error {
 	exList, err := c.getExperimentsForRollout(rollout)
 	if err != nil {
@@ -64,6 +75,10 @@ func (c *Controller) rolloutCanary(rollout *v1alpha1.Rollout, rsList []*appsv1.R
 		return err
 	}
 
+	c.reconcileMaxWeightSchedule(roCtx)
+	c.reconcileAbortRampDown(roCtx)
+	c.reconcileAnalysisAutoRetry(roCtx)
+
 	logCtx.Info("Reconciling Experiment step")
 	err = c.reconcileExperiments(roCtx)
 	if err != nil {
@@ -133,6 +148,11 @@ func (c *Controller) reconcileCanaryPause(roCtx *canaryContext) bool {
 		return false
 	}
 
+	if replicasetutil.InCanaryWarmup(rollout) {
+		logCtx.Info("Canary is warming up before the first step")
+		return false
+	}
+
 	if currentStep.Pause == nil {
 		return false
 	}
@@ -152,9 +172,231 @@ func (c *Controller) reconcileCanaryPause(roCtx *canaryContext) bool {
 		return true
 	}
 	c.checkEnqueueRolloutDuringWait(rollout, cond.StartTime, currentStep.Pause.DurationSeconds())
+	expiredTime := cond.StartTime.Add(time.Duration(currentStep.Pause.DurationSeconds()) * time.Second)
+	if currentStep.Pause.RequireHealthyAnalysis && nowFn().After(expiredTime) {
+		logCtx.Infof("Enqueueing Rollout in %s to check the background analysis", healthyAnalysisCheckTime.String())
+		c.enqueueRolloutAfter(rollout, healthyAnalysisCheckTime)
+	}
 	return true
 }
 
+// reconcileMaxWeightSchedule requeues the rollout at the next start or end of a MaxWeightSchedule window so that
+// the canary weight is capped or released on time while the rollout is progressing through its steps
+func (c *Controller) reconcileMaxWeightSchedule(roCtx *canaryContext) {
+	rollout := roCtx.Rollout()
+	currentStep, _ := replicasetutil.GetCurrentCanaryStep(rollout)
+	if currentStep == nil || rollout.Status.Abort {
+		return
+	}
+	untilTransition, err := replicasetutil.GetNextMaxWeightScheduleTransition(rollout, nowFn())
+	if err != nil {
+		// The canary weight is capped by the lowest max weight of the windows until the schedule can be evaluated
+		msg := fmt.Sprintf("Capping the canary weight by the lowest max weight of the windows: %v", err)
+		roCtx.Log().Warn(msg)
+		c.recorder.Event(rollout, corev1.EventTypeWarning, "MaxWeightScheduleError", msg)
+		return
+	}
+	if untilTransition == nil {
+		return
+	}
+	roCtx.Log().Infof("Enqueueing rollout in %s for the next MaxWeightSchedule transition", untilTransition.String())
+	c.enqueueRolloutAfter(rollout, *untilTransition)
+}
+
+// reconcileAbortRampDown requeues the rollout at the next step of the abort ramp down so that the traffic is
+// shifted back to the stable version on time
+func (c *Controller) reconcileAbortRampDown(roCtx *canaryContext) {
+	rollout := roCtx.Rollout()
+	untilNextStep := replicasetutil.GetNextAbortRampDownStep(rollout, nowFn())
+	if untilNextStep == nil {
+		return
+	}
+	roCtx.Log().Infof("Enqueueing rollout in %s for the next abort ramp down step", untilNextStep.String())
+	c.enqueueRolloutAfter(rollout, *untilNextStep)
+}
+
+// reconcileCanaryQuarantine labels the canary ReplicaSet and its pods as quarantined while they are kept for
+// inspection after the rollout was aborted, and removes the label once the quarantine ends. The rollout is requeued
+// when the quarantine expires, at which point the canary ReplicaSet is scaled down.
+func (c *Controller) reconcileCanaryQuarantine(roCtx *canaryContext) error {
+	rollout := roCtx.Rollout()
+	newRS := roCtx.NewRS()
+	if newRS == nil {
+		return nil
+	}
+	now := nowFn()
+	quarantined := replicasetutil.InCanaryQuarantine(rollout, now)
+	if _, labeled := newRS.Labels[v1alpha1.RolloutQuarantineLabelKey]; !quarantined && !labeled {
+		return nil
+	}
+	if untilExpiration := replicasetutil.GetCanaryQuarantineExpiration(rollout, now); untilExpiration != nil {
+		roCtx.Log().Infof("Enqueueing rollout in %s for the expiration of the quarantine", untilExpiration.String())
+		c.enqueueRolloutAfter(rollout, *untilExpiration)
+	}
+	return c.setQuarantineLabel(roCtx, newRS, quarantined)
+}
+
+// reconcileAnalysisAutoRetry retries a rollout aborted by a failed analysis once the cooldown of the autoRetry of its
+// abortPolicy has elapsed, until the retries of the revision reach the limit. The rollout is requeued at the end of
+// the cooldown.
+func (c *Controller) reconcileAnalysisAutoRetry(roCtx *canaryContext) {
+	rollout := roCtx.Rollout()
+	abortPolicy := rollout.Spec.Strategy.Canary.AbortPolicy
+	if abortPolicy == nil || abortPolicy.AutoRetry == nil || !rollout.Status.Abort || rollout.Status.AbortedAt == nil {
+		return
+	}
+	if !abortedByFailedAnalysis(rollout) {
+		return
+	}
+	autoRetry := abortPolicy.AutoRetry
+	if rollout.Status.Canary.AnalysisRetries >= autoRetry.Limit {
+		return
+	}
+	retryAt := rollout.Status.AbortedAt.Add(time.Duration(autoRetry.CooldownSeconds) * time.Second)
+	if now := nowFn(); now.Before(retryAt) {
+		roCtx.Log().Infof("Enqueueing rollout in %s to retry the failed analysis", retryAt.Sub(now).String())
+		c.enqueueRolloutAfter(rollout, retryAt.Sub(now))
+		return
+	}
+	msg := fmt.Sprintf("Retrying the rollout aborted by a failed analysis (retry %d of %d)", rollout.Status.Canary.AnalysisRetries+1, autoRetry.Limit)
+	roCtx.Log().Info(msg)
+	c.recorder.Event(rollout, corev1.EventTypeNormal, "AnalysisAutoRetry", msg)
+	roCtx.PauseContext().RetryAbort()
+}
+
+// abortedByFailedAnalysis returns whether the step or background analysis of the rollout failed, as opposed to the
+// rollout being aborted by the user
+func abortedByFailedAnalysis(r *v1alpha1.Rollout) bool {
+	arStatuses := []*v1alpha1.RolloutAnalysisRunStatus{r.Status.Canary.CurrentStepAnalysisRunStatus, r.Status.Canary.CurrentBackgroundAnalysisRunStatus}
+	for i := range r.Status.Canary.CurrentStepAnalysisRuns {
+		arStatuses = append(arStatuses, &r.Status.Canary.CurrentStepAnalysisRuns[i])
+	}
+	for _, arStatus := range arStatuses {
+		if arStatus != nil && (arStatus.Status == v1alpha1.AnalysisPhaseFailed || arStatus.Status == v1alpha1.AnalysisPhaseError) {
+			return true
+		}
+	}
+	return false
+}
+
+// calculateAbortedWeight returns the canary weight to ramp down from when the rollout is aborted. The weight is
+// recorded when the abort is first reconciled, since the current step index is reset afterwards.
+func calculateAbortedWeight(r *v1alpha1.Rollout) int32 {
+	if r.Spec.Strategy.Canary.TrafficRouting == nil || r.Spec.Strategy.Canary.TrafficRouting.AbortRampDownSeconds == nil {
+		return 0
+	}
+	if r.Status.AbortedAt != nil {
+		return r.Status.Canary.AbortedWeight
+	}
+	return replicasetutil.GetCurrentSetWeight(r)
+}
+
+// completedBakeTime returns whether the new ReplicaSet has been baking at 100% weight for the bakeTime of the
+// rollout. The background analysis keeps running while baking, and aborts the rollout if it fails.
+func (c *Controller) completedBakeTime(roCtx *canaryContext, newStatus *v1alpha1.RolloutStatus) bool {
+	r := roCtx.Rollout()
+	bakeTimeSeconds := r.Spec.Strategy.Canary.BakeTimeSeconds()
+	if bakeTimeSeconds <= 0 {
+		return true
+	}
+	bakeStartedAt := r.Status.Canary.BakeStartedAt
+	if bakeStartedAt == nil {
+		now := metav1.NewTime(nowFn())
+		bakeStartedAt = &now
+		roCtx.Log().Infof("Baking the new RS for %d seconds", bakeTimeSeconds)
+	}
+	expiredTime := bakeStartedAt.Add(time.Duration(bakeTimeSeconds) * time.Second)
+	if !nowFn().Before(expiredTime) {
+		roCtx.Log().Info("New RS has completed baking")
+		return true
+	}
+	newStatus.Canary.BakeStartedAt = bakeStartedAt
+	c.checkEnqueueRolloutDuringWait(r, *bakeStartedAt, bakeTimeSeconds)
+	return false
+}
+
+// completedCanaryWarmup returns whether all the canary pods have been ready at the warmup weight for the
+// readySeconds of the warmupReadyCheck. The ready check restarts whenever a canary pod is not ready.
+func (c *Controller) completedCanaryWarmup(roCtx *canaryContext, newStatus *v1alpha1.RolloutStatus) bool {
+	r := roCtx.Rollout()
+	if r.Spec.Paused || !replicasetutil.AtDesiredReplicaCountsForCanary(r, roCtx.NewRS(), roCtx.StableRS(), roCtx.OlderRSs()) {
+		return false
+	}
+	readySeconds := int32(0)
+	if r.Spec.Strategy.Canary.WarmupReadyCheck != nil {
+		readySeconds = r.Spec.Strategy.Canary.WarmupReadyCheck.ReadySeconds
+	}
+	readySince := r.Status.Canary.WarmupReadySince
+	if readySince == nil {
+		now := metav1.NewTime(nowFn())
+		readySince = &now
+		roCtx.Log().Infof("Canary pods are ready at the warmup weight, checking them for %d seconds", readySeconds)
+	}
+	expiredTime := readySince.Add(time.Duration(readySeconds) * time.Second)
+	if !nowFn().Before(expiredTime) {
+		return true
+	}
+	newStatus.Canary.WarmupReadySince = readySince
+	c.checkEnqueueRolloutDuringWait(r, *readySince, readySeconds)
+	return false
+}
+
+// satisfiedDependency returns whether the rollout referenced by the DependsOnRollout of the canary is Healthy. A
+// missing rollout is not considered Healthy, since it may not be created yet.
+func (c *Controller) satisfiedDependency(roCtx *canaryContext) (bool, error) {
+	r := roCtx.Rollout()
+	name := r.Spec.Strategy.Canary.DependsOnRollout
+	dependency, err := c.rolloutsLister.Rollouts(r.Namespace).Get(name)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			roCtx.Log().Infof("Rollout '%s' the canary depends on is not found", name)
+			return false, nil
+		}
+		return false, err
+	}
+	return conditions.RolloutHealthy(dependency), nil
+}
+
+// satisfiedCanaryReadinessGate returns whether all the canary pods satisfy the readiness gate of the rollout, which
+// requires the new RS to be at its desired replica count and its pods to have the condition of the gate set to True
+func (c *Controller) satisfiedCanaryReadinessGate(roCtx *canaryContext) (bool, error) {
+	r := roCtx.Rollout()
+	newRS := roCtx.NewRS()
+	if newRS == nil || !replicasetutil.AtDesiredReplicaCountsForCanary(r, newRS, roCtx.StableRS(), roCtx.OlderRSs()) {
+		return false, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(newRS.Spec.Selector)
+	if err != nil {
+		return false, err
+	}
+	pods, err := c.podLister.Pods(newRS.Namespace).List(selector)
+	if err != nil {
+		return false, err
+	}
+	conditionType := r.Spec.Strategy.Canary.ReadinessGate.ConditionType
+	satisfiedPods := int32(0)
+	for _, pod := range pods {
+		if !metav1.IsControlledBy(pod, newRS) || pod.DeletionTimestamp != nil {
+			continue
+		}
+		if !podConditionTrue(pod, conditionType) {
+			roCtx.Log().Infof("Canary pod '%s' does not satisfy the readiness gate '%s'", pod.Name, conditionType)
+			return false, nil
+		}
+		satisfiedPods++
+	}
+	return satisfiedPods >= defaults.GetReplicasOrDefault(newRS.Spec.Replicas), nil
+}
+
+func podConditionTrue(pod *corev1.Pod, conditionType corev1.PodConditionType) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == conditionType {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
 func (c *Controller) reconcileOldReplicaSetsCanary(allRSs []*appsv1.ReplicaSet, oldRSs []*appsv1.ReplicaSet, roCtx *canaryContext) (bool, error) {
 	rollout := roCtx.Rollout()
 	logCtx := roCtx.Log()
@@ -226,13 +468,17 @@ func completedCurrentCanaryStep(roCtx *canaryContext) bool {
 	if r.Spec.Paused {
 		return false
 	}
+	// A rollout rolled back to an earlier step stays at that step until it is resumed
+	if getPauseCondition(r, v1alpha1.PauseReasonRollbackToStep) != nil {
+		return false
+	}
 	logCtx := roCtx.Log()
 	currentStep, _ := replicasetutil.GetCurrentCanaryStep(r)
 	if currentStep == nil {
 		return false
 	}
 	if currentStep.Pause != nil {
-		return roCtx.PauseContext().CompletedPauseStep(*currentStep.Pause)
+		return roCtx.PauseContext().CompletedPauseStep(*currentStep.Pause, roCtx.CurrentAnalysisRuns().CanaryBackground)
 	}
 	modifyReplicasStep := currentStep.SetWeight != nil || currentStep.SetCanaryScale != nil
 	if modifyReplicasStep && replicasetutil.AtDesiredReplicaCountsForCanary(r, roCtx.NewRS(), roCtx.StableRS(), roCtx.OlderRSs()) {
@@ -243,6 +489,10 @@ func completedCurrentCanaryStep(roCtx *canaryContext) bool {
 	if currentStep.Experiment != nil && experiment != nil && experiment.Status.Phase.Completed() && experiment.Status.Phase == v1alpha1.AnalysisPhaseSuccessful {
 		return true
 	}
+	if currentStep.Analysis != nil && currentStep.Analysis.Aggregation != "" {
+		phase, _ := aggregateStepAnalysisRunPhase(currentStep.Analysis, roCtx.CurrentAnalysisRuns().CanaryAggregatedStep)
+		return phase == v1alpha1.AnalysisPhaseSuccessful
+	}
 	currentStepAr := roCtx.CurrentAnalysisRuns().CanaryStep
 	analysisExistsAndCompleted := currentStepAr != nil && currentStepAr.Status.Phase.Completed()
 	if currentStep.Analysis != nil && analysisExistsAndCompleted && currentStepAr.Status.Phase == v1alpha1.AnalysisPhaseSuccessful {
@@ -286,6 +536,7 @@ func (c *Controller) syncRolloutStatusCanary(roCtx *canaryContext) error {
 		roCtx.SetRestartedAt()
 		newStatus = c.calculateRolloutConditions(roCtx, newStatus)
 		newStatus.Canary.CurrentStepAnalysisRunStatus = nil
+		newStatus.Canary.CurrentStepAnalysisRuns = nil
 		newStatus.Canary.CurrentBackgroundAnalysisRunStatus = nil
 		return c.persistRolloutStatus(roCtx, &newStatus)
 	}
@@ -308,7 +559,18 @@ func (c *Controller) syncRolloutStatusCanary(roCtx *canaryContext) error {
 		return c.persistRolloutStatus(roCtx, &newStatus)
 	}
 
+	newStatus.Canary.AnalysisRetries = r.Status.Canary.AnalysisRetries
+	if roCtx.PauseContext().retryAbort {
+		newStatus.Canary.AnalysisRetries++
+	}
+
+	// The quarantine label is set once the ReplicaSets are scaled, since the label is patched on the canary ReplicaSet
+	if err := c.reconcileCanaryQuarantine(roCtx); err != nil {
+		return err
+	}
+
 	if roCtx.PauseContext().IsAborted() {
+		newStatus.Canary.AbortedWeight = calculateAbortedWeight(r)
 		if stepCount > int32(0) {
 			if newStatus.StableRS == newStatus.CurrentPodHash {
 				newStatus.CurrentStepIndex = &stepCount
@@ -323,7 +585,7 @@ func (c *Controller) syncRolloutStatusCanary(roCtx *canaryContext) error {
 	if currentStepIndex != nil && *currentStepIndex == stepCount {
 		logCtx.Info("Rollout has executed every step")
 		newStatus.CurrentStepIndex = &stepCount
-		if newRS != nil && newRS.Status.AvailableReplicas == defaults.GetReplicasOrDefault(r.Spec.Replicas) {
+		if newRS != nil && newRS.Status.AvailableReplicas == defaults.GetReplicasOrDefault(r.Spec.Replicas) && c.completedBakeTime(roCtx, &newStatus) {
 			logCtx.Info("New RS has successfully progressed")
 			newStatus.StableRS = newStatus.CurrentPodHash
 			//TODO(dthomson) Remove in v0.9.0
@@ -336,7 +598,7 @@ func (c *Controller) syncRolloutStatusCanary(roCtx *canaryContext) error {
 
 	if stepCount == 0 {
 		logCtx.Info("Rollout has no steps")
-		if newRS != nil && newRS.Status.AvailableReplicas == defaults.GetReplicasOrDefault(r.Spec.Replicas) {
+		if newRS != nil && newRS.Status.AvailableReplicas == defaults.GetReplicasOrDefault(r.Spec.Replicas) && c.completedBakeTime(roCtx, &newStatus) {
 			logCtx.Info("New RS has successfully progressed")
 			newStatus.StableRS = newStatus.CurrentPodHash
 			//TODO(dthomson) Remove in v0.9.0
@@ -346,10 +608,91 @@ func (c *Controller) syncRolloutStatusCanary(roCtx *canaryContext) error {
 		return c.persistRolloutStatus(roCtx, &newStatus)
 	}
 
+	// The warmup is not repeated when the rollout is rolled back to the first step
+	newStatus.Canary.WarmupCompleted = r.Status.Canary.WarmupCompleted
+	newStatus.Canary.ReadinessGateSatisfied = r.Status.Canary.ReadinessGateSatisfied
+	newStatus.Canary.DependencySatisfied = r.Status.Canary.DependencySatisfied
+	if rollbackToStep := roCtx.PauseContext().rollbackToStep; rollbackToStep != nil {
+		msg := fmt.Sprintf("Rolling back to step %d after a failed analysis: %s", *rollbackToStep, roCtx.PauseContext().rollbackMessage)
+		logCtx.Info(msg)
+		c.recorder.Event(r, corev1.EventTypeWarning, "RollbackToStep", msg)
+		newStatus.CurrentStepIndex = rollbackToStep
+		// The analysis runs of the rolled back steps are created again once the rollout is resumed
+		newStatus.Canary.CurrentStepAnalysisRun = ""
+		newStatus.Canary.CurrentStepAnalysisRunStatus = nil
+		newStatus.Canary.CurrentStepAnalysisRuns = nil
+		newStatus.Canary.CurrentBackgroundAnalysisRun = ""
+		newStatus.Canary.CurrentBackgroundAnalysisRunStatus = nil
+		newStatus = c.calculateRolloutConditions(roCtx, newStatus)
+		return c.persistRolloutStatus(roCtx, &newStatus)
+	}
+
+	// The first step is held until the rollout the canary depends on is Healthy
+	if replicasetutil.WaitingForDependentRollout(r) {
+		satisfied, err := c.satisfiedDependency(roCtx)
+		if err != nil {
+			return err
+		}
+		if satisfied {
+			msg := fmt.Sprintf("Rollout '%s' the canary depends on is Healthy", r.Spec.Strategy.Canary.DependsOnRollout)
+			logCtx.Info(msg)
+			c.recorder.Event(r, corev1.EventTypeNormal, "DependencySatisfied", msg)
+			newStatus.Canary.DependencySatisfied = true
+		} else {
+			// The controller is not notified of the changes of the status of the rollout the canary depends on
+			logCtx.Infof("Enqueueing Rollout in %s to check the rollout it depends on", dependencyCheckTime.String())
+			c.enqueueRolloutAfter(r, dependencyCheckTime)
+		}
+		newStatus.CurrentStepIndex = currentStepIndex
+		newStatus = c.calculateRolloutConditions(roCtx, newStatus)
+		return c.persistRolloutStatus(roCtx, &newStatus)
+	}
+
+	// The readiness gate is checked once the canary is due to receive traffic, and holds the current step until all the
+	// canary pods satisfy it
+	if replicasetutil.WaitingForCanaryReadinessGate(r) && replicasetutil.GetCurrentSetWeight(r) > 0 {
+		satisfied, err := c.satisfiedCanaryReadinessGate(roCtx)
+		if err != nil {
+			return err
+		}
+		if satisfied {
+			msg := fmt.Sprintf("Canary pods satisfied the readiness gate '%s'", r.Spec.Strategy.Canary.ReadinessGate.ConditionType)
+			logCtx.Info(msg)
+			c.recorder.Event(r, corev1.EventTypeNormal, "ReadinessGateSatisfied", msg)
+			newStatus.Canary.ReadinessGateSatisfied = true
+		} else {
+			// The controller is not notified of the changes of the conditions of the pods
+			logCtx.Infof("Enqueueing Rollout in %s to check the readiness gate", readinessGateCheckTime.String())
+			c.enqueueRolloutAfter(r, readinessGateCheckTime)
+		}
+		newStatus.CurrentStepIndex = currentStepIndex
+		newStatus = c.calculateRolloutConditions(roCtx, newStatus)
+		return c.persistRolloutStatus(roCtx, &newStatus)
+	}
+
+	if replicasetutil.InCanaryWarmup(r) {
+		if c.completedCanaryWarmup(roCtx, &newStatus) {
+			msg := "Canary pods passed the warmup ready check"
+			logCtx.Info(msg)
+			c.recorder.Event(r, corev1.EventTypeNormal, "WarmupCompleted", msg)
+			newStatus.Canary.WarmupCompleted = true
+		}
+		newStatus.CurrentStepIndex = currentStepIndex
+		newStatus = c.calculateRolloutConditions(roCtx, newStatus)
+		return c.persistRolloutStatus(roCtx, &newStatus)
+	}
+
+	// A shortfall of canary replicas once the current step reached its replica counts is a loss of canary pods
+	newStatus.Canary.ReplicasReachedStepIndex = r.Status.Canary.ReplicasReachedStepIndex
+	if r.Spec.Strategy.Canary.RebalanceWeightOnReplicaLoss && currentStepIndex != nil && replicasetutil.AtDesiredReplicaCountsForCanary(r, newRS, stableRS, roCtx.OlderRSs()) {
+		newStatus.Canary.ReplicasReachedStepIndex = pointer.Int32Ptr(*currentStepIndex)
+	}
+
 	if completedCurrentCanaryStep(roCtx) {
 		*currentStepIndex++
 		newStatus.CurrentStepIndex = currentStepIndex
 		newStatus.Canary.CurrentStepAnalysisRun = ""
+		newStatus.Canary.CurrentStepAnalysisRuns = nil
 		if int(*currentStepIndex) == len(r.Spec.Strategy.Canary.Steps) {
 			c.recorder.Event(r, corev1.EventTypeNormal, "SettingStableRS", "Completed all steps")
 		}
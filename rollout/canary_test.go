@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -316,6 +317,256 @@ func TestCanaryRolloutUpdateStatusWhenAtEndOfSteps(t *testing.T) {
 	assert.Equal(t, calculatePatch(r2, expectedPatch), patch)
 }
 
+func TestCanaryRolloutBakeAtEndOfSteps(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	steps := []v1alpha1.CanaryStep{
+		{
+			Pause: &v1alpha1.RolloutPause{},
+		},
+	}
+	r1 := newCanaryRollout("foo", 10, nil, steps, pointer.Int32Ptr(1), intstr.FromInt(1), intstr.FromInt(0))
+	r1.Spec.Strategy.Canary.BakeTime = v1alpha1.DurationFromInt(60)
+	r2 := bumpVersion(r1)
+
+	rs1 := newReplicaSetWithStatus(r1, 0, 0)
+	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+	rs2 := newReplicaSetWithStatus(r2, 10, 10)
+	f.kubeobjects = append(f.kubeobjects, rs1, rs2)
+	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+
+	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 10, 10, 10, false)
+
+	f.rolloutLister = append(f.rolloutLister, r2)
+	f.objects = append(f.objects, r2)
+
+	patchIndex := f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+
+	patch := f.getPatchedRollout(patchIndex)
+	var patchObj map[string]interface{}
+	err := json.Unmarshal([]byte(patch), &patchObj)
+	assert.NoError(t, err)
+
+	status := patchObj["status"].(map[string]interface{})
+	_, ok := status["stableRS"]
+	assert.False(t, ok)
+	canaryStatus := status["canary"].(map[string]interface{})
+	now := metav1.Now().UTC().Format(time.RFC3339)
+	assert.Equal(t, now, canaryStatus["bakeStartedAt"])
+}
+
+func TestCanaryRolloutPromoteAfterBakeTime(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	steps := []v1alpha1.CanaryStep{
+		{
+			Pause: &v1alpha1.RolloutPause{},
+		},
+	}
+	r1 := newCanaryRollout("foo", 10, nil, steps, pointer.Int32Ptr(1), intstr.FromInt(1), intstr.FromInt(0))
+	r1.Spec.Strategy.Canary.BakeTime = v1alpha1.DurationFromString("1m")
+	r2 := bumpVersion(r1)
+
+	expectedStableRS := r2.Status.CurrentPodHash
+	rs1 := newReplicaSetWithStatus(r1, 0, 0)
+	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+	rs2 := newReplicaSetWithStatus(r2, 10, 10)
+	f.kubeobjects = append(f.kubeobjects, rs1, rs2)
+	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+
+	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 10, 10, 10, false)
+	overAMinuteAgo := metav1.NewTime(time.Now().Add(-61 * time.Second))
+	r2.Status.Canary.BakeStartedAt = &overAMinuteAgo
+
+	f.rolloutLister = append(f.rolloutLister, r2)
+	f.objects = append(f.objects, r2)
+
+	patchIndex := f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+
+	patch := f.getPatchedRollout(patchIndex)
+	var patchObj map[string]interface{}
+	err := json.Unmarshal([]byte(patch), &patchObj)
+	assert.NoError(t, err)
+
+	status := patchObj["status"].(map[string]interface{})
+	assert.Equal(t, expectedStableRS, status["stableRS"])
+	canaryStatus := status["canary"].(map[string]interface{})
+	bakeStartedAt, ok := canaryStatus["bakeStartedAt"]
+	assert.True(t, ok)
+	assert.Nil(t, bakeStartedAt)
+}
+
+func newWarmupCanaryRollout(readySeconds int32) *v1alpha1.Rollout {
+	steps := []v1alpha1.CanaryStep{{
+		SetWeight: int32Ptr(50),
+	}}
+	r := newCanaryRollout("foo", 10, nil, steps, int32Ptr(0), intstr.FromInt(5), intstr.FromInt(0))
+	r.Spec.Strategy.Canary.WarmupWeight = int32Ptr(10)
+	r.Spec.Strategy.Canary.WarmupReadyCheck = &v1alpha1.WarmupReadyCheck{ReadySeconds: readySeconds}
+	return r
+}
+
+func TestCanaryRolloutScaleUpToWarmupWeight(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	r1 := newWarmupCanaryRollout(30)
+	r2 := bumpVersion(r1)
+	rs1 := newReplicaSetWithStatus(r1, 10, 10)
+	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+	rs2 := newReplicaSetWithStatus(r2, 0, 0)
+	f.kubeobjects = append(f.kubeobjects, rs1, rs2)
+	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+
+	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 10, 0, 10, false)
+	f.rolloutLister = append(f.rolloutLister, r2)
+	f.objects = append(f.objects, r2)
+
+	// the canary is scaled to the warmup weight instead of the weight of the first step
+	updatedRSIndex := f.expectUpdateReplicaSetAction(rs2)
+	f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+
+	updatedRS := f.getUpdatedReplicaSet(updatedRSIndex)
+	assert.Equal(t, int32(1), *updatedRS.Spec.Replicas)
+}
+
+func TestCanaryRolloutWarmupWaitsForReadyPods(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	r1 := newWarmupCanaryRollout(30)
+	r2 := bumpVersion(r1)
+	rs1 := newReplicaSetWithStatus(r1, 9, 9)
+	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+	rs2 := newReplicaSetWithStatus(r2, 1, 0)
+	f.kubeobjects = append(f.kubeobjects, rs1, rs2)
+	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+
+	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 9, 1, 10, false)
+	f.rolloutLister = append(f.rolloutLister, r2)
+	f.objects = append(f.objects, r2)
+
+	patchIndex := f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+
+	patch := f.getPatchedRollout(patchIndex)
+	var patchObj map[string]interface{}
+	err := json.Unmarshal([]byte(patch), &patchObj)
+	assert.NoError(t, err)
+
+	status := patchObj["status"].(map[string]interface{})
+	_, ok := status["currentStepIndex"]
+	assert.False(t, ok)
+	_, ok = status["canary"]
+	assert.False(t, ok)
+}
+
+func TestCanaryRolloutWarmupStartsReadyCheck(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	r1 := newWarmupCanaryRollout(30)
+	r2 := bumpVersion(r1)
+	rs1 := newReplicaSetWithStatus(r1, 9, 9)
+	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+	rs2 := newReplicaSetWithStatus(r2, 1, 1)
+	f.kubeobjects = append(f.kubeobjects, rs1, rs2)
+	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+
+	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 10, 1, 10, false)
+	f.rolloutLister = append(f.rolloutLister, r2)
+	f.objects = append(f.objects, r2)
+
+	patchIndex := f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+
+	patch := f.getPatchedRollout(patchIndex)
+	var patchObj map[string]interface{}
+	err := json.Unmarshal([]byte(patch), &patchObj)
+	assert.NoError(t, err)
+
+	status := patchObj["status"].(map[string]interface{})
+	_, ok := status["currentStepIndex"]
+	assert.False(t, ok)
+	canaryStatus := status["canary"].(map[string]interface{})
+	now := metav1.Now().UTC().Format(time.RFC3339)
+	assert.Equal(t, now, canaryStatus["warmupReadySince"])
+	_, ok = canaryStatus["warmupCompleted"]
+	assert.False(t, ok)
+}
+
+func TestCanaryRolloutCompleteWarmupAfterReadySeconds(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	r1 := newWarmupCanaryRollout(30)
+	r2 := bumpVersion(r1)
+	rs1 := newReplicaSetWithStatus(r1, 9, 9)
+	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+	rs2 := newReplicaSetWithStatus(r2, 1, 1)
+	f.kubeobjects = append(f.kubeobjects, rs1, rs2)
+	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+
+	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 10, 1, 10, false)
+	overThirtySecondsAgo := metav1.NewTime(time.Now().Add(-31 * time.Second))
+	r2.Status.Canary.WarmupReadySince = &overThirtySecondsAgo
+	f.rolloutLister = append(f.rolloutLister, r2)
+	f.objects = append(f.objects, r2)
+
+	patchIndex := f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+
+	patch := f.getPatchedRollout(patchIndex)
+	var patchObj map[string]interface{}
+	err := json.Unmarshal([]byte(patch), &patchObj)
+	assert.NoError(t, err)
+
+	status := patchObj["status"].(map[string]interface{})
+	_, ok := status["currentStepIndex"]
+	assert.False(t, ok)
+	canaryStatus := status["canary"].(map[string]interface{})
+	assert.Equal(t, true, canaryStatus["warmupCompleted"])
+	readySince, ok := canaryStatus["warmupReadySince"]
+	assert.True(t, ok)
+	assert.Nil(t, readySince)
+}
+
+func TestCanaryRolloutIncrementStepAfterWarmup(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	r1 := newWarmupCanaryRollout(30)
+	r2 := bumpVersion(r1)
+	rs1 := newReplicaSetWithStatus(r1, 5, 5)
+	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+	rs2 := newReplicaSetWithStatus(r2, 5, 5)
+	f.kubeobjects = append(f.kubeobjects, rs1, rs2)
+	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+
+	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 10, 5, 10, false)
+	r2.Status.Canary.WarmupCompleted = true
+	f.rolloutLister = append(f.rolloutLister, r2)
+	f.objects = append(f.objects, r2)
+
+	patchIndex := f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+
+	patch := f.getPatchedRollout(patchIndex)
+	expectedPatch := `{
+		"status":{
+			"currentStepIndex":1,
+			"conditions": %s
+		}
+	}`
+	newConditions := generateConditionsPatch(true, conditions.ReplicaSetUpdatedReason, rs2, false, "")
+	assert.Equal(t, calculatePatch(r2, fmt.Sprintf(expectedPatch, newConditions)), patch)
+}
+
 func TestResetCurrentStepIndexOnStepChange(t *testing.T) {
 	f := newFixture(t)
 	defer f.Close()
@@ -1160,6 +1411,104 @@ func TestResumeRolloutAfterPauseDuration(t *testing.T) {
 	assert.Nil(t, controllerPause)
 }
 
+func TestCompletedCurrentCanaryStepAfterRollbackToStep(t *testing.T) {
+	steps := []v1alpha1.CanaryStep{{SetWeight: pointer.Int32Ptr(10)}}
+	r := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(1), intstr.FromInt(1))
+	rs := newReplicaSetWithStatus(r, 1, 1)
+	r.Status.ControllerPause = true
+	r.Status.PauseConditions = []v1alpha1.PauseCondition{{
+		Reason:    v1alpha1.PauseReasonRollbackToStep,
+		StartTime: metav1.Now(),
+	}}
+
+	// The rollout stays at the step it rolled back to until it is resumed
+	roCtx := newCanaryCtx(r, rs, nil, nil, nil)
+	assert.False(t, completedCurrentCanaryStep(roCtx))
+}
+
+func TestCompletedPauseStepRequiringHealthyAnalysis(t *testing.T) {
+	steps := []v1alpha1.CanaryStep{
+		{
+			Pause: &v1alpha1.RolloutPause{
+				Duration:               v1alpha1.DurationFromInt(60),
+				RequireHealthyAnalysis: true,
+			},
+		},
+		{
+			SetWeight: pointer.Int32Ptr(20),
+		},
+	}
+	newPausedRollout := func(pausedFor time.Duration) *v1alpha1.Rollout {
+		r := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(1), intstr.FromInt(1))
+		r.Spec.Strategy.Canary.Analysis = &v1alpha1.RolloutAnalysisBackground{
+			RolloutAnalysis: v1alpha1.RolloutAnalysis{
+				TemplateName: "bar",
+			},
+		}
+		r.Status.ControllerPause = true
+		r.Status.PauseConditions = []v1alpha1.PauseCondition{{
+			Reason:    v1alpha1.PauseReasonCanaryPauseStep,
+			StartTime: metav1.NewTime(time.Now().Add(-pausedFor)),
+		}}
+		r.Status.Canary.CurrentBackgroundAnalysisRun = "foo-background"
+		return r
+	}
+	newBackgroundAnalysisRun := func(phase v1alpha1.AnalysisPhase, lastMeasurement v1alpha1.AnalysisPhase) *v1alpha1.AnalysisRun {
+		return &v1alpha1.AnalysisRun{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "foo-background",
+			},
+			Status: v1alpha1.AnalysisRunStatus{
+				Phase: phase,
+				MetricResults: []v1alpha1.MetricResult{{
+					Name:  "success-rate",
+					Phase: v1alpha1.AnalysisPhaseRunning,
+					Measurements: []v1alpha1.Measurement{
+						{Phase: v1alpha1.AnalysisPhaseSuccessful},
+						{Phase: lastMeasurement},
+					},
+				}},
+			},
+		}
+	}
+
+	t.Run("resume when the analysis is passing", func(t *testing.T) {
+		ar := newBackgroundAnalysisRun(v1alpha1.AnalysisPhaseRunning, v1alpha1.AnalysisPhaseSuccessful)
+		roCtx := newCanaryCtx(newPausedRollout(61*time.Second), nil, nil, nil, []*v1alpha1.AnalysisRun{ar})
+		assert.True(t, completedCurrentCanaryStep(roCtx))
+	})
+
+	t.Run("stay paused when the last measurement failed", func(t *testing.T) {
+		ar := newBackgroundAnalysisRun(v1alpha1.AnalysisPhaseRunning, v1alpha1.AnalysisPhaseFailed)
+		roCtx := newCanaryCtx(newPausedRollout(61*time.Second), nil, nil, nil, []*v1alpha1.AnalysisRun{ar})
+		assert.False(t, completedCurrentCanaryStep(roCtx))
+	})
+
+	t.Run("stay paused without a background analysis run", func(t *testing.T) {
+		roCtx := newCanaryCtx(newPausedRollout(61*time.Second), nil, nil, nil, nil)
+		assert.False(t, completedCurrentCanaryStep(roCtx))
+	})
+
+	t.Run("stay paused during the duration", func(t *testing.T) {
+		ar := newBackgroundAnalysisRun(v1alpha1.AnalysisPhaseRunning, v1alpha1.AnalysisPhaseSuccessful)
+		roCtx := newCanaryCtx(newPausedRollout(30*time.Second), nil, nil, nil, []*v1alpha1.AnalysisRun{ar})
+		assert.False(t, completedCurrentCanaryStep(roCtx))
+	})
+
+	t.Run("requeue to check the analysis after the duration", func(t *testing.T) {
+		var requeuedAfter []time.Duration
+		c := &Controller{
+			enqueueRolloutAfter: func(obj interface{}, duration time.Duration) {
+				requeuedAfter = append(requeuedAfter, duration)
+			},
+		}
+		ar := newBackgroundAnalysisRun(v1alpha1.AnalysisPhaseRunning, v1alpha1.AnalysisPhaseFailed)
+		roCtx := newCanaryCtx(newPausedRollout(61*time.Second), nil, nil, nil, []*v1alpha1.AnalysisRun{ar})
+		assert.True(t, c.reconcileCanaryPause(roCtx))
+		assert.Equal(t, []time.Duration{healthyAnalysisCheckTime}, requeuedAfter)
+	})
+}
+
 func TestNoResumeAfterPauseDurationIfUserPaused(t *testing.T) {
 	f := newFixture(t)
 	defer f.Close()
@@ -1239,6 +1588,106 @@ func TestHandleNilNewRSOnScaleAndImageChange(t *testing.T) {
 	assert.Equal(t, calculatePatch(r2, OnlyObservedGenerationPatch), patch)
 }
 
+func TestCanaryQuarantine(t *testing.T) {
+	// newQuarantineRollout returns a rollout with a quarantine aborted at its third step, along with its canary
+	// ReplicaSet and the pod of the canary
+	newQuarantineRollout := func(f *fixture, abortedAt time.Time, canaryReplicas int, quarantined bool) (*v1alpha1.Rollout, *appsv1.ReplicaSet, *corev1.Pod) {
+		steps := []v1alpha1.CanaryStep{
+			{SetWeight: int32Ptr(10)},
+			{SetWeight: int32Ptr(20)},
+			{SetWeight: int32Ptr(30)},
+		}
+		r1 := newCanaryRollout("foo", 10, nil, steps, int32Ptr(2), intstr.FromInt(1), intstr.FromInt(0))
+		r1.Spec.Strategy.Canary.TrafficRouting = &v1alpha1.RolloutTrafficRouting{}
+		r1.Spec.Strategy.Canary.CanaryService = "canary"
+		r1.Spec.Strategy.Canary.StableService = "stable"
+		r1.Spec.Strategy.Canary.QuarantineOnFailure = &v1alpha1.CanaryQuarantine{}
+		rs1 := newReplicaSetWithStatus(r1, 10, 10)
+		rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+		r2 := bumpVersion(r1)
+		rs2 := newReplicaSetWithStatus(r2, canaryReplicas, canaryReplicas)
+		rs2PodHash := rs2.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+		pod := newCanaryPod(rs2, "canary-pod", corev1.ConditionTrue)
+		if quarantined {
+			rsLabels := map[string]string{v1alpha1.RolloutQuarantineLabelKey: "true"}
+			for k, v := range rs2.Labels {
+				rsLabels[k] = v
+			}
+			rs2.Labels = rsLabels
+			pod.Labels[v1alpha1.RolloutQuarantineLabelKey] = "true"
+		}
+		canarySvc := newService("canary", 80, map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: rs2PodHash}, r2)
+		stableSvc := newService("stable", 80, map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: rs1PodHash}, r2)
+
+		f.kubeobjects = append(f.kubeobjects, rs1, rs2, canarySvc, stableSvc, pod)
+		f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+
+		r2 = updateCanaryRolloutStatus(r2, rs1PodHash, int32(10+canaryReplicas), int32(canaryReplicas), int32(10+canaryReplicas), false)
+		r2.Status.Abort = true
+		r2.Status.AbortedAt = &metav1.Time{Time: abortedAt}
+		f.rolloutLister = append(f.rolloutLister, r2)
+		f.objects = append(f.objects, r2)
+		return r2, rs2, pod
+	}
+
+	t.Run("Scale down the canary to the quarantined pods", func(t *testing.T) {
+		f := newFixture(t)
+		defer f.Close()
+
+		r2, rs2, pod := newQuarantineRollout(f, time.Now().Add(-time.Minute), 3, false)
+
+		rsIndex := f.expectUpdateReplicaSetAction(rs2)
+		f.expectPatchReplicaSetAction(rs2)
+		f.expectListPodAction(rs2.Namespace)
+		f.expectPatchPodAction(pod)
+		f.expectPatchRolloutAction(r2)
+		f.run(getKey(r2, t))
+
+		// the quarantined pods are kept without traffic
+		updatedRS := f.getUpdatedReplicaSet(rsIndex)
+		assert.Equal(t, int32(1), *updatedRS.Spec.Replicas)
+		assert.Equal(t, int32(0), f.fakeTrafficRouting.controllerSetDesiredWeight)
+		labeledRS, err := f.kubeclient.AppsV1().ReplicaSets(rs2.Namespace).Get(rs2.Name, metav1.GetOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, "true", labeledRS.Labels[v1alpha1.RolloutQuarantineLabelKey])
+		labeledPod, err := f.kubeclient.CoreV1().Pods(pod.Namespace).Get(pod.Name, metav1.GetOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, "true", labeledPod.Labels[v1alpha1.RolloutQuarantineLabelKey])
+	})
+
+	t.Run("Clean up the quarantine after the TTL", func(t *testing.T) {
+		f := newFixture(t)
+		defer f.Close()
+
+		r2, rs2, pod := newQuarantineRollout(f, time.Now().Add(-2*time.Hour), 1, true)
+
+		rsIndex := f.expectUpdateReplicaSetAction(rs2)
+		f.expectPatchReplicaSetAction(rs2)
+		f.expectListPodAction(rs2.Namespace)
+		f.expectPatchPodAction(pod)
+		f.expectPatchRolloutAction(r2)
+		f.run(getKey(r2, t))
+
+		updatedRS := f.getUpdatedReplicaSet(rsIndex)
+		assert.Equal(t, int32(0), *updatedRS.Spec.Replicas)
+		unlabeledPod, err := f.kubeclient.CoreV1().Pods(pod.Namespace).Get(pod.Name, metav1.GetOptions{})
+		assert.NoError(t, err)
+		assert.NotContains(t, unlabeledPod.Labels, v1alpha1.RolloutQuarantineLabelKey)
+	})
+
+	t.Run("Keep the quarantined pods until the TTL", func(t *testing.T) {
+		f := newFixture(t)
+		defer f.Close()
+
+		r2, rs2, _ := newQuarantineRollout(f, time.Now().Add(-time.Minute), 1, true)
+
+		// the labels are already set, so the pods are only listed
+		f.expectListPodAction(rs2.Namespace)
+		f.expectPatchRolloutAction(r2)
+		f.run(getKey(r2, t))
+	})
+}
+
 func TestHandleCanaryAbort(t *testing.T) {
 	t.Run("Scale up stable ReplicaSet", func(t *testing.T) {
 		f := newFixture(t)
@@ -1283,6 +1732,56 @@ func TestHandleCanaryAbort(t *testing.T) {
 		assert.Equal(t, calculatePatch(r2, fmt.Sprintf(expectedPatch, newConditions)), patch)
 	})
 
+	t.Run("Ramp down the traffic of the canary", func(t *testing.T) {
+		f := newFixture(t)
+		defer f.Close()
+
+		steps := []v1alpha1.CanaryStep{
+			{SetWeight: int32Ptr(10)},
+			{SetWeight: int32Ptr(20)},
+			{SetWeight: int32Ptr(30)},
+		}
+		r1 := newCanaryRollout("foo", 10, nil, steps, int32Ptr(1), intstr.FromInt(1), intstr.FromInt(0))
+		r1.Spec.Strategy.Canary.TrafficRouting = &v1alpha1.RolloutTrafficRouting{
+			AbortRampDownSeconds: int32Ptr(100),
+		}
+		r1.Spec.Strategy.Canary.CanaryService = "canary"
+		r1.Spec.Strategy.Canary.StableService = "stable"
+		rs1 := newReplicaSetWithStatus(r1, 10, 10)
+		rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+		r2 := bumpVersion(r1)
+		rs2 := newReplicaSetWithStatus(r2, 1, 1)
+		rs2PodHash := rs2.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+		canarySvc := newService("canary", 80, map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: rs2PodHash}, r2)
+		stableSvc := newService("stable", 80, map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: rs1PodHash}, r2)
+
+		f.kubeobjects = append(f.kubeobjects, rs1, rs2, canarySvc, stableSvc)
+		f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+
+		r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 11, 1, 11, false)
+		r2.Status.Abort = true
+		// halfway through the ramp down from the weight of the second step
+		abortedAt := metav1.NewTime(time.Now().Add(-55 * time.Second))
+		r2.Status.AbortedAt = &abortedAt
+		r2.Status.Canary.AbortedWeight = 20
+		f.rolloutLister = append(f.rolloutLister, r2)
+		f.objects = append(f.objects, r2)
+
+		patchIndex := f.expectPatchRolloutAction(r2)
+		f.run(getKey(r2, t))
+
+		assert.Equal(t, int32(10), f.fakeTrafficRouting.controllerSetDesiredWeight)
+		patch := f.getPatchedRollout(patchIndex)
+		expectedPatch := `{
+			"status":{
+				"currentStepIndex": 0,
+				"conditions": %s
+			}
+		}`
+		newConditions := generateConditionsPatch(true, conditions.RolloutAbortedReason, r2, false, "")
+		assert.Equal(t, calculatePatch(r2, fmt.Sprintf(expectedPatch, newConditions)), patch)
+	})
+
 	t.Run("Do not reset currentStepCount if newRS is stableRS", func(t *testing.T) {
 		f := newFixture(t)
 		defer f.Close()
@@ -4,9 +4,11 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
 	analysisutil "github.com/argoproj/argo-rollouts/utils/analysis"
+	"github.com/argoproj/argo-rollouts/utils/annotations"
 	experimentutil "github.com/argoproj/argo-rollouts/utils/experiment"
 	logutil "github.com/argoproj/argo-rollouts/utils/log"
 	replicasetutil "github.com/argoproj/argo-rollouts/utils/replicaset"
@@ -82,6 +84,10 @@ func newBlueGreenCtx(r *v1alpha1.Rollout, newRS *appsv1.ReplicaSet, olderRSs []*
 
 		newStatus: v1alpha1.RolloutStatus{
 			RestartedAt: r.Status.RestartedAt,
+			BlueGreen: v1alpha1.BlueGreenStatus{
+				PreviewTrafficRampStepIndex:     r.Status.BlueGreen.PreviewTrafficRampStepIndex,
+				PreviewTrafficRampStepStartedAt: r.Status.BlueGreen.PreviewTrafficRampStepStartedAt,
+			},
 		},
 		pauseContext: &pauseContext{
 			rollout: r,
@@ -134,20 +140,54 @@ func (bgCtx *blueGreenContext) SetCurrentAnalysisRuns(currAr analysisutil.Curren
 	if currPrePromoAr != nil && currPrePromoAr.Status.Phase != v1alpha1.AnalysisPhaseSuccessful {
 		bgCtx.newStatus.BlueGreen.PrePromotionAnalysisRun = currPrePromoAr.Name
 		bgCtx.newStatus.BlueGreen.PrePromotionAnalysisRunStatus = &v1alpha1.RolloutAnalysisRunStatus{
-			Name:    currPrePromoAr.Name,
-			Status:  currPrePromoAr.Status.Phase,
-			Message: currPrePromoAr.Status.Message,
+			Name:                 currPrePromoAr.Name,
+			Status:               currPrePromoAr.Status.Phase,
+			Message:              currPrePromoAr.Status.Message,
+			TemplateChangePolicy: templateChangePolicy(currPrePromoAr),
 		}
 	}
 	currPostPromoAr := currAr.BlueGreenPostPromotion
 	if currPostPromoAr != nil && currPostPromoAr.Status.Phase != v1alpha1.AnalysisPhaseSuccessful {
 		bgCtx.newStatus.BlueGreen.PostPromotionAnalysisRun = currPostPromoAr.Name
 		bgCtx.newStatus.BlueGreen.PostPromotionAnalysisRunStatus = &v1alpha1.RolloutAnalysisRunStatus{
-			Name:    currPostPromoAr.Name,
-			Status:  currPostPromoAr.Status.Phase,
-			Message: currPostPromoAr.Status.Message,
+			Name:                 currPostPromoAr.Name,
+			Status:               currPostPromoAr.Status.Phase,
+			Message:              currPostPromoAr.Status.Message,
+			TemplateChangePolicy: templateChangePolicy(currPostPromoAr),
 		}
 	}
+	// The analysis of the preview traffic ramp stays current until the ramp completes, even once it is successful
+	currPreviewTrafficRampAr := currAr.BlueGreenPreviewTrafficRamp
+	if currPreviewTrafficRampAr != nil {
+		bgCtx.newStatus.BlueGreen.PreviewTrafficRampAnalysisRunStatus = &v1alpha1.RolloutAnalysisRunStatus{
+			Name:                 currPreviewTrafficRampAr.Name,
+			Status:               currPreviewTrafficRampAr.Status.Phase,
+			Message:              currPreviewTrafficRampAr.Status.Message,
+			TemplateChangePolicy: templateChangePolicy(currPreviewTrafficRampAr),
+		}
+	}
+	// The analysis of the scale down drain stays current until its ReplicaSet is scaled down
+	currScaleDownDrainAr := currAr.BlueGreenScaleDownDrain
+	if currScaleDownDrainAr != nil {
+		bgCtx.newStatus.BlueGreen.ScaleDownDrainAnalysisRunStatus = &v1alpha1.RolloutAnalysisRunStatus{
+			Name:                 currScaleDownDrainAr.Name,
+			Status:               currScaleDownDrainAr.Status.Phase,
+			Message:              currScaleDownDrainAr.Status.Message,
+			TemplateChangePolicy: templateChangePolicy(currScaleDownDrainAr),
+		}
+	}
+}
+
+// SetPreviewTrafficRampStep sets the current step of the preview traffic ramp and when it started
+func (bgCtx *blueGreenContext) SetPreviewTrafficRampStep(index int32, startedAt metav1.Time) {
+	bgCtx.newStatus.BlueGreen.PreviewTrafficRampStepIndex = &index
+	bgCtx.newStatus.BlueGreen.PreviewTrafficRampStepStartedAt = &startedAt
+}
+
+// ClearPreviewTrafficRamp restarts the preview traffic ramp at the next promotion
+func (bgCtx *blueGreenContext) ClearPreviewTrafficRamp() {
+	bgCtx.newStatus.BlueGreen.PreviewTrafficRampStepIndex = nil
+	bgCtx.newStatus.BlueGreen.PreviewTrafficRampStepStartedAt = nil
 }
 
 func (bgCtx *blueGreenContext) OtherExperiments() []*v1alpha1.Experiment {
@@ -229,20 +269,32 @@ func (cCtx *canaryContext) SetCurrentAnalysisRuns(currARs analysisutil.CurrentAn
 	if currBackgroundAr != nil && currBackgroundAr.Status.Phase != v1alpha1.AnalysisPhaseSuccessful {
 		cCtx.newStatus.Canary.CurrentBackgroundAnalysisRun = currBackgroundAr.Name
 		cCtx.newStatus.Canary.CurrentBackgroundAnalysisRunStatus = &v1alpha1.RolloutAnalysisRunStatus{
-			Name:    currBackgroundAr.Name,
-			Status:  currBackgroundAr.Status.Phase,
-			Message: currBackgroundAr.Status.Message,
+			Name:                 currBackgroundAr.Name,
+			Status:               currBackgroundAr.Status.Phase,
+			Message:              currBackgroundAr.Status.Message,
+			TemplateChangePolicy: templateChangePolicy(currBackgroundAr),
 		}
 	}
 	currStepAr := currARs.CanaryStep
 	if currStepAr != nil && currStepAr.Status.Phase != v1alpha1.AnalysisPhaseSuccessful {
 		cCtx.newStatus.Canary.CurrentStepAnalysisRun = currStepAr.Name
 		cCtx.newStatus.Canary.CurrentStepAnalysisRunStatus = &v1alpha1.RolloutAnalysisRunStatus{
-			Name:    currStepAr.Name,
-			Status:  currStepAr.Status.Phase,
-			Message: currStepAr.Status.Message,
+			Name:                 currStepAr.Name,
+			Status:               currStepAr.Status.Phase,
+			Message:              currStepAr.Status.Message,
+			TemplateChangePolicy: templateChangePolicy(currStepAr),
 		}
 	}
+	// The AnalysisRuns of an aggregated step stay current until the step completes, even once they are successful
+	for _, ar := range currARs.CanaryAggregatedStep {
+		cCtx.newStatus.Canary.CurrentStepAnalysisRuns = append(cCtx.newStatus.Canary.CurrentStepAnalysisRuns, v1alpha1.RolloutAnalysisRunStatus{
+			Name:                 ar.Name,
+			Status:               ar.Status.Phase,
+			Message:              ar.Status.Message,
+			TemplateChangePolicy: templateChangePolicy(ar),
+			Region:               ar.Labels[v1alpha1.RolloutAnalysisRegionLabel],
+		})
+	}
 }
 
 func (cCtx *canaryContext) CurrentAnalysisRuns() analysisutil.CurrentAnalysisRuns {
@@ -283,3 +335,8 @@ func (cCtx *canaryContext) NewStatus() v1alpha1.RolloutStatus {
 func (cCtx *canaryContext) SetRestartedAt() {
 	cCtx.newStatus.RestartedAt = cCtx.rollout.Spec.RestartAt
 }
+
+// templateChangePolicy returns the template change policy of the analysis the AnalysisRun was created for
+func templateChangePolicy(ar *v1alpha1.AnalysisRun) v1alpha1.AnalysisTemplateChangePolicy {
+	return v1alpha1.AnalysisTemplateChangePolicy(ar.Annotations[annotations.AnalysisTemplateChangePolicyAnnotation])
+}
@@ -44,6 +44,7 @@ import (
 	clientset "github.com/argoproj/argo-rollouts/pkg/client/clientset/versioned"
 	informers "github.com/argoproj/argo-rollouts/pkg/client/informers/externalversions/rollouts/v1alpha1"
 	listers "github.com/argoproj/argo-rollouts/pkg/client/listers/rollouts/v1alpha1"
+	"github.com/argoproj/argo-rollouts/rollout/trafficrouting/plugin"
 	"github.com/argoproj/argo-rollouts/utils/conditions"
 	controllerutil "github.com/argoproj/argo-rollouts/utils/controller"
 	"github.com/argoproj/argo-rollouts/utils/defaults"
@@ -72,6 +73,9 @@ type Controller struct {
 	smiclientset               smiclientset.Interface
 	defaultIstioVersion        string
 	defaultTrafficSplitVersion string
+	defaultTraefikVersion      string
+	// trafficRouterPlugins are the routers of the traffic router plugins keyed by name
+	trafficRouterPlugins plugin.Routers
 
 	replicaSetLister              appslisters.ReplicaSetLister
 	replicaSetSynced              cache.InformerSynced
@@ -79,6 +83,7 @@ type Controller struct {
 	rolloutsSynced                cache.InformerSynced
 	rolloutsIndexer               cache.Indexer
 	servicesLister                v1.ServiceLister
+	podLister                     v1.PodLister
 	ingressesLister               extensionslisters.IngressLister
 	experimentsLister             listers.ExperimentLister
 	analysisRunLister             listers.AnalysisRunLister
@@ -123,6 +128,7 @@ type ControllerConfig struct {
 	ClusterAnalysisTemplateInformer informers.ClusterAnalysisTemplateInformer
 	ReplicaSetInformer              appsinformers.ReplicaSetInformer
 	ServicesInformer                coreinformers.ServiceInformer
+	PodInformer                     coreinformers.PodInformer
 	IngressInformer                 extensionsinformers.IngressInformer
 	RolloutsInformer                informers.RolloutInformer
 	IstioVirtualServiceInformer     cache.SharedIndexInformer
@@ -134,6 +140,9 @@ type ControllerConfig struct {
 	Recorder                        record.EventRecorder
 	DefaultIstioVersion             string
 	DefaultTrafficSplitVersion      string
+	DefaultTraefikVersion           string
+	// TrafficRouterPlugins are the routers of the traffic router plugins which rollouts may use, keyed by name
+	TrafficRouterPlugins plugin.Routers
 }
 
 // NewController returns a new rollout controller
@@ -160,6 +169,8 @@ func NewController(cfg ControllerConfig) *Controller {
 		smiclientset:                  cfg.SmiClientSet,
 		defaultIstioVersion:           cfg.DefaultIstioVersion,
 		defaultTrafficSplitVersion:    cfg.DefaultTrafficSplitVersion,
+		defaultTraefikVersion:         cfg.DefaultTraefikVersion,
+		trafficRouterPlugins:          cfg.TrafficRouterPlugins,
 		replicaSetControl:             replicaSetControl,
 		replicaSetLister:              cfg.ReplicaSetInformer.Lister(),
 		replicaSetSynced:              cfg.ReplicaSetInformer.Informer().HasSynced,
@@ -170,6 +181,7 @@ func NewController(cfg ControllerConfig) *Controller {
 		serviceWorkqueue:              cfg.ServiceWorkQueue,
 		ingressWorkqueue:              cfg.IngressWorkQueue,
 		servicesLister:                cfg.ServicesInformer.Lister(),
+		podLister:                     cfg.PodInformer.Lister(),
 		ingressesLister:               cfg.IngressInformer.Lister(),
 		experimentsLister:             cfg.ExperimentInformer.Lister(),
 		analysisRunLister:             cfg.AnalysisRunInformer.Lister(),
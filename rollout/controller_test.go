@@ -76,6 +76,7 @@ type fixture struct {
 	analysisTemplateLister        []*v1alpha1.AnalysisTemplate
 	replicaSetLister              []*appsv1.ReplicaSet
 	serviceLister                 []*corev1.Service
+	podLister                     []*corev1.Pod
 	ingressLister                 []*extensionsv1beta1.Ingress
 	// Actions expected to happen on the client.
 	kubeactions []core.Action
@@ -419,6 +420,7 @@ func (f *fixture) newController(resync resyncFunc) (*Controller, informers.Share
 		ClusterAnalysisTemplateInformer: i.Argoproj().V1alpha1().ClusterAnalysisTemplates(),
 		ReplicaSetInformer:              k8sI.Apps().V1().ReplicaSets(),
 		ServicesInformer:                k8sI.Core().V1().Services(),
+		PodInformer:                     k8sI.Core().V1().Pods(),
 		IngressInformer:                 k8sI.Extensions().V1beta1().Ingresses(),
 		RolloutsInformer:                i.Argoproj().V1alpha1().Rollouts(),
 		IstioVirtualServiceInformer:     istioVirtualServiceInformer,
@@ -470,6 +472,9 @@ func (f *fixture) newController(resync resyncFunc) (*Controller, informers.Share
 	for _, s := range f.serviceLister {
 		k8sI.Core().V1().Services().Informer().GetIndexer().Add(s)
 	}
+	for _, p := range f.podLister {
+		k8sI.Core().V1().Pods().Informer().GetIndexer().Add(p)
+	}
 	for _, i := range f.ingressLister {
 		k8sI.Extensions().V1beta1().Ingresses().Informer().GetIndexer().Add(i)
 	}
@@ -589,7 +594,10 @@ func filterInformerActions(actions []core.Action) []core.Action {
 			action.Matches("list", "services") ||
 			action.Matches("watch", "services") ||
 			action.Matches("list", "ingresses") ||
-			action.Matches("watch", "ingresses") {
+			action.Matches("watch", "ingresses") ||
+			action.Matches("watch", "pods") ||
+			// unlike the pods listed by the controller, the pods listed by the informer are not namespaced
+			(action.Matches("list", "pods") && action.GetNamespace() == metav1.NamespaceAll) {
 			continue
 		}
 		ret = append(ret, action)
@@ -869,6 +877,19 @@ func (f *fixture) expectDeleteExperimentAction(ex *v1alpha1.Experiment) int {
 	return len
 }
 
+func (f *fixture) expectListPodAction(namespace string) int {
+	action := core.NewListAction(schema.GroupVersionResource{Resource: "pods"}, schema.GroupVersionKind{Kind: "Pod"}, namespace, metav1.ListOptions{})
+	len := len(f.kubeactions)
+	f.kubeactions = append(f.kubeactions, action)
+	return len
+}
+
+func (f *fixture) expectPatchPodAction(pod *corev1.Pod) int {
+	len := len(f.kubeactions)
+	f.kubeactions = append(f.kubeactions, core.NewPatchAction(schema.GroupVersionResource{Resource: "pods"}, pod.Namespace, pod.Name, types.MergePatchType, nil))
+	return len
+}
+
 func (f *fixture) expectDeleteReplicaSetAction(rs *appsv1.ReplicaSet) int {
 	action := core.NewDeleteAction(schema.GroupVersionResource{Resource: "replicasets"}, rs.Namespace, rs.Name)
 	len := len(f.kubeactions)
@@ -1187,7 +1208,7 @@ func TestComputeHashChangeTolerationBlueGreen(t *testing.T) {
 	// this should only update observedGeneration and nothing else
 	// NOTE: This test will fail on every k8s library upgrade.
 	// To fix it, update expectedPatch to match the new hash.
-	expectedPatch := `{"status":{"observedGeneration":"6979d9866d"}}`
+	expectedPatch := `{"status":{"observedGeneration":"bcbfcdbd8"}}`
 	patch := f.getPatchedRollout(patchIndex)
 	assert.Equal(t, expectedPatch, patch)
 }
@@ -1232,7 +1253,7 @@ func TestComputeHashChangeTolerationCanary(t *testing.T) {
 	// this should only update observedGeneration and nothing else
 	// NOTE: This test will fail on every k8s library upgrade.
 	// To fix it, update expectedPatch to match the new hash.
-	expectedPatch := `{"status":{"observedGeneration":"7c59bcf464"}}`
+	expectedPatch := `{"status":{"observedGeneration":"69c7555f67"}}`
 	patch := f.getPatchedRollout(patchIndex)
 	assert.Equal(t, expectedPatch, patch)
 }
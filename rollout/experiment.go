@@ -102,21 +102,26 @@ func GetExperimentFromTemplate(r *v1alpha1.Rollout, stableRS, newRS *appsv1.Repl
 
 	for i := range step.Analyses {
 		analysis := step.Analyses[i]
-		args := analysisutil.BuildArgumentsForRolloutAnalysisRun(analysis.Args, stableRS, newRS)
+		args, err := analysisutil.BuildArgumentsForRolloutAnalysisRun(analysis.Args, stableRS, newRS, r)
+		if err != nil {
+			return nil, err
+		}
 		var analysisTemplate v1alpha1.ExperimentAnalysisTemplateRef
 
 		if analysis.ClusterScope {
 			analysisTemplate = v1alpha1.ExperimentAnalysisTemplateRef{
-				Name:         analysis.Name,
-				TemplateName: analysis.TemplateName,
-				ClusterScope: true,
-				Args:         args,
+				Name:                  analysis.Name,
+				TemplateName:          analysis.TemplateName,
+				ClusterScope:          true,
+				Args:                  args,
+				RequiredForCompletion: analysis.RequiredForCompletion,
 			}
 		} else {
 			analysisTemplate = v1alpha1.ExperimentAnalysisTemplateRef{
-				Name:         analysis.Name,
-				TemplateName: analysis.TemplateName,
-				Args:         args,
+				Name:                  analysis.Name,
+				TemplateName:          analysis.TemplateName,
+				Args:                  args,
+				RequiredForCompletion: analysis.RequiredForCompletion,
 			}
 		}
 		experiment.Spec.Analyses = append(experiment.Spec.Analyses, analysisTemplate)
@@ -169,6 +174,14 @@ func (c *Controller) reconcileExperiments(roCtx *canaryContext) error {
 				logCtx.Infof("Cannot create experiment until stableRS exists")
 				return nil
 			}
+			if replicasetutil.InCanaryWarmup(rollout) {
+				logCtx.Infof("Cannot create experiment until the canary warmup completes")
+				return nil
+			}
+			if replicasetutil.WaitingForDependentRollout(rollout) {
+				logCtx.Infof("Cannot create experiment until the rollout '%s' is Healthy", rollout.Spec.Strategy.Canary.DependsOnRollout)
+				return nil
+			}
 
 			newEx, err := GetExperimentFromTemplate(rollout, stableRS, newRS)
 			if err != nil {
@@ -10,6 +10,7 @@ import (
 	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
 	"github.com/argoproj/argo-rollouts/utils/conditions"
 	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/utils/pointer"
@@ -488,6 +489,112 @@ func TestRolloutExperimentFinishedIncrementStep(t *testing.T) {
 	assert.Equal(t, calculatePatch(r2, fmt.Sprintf(expectedPatch, generatedConditions)), patch)
 }
 
+// TestRolloutExperimentGatesOnRequiredAnalysis verifies the experiment step waits on the result of its required
+// analysis, and proceeds or aborts the rollout with it
+func TestRolloutExperimentGatesOnRequiredAnalysis(t *testing.T) {
+	newFixtureWithExperiment := func(t *testing.T, exPhase, analysisPhase v1alpha1.AnalysisPhase) (*fixture, *v1alpha1.Rollout, *v1alpha1.Rollout, *appsv1.ReplicaSet) {
+		f := newFixture(t)
+		steps := []v1alpha1.CanaryStep{{
+			Experiment: &v1alpha1.RolloutExperimentStep{
+				Duration: "10m",
+				Templates: []v1alpha1.RolloutExperimentTemplate{{
+					Name:     "canary-template",
+					SpecRef:  v1alpha1.CanarySpecRef,
+					Replicas: pointer.Int32Ptr(1),
+				}},
+				Analyses: []v1alpha1.RolloutExperimentStepAnalysisTemplateRef{{
+					Name:                  "mann-whitney",
+					TemplateName:          "mann-whitney",
+					RequiredForCompletion: true,
+				}},
+			},
+		}}
+		r1 := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(0), intstr.FromInt(1))
+		r2 := bumpVersion(r1)
+
+		rs1 := newReplicaSetWithStatus(r1, 1, 1)
+		rs2 := newReplicaSetWithStatus(r2, 0, 0)
+		f.kubeobjects = append(f.kubeobjects, rs1, rs2)
+		f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+		rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+
+		r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 1, 0, 1, false)
+		ex, _ := GetExperimentFromTemplate(r2, rs1, rs2)
+		// The duration of the experiment passed
+		availableAt := metav1.NewTime(metav1.Now().Add(-time.Hour))
+		ex.Status.AvailableAt = &availableAt
+		ex.Status.Phase = exPhase
+		ex.Status.Message = fmt.Sprintf("Analysis %s", analysisPhase)
+		ex.Status.AnalysisRuns = []v1alpha1.ExperimentAnalysisRunStatus{{
+			Name:        "mann-whitney",
+			AnalysisRun: ex.Name + "-mann-whitney",
+			Phase:       analysisPhase,
+		}}
+		r2.Status.Canary.CurrentExperiment = ex.Name
+
+		f.rolloutLister = append(f.rolloutLister, r2)
+		f.experimentLister = append(f.experimentLister, ex)
+		f.objects = append(f.objects, r2, ex)
+		return f, r1, r2, rs2
+	}
+
+	t.Run("Wait while the analysis runs", func(t *testing.T) {
+		f, r1, r2, rs2 := newFixtureWithExperiment(t, v1alpha1.AnalysisPhaseRunning, v1alpha1.AnalysisPhaseRunning)
+		defer f.Close()
+		progressingCondition, _ := newProgressingCondition(conditions.ReplicaSetUpdatedReason, rs2, "")
+		conditions.SetRolloutCondition(&r2.Status, progressingCondition)
+		availableCondition, _ := newAvailableCondition(true)
+		conditions.SetRolloutCondition(&r2.Status, availableCondition)
+
+		patchIndex := f.expectPatchRolloutAction(r1)
+		f.run(getKey(r2, t))
+		patch := f.getPatchedRollout(patchIndex)
+		assert.Equal(t, calculatePatch(r2, OnlyObservedGenerationPatch), patch)
+	})
+
+	t.Run("Proceed once the analysis succeeds", func(t *testing.T) {
+		f, r1, r2, rs2 := newFixtureWithExperiment(t, v1alpha1.AnalysisPhaseSuccessful, v1alpha1.AnalysisPhaseSuccessful)
+		defer f.Close()
+
+		patchIndex := f.expectPatchRolloutAction(r1)
+		f.run(getKey(r2, t))
+		patch := f.getPatchedRollout(patchIndex)
+		expectedPatch := `{
+			"status": {
+				"canary": {
+					"currentExperiment":null
+				},
+				"currentStepIndex": 1,
+				"conditions": %s
+			}
+		}`
+		generatedConditions := generateConditionsPatch(true, conditions.ReplicaSetUpdatedReason, rs2, false, "")
+		assert.Equal(t, calculatePatch(r2, fmt.Sprintf(expectedPatch, generatedConditions)), patch)
+	})
+
+	t.Run("Abort once the analysis fails", func(t *testing.T) {
+		f, r1, r2, _ := newFixtureWithExperiment(t, v1alpha1.AnalysisPhaseFailed, v1alpha1.AnalysisPhaseFailed)
+		defer f.Close()
+
+		patchIndex := f.expectPatchRolloutAction(r1)
+		f.run(getKey(r2, t))
+		patch := f.getPatchedRollout(patchIndex)
+		expectedPatch := `{
+			"status": {
+				"abort": true,
+				"abortedAt": "%s",
+				"conditions": %s,
+				"canary": {
+					"currentExperiment": null
+				}
+			}
+		}`
+		now := metav1.Now().UTC().Format(time.RFC3339)
+		generatedConditions := generateConditionsPatch(true, conditions.RolloutAbortedReason, r2, false, "Analysis Failed")
+		assert.Equal(t, calculatePatch(r2, fmt.Sprintf(expectedPatch, now, generatedConditions)), patch)
+	})
+}
+
 func TestRolloutDoNotCreateExperimentWithoutStableRS(t *testing.T) {
 	f := newFixture(t)
 	defer f.Close()
@@ -578,6 +685,39 @@ func TestGetExperimentFromTemplate(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestGetExperimentFromTemplateWithRequiredAnalysis(t *testing.T) {
+	steps := []v1alpha1.CanaryStep{{
+		Experiment: &v1alpha1.RolloutExperimentStep{
+			Templates: []v1alpha1.RolloutExperimentTemplate{{
+				Name:    "canary-template",
+				SpecRef: v1alpha1.CanarySpecRef,
+			}},
+			Analyses: []v1alpha1.RolloutExperimentStepAnalysisTemplateRef{
+				{
+					Name:                  "mann-whitney",
+					TemplateName:          "mann-whitney",
+					RequiredForCompletion: true,
+				},
+				{
+					Name:         "smoke-test",
+					TemplateName: "smoke-test",
+					ClusterScope: true,
+				},
+			},
+		},
+	}}
+	r1 := newCanaryRollout("foo", 1, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(0), intstr.FromInt(1))
+	r2 := bumpVersion(r1)
+	rs1 := newReplicaSetWithStatus(r1, 1, 1)
+	rs2 := newReplicaSetWithStatus(r2, 1, 1)
+
+	ex, err := GetExperimentFromTemplate(r2, rs1, rs2)
+	assert.Nil(t, err)
+	assert.True(t, ex.Spec.Analyses[0].RequiredForCompletion)
+	assert.False(t, ex.Spec.Analyses[1].RequiredForCompletion)
+	assert.True(t, ex.Spec.Analyses[1].ClusterScope)
+}
+
 func TestDeleteExperimentWithNoMatchingRS(t *testing.T) {
 	f := newFixture(t)
 	defer f.Close()
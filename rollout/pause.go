@@ -7,9 +7,17 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	analysisutil "github.com/argoproj/argo-rollouts/utils/analysis"
 	logutil "github.com/argoproj/argo-rollouts/utils/log"
 )
 
+const (
+	// healthyAnalysisCheckTime is the interval at which a rollout is requeued after the duration of a pause step
+	// requiring a healthy analysis. The controller is not notified of the measurements of the background analysis run,
+	// only of the changes of its phase, and would otherwise wait for the resync period to resume the rollout.
+	healthyAnalysisCheckTime = 30 * time.Second
+)
+
 type pauseContext struct {
 	rollout *v1alpha1.Rollout
 	log     *log.Entry
@@ -19,7 +27,10 @@ type pauseContext struct {
 	clearPauseConditions bool
 	addAbort             bool
 	removeAbort          bool
+	retryAbort           bool
 	abortMessage         string
+	rollbackToStep       *int32
+	rollbackMessage      string
 }
 
 func (pCtx *pauseContext) HasAddPause() bool {
@@ -45,6 +56,20 @@ func (pCtx *pauseContext) RemoveAbort() {
 	pCtx.removeAbort = true
 }
 
+// RetryAbort removes the abort of a rollout aborted by a failed analysis so that the analysis runs again, like a
+// retry of the rollout by the user
+func (pCtx *pauseContext) RetryAbort() {
+	pCtx.RemoveAbort()
+	pCtx.retryAbort = true
+}
+
+// RollbackToStep returns the rollout to an earlier step and pauses it, instead of aborting it
+func (pCtx *pauseContext) RollbackToStep(stepIndex int32, message string) {
+	pCtx.rollbackToStep = &stepIndex
+	pCtx.rollbackMessage = message
+	pCtx.AddPauseCondition(v1alpha1.PauseReasonRollbackToStep)
+}
+
 func (pCtx *pauseContext) AddPauseCondition(reason v1alpha1.PauseReason) {
 	pCtx.addPauseReasons = append(pCtx.addPauseReasons, reason)
 }
@@ -65,6 +90,11 @@ func (pCtx *pauseContext) CalculatePauseStatus(newStatus *v1alpha1.RolloutStatus
 	}
 	if !pCtx.removeAbort && pCtx.rollout.Status.Abort {
 		newStatus.Abort = true
+		// Keep the original time of the abort since the abort ramp down of the traffic is calculated from it
+		if pCtx.rollout.Status.AbortedAt != nil {
+			newStatus.AbortedAt = pCtx.rollout.Status.AbortedAt
+			return
+		}
 		now := metav1.Now()
 		newStatus.AbortedAt = &now
 		return
@@ -169,7 +199,9 @@ func (pCtx *pauseContext) CompletedBlueGreenPause() bool {
 	return cond == nil && (rollout.Status.ControllerPause || rollout.Status.BlueGreen.ScaleUpPreviewCheckPoint)
 }
 
-func (pCtx *pauseContext) CompletedPauseStep(pause v1alpha1.RolloutPause) bool {
+// CompletedPauseStep checks if the pause step has completed. A pause step requiring a healthy analysis only completes
+// at the end of its duration if the background analysis run is currently passing.
+func (pCtx *pauseContext) CompletedPauseStep(pause v1alpha1.RolloutPause, backgroundAr *v1alpha1.AnalysisRun) bool {
 	rollout := pCtx.rollout
 	pauseCondition := getPauseCondition(rollout, v1alpha1.PauseReasonCanaryPauseStep)
 
@@ -178,6 +210,10 @@ func (pCtx *pauseContext) CompletedPauseStep(pause v1alpha1.RolloutPause) bool {
 		if pauseCondition != nil {
 			expiredTime := pauseCondition.StartTime.Add(time.Duration(pause.DurationSeconds()) * time.Second)
 			if now.After(expiredTime) {
+				if pause.RequireHealthyAnalysis && !analysisutil.IsPassing(backgroundAr) {
+					pCtx.log.Info("Rollout has waited the duration of the pause step but the background analysis is not passing")
+					return false
+				}
 				pCtx.log.Info("Rollout has waited the duration of the pause step")
 				return true
 			}
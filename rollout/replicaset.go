@@ -6,6 +6,7 @@ import (
 	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	patchtypes "k8s.io/apimachinery/pkg/types"
@@ -21,6 +22,7 @@ var controllerKind = v1alpha1.SchemeGroupVersion.WithKind("Rollout")
 const (
 	addScaleDownAtAnnotationsPatch    = `[{ "op": "add", "path": "/metadata/annotations/%s", "value": "%s"}]`
 	removeScaleDownAtAnnotationsPatch = `[{ "op": "remove", "path": "/metadata/annotations/%s"}]`
+	quarantineLabelPatch              = `{"metadata": {"labels": {"%s": %s}}}`
 )
 
 func (c *Controller) removeScaleDownDelay(roCtx rolloutContext, rs *appsv1.ReplicaSet) error {
@@ -35,12 +37,54 @@ func (c *Controller) addScaleDownDelay(roCtx rolloutContext, rs *appsv1.ReplicaS
 	logCtx := roCtx.Log()
 	logCtx.Infof("Adding '%s' annotation to RS '%s'", v1alpha1.DefaultReplicaSetScaleDownDeadlineAnnotationKey, rs.Name)
 	scaleDownDelaySeconds := time.Duration(defaults.GetScaleDownDelaySecondsOrDefault(roCtx.Rollout()))
+	if blueGreen := roCtx.Rollout().Spec.Strategy.BlueGreen; blueGreen != nil && blueGreen.ScaleDownDrain != nil {
+		// The ReplicaSet is scaled down earlier once its connections drain
+		scaleDownDelaySeconds = time.Duration(defaults.GetScaleDownDrainMaxDelaySecondsOrDefault(roCtx.Rollout()))
+	}
 	now := metav1.Now().Add(scaleDownDelaySeconds * time.Second).UTC().Format(time.RFC3339)
 	patch := fmt.Sprintf(addScaleDownAtAnnotationsPatch, v1alpha1.DefaultReplicaSetScaleDownDeadlineAnnotationKey, now)
 	_, err := c.kubeclientset.AppsV1().ReplicaSets(rs.Namespace).Patch(rs.Name, patchtypes.JSONPatchType, []byte(patch))
 	return err
 }
 
+// setQuarantineLabel adds or removes the quarantine label on the ReplicaSet and its pods. The pods are labeled
+// individually, since the labels of the pod template of a ReplicaSet only apply to the pods it creates afterwards.
+func (c *Controller) setQuarantineLabel(roCtx rolloutContext, rs *appsv1.ReplicaSet, quarantined bool) error {
+	logCtx := roCtx.Log()
+	value := "null"
+	if quarantined {
+		value = `"true"`
+	}
+	patch := []byte(fmt.Sprintf(quarantineLabelPatch, v1alpha1.RolloutQuarantineLabelKey, value))
+	if _, labeled := rs.Labels[v1alpha1.RolloutQuarantineLabelKey]; labeled != quarantined {
+		logCtx.Infof("Setting '%s' label of RS '%s' to %s", v1alpha1.RolloutQuarantineLabelKey, rs.Name, value)
+		_, err := c.kubeclientset.AppsV1().ReplicaSets(rs.Namespace).Patch(rs.Name, patchtypes.MergePatchType, patch)
+		if err != nil {
+			return err
+		}
+	}
+	pods, err := c.kubeclientset.CoreV1().Pods(rs.Namespace).List(metav1.ListOptions{
+		LabelSelector: metav1.FormatLabelSelector(rs.Spec.Selector),
+	})
+	if err != nil {
+		return err
+	}
+	for _, pod := range pods.Items {
+		if _, labeled := pod.Labels[v1alpha1.RolloutQuarantineLabelKey]; labeled == quarantined || pod.DeletionTimestamp != nil {
+			continue
+		}
+		logCtx.Infof("Setting '%s' label of pod '%s' to %s", v1alpha1.RolloutQuarantineLabelKey, pod.Name, value)
+		_, err := c.kubeclientset.CoreV1().Pods(pod.Namespace).Patch(pod.Name, patchtypes.MergePatchType, patch)
+		if err != nil {
+			if k8serrors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
+
 func (c *Controller) getReplicaSetsForRollouts(r *v1alpha1.Rollout) ([]*appsv1.ReplicaSet, error) {
 	// List all ReplicaSets to find those we own but that no longer match our
 	// selector. They will be orphaned by ClaimReplicaSets().
@@ -7,7 +7,9 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	k8sfake "k8s.io/client-go/kubernetes/fake"
 	core "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/record"
@@ -188,6 +190,61 @@ func TestReconcileNewReplicaSet(t *testing.T) {
 	}
 }
 
+func TestSetQuarantineLabel(t *testing.T) {
+	newPod := func(name string, rs *appsv1.ReplicaSet, labels map[string]string) *corev1.Pod {
+		podLabels := map[string]string{}
+		for k, v := range rs.Spec.Selector.MatchLabels {
+			podLabels[k] = v
+		}
+		for k, v := range labels {
+			podLabels[k] = v
+		}
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: rs.Namespace, Labels: podLabels}}
+	}
+	quarantined := map[string]string{v1alpha1.RolloutQuarantineLabelKey: "true"}
+	rollout := newCanaryRollout("foo", 1, nil, nil, nil, intstr.FromInt(1), intstr.FromInt(0))
+	roCtx := newCanaryCtx(rollout, nil, nil, nil, nil)
+
+	t.Run("Add the label", func(t *testing.T) {
+		newRS := newReplicaSetWithStatus(rollout, 2, 2)
+		labeledPod := newPod("labeled", newRS, quarantined)
+		k8sfake := k8sfake.NewSimpleClientset(newRS, newPod("unlabeled", newRS, nil), labeledPod)
+		controller := &Controller{kubeclientset: k8sfake, recorder: &record.FakeRecorder{}}
+
+		err := controller.setQuarantineLabel(roCtx, newRS, true)
+		assert.NoError(t, err)
+		actions := k8sfake.Actions()
+		assert.Len(t, actions, 3)
+		assert.True(t, actions[0].Matches("patch", "replicasets"))
+		assert.Equal(t, `{"metadata": {"labels": {"argo-rollouts.argoproj.io/quarantined": "true"}}}`, string(actions[0].(core.PatchAction).GetPatch()))
+		assert.True(t, actions[1].Matches("list", "pods"))
+		// only the pod which is not labeled yet is patched
+		assert.True(t, actions[2].Matches("patch", "pods"))
+		assert.Equal(t, "unlabeled", actions[2].(core.PatchAction).GetName())
+	})
+
+	t.Run("Remove the label", func(t *testing.T) {
+		newRS := newReplicaSetWithStatus(rollout, 1, 1)
+		rsLabels := map[string]string{v1alpha1.RolloutQuarantineLabelKey: "true"}
+		for k, v := range newRS.Labels {
+			rsLabels[k] = v
+		}
+		newRS.Labels = rsLabels
+		k8sfake := k8sfake.NewSimpleClientset(newRS, newPod("labeled", newRS, quarantined))
+		controller := &Controller{kubeclientset: k8sfake, recorder: &record.FakeRecorder{}}
+
+		err := controller.setQuarantineLabel(roCtx, newRS, false)
+		assert.NoError(t, err)
+		actions := k8sfake.Actions()
+		assert.Len(t, actions, 3)
+		assert.Equal(t, `{"metadata": {"labels": {"argo-rollouts.argoproj.io/quarantined": null}}}`, string(actions[0].(core.PatchAction).GetPatch()))
+		assert.Equal(t, "labeled", actions[2].(core.PatchAction).GetName())
+		pod, err := k8sfake.CoreV1().Pods(newRS.Namespace).Get("labeled", metav1.GetOptions{})
+		assert.NoError(t, err)
+		assert.NotContains(t, pod.Labels, v1alpha1.RolloutQuarantineLabelKey)
+	})
+}
+
 func TestReconcileOldReplicaSet(t *testing.T) {
 	tests := []struct {
 		name                string
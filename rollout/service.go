@@ -2,8 +2,10 @@ package rollout
 
 import (
 	"fmt"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	patchtypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/kubernetes/pkg/controller"
 
@@ -101,6 +103,12 @@ func (c *Controller) reconcileActiveService(roCtx *blueGreenContext, previewSvc,
 		newPodHash = newRS.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
 	}
 
+	// The active service keeps its selector during the preview traffic ramp preceding the promotion
+	activePodHash, hasActiveSelector := activeSvc.Spec.Selector[v1alpha1.DefaultRolloutUniqueLabelKey]
+	if hasActiveSelector && newPodHash != activePodHash && !r.Status.Abort && !c.reconcilePreviewTrafficRamp(roCtx) {
+		newPodHash = activePodHash
+	}
+
 	if r.Status.Abort {
 		currentRevision := int(0)
 		for _, rs := range controller.FilterActiveReplicaSets(roCtx.OlderRSs()) {
@@ -119,6 +127,46 @@ func (c *Controller) reconcileActiveService(roCtx *blueGreenContext, previewSvc,
 	return nil
 }
 
+// reconcilePreviewTrafficRamp progresses the preview traffic ramp of a rollout ready to be promoted, moving to the
+// next step once the duration of the current step elapsed. It returns true once all the steps are completed.
+func (c *Controller) reconcilePreviewTrafficRamp(roCtx *blueGreenContext) bool {
+	r := roCtx.Rollout()
+	steps := r.Spec.Strategy.BlueGreen.PreviewTrafficRamp
+	if len(steps) == 0 {
+		return true
+	}
+	if r.Spec.Paused || getPauseCondition(r, v1alpha1.PauseReasonInconclusiveAnalysis) != nil {
+		roCtx.Log().Info("Holding preview traffic ramp while the rollout is paused")
+		return false
+	}
+	now := metav1.NewTime(nowFn())
+	index := r.Status.BlueGreen.PreviewTrafficRampStepIndex
+	startedAt := r.Status.BlueGreen.PreviewTrafficRampStepStartedAt
+	if index == nil || startedAt == nil {
+		roCtx.Log().Infof("Starting preview traffic ramp with a weight of %d", steps[0].Weight)
+		roCtx.SetPreviewTrafficRampStep(0, now)
+		c.checkEnqueueRolloutDuringWait(r, now, steps[0].DurationSeconds())
+		return false
+	}
+	if *index >= int32(len(steps)) {
+		return true
+	}
+	expiredTime := startedAt.Add(time.Duration(steps[*index].DurationSeconds()) * time.Second)
+	if now.Time.Before(expiredTime) {
+		c.checkEnqueueRolloutDuringWait(r, *startedAt, steps[*index].DurationSeconds())
+		return false
+	}
+	nextIndex := *index + 1
+	roCtx.SetPreviewTrafficRampStep(nextIndex, now)
+	if nextIndex == int32(len(steps)) {
+		roCtx.Log().Info("Completed preview traffic ramp")
+		return true
+	}
+	roCtx.Log().Infof("Moving preview traffic ramp to a weight of %d", steps[nextIndex].Weight)
+	c.checkEnqueueRolloutDuringWait(r, now, steps[nextIndex].DurationSeconds())
+	return false
+}
+
 func (c *Controller) getPreviewAndActiveServices(r *v1alpha1.Rollout) (*corev1.Service, *corev1.Service, error) {
 	var previewSvc *corev1.Service
 	var activeSvc *corev1.Service
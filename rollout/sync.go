@@ -112,6 +112,8 @@ func (c *Controller) getNewReplicaSet(rollout *v1alpha1.Rollout, rsList, oldRSs
 	newRSTemplate := *rollout.Spec.Template.DeepCopy()
 	// Add default anti-affinity rule if antiAffinity bool set and RSTemplate meets requirements
 	newRSTemplate.Spec.Affinity = replicasetutil.GenerateReplicaSetAffinity(*rollout)
+	// Add the readiness gate of the canary to the pods of a canary RS
+	newRSTemplate.Spec.ReadinessGates = replicasetutil.GenerateReplicaSetReadinessGates(*rollout)
 	podTemplateSpecHash := controller.ComputeHash(&rollout.Spec.Template, rollout.Status.CollisionCount)
 	newRSTemplate.Labels = labelsutil.CloneAndAddLabel(rollout.Spec.Template.Labels, v1alpha1.DefaultRolloutUniqueLabelKey, podTemplateSpecHash)
 	// Add podTemplateHash label to selector.
@@ -162,7 +164,9 @@ func (c *Controller) getNewReplicaSet(rollout *v1alpha1.Rollout, rsList, oldRSs
 		// Otherwise, this is a hash collision and we need to increment the collisionCount field in
 		// the status of the Rollout and requeue to try the creation in the next sync.
 		controllerRef := metav1.GetControllerOf(rs)
-		if controllerRef != nil && controllerRef.UID == rollout.UID && replicasetutil.PodTemplateEqualIgnoreHash(&rs.Spec.Template, &rollout.Spec.Template) {
+		live := rs.Spec.Template.DeepCopy()
+		live.Spec.ReadinessGates = replicasetutil.RemoveInjectedReadinessGate(live.Spec.ReadinessGates, *rollout)
+		if controllerRef != nil && controllerRef.UID == rollout.UID && replicasetutil.PodTemplateEqualIgnoreHash(live, &rollout.Spec.Template) {
 			createdRS = rs
 			err = nil
 			break
@@ -509,9 +513,12 @@ func (c *Controller) patchCondition(r *v1alpha1.Rollout, newStatus *v1alpha1.Rol
 	return nil
 }
 
-// isIndefiniteStep returns whether or not the rollout is at an Experiment or Analysis step which should
-// not affect the progressDeadlineSeconds
+// isIndefiniteStep returns whether or not the rollout is at an Experiment or Analysis step, or is baking the new
+// ReplicaSet, which should not affect the progressDeadlineSeconds
 func isIndefiniteStep(r *v1alpha1.Rollout) bool {
+	if r.Spec.Strategy.Canary != nil && r.Status.Canary.BakeStartedAt != nil {
+		return true
+	}
 	currentStep, _ := replicasetutil.GetCurrentCanaryStep(r)
 	return currentStep != nil && (currentStep.Experiment != nil || currentStep.Analysis != nil)
 }
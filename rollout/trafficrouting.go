@@ -3,10 +3,14 @@ package rollout
 import (
 	corev1 "k8s.io/api/core/v1"
 
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
 	"github.com/argoproj/argo-rollouts/rollout/trafficrouting/alb"
 	"github.com/argoproj/argo-rollouts/rollout/trafficrouting/istio"
 	"github.com/argoproj/argo-rollouts/rollout/trafficrouting/nginx"
+	"github.com/argoproj/argo-rollouts/rollout/trafficrouting/plugin"
 	"github.com/argoproj/argo-rollouts/rollout/trafficrouting/smi"
+	"github.com/argoproj/argo-rollouts/rollout/trafficrouting/traefik"
+	"github.com/argoproj/argo-rollouts/utils/defaults"
 
 	replicasetutil "github.com/argoproj/argo-rollouts/utils/replicaset"
 )
@@ -19,8 +23,8 @@ type TrafficRoutingReconciler interface {
 
 // NewTrafficRoutingReconciler identifies return the TrafficRouting Plugin that the rollout wants to modify
 func (c *Controller) NewTrafficRoutingReconciler(roCtx rolloutContext) (TrafficRoutingReconciler, error) {
-	rollout := roCtx.Rollout()
-	if rollout.Spec.Strategy.Canary.TrafficRouting == nil {
+	rollout := trafficRoutingRollout(roCtx.Rollout())
+	if rollout.Spec.Strategy.Canary == nil || rollout.Spec.Strategy.Canary.TrafficRouting == nil {
 		return nil, nil
 	}
 	if rollout.Spec.Strategy.Canary.TrafficRouting.Istio != nil {
@@ -57,9 +61,40 @@ func (c *Controller) NewTrafficRoutingReconciler(roCtx rolloutContext) (TrafficR
 			ApiVersion:     c.defaultTrafficSplitVersion,
 		})
 	}
+	if rollout.Spec.Strategy.Canary.TrafficRouting.Traefik != nil {
+		return traefik.NewReconciler(traefik.ReconcilerConfig{
+			Rollout:    rollout,
+			Client:     c.dynamicclientset,
+			Recorder:   c.recorder,
+			ApiVersion: c.defaultTraefikVersion,
+		}), nil
+	}
+	if rollout.Spec.Strategy.Canary.TrafficRouting.Plugin != nil {
+		return plugin.NewReconciler(plugin.ReconcilerConfig{
+			Rollout: rollout,
+			Routers: c.trafficRouterPlugins,
+		})
+	}
 	return nil, nil
 }
 
+// trafficRoutingRollout returns the rollout given to the TrafficRouting reconcilers. The reconcilers split the traffic
+// between the stable and canary services of a canary strategy, so a blue-green rollout is given a canary strategy
+// splitting the traffic between its active and preview services.
+func trafficRoutingRollout(rollout *v1alpha1.Rollout) *v1alpha1.Rollout {
+	blueGreen := rollout.Spec.Strategy.BlueGreen
+	if blueGreen == nil || blueGreen.TrafficRouting == nil {
+		return rollout
+	}
+	ro := rollout.DeepCopy()
+	ro.Spec.Strategy.Canary = &v1alpha1.CanaryStrategy{
+		StableService:  blueGreen.ActiveService,
+		CanaryService:  blueGreen.PreviewService,
+		TrafficRouting: blueGreen.TrafficRouting.DeepCopy(),
+	}
+	return ro
+}
+
 func (c *Controller) reconcileTrafficRouting(roCtx *canaryContext) error {
 	rollout := roCtx.Rollout()
 	reconciler, err := c.newTrafficRoutingReconciler(roCtx)
@@ -76,14 +111,32 @@ func (c *Controller) reconcileTrafficRouting(roCtx *canaryContext) error {
 
 	_, index := replicasetutil.GetCurrentCanaryStep(rollout)
 	desiredWeight := int32(0)
-	if index != nil {
+	if rollout.Status.Abort {
+		// An aborted rollout shifts the traffic back to the stable version, either instantly or gradually if the
+		// trafficRouting has an AbortRampDownSeconds. GetCurrentSetWeight returns the weight of the ramp down.
+		desiredWeight = replicasetutil.GetCurrentSetWeight(rollout)
+	} else if rollout.Status.Canary.BakeStartedAt != nil {
+		// The new RS is baking with all the traffic before it is marked as stable
+		desiredWeight = defaults.GetMaxTrafficWeightOrDefault(rollout)
+	} else if replicasetutil.WaitingForDependentRollout(rollout) {
+		// The canary receives no traffic until the rollout it depends on is Healthy
+		desiredWeight = 0
+	} else if replicasetutil.WaitingForCanaryReadinessGate(rollout) {
+		// The canary receives no traffic until all the canary pods satisfy the readiness gate
+		desiredWeight = 0
+	} else if index != nil {
 		atDesiredReplicaCount := replicasetutil.AtDesiredReplicaCountsForCanary(rollout, newRS, stableRS, olderRS)
-		if !atDesiredReplicaCount {
+		if rebalancedWeight, lostReplicas := replicasetutil.RebalancedCanaryWeight(rollout, newRS, stableRS); !atDesiredReplicaCount && lostReplicas {
+			// The canary lost pods, whose traffic is not sent to the remaining pods until the canary recovers
+			roCtx.Log().Infof("Rebalancing the canary weight to %d: %d of %d canary pods available", rebalancedWeight, newRS.Status.AvailableReplicas, *newRS.Spec.Replicas)
+			desiredWeight = rebalancedWeight
+		} else if !atDesiredReplicaCount {
 			// Use the previous weight since the new RS is not ready for a new weight
 			for i := *index - 1; i >= 0; i-- {
 				step := rollout.Spec.Strategy.Canary.Steps[i]
 				if step.SetWeight != nil {
-					desiredWeight = *step.SetWeight
+					// An error evaluating the schedule is reported when reconciling the MaxWeightSchedule
+					desiredWeight, _ = replicasetutil.CapWeightByMaxWeightSchedule(rollout, *step.SetWeight, nowFn())
 					break
 				}
 			}
@@ -104,3 +157,33 @@ func (c *Controller) reconcileTrafficRouting(roCtx *canaryContext) error {
 	}
 	return err
 }
+
+// reconcileBlueGreenTrafficRouting sends the weight of the current step of the preview traffic ramp to the preview
+// service. Outside of the ramp, or once the rollout is aborted, all the traffic is sent to the active service.
+func (c *Controller) reconcileBlueGreenTrafficRouting(roCtx *blueGreenContext) error {
+	rollout := roCtx.Rollout()
+	if rollout.Spec.Strategy.BlueGreen.TrafficRouting == nil {
+		return nil
+	}
+	reconciler, err := c.newTrafficRoutingReconciler(roCtx)
+	if err != nil {
+		return err
+	}
+	if reconciler == nil {
+		return nil
+	}
+	roCtx.Log().Infof("Reconciling TrafficRouting with type '%s'", reconciler.Type())
+
+	desiredWeight := int32(0)
+	steps := rollout.Spec.Strategy.BlueGreen.PreviewTrafficRamp
+	index := roCtx.NewStatus().BlueGreen.PreviewTrafficRampStepIndex
+	if !roCtx.PauseContext().IsAborted() && index != nil && *index < int32(len(steps)) {
+		desiredWeight = steps[*index].Weight
+	}
+
+	err = reconciler.Reconcile(desiredWeight)
+	if err != nil {
+		c.recorder.Event(rollout, corev1.EventTypeWarning, "TrafficRoutingError", err.Error())
+	}
+	return err
+}
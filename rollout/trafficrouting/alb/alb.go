@@ -63,17 +63,16 @@ func (r *Reconciler) Reconcile(desiredWeight int32) error {
 	if err != nil {
 		return err
 	}
-	actionService := r.cfg.Rollout.Spec.Strategy.Canary.StableService
-	if r.cfg.Rollout.Spec.Strategy.Canary.TrafficRouting.ALB.RootService != "" {
-		actionService = r.cfg.Rollout.Spec.Strategy.Canary.TrafficRouting.ALB.RootService
-
-	}
-	port := r.cfg.Rollout.Spec.Strategy.Canary.TrafficRouting.ALB.ServicePort
-	if !ingressutil.HasRuleWithService(ingress, actionService) {
-		return fmt.Errorf("ingress does not have service `%s` in rules", actionService)
+	rules := ingressutil.ALBListenerRules(rollout)
+	for _, rule := range rules {
+		if !ingressutil.HasRuleWithService(ingress, rule.RootService) {
+			return fmt.Errorf("ingress does not have service `%s` in rules", rule.RootService)
+		}
 	}
 
-	desired, err := getDesiredAnnotations(ingress, rollout, port, desiredWeight)
+	// The actions of all the listener rules are updated with a single patch, so that a failure leaves all of them
+	// at the previous weight until the patch is retried on the next reconciliation
+	desired, err := getDesiredAnnotations(ingress, rollout, rules, desiredWeight)
 	if err != nil {
 		return err
 	}
@@ -118,6 +117,7 @@ func getForwardActionString(r *v1alpha1.Rollout, port int32, desiredWeight int32
 	action := ingressutil.ALBAction{
 		Type: "forward",
 		ForwardConfig: ingressutil.ALBForwardConfig{
+			TargetGroupStickinessConfig: getStickinessConfig(r, desiredWeight),
 			TargetGroups: []ingressutil.ALBTargetGroup{
 				{
 					ServiceName: stableService,
@@ -135,15 +135,43 @@ func getForwardActionString(r *v1alpha1.Rollout, port int32, desiredWeight int32
 	return string(bytes)
 }
 
-func getDesiredAnnotations(current *extensionsv1beta1.Ingress, r *v1alpha1.Rollout, port int32, desiredWeight int32) (map[string]string, error) {
+// getStickinessConfig returns the target group stickiness of the forward action. While the canary receives traffic, the
+// stickiness is reduced to the CanaryDurationSeconds, or disabled when it is 0, so that the clients follow the weight
+// changes. The DurationSeconds is restored once the canary no longer receives traffic, such as after the promotion.
+func getStickinessConfig(r *v1alpha1.Rollout, desiredWeight int32) *ingressutil.ALBTargetGroupStickinessConfig {
+	config := r.Spec.Strategy.Canary.TrafficRouting.ALB.StickinessConfig
+	if config == nil || !config.Enabled {
+		return nil
+	}
+	duration := config.DurationSeconds
+	if desiredWeight > 0 && config.CanaryDurationSeconds != nil {
+		duration = *config.CanaryDurationSeconds
+	}
+	if duration <= 0 {
+		return &ingressutil.ALBTargetGroupStickinessConfig{Enabled: false}
+	}
+	return &ingressutil.ALBTargetGroupStickinessConfig{
+		Enabled:         true,
+		DurationSeconds: duration,
+	}
+}
+
+func getDesiredAnnotations(current *extensionsv1beta1.Ingress, r *v1alpha1.Rollout, rules []v1alpha1.ALBListenerRule, desiredWeight int32) (map[string]string, error) {
 	desired := current.DeepCopy().Annotations
-	key := ingressutil.ALBActionAnnotationKey(r)
-	desired[key] = getForwardActionString(r, port, desiredWeight)
+	if desired == nil {
+		desired = map[string]string{}
+	}
+	keys := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		key := ingressutil.ALBActionAnnotationKeyForService(r, rule.RootService)
+		desired[key] = getForwardActionString(r, rule.ServicePort, desiredWeight)
+		keys = append(keys, key)
+	}
 	m, err := ingressutil.NewManagedALBActions(desired[ingressutil.ManagedActionsAnnotation])
 	if err != nil {
 		return nil, err
 	}
-	m[r.Name] = key
+	m.SetActionKeys(r.Name, keys)
 	desired[ingressutil.ManagedActionsAnnotation] = m.String()
 	return desired, nil
 }
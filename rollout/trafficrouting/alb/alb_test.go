@@ -18,6 +18,7 @@ import (
 	"k8s.io/client-go/kubernetes/fake"
 	k8stesting "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
 
 	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
 	jsonutil "github.com/argoproj/argo-rollouts/utils/json"
@@ -257,3 +258,181 @@ func TestErrorPatching(t *testing.T) {
 	assert.Error(t, err, "some error occurred")
 	assert.Len(t, client.Actions(), 1)
 }
+
+func addListenerRule(ro *v1alpha1.Rollout, i *extensionsv1beta1.Ingress, rootService string, port int32) {
+	ro.Spec.Strategy.Canary.TrafficRouting.ALB.ListenerRules = append(ro.Spec.Strategy.Canary.TrafficRouting.ALB.ListenerRules, v1alpha1.ALBListenerRule{
+		RootService: rootService,
+		ServicePort: port,
+	})
+	i.Spec.Rules = append(i.Spec.Rules, extensionsv1beta1.IngressRule{
+		IngressRuleValue: extensionsv1beta1.IngressRuleValue{
+			HTTP: &extensionsv1beta1.HTTPIngressRuleValue{
+				Paths: []extensionsv1beta1.HTTPIngressPath{{
+					Backend: extensionsv1beta1.IngressBackend{
+						ServiceName: rootService,
+						ServicePort: intstr.Parse("use-annotation"),
+					},
+				}},
+			},
+		},
+	})
+}
+
+func TestUpdateDesiredWeightWithListenerRules(t *testing.T) {
+	ro := fakeRollout("stable-svc", "canary-svc", "ingress", 443)
+	i := ingress("ingress", "stable-svc", "canary-svc", 443, 5, ro.Name)
+	addListenerRule(ro, i, "http-redirect", 80)
+	client := fake.NewSimpleClientset(i)
+	k8sI := kubeinformers.NewSharedInformerFactory(client, 0)
+	k8sI.Extensions().V1beta1().Ingresses().Informer().GetIndexer().Add(i)
+	r := NewReconciler(ReconcilerConfig{
+		Rollout:        ro,
+		Client:         client,
+		Recorder:       &record.FakeRecorder{},
+		ControllerKind: schema.GroupVersionKind{Group: "foo", Version: "v1", Kind: "Bar"},
+		IngressLister:  k8sI.Extensions().V1beta1().Ingresses().Lister(),
+	})
+	err := r.Reconcile(10)
+	assert.Nil(t, err)
+	actions := client.Actions()
+	assert.Len(t, actions, 1)
+
+	// Both actions are updated by a single patch
+	patch := struct {
+		Metadata struct {
+			Annotations map[string]string `json:"annotations"`
+		} `json:"metadata"`
+	}{}
+	err = json.Unmarshal(actions[0].(k8stesting.PatchAction).GetPatch(), &patch)
+	assert.Nil(t, err)
+	annotations := patch.Metadata.Annotations
+	assert.JSONEq(t, fmt.Sprintf(actionTemplate, "stable-svc", 443, 90, "canary-svc", 443, 10), annotations[albActionAnnotation("stable-svc")])
+	assert.JSONEq(t, fmt.Sprintf(actionTemplate, "stable-svc", 80, 90, "canary-svc", 80, 10), annotations[albActionAnnotation("http-redirect")])
+	managedActions, err := ingressutil.NewManagedALBActions(annotations[ingressutil.ManagedActionsAnnotation])
+	assert.Nil(t, err)
+	assert.Equal(t, []string{albActionAnnotation("stable-svc"), albActionAnnotation("http-redirect")}, managedActions.ActionKeys(ro.Name))
+}
+
+func TestNoChangesWithListenerRules(t *testing.T) {
+	ro := fakeRollout("stable-svc", "canary-svc", "ingress", 443)
+	i := ingress("ingress", "stable-svc", "canary-svc", 443, 10, ro.Name)
+	addListenerRule(ro, i, "http-redirect", 0)
+	i.Annotations[albActionAnnotation("http-redirect")] = getForwardActionString(ro, 443, 10)
+	i.Annotations[ingressutil.ManagedActionsAnnotation] = fmt.Sprintf("%s:%s;%s", ro.Name, albActionAnnotation("stable-svc"), albActionAnnotation("http-redirect"))
+	client := fake.NewSimpleClientset()
+	k8sI := kubeinformers.NewSharedInformerFactory(client, 0)
+	k8sI.Extensions().V1beta1().Ingresses().Informer().GetIndexer().Add(i)
+	r := NewReconciler(ReconcilerConfig{
+		Rollout:        ro,
+		Client:         client,
+		Recorder:       &record.FakeRecorder{},
+		ControllerKind: schema.GroupVersionKind{Group: "foo", Version: "v1", Kind: "Bar"},
+		IngressLister:  k8sI.Extensions().V1beta1().Ingresses().Lister(),
+	})
+	// The listener rule without a port uses the service port of the ALB traffic routing
+	err := r.Reconcile(10)
+	assert.Nil(t, err)
+	assert.Len(t, client.Actions(), 0)
+}
+
+func TestListenerRuleServiceNotFoundInIngress(t *testing.T) {
+	ro := fakeRollout("stable-svc", "canary-svc", "ingress", 443)
+	ro.Spec.Strategy.Canary.TrafficRouting.ALB.ListenerRules = []v1alpha1.ALBListenerRule{{RootService: "http-redirect"}}
+	i := ingress("ingress", "stable-svc", "canary-svc", 443, 5, ro.Name)
+	client := fake.NewSimpleClientset(i)
+	k8sI := kubeinformers.NewSharedInformerFactory(client, 0)
+	k8sI.Extensions().V1beta1().Ingresses().Informer().GetIndexer().Add(i)
+	r := NewReconciler(ReconcilerConfig{
+		Rollout:        ro,
+		Client:         client,
+		Recorder:       &record.FakeRecorder{},
+		ControllerKind: schema.GroupVersionKind{Group: "foo", Version: "v1", Kind: "Bar"},
+		IngressLister:  k8sI.Extensions().V1beta1().Ingresses().Lister(),
+	})
+	// None of the actions are updated when a listener rule is missing
+	err := r.Reconcile(10)
+	assert.EqualError(t, err, "ingress does not have service `http-redirect` in rules")
+	assert.Len(t, client.Actions(), 0)
+}
+
+func TestGetStickinessConfig(t *testing.T) {
+	ro := fakeRollout("stable", "canary", "ingress", 443)
+	assert.Nil(t, getStickinessConfig(ro, 10))
+
+	ro.Spec.Strategy.Canary.TrafficRouting.ALB.StickinessConfig = &v1alpha1.ALBStickinessConfig{
+		Enabled:         false,
+		DurationSeconds: 3600,
+	}
+	assert.Nil(t, getStickinessConfig(ro, 10))
+
+	// the duration is kept during the canary when no canary duration is set
+	ro.Spec.Strategy.Canary.TrafficRouting.ALB.StickinessConfig.Enabled = true
+	assert.Equal(t, &ingressutil.ALBTargetGroupStickinessConfig{Enabled: true, DurationSeconds: 3600}, getStickinessConfig(ro, 0))
+	assert.Equal(t, &ingressutil.ALBTargetGroupStickinessConfig{Enabled: true, DurationSeconds: 3600}, getStickinessConfig(ro, 10))
+
+	// the duration is reduced while the canary receives traffic
+	ro.Spec.Strategy.Canary.TrafficRouting.ALB.StickinessConfig.CanaryDurationSeconds = pointer.Int64Ptr(60)
+	assert.Equal(t, &ingressutil.ALBTargetGroupStickinessConfig{Enabled: true, DurationSeconds: 3600}, getStickinessConfig(ro, 0))
+	assert.Equal(t, &ingressutil.ALBTargetGroupStickinessConfig{Enabled: true, DurationSeconds: 60}, getStickinessConfig(ro, 10))
+
+	// the stickiness is disabled while the canary receives traffic
+	ro.Spec.Strategy.Canary.TrafficRouting.ALB.StickinessConfig.CanaryDurationSeconds = pointer.Int64Ptr(0)
+	assert.Equal(t, &ingressutil.ALBTargetGroupStickinessConfig{Enabled: true, DurationSeconds: 3600}, getStickinessConfig(ro, 0))
+	assert.Equal(t, &ingressutil.ALBTargetGroupStickinessConfig{Enabled: false}, getStickinessConfig(ro, 100))
+}
+
+// TestStickinessLifecycle verifies the stickiness is disabled when the canary starts to receive traffic, and restored
+// when the traffic is shifted back to the stable service on promotion
+func TestStickinessLifecycle(t *testing.T) {
+	ro := fakeRollout("stable-svc", "canary-svc", "ingress", 443)
+	ro.Spec.Strategy.Canary.TrafficRouting.ALB.StickinessConfig = &v1alpha1.ALBStickinessConfig{
+		Enabled:               true,
+		DurationSeconds:       3600,
+		CanaryDurationSeconds: pointer.Int64Ptr(0),
+	}
+	i := ingress("ingress", "stable-svc", "canary-svc", 443, 0, ro.Name)
+	i.Annotations[albActionAnnotation("stable-svc")] = getForwardActionString(ro, 443, 0)
+
+	reconcile := func(desiredWeight int32) *ingressutil.ALBAction {
+		client := fake.NewSimpleClientset(i)
+		k8sI := kubeinformers.NewSharedInformerFactory(client, 0)
+		k8sI.Extensions().V1beta1().Ingresses().Informer().GetIndexer().Add(i)
+		r := NewReconciler(ReconcilerConfig{
+			Rollout:        ro,
+			Client:         client,
+			Recorder:       &record.FakeRecorder{},
+			ControllerKind: schema.GroupVersionKind{Group: "foo", Version: "v1", Kind: "Bar"},
+			IngressLister:  k8sI.Extensions().V1beta1().Ingresses().Lister(),
+		})
+		err := r.Reconcile(desiredWeight)
+		assert.Nil(t, err)
+		actions := client.Actions()
+		if len(actions) == 0 {
+			return nil
+		}
+		updated, err := client.ExtensionsV1beta1().Ingresses(i.Namespace).Get(i.Name, metav1.GetOptions{})
+		assert.Nil(t, err)
+		i = updated
+		var action ingressutil.ALBAction
+		err = json.Unmarshal([]byte(i.Annotations[albActionAnnotation("stable-svc")]), &action)
+		assert.Nil(t, err)
+		return &action
+	}
+
+	// the stickiness is enabled before the canary receives traffic
+	assert.Nil(t, reconcile(0))
+
+	// the stickiness is disabled once the canary receives traffic
+	action := reconcile(20)
+	assert.NotNil(t, action)
+	assert.Equal(t, &ingressutil.ALBTargetGroupStickinessConfig{Enabled: false}, action.ForwardConfig.TargetGroupStickinessConfig)
+	assert.Nil(t, reconcile(20))
+	action = reconcile(50)
+	assert.NotNil(t, action)
+	assert.Equal(t, &ingressutil.ALBTargetGroupStickinessConfig{Enabled: false}, action.ForwardConfig.TargetGroupStickinessConfig)
+
+	// the stickiness is restored on promotion
+	action = reconcile(0)
+	assert.NotNil(t, action)
+	assert.Equal(t, &ingressutil.ALBTargetGroupStickinessConfig{Enabled: true, DurationSeconds: 3600}, action.ForwardConfig.TargetGroupStickinessConfig)
+}
@@ -14,6 +14,7 @@ import (
 	"k8s.io/client-go/tools/record"
 
 	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/argoproj/argo-rollouts/utils/defaults"
 	istioutil "github.com/argoproj/argo-rollouts/utils/istio"
 	logutil "github.com/argoproj/argo-rollouts/utils/log"
 )
@@ -97,6 +98,7 @@ func (patches virtualServicePatches) patchVirtualService(httpRoutes []interface{
 func (r *Reconciler) generateVirtualServicePatches(httpRoutes []HttpRoute, desiredWeight int64) virtualServicePatches {
 	canarySvc := r.rollout.Spec.Strategy.Canary.CanaryService
 	stableSvc := r.rollout.Spec.Strategy.Canary.StableService
+	stableWeight := int64(defaults.GetMaxTrafficWeightOrDefault(r.rollout)) - desiredWeight
 	routes := map[string]bool{}
 	for _, r := range r.rollout.Spec.Strategy.Canary.TrafficRouting.Istio.VirtualService.Routes {
 		routes[r] = true
@@ -120,11 +122,11 @@ func (r *Reconciler) generateVirtualServicePatches(httpRoutes []HttpRoute, desir
 				}
 				patches = append(patches, patch)
 			}
-			if host == stableSvc && weight != 100-desiredWeight {
+			if host == stableSvc && weight != stableWeight {
 				patch := virtualServicePatch{
 					routeIndex:       i,
 					destinationIndex: j,
-					weight:           100 - desiredWeight,
+					weight:           stableWeight,
 				}
 				patches = append(patches, patch)
 			}
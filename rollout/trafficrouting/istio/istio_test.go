@@ -132,6 +132,25 @@ func TestReconcileWeightsBaseCase(t *testing.T) {
 	checkDestination(t, unmodifiedRoute, "canary", 0)
 }
 
+func TestReconcileWeightsMaxTrafficWeight(t *testing.T) {
+	ro := rollout("stable", "canary", "vsvc", []string{"primary"})
+	maxTrafficWeight := int32(1000)
+	ro.Spec.Strategy.Canary.TrafficRouting.MaxTrafficWeight = &maxTrafficWeight
+	r := &Reconciler{
+		rollout: ro,
+	}
+	obj := strToUnstructured(regularVsvc)
+	// a weight of 1 out of 1000 sends 0.1% of the traffic to the canary
+	modifedObj, _, err := r.reconcileVirtualService(obj, 1)
+	assert.Nil(t, err)
+	routes, ok, err := unstructured.NestedSlice(modifedObj.Object, "spec", "http")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	route := routes[0].(map[string]interface{})
+	checkDestination(t, route, "stable", 999)
+	checkDestination(t, route, "canary", 1)
+}
+
 func TestReconcileUpdateVirtualService(t *testing.T) {
 	obj := strToUnstructured(regularVsvc)
 	schema := runtime.NewScheme()
@@ -0,0 +1,87 @@
+package configmap
+
+import (
+	"fmt"
+	"strconv"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/argoproj/argo-rollouts/utils/defaults"
+)
+
+const (
+	// PluginName is the name under which the ConfigMap router is registered
+	PluginName = "configmap"
+	// ConfigMapConfigKey is the key of the plugin configuration holding the name of the ConfigMap
+	ConfigMapConfigKey = "configMap"
+	// TrafficRouterLabelKey is the label opting a ConfigMap in to being written by the router. Its value must be
+	// "true".
+	TrafficRouterLabelKey = "rollouts.argoproj.io/traffic-router"
+
+	// Keys of the ConfigMap set by the router
+	StableServiceKey = "stableService"
+	CanaryServiceKey = "canaryService"
+	StableWeightKey  = "stableWeight"
+	CanaryWeightKey  = "canaryWeight"
+)
+
+// Router is the reference traffic router plugin. It writes the services and the weights of the traffic split of a
+// rollout to a ConfigMap in the namespace of the rollout, for a traffic controller which reads its configuration from
+// the ConfigMap (e.g. a homegrown proxy or an operator reconciling a custom resource).
+type Router struct {
+	client kubernetes.Interface
+}
+
+// NewRouter returns a ConfigMap router
+func NewRouter(client kubernetes.Interface) *Router {
+	return &Router{
+		client: client,
+	}
+}
+
+// SetWeight writes the services and the weights of the rollout to the ConfigMap of the config. The ConfigMap must
+// exist and opt in with the TrafficRouterLabelKey label, and is only updated when one of the values changed. The
+// ConfigMap of the controller configuration is never written.
+func (r *Router) SetWeight(rollout *v1alpha1.Rollout, desiredWeight int32, config map[string]string) error {
+	name := config[ConfigMapConfigKey]
+	if name == "" {
+		return fmt.Errorf("the '%s' key of the configuration of the '%s' traffic router plugin is required", ConfigMapConfigKey, PluginName)
+	}
+	if name == defaults.ControllerConfigMapName {
+		return fmt.Errorf("the '%s' traffic router plugin can not write the ConfigMap '%s' of the controller configuration", PluginName, name)
+	}
+	configMaps := r.client.CoreV1().ConfigMaps(rollout.Namespace)
+	configMap, err := configMaps.Get(name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if configMap.Labels[TrafficRouterLabelKey] != "true" {
+		return fmt.Errorf("the ConfigMap '%s' is not labeled '%s=true' to be written by the '%s' traffic router plugin", name, TrafficRouterLabelKey, PluginName)
+	}
+	values := map[string]string{
+		StableServiceKey: rollout.Spec.Strategy.Canary.StableService,
+		CanaryServiceKey: rollout.Spec.Strategy.Canary.CanaryService,
+		StableWeightKey:  strconv.Itoa(int(100 - desiredWeight)),
+		CanaryWeightKey:  strconv.Itoa(int(desiredWeight)),
+	}
+	modified := false
+	for key, value := range values {
+		if current, ok := configMap.Data[key]; !ok || current != value {
+			modified = true
+		}
+	}
+	if !modified {
+		return nil
+	}
+	newConfigMap := configMap.DeepCopy()
+	if newConfigMap.Data == nil {
+		newConfigMap.Data = map[string]string{}
+	}
+	for key, value := range values {
+		newConfigMap.Data[key] = value
+	}
+	_, err = configMaps.Update(newConfigMap)
+	return err
+}
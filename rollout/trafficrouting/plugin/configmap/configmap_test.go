@@ -0,0 +1,103 @@
+package configmap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/argoproj/argo-rollouts/utils/defaults"
+)
+
+func rollout() *v1alpha1.Rollout {
+	return &v1alpha1.Rollout{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "rollout",
+			Namespace: metav1.NamespaceDefault,
+		},
+		Spec: v1alpha1.RolloutSpec{
+			Strategy: v1alpha1.RolloutStrategy{
+				Canary: &v1alpha1.CanaryStrategy{
+					StableService: "stable",
+					CanaryService: "canary",
+				},
+			},
+		},
+	}
+}
+
+func configMap(data map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "traffic-split",
+			Namespace: metav1.NamespaceDefault,
+			Labels:    map[string]string{TrafficRouterLabelKey: "true"},
+		},
+		Data: data,
+	}
+}
+
+func TestSetWeight(t *testing.T) {
+	client := fake.NewSimpleClientset(configMap(map[string]string{"other": "value"}))
+	r := NewRouter(client)
+	err := r.SetWeight(rollout(), 30, map[string]string{ConfigMapConfigKey: "traffic-split"})
+	assert.NoError(t, err)
+
+	cm, err := client.CoreV1().ConfigMaps(metav1.NamespaceDefault).Get("traffic-split", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"other":          "value",
+		StableServiceKey: "stable",
+		CanaryServiceKey: "canary",
+		StableWeightKey:  "70",
+		CanaryWeightKey:  "30",
+	}, cm.Data)
+}
+
+func TestSetWeightNotModified(t *testing.T) {
+	client := fake.NewSimpleClientset(configMap(map[string]string{
+		StableServiceKey: "stable",
+		CanaryServiceKey: "canary",
+		StableWeightKey:  "70",
+		CanaryWeightKey:  "30",
+	}))
+	r := NewRouter(client)
+	err := r.SetWeight(rollout(), 30, map[string]string{ConfigMapConfigKey: "traffic-split"})
+	assert.NoError(t, err)
+	// Only the ConfigMap is read
+	assert.Len(t, client.Actions(), 1)
+	assert.Equal(t, "get", client.Actions()[0].GetVerb())
+}
+
+func TestSetWeightErrors(t *testing.T) {
+	r := NewRouter(fake.NewSimpleClientset())
+	err := r.SetWeight(rollout(), 30, nil)
+	assert.EqualError(t, err, "the 'configMap' key of the configuration of the 'configmap' traffic router plugin is required")
+
+	err = r.SetWeight(rollout(), 30, map[string]string{ConfigMapConfigKey: "traffic-split"})
+	assert.EqualError(t, err, `configmaps "traffic-split" not found`)
+}
+
+func TestSetWeightNotOptedIn(t *testing.T) {
+	cm := configMap(map[string]string{"other": "value"})
+	delete(cm.Labels, TrafficRouterLabelKey)
+	client := fake.NewSimpleClientset(cm)
+	r := NewRouter(client)
+	err := r.SetWeight(rollout(), 30, map[string]string{ConfigMapConfigKey: "traffic-split"})
+	assert.EqualError(t, err, "the ConfigMap 'traffic-split' is not labeled 'rollouts.argoproj.io/traffic-router=true' to be written by the 'configmap' traffic router plugin")
+	// The ConfigMap is only read
+	assert.Len(t, client.Actions(), 1)
+}
+
+func TestSetWeightControllerConfigMap(t *testing.T) {
+	cm := configMap(nil)
+	cm.Name = defaults.ControllerConfigMapName
+	client := fake.NewSimpleClientset(cm)
+	r := NewRouter(client)
+	err := r.SetWeight(rollout(), 30, map[string]string{ConfigMapConfigKey: defaults.ControllerConfigMapName})
+	assert.EqualError(t, err, "the 'configmap' traffic router plugin can not write the ConfigMap 'argo-rollouts-config' of the controller configuration")
+	assert.Len(t, client.Actions(), 0)
+}
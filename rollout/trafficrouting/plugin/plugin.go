@@ -0,0 +1,64 @@
+package plugin
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	logutil "github.com/argoproj/argo-rollouts/utils/log"
+)
+
+// Type holds this controller type
+const Type = "Plugin"
+
+// Router routes the traffic of the rollouts using a traffic router plugin. A router is registered in the controller
+// under the name of its plugin, and receives the configuration of the plugin in the trafficRouting of the rollout.
+type Router interface {
+	// SetWeight routes the desired weight percentage of the traffic to the canary service of the rollout, and the rest
+	// of the traffic to its stable service
+	SetWeight(rollout *v1alpha1.Rollout, desiredWeight int32, config map[string]string) error
+}
+
+// Routers are the routers of the traffic router plugins keyed by the name of their plugin
+type Routers map[string]Router
+
+// ReconcilerConfig describes static configuration data for the plugin reconciler
+type ReconcilerConfig struct {
+	Rollout *v1alpha1.Rollout
+	Routers Routers
+}
+
+// Reconciler holds required fields to reconcile the traffic routing of a plugin
+type Reconciler struct {
+	cfg    ReconcilerConfig
+	log    *logrus.Entry
+	router Router
+}
+
+// NewReconciler returns a reconciler delegating the traffic routing to the router of the plugin of the rollout. It
+// errors when no router is registered for the plugin.
+func NewReconciler(cfg ReconcilerConfig) (*Reconciler, error) {
+	name := cfg.Rollout.Spec.Strategy.Canary.TrafficRouting.Plugin.Name
+	router, ok := cfg.Routers[name]
+	if !ok {
+		return nil, fmt.Errorf("traffic router plugin '%s' is not registered", name)
+	}
+	return &Reconciler{
+		cfg:    cfg,
+		log:    logutil.WithRollout(cfg.Rollout).WithField("plugin", name),
+		router: router,
+	}, nil
+}
+
+// Type indicates this reconciler is a plugin reconciler
+func (r *Reconciler) Type() string {
+	return Type
+}
+
+// Reconcile sets the desired weight of the canary with the router of the plugin
+func (r *Reconciler) Reconcile(desiredWeight int32) error {
+	plugin := r.cfg.Rollout.Spec.Strategy.Canary.TrafficRouting.Plugin
+	r.log.Infof("Setting the weight of the canary to %d", desiredWeight)
+	return r.router.SetWeight(r.cfg.Rollout, desiredWeight, plugin.Config)
+}
@@ -0,0 +1,85 @@
+package plugin
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+)
+
+type fakeRouter struct {
+	rollout       *v1alpha1.Rollout
+	desiredWeight int32
+	config        map[string]string
+	err           error
+}
+
+func (f *fakeRouter) SetWeight(rollout *v1alpha1.Rollout, desiredWeight int32, config map[string]string) error {
+	f.rollout = rollout
+	f.desiredWeight = desiredWeight
+	f.config = config
+	return f.err
+}
+
+func rollout(name string, config map[string]string) *v1alpha1.Rollout {
+	return &v1alpha1.Rollout{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "rollout",
+			Namespace: metav1.NamespaceDefault,
+		},
+		Spec: v1alpha1.RolloutSpec{
+			Strategy: v1alpha1.RolloutStrategy{
+				Canary: &v1alpha1.CanaryStrategy{
+					StableService: "stable",
+					CanaryService: "canary",
+					TrafficRouting: &v1alpha1.RolloutTrafficRouting{
+						Plugin: &v1alpha1.PluginTrafficRouting{
+							Name:   name,
+							Config: config,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestType(t *testing.T) {
+	r, err := NewReconciler(ReconcilerConfig{
+		Rollout: rollout("custom", nil),
+		Routers: Routers{"custom": &fakeRouter{}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, Type, r.Type())
+}
+
+func TestReconcile(t *testing.T) {
+	router := &fakeRouter{}
+	ro := rollout("custom", map[string]string{"route": "checkout"})
+	r, err := NewReconciler(ReconcilerConfig{
+		Rollout: ro,
+		Routers: Routers{"custom": router, "other": &fakeRouter{err: errors.New("wrong router")}},
+	})
+	assert.NoError(t, err)
+
+	err = r.Reconcile(20)
+	assert.NoError(t, err)
+	assert.Equal(t, ro, router.rollout)
+	assert.Equal(t, int32(20), router.desiredWeight)
+	assert.Equal(t, map[string]string{"route": "checkout"}, router.config)
+
+	router.err = errors.New("intentional error")
+	err = r.Reconcile(40)
+	assert.EqualError(t, err, "intentional error")
+}
+
+func TestNewReconcilerNotRegistered(t *testing.T) {
+	_, err := NewReconciler(ReconcilerConfig{
+		Rollout: rollout("custom", nil),
+		Routers: Routers{"other": &fakeRouter{}},
+	})
+	assert.EqualError(t, err, "traffic router plugin 'custom' is not registered")
+}
@@ -17,6 +17,7 @@ import (
 	"k8s.io/client-go/tools/record"
 
 	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/argoproj/argo-rollouts/utils/defaults"
 	"github.com/argoproj/argo-rollouts/utils/diff"
 	logutil "github.com/argoproj/argo-rollouts/utils/log"
 )
@@ -266,7 +267,7 @@ func trafficSplitV1Alpha1(ro *v1alpha1.Rollout, objectMeta metav1.ObjectMeta, ro
 				},
 				{
 					Service: ro.Spec.Strategy.Canary.StableService,
-					Weight:  resource.NewQuantity(int64(100-desiredWeight), resource.DecimalExponent),
+					Weight:  resource.NewQuantity(int64(defaults.GetMaxTrafficWeightOrDefault(ro)-desiredWeight), resource.DecimalExponent),
 				},
 			},
 		},
@@ -285,7 +286,7 @@ func trafficSplitV1Alpha2(ro *v1alpha1.Rollout, objectMeta metav1.ObjectMeta, ro
 				},
 				{
 					Service: ro.Spec.Strategy.Canary.StableService,
-					Weight:  int(100 - desiredWeight),
+					Weight:  int(defaults.GetMaxTrafficWeightOrDefault(ro) - desiredWeight),
 				},
 			},
 		},
@@ -304,7 +305,7 @@ func trafficSplitV1Alpha3(ro *v1alpha1.Rollout, objectMeta metav1.ObjectMeta, ro
 				},
 				{
 					Service: ro.Spec.Strategy.Canary.StableService,
-					Weight:  int(100 - desiredWeight),
+					Weight:  int(defaults.GetMaxTrafficWeightOrDefault(ro) - desiredWeight),
 				},
 			},
 		},
@@ -20,6 +20,7 @@ import (
 	core "k8s.io/client-go/testing"
 	k8stesting "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
 
 	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
 )
@@ -171,6 +172,30 @@ func TestReconcileCreateNewTrafficSplit(t *testing.T) {
 	})
 }
 
+func TestReconcileCreateNewTrafficSplitMaxTrafficWeight(t *testing.T) {
+	ro := fakeRollout("stable-service", "canary-service", "root-service", "traffic-split-name")
+	ro.Spec.Strategy.Canary.TrafficRouting.MaxTrafficWeight = pointer.Int32Ptr(1000)
+	client := fake.NewSimpleClientset()
+	r, err := NewReconciler(ReconcilerConfig{
+		Rollout:        ro,
+		Client:         client,
+		Recorder:       &record.FakeRecorder{},
+		ControllerKind: schema.GroupVersionKind{},
+		ApiVersion:     "v1alpha3",
+	})
+	assert.Nil(t, err)
+
+	err = r.Reconcile(1)
+	assert.Nil(t, err)
+	actions := client.Actions()
+	assert.Len(t, actions, 2)
+	ts3 := actions[1].(core.CreateAction).GetObject().(*smiv1alpha3.TrafficSplit)
+	assert.Equal(t, "canary-service", ts3.Spec.Backends[0].Service)
+	assert.Equal(t, 1, ts3.Spec.Backends[0].Weight)
+	assert.Equal(t, "stable-service", ts3.Spec.Backends[1].Service)
+	assert.Equal(t, 999, ts3.Spec.Backends[1].Weight)
+}
+
 func TestReconcilePatchExistingTrafficSplit(t *testing.T) {
 	ro := fakeRollout("stable-service", "canary-service", "root-service", "traffic-split-name")
 	objectMeta := objectMeta("traffic-split-name", ro, schema.GroupVersionKind{})
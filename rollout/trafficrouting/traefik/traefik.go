@@ -0,0 +1,178 @@
+package traefik
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/argoproj/argo-rollouts/utils/defaults"
+	logutil "github.com/argoproj/argo-rollouts/utils/log"
+)
+
+const (
+	// Type holds this controller type
+	Type = "Traefik"
+	// serviceKind is the kind of the services of a route which are Kubernetes services
+	serviceKind = "Service"
+)
+
+// GetIngressRouteGVR returns the resource of the Traefik IngressRoutes of the api version
+func GetIngressRouteGVR(apiVersion string) schema.GroupVersionResource {
+	return schema.GroupVersionResource{
+		Group:    "traefik.containo.us",
+		Version:  apiVersion,
+		Resource: "ingressroutes",
+	}
+}
+
+// ReconcilerConfig describes static configuration data for the Traefik reconciler
+type ReconcilerConfig struct {
+	Rollout    *v1alpha1.Rollout
+	Client     dynamic.Interface
+	Recorder   record.EventRecorder
+	ApiVersion string
+}
+
+// Reconciler holds required fields to reconcile a Traefik IngressRoute
+type Reconciler struct {
+	cfg ReconcilerConfig
+	log *logrus.Entry
+}
+
+// NewReconciler returns a reconciler struct that brings the IngressRoute into the desired state
+func NewReconciler(cfg ReconcilerConfig) *Reconciler {
+	return &Reconciler{
+		cfg: cfg,
+		log: logutil.WithRollout(cfg.Rollout),
+	}
+}
+
+// Type indicates this reconciler is a Traefik reconciler
+func (r *Reconciler) Type() string {
+	return Type
+}
+
+// Reconcile sets the weights of the stable and canary services of the routes of the IngressRoute
+func (r *Reconciler) Reconcile(desiredWeight int32) error {
+	ingressRouteName := r.cfg.Rollout.Spec.Strategy.Canary.TrafficRouting.Traefik.IngressRoute
+	client := r.cfg.Client.Resource(GetIngressRouteGVR(r.cfg.ApiVersion)).Namespace(r.cfg.Rollout.Namespace)
+	ingressRoute, err := client.Get(ingressRouteName, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			msg := fmt.Sprintf("IngressRoute `%s` not found", ingressRouteName)
+			r.cfg.Recorder.Event(r.cfg.Rollout, corev1.EventTypeWarning, "IngressRouteNotFound", msg)
+		}
+		return err
+	}
+	modifiedIngressRoute, modified, err := r.reconcileIngressRoute(ingressRoute, desiredWeight)
+	if err != nil {
+		return err
+	}
+	if !modified {
+		return nil
+	}
+	msg := fmt.Sprintf("Updating IngressRoute `%s` to desiredWeight '%d'", ingressRouteName, desiredWeight)
+	r.log.Info(msg)
+	r.cfg.Recorder.Event(r.cfg.Rollout, corev1.EventTypeNormal, "UpdatingIngressRoute", msg)
+	_, err = client.Update(modifiedIngressRoute, metav1.UpdateOptions{})
+	return err
+}
+
+// reconcileIngressRoute returns a copy of the IngressRoute with the weights of the stable and canary services of its
+// routes set to the desired weight, and whether a weight was modified. Every route with both the stable and the canary
+// service is modified, and the IngressRoute must have at least one.
+func (r *Reconciler) reconcileIngressRoute(obj *unstructured.Unstructured, desiredWeight int32) (*unstructured.Unstructured, bool, error) {
+	newObj := obj.DeepCopy()
+	routes, found, err := unstructured.NestedSlice(newObj.Object, "spec", "routes")
+	if err != nil {
+		return nil, false, err
+	}
+	if !found {
+		return nil, false, fmt.Errorf(".spec.routes is not defined")
+	}
+	stableSvc := r.cfg.Rollout.Spec.Strategy.Canary.StableService
+	canarySvc := r.cfg.Rollout.Spec.Strategy.Canary.CanaryService
+	weights := map[string]int64{
+		stableSvc: int64(defaults.GetMaxTrafficWeightOrDefault(r.cfg.Rollout) - desiredWeight),
+		canarySvc: int64(desiredWeight),
+	}
+
+	modified := false
+	splitRoutes := 0
+	for i := range routes {
+		route, ok := routes[i].(map[string]interface{})
+		if !ok {
+			return nil, false, fmt.Errorf("Invalid casting: field 'routes[]' is not of type 'map[string]interface'")
+		}
+		services, ok := route["services"].([]interface{})
+		if !ok {
+			continue
+		}
+		if !hasServices(services, stableSvc, canarySvc) {
+			continue
+		}
+		splitRoutes++
+		for j := range services {
+			service := services[j].(map[string]interface{})
+			name, _ := service["name"].(string)
+			weight, ok := weights[name]
+			if !ok || !isKubernetesService(service) {
+				continue
+			}
+			if currentWeight, ok := serviceWeight(service); ok && currentWeight == weight {
+				continue
+			}
+			service["weight"] = weight
+			modified = true
+		}
+	}
+	if splitRoutes == 0 {
+		return nil, false, fmt.Errorf("IngressRoute '%s' has no route with both the stable service '%s' and the canary service '%s'", obj.GetName(), stableSvc, canarySvc)
+	}
+	err = unstructured.SetNestedSlice(newObj.Object, routes, "spec", "routes")
+	return newObj, modified, err
+}
+
+// hasServices returns whether the services of a route include both the stable and the canary service
+func hasServices(services []interface{}, stableSvc, canarySvc string) bool {
+	hasStableSvc := false
+	hasCanarySvc := false
+	for _, s := range services {
+		service, ok := s.(map[string]interface{})
+		if !ok || !isKubernetesService(service) {
+			continue
+		}
+		switch service["name"] {
+		case stableSvc:
+			hasStableSvc = true
+		case canarySvc:
+			hasCanarySvc = true
+		}
+	}
+	return hasStableSvc && hasCanarySvc
+}
+
+// isKubernetesService returns whether the service of a route is a Kubernetes service rather than a TraefikService
+func isKubernetesService(service map[string]interface{}) bool {
+	kind, _ := service["kind"].(string)
+	return kind == "" || kind == serviceKind
+}
+
+// serviceWeight returns the weight of the service of a route, if it has one
+func serviceWeight(service map[string]interface{}) (int64, bool) {
+	switch weight := service["weight"].(type) {
+	case int64:
+		return weight, true
+	case float64:
+		return int64(weight), true
+	}
+	return 0, false
+}
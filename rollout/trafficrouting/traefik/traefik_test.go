@@ -0,0 +1,202 @@
+package traefik
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/stretchr/testify/assert"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+)
+
+const ingressRoute = `apiVersion: traefik.containo.us/v1alpha1
+kind: IngressRoute
+metadata:
+  name: ingress-route
+  namespace: default
+spec:
+  entryPoints:
+  - web
+  routes:
+  - match: Host(` + "`guestbook.argoproj.io`" + `)
+    kind: Rule
+    services:
+    - name: stable
+      port: 80
+      weight: 100
+    - name: canary
+      port: 80
+      weight: 0
+  - match: Host(` + "`guestbook.argoproj.io`" + `) && PathPrefix(` + "`/static`" + `)
+    kind: Rule
+    services:
+    - name: static
+      port: 80`
+
+const ingressRouteWithoutSplit = `apiVersion: traefik.containo.us/v1alpha1
+kind: IngressRoute
+metadata:
+  name: ingress-route
+  namespace: default
+spec:
+  routes:
+  - match: Host(` + "`guestbook.argoproj.io`" + `)
+    kind: Rule
+    services:
+    - name: stable
+      port: 80
+    - name: canary
+      kind: TraefikService`
+
+func strToUnstructured(yamlStr string) *unstructured.Unstructured {
+	obj := make(map[string]interface{})
+	yamlStr = strings.ReplaceAll(yamlStr, "\t", "    ")
+	err := yaml.Unmarshal([]byte(yamlStr), &obj)
+	if err != nil {
+		panic(err)
+	}
+	return &unstructured.Unstructured{Object: obj}
+}
+
+func rollout(stableSvc, canarySvc, ingressRoute string) *v1alpha1.Rollout {
+	return &v1alpha1.Rollout{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "rollout",
+			Namespace: metav1.NamespaceDefault,
+		},
+		Spec: v1alpha1.RolloutSpec{
+			Strategy: v1alpha1.RolloutStrategy{
+				Canary: &v1alpha1.CanaryStrategy{
+					StableService: stableSvc,
+					CanaryService: canarySvc,
+					TrafficRouting: &v1alpha1.RolloutTrafficRouting{
+						Traefik: &v1alpha1.TraefikTrafficRouting{
+							IngressRoute: ingressRoute,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func newReconciler(ro *v1alpha1.Rollout, objs ...runtime.Object) (*Reconciler, *fake.FakeDynamicClient) {
+	client := fake.NewSimpleDynamicClient(runtime.NewScheme(), objs...)
+	r := NewReconciler(ReconcilerConfig{
+		Rollout:    ro,
+		Client:     client,
+		Recorder:   &record.FakeRecorder{},
+		ApiVersion: "v1alpha1",
+	})
+	return r, client
+}
+
+func getIngressRoute(t *testing.T, client *fake.FakeDynamicClient) *unstructured.Unstructured {
+	obj, err := client.Resource(GetIngressRouteGVR("v1alpha1")).Namespace(metav1.NamespaceDefault).Get("ingress-route", metav1.GetOptions{})
+	assert.NoError(t, err)
+	return obj
+}
+
+func checkWeight(t *testing.T, obj *unstructured.Unstructured, routeIndex int, svc string, expectWeight int64) {
+	routes, _, err := unstructured.NestedSlice(obj.Object, "spec", "routes")
+	assert.NoError(t, err)
+	services := routes[routeIndex].(map[string]interface{})["services"].([]interface{})
+	for _, elem := range services {
+		service := elem.(map[string]interface{})
+		if service["name"] == svc {
+			weight, ok := serviceWeight(service)
+			assert.True(t, ok)
+			assert.Equal(t, expectWeight, weight)
+			return
+		}
+	}
+	assert.Failf(t, "service not found", "Service '%s' not found within services of route %d", svc, routeIndex)
+}
+
+func TestType(t *testing.T) {
+	r, _ := newReconciler(rollout("stable", "canary", "ingress-route"))
+	assert.Equal(t, Type, r.Type())
+}
+
+func TestReconcileSetWeight(t *testing.T) {
+	r, client := newReconciler(rollout("stable", "canary", "ingress-route"), strToUnstructured(ingressRoute))
+
+	err := r.Reconcile(10)
+	assert.NoError(t, err)
+	actions := client.Actions()
+	assert.Len(t, actions, 2)
+	assert.Equal(t, "get", actions[0].GetVerb())
+	assert.Equal(t, "update", actions[1].GetVerb())
+
+	obj := getIngressRoute(t, client)
+	checkWeight(t, obj, 0, "stable", 90)
+	checkWeight(t, obj, 0, "canary", 10)
+	staticRoute, _, _ := unstructured.NestedSlice(obj.Object, "spec", "routes")
+	staticService := staticRoute[1].(map[string]interface{})["services"].([]interface{})[0].(map[string]interface{})
+	assert.NotContains(t, staticService, "weight")
+}
+
+func TestReconcileResetWeight(t *testing.T) {
+	r, client := newReconciler(rollout("stable", "canary", "ingress-route"), strToUnstructured(ingressRoute))
+	assert.NoError(t, r.Reconcile(40))
+
+	// A promoted or aborted rollout sends all the traffic back to the stable service
+	assert.NoError(t, r.Reconcile(0))
+	obj := getIngressRoute(t, client)
+	checkWeight(t, obj, 0, "stable", 100)
+	checkWeight(t, obj, 0, "canary", 0)
+}
+
+func TestReconcileMaxTrafficWeight(t *testing.T) {
+	ro := rollout("stable", "canary", "ingress-route")
+	ro.Spec.Strategy.Canary.TrafficRouting.MaxTrafficWeight = pointer.Int32Ptr(1000)
+	r, client := newReconciler(ro, strToUnstructured(ingressRoute))
+
+	// The weights are permille of the traffic
+	assert.NoError(t, r.Reconcile(5))
+	obj := getIngressRoute(t, client)
+	checkWeight(t, obj, 0, "stable", 995)
+	checkWeight(t, obj, 0, "canary", 5)
+}
+
+func TestReconcileWeightAlreadySet(t *testing.T) {
+	r, client := newReconciler(rollout("stable", "canary", "ingress-route"), strToUnstructured(ingressRoute))
+
+	err := r.Reconcile(0)
+	assert.NoError(t, err)
+	actions := client.Actions()
+	assert.Len(t, actions, 1)
+	assert.Equal(t, "get", actions[0].GetVerb())
+}
+
+func TestReconcileNoRouteWithStableAndCanaryServices(t *testing.T) {
+	r, client := newReconciler(rollout("stable", "canary", "ingress-route"), strToUnstructured(ingressRouteWithoutSplit))
+
+	err := r.Reconcile(10)
+	assert.EqualError(t, err, "IngressRoute 'ingress-route' has no route with both the stable service 'stable' and the canary service 'canary'")
+	assert.Len(t, client.Actions(), 1)
+}
+
+func TestReconcileIngressRouteNotFound(t *testing.T) {
+	r, _ := newReconciler(rollout("stable", "canary", "ingress-route"))
+
+	err := r.Reconcile(10)
+	assert.True(t, k8serrors.IsNotFound(err))
+}
+
+func TestReconcileRoutesNotDefined(t *testing.T) {
+	obj := strToUnstructured(ingressRoute)
+	unstructured.RemoveNestedField(obj.Object, "spec", "routes")
+	r, _ := newReconciler(rollout("stable", "canary", "ingress-route"), obj)
+
+	err := r.Reconcile(10)
+	assert.EqualError(t, err, ".spec.routes is not defined")
+}
@@ -1,17 +1,26 @@
 package rollout
 
 import (
+	"encoding/json"
 	"fmt"
 	"testing"
+	"time"
 
 	"k8s.io/client-go/dynamic/dynamiclister"
 
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic/dynamicinformer"
 	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
 
+	smifake "github.com/servicemeshinterface/smi-sdk-go/pkg/gen/client/split/clientset/versioned/fake"
 	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/utils/pointer"
 
@@ -19,8 +28,12 @@ import (
 	"github.com/argoproj/argo-rollouts/rollout/trafficrouting/alb"
 	"github.com/argoproj/argo-rollouts/rollout/trafficrouting/istio"
 	"github.com/argoproj/argo-rollouts/rollout/trafficrouting/nginx"
+	"github.com/argoproj/argo-rollouts/rollout/trafficrouting/plugin"
+	"github.com/argoproj/argo-rollouts/rollout/trafficrouting/plugin/configmap"
 	"github.com/argoproj/argo-rollouts/rollout/trafficrouting/smi"
+	"github.com/argoproj/argo-rollouts/rollout/trafficrouting/traefik"
 	"github.com/argoproj/argo-rollouts/utils/conditions"
+	istioutil "github.com/argoproj/argo-rollouts/utils/istio"
 	logutil "github.com/argoproj/argo-rollouts/utils/log"
 )
 
@@ -123,6 +136,345 @@ func TestRolloutUseDesiredWeight(t *testing.T) {
 	assert.Equal(t, int32(10), f.fakeTrafficRouting.controllerSetDesiredWeight)
 }
 
+// newReadinessGateRollout returns a rollout at its first setWeight step with a readiness gate, along with its stable
+// and canary ReplicaSets, the canary being at its desired replica count
+func newReadinessGateRollout(f *fixture) (*v1alpha1.Rollout, *appsv1.ReplicaSet) {
+	steps := []v1alpha1.CanaryStep{
+		{
+			SetWeight: pointer.Int32Ptr(10),
+		},
+		{
+			Pause: &v1alpha1.RolloutPause{},
+		},
+	}
+	r1 := newCanaryRollout("foo", 10, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(1), intstr.FromInt(0))
+	r2 := bumpVersion(r1)
+	r2.Spec.Strategy.Canary.TrafficRouting = &v1alpha1.RolloutTrafficRouting{}
+	r2.Spec.Strategy.Canary.CanaryService = "canary"
+	r2.Spec.Strategy.Canary.StableService = "stable"
+	r2.Spec.Strategy.Canary.ReadinessGate = &v1alpha1.CanaryReadinessGate{ConditionType: "MeshConfigured"}
+
+	rs1 := newReplicaSetWithStatus(r1, 10, 10)
+	rs2 := newReplicaSetWithStatus(r2, 1, 1)
+
+	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+	rs2PodHash := rs2.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+	canarySelector := map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: rs2PodHash}
+	stableSelector := map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: rs1PodHash}
+	canarySvc := newService("canary", 80, canarySelector, r2)
+	stableSvc := newService("stable", 80, stableSelector, r2)
+
+	f.kubeobjects = append(f.kubeobjects, rs1, rs2, canarySvc, stableSvc)
+	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+
+	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 10, 1, 10, false)
+	return r2, rs2
+}
+
+// newCanaryPod returns a pod of the ReplicaSet with the MeshConfigured condition set to the status
+func newCanaryPod(rs *appsv1.ReplicaSet, name string, status corev1.ConditionStatus) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       rs.Namespace,
+			Labels:          rs.Spec.Selector.MatchLabels,
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(rs, appsv1.SchemeGroupVersion.WithKind("ReplicaSet"))},
+		},
+		Status: corev1.PodStatus{
+			Conditions: []corev1.PodCondition{
+				{Type: corev1.PodReady, Status: corev1.ConditionTrue},
+				{Type: "MeshConfigured", Status: status},
+			},
+		},
+	}
+}
+
+func TestRolloutReadinessGateHoldsTraffic(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	r2, rs2 := newReadinessGateRollout(f)
+	pod := newCanaryPod(rs2, "canary-pod", corev1.ConditionFalse)
+	f.kubeobjects = append(f.kubeobjects, pod)
+	f.podLister = append(f.podLister, pod)
+	f.rolloutLister = append(f.rolloutLister, r2)
+	f.objects = append(f.objects, r2)
+
+	patchIndex := f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+
+	assert.Equal(t, int32(0), f.fakeTrafficRouting.controllerSetDesiredWeight)
+	patch := f.getPatchedRollout(patchIndex)
+	var patchObj map[string]interface{}
+	err := json.Unmarshal([]byte(patch), &patchObj)
+	assert.NoError(t, err)
+	status := patchObj["status"].(map[string]interface{})
+	_, ok := status["currentStepIndex"]
+	assert.False(t, ok)
+	_, ok = status["canary"]
+	assert.False(t, ok)
+}
+
+func TestRolloutReadinessGateSatisfied(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	r2, rs2 := newReadinessGateRollout(f)
+	pod := newCanaryPod(rs2, "canary-pod", corev1.ConditionTrue)
+	f.kubeobjects = append(f.kubeobjects, pod)
+	f.podLister = append(f.podLister, pod)
+	f.rolloutLister = append(f.rolloutLister, r2)
+	f.objects = append(f.objects, r2)
+
+	patchIndex := f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+
+	// The traffic is shifted to the canary once the status records the readiness gate is satisfied
+	assert.Equal(t, int32(0), f.fakeTrafficRouting.controllerSetDesiredWeight)
+	patch := f.getPatchedRollout(patchIndex)
+	var patchObj map[string]interface{}
+	err := json.Unmarshal([]byte(patch), &patchObj)
+	assert.NoError(t, err)
+	status := patchObj["status"].(map[string]interface{})
+	_, ok := status["currentStepIndex"]
+	assert.False(t, ok)
+	canaryStatus := status["canary"].(map[string]interface{})
+	assert.Equal(t, true, canaryStatus["readinessGateSatisfied"])
+}
+
+func TestRolloutReadinessGateWaitsForAllCanaryPods(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	r2, rs2 := newReadinessGateRollout(f)
+	pod1 := newCanaryPod(rs2, "canary-pod-1", corev1.ConditionTrue)
+	pod2 := newCanaryPod(rs2, "canary-pod-2", corev1.ConditionUnknown)
+	f.kubeobjects = append(f.kubeobjects, pod1, pod2)
+	f.podLister = append(f.podLister, pod1, pod2)
+	f.rolloutLister = append(f.rolloutLister, r2)
+	f.objects = append(f.objects, r2)
+
+	patchIndex := f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+
+	assert.Equal(t, int32(0), f.fakeTrafficRouting.controllerSetDesiredWeight)
+	patch := f.getPatchedRollout(patchIndex)
+	var patchObj map[string]interface{}
+	err := json.Unmarshal([]byte(patch), &patchObj)
+	assert.NoError(t, err)
+	status := patchObj["status"].(map[string]interface{})
+	_, ok := status["canary"]
+	assert.False(t, ok)
+}
+
+func TestRolloutReadinessGateInjectedInCanaryPods(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	steps := []v1alpha1.CanaryStep{{
+		SetWeight: pointer.Int32Ptr(10),
+	}}
+	r1 := newCanaryRollout("foo", 10, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(1), intstr.FromInt(0))
+	r1.Spec.Strategy.Canary.TrafficRouting = &v1alpha1.RolloutTrafficRouting{}
+	r1.Spec.Strategy.Canary.CanaryService = "canary"
+	r1.Spec.Strategy.Canary.StableService = "stable"
+	r1.Spec.Strategy.Canary.ReadinessGate = &v1alpha1.CanaryReadinessGate{ConditionType: "MeshConfigured"}
+	rs1 := newReplicaSetWithStatus(r1, 10, 10)
+	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+	r1 = updateCanaryRolloutStatus(r1, rs1PodHash, 10, 10, 10, false)
+	r2 := bumpVersion(r1)
+	rs2 := newReplicaSetWithStatus(r2, 1, 0)
+
+	stableSelector := map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: rs1PodHash}
+	canarySvc := newService("canary", 80, stableSelector, r2)
+	stableSvc := newService("stable", 80, stableSelector, r2)
+	f.kubeobjects = append(f.kubeobjects, rs1, canarySvc, stableSvc)
+	f.replicaSetLister = append(f.replicaSetLister, rs1)
+	f.serviceLister = append(f.serviceLister, canarySvc, stableSvc)
+	f.rolloutLister = append(f.rolloutLister, r2)
+	f.objects = append(f.objects, r2)
+
+	createdRSIndex := f.expectCreateReplicaSetAction(rs2)
+	f.expectPatchServiceAction(canarySvc, rs2.Labels[v1alpha1.DefaultRolloutUniqueLabelKey])
+	f.expectUpdateRolloutAction(r2)
+	f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+
+	// The readiness gate is only injected in the pods of the canary RS, and the rollout template is not modified
+	createdRS := f.getCreatedReplicaSet(createdRSIndex)
+	assert.Equal(t, []corev1.PodReadinessGate{{ConditionType: "MeshConfigured"}}, createdRS.Spec.Template.Spec.ReadinessGates)
+	assert.Empty(t, rs1.Spec.Template.Spec.ReadinessGates)
+	assert.Empty(t, r2.Spec.Template.Spec.ReadinessGates)
+}
+
+func TestRolloutReadinessGateReleasesTraffic(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	r2, _ := newReadinessGateRollout(f)
+	r2.Status.Canary.ReadinessGateSatisfied = true
+	f.rolloutLister = append(f.rolloutLister, r2)
+	f.objects = append(f.objects, r2)
+
+	patchIndex := f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+
+	assert.Equal(t, int32(10), f.fakeTrafficRouting.controllerSetDesiredWeight)
+	patch := f.getPatchedRollout(patchIndex)
+	var patchObj map[string]interface{}
+	err := json.Unmarshal([]byte(patch), &patchObj)
+	assert.NoError(t, err)
+	status := patchObj["status"].(map[string]interface{})
+	assert.Equal(t, float64(1), status["currentStepIndex"])
+}
+
+// newDependentRollout returns a rollout at its first setWeight step depending on the rollout 'payments', with a
+// background analysis and its canary not yet scaled up, along with the rollout it depends on
+func newDependentRollout(f *fixture, dependencyHealthy bool) (*v1alpha1.Rollout, *v1alpha1.Rollout) {
+	steps := []v1alpha1.CanaryStep{
+		{
+			SetWeight: pointer.Int32Ptr(10),
+		},
+		{
+			Pause: &v1alpha1.RolloutPause{},
+		},
+	}
+	at := analysisTemplate("success-rate")
+	r1 := newCanaryRollout("foo", 10, nil, steps, pointer.Int32Ptr(0), intstr.FromInt(1), intstr.FromInt(0))
+	r2 := bumpVersion(r1)
+	r2.Spec.Strategy.Canary.TrafficRouting = &v1alpha1.RolloutTrafficRouting{}
+	r2.Spec.Strategy.Canary.CanaryService = "canary"
+	r2.Spec.Strategy.Canary.StableService = "stable"
+	r2.Spec.Strategy.Canary.DependsOnRollout = "payments"
+	r2.Spec.Strategy.Canary.Analysis = &v1alpha1.RolloutAnalysisBackground{
+		RolloutAnalysis: v1alpha1.RolloutAnalysis{
+			TemplateName: at.Name,
+		},
+	}
+
+	rs1 := newReplicaSetWithStatus(r1, 10, 10)
+	rs2 := newReplicaSetWithStatus(r2, 0, 0)
+
+	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+	rs2PodHash := rs2.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+	canarySelector := map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: rs2PodHash}
+	stableSelector := map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: rs1PodHash}
+	canarySvc := newService("canary", 80, canarySelector, r2)
+	stableSvc := newService("stable", 80, stableSelector, r2)
+
+	f.kubeobjects = append(f.kubeobjects, rs1, rs2, canarySvc, stableSvc)
+	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
+	f.objects = append(f.objects, at)
+
+	dependency := newCanaryRollout("payments", 1, nil, nil, nil, intstr.FromInt(1), intstr.FromInt(0))
+	dependency.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "payments"}}
+	dependency.Status.Replicas = 1
+	dependency.Status.UpdatedReplicas = 1
+	dependency.Status.AvailableReplicas = 1
+	dependency.Status.CurrentPodHash = "abc123"
+	dependency.Status.StableRS = "abc123"
+	if !dependencyHealthy {
+		dependency.Status.StableRS = "def456"
+	}
+	dependency.Status.ObservedGeneration = conditions.ComputeGenerationHash(dependency.Spec)
+
+	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 10, 0, 10, false)
+	return r2, dependency
+}
+
+func TestRolloutDependsOnRolloutHoldsTrafficAndAnalysis(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	r2, dependency := newDependentRollout(f, false)
+	f.rolloutLister = append(f.rolloutLister, r2, dependency)
+	f.objects = append(f.objects, r2, dependency)
+
+	// The background analysis is not created while the rollout it depends on is not Healthy
+	patchIndex := f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+
+	assert.Equal(t, int32(0), f.fakeTrafficRouting.controllerSetDesiredWeight)
+	patch := f.getPatchedRollout(patchIndex)
+	var patchObj map[string]interface{}
+	err := json.Unmarshal([]byte(patch), &patchObj)
+	assert.NoError(t, err)
+	status := patchObj["status"].(map[string]interface{})
+	_, ok := status["currentStepIndex"]
+	assert.False(t, ok)
+	_, ok = status["canary"]
+	assert.False(t, ok)
+}
+
+func TestRolloutDependsOnRolloutNotFound(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	r2, _ := newDependentRollout(f, true)
+	f.rolloutLister = append(f.rolloutLister, r2)
+	f.objects = append(f.objects, r2)
+
+	patchIndex := f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+
+	assert.Equal(t, int32(0), f.fakeTrafficRouting.controllerSetDesiredWeight)
+	patch := f.getPatchedRollout(patchIndex)
+	var patchObj map[string]interface{}
+	err := json.Unmarshal([]byte(patch), &patchObj)
+	assert.NoError(t, err)
+	status := patchObj["status"].(map[string]interface{})
+	_, ok := status["canary"]
+	assert.False(t, ok)
+}
+
+func TestRolloutDependsOnRolloutHealthy(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	r2, dependency := newDependentRollout(f, true)
+	f.rolloutLister = append(f.rolloutLister, r2, dependency)
+	f.objects = append(f.objects, r2, dependency)
+
+	patchIndex := f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+
+	// The canary is scaled up and the analysis is started once the status records the dependency is satisfied
+	assert.Equal(t, int32(0), f.fakeTrafficRouting.controllerSetDesiredWeight)
+	patch := f.getPatchedRollout(patchIndex)
+	var patchObj map[string]interface{}
+	err := json.Unmarshal([]byte(patch), &patchObj)
+	assert.NoError(t, err)
+	status := patchObj["status"].(map[string]interface{})
+	_, ok := status["currentStepIndex"]
+	assert.False(t, ok)
+	canaryStatus := status["canary"].(map[string]interface{})
+	assert.Equal(t, true, canaryStatus["dependencySatisfied"])
+}
+
+func TestRolloutDependsOnRolloutProceeds(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	r2, dependency := newDependentRollout(f, true)
+	r2.Status.Canary.DependencySatisfied = true
+	f.rolloutLister = append(f.rolloutLister, r2, dependency)
+	f.objects = append(f.objects, r2, dependency)
+
+	// The rollout no longer checks the rollout it depends on, which may start another update in the meantime
+	dependency.Status.StableRS = "def456"
+
+	at := f.analysisTemplateLister[0]
+	f.expectCreateAnalysisRunAction(analysisRun(at, v1alpha1.RolloutTypeBackgroundRunLabel, r2))
+	rs2 := f.replicaSetLister[1]
+	updatedIndex := f.expectUpdateReplicaSetAction(rs2)
+	f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+
+	updatedRS := f.getUpdatedReplicaSet(updatedIndex)
+	assert.Equal(t, int32(1), *updatedRS.Spec.Replicas)
+}
+
 func TestRolloutUsePreviousSetWeight(t *testing.T) {
 	f := newFixture(t)
 	defer f.Close()
@@ -165,6 +517,105 @@ func TestRolloutUsePreviousSetWeight(t *testing.T) {
 	assert.Equal(t, int32(10), f.fakeTrafficRouting.controllerSetDesiredWeight)
 }
 
+// newRebalanceRollout returns a rollout rebalancing the weight on replica loss at its setWeight 40 step, whose canary
+// ReplicaSet is at its desired replica count of 4 with the available pods, and which reached the replica counts of the
+// step at the index
+func newRebalanceRollout(f *fixture, availableCanaryReplicas int, replicasReachedStepIndex *int32) *v1alpha1.Rollout {
+	steps := []v1alpha1.CanaryStep{
+		{
+			SetWeight: pointer.Int32Ptr(20),
+		},
+		{
+			Pause: &v1alpha1.RolloutPause{},
+		},
+		{
+			SetWeight: pointer.Int32Ptr(40),
+		},
+		{
+			Pause: &v1alpha1.RolloutPause{},
+		},
+	}
+	r1 := newCanaryRollout("foo", 10, nil, steps, pointer.Int32Ptr(2), intstr.FromInt(1), intstr.FromInt(0))
+	r2 := bumpVersion(r1)
+	r2.Spec.Strategy.Canary.TrafficRouting = &v1alpha1.RolloutTrafficRouting{}
+	r2.Spec.Strategy.Canary.CanaryService = "canary"
+	r2.Spec.Strategy.Canary.StableService = "stable"
+	r2.Spec.Strategy.Canary.RebalanceWeightOnReplicaLoss = true
+
+	rs1 := newReplicaSetWithStatus(r1, 10, 10)
+	rs2 := newReplicaSetWithStatus(r2, 4, availableCanaryReplicas)
+
+	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+	rs2PodHash := rs2.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+	canarySelector := map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: rs2PodHash}
+	stableSelector := map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: rs1PodHash}
+	canarySvc := newService("canary", 80, canarySelector, r2)
+	stableSvc := newService("stable", 80, stableSelector, r2)
+
+	f.kubeobjects = append(f.kubeobjects, rs1, rs2, canarySvc, stableSvc)
+	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+
+	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 10+int32(availableCanaryReplicas), 4, 14, false)
+	r2.Status.Canary.ReplicasReachedStepIndex = replicasReachedStepIndex
+	f.rolloutLister = append(f.rolloutLister, r2)
+	f.objects = append(f.objects, r2)
+	return r2
+}
+
+func TestRolloutRebalancesWeightOnReplicaLoss(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	r2 := newRebalanceRollout(f, 3, pointer.Int32Ptr(2))
+	f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+
+	// 3 of the 4 canary pods are available
+	assert.Equal(t, int32(30), f.fakeTrafficRouting.controllerSetDesiredWeight)
+}
+
+func TestRolloutRestoresWeightOnReplicaRecovery(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	r2 := newRebalanceRollout(f, 4, pointer.Int32Ptr(2))
+	f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+
+	assert.Equal(t, int32(40), f.fakeTrafficRouting.controllerSetDesiredWeight)
+}
+
+func TestRolloutDoesNotRebalanceWeightWhileScalingUp(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	// The canary has yet to reach the replica count of the step, so the weight of the previous step is used
+	r2 := newRebalanceRollout(f, 3, pointer.Int32Ptr(0))
+	f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+
+	assert.Equal(t, int32(20), f.fakeTrafficRouting.controllerSetDesiredWeight)
+}
+
+func TestRolloutRecordsReplicasReachedStep(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	r2 := newRebalanceRollout(f, 4, nil)
+	patchIndex := f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+
+	assert.Equal(t, int32(40), f.fakeTrafficRouting.controllerSetDesiredWeight)
+	patch := f.getPatchedRollout(patchIndex)
+	var patchObj map[string]interface{}
+	err := json.Unmarshal([]byte(patch), &patchObj)
+	assert.NoError(t, err)
+	status := patchObj["status"].(map[string]interface{})
+	assert.Equal(t, float64(3), status["currentStepIndex"])
+	canaryStatus := status["canary"].(map[string]interface{})
+	assert.Equal(t, float64(2), canaryStatus["replicasReachedStepIndex"])
+}
+
 func TestRolloutSetWeightToZeroWhenFullyRolledOut(t *testing.T) {
 	f := newFixture(t)
 	defer f.Close()
@@ -202,6 +653,47 @@ func TestRolloutSetWeightToZeroWhenFullyRolledOut(t *testing.T) {
 	assert.Equal(t, int32(0), f.fakeTrafficRouting.controllerSetDesiredWeight)
 }
 
+func TestRolloutSetWeightToFullWhileBaking(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	steps := []v1alpha1.CanaryStep{
+		{
+			SetWeight: pointer.Int32Ptr(10),
+		},
+	}
+	r1 := newCanaryRollout("foo", 10, nil, steps, pointer.Int32Ptr(1), intstr.FromInt(1), intstr.FromInt(0))
+	r1.Spec.Strategy.Canary.BakeTime = v1alpha1.DurationFromInt(60)
+	r2 := bumpVersion(r1)
+	r2.Spec.Strategy.Canary.TrafficRouting = &v1alpha1.RolloutTrafficRouting{}
+	r2.Spec.Strategy.Canary.CanaryService = "canary"
+	r2.Spec.Strategy.Canary.StableService = "stable"
+
+	rs1 := newReplicaSetWithStatus(r1, 10, 10)
+	rs2 := newReplicaSetWithStatus(r2, 10, 10)
+
+	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+	rs2PodHash := rs2.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+	canarySelector := map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: rs2PodHash}
+	stableSelector := map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: rs1PodHash}
+	canarySvc := newService("canary", 80, canarySelector, r2)
+	stableSvc := newService("stable", 80, stableSelector, r2)
+
+	f.kubeobjects = append(f.kubeobjects, rs1, rs2, canarySvc, stableSvc)
+	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+
+	r2 = updateCanaryRolloutStatus(r2, rs1PodHash, 10, 10, 10, false)
+	bakeStartedAt := metav1.Now()
+	r2.Status.Canary.BakeStartedAt = &bakeStartedAt
+	f.rolloutLister = append(f.rolloutLister, r2)
+	f.objects = append(f.objects, r2)
+
+	f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+
+	assert.Equal(t, int32(100), f.fakeTrafficRouting.controllerSetDesiredWeight)
+}
+
 func TestNewTrafficRoutingReconciler(t *testing.T) {
 	rc := Controller{}
 	gvk := schema.ParseGroupResource("virtualservices.networking.istio.io").WithVersion("v1alpha3")
@@ -327,4 +819,280 @@ func TestNewTrafficRoutingReconciler(t *testing.T) {
 		assert.NotNil(t, networkReconciler)
 		assert.Equal(t, smi.Type, networkReconciler.Type())
 	}
+	{
+		r := newCanaryRollout("foo", 10, nil, steps, pointer.Int32Ptr(1), intstr.FromInt(1), intstr.FromInt(0))
+		r.Spec.Strategy.Canary.TrafficRouting = &v1alpha1.RolloutTrafficRouting{
+			Traefik: &v1alpha1.TraefikTrafficRouting{},
+		}
+		roCtx := &canaryContext{
+			rollout: r,
+			log:     logutil.WithRollout(r),
+		}
+		networkReconciler, err := rc.NewTrafficRoutingReconciler(roCtx)
+		assert.Nil(t, err)
+		assert.NotNil(t, networkReconciler)
+		assert.Equal(t, traefik.Type, networkReconciler.Type())
+	}
+	{
+		r := newCanaryRollout("foo", 10, nil, steps, pointer.Int32Ptr(1), intstr.FromInt(1), intstr.FromInt(0))
+		r.Spec.Strategy.Canary.TrafficRouting = &v1alpha1.RolloutTrafficRouting{
+			Plugin: &v1alpha1.PluginTrafficRouting{Name: "custom"},
+		}
+		roCtx := &canaryContext{
+			rollout: r,
+			log:     logutil.WithRollout(r),
+		}
+		_, err := rc.NewTrafficRoutingReconciler(roCtx)
+		assert.EqualError(t, err, "traffic router plugin 'custom' is not registered")
+
+		rc.trafficRouterPlugins = plugin.Routers{"custom": configmap.NewRouter(k8sfake.NewSimpleClientset())}
+		networkReconciler, err := rc.NewTrafficRoutingReconciler(roCtx)
+		assert.Nil(t, err)
+		assert.NotNil(t, networkReconciler)
+		assert.Equal(t, plugin.Type, networkReconciler.Type())
+	}
+}
+
+// newPreviewTrafficRampFixture returns a fixture of a blue-green rollout ready to be promoted from rs1 to rs2 with a
+// preview traffic ramp of 10% then 50% for 2 minutes each
+func newPreviewTrafficRampFixture(t *testing.T, rampIndex *int32, stepStartedAt time.Time) (*fixture, *v1alpha1.Rollout, *appsv1.ReplicaSet, *appsv1.ReplicaSet, *corev1.Service) {
+	f := newFixture(t)
+
+	r1 := newBlueGreenRollout("foo", 1, nil, "active", "preview")
+	duration := intstr.FromString("2m")
+	r1.Spec.Strategy.BlueGreen.TrafficRouting = &v1alpha1.RolloutTrafficRouting{
+		SMI: &v1alpha1.SMITrafficRouting{},
+	}
+	r1.Spec.Strategy.BlueGreen.PreviewTrafficRamp = []v1alpha1.PreviewTrafficRampStep{
+		{Weight: 10, Duration: &duration},
+		{Weight: 50, Duration: &duration},
+	}
+	r2 := bumpVersion(r1)
+
+	rs1 := newReplicaSetWithStatus(r1, 1, 1)
+	rs2 := newReplicaSetWithStatus(r2, 1, 1)
+	rs1PodHash := rs1.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+	rs2PodHash := rs2.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+
+	r2 = updateBlueGreenRolloutStatus(r2, rs2PodHash, rs1PodHash, rs1PodHash, 1, 1, 2, 1, false, true)
+	progressingCondition, _ := newProgressingCondition(conditions.NewReplicaSetReason, rs2, "")
+	conditions.SetRolloutCondition(&r2.Status, progressingCondition)
+	if rampIndex != nil {
+		startedAt := metav1.NewTime(stepStartedAt)
+		r2.Status.BlueGreen.PreviewTrafficRampStepIndex = rampIndex
+		r2.Status.BlueGreen.PreviewTrafficRampStepStartedAt = &startedAt
+	}
+
+	activeSvc := newService("active", 80, map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: rs1PodHash}, r2)
+	previewSvc := newService("preview", 80, map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: rs2PodHash}, r2)
+
+	f.objects = append(f.objects, r2)
+	f.kubeobjects = append(f.kubeobjects, activeSvc, previewSvc, rs1, rs2)
+	f.rolloutLister = append(f.rolloutLister, r2)
+	f.replicaSetLister = append(f.replicaSetLister, rs1, rs2)
+	f.serviceLister = append(f.serviceLister, activeSvc, previewSvc)
+	return f, r2, rs1, rs2, activeSvc
+}
+
+// getPatchedBlueGreenStatus returns the blueGreen status of a rollout patch
+func getPatchedBlueGreenStatus(t *testing.T, patch string) map[string]interface{} {
+	patchObj := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal([]byte(patch), &patchObj))
+	status, _ := patchObj["status"].(map[string]interface{})
+	blueGreen, _ := status["blueGreen"].(map[string]interface{})
+	return blueGreen
+}
+
+func TestBlueGreenPreviewTrafficRampStarts(t *testing.T) {
+	f, r2, _, _, _ := newPreviewTrafficRampFixture(t, nil, time.Time{})
+	defer f.Close()
+
+	patchIndex := f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+
+	blueGreen := getPatchedBlueGreenStatus(t, f.getPatchedRollout(patchIndex))
+	assert.Equal(t, float64(0), blueGreen["previewTrafficRampStepIndex"])
+	assert.NotNil(t, blueGreen["previewTrafficRampStepStartedAt"])
+	assert.Equal(t, int32(10), f.fakeTrafficRouting.controllerSetDesiredWeight)
+}
+
+func TestBlueGreenPreviewTrafficRampHoldsStepDuringDuration(t *testing.T) {
+	f, r2, _, _, _ := newPreviewTrafficRampFixture(t, pointer.Int32Ptr(0), time.Now().Add(-1*time.Minute))
+	defer f.Close()
+
+	patchIndex := f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+
+	patch := f.getPatchedRollout(patchIndex)
+	assert.NotContains(t, patch, "previewTrafficRamp")
+	assert.Equal(t, int32(10), f.fakeTrafficRouting.controllerSetDesiredWeight)
+}
+
+func TestBlueGreenPreviewTrafficRampMovesToNextStep(t *testing.T) {
+	f, r2, _, _, _ := newPreviewTrafficRampFixture(t, pointer.Int32Ptr(0), time.Now().Add(-3*time.Minute))
+	defer f.Close()
+
+	patchIndex := f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+
+	blueGreen := getPatchedBlueGreenStatus(t, f.getPatchedRollout(patchIndex))
+	assert.Equal(t, float64(1), blueGreen["previewTrafficRampStepIndex"])
+	assert.Equal(t, int32(50), f.fakeTrafficRouting.controllerSetDesiredWeight)
+}
+
+func TestBlueGreenPreviewTrafficRampPromotesAfterLastStep(t *testing.T) {
+	f, r2, rs1, rs2, activeSvc := newPreviewTrafficRampFixture(t, pointer.Int32Ptr(1), time.Now().Add(-3*time.Minute))
+	defer f.Close()
+	f.fakeTrafficRouting.controllerSetDesiredWeight = 50
+	rs2PodHash := rs2.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+
+	servicePatchIndex := f.expectPatchServiceAction(activeSvc, rs2PodHash)
+	f.expectPatchReplicaSetAction(rs1)
+	patchIndex := f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+
+	f.verifyPatchedService(servicePatchIndex, rs2PodHash, "")
+	blueGreen := getPatchedBlueGreenStatus(t, f.getPatchedRollout(patchIndex))
+	assert.Equal(t, rs2PodHash, blueGreen["activeSelector"])
+	assert.Contains(t, blueGreen, "previewTrafficRampStepIndex")
+	assert.Nil(t, blueGreen["previewTrafficRampStepIndex"])
+	assert.Equal(t, int32(0), f.fakeTrafficRouting.controllerSetDesiredWeight)
+}
+
+func TestBlueGreenPreviewTrafficRampAbortsOnFailedAnalysis(t *testing.T) {
+	f, r2, _, _, _ := newPreviewTrafficRampFixture(t, pointer.Int32Ptr(0), time.Now().Add(-1*time.Minute))
+	defer f.Close()
+	f.fakeTrafficRouting.controllerSetDesiredWeight = 10
+
+	at := analysisTemplate("success-rate")
+	r2.Spec.Strategy.BlueGreen.PreviewTrafficRampAnalysis = &v1alpha1.RolloutAnalysis{
+		Templates: []v1alpha1.RolloutAnalysisTemplate{{TemplateName: at.Name}},
+	}
+	ar := analysisRun(at, v1alpha1.RolloutTypePreviewTrafficRampLabel, r2)
+	ar.Status.Phase = v1alpha1.AnalysisPhaseFailed
+	ar.Status.Message = "success rate below threshold"
+	r2.Status.BlueGreen.PreviewTrafficRampAnalysisRunStatus = &v1alpha1.RolloutAnalysisRunStatus{
+		Name:   ar.Name,
+		Status: v1alpha1.AnalysisPhaseRunning,
+	}
+	f.objects = append(f.objects, at, ar)
+	f.analysisTemplateLister = append(f.analysisTemplateLister, at)
+	f.analysisRunLister = append(f.analysisRunLister, ar)
+
+	patchIndex := f.expectPatchRolloutAction(r2)
+	f.run(getKey(r2, t))
+
+	patch := f.getPatchedRollout(patchIndex)
+	patchedRollout := v1alpha1.Rollout{}
+	assert.NoError(t, json.Unmarshal([]byte(patch), &patchedRollout))
+	assert.True(t, patchedRollout.Status.Abort)
+	blueGreen := getPatchedBlueGreenStatus(t, patch)
+	assert.Contains(t, blueGreen, "previewTrafficRampStepIndex")
+	assert.Nil(t, blueGreen["previewTrafficRampStepIndex"])
+	assert.Equal(t, int32(0), f.fakeTrafficRouting.controllerSetDesiredWeight)
+}
+
+func TestNewTrafficRoutingReconcilerBlueGreen(t *testing.T) {
+	r := newBlueGreenRollout("foo", 1, nil, "active", "preview")
+	roCtx := &blueGreenContext{
+		rollout: r,
+		log:     logutil.WithRollout(r),
+	}
+	rc := Controller{}
+	networkReconciler, err := rc.NewTrafficRoutingReconciler(roCtx)
+	assert.Nil(t, err)
+	assert.Nil(t, networkReconciler)
+
+	r.Spec.Strategy.BlueGreen.TrafficRouting = &v1alpha1.RolloutTrafficRouting{
+		Istio: &v1alpha1.IstioTrafficRouting{
+			VirtualService: v1alpha1.IstioVirtualService{Name: "vsvc", Routes: []string{"primary"}},
+		},
+	}
+	trafficRoutingRo := trafficRoutingRollout(r)
+	assert.Equal(t, "active", trafficRoutingRo.Spec.Strategy.Canary.StableService)
+	assert.Equal(t, "preview", trafficRoutingRo.Spec.Strategy.Canary.CanaryService)
+	assert.Equal(t, r.Spec.Strategy.BlueGreen.TrafficRouting, trafficRoutingRo.Spec.Strategy.Canary.TrafficRouting)
+	// The rollout of the context is not modified
+	assert.Nil(t, r.Spec.Strategy.Canary)
+}
+
+func TestBlueGreenPreviewTrafficRampIstioVirtualService(t *testing.T) {
+	route := func(activeWeight, previewWeight int64) map[string]interface{} {
+		return map[string]interface{}{
+			"name": "primary",
+			"route": []interface{}{
+				map[string]interface{}{"destination": map[string]interface{}{"host": "active"}, "weight": activeWeight},
+				map[string]interface{}{"destination": map[string]interface{}{"host": "preview"}, "weight": previewWeight},
+			},
+		}
+	}
+	vsvc := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "networking.istio.io/v1alpha3",
+		"kind":       "VirtualService",
+		"metadata": map[string]interface{}{
+			"name":      "vsvc",
+			"namespace": metav1.NamespaceDefault,
+		},
+		"spec": map[string]interface{}{
+			"hosts": []interface{}{"istio-rollout.dev.argoproj.io"},
+			"http":  []interface{}{route(100, 0)},
+		},
+	}}
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), vsvc)
+	gvr := istioutil.GetIstioGVR("v1alpha3")
+	dynamicInformerFactory := dynamicinformer.NewDynamicSharedInformerFactory(client, 0)
+	rc := Controller{
+		dynamicclientset:            client,
+		defaultIstioVersion:         "v1alpha3",
+		recorder:                    &record.FakeRecorder{},
+		istioVirtualServiceInformer: dynamicInformerFactory.ForResource(gvr).Informer(),
+	}
+
+	r := newBlueGreenRollout("foo", 1, nil, "active", "preview")
+	r.Spec.Strategy.BlueGreen.TrafficRouting = &v1alpha1.RolloutTrafficRouting{
+		Istio: &v1alpha1.IstioTrafficRouting{
+			VirtualService: v1alpha1.IstioVirtualService{Name: "vsvc", Routes: []string{"primary"}},
+		},
+	}
+	networkReconciler, err := rc.NewTrafficRoutingReconciler(&blueGreenContext{rollout: r, log: logutil.WithRollout(r)})
+	assert.Nil(t, err)
+	assert.Equal(t, istio.Type, networkReconciler.Type())
+
+	assert.Nil(t, networkReconciler.Reconcile(10))
+	updatedVsvc, err := client.Resource(gvr).Namespace(metav1.NamespaceDefault).Get("vsvc", metav1.GetOptions{})
+	assert.Nil(t, err)
+	routes, _, _ := unstructured.NestedSlice(updatedVsvc.Object, "spec", "http")
+	weights := map[string]string{}
+	for _, destination := range routes[0].(map[string]interface{})["route"].([]interface{}) {
+		destinationMap := destination.(map[string]interface{})
+		host := destinationMap["destination"].(map[string]interface{})["host"].(string)
+		weights[host] = fmt.Sprint(destinationMap["weight"])
+	}
+	assert.Equal(t, map[string]string{"active": "90", "preview": "10"}, weights)
+}
+
+func TestBlueGreenPreviewTrafficRampSMITrafficSplit(t *testing.T) {
+	client := smifake.NewSimpleClientset()
+	rc := Controller{
+		smiclientset:               client,
+		defaultTrafficSplitVersion: "v1alpha1",
+		recorder:                   &record.FakeRecorder{},
+	}
+
+	r := newBlueGreenRollout("foo", 1, nil, "active", "preview")
+	r.Spec.Strategy.BlueGreen.TrafficRouting = &v1alpha1.RolloutTrafficRouting{
+		SMI: &v1alpha1.SMITrafficRouting{},
+	}
+	networkReconciler, err := rc.NewTrafficRoutingReconciler(&blueGreenContext{rollout: r, log: logutil.WithRollout(r)})
+	assert.Nil(t, err)
+	assert.Equal(t, smi.Type, networkReconciler.Type())
+
+	assert.Nil(t, networkReconciler.Reconcile(10))
+	ts, err := client.SplitV1alpha1().TrafficSplits(r.Namespace).Get(r.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "active", ts.Spec.Service)
+	assert.Equal(t, "preview", ts.Spec.Backends[0].Service)
+	assert.Equal(t, int64(10), ts.Spec.Backends[0].Weight.Value())
+	assert.Equal(t, "active", ts.Spec.Backends[1].Service)
+	assert.Equal(t, int64(90), ts.Spec.Backends[1].Weight.Value())
 }
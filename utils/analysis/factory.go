@@ -1,21 +1,60 @@
 package analysis
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 
+	"github.com/antonmedv/expr"
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/kubernetes/pkg/fieldpath"
 
 	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/argoproj/argo-rollouts/utils/annotations"
+	"github.com/argoproj/argo-rollouts/utils/cron"
+	"github.com/argoproj/argo-rollouts/utils/evaluate"
+	templateutil "github.com/argoproj/argo-rollouts/utils/template"
 )
 
+// CanaryWeightArgName is the name of the argument holding the weight of the canary, which the rollout supplies to
+// the analyses of a canary rollout
+const CanaryWeightArgName = "canary-weight"
+
+// RegionArgName is the name of the argument holding the region of an AnalysisRun of an aggregated analysis step
+// running the analysis in each of its regions
+const RegionArgName = "region"
+
+// DrainingPodTemplateHashArgName is the name of the argument holding the pod template hash of the previous active
+// ReplicaSet of a blue-green rollout, which the rollout supplies to the analysis of the scale down drain
+const DrainingPodTemplateHashArgName = "draining-pod-template-hash"
+
+// variableNameRegex matches the names of the variables a metric defines for its conditions
+var variableNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// CanaryWeightArg returns the argument holding the weight of the canary. The argument is only used by the templates
+// declaring it, so that the conditions of a metric can depend on the weight of the step being analyzed.
+func CanaryWeightArg(weight int32) v1alpha1.Argument {
+	value := strconv.Itoa(int(weight))
+	return v1alpha1.Argument{
+		Name:  CanaryWeightArgName,
+		Value: &value,
+	}
+}
+
 // BuildArgumentsForRolloutAnalysisRun builds the arguments for a analysis base created by a rollout
-func BuildArgumentsForRolloutAnalysisRun(args []v1alpha1.AnalysisRunArgument, stableRS, newRS *appsv1.ReplicaSet) []v1alpha1.Argument {
+func BuildArgumentsForRolloutAnalysisRun(args []v1alpha1.AnalysisRunArgument, stableRS, newRS *appsv1.ReplicaSet, r *v1alpha1.Rollout) ([]v1alpha1.Argument, error) {
 	arguments := []v1alpha1.Argument{}
 	for i := range args {
 		arg := args[i]
 		value := arg.Value
-		if arg.ValueFrom != nil {
+		if arg.ValueFrom != nil && arg.ValueFrom.PodTemplateHashValue != nil {
 			switch *arg.ValueFrom.PodTemplateHashValue {
 			case v1alpha1.Latest:
 				value = newRS.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
@@ -23,6 +62,13 @@ func BuildArgumentsForRolloutAnalysisRun(args []v1alpha1.AnalysisRunArgument, st
 				value = stableRS.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
 			}
 		}
+		if arg.ValueFrom != nil && arg.ValueFrom.FieldRef != nil {
+			var err error
+			value, err = ExtractRolloutField(r, arg.ValueFrom.FieldRef.FieldPath)
+			if err != nil {
+				return nil, fmt.Errorf("unable to resolve the argument '%s': %v", arg.Name, err)
+			}
+		}
 		analysisArg := v1alpha1.Argument{
 			Name:  arg.Name,
 			Value: &value,
@@ -30,6 +76,92 @@ func BuildArgumentsForRolloutAnalysisRun(args []v1alpha1.AnalysisRunArgument, st
 		arguments = append(arguments, analysisArg)
 
 	}
+	// The annotations of the rollout take precedence over the arguments of the analysis, so that the same manifest
+	// can be tuned per environment
+	for _, annotationArg := range AnnotationArgs(r) {
+		overridden := false
+		for i := range arguments {
+			if arguments[i].Name == annotationArg.Name {
+				arguments[i] = annotationArg
+				overridden = true
+			}
+		}
+		if !overridden {
+			arguments = append(arguments, annotationArg)
+		}
+	}
+	return arguments, nil
+}
+
+// AnnotationArgs returns the arguments set by the rollout.argoproj.io/arg-<name> annotations of the rollout, sorted
+// by name. The arguments are only used by the templates declaring them.
+func AnnotationArgs(r *v1alpha1.Rollout) []v1alpha1.Argument {
+	var args []v1alpha1.Argument
+	for key, value := range r.Annotations {
+		if !strings.HasPrefix(key, annotations.AnalysisArgAnnotationPrefix) {
+			continue
+		}
+		name := strings.TrimPrefix(key, annotations.AnalysisArgAnnotationPrefix)
+		if name == "" {
+			continue
+		}
+		value := value
+		args = append(args, v1alpha1.Argument{Name: name, Value: &value})
+	}
+	sort.Slice(args, func(i, j int) bool {
+		return args[i].Name < args[j].Name
+	})
+	return args
+}
+
+// ExtractRolloutField returns the value of the field of the rollout selected by the path. The metadata fields are
+// selected with the paths supported by the downward API (e.g. metadata.labels['team']), and the spec fields with a
+// dotted path to a string, number or boolean (e.g. spec.strategy.canary.stableService).
+func ExtractRolloutField(r *v1alpha1.Rollout, fieldPath string) (string, error) {
+	if strings.HasPrefix(fieldPath, "metadata.") {
+		return fieldpath.ExtractFieldPathAsString(r, fieldPath)
+	}
+	if !strings.HasPrefix(fieldPath, "spec.") {
+		return "", fmt.Errorf("field path '%s' must select a field of the metadata or the spec of the rollout", fieldPath)
+	}
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(r)
+	if err != nil {
+		return "", err
+	}
+	value, found, err := unstructured.NestedFieldNoCopy(obj, strings.Split(fieldPath, ".")...)
+	if err != nil {
+		return "", fmt.Errorf("field path '%s' is invalid: %v", fieldPath, err)
+	}
+	if !found {
+		return "", fmt.Errorf("field path '%s' is not set in the rollout", fieldPath)
+	}
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool, int64, float64:
+		return fmt.Sprint(v), nil
+	}
+	return "", fmt.Errorf("field path '%s' does not select a string, number or boolean", fieldPath)
+}
+
+// InheritArgs returns the inherited arguments overridden by the arguments with the same name, followed
+// by the remaining arguments
+func InheritArgs(inheritedArgs, args []v1alpha1.Argument) []v1alpha1.Argument {
+	arguments := make([]v1alpha1.Argument, len(inheritedArgs))
+	copy(arguments, inheritedArgs)
+	for _, arg := range args {
+		found := false
+		for i := range arguments {
+			if arguments[i].Name == arg.Name {
+				arguments[i] = arg
+				found = true
+				break
+			}
+		}
+		if !found {
+			arguments = append(arguments, arg)
+		}
+	}
 	return arguments
 }
 
@@ -59,6 +191,31 @@ func PrePromotionLabels(podHash, instanceID string) map[string]string {
 
 }
 
+// PreviewTrafficRampLabels returns a map[string]string of common labels for the analysis of the preview traffic ramp
+func PreviewTrafficRampLabels(podHash, instanceID string) map[string]string {
+	labels := map[string]string{
+		v1alpha1.DefaultRolloutUniqueLabelKey: podHash,
+		v1alpha1.RolloutTypeLabel:             v1alpha1.RolloutTypePreviewTrafficRampLabel,
+	}
+	if instanceID != "" {
+		labels[v1alpha1.LabelKeyControllerInstanceID] = instanceID
+	}
+	return labels
+}
+
+// ScaleDownDrainLabels returns a map[string]string of common labels for the analysis of the scale down drain of the
+// ReplicaSet with the pod hash
+func ScaleDownDrainLabels(podHash, instanceID string) map[string]string {
+	labels := map[string]string{
+		v1alpha1.DefaultRolloutUniqueLabelKey: podHash,
+		v1alpha1.RolloutTypeLabel:             v1alpha1.RolloutTypeScaleDownDrainLabel,
+	}
+	if instanceID != "" {
+		labels[v1alpha1.LabelKeyControllerInstanceID] = instanceID
+	}
+	return labels
+}
+
 // BackgroundLabels returns a map[string]string of common labels for the background analysis
 func BackgroundLabels(podHash, instanceID string) map[string]string {
 	labels := map[string]string{
@@ -104,6 +261,42 @@ func ValidateMetrics(metrics []v1alpha1.Metric) error {
 	return nil
 }
 
+// ValidateJobMetricArgs validates the templates of the command, args and env of the containers of the job metrics,
+// which are substituted with the arguments of the analysis before the job is created. The templates need to be well
+// formed and only reference the given arguments.
+func ValidateJobMetricArgs(metrics []v1alpha1.Metric, args []v1alpha1.Argument) error {
+	for _, metric := range metrics {
+		if metric.Provider.Job == nil {
+			continue
+		}
+		for _, container := range metric.Provider.Job.Spec.Template.Spec.Containers {
+			templated, err := json.Marshal(struct {
+				Command []string        `json:"command"`
+				Args    []string        `json:"args"`
+				Env     []corev1.EnvVar `json:"env"`
+			}{container.Command, container.Args, container.Env})
+			if err != nil {
+				return err
+			}
+			referencedArgs, err := templateutil.ReferencedArgs(string(templated))
+			if err != nil {
+				return fmt.Errorf("metric '%s': invalid template in container '%s': %v", metric.Name, container.Name, err)
+			}
+			names := make([]string, 0, len(referencedArgs))
+			for name := range referencedArgs {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				if findArg(name, args) < 0 {
+					return fmt.Errorf("metric '%s': container '%s' references args.%s which is not an argument", metric.Name, container.Name, name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
 // ValidateMetric validates a single metric spec
 func ValidateMetric(metric v1alpha1.Metric) error {
 	if metric.Count > 0 {
@@ -114,7 +307,7 @@ func ValidateMetric(metric v1alpha1.Metric) error {
 			return fmt.Errorf("count must be >= inconclusiveLimit")
 		}
 	}
-	if metric.Count > 1 && metric.Interval == "" {
+	if metric.Count > 1 && metric.Interval == "" && metric.Schedule == "" {
 		return fmt.Errorf("interval must be specified when count > 1")
 	}
 	if metric.Interval != "" {
@@ -122,12 +315,40 @@ func ValidateMetric(metric v1alpha1.Metric) error {
 			return fmt.Errorf("invalid interval string: %v", err)
 		}
 	}
+	if metric.DecisionInterval != "" {
+		if metric.Interval == "" {
+			return fmt.Errorf("decisionInterval requires an interval")
+		}
+		if _, err := metric.DecisionInterval.Duration(); err != nil {
+			return fmt.Errorf("invalid decisionInterval string: %v", err)
+		}
+	}
+	if metric.Schedule != "" {
+		if metric.Interval != "" {
+			return fmt.Errorf("interval and schedule are mutually exclusive")
+		}
+		if _, err := cron.Parse(metric.Schedule); err != nil {
+			return fmt.Errorf("invalid schedule: %v", err)
+		}
+	}
 	if metric.InitialDelay != "" {
 		if _, err := metric.InitialDelay.Duration(); err != nil {
 			return fmt.Errorf("invalid startDelay string: %v", err)
 		}
 	}
 
+	if metric.MaxMeasurements < 0 {
+		return fmt.Errorf("maxMeasurements must be >= 0")
+	}
+	switch metric.MaxMeasurementsPhase {
+	case "", v1alpha1.AnalysisPhaseSuccessful, v1alpha1.AnalysisPhaseFailed, v1alpha1.AnalysisPhaseInconclusive:
+	default:
+		return fmt.Errorf("invalid maxMeasurementsPhase '%s': must be one of Successful, Failed, Inconclusive", metric.MaxMeasurementsPhase)
+	}
+	if metric.MaxMeasurementsPhase != "" && metric.MaxMeasurements == 0 {
+		return fmt.Errorf("maxMeasurementsPhase requires maxMeasurements")
+	}
+
 	if metric.FailureLimit < 0 {
 		return fmt.Errorf("failureLimit must be >= 0")
 	}
@@ -137,21 +358,272 @@ func ValidateMetric(metric v1alpha1.Metric) error {
 	if metric.ConsecutiveErrorLimit != nil && *metric.ConsecutiveErrorLimit < 0 {
 		return fmt.Errorf("consecutiveErrorLimit must be >= 0")
 	}
+	if metric.InitialErrorGrace < 0 {
+		return fmt.Errorf("initialErrorGrace must be >= 0")
+	}
+	switch metric.NaNHandling {
+	case "", v1alpha1.NaNHandlingError, v1alpha1.NaNHandlingFail, v1alpha1.NaNHandlingPass:
+	default:
+		return fmt.Errorf("invalid nanHandling '%s': must be one of error, fail, pass", metric.NaNHandling)
+	}
+	if metric.RecentResultsWindow < 0 {
+		return fmt.Errorf("recentResultsWindow must be >= 0")
+	}
+	// Thresholds referencing arguments are validated when the measurement is evaluated, once they are resolved
+	if metric.MarginalBand != nil && !isTemplated(metric.MarginalBand.Lower) && !isTemplated(metric.MarginalBand.Upper) {
+		lower, err := strconv.ParseFloat(metric.MarginalBand.Lower, 64)
+		if err != nil {
+			return fmt.Errorf("invalid marginalBand lower threshold '%s'", metric.MarginalBand.Lower)
+		}
+		upper, err := strconv.ParseFloat(metric.MarginalBand.Upper, 64)
+		if err != nil {
+			return fmt.Errorf("invalid marginalBand upper threshold '%s'", metric.MarginalBand.Upper)
+		}
+		if lower > upper {
+			return fmt.Errorf("marginalBand lower threshold must be <= upper threshold")
+		}
+	}
+	// A transform referencing arguments is compiled when the measurement is evaluated, once they are resolved
+	if metric.Transform != "" && !isTemplated(metric.Transform) {
+		if _, err := expr.Compile(metric.Transform); err != nil {
+			return fmt.Errorf("invalid transform: %v", err)
+		}
+	}
+	if err := validateMetricProvider(metric.Provider); err != nil {
+		return err
+	}
+	if metric.FallbackProvider != nil {
+		if err := validateMetricProvider(metric.FallbackProvider.MetricProvider()); err != nil {
+			return fmt.Errorf("fallbackProvider: %v", err)
+		}
+	}
+	return nil
+}
+
+// isTemplated returns whether the value references arguments which are resolved when the measurement is taken
+func isTemplated(value string) bool {
+	return strings.Contains(value, "{{")
+}
+
+// validateMetricProvider validates that exactly one provider is specified and that the web settings are valid
+func validateMetricProvider(provider v1alpha1.MetricProvider) error {
 	numProviders := 0
-	if metric.Provider.Prometheus != nil {
+	if provider.Prometheus != nil {
 		numProviders++
+		if provider.Prometheus.QueryTimeout != "" {
+			queryTimeout, err := provider.Prometheus.QueryTimeout.Duration()
+			if err != nil || queryTimeout <= 0 {
+				return fmt.Errorf("prometheus.queryTimeout must be a positive duration")
+			}
+		}
+		switch provider.Prometheus.BaselineCapture {
+		case "", v1alpha1.BaselineCaptureEveryMeasurement:
+		case v1alpha1.BaselineCaptureRunStart:
+			if provider.Prometheus.BaselineQuery == "" {
+				return fmt.Errorf("prometheus.baselineCapture requires prometheus.baselineQuery")
+			}
+		default:
+			return fmt.Errorf("prometheus.baselineCapture must be either '%s' or '%s'", v1alpha1.BaselineCaptureEveryMeasurement, v1alpha1.BaselineCaptureRunStart)
+		}
 	}
-	if metric.Provider.Job != nil {
+	if provider.Job != nil {
 		numProviders++
 	}
-	if metric.Provider.Web != nil {
+	if provider.Web != nil {
 		numProviders++
+		if provider.Web.PageLimit < 0 {
+			return fmt.Errorf("web.pageLimit must be >= 0")
+		}
+		if provider.Web.PageLimit > 0 && provider.Web.NextPageJSONPath == "" {
+			return fmt.Errorf("web.pageLimit requires web.nextPageJsonPath")
+		}
+		if len(provider.Web.ValuesFrom) > 0 && provider.Web.NextPageJSONPath != "" {
+			return fmt.Errorf("web.valuesFrom is not supported with web.nextPageJsonPath")
+		}
+		names := make([]string, 0, len(provider.Web.ValuesFrom))
+		for name := range provider.Web.ValuesFrom {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if !variableNameRegex.MatchString(name) {
+				return fmt.Errorf("web.valuesFrom name '%s' must be a valid identifier", name)
+			}
+			if evaluate.IsReservedName(name) {
+				return fmt.Errorf("web.valuesFrom name '%s' is reserved", name)
+			}
+		}
+		for i, statusRange := range provider.Web.SuccessStatusRanges {
+			if statusRange.Min < 100 || statusRange.Min > 599 || (statusRange.Max != 0 && (statusRange.Max < statusRange.Min || statusRange.Max > 599)) {
+				return fmt.Errorf("web.successStatusRanges[%d] must be a range of status codes between 100 and 599", i)
+			}
+		}
+	}
+	if provider.Wavefront != nil {
+		numProviders++
+		if provider.Wavefront.OffsetSeconds < 0 {
+			return fmt.Errorf("wavefront.offsetSeconds must be >= 0")
+		}
+	}
+	if provider.Kayenta != nil {
+		numProviders++
+	}
+	if provider.Elasticsearch != nil {
+		numProviders++
+	}
+	if provider.KubernetesEvent != nil {
+		numProviders++
+	}
+	if provider.PodExec != nil {
+		numProviders++
+		if len(provider.PodExec.Command) == 0 {
+			return fmt.Errorf("podExec.command must not be empty")
+		}
+		switch provider.PodExec.Pods {
+		case "", v1alpha1.PodExecAnyPod, v1alpha1.PodExecAllPods:
+		default:
+			return fmt.Errorf("podExec.pods must be either '%s' or '%s'", v1alpha1.PodExecAnyPod, v1alpha1.PodExecAllPods)
+		}
+		switch provider.PodExec.Result {
+		case "", v1alpha1.PodExecResultStdout, v1alpha1.PodExecResultExitCode:
+		default:
+			return fmt.Errorf("podExec.result must be either '%s' or '%s'", v1alpha1.PodExecResultStdout, v1alpha1.PodExecResultExitCode)
+		}
+	}
+	if provider.Alertmanager != nil {
+		numProviders++
+		if len(provider.Alertmanager.Matchers) == 0 {
+			return fmt.Errorf("alertmanager.matchers must not be empty")
+		}
+		for _, matcher := range provider.Alertmanager.Matchers {
+			if matcher.Name == "" {
+				return fmt.Errorf("alertmanager.matchers must have a name")
+			}
+			switch matcher.Type {
+			case "", v1alpha1.AlertmanagerMatchEqual, v1alpha1.AlertmanagerMatchNotEqual, v1alpha1.AlertmanagerMatchRegex, v1alpha1.AlertmanagerMatchNotRegex:
+			default:
+				return fmt.Errorf("alertmanager.matchers type must be one of '%s', '%s', '%s' or '%s'", v1alpha1.AlertmanagerMatchEqual, v1alpha1.AlertmanagerMatchNotEqual, v1alpha1.AlertmanagerMatchRegex, v1alpha1.AlertmanagerMatchNotRegex)
+			}
+		}
+	}
+	if provider.Decision != nil {
+		numProviders++
+		switch provider.Decision.Method {
+		case "", "GET":
+			if provider.Decision.Body != "" {
+				return fmt.Errorf("decision.body requires decision.method to be POST")
+			}
+		case "POST":
+		default:
+			return fmt.Errorf("decision.method must be either 'GET' or 'POST'")
+		}
+	}
+	if provider.Loki != nil {
+		numProviders++
+		if provider.Loki.Query == "" {
+			return fmt.Errorf("loki.query must not be empty")
+		}
+		if provider.Loki.Window != "" {
+			if _, err := provider.Loki.Window.Duration(); err != nil {
+				return fmt.Errorf("invalid loki.window string: %v", err)
+			}
+		}
+		if provider.Loki.Step != "" {
+			if _, err := provider.Loki.Step.Duration(); err != nil {
+				return fmt.Errorf("invalid loki.step string: %v", err)
+			}
+		}
+		switch provider.Loki.Reduce {
+		case "", v1alpha1.LokiReduceLast, v1alpha1.LokiReduceSum, v1alpha1.LokiReduceAvg, v1alpha1.LokiReduceMin, v1alpha1.LokiReduceMax:
+		default:
+			return fmt.Errorf("loki.reduce must be one of '%s', '%s', '%s', '%s' or '%s'", v1alpha1.LokiReduceLast, v1alpha1.LokiReduceSum, v1alpha1.LokiReduceAvg, v1alpha1.LokiReduceMin, v1alpha1.LokiReduceMax)
+		}
+		if provider.Loki.OffsetSeconds < 0 {
+			return fmt.Errorf("loki.offsetSeconds must be >= 0")
+		}
+	}
+	if provider.Pingdom != nil {
+		numProviders++
+		if provider.Pingdom.CheckID == "" {
+			return fmt.Errorf("pingdom.checkId must not be empty")
+		}
 	}
-	if metric.Provider.Wavefront != nil {
+	if provider.Datadog != nil {
 		numProviders++
+		if provider.Datadog.Logs != nil {
+			if provider.Datadog.SLOID != "" {
+				return fmt.Errorf("datadog must specify exactly one of sloId or logs")
+			}
+			if provider.Datadog.Timeframe != "" {
+				return fmt.Errorf("datadog.timeframe applies to SLOs, use datadog.logs.timeframe instead")
+			}
+			if provider.Datadog.Logs.Query == "" {
+				return fmt.Errorf("datadog.logs.query must not be empty")
+			}
+			if provider.Datadog.Logs.Timeframe != "" {
+				if _, err := provider.Datadog.Logs.Timeframe.Duration(); err != nil {
+					return fmt.Errorf("invalid datadog.logs.timeframe string: %v", err)
+				}
+			}
+		} else {
+			if provider.Datadog.SLOID == "" {
+				return fmt.Errorf("datadog.sloId must not be empty")
+			}
+			switch provider.Datadog.Timeframe {
+			case "", "7d", "30d", "90d":
+			default:
+				return fmt.Errorf("datadog.timeframe must be one of '7d', '30d' or '90d'")
+			}
+		}
+	}
+	if provider.GRPC != nil {
+		numProviders++
+		if err := validateGRPCMetric(provider.GRPC); err != nil {
+			return err
+		}
 	}
-	if metric.Provider.Kayenta != nil {
+	if provider.ReplicaSet != nil {
 		numProviders++
+		if err := validateReplicaSetMetric(provider.ReplicaSet); err != nil {
+			return err
+		}
+	}
+	if provider.PodMetrics != nil {
+		numProviders++
+	}
+	if provider.Incident != nil {
+		numProviders++
+		if provider.Incident.Address == "" {
+			return fmt.Errorf("incident.address must not be empty")
+		}
+		if provider.Incident.Service == "" {
+			return fmt.Errorf("incident.service must not be empty")
+		}
+	}
+	if provider.XRay != nil {
+		numProviders++
+		if (provider.XRay.ServiceName == "") == (provider.XRay.FilterExpression == "") {
+			return fmt.Errorf("xray must specify exactly one of serviceName or filterExpression")
+		}
+		if provider.XRay.Interval != "" {
+			if _, err := provider.XRay.Interval.Duration(); err != nil {
+				return fmt.Errorf("invalid xray.interval string: %v", err)
+			}
+		}
+	}
+	if provider.CommitStatus != nil {
+		numProviders++
+		switch provider.CommitStatus.VCS {
+		case v1alpha1.CommitStatusVCSGitHub, v1alpha1.CommitStatusVCSGitLab:
+		default:
+			return fmt.Errorf("commitStatus.vcs must be either '%s' or '%s'", v1alpha1.CommitStatusVCSGitHub, v1alpha1.CommitStatusVCSGitLab)
+		}
+		if provider.CommitStatus.Repository == "" {
+			return fmt.Errorf("commitStatus.repository must not be empty")
+		}
+		if provider.CommitStatus.SHA == "" {
+			return fmt.Errorf("commitStatus.sha must not be empty")
+		}
 	}
 	if numProviders == 0 {
 		return fmt.Errorf("no provider specified")
@@ -161,3 +633,56 @@ func ValidateMetric(metric v1alpha1.Metric) error {
 	}
 	return nil
 }
+
+// validateGRPCMetric validates the method, request and field of a gRPC metric
+func validateGRPCMetric(metric *v1alpha1.GRPCMetric) error {
+	if metric.Address == "" {
+		return fmt.Errorf("grpc.address must not be empty")
+	}
+	if !strings.HasPrefix(metric.Method, "/") || strings.Count(metric.Method, "/") != 2 {
+		return fmt.Errorf("grpc.method must be the full name of a method, such as /package.Service/Method")
+	}
+	if _, err := base64.StdEncoding.DecodeString(metric.Request); err != nil {
+		return fmt.Errorf("grpc.request must be encoded in base64: %v", err)
+	}
+	for _, part := range strings.Split(metric.Field, ".") {
+		if fieldNumber, err := strconv.Atoi(part); err != nil || fieldNumber <= 0 {
+			return fmt.Errorf("grpc.field must be made of positive field numbers separated by dots")
+		}
+	}
+	switch metric.FieldType {
+	case "", v1alpha1.GRPCFieldTypeDouble, v1alpha1.GRPCFieldTypeFloat, v1alpha1.GRPCFieldTypeInt64, v1alpha1.GRPCFieldTypeUint64,
+		v1alpha1.GRPCFieldTypeInt32, v1alpha1.GRPCFieldTypeUint32, v1alpha1.GRPCFieldTypeSint64, v1alpha1.GRPCFieldTypeSint32,
+		v1alpha1.GRPCFieldTypeBool:
+	default:
+		return fmt.Errorf("grpc.fieldType '%s' is not supported", metric.FieldType)
+	}
+	return nil
+}
+
+// validateReplicaSetMetric validates the names, paths and sources of the fields of a ReplicaSet metric. The paths are
+// parsed by the provider, since they may reference arguments
+func validateReplicaSetMetric(metric *v1alpha1.ReplicaSetMetric) error {
+	if len(metric.Fields) == 0 {
+		return fmt.Errorf("replicaSet.fields must not be empty")
+	}
+	names := map[string]bool{}
+	for _, field := range metric.Fields {
+		if field.Name == "" {
+			return fmt.Errorf("replicaSet.fields must have a name")
+		}
+		if names[field.Name] {
+			return fmt.Errorf("replicaSet.fields has duplicate name '%s'", field.Name)
+		}
+		names[field.Name] = true
+		if field.JSONPath == "" {
+			return fmt.Errorf("replicaSet.fields '%s' must have a jsonPath", field.Name)
+		}
+		switch field.Source {
+		case "", v1alpha1.ReplicaSetMetricFieldSourceReplicaSet, v1alpha1.ReplicaSetMetricFieldSourcePods:
+		default:
+			return fmt.Errorf("replicaSet.fields '%s' source must be either '%s' or '%s'", field.Name, v1alpha1.ReplicaSetMetricFieldSourceReplicaSet, v1alpha1.ReplicaSetMetricFieldSourcePods)
+		}
+	}
+	return nil
+}
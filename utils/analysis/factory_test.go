@@ -2,9 +2,12 @@ package analysis
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/pointer"
 
@@ -46,13 +49,145 @@ func TestBuildArgumentsForRolloutAnalysisRun(t *testing.T) {
 			Labels: map[string]string{v1alpha1.DefaultRolloutUniqueLabelKey: "123456"},
 		},
 	}
-	args := BuildArgumentsForRolloutAnalysisRun(rolloutAnalysis.Args, stableRS, newRS)
+	args, err := BuildArgumentsForRolloutAnalysisRun(rolloutAnalysis.Args, stableRS, newRS, &v1alpha1.Rollout{})
+	assert.NoError(t, err)
 	assert.Contains(t, args, v1alpha1.Argument{Name: "hard-coded-value-key", Value: pointer.StringPtr("hard-coded-value")})
 	assert.Contains(t, args, v1alpha1.Argument{Name: "stable-key", Value: pointer.StringPtr("abcdef")})
 	assert.Contains(t, args, v1alpha1.Argument{Name: "new-key", Value: pointer.StringPtr("123456")})
 
 }
 
+func TestBuildArgumentsForRolloutAnalysisRunFieldRef(t *testing.T) {
+	rollout := &v1alpha1.Rollout{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "guestbook",
+			Labels:      map[string]string{"team": "payments"},
+			Annotations: map[string]string{"example.com/slo": "99.9"},
+		},
+	}
+	newArg := func(name, fieldPath string) v1alpha1.AnalysisRunArgument {
+		return v1alpha1.AnalysisRunArgument{
+			Name: name,
+			ValueFrom: &v1alpha1.ArgumentValueFrom{
+				FieldRef: &v1alpha1.FieldRef{FieldPath: fieldPath},
+			},
+		}
+	}
+	args, err := BuildArgumentsForRolloutAnalysisRun([]v1alpha1.AnalysisRunArgument{
+		newArg("team", "metadata.labels['team']"),
+		newArg("slo", "metadata.annotations['example.com/slo']"),
+		newArg("owner", "metadata.labels['owner']"),
+	}, nil, nil, rollout)
+	assert.NoError(t, err)
+	assert.Equal(t, []v1alpha1.Argument{
+		{Name: "team", Value: pointer.StringPtr("payments")},
+		{Name: "slo", Value: pointer.StringPtr("99.9")},
+		{Name: "owner", Value: pointer.StringPtr("")},
+	}, args)
+
+	_, err = BuildArgumentsForRolloutAnalysisRun([]v1alpha1.AnalysisRunArgument{newArg("team", "metadata.team")}, nil, nil, rollout)
+	assert.EqualError(t, err, "unable to resolve the argument 'team': unsupported fieldPath: metadata.team")
+}
+
+func TestBuildArgumentsForRolloutAnalysisRunAnnotations(t *testing.T) {
+	rollout := &v1alpha1.Rollout{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "guestbook",
+			Annotations: map[string]string{
+				"rollout.argoproj.io/arg-error-threshold": "0.01",
+				"rollout.argoproj.io/arg-latency-slo":     "250",
+				"rollout.argoproj.io/revision":            "2",
+				"example.com/arg-team":                    "payments",
+			},
+		},
+	}
+	args, err := BuildArgumentsForRolloutAnalysisRun([]v1alpha1.AnalysisRunArgument{
+		{Name: "service-name", Value: "guestbook"},
+		{Name: "error-threshold", Value: "0.05"},
+	}, nil, nil, rollout)
+	assert.NoError(t, err)
+	// the annotations override the arguments of the analysis of the same names, and add the others
+	assert.Equal(t, []v1alpha1.Argument{
+		{Name: "service-name", Value: pointer.StringPtr("guestbook")},
+		{Name: "error-threshold", Value: pointer.StringPtr("0.01")},
+		{Name: "latency-slo", Value: pointer.StringPtr("250")},
+	}, args)
+
+	// the overridden values take precedence over the values of the template
+	merged, err := MergeArgs(args, []v1alpha1.Argument{
+		{Name: "service-name"},
+		{Name: "error-threshold", Value: pointer.StringPtr("0.05")},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []v1alpha1.Argument{
+		{Name: "service-name", Value: pointer.StringPtr("guestbook")},
+		{Name: "error-threshold", Value: pointer.StringPtr("0.01")},
+	}, merged)
+}
+
+func TestExtractRolloutField(t *testing.T) {
+	rollout := &v1alpha1.Rollout{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "guestbook",
+			Labels: map[string]string{"team": "payments"},
+		},
+		Spec: v1alpha1.RolloutSpec{
+			Replicas: pointer.Int32Ptr(5),
+			Strategy: v1alpha1.RolloutStrategy{
+				Canary: &v1alpha1.CanaryStrategy{
+					StableService: "guestbook-stable",
+				},
+			},
+		},
+	}
+	tests := []struct {
+		fieldPath     string
+		expectedValue string
+		expectedError string
+	}{
+		{fieldPath: "metadata.name", expectedValue: "guestbook"},
+		{fieldPath: "metadata.labels['team']", expectedValue: "payments"},
+		{fieldPath: "spec.replicas", expectedValue: "5"},
+		{fieldPath: "spec.strategy.canary.stableService", expectedValue: "guestbook-stable"},
+		{fieldPath: "spec.strategy.canary.canaryService", expectedError: "field path 'spec.strategy.canary.canaryService' is not set in the rollout"},
+		{fieldPath: "spec.strategy.canary", expectedError: "field path 'spec.strategy.canary' does not select a string, number or boolean"},
+		{fieldPath: "spec.replicas.value", expectedError: "field path 'spec.replicas.value' is invalid"},
+		{fieldPath: "status.stableRS", expectedError: "field path 'status.stableRS' must select a field of the metadata or the spec of the rollout"},
+	}
+	for _, test := range tests {
+		value, err := ExtractRolloutField(rollout, test.fieldPath)
+		if test.expectedError != "" {
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), test.expectedError)
+			continue
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, test.expectedValue, value)
+	}
+}
+
+func TestCanaryWeightArg(t *testing.T) {
+	assert.Equal(t, v1alpha1.Argument{Name: "canary-weight", Value: pointer.StringPtr("25")}, CanaryWeightArg(25))
+}
+
+func TestInheritArgs(t *testing.T) {
+	inheritedArgs := []v1alpha1.Argument{
+		{Name: "service-name", Value: pointer.StringPtr("guestbook")},
+		{Name: "canary-hash", Value: pointer.StringPtr("abcdef")},
+	}
+	args := []v1alpha1.Argument{
+		{Name: "canary-hash", Value: pointer.StringPtr("123456")},
+		{Name: "threshold", Value: pointer.StringPtr("0.95")},
+	}
+	assert.Equal(t, []v1alpha1.Argument{
+		{Name: "service-name", Value: pointer.StringPtr("guestbook")},
+		{Name: "canary-hash", Value: pointer.StringPtr("123456")},
+		{Name: "threshold", Value: pointer.StringPtr("0.95")},
+	}, InheritArgs(inheritedArgs, args))
+	// The inherited arguments are not modified
+	assert.Equal(t, "abcdef", *inheritedArgs[1].Value)
+}
+
 func TestPrePromotionLabels(t *testing.T) {
 	podHash := "abcd123"
 	expected := map[string]string{
@@ -75,6 +210,28 @@ func TestPostPromotionLabels(t *testing.T) {
 	assert.Equal(t, expected, generated)
 }
 
+func TestPreviewTrafficRampLabels(t *testing.T) {
+	podHash := "abcd123"
+	expected := map[string]string{
+		v1alpha1.LabelKeyControllerInstanceID: "test",
+		v1alpha1.RolloutTypeLabel:             v1alpha1.RolloutTypePreviewTrafficRampLabel,
+		v1alpha1.DefaultRolloutUniqueLabelKey: podHash,
+	}
+	generated := PreviewTrafficRampLabels(podHash, "test")
+	assert.Equal(t, expected, generated)
+}
+
+func TestScaleDownDrainLabels(t *testing.T) {
+	podHash := "abcd123"
+	expected := map[string]string{
+		v1alpha1.LabelKeyControllerInstanceID: "test",
+		v1alpha1.RolloutTypeLabel:             v1alpha1.RolloutTypeScaleDownDrainLabel,
+		v1alpha1.DefaultRolloutUniqueLabelKey: podHash,
+	}
+	generated := ScaleDownDrainLabels(podHash, "test")
+	assert.Equal(t, expected, generated)
+}
+
 func TestStepLabels(t *testing.T) {
 	podHash := "abcd123"
 	expected := map[string]string{
@@ -171,6 +328,29 @@ func TestValidateMetrics(t *testing.T) {
 		err := ValidateMetrics(spec.Metrics)
 		assert.EqualError(t, err, "metrics[0]: invalid interval string: time: unknown unit s-typo in duration 60s-typo")
 	})
+	t.Run("Ensure valid decisionInterval", func(t *testing.T) {
+		spec := v1alpha1.AnalysisTemplateSpec{
+			Metrics: []v1alpha1.Metric{
+				{
+					Name:             "success-rate",
+					DecisionInterval: "30s",
+					FailureLimit:     2,
+					Provider: v1alpha1.MetricProvider{
+						Prometheus: &v1alpha1.PrometheusMetric{},
+					},
+				},
+			},
+		}
+		err := ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: decisionInterval requires an interval")
+		spec.Metrics[0].Interval = "5m"
+		spec.Metrics[0].DecisionInterval = "30s-typo"
+		err = ValidateMetrics(spec.Metrics)
+		_, parseErr := time.ParseDuration("30s-typo")
+		assert.EqualError(t, err, "metrics[0]: invalid decisionInterval string: "+parseErr.Error())
+		spec.Metrics[0].DecisionInterval = "30s"
+		assert.NoError(t, ValidateMetrics(spec.Metrics))
+	})
 	t.Run("Ensure valid intialDelay string", func(t *testing.T) {
 		spec := v1alpha1.AnalysisTemplateSpec{
 			Metrics: []v1alpha1.Metric{
@@ -211,6 +391,26 @@ func TestValidateMetrics(t *testing.T) {
 		err := ValidateMetrics(spec.Metrics)
 		assert.EqualError(t, err, "metrics[0]: interval must be specified when count > 1")
 	})
+	t.Run("Ensure schedule is valid", func(t *testing.T) {
+		metric := v1alpha1.Metric{
+			Name:     "success-rate",
+			Count:    2,
+			Schedule: "0 */2 * * *",
+			Provider: v1alpha1.MetricProvider{
+				Prometheus: &v1alpha1.PrometheusMetric{},
+			},
+		}
+		assert.NoError(t, ValidateMetrics([]v1alpha1.Metric{metric}))
+
+		metric.Schedule = "0 */2 * *"
+		err := ValidateMetrics([]v1alpha1.Metric{metric})
+		assert.EqualError(t, err, "metrics[0]: invalid schedule: expected 5 fields in cron expression '0 */2 * *', found 4")
+
+		metric.Schedule = "0 */2 * * *"
+		metric.Interval = "1h"
+		err = ValidateMetrics([]v1alpha1.Metric{metric})
+		assert.EqualError(t, err, "metrics[0]: interval and schedule are mutually exclusive")
+	})
 	t.Run("Ensure no duplicate metric names", func(t *testing.T) {
 		spec := v1alpha1.AnalysisTemplateSpec{
 			Metrics: []v1alpha1.Metric{
@@ -276,6 +476,524 @@ func TestValidateMetrics(t *testing.T) {
 		err := ValidateMetrics(spec.Metrics)
 		assert.EqualError(t, err, "metrics[0]: consecutiveErrorLimit must be >= 0")
 	})
+	t.Run("Ensure initialErrorGrace is nonnegative", func(t *testing.T) {
+		spec := v1alpha1.AnalysisTemplateSpec{
+			Metrics: []v1alpha1.Metric{
+				{
+					Name:              "success-rate",
+					InitialErrorGrace: -1,
+					Provider: v1alpha1.MetricProvider{
+						Prometheus: &v1alpha1.PrometheusMetric{},
+					},
+				},
+			},
+		}
+		err := ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: initialErrorGrace must be >= 0")
+	})
+	t.Run("Ensure nanHandling is valid", func(t *testing.T) {
+		spec := v1alpha1.AnalysisTemplateSpec{
+			Metrics: []v1alpha1.Metric{
+				{
+					Name:        "success-rate",
+					NaNHandling: "ignore",
+					Provider: v1alpha1.MetricProvider{
+						Prometheus: &v1alpha1.PrometheusMetric{},
+					},
+				},
+			},
+		}
+		err := ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: invalid nanHandling 'ignore': must be one of error, fail, pass")
+	})
+	t.Run("Ensure marginalBand is valid", func(t *testing.T) {
+		spec := v1alpha1.AnalysisTemplateSpec{
+			Metrics: []v1alpha1.Metric{
+				{
+					Name: "success-rate",
+					MarginalBand: &v1alpha1.MarginalBand{
+						Lower: "0.95",
+						Upper: "high",
+					},
+					Provider: v1alpha1.MetricProvider{
+						Prometheus: &v1alpha1.PrometheusMetric{},
+					},
+				},
+			},
+		}
+		err := ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: invalid marginalBand upper threshold 'high'")
+		spec.Metrics[0].MarginalBand.Upper = "0.90"
+		err = ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: marginalBand lower threshold must be <= upper threshold")
+		spec.Metrics[0].MarginalBand.Lower = "0.90"
+		spec.Metrics[0].MarginalBand.Upper = "0.95"
+		assert.NoError(t, ValidateMetrics(spec.Metrics))
+		// thresholds referencing arguments are not parsed before the arguments are resolved
+		spec.Metrics[0].MarginalBand.Lower = "{{args.marginal-lower}}"
+		assert.NoError(t, ValidateMetrics(spec.Metrics))
+	})
+	t.Run("Ensure maxMeasurements is valid", func(t *testing.T) {
+		spec := v1alpha1.AnalysisTemplateSpec{
+			Metrics: []v1alpha1.Metric{
+				{
+					Name:            "success-rate",
+					Interval:        "1m",
+					MaxMeasurements: -1,
+					Provider: v1alpha1.MetricProvider{
+						Prometheus: &v1alpha1.PrometheusMetric{},
+					},
+				},
+			},
+		}
+		err := ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: maxMeasurements must be >= 0")
+		spec.Metrics[0].MaxMeasurements = 0
+		spec.Metrics[0].MaxMeasurementsPhase = v1alpha1.AnalysisPhaseFailed
+		err = ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: maxMeasurementsPhase requires maxMeasurements")
+		spec.Metrics[0].MaxMeasurements = 100
+		spec.Metrics[0].MaxMeasurementsPhase = v1alpha1.AnalysisPhaseError
+		err = ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: invalid maxMeasurementsPhase 'Error': must be one of Successful, Failed, Inconclusive")
+		spec.Metrics[0].MaxMeasurementsPhase = v1alpha1.AnalysisPhaseInconclusive
+		assert.NoError(t, ValidateMetrics(spec.Metrics))
+	})
+	t.Run("Ensure web pageLimit is valid", func(t *testing.T) {
+		spec := v1alpha1.AnalysisTemplateSpec{
+			Metrics: []v1alpha1.Metric{
+				{
+					Name: "success-rate",
+					Provider: v1alpha1.MetricProvider{
+						Web: &v1alpha1.WebMetric{
+							PageLimit: -1,
+						},
+					},
+				},
+			},
+		}
+		err := ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: web.pageLimit must be >= 0")
+		spec.Metrics[0].Provider.Web.PageLimit = 5
+		err = ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: web.pageLimit requires web.nextPageJsonPath")
+		spec.Metrics[0].Provider.Web.NextPageJSONPath = "{$.next}"
+		assert.NoError(t, ValidateMetrics(spec.Metrics))
+	})
+	t.Run("Ensure web valuesFrom is valid", func(t *testing.T) {
+		spec := v1alpha1.AnalysisTemplateSpec{
+			Metrics: []v1alpha1.Metric{
+				{
+					Name: "health",
+					Provider: v1alpha1.MetricProvider{
+						Web: &v1alpha1.WebMetric{
+							ValuesFrom: map[string]string{
+								"latency":    "{$.latency}",
+								"error-rate": "{$.errorRate}",
+							},
+						},
+					},
+				},
+			},
+		}
+		err := ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: web.valuesFrom name 'error-rate' must be a valid identifier")
+		delete(spec.Metrics[0].Provider.Web.ValuesFrom, "error-rate")
+		spec.Metrics[0].Provider.Web.ValuesFrom["result"] = "{$.status}"
+		err = ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: web.valuesFrom name 'result' is reserved")
+		delete(spec.Metrics[0].Provider.Web.ValuesFrom, "result")
+		spec.Metrics[0].Provider.Web.ValuesFrom["errorRate"] = "{$.errorRate}"
+		assert.NoError(t, ValidateMetrics(spec.Metrics))
+		spec.Metrics[0].Provider.Web.NextPageJSONPath = "{$.next}"
+		err = ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: web.valuesFrom is not supported with web.nextPageJsonPath")
+	})
+	t.Run("Ensure web successStatusRanges are valid", func(t *testing.T) {
+		spec := v1alpha1.AnalysisTemplateSpec{
+			Metrics: []v1alpha1.Metric{
+				{
+					Name: "success-rate",
+					Provider: v1alpha1.MetricProvider{
+						Web: &v1alpha1.WebMetric{
+							SuccessStatusRanges: []v1alpha1.HTTPStatusRange{{Min: 200, Max: 299}, {Min: 404}},
+						},
+					},
+				},
+			},
+		}
+		assert.NoError(t, ValidateMetrics(spec.Metrics))
+		for _, invalid := range []v1alpha1.HTTPStatusRange{{Min: 0}, {Min: 404, Max: 400}, {Min: 400, Max: 600}} {
+			spec.Metrics[0].Provider.Web.SuccessStatusRanges[1] = invalid
+			err := ValidateMetrics(spec.Metrics)
+			assert.EqualError(t, err, "metrics[0]: web.successStatusRanges[1] must be a range of status codes between 100 and 599")
+		}
+	})
+	t.Run("Ensure prometheus queryTimeout is valid", func(t *testing.T) {
+		spec := v1alpha1.AnalysisTemplateSpec{
+			Metrics: []v1alpha1.Metric{
+				{
+					Name: "success-rate",
+					Provider: v1alpha1.MetricProvider{
+						Prometheus: &v1alpha1.PrometheusMetric{
+							QueryTimeout: "10",
+						},
+					},
+				},
+			},
+		}
+		err := ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: prometheus.queryTimeout must be a positive duration")
+		spec.Metrics[0].Provider.Prometheus.QueryTimeout = "0s"
+		err = ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: prometheus.queryTimeout must be a positive duration")
+		spec.Metrics[0].Provider.Prometheus.QueryTimeout = "10s"
+		assert.NoError(t, ValidateMetrics(spec.Metrics))
+	})
+	t.Run("Ensure prometheus baselineCapture is valid", func(t *testing.T) {
+		spec := v1alpha1.AnalysisTemplateSpec{
+			Metrics: []v1alpha1.Metric{
+				{
+					Name: "error-rate",
+					Provider: v1alpha1.MetricProvider{
+						Prometheus: &v1alpha1.PrometheusMetric{
+							Query:           "canary",
+							BaselineCapture: "Once",
+						},
+					},
+				},
+			},
+		}
+		err := ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: prometheus.baselineCapture must be either 'EveryMeasurement' or 'RunStart'")
+		spec.Metrics[0].Provider.Prometheus.BaselineCapture = v1alpha1.BaselineCaptureRunStart
+		err = ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: prometheus.baselineCapture requires prometheus.baselineQuery")
+		spec.Metrics[0].Provider.Prometheus.BaselineQuery = "baseline"
+		assert.NoError(t, ValidateMetrics(spec.Metrics))
+	})
+	t.Run("Ensure podExec is valid", func(t *testing.T) {
+		spec := v1alpha1.AnalysisTemplateSpec{
+			Metrics: []v1alpha1.Metric{
+				{
+					Name: "health",
+					Provider: v1alpha1.MetricProvider{
+						PodExec: &v1alpha1.PodExecMetric{},
+					},
+				},
+			},
+		}
+		err := ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: podExec.command must not be empty")
+		spec.Metrics[0].Provider.PodExec.Command = []string{"curl", "-sf", "localhost:8080/healthz"}
+		spec.Metrics[0].Provider.PodExec.Pods = "Some"
+		err = ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: podExec.pods must be either 'Any' or 'All'")
+		spec.Metrics[0].Provider.PodExec.Pods = v1alpha1.PodExecAllPods
+		spec.Metrics[0].Provider.PodExec.Result = "Stderr"
+		err = ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: podExec.result must be either 'Stdout' or 'ExitCode'")
+		spec.Metrics[0].Provider.PodExec.Result = v1alpha1.PodExecResultExitCode
+		assert.NoError(t, ValidateMetrics(spec.Metrics))
+	})
+	t.Run("Ensure alertmanager is valid", func(t *testing.T) {
+		spec := v1alpha1.AnalysisTemplateSpec{
+			Metrics: []v1alpha1.Metric{
+				{
+					Name: "alerts",
+					Provider: v1alpha1.MetricProvider{
+						Alertmanager: &v1alpha1.AlertmanagerMetric{
+							Address: "http://alertmanager.example.com:9093",
+						},
+					},
+				},
+			},
+		}
+		err := ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: alertmanager.matchers must not be empty")
+		spec.Metrics[0].Provider.Alertmanager.Matchers = []v1alpha1.AlertmanagerMatcher{{Value: "HighErrorRate"}}
+		err = ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: alertmanager.matchers must have a name")
+		spec.Metrics[0].Provider.Alertmanager.Matchers[0].Name = "alertname"
+		spec.Metrics[0].Provider.Alertmanager.Matchers[0].Type = "Like"
+		err = ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: alertmanager.matchers type must be one of 'Equal', 'NotEqual', 'Regex' or 'NotRegex'")
+		spec.Metrics[0].Provider.Alertmanager.Matchers[0].Type = v1alpha1.AlertmanagerMatchRegex
+		assert.NoError(t, ValidateMetrics(spec.Metrics))
+	})
+	t.Run("Ensure decision is valid", func(t *testing.T) {
+		spec := v1alpha1.AnalysisTemplateSpec{
+			Metrics: []v1alpha1.Metric{
+				{
+					Name: "feature-flags",
+					Provider: v1alpha1.MetricProvider{
+						Decision: &v1alpha1.DecisionMetric{
+							URL:  "http://decisions.example.com/canary",
+							Body: `{"cohort": "canary"}`,
+						},
+					},
+				},
+			},
+		}
+		err := ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: decision.body requires decision.method to be POST")
+		spec.Metrics[0].Provider.Decision.Method = "PUT"
+		err = ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: decision.method must be either 'GET' or 'POST'")
+		spec.Metrics[0].Provider.Decision.Method = "POST"
+		assert.NoError(t, ValidateMetrics(spec.Metrics))
+	})
+	t.Run("Ensure loki is valid", func(t *testing.T) {
+		spec := v1alpha1.AnalysisTemplateSpec{
+			Metrics: []v1alpha1.Metric{
+				{
+					Name: "error-logs",
+					Provider: v1alpha1.MetricProvider{
+						Loki: &v1alpha1.LokiMetric{
+							Address: "http://loki.example.com:3100",
+						},
+					},
+				},
+			},
+		}
+		err := ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: loki.query must not be empty")
+		spec.Metrics[0].Provider.Loki.Query = `sum(count_over_time({app="guestbook"} |= "error" [1m]))`
+		spec.Metrics[0].Provider.Loki.Window = "5 minutes"
+		err = ValidateMetrics(spec.Metrics)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "metrics[0]: invalid loki.window string")
+		spec.Metrics[0].Provider.Loki.Window = "5m"
+		spec.Metrics[0].Provider.Loki.Reduce = "Median"
+		err = ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: loki.reduce must be one of 'Last', 'Sum', 'Avg', 'Min' or 'Max'")
+		spec.Metrics[0].Provider.Loki.Reduce = v1alpha1.LokiReduceMax
+		spec.Metrics[0].Provider.Loki.OffsetSeconds = -30
+		err = ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: loki.offsetSeconds must be >= 0")
+		spec.Metrics[0].Provider.Loki.OffsetSeconds = 30
+		assert.NoError(t, ValidateMetrics(spec.Metrics))
+	})
+	t.Run("Ensure pingdom is valid", func(t *testing.T) {
+		spec := v1alpha1.AnalysisTemplateSpec{
+			Metrics: []v1alpha1.Metric{
+				{
+					Name: "synthetic-check",
+					Provider: v1alpha1.MetricProvider{
+						Pingdom: &v1alpha1.PingdomMetric{},
+					},
+				},
+			},
+		}
+		err := ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: pingdom.checkId must not be empty")
+		spec.Metrics[0].Provider.Pingdom.CheckID = "85975"
+		assert.NoError(t, ValidateMetrics(spec.Metrics))
+	})
+	t.Run("Ensure datadog is valid", func(t *testing.T) {
+		spec := v1alpha1.AnalysisTemplateSpec{
+			Metrics: []v1alpha1.Metric{
+				{
+					Name: "checkout-slo",
+					Provider: v1alpha1.MetricProvider{
+						Datadog: &v1alpha1.DatadogMetric{},
+					},
+				},
+			},
+		}
+		err := ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: datadog.sloId must not be empty")
+		spec.Metrics[0].Provider.Datadog.SLOID = "e8f4a6a4c1a55b1e9e3f9c5a2d7b6c01"
+		spec.Metrics[0].Provider.Datadog.Timeframe = "14d"
+		err = ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: datadog.timeframe must be one of '7d', '30d' or '90d'")
+		spec.Metrics[0].Provider.Datadog.Timeframe = "7d"
+		assert.NoError(t, ValidateMetrics(spec.Metrics))
+
+		spec.Metrics[0].Provider.Datadog.Logs = &v1alpha1.DatadogLogsQuery{}
+		err = ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: datadog must specify exactly one of sloId or logs")
+		spec.Metrics[0].Provider.Datadog.SLOID = ""
+		err = ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: datadog.timeframe applies to SLOs, use datadog.logs.timeframe instead")
+		spec.Metrics[0].Provider.Datadog.Timeframe = ""
+		err = ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: datadog.logs.query must not be empty")
+		spec.Metrics[0].Provider.Datadog.Logs.Query = "service:checkout status:error"
+		spec.Metrics[0].Provider.Datadog.Logs.Timeframe = "5x"
+		err = ValidateMetrics(spec.Metrics)
+		assert.Contains(t, err.Error(), "metrics[0]: invalid datadog.logs.timeframe string")
+		spec.Metrics[0].Provider.Datadog.Logs.Timeframe = "15m"
+		assert.NoError(t, ValidateMetrics(spec.Metrics))
+	})
+	t.Run("Ensure incident is valid", func(t *testing.T) {
+		spec := v1alpha1.AnalysisTemplateSpec{
+			Metrics: []v1alpha1.Metric{
+				{
+					Name: "open-incidents",
+					Provider: v1alpha1.MetricProvider{
+						Incident: &v1alpha1.IncidentMetric{},
+					},
+				},
+			},
+		}
+		err := ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: incident.address must not be empty")
+		spec.Metrics[0].Provider.Incident.Address = "https://api.pagerduty.com/incidents"
+		err = ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: incident.service must not be empty")
+		spec.Metrics[0].Provider.Incident.Service = "checkout"
+		assert.NoError(t, ValidateMetrics(spec.Metrics))
+	})
+	t.Run("Ensure commitStatus is valid", func(t *testing.T) {
+		spec := v1alpha1.AnalysisTemplateSpec{
+			Metrics: []v1alpha1.Metric{
+				{
+					Name: "required-checks",
+					Provider: v1alpha1.MetricProvider{
+						CommitStatus: &v1alpha1.CommitStatusMetric{},
+					},
+				},
+			},
+		}
+		err := ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: commitStatus.vcs must be either 'GitHub' or 'GitLab'")
+		spec.Metrics[0].Provider.CommitStatus.VCS = v1alpha1.CommitStatusVCSGitHub
+		err = ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: commitStatus.repository must not be empty")
+		spec.Metrics[0].Provider.CommitStatus.Repository = "argoproj/argo-rollouts"
+		err = ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: commitStatus.sha must not be empty")
+		spec.Metrics[0].Provider.CommitStatus.SHA = "{{args.commit-sha}}"
+		assert.NoError(t, ValidateMetrics(spec.Metrics))
+	})
+	t.Run("Ensure xray is valid", func(t *testing.T) {
+		spec := v1alpha1.AnalysisTemplateSpec{
+			Metrics: []v1alpha1.Metric{
+				{
+					Name: "fault-rate",
+					Provider: v1alpha1.MetricProvider{
+						XRay: &v1alpha1.XRayMetric{},
+					},
+				},
+			},
+		}
+		err := ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: xray must specify exactly one of serviceName or filterExpression")
+		spec.Metrics[0].Provider.XRay.ServiceName = "checkout"
+		spec.Metrics[0].Provider.XRay.FilterExpression = `annotation.version = "canary"`
+		err = ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: xray must specify exactly one of serviceName or filterExpression")
+		spec.Metrics[0].Provider.XRay.ServiceName = ""
+		spec.Metrics[0].Provider.XRay.Interval = "5x"
+		err = ValidateMetrics(spec.Metrics)
+		assert.Contains(t, err.Error(), "metrics[0]: invalid xray.interval string")
+		spec.Metrics[0].Provider.XRay.Interval = "10m"
+		assert.NoError(t, ValidateMetrics(spec.Metrics))
+	})
+	t.Run("Ensure grpc is valid", func(t *testing.T) {
+		spec := v1alpha1.AnalysisTemplateSpec{
+			Metrics: []v1alpha1.Metric{
+				{
+					Name: "success-rate",
+					Provider: v1alpha1.MetricProvider{
+						GRPC: &v1alpha1.GRPCMetric{},
+					},
+				},
+			},
+		}
+		err := ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: grpc.address must not be empty")
+		spec.Metrics[0].Provider.GRPC.Address = "metrics.default.svc:9090"
+		spec.Metrics[0].Provider.GRPC.Method = "GetSuccessRate"
+		err = ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: grpc.method must be the full name of a method, such as /package.Service/Method")
+		spec.Metrics[0].Provider.GRPC.Method = "/metrics.v1.Metrics/GetSuccessRate"
+		spec.Metrics[0].Provider.GRPC.Request = "not base64!"
+		err = ValidateMetrics(spec.Metrics)
+		assert.Contains(t, err.Error(), "metrics[0]: grpc.request must be encoded in base64")
+		spec.Metrics[0].Provider.GRPC.Request = "CAc="
+		spec.Metrics[0].Provider.GRPC.Field = "2.0"
+		err = ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: grpc.field must be made of positive field numbers separated by dots")
+		spec.Metrics[0].Provider.GRPC.Field = "2.1"
+		spec.Metrics[0].Provider.GRPC.FieldType = "string"
+		err = ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: grpc.fieldType 'string' is not supported")
+		spec.Metrics[0].Provider.GRPC.FieldType = v1alpha1.GRPCFieldTypeInt64
+		assert.NoError(t, ValidateMetrics(spec.Metrics))
+	})
+	t.Run("Ensure replicaSet is valid", func(t *testing.T) {
+		spec := v1alpha1.AnalysisTemplateSpec{
+			Metrics: []v1alpha1.Metric{
+				{
+					Name: "image-digest",
+					Provider: v1alpha1.MetricProvider{
+						ReplicaSet: &v1alpha1.ReplicaSetMetric{},
+					},
+				},
+			},
+		}
+		err := ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: replicaSet.fields must not be empty")
+		spec.Metrics[0].Provider.ReplicaSet.Fields = []v1alpha1.ReplicaSetMetricField{{JSONPath: "{.metadata.name}"}}
+		err = ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: replicaSet.fields must have a name")
+		spec.Metrics[0].Provider.ReplicaSet.Fields = []v1alpha1.ReplicaSetMetricField{{Name: "digest"}}
+		err = ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: replicaSet.fields 'digest' must have a jsonPath")
+		spec.Metrics[0].Provider.ReplicaSet.Fields = []v1alpha1.ReplicaSetMetricField{
+			{Name: "digest", JSONPath: "{.metadata.annotations.digest}"},
+			{Name: "digest", JSONPath: "{.status.containerStatuses[0].imageID}", Source: v1alpha1.ReplicaSetMetricFieldSourcePods},
+		}
+		err = ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: replicaSet.fields has duplicate name 'digest'")
+		spec.Metrics[0].Provider.ReplicaSet.Fields[1].Name = "images"
+		spec.Metrics[0].Provider.ReplicaSet.Fields[1].Source = "Deployment"
+		err = ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: replicaSet.fields 'images' source must be either 'ReplicaSet' or 'Pods'")
+		spec.Metrics[0].Provider.ReplicaSet.Fields[1].Source = v1alpha1.ReplicaSetMetricFieldSourcePods
+		assert.NoError(t, ValidateMetrics(spec.Metrics))
+	})
+	t.Run("Ensure wavefront offsetSeconds is valid", func(t *testing.T) {
+		spec := v1alpha1.AnalysisTemplateSpec{
+			Metrics: []v1alpha1.Metric{
+				{
+					Name: "success-rate",
+					Provider: v1alpha1.MetricProvider{
+						Wavefront: &v1alpha1.WavefrontMetric{
+							OffsetSeconds: -30,
+						},
+					},
+				},
+			},
+		}
+		err := ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: wavefront.offsetSeconds must be >= 0")
+		spec.Metrics[0].Provider.Wavefront.OffsetSeconds = 30
+		assert.NoError(t, ValidateMetrics(spec.Metrics))
+	})
+	t.Run("Ensure transform is valid", func(t *testing.T) {
+		spec := v1alpha1.AnalysisTemplateSpec{
+			Metrics: []v1alpha1.Metric{
+				{
+					Name:      "memory",
+					Transform: "result / (1024",
+					Provider: v1alpha1.MetricProvider{
+						Prometheus: &v1alpha1.PrometheusMetric{},
+					},
+				},
+			},
+		}
+		err := ValidateMetrics(spec.Metrics)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "metrics[0]: invalid transform")
+		spec.Metrics[0].Transform = "result / 1024 / 1024"
+		assert.NoError(t, ValidateMetrics(spec.Metrics))
+		// a transform referencing arguments is not compiled before the arguments are resolved
+		spec.Metrics[0].Transform = "result / {{args.divisor}}"
+		assert.NoError(t, ValidateMetrics(spec.Metrics))
+	})
 	t.Run("Ensure metric has provider", func(t *testing.T) {
 		spec := v1alpha1.AnalysisTemplateSpec{
 			Metrics: []v1alpha1.Metric{
@@ -294,11 +1012,12 @@ func TestValidateMetrics(t *testing.T) {
 				{
 					Name: "success-rate",
 					Provider: v1alpha1.MetricProvider{
-						Prometheus: &v1alpha1.PrometheusMetric{},
-						Job:        &v1alpha1.JobMetric{},
-						Wavefront:  &v1alpha1.WavefrontMetric{},
-						Kayenta:    &v1alpha1.KayentaMetric{},
-						Web:        &v1alpha1.WebMetric{},
+						Prometheus:    &v1alpha1.PrometheusMetric{},
+						Job:           &v1alpha1.JobMetric{},
+						Wavefront:     &v1alpha1.WavefrontMetric{},
+						Kayenta:       &v1alpha1.KayentaMetric{},
+						Web:           &v1alpha1.WebMetric{},
+						Elasticsearch: &v1alpha1.ElasticsearchMetric{},
 					},
 				},
 			},
@@ -306,4 +1025,75 @@ func TestValidateMetrics(t *testing.T) {
 		err := ValidateMetrics(spec.Metrics)
 		assert.EqualError(t, err, "metrics[0]: multiple providers specified")
 	})
+	t.Run("Ensure fallback provider has exactly 1 provider", func(t *testing.T) {
+		spec := v1alpha1.AnalysisTemplateSpec{
+			Metrics: []v1alpha1.Metric{
+				{
+					Name: "success-rate",
+					Provider: v1alpha1.MetricProvider{
+						Prometheus: &v1alpha1.PrometheusMetric{},
+					},
+					FallbackProvider: &v1alpha1.FallbackMetricProvider{},
+				},
+			},
+		}
+		err := ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: fallbackProvider: no provider specified")
+
+		spec.Metrics[0].FallbackProvider.Web = &v1alpha1.WebMetric{}
+		assert.NoError(t, ValidateMetrics(spec.Metrics))
+
+		spec.Metrics[0].FallbackProvider.Wavefront = &v1alpha1.WavefrontMetric{}
+		err = ValidateMetrics(spec.Metrics)
+		assert.EqualError(t, err, "metrics[0]: fallbackProvider: multiple providers specified")
+	})
+}
+
+func TestValidateJobMetricArgs(t *testing.T) {
+	newMetrics := func(container corev1.Container) []v1alpha1.Metric {
+		return []v1alpha1.Metric{
+			{
+				Name: "success-rate",
+				Provider: v1alpha1.MetricProvider{
+					Prometheus: &v1alpha1.PrometheusMetric{Query: "{{args.undeclared}}"},
+				},
+			},
+			{
+				Name: "integration-test",
+				Provider: v1alpha1.MetricProvider{
+					Job: &v1alpha1.JobMetric{
+						Spec: batchv1.JobSpec{
+							Template: corev1.PodTemplateSpec{
+								Spec: corev1.PodSpec{
+									Containers: []corev1.Container{container},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+	args := []v1alpha1.Argument{{Name: "canary-hash"}, {Name: "environment"}}
+
+	metrics := newMetrics(corev1.Container{
+		Name:    "test",
+		Command: []string{"./run-tests.sh", "--selector", "rollouts-pod-template-hash={{args.canary-hash}}"},
+		Env:     []corev1.EnvVar{{Name: "ENVIRONMENT", Value: "{{ args.environment }}"}},
+	})
+	assert.NoError(t, ValidateJobMetricArgs(metrics, args))
+
+	metrics = newMetrics(corev1.Container{
+		Name: "test",
+		Args: []string{"--region", "{{args.region}}"},
+	})
+	assert.EqualError(t, ValidateJobMetricArgs(metrics, args), "metric 'integration-test': container 'test' references args.region which is not an argument")
+
+	metrics = newMetrics(corev1.Container{
+		Name: "test",
+		Args: []string{"--env", "{{args.environment"},
+	})
+	err := ValidateJobMetricArgs(metrics, args)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "metric 'integration-test': invalid template in container 'test'")
 }
@@ -21,9 +21,21 @@ func GetCurrentAnalysisRunByType(currentArs []*v1alpha1.AnalysisRun, kind string
 func FilterCurrentRolloutAnalysisRuns(analysisRuns []*v1alpha1.AnalysisRun, r *v1alpha1.Rollout) (CurrentAnalysisRuns, []*v1alpha1.AnalysisRun) {
 	currArs := CurrentAnalysisRuns{}
 	otherArs := []*v1alpha1.AnalysisRun{}
+	previewTrafficRampAnalysisRun := ""
+	if r.Status.BlueGreen.PreviewTrafficRampAnalysisRunStatus != nil {
+		previewTrafficRampAnalysisRun = r.Status.BlueGreen.PreviewTrafficRampAnalysisRunStatus.Name
+	}
+	scaleDownDrainAnalysisRun := ""
+	if r.Status.BlueGreen.ScaleDownDrainAnalysisRunStatus != nil {
+		scaleDownDrainAnalysisRun = r.Status.BlueGreen.ScaleDownDrainAnalysisRunStatus.Name
+	}
 	for i := range analysisRuns {
 		ar := analysisRuns[i]
 		if ar != nil {
+			if isAggregatedStepAnalysisRun(ar, r) {
+				currArs.CanaryAggregatedStep = append(currArs.CanaryAggregatedStep, ar)
+				continue
+			}
 			switch ar.Name {
 			case r.Status.Canary.CurrentStepAnalysisRun:
 				currArs.CanaryStep = ar
@@ -33,6 +45,10 @@ func FilterCurrentRolloutAnalysisRuns(analysisRuns []*v1alpha1.AnalysisRun, r *v
 				currArs.BlueGreenPrePromotion = ar
 			case r.Status.BlueGreen.PostPromotionAnalysisRun:
 				currArs.BlueGreenPostPromotion = ar
+			case previewTrafficRampAnalysisRun:
+				currArs.BlueGreenPreviewTrafficRamp = ar
+			case scaleDownDrainAnalysisRun:
+				currArs.BlueGreenScaleDownDrain = ar
 			default:
 				otherArs = append(otherArs, ar)
 			}
@@ -41,6 +57,17 @@ func FilterCurrentRolloutAnalysisRuns(analysisRuns []*v1alpha1.AnalysisRun, r *v
 	return currArs, otherArs
 }
 
+// isAggregatedStepAnalysisRun returns whether the AnalysisRun is one of the AnalysisRuns of the current aggregated
+// canary analysis step
+func isAggregatedStepAnalysisRun(ar *v1alpha1.AnalysisRun, r *v1alpha1.Rollout) bool {
+	for _, arStatus := range r.Status.Canary.CurrentStepAnalysisRuns {
+		if arStatus.Name == ar.Name {
+			return true
+		}
+	}
+	return false
+}
+
 // FilterAnalysisRunsByRolloutType returns a list of analysisRuns that have the rollout-type of the typeFilter
 func FilterAnalysisRunsByRolloutType(analysisRuns []*v1alpha1.AnalysisRun, typeFilter string) []*v1alpha1.AnalysisRun {
 	analysisRunsByType, _ := FilterAnalysisRuns(analysisRuns, func(ar *v1alpha1.AnalysisRun) bool {
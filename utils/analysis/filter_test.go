@@ -74,6 +74,25 @@ func TestFilterCurrentRolloutAnalysisRuns(t *testing.T) {
 		assert.Nil(t, currentArs.BlueGreenPrePromotion)
 
 	})
+	t.Run("CanaryAggregatedStep", func(t *testing.T) {
+		r := &v1alpha1.Rollout{
+			Status: v1alpha1.RolloutStatus{
+				Canary: v1alpha1.CanaryStatus{
+					CurrentStepAnalysisRuns: []v1alpha1.RolloutAnalysisRunStatus{
+						{Name: "foo", Status: v1alpha1.AnalysisPhaseSuccessful},
+						{Name: "baz", Status: v1alpha1.AnalysisPhaseRunning},
+					},
+					CurrentBackgroundAnalysisRun: "bar",
+				},
+			},
+		}
+		currentArs, nonCurrentArs := FilterCurrentRolloutAnalysisRuns(ars, r)
+		assert.Empty(t, nonCurrentArs)
+		assert.Equal(t, []*v1alpha1.AnalysisRun{ars[0], ars[2]}, currentArs.CanaryAggregatedStep)
+		assert.Equal(t, currentArs.CanaryBackground, ars[1])
+		assert.Nil(t, currentArs.CanaryStep)
+		assert.Len(t, currentArs.ToArray(), 3)
+	})
 	t.Run("BlueGreen", func(t *testing.T) {
 		r := &v1alpha1.Rollout{
 			Status: v1alpha1.RolloutStatus{
@@ -90,6 +109,35 @@ func TestFilterCurrentRolloutAnalysisRuns(t *testing.T) {
 		assert.Nil(t, currentArs.CanaryBackground)
 		assert.Nil(t, currentArs.CanaryStep)
 	})
+	t.Run("BlueGreenPreviewTrafficRamp", func(t *testing.T) {
+		r := &v1alpha1.Rollout{
+			Status: v1alpha1.RolloutStatus{
+				BlueGreen: v1alpha1.BlueGreenStatus{
+					PreviewTrafficRampAnalysisRunStatus: &v1alpha1.RolloutAnalysisRunStatus{Name: "baz"},
+				},
+			},
+		}
+		currentArs, nonCurrentArs := FilterCurrentRolloutAnalysisRuns(ars, r)
+		assert.Len(t, nonCurrentArs, 2)
+		assert.Equal(t, currentArs.BlueGreenPreviewTrafficRamp, ars[2])
+		assert.Nil(t, currentArs.BlueGreenPrePromotion)
+		assert.Nil(t, currentArs.BlueGreenPostPromotion)
+	})
+	t.Run("BlueGreenScaleDownDrain", func(t *testing.T) {
+		r := &v1alpha1.Rollout{
+			Status: v1alpha1.RolloutStatus{
+				BlueGreen: v1alpha1.BlueGreenStatus{
+					PostPromotionAnalysisRun:        "foo",
+					ScaleDownDrainAnalysisRunStatus: &v1alpha1.RolloutAnalysisRunStatus{Name: "baz"},
+				},
+			},
+		}
+		currentArs, nonCurrentArs := FilterCurrentRolloutAnalysisRuns(ars, r)
+		assert.Len(t, nonCurrentArs, 1)
+		assert.Equal(t, currentArs.BlueGreenScaleDownDrain, ars[2])
+		assert.Equal(t, currentArs.BlueGreenPostPromotion, ars[0])
+		assert.Len(t, currentArs.ToArray(), 2)
+	})
 }
 
 func TestFilterAnalysisRunsByName(t *testing.T) {
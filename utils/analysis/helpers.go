@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash/fnv"
+	"strings"
 
 	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
 	log "github.com/sirupsen/logrus"
@@ -12,16 +14,22 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	patchtypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/rand"
 
 	argoprojclient "github.com/argoproj/argo-rollouts/pkg/client/clientset/versioned/typed/rollouts/v1alpha1"
+	templateutil "github.com/argoproj/argo-rollouts/utils/template"
 )
 
 // CurrentAnalysisRuns holds all the current analysis runs for a Rollout
 type CurrentAnalysisRuns struct {
-	BlueGreenPrePromotion  *v1alpha1.AnalysisRun
-	BlueGreenPostPromotion *v1alpha1.AnalysisRun
-	CanaryStep             *v1alpha1.AnalysisRun
-	CanaryBackground       *v1alpha1.AnalysisRun
+	BlueGreenPrePromotion       *v1alpha1.AnalysisRun
+	BlueGreenPostPromotion      *v1alpha1.AnalysisRun
+	BlueGreenPreviewTrafficRamp *v1alpha1.AnalysisRun
+	BlueGreenScaleDownDrain     *v1alpha1.AnalysisRun
+	CanaryStep                  *v1alpha1.AnalysisRun
+	CanaryBackground            *v1alpha1.AnalysisRun
+	// CanaryAggregatedStep are the AnalysisRuns of the templates of a canary analysis step aggregating its templates
+	CanaryAggregatedStep []*v1alpha1.AnalysisRun
 }
 
 func (c CurrentAnalysisRuns) ToArray() []*v1alpha1.AnalysisRun {
@@ -32,12 +40,19 @@ func (c CurrentAnalysisRuns) ToArray() []*v1alpha1.AnalysisRun {
 	if c.BlueGreenPrePromotion != nil {
 		currentAnalysisRuns = append(currentAnalysisRuns, c.BlueGreenPrePromotion)
 	}
+	if c.BlueGreenPreviewTrafficRamp != nil {
+		currentAnalysisRuns = append(currentAnalysisRuns, c.BlueGreenPreviewTrafficRamp)
+	}
+	if c.BlueGreenScaleDownDrain != nil {
+		currentAnalysisRuns = append(currentAnalysisRuns, c.BlueGreenScaleDownDrain)
+	}
 	if c.CanaryStep != nil {
 		currentAnalysisRuns = append(currentAnalysisRuns, c.CanaryStep)
 	}
 	if c.CanaryBackground != nil {
 		currentAnalysisRuns = append(currentAnalysisRuns, c.CanaryBackground)
 	}
+	currentAnalysisRuns = append(currentAnalysisRuns, c.CanaryAggregatedStep...)
 	return currentAnalysisRuns
 }
 
@@ -84,13 +99,42 @@ func IsTerminating(run *v1alpha1.AnalysisRun) bool {
 	}
 	for _, res := range run.Status.MetricResults {
 		switch res.Phase {
-		case v1alpha1.AnalysisPhaseFailed, v1alpha1.AnalysisPhaseError, v1alpha1.AnalysisPhaseInconclusive:
+		case v1alpha1.AnalysisPhaseFailed, v1alpha1.AnalysisPhaseInconclusive:
 			return true
+		case v1alpha1.AnalysisPhaseError:
+			// The other metrics of a run whose errored metrics are inconclusive decide the phase of the run
+			if run.Spec.MetricErrorPolicy != v1alpha1.MetricErrorPolicyInconclusive {
+				return true
+			}
 		}
 	}
 	return false
 }
 
+// IsPassing returns whether or not the analysis run is currently passing: the run is running or successful, and the
+// latest completed measurement of every metric is successful.
+func IsPassing(run *v1alpha1.AnalysisRun) bool {
+	if run == nil || len(run.Status.MetricResults) == 0 {
+		return false
+	}
+	if run.Status.Phase != v1alpha1.AnalysisPhaseRunning && run.Status.Phase != v1alpha1.AnalysisPhaseSuccessful {
+		return false
+	}
+	for _, res := range run.Status.MetricResults {
+		passing := false
+		for i := len(res.Measurements) - 1; i >= 0; i-- {
+			if res.Measurements[i].Phase.Completed() {
+				passing = res.Measurements[i].Phase == v1alpha1.AnalysisPhaseSuccessful
+				break
+			}
+		}
+		if !passing {
+			return false
+		}
+	}
+	return true
+}
+
 // GetResult returns the metric result by name
 func GetResult(run *v1alpha1.AnalysisRun, metricName string) *v1alpha1.MetricResult {
 	for _, result := range run.Status.MetricResults {
@@ -182,6 +226,35 @@ func MergeArgs(incomingArgs, templateArgs []v1alpha1.Argument) ([]v1alpha1.Argum
 	return newArgs, nil
 }
 
+// ResolveMetricIntervals returns a copy of the metrics with the arguments referenced by their intervals substituted.
+// The intervals are resolved when the AnalysisRun is created, so that the runs created from the same template can
+// measure at different intervals (e.g. a coarse interval at the first steps of a canary and a fine one at the last).
+func ResolveMetricIntervals(metrics []v1alpha1.Metric, args []v1alpha1.Argument) ([]v1alpha1.Metric, error) {
+	if metrics == nil {
+		return nil, nil
+	}
+	valueArgs := make([]v1alpha1.Argument, 0, len(args))
+	for _, arg := range args {
+		if arg.Value != nil {
+			valueArgs = append(valueArgs, arg)
+		}
+	}
+	resolved := make([]v1alpha1.Metric, len(metrics))
+	copy(resolved, metrics)
+	for i := range resolved {
+		interval := string(resolved[i].Interval)
+		if !strings.Contains(interval, "{{") {
+			continue
+		}
+		value, err := templateutil.ResolveArgs(interval, valueArgs)
+		if err != nil {
+			return nil, fmt.Errorf("metric '%s': unable to resolve the interval: %v", resolved[i].Name, err)
+		}
+		resolved[i].Interval = v1alpha1.DurationString(strings.TrimSpace(value))
+	}
+	return resolved, nil
+}
+
 // CreateWithCollisionCounter attempts to create the given analysisrun and if an AlreadyExists error
 // is encountered, and the existing run is semantically equal and running, returns the exiting run.
 func CreateWithCollisionCounter(logCtx *log.Entry, analysisRunIf argoprojclient.AnalysisRunInterface, run v1alpha1.AnalysisRun) (*v1alpha1.AnalysisRun, error) {
@@ -227,6 +300,10 @@ func NewAnalysisRunFromTemplates(templates []*v1alpha1.AnalysisTemplate, cluster
 	if err != nil {
 		return nil, err
 	}
+	metrics, err := ResolveMetricIntervals(template.Spec.Metrics, newArgs)
+	if err != nil {
+		return nil, err
+	}
 	ar := v1alpha1.AnalysisRun{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:         name,
@@ -234,13 +311,35 @@ func NewAnalysisRunFromTemplates(templates []*v1alpha1.AnalysisTemplate, cluster
 			Namespace:    namespace,
 		},
 		Spec: v1alpha1.AnalysisRunSpec{
-			Metrics: template.Spec.Metrics,
+			Metrics: metrics,
 			Args:    newArgs,
 		},
 	}
 	return &ar, nil
 }
 
+// ComputeTemplatesHash returns a hash value calculated from the specs of the templates, which detects a change of
+// the templates snapshotted in an AnalysisRun. The hash will be safe encoded to avoid bad words.
+func ComputeTemplatesHash(templates []*v1alpha1.AnalysisTemplate, clusterTemplates []*v1alpha1.ClusterAnalysisTemplate) string {
+	specs := make([]v1alpha1.AnalysisTemplateSpec, 0, len(templates)+len(clusterTemplates))
+	for _, template := range templates {
+		specs = append(specs, template.Spec)
+	}
+	for _, template := range clusterTemplates {
+		specs = append(specs, template.Spec)
+	}
+	specsBytes, err := json.Marshal(specs)
+	if err != nil {
+		panic(err)
+	}
+	templatesHasher := fnv.New32a()
+	_, err = templatesHasher.Write(specsBytes)
+	if err != nil {
+		panic(err)
+	}
+	return rand.SafeEncodeString(fmt.Sprint(templatesHasher.Sum32()))
+}
+
 func FlattenTemplates(templates []*v1alpha1.AnalysisTemplate, clusterTemplates []*v1alpha1.ClusterAnalysisTemplate) (*v1alpha1.AnalysisTemplate, error) {
 	metrics, err := flattenMetrics(templates, clusterTemplates)
 	if err != nil {
@@ -326,12 +425,109 @@ func flattenMetrics(templates []*v1alpha1.AnalysisTemplate, clusterTemplates []*
 	return metrics, nil
 }
 
+// ExpandMetricMixins returns the template spec with its metric mixins replaced by the metrics they include. The
+// arguments overridden by a mixin are substituted in its metric, while the other arguments of the
+// ClusterAnalysisTemplate referenced by the metric are added to the arguments of the spec.
+func ExpandMetricMixins(spec v1alpha1.AnalysisTemplateSpec, getClusterTemplate func(name string) (*v1alpha1.ClusterAnalysisTemplate, error)) (v1alpha1.AnalysisTemplateSpec, error) {
+	if len(spec.MetricMixins) == 0 {
+		return spec, nil
+	}
+	metrics := append(spec.Metrics[:0:0], spec.Metrics...)
+	args := append(spec.Args[:0:0], spec.Args...)
+	metricNames := make(map[string]bool)
+	for _, metric := range metrics {
+		metricNames[metric.Name] = true
+	}
+	for _, mixin := range spec.MetricMixins {
+		clusterTemplate, err := getClusterTemplate(mixin.ClusterTemplateName)
+		if err != nil {
+			return spec, err
+		}
+		metric, mixinArgs, err := expandMetricMixin(mixin, clusterTemplate)
+		if err != nil {
+			return spec, err
+		}
+		if metricNames[metric.Name] {
+			return spec, fmt.Errorf("metric mixin '%s' of ClusterAnalysisTemplate '%s' collides with another metric named '%s'", mixin.Metric, mixin.ClusterTemplateName, metric.Name)
+		}
+		metricNames[metric.Name] = true
+		metrics = append(metrics, *metric)
+		for _, arg := range mixinArgs {
+			i := findArg(arg.Name, args)
+			if i < 0 {
+				args = append(args, arg)
+				continue
+			}
+			if arg.Value != nil && args[i].Value != nil && *arg.Value != *args[i].Value {
+				return spec, fmt.Errorf("metric mixin '%s' of ClusterAnalysisTemplate '%s' has the argument %s with a different value", mixin.Metric, mixin.ClusterTemplateName, arg.Name)
+			}
+			if args[i].Value == nil && args[i].ValueFrom == nil {
+				args[i] = arg
+			}
+		}
+	}
+	return v1alpha1.AnalysisTemplateSpec{
+		Metrics: metrics,
+		Args:    args,
+	}, nil
+}
+
+// expandMetricMixin returns the metric included by the mixin, with the overridden arguments substituted, and the
+// arguments of the ClusterAnalysisTemplate still referenced by the metric
+func expandMetricMixin(mixin v1alpha1.MetricMixin, clusterTemplate *v1alpha1.ClusterAnalysisTemplate) (*v1alpha1.Metric, []v1alpha1.Argument, error) {
+	var metric *v1alpha1.Metric
+	for i := range clusterTemplate.Spec.Metrics {
+		if clusterTemplate.Spec.Metrics[i].Name == mixin.Metric {
+			metric = &clusterTemplate.Spec.Metrics[i]
+			break
+		}
+	}
+	if metric == nil {
+		return nil, nil, fmt.Errorf("ClusterAnalysisTemplate '%s' has no metric named '%s'", mixin.ClusterTemplateName, mixin.Metric)
+	}
+	for _, arg := range mixin.Args {
+		if findArg(arg.Name, clusterTemplate.Spec.Args) < 0 {
+			return nil, nil, fmt.Errorf("metric mixin '%s' overrides args.%s which is not an argument of ClusterAnalysisTemplate '%s'", mixin.Metric, arg.Name, mixin.ClusterTemplateName)
+		}
+	}
+	metricBytes, err := json.Marshal(metric)
+	if err != nil {
+		return nil, nil, err
+	}
+	metricStr, err := templateutil.ResolveSuppliedQuotedArgs(string(metricBytes), mixin.Args)
+	if err != nil {
+		return nil, nil, fmt.Errorf("metric mixin '%s': %v", mixin.Metric, err)
+	}
+	var expanded v1alpha1.Metric
+	if err := json.Unmarshal([]byte(metricStr), &expanded); err != nil {
+		return nil, nil, err
+	}
+	if mixin.Name != "" {
+		expanded.Name = mixin.Name
+	}
+	referencedArgs, err := templateutil.ReferencedArgs(metricStr)
+	if err != nil {
+		return nil, nil, err
+	}
+	var args []v1alpha1.Argument
+	for _, arg := range clusterTemplate.Spec.Args {
+		if referencedArgs[arg.Name] {
+			args = append(args, arg)
+		}
+	}
+	return &expanded, args, nil
+}
+
 //TODO(dthomson) remove v0.9.0
 func NewAnalysisRunFromClusterTemplate(template *v1alpha1.ClusterAnalysisTemplate, args []v1alpha1.Argument, name, generateName, namespace string) (*v1alpha1.AnalysisRun, error) {
 	newArgs, err := MergeArgs(args, template.Spec.Args)
 	if err != nil {
 		return nil, err
 	}
+	metrics, err := ResolveMetricIntervals(template.Spec.Metrics, newArgs)
+	if err != nil {
+		return nil, err
+	}
 	ar := v1alpha1.AnalysisRun{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:         name,
@@ -339,7 +535,7 @@ func NewAnalysisRunFromClusterTemplate(template *v1alpha1.ClusterAnalysisTemplat
 			Namespace:    namespace,
 		},
 		Spec: v1alpha1.AnalysisRunSpec{
-			Metrics: template.Spec.Metrics,
+			Metrics: metrics,
 			Args:    newArgs,
 		},
 	}
@@ -352,6 +548,10 @@ func NewAnalysisRunFromTemplate(template *v1alpha1.AnalysisTemplate, args []v1al
 	if err != nil {
 		return nil, err
 	}
+	metrics, err := ResolveMetricIntervals(template.Spec.Metrics, newArgs)
+	if err != nil {
+		return nil, err
+	}
 	ar := v1alpha1.AnalysisRun{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:         name,
@@ -359,7 +559,7 @@ func NewAnalysisRunFromTemplate(template *v1alpha1.AnalysisTemplate, args []v1al
 			Namespace:    namespace,
 		},
 		Spec: v1alpha1.AnalysisRunSpec{
-			Metrics: template.Spec.Metrics,
+			Metrics: metrics,
 			Args:    newArgs,
 		},
 	}
@@ -131,6 +131,37 @@ func TestMetricCompleted(t *testing.T) {
 	assert.True(t, MetricCompleted(run, "success-rate"))
 }
 
+func TestIsPassing(t *testing.T) {
+	successful := v1alpha1.Measurement{Phase: v1alpha1.AnalysisPhaseSuccessful}
+	failed := v1alpha1.Measurement{Phase: v1alpha1.AnalysisPhaseFailed}
+	running := v1alpha1.Measurement{Phase: v1alpha1.AnalysisPhaseRunning}
+	newRun := func(phase v1alpha1.AnalysisPhase, measurements ...[]v1alpha1.Measurement) *v1alpha1.AnalysisRun {
+		run := &v1alpha1.AnalysisRun{
+			Status: v1alpha1.AnalysisRunStatus{
+				Phase: phase,
+			},
+		}
+		for i := range measurements {
+			run.Status.MetricResults = append(run.Status.MetricResults, v1alpha1.MetricResult{
+				Name:         fmt.Sprintf("metric-%d", i),
+				Phase:        v1alpha1.AnalysisPhaseRunning,
+				Measurements: measurements[i],
+			})
+		}
+		return run
+	}
+	assert.False(t, IsPassing(nil))
+	assert.False(t, IsPassing(newRun(v1alpha1.AnalysisPhaseRunning)))
+	assert.True(t, IsPassing(newRun(v1alpha1.AnalysisPhaseRunning, []v1alpha1.Measurement{failed, successful})))
+	assert.True(t, IsPassing(newRun(v1alpha1.AnalysisPhaseSuccessful, []v1alpha1.Measurement{successful})))
+	// the measurement in progress is ignored in favor of the latest completed one
+	assert.True(t, IsPassing(newRun(v1alpha1.AnalysisPhaseRunning, []v1alpha1.Measurement{successful, running})))
+	assert.False(t, IsPassing(newRun(v1alpha1.AnalysisPhaseRunning, []v1alpha1.Measurement{successful, failed})))
+	assert.False(t, IsPassing(newRun(v1alpha1.AnalysisPhaseRunning, []v1alpha1.Measurement{running})))
+	assert.False(t, IsPassing(newRun(v1alpha1.AnalysisPhaseRunning, []v1alpha1.Measurement{successful}, []v1alpha1.Measurement{})))
+	assert.False(t, IsPassing(newRun(v1alpha1.AnalysisPhaseInconclusive, []v1alpha1.Measurement{successful})))
+}
+
 func TestLastMeasurement(t *testing.T) {
 	m1 := v1alpha1.Measurement{
 		Phase: v1alpha1.AnalysisPhaseSuccessful,
@@ -184,6 +215,13 @@ func TestIsTerminating(t *testing.T) {
 	successRate.Phase = v1alpha1.AnalysisPhaseError
 	run.Status.MetricResults[1] = successRate
 	assert.True(t, IsTerminating(run))
+
+	// an errored metric does not terminate a run whose errored metrics are inconclusive
+	run.Spec.MetricErrorPolicy = v1alpha1.MetricErrorPolicyInconclusive
+	assert.False(t, IsTerminating(run))
+	successRate.Phase = v1alpha1.AnalysisPhaseFailed
+	run.Status.MetricResults[1] = successRate
+	assert.True(t, IsTerminating(run))
 }
 
 func TestTerminateRun(t *testing.T) {
@@ -305,6 +343,26 @@ func TestCreateWithCollisionCounter(t *testing.T) {
 	assert.Equal(t, run.Name+".1", createdRun.Name)
 }
 
+func TestComputeTemplatesHash(t *testing.T) {
+	template := &v1alpha1.AnalysisTemplate{
+		Spec: v1alpha1.AnalysisTemplateSpec{
+			Metrics: []v1alpha1.Metric{{Name: "success-rate", SuccessCondition: "result > 0.95"}},
+		},
+	}
+	clusterTemplate := &v1alpha1.ClusterAnalysisTemplate{
+		Spec: v1alpha1.AnalysisTemplateSpec{
+			Metrics: []v1alpha1.Metric{{Name: "latency", SuccessCondition: "result < 0.5"}},
+		},
+	}
+	hash := ComputeTemplatesHash([]*v1alpha1.AnalysisTemplate{template}, []*v1alpha1.ClusterAnalysisTemplate{clusterTemplate})
+	assert.Equal(t, hash, ComputeTemplatesHash([]*v1alpha1.AnalysisTemplate{template.DeepCopy()}, []*v1alpha1.ClusterAnalysisTemplate{clusterTemplate.DeepCopy()}))
+
+	changedTemplate := template.DeepCopy()
+	changedTemplate.Spec.Metrics[0].SuccessCondition = "result > 0.99"
+	assert.NotEqual(t, hash, ComputeTemplatesHash([]*v1alpha1.AnalysisTemplate{changedTemplate}, []*v1alpha1.ClusterAnalysisTemplate{clusterTemplate}))
+	assert.NotEqual(t, hash, ComputeTemplatesHash([]*v1alpha1.AnalysisTemplate{template}, nil))
+}
+
 func TestFlattenTemplates(t *testing.T) {
 	metric := func(name, successCondition string) v1alpha1.Metric {
 		return v1alpha1.Metric{
@@ -462,6 +520,180 @@ func TestFlattenTemplates(t *testing.T) {
 	})
 }
 
+func TestExpandMetricMixins(t *testing.T) {
+	latency := &v1alpha1.ClusterAnalysisTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "standard-latency"},
+		Spec: v1alpha1.AnalysisTemplateSpec{
+			Metrics: []v1alpha1.Metric{{
+				Name:             "latency",
+				SuccessCondition: "result < {{args.threshold}}",
+				Provider: v1alpha1.MetricProvider{
+					Prometheus: &v1alpha1.PrometheusMetric{
+						Query: `histogram_quantile(0.99, rate(latency_bucket{service="{{args.service}}"}[5m]))`,
+					},
+				},
+			}, {
+				Name:             "error-rate",
+				SuccessCondition: "result < 0.01",
+			}},
+			Args: []v1alpha1.Argument{
+				{Name: "service"},
+				{Name: "threshold", Value: pointer.StringPtr("0.5")},
+				{Name: "unused"},
+			},
+		},
+	}
+	getClusterTemplate := func(name string) (*v1alpha1.ClusterAnalysisTemplate, error) {
+		if name != latency.Name {
+			return nil, fmt.Errorf("ClusterAnalysisTemplate '%s' not found", name)
+		}
+		return latency, nil
+	}
+	newSpec := func(mixins ...v1alpha1.MetricMixin) v1alpha1.AnalysisTemplateSpec {
+		return v1alpha1.AnalysisTemplateSpec{
+			Metrics: []v1alpha1.Metric{{
+				Name:             "success-rate",
+				SuccessCondition: "result > 0.99",
+			}},
+			Args:         []v1alpha1.Argument{{Name: "service"}},
+			MetricMixins: mixins,
+		}
+	}
+
+	t.Run("No mixins", func(t *testing.T) {
+		spec := newSpec()
+		expanded, err := ExpandMetricMixins(spec, getClusterTemplate)
+		assert.NoError(t, err)
+		assert.Equal(t, spec, expanded)
+	})
+	t.Run("Expand mixin with overridden args", func(t *testing.T) {
+		spec := newSpec(v1alpha1.MetricMixin{
+			ClusterTemplateName: latency.Name,
+			Metric:              "latency",
+			Args:                []v1alpha1.Argument{{Name: "threshold", Value: pointer.StringPtr("0.2")}},
+		})
+		expanded, err := ExpandMetricMixins(spec, getClusterTemplate)
+		assert.NoError(t, err)
+		assert.Nil(t, expanded.MetricMixins)
+		assert.Len(t, expanded.Metrics, 2)
+		assert.Equal(t, spec.Metrics[0], expanded.Metrics[0])
+		assert.Equal(t, "latency", expanded.Metrics[1].Name)
+		assert.Equal(t, "result < 0.2", expanded.Metrics[1].SuccessCondition)
+		assert.Equal(t, `histogram_quantile(0.99, rate(latency_bucket{service="{{args.service}}"}[5m]))`, expanded.Metrics[1].Provider.Prometheus.Query)
+		// The overridden and unreferenced arguments are not added to the spec
+		assert.Equal(t, []v1alpha1.Argument{{Name: "service"}}, expanded.Args)
+		// The cluster template is left untouched
+		assert.Equal(t, "result < {{args.threshold}}", latency.Spec.Metrics[0].SuccessCondition)
+	})
+	t.Run("Expand mixin with default args", func(t *testing.T) {
+		spec := newSpec(v1alpha1.MetricMixin{
+			ClusterTemplateName: latency.Name,
+			Metric:              "latency",
+		})
+		spec.Args = nil
+		expanded, err := ExpandMetricMixins(spec, getClusterTemplate)
+		assert.NoError(t, err)
+		assert.Equal(t, "result < {{args.threshold}}", expanded.Metrics[1].SuccessCondition)
+		assert.Equal(t, []v1alpha1.Argument{{Name: "service"}, {Name: "threshold", Value: pointer.StringPtr("0.5")}}, expanded.Args)
+	})
+	t.Run("Include the same metric twice under different names", func(t *testing.T) {
+		spec := newSpec(v1alpha1.MetricMixin{
+			ClusterTemplateName: latency.Name,
+			Metric:              "latency",
+			Name:                "checkout-latency",
+			Args:                []v1alpha1.Argument{{Name: "service", Value: pointer.StringPtr("checkout")}},
+		}, v1alpha1.MetricMixin{
+			ClusterTemplateName: latency.Name,
+			Metric:              "latency",
+			Name:                "payment-latency",
+			Args:                []v1alpha1.Argument{{Name: "service", Value: pointer.StringPtr("payment")}},
+		})
+		expanded, err := ExpandMetricMixins(spec, getClusterTemplate)
+		assert.NoError(t, err)
+		assert.Len(t, expanded.Metrics, 3)
+		assert.Equal(t, "checkout-latency", expanded.Metrics[1].Name)
+		assert.Equal(t, `histogram_quantile(0.99, rate(latency_bucket{service="checkout"}[5m]))`, expanded.Metrics[1].Provider.Prometheus.Query)
+		assert.Equal(t, "payment-latency", expanded.Metrics[2].Name)
+		assert.Equal(t, `histogram_quantile(0.99, rate(latency_bucket{service="payment"}[5m]))`, expanded.Metrics[2].Provider.Prometheus.Query)
+		assert.Equal(t, []v1alpha1.Argument{{Name: "service"}, {Name: "threshold", Value: pointer.StringPtr("0.5")}}, expanded.Args)
+	})
+	t.Run("Fail on metric name collision", func(t *testing.T) {
+		spec := newSpec(v1alpha1.MetricMixin{
+			ClusterTemplateName: latency.Name,
+			Metric:              "error-rate",
+			Name:                "success-rate",
+		})
+		_, err := ExpandMetricMixins(spec, getClusterTemplate)
+		assert.EqualError(t, err, "metric mixin 'error-rate' of ClusterAnalysisTemplate 'standard-latency' collides with another metric named 'success-rate'")
+
+		spec = newSpec(v1alpha1.MetricMixin{
+			ClusterTemplateName: latency.Name,
+			Metric:              "error-rate",
+		}, v1alpha1.MetricMixin{
+			ClusterTemplateName: latency.Name,
+			Metric:              "error-rate",
+		})
+		_, err = ExpandMetricMixins(spec, getClusterTemplate)
+		assert.EqualError(t, err, "metric mixin 'error-rate' of ClusterAnalysisTemplate 'standard-latency' collides with another metric named 'error-rate'")
+	})
+	t.Run("Fail on argument collision", func(t *testing.T) {
+		spec := newSpec(v1alpha1.MetricMixin{
+			ClusterTemplateName: latency.Name,
+			Metric:              "latency",
+		})
+		spec.Args = append(spec.Args, v1alpha1.Argument{Name: "threshold", Value: pointer.StringPtr("0.3")})
+		_, err := ExpandMetricMixins(spec, getClusterTemplate)
+		assert.EqualError(t, err, "metric mixin 'latency' of ClusterAnalysisTemplate 'standard-latency' has the argument threshold with a different value")
+	})
+	t.Run("Fail on unknown metric or argument", func(t *testing.T) {
+		_, err := ExpandMetricMixins(newSpec(v1alpha1.MetricMixin{
+			ClusterTemplateName: latency.Name,
+			Metric:              "throughput",
+		}), getClusterTemplate)
+		assert.EqualError(t, err, "ClusterAnalysisTemplate 'standard-latency' has no metric named 'throughput'")
+
+		_, err = ExpandMetricMixins(newSpec(v1alpha1.MetricMixin{
+			ClusterTemplateName: latency.Name,
+			Metric:              "latency",
+			Args:                []v1alpha1.Argument{{Name: "region", Value: pointer.StringPtr("us-east-1")}},
+		}), getClusterTemplate)
+		assert.EqualError(t, err, "metric mixin 'latency' overrides args.region which is not an argument of ClusterAnalysisTemplate 'standard-latency'")
+
+		_, err = ExpandMetricMixins(newSpec(v1alpha1.MetricMixin{
+			ClusterTemplateName: latency.Name,
+			Metric:              "latency",
+			Args:                []v1alpha1.Argument{{Name: "threshold"}},
+		}), getClusterTemplate)
+		assert.EqualError(t, err, "metric mixin 'latency': argument \"threshold\" was not supplied")
+
+		_, err = ExpandMetricMixins(newSpec(v1alpha1.MetricMixin{
+			ClusterTemplateName: "missing",
+			Metric:              "latency",
+		}), getClusterTemplate)
+		assert.EqualError(t, err, "ClusterAnalysisTemplate 'missing' not found")
+	})
+}
+
+func TestResolveMetricIntervals(t *testing.T) {
+	metrics := []v1alpha1.Metric{
+		{Name: "coarse", Interval: "{{ args.interval }}"},
+		{Name: "fixed", Interval: "1m"},
+	}
+	args := []v1alpha1.Argument{
+		{Name: "interval", Value: pointer.StringPtr("5m")},
+		{Name: "my-secret", ValueFrom: &v1alpha1.ValueFrom{SecretKeyRef: &v1alpha1.SecretKeyRef{Name: "name", Key: "key"}}},
+	}
+	resolved, err := ResolveMetricIntervals(metrics, args)
+	assert.NoError(t, err)
+	assert.Equal(t, v1alpha1.DurationString("5m"), resolved[0].Interval)
+	assert.Equal(t, v1alpha1.DurationString("1m"), resolved[1].Interval)
+	// The metrics of the template are not modified
+	assert.Equal(t, v1alpha1.DurationString("{{ args.interval }}"), metrics[0].Interval)
+
+	_, err = ResolveMetricIntervals(metrics, args[1:])
+	assert.EqualError(t, err, "metric 'coarse': unable to resolve the interval: failed to resolve {{ args.interval }}")
+}
+
 func TestNewAnalysisRunFromTemplates(t *testing.T) {
 	templates := []*v1alpha1.AnalysisTemplate{{
 		ObjectMeta: metav1.ObjectMeta{
@@ -0,0 +1,81 @@
+package analysis
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+)
+
+// SummarizeMetrics returns the summary of each metric of the run. The numbers of measurements come from the counters
+// of the metric results, while the minimum, maximum and average are computed from the numeric values of the
+// measurements still retained in the status, since the older measurements are garbage collected.
+func SummarizeMetrics(run *v1alpha1.AnalysisRun) []v1alpha1.MetricSummary {
+	summaries := make([]v1alpha1.MetricSummary, 0, len(run.Status.MetricResults))
+	for _, metric := range run.Spec.Metrics {
+		result := GetResult(run, metric.Name)
+		if result == nil {
+			continue
+		}
+		summaries = append(summaries, SummarizeMetricResult(*result))
+	}
+	return summaries
+}
+
+// SummarizeMetricResult returns the summary of the measurements of a metric result
+func SummarizeMetricResult(result v1alpha1.MetricResult) v1alpha1.MetricSummary {
+	summary := v1alpha1.MetricSummary{
+		Name:         result.Name,
+		Phase:        result.Phase,
+		Measurements: result.Count + result.Error,
+		Successful:   result.Successful,
+		Failed:       result.Failed,
+		Inconclusive: result.Inconclusive,
+		Error:        result.Error,
+	}
+	var min, max, sum float64
+	numericValues := 0
+	for _, measurement := range result.Measurements {
+		if measurement.Phase == v1alpha1.AnalysisPhaseError {
+			continue
+		}
+		value, ok := numericValue(measurement.Value)
+		if !ok {
+			continue
+		}
+		if numericValues == 0 || value < min {
+			min = value
+		}
+		if numericValues == 0 || value > max {
+			max = value
+		}
+		sum += value
+		numericValues++
+	}
+	if numericValues > 0 {
+		summary.Min = formatFloat(min)
+		summary.Max = formatFloat(max)
+		// The average is rounded to 6 decimals to keep the summary readable
+		summary.Avg = formatFloat(math.Round(sum/float64(numericValues)*1e6) / 1e6)
+	}
+	return summary
+}
+
+// numericValue returns the number of a measurement value, which is either a number or a vector of a single number
+// (e.g. the result of a Prometheus query)
+func numericValue(value string) (float64, bool) {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+		value = strings.TrimSpace(value[1 : len(value)-1])
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, false
+	}
+	return f, true
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
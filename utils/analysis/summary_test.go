@@ -0,0 +1,106 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+)
+
+func TestSummarizeMetricResult(t *testing.T) {
+	result := v1alpha1.MetricResult{
+		Name:         "success-rate",
+		Phase:        v1alpha1.AnalysisPhaseFailed,
+		Count:        5,
+		Successful:   3,
+		Failed:       1,
+		Inconclusive: 1,
+		Error:        1,
+		Measurements: []v1alpha1.Measurement{
+			{Phase: v1alpha1.AnalysisPhaseSuccessful, Value: "0.99"},
+			{Phase: v1alpha1.AnalysisPhaseSuccessful, Value: "[0.97]"},
+			{Phase: v1alpha1.AnalysisPhaseError, Value: "0.1"},
+			{Phase: v1alpha1.AnalysisPhaseInconclusive, Value: "not a number"},
+			{Phase: v1alpha1.AnalysisPhaseSuccessful, Value: " 0.98 "},
+			{Phase: v1alpha1.AnalysisPhaseFailed, Value: "0.5"},
+		},
+	}
+	assert.Equal(t, v1alpha1.MetricSummary{
+		Name:         "success-rate",
+		Phase:        v1alpha1.AnalysisPhaseFailed,
+		Measurements: 6,
+		Successful:   3,
+		Failed:       1,
+		Inconclusive: 1,
+		Error:        1,
+		Min:          "0.5",
+		Max:          "0.99",
+		Avg:          "0.86",
+	}, SummarizeMetricResult(result))
+}
+
+func TestSummarizeMetricResultRoundsAverage(t *testing.T) {
+	result := v1alpha1.MetricResult{
+		Count:      3,
+		Successful: 3,
+		Measurements: []v1alpha1.Measurement{
+			{Phase: v1alpha1.AnalysisPhaseSuccessful, Value: "1"},
+			{Phase: v1alpha1.AnalysisPhaseSuccessful, Value: "1"},
+			{Phase: v1alpha1.AnalysisPhaseSuccessful, Value: "2"},
+		},
+	}
+	summary := SummarizeMetricResult(result)
+	assert.Equal(t, "1", summary.Min)
+	assert.Equal(t, "2", summary.Max)
+	assert.Equal(t, "1.333333", summary.Avg)
+}
+
+func TestSummarizeMetricResultNoNumericValue(t *testing.T) {
+	result := v1alpha1.MetricResult{
+		Count:      2,
+		Successful: 2,
+		Measurements: []v1alpha1.Measurement{
+			{Phase: v1alpha1.AnalysisPhaseSuccessful, Value: "[0.9 0.8]"},
+			{Phase: v1alpha1.AnalysisPhaseSuccessful, Value: "NaN"},
+		},
+	}
+	summary := SummarizeMetricResult(result)
+	assert.Equal(t, int32(2), summary.Measurements)
+	assert.Empty(t, summary.Min)
+	assert.Empty(t, summary.Max)
+	assert.Empty(t, summary.Avg)
+}
+
+func TestSummarizeMetrics(t *testing.T) {
+	run := &v1alpha1.AnalysisRun{
+		Spec: v1alpha1.AnalysisRunSpec{
+			Metrics: []v1alpha1.Metric{{Name: "latency"}, {Name: "not-measured"}, {Name: "success-rate"}},
+		},
+		Status: v1alpha1.AnalysisRunStatus{
+			MetricResults: []v1alpha1.MetricResult{
+				{
+					Name:         "success-rate",
+					Phase:        v1alpha1.AnalysisPhaseSuccessful,
+					Count:        1,
+					Successful:   1,
+					Measurements: []v1alpha1.Measurement{{Phase: v1alpha1.AnalysisPhaseSuccessful, Value: "0.99"}},
+				},
+				{
+					Name:         "latency",
+					Phase:        v1alpha1.AnalysisPhaseSuccessful,
+					Count:        1,
+					Successful:   1,
+					Measurements: []v1alpha1.Measurement{{Phase: v1alpha1.AnalysisPhaseSuccessful, Value: "120"}},
+				},
+			},
+		},
+	}
+	summaries := SummarizeMetrics(run)
+	// the summaries follow the order of the metrics of the spec
+	assert.Len(t, summaries, 2)
+	assert.Equal(t, "latency", summaries[0].Name)
+	assert.Equal(t, "120", summaries[0].Avg)
+	assert.Equal(t, "success-rate", summaries[1].Name)
+	assert.Equal(t, "0.99", summaries[1].Avg)
+}
@@ -25,6 +25,14 @@ const (
 	// in its replica sets. Helps in separating scaling events from the rollout process and for
 	// determining if the new replica set for a rollout is really saturated.
 	DesiredReplicasAnnotation = RolloutLabel + "/desired-replicas"
+	// AnalysisTemplateHashAnnotation is the hash of the templates snapshotted in an AnalysisRun, which detects a change
+	// of the templates while the AnalysisRun is running
+	AnalysisTemplateHashAnnotation = RolloutLabel + "/analysis-template-hash"
+	// AnalysisTemplateChangePolicyAnnotation is the template change policy of the analysis an AnalysisRun was created for
+	AnalysisTemplateChangePolicyAnnotation = RolloutLabel + "/analysis-template-change-policy"
+	// AnalysisArgAnnotationPrefix is the prefix of the annotations of a rollout overriding the value of the analysis
+	// argument named after the prefix (e.g. rollout.argoproj.io/arg-error-threshold)
+	AnalysisArgAnnotationPrefix = RolloutLabel + "/arg-"
 )
 
 // GetDesiredReplicasAnnotation returns the number of desired replicas
@@ -235,6 +235,18 @@ func RolloutComplete(rollout *v1alpha1.Rollout, newStatus *v1alpha1.RolloutStatu
 		completedStrategy
 }
 
+// RolloutHealthy considers a rollout to be healthy once it is complete, neither paused nor aborted, and its spec is
+// valid
+func RolloutHealthy(rollout *v1alpha1.Rollout) bool {
+	if GetRolloutCondition(rollout.Status, v1alpha1.InvalidSpec) != nil {
+		return false
+	}
+	if rollout.Spec.Paused || len(rollout.Status.PauseConditions) > 0 || rollout.Status.Abort {
+		return false
+	}
+	return RolloutComplete(rollout, &rollout.Status)
+}
+
 // ComputeStepHash returns a hash value calculated from the Rollout's steps. The hash will
 // be safe encoded to avoid bad words.
 func ComputeStepHash(rollout *v1alpha1.Rollout) string {
@@ -479,6 +479,52 @@ func TestRolloutComplete(t *testing.T) {
 
 }
 
+func TestRolloutHealthy(t *testing.T) {
+	newRollout := func() *v1alpha1.Rollout {
+		r := &v1alpha1.Rollout{
+			Spec: v1alpha1.RolloutSpec{
+				Replicas: pointer.Int32Ptr(1),
+				Strategy: v1alpha1.RolloutStrategy{
+					Canary: &v1alpha1.CanaryStrategy{
+						Steps: []v1alpha1.CanaryStep{{SetWeight: pointer.Int32Ptr(30)}},
+					},
+				},
+			},
+			Status: v1alpha1.RolloutStatus{
+				Replicas:          1,
+				UpdatedReplicas:   1,
+				AvailableReplicas: 1,
+				CurrentPodHash:    "abc123",
+				StableRS:          "abc123",
+				CurrentStepIndex:  pointer.Int32Ptr(1),
+			},
+		}
+		r.Status.ObservedGeneration = ComputeGenerationHash(r.Spec)
+		return r
+	}
+	assert.True(t, RolloutHealthy(newRollout()))
+
+	progressing := newRollout()
+	progressing.Status.StableRS = "def456"
+	assert.False(t, RolloutHealthy(progressing))
+
+	notObserved := newRollout()
+	notObserved.Spec.Replicas = pointer.Int32Ptr(2)
+	assert.False(t, RolloutHealthy(notObserved))
+
+	paused := newRollout()
+	paused.Status.PauseConditions = []v1alpha1.PauseCondition{{Reason: v1alpha1.PauseReasonCanaryPauseStep}}
+	assert.False(t, RolloutHealthy(paused))
+
+	aborted := newRollout()
+	aborted.Status.Abort = true
+	assert.False(t, RolloutHealthy(aborted))
+
+	invalidSpec := newRollout()
+	invalidSpec.Status.Conditions = []v1alpha1.RolloutCondition{condInvalidSpec()}
+	assert.False(t, RolloutHealthy(invalidSpec))
+}
+
 func TestRolloutTimedOut(t *testing.T) {
 
 	before := metav1.Time{
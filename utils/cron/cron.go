@@ -0,0 +1,201 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSearchYears bounds the search of the next time of a schedule, so that a schedule which never matches (e.g. on
+// February 30th) does not loop forever
+const maxSearchYears = 5
+
+// macros are the shorthands of the common schedules
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var monthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var dayNames = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+// field describes the range and the names of the values of a field of a cron expression
+type field struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var (
+	minuteField     = field{name: "minute", min: 0, max: 59}
+	hourField       = field{name: "hour", min: 0, max: 23}
+	dayOfMonthField = field{name: "day of month", min: 1, max: 31}
+	monthField      = field{name: "month", min: 1, max: 12, names: monthNames}
+	// 7 is accepted as Sunday, like in most cron implementations
+	dayOfWeekField = field{name: "day of week", min: 0, max: 7, names: dayNames}
+)
+
+// Schedule is a parsed cron expression. The times of a schedule are in UTC
+type Schedule struct {
+	minute     uint64
+	hour       uint64
+	dayOfMonth uint64
+	month      uint64
+	dayOfWeek  uint64
+	// When both the day of month and the day of week are restricted, a day matches if either matches
+	dayOfMonthStar bool
+	dayOfWeekStar  bool
+}
+
+// Parse parses a standard cron expression made of five fields: minute, hour, day of month, month and day of week.
+// A field is a list of values, ranges (1-5) and steps (*/15 or 0-30/10) separated by commas, or * for all the values.
+// The months and the days of week may also be named by their first three letters (e.g. jan, mon), and the @yearly,
+// @monthly, @weekly, @daily and @hourly macros are supported.
+func Parse(spec string) (*Schedule, error) {
+	expression := strings.TrimSpace(spec)
+	if macro, ok := macros[strings.ToLower(expression)]; ok {
+		expression = macro
+	}
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields in cron expression '%s', found %d", spec, len(fields))
+	}
+	var s Schedule
+	var err error
+	if s.minute, err = parseField(fields[0], minuteField); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseField(fields[1], hourField); err != nil {
+		return nil, err
+	}
+	if s.dayOfMonth, err = parseField(fields[2], dayOfMonthField); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseField(fields[3], monthField); err != nil {
+		return nil, err
+	}
+	if s.dayOfWeek, err = parseField(fields[4], dayOfWeekField); err != nil {
+		return nil, err
+	}
+	// Sunday is both 0 and 7
+	if s.dayOfWeek&(1<<7) != 0 {
+		s.dayOfWeek |= 1
+	}
+	s.dayOfMonthStar = fields[2] == "*"
+	s.dayOfWeekStar = fields[4] == "*"
+	if s.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("cron expression '%s' never matches", spec)
+	}
+	return &s, nil
+}
+
+// parseField returns the set of the values of a field as a bitmask
+func parseField(value string, f field) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(value, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			rangePart = part[:i]
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step '%s' of the %s field", part[i+1:], f.name)
+			}
+		}
+		start, end := f.min, f.max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if start, err = parseValue(bounds[0], f); err != nil {
+				return 0, err
+			}
+			if end, err = parseValue(bounds[1], f); err != nil {
+				return 0, err
+			}
+			if start > end {
+				return 0, fmt.Errorf("invalid range '%s' of the %s field", rangePart, f.name)
+			}
+		default:
+			var err error
+			if start, err = parseValue(rangePart, f); err != nil {
+				return 0, err
+			}
+			// A single value with a step (e.g. 5/15) starts the step at the value
+			if step == 1 {
+				end = start
+			}
+		}
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func parseValue(value string, f field) (int, error) {
+	if v, ok := f.names[strings.ToLower(value)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(value)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid value '%s' of the %s field: must be between %d and %d", value, f.name, f.min, f.max)
+	}
+	return v, nil
+}
+
+// Next returns the first time of the schedule strictly after the given time, or the zero time if the schedule does
+// not match any time in the next years
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+	yearLimit := t.Year() + maxSearchYears
+	for t.Year() <= yearLimit {
+		if !has(s.month, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !has(s.hour, t.Hour()) {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+		if !has(s.minute, t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dayMatches returns whether the day of the time matches the day of month and the day of week of the schedule.
+// Like in standard cron, a day matches either of them when both are restricted
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dayOfMonth := has(s.dayOfMonth, t.Day())
+	dayOfWeek := has(s.dayOfWeek, int(t.Weekday()))
+	if s.dayOfMonthStar || s.dayOfWeekStar {
+		return dayOfMonth && dayOfWeek
+	}
+	return dayOfMonth || dayOfWeek
+}
+
+func has(bits uint64, v int) bool {
+	return bits&(1<<uint(v)) != 0
+}
@@ -0,0 +1,78 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mustParse(t *testing.T, spec string) *Schedule {
+	s, err := Parse(spec)
+	assert.NoError(t, err)
+	return s
+}
+
+func date(value string) time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		spec     string
+		from     string
+		expected string
+	}{
+		{"* * * * *", "2020-06-15T10:30:00Z", "2020-06-15T10:31:00Z"},
+		// The next time is strictly after the given time
+		{"* * * * *", "2020-06-15T10:30:59Z", "2020-06-15T10:31:00Z"},
+		{"*/15 * * * *", "2020-06-15T10:30:00Z", "2020-06-15T10:45:00Z"},
+		{"*/15 * * * *", "2020-06-15T10:50:00Z", "2020-06-15T11:00:00Z"},
+		{"5/20 * * * *", "2020-06-15T10:30:00Z", "2020-06-15T10:45:00Z"},
+		{"0 2 * * *", "2020-06-15T10:30:00Z", "2020-06-16T02:00:00Z"},
+		{"0,30 9-17 * * *", "2020-06-15T17:30:00Z", "2020-06-16T09:00:00Z"},
+		{"0 9 * * mon-fri", "2020-06-19T10:00:00Z", "2020-06-22T09:00:00Z"},
+		{"0 0 * * 7", "2020-06-15T10:00:00Z", "2020-06-21T00:00:00Z"},
+		{"0 0 1 jan *", "2020-06-15T10:00:00Z", "2021-01-01T00:00:00Z"},
+		{"0 0 31 * *", "2020-04-15T10:00:00Z", "2020-05-31T00:00:00Z"},
+		{"0 0 29 2 *", "2021-03-01T00:00:00Z", "2024-02-29T00:00:00Z"},
+		// When both the day of month and the day of week are restricted, either matches
+		{"0 0 1 * mon", "2020-06-02T10:00:00Z", "2020-06-08T00:00:00Z"},
+		{"@hourly", "2020-06-15T10:30:00Z", "2020-06-15T11:00:00Z"},
+		{"@daily", "2020-12-31T10:30:00Z", "2021-01-01T00:00:00Z"},
+	}
+	for _, test := range tests {
+		s := mustParse(t, test.spec)
+		assert.Equal(t, date(test.expected), s.Next(date(test.from)), "%s from %s", test.spec, test.from)
+	}
+}
+
+func TestNextInUTC(t *testing.T) {
+	s := mustParse(t, "0 2 * * *")
+	from := date("2020-06-15T10:30:00Z").In(time.FixedZone("UTC+5", 5*60*60))
+	assert.Equal(t, date("2020-06-16T02:00:00Z"), s.Next(from))
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		spec          string
+		expectedError string
+	}{
+		{"* * * *", "expected 5 fields in cron expression '* * * *', found 4"},
+		{"60 * * * *", "invalid value '60' of the minute field: must be between 0 and 59"},
+		{"* 24 * * *", "invalid value '24' of the hour field: must be between 0 and 23"},
+		{"* * 0 * *", "invalid value '0' of the day of month field: must be between 1 and 31"},
+		{"* * * foo *", "invalid value 'foo' of the month field: must be between 1 and 12"},
+		{"*/0 * * * *", "invalid step '0' of the minute field"},
+		{"* * * * 5-1", "invalid range '5-1' of the day of week field"},
+		{"0 0 30 2 *", "cron expression '0 0 30 2 *' never matches"},
+	}
+	for _, test := range tests {
+		_, err := Parse(test.spec)
+		assert.EqualError(t, err, test.expectedError)
+	}
+}
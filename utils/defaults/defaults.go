@@ -1,6 +1,10 @@
 package defaults
 
 import (
+	"io/ioutil"
+	"os"
+	"strings"
+
 	"k8s.io/apimachinery/pkg/util/intstr"
 
 	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
@@ -19,11 +23,27 @@ const (
 	DefaultProgressDeadlineSeconds = int32(600)
 	// DefaultScaleDownDelaySeconds default seconds before scaling down old replicaset after switching services
 	DefaultScaleDownDelaySeconds = int32(30)
+	// DefaultScaleDownDrainMaxDelaySeconds default maximum seconds before scaling down the old replicaset draining its
+	// connections after switching services
+	DefaultScaleDownDrainMaxDelaySeconds = int32(3600)
 	// DefaultAutoPromotionEnabled default value for auto promoting a blueGreen strategy
 	DefaultAutoPromotionEnabled = true
 	// DefaultConsecutiveErrorLimit is the default number times a metric can error in sequence before
 	// erroring the entire metric.
 	DefaultConsecutiveErrorLimit int32 = 4
+	// DefaultTerminateAnalysisOnPromote default value for terminating the background analysis of a canary once the
+	// rollout is fully promoted
+	DefaultTerminateAnalysisOnPromote = true
+	// DefaultQuarantineReplicas default number of canary pods kept in quarantine after the rollout is aborted
+	DefaultQuarantineReplicas = int32(1)
+	// DefaultQuarantineTTLSeconds default seconds the canary pods are kept in quarantine after the rollout is aborted
+	DefaultQuarantineTTLSeconds = int32(3600)
+	// DefaultMaxTrafficWeight default total weight of the traffic split by the traffic routing, which makes the weights
+	// percentages
+	DefaultMaxTrafficWeight = int32(100)
+	// ControllerConfigMapName is the name of the ConfigMap in the controller namespace holding the controller
+	// configuration
+	ControllerConfigMapName = "argo-rollouts-config"
 )
 
 // GetReplicasOrDefault returns the deferenced number of replicas or the default number
@@ -100,6 +120,18 @@ func GetScaleDownDelaySecondsOrDefault(rollout *v1alpha1.Rollout) int32 {
 	return *rollout.Spec.Strategy.BlueGreen.ScaleDownDelaySeconds
 }
 
+// GetScaleDownDrainMaxDelaySecondsOrDefault returns the maximum seconds before scaling down the old replicaset draining
+// its connections
+func GetScaleDownDrainMaxDelaySecondsOrDefault(rollout *v1alpha1.Rollout) int32 {
+	if rollout.Spec.Strategy.BlueGreen == nil || rollout.Spec.Strategy.BlueGreen.ScaleDownDrain == nil {
+		return DefaultScaleDownDrainMaxDelaySeconds
+	}
+	if rollout.Spec.Strategy.BlueGreen.ScaleDownDrain.MaxDelaySeconds == nil {
+		return DefaultScaleDownDrainMaxDelaySeconds
+	}
+	return *rollout.Spec.Strategy.BlueGreen.ScaleDownDrain.MaxDelaySeconds
+}
+
 func GetAutoPromotionEnabledOrDefault(rollout *v1alpha1.Rollout) bool {
 	if rollout.Spec.Strategy.BlueGreen == nil {
 		return DefaultAutoPromotionEnabled
@@ -110,9 +142,70 @@ func GetAutoPromotionEnabledOrDefault(rollout *v1alpha1.Rollout) bool {
 	return *rollout.Spec.Strategy.BlueGreen.AutoPromotionEnabled
 }
 
+func GetTerminateAnalysisOnPromoteOrDefault(rollout *v1alpha1.Rollout) bool {
+	if rollout.Spec.Strategy.Canary == nil || rollout.Spec.Strategy.Canary.Analysis == nil {
+		return DefaultTerminateAnalysisOnPromote
+	}
+	if rollout.Spec.Strategy.Canary.Analysis.TerminateAnalysisOnPromote == nil {
+		return DefaultTerminateAnalysisOnPromote
+	}
+	return *rollout.Spec.Strategy.Canary.Analysis.TerminateAnalysisOnPromote
+}
+
+func GetQuarantineReplicasOrDefault(rollout *v1alpha1.Rollout) int32 {
+	if rollout.Spec.Strategy.Canary == nil || rollout.Spec.Strategy.Canary.QuarantineOnFailure == nil {
+		return DefaultQuarantineReplicas
+	}
+	if rollout.Spec.Strategy.Canary.QuarantineOnFailure.Replicas == nil {
+		return DefaultQuarantineReplicas
+	}
+	return *rollout.Spec.Strategy.Canary.QuarantineOnFailure.Replicas
+}
+
+func GetQuarantineTTLSecondsOrDefault(rollout *v1alpha1.Rollout) int32 {
+	if rollout.Spec.Strategy.Canary == nil || rollout.Spec.Strategy.Canary.QuarantineOnFailure == nil {
+		return DefaultQuarantineTTLSeconds
+	}
+	if rollout.Spec.Strategy.Canary.QuarantineOnFailure.TTLSeconds == nil {
+		return DefaultQuarantineTTLSeconds
+	}
+	return *rollout.Spec.Strategy.Canary.QuarantineOnFailure.TTLSeconds
+}
+
+// GetMaxTrafficWeightOrDefault returns the total weight of the traffic split by the traffic routing of the rollout,
+// which the weights of its steps are relative to
+func GetMaxTrafficWeightOrDefault(rollout *v1alpha1.Rollout) int32 {
+	var trafficRouting *v1alpha1.RolloutTrafficRouting
+	if rollout.Spec.Strategy.Canary != nil {
+		trafficRouting = rollout.Spec.Strategy.Canary.TrafficRouting
+	} else if rollout.Spec.Strategy.BlueGreen != nil {
+		trafficRouting = rollout.Spec.Strategy.BlueGreen.TrafficRouting
+	}
+	if trafficRouting == nil || trafficRouting.MaxTrafficWeight == nil {
+		return DefaultMaxTrafficWeight
+	}
+	return *trafficRouting.MaxTrafficWeight
+}
+
 func GetConsecutiveErrorLimitOrDefault(metric *v1alpha1.Metric) int32 {
 	if metric.ConsecutiveErrorLimit != nil {
 		return *metric.ConsecutiveErrorLimit
 	}
 	return DefaultConsecutiveErrorLimit
 }
+
+// Namespace returns the namespace the controller runs in
+func Namespace() string {
+	// This way assumes you've set the POD_NAMESPACE environment variable using the downward API.
+	// This check has to be done first for backwards compatibility with the way InClusterConfig was originally set up
+	if ns, ok := os.LookupEnv("POD_NAMESPACE"); ok {
+		return ns
+	}
+	// Fall back to the namespace associated with the service account token, if available
+	if data, err := ioutil.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace"); err == nil {
+		if ns := strings.TrimSpace(string(data)); len(ns) > 0 {
+			return ns
+		}
+	}
+	return "argo-rollouts"
+}
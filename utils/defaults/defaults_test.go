@@ -148,6 +148,32 @@ func TestGetScaleDownDelaySecondsOrDefault(t *testing.T) {
 	assert.Equal(t, DefaultScaleDownDelaySeconds, GetScaleDownDelaySecondsOrDefault(rolloutNoScaleDownDelaySeconds))
 }
 
+func TestGetScaleDownDrainMaxDelaySecondsOrDefault(t *testing.T) {
+	maxDelaySeconds := int32(600)
+	rolloutNonDefaultValue := &v1alpha1.Rollout{
+		Spec: v1alpha1.RolloutSpec{
+			Strategy: v1alpha1.RolloutStrategy{
+				BlueGreen: &v1alpha1.BlueGreenStrategy{
+					ScaleDownDrain: &v1alpha1.ScaleDownDrain{MaxDelaySeconds: &maxDelaySeconds},
+				},
+			},
+		},
+	}
+	assert.Equal(t, maxDelaySeconds, GetScaleDownDrainMaxDelaySecondsOrDefault(rolloutNonDefaultValue))
+	rolloutNoStrategyDefaultValue := &v1alpha1.Rollout{}
+	assert.Equal(t, DefaultScaleDownDrainMaxDelaySeconds, GetScaleDownDrainMaxDelaySecondsOrDefault(rolloutNoStrategyDefaultValue))
+	rolloutNoMaxDelaySeconds := &v1alpha1.Rollout{
+		Spec: v1alpha1.RolloutSpec{
+			Strategy: v1alpha1.RolloutStrategy{
+				BlueGreen: &v1alpha1.BlueGreenStrategy{
+					ScaleDownDrain: &v1alpha1.ScaleDownDrain{},
+				},
+			},
+		},
+	}
+	assert.Equal(t, DefaultScaleDownDrainMaxDelaySeconds, GetScaleDownDrainMaxDelaySecondsOrDefault(rolloutNoMaxDelaySeconds))
+}
+
 func TestGetAutoPromotionEnabledOrDefault(t *testing.T) {
 	autoPromote := false
 	rolloutNonDefaultValue := &v1alpha1.Rollout{
@@ -173,6 +199,35 @@ func TestGetAutoPromotionEnabledOrDefault(t *testing.T) {
 	assert.Equal(t, DefaultAutoPromotionEnabled, GetAutoPromotionEnabledOrDefault(rolloutNoAutoPromotionEnabled))
 }
 
+func TestGetTerminateAnalysisOnPromoteOrDefault(t *testing.T) {
+	terminate := false
+	rolloutNonDefaultValue := &v1alpha1.Rollout{
+		Spec: v1alpha1.RolloutSpec{
+			Strategy: v1alpha1.RolloutStrategy{
+				Canary: &v1alpha1.CanaryStrategy{
+					Analysis: &v1alpha1.RolloutAnalysisBackground{
+						TerminateAnalysisOnPromote: &terminate,
+					},
+				},
+			},
+		},
+	}
+
+	assert.Equal(t, terminate, GetTerminateAnalysisOnPromoteOrDefault(rolloutNonDefaultValue))
+	rolloutNoStrategyDefaultValue := &v1alpha1.Rollout{}
+	assert.Equal(t, DefaultTerminateAnalysisOnPromote, GetTerminateAnalysisOnPromoteOrDefault(rolloutNoStrategyDefaultValue))
+	rolloutNoTerminateAnalysisOnPromote := &v1alpha1.Rollout{
+		Spec: v1alpha1.RolloutSpec{
+			Strategy: v1alpha1.RolloutStrategy{
+				Canary: &v1alpha1.CanaryStrategy{
+					Analysis: &v1alpha1.RolloutAnalysisBackground{},
+				},
+			},
+		},
+	}
+	assert.Equal(t, DefaultTerminateAnalysisOnPromote, GetTerminateAnalysisOnPromoteOrDefault(rolloutNoTerminateAnalysisOnPromote))
+}
+
 func TestGetExperimentProgressDeadlineSecondsOrDefault(t *testing.T) {
 	seconds := int32(2)
 	nonDefaultValue := &v1alpha1.Experiment{
@@ -196,3 +251,88 @@ func TestGetConsecutiveErrorLimitOrDefault(t *testing.T) {
 	metricDefaultValue := &v1alpha1.Metric{}
 	assert.Equal(t, DefaultConsecutiveErrorLimit, GetConsecutiveErrorLimitOrDefault(metricDefaultValue))
 }
+
+func TestGetQuarantineReplicasOrDefault(t *testing.T) {
+	replicas := int32(2)
+	rolloutNonDefaultValue := &v1alpha1.Rollout{
+		Spec: v1alpha1.RolloutSpec{
+			Strategy: v1alpha1.RolloutStrategy{
+				Canary: &v1alpha1.CanaryStrategy{
+					QuarantineOnFailure: &v1alpha1.CanaryQuarantine{
+						Replicas: &replicas,
+					},
+				},
+			},
+		},
+	}
+
+	assert.Equal(t, replicas, GetQuarantineReplicasOrDefault(rolloutNonDefaultValue))
+	rolloutNoStrategyDefaultValue := &v1alpha1.Rollout{}
+	assert.Equal(t, DefaultQuarantineReplicas, GetQuarantineReplicasOrDefault(rolloutNoStrategyDefaultValue))
+	rolloutNoReplicasDefaultValue := &v1alpha1.Rollout{
+		Spec: v1alpha1.RolloutSpec{
+			Strategy: v1alpha1.RolloutStrategy{
+				Canary: &v1alpha1.CanaryStrategy{
+					QuarantineOnFailure: &v1alpha1.CanaryQuarantine{},
+				},
+			},
+		},
+	}
+	assert.Equal(t, DefaultQuarantineReplicas, GetQuarantineReplicasOrDefault(rolloutNoReplicasDefaultValue))
+}
+
+func TestGetQuarantineTTLSecondsOrDefault(t *testing.T) {
+	ttlSeconds := int32(600)
+	rolloutNonDefaultValue := &v1alpha1.Rollout{
+		Spec: v1alpha1.RolloutSpec{
+			Strategy: v1alpha1.RolloutStrategy{
+				Canary: &v1alpha1.CanaryStrategy{
+					QuarantineOnFailure: &v1alpha1.CanaryQuarantine{
+						TTLSeconds: &ttlSeconds,
+					},
+				},
+			},
+		},
+	}
+
+	assert.Equal(t, ttlSeconds, GetQuarantineTTLSecondsOrDefault(rolloutNonDefaultValue))
+	rolloutNoStrategyDefaultValue := &v1alpha1.Rollout{}
+	assert.Equal(t, DefaultQuarantineTTLSeconds, GetQuarantineTTLSecondsOrDefault(rolloutNoStrategyDefaultValue))
+}
+
+func TestGetMaxTrafficWeightOrDefault(t *testing.T) {
+	maxTrafficWeight := int32(1000)
+	canaryNonDefaultValue := &v1alpha1.Rollout{
+		Spec: v1alpha1.RolloutSpec{
+			Strategy: v1alpha1.RolloutStrategy{
+				Canary: &v1alpha1.CanaryStrategy{
+					TrafficRouting: &v1alpha1.RolloutTrafficRouting{
+						MaxTrafficWeight: &maxTrafficWeight,
+					},
+				},
+			},
+		},
+	}
+	assert.Equal(t, maxTrafficWeight, GetMaxTrafficWeightOrDefault(canaryNonDefaultValue))
+	blueGreenNonDefaultValue := &v1alpha1.Rollout{
+		Spec: v1alpha1.RolloutSpec{
+			Strategy: v1alpha1.RolloutStrategy{
+				BlueGreen: &v1alpha1.BlueGreenStrategy{
+					TrafficRouting: &v1alpha1.RolloutTrafficRouting{
+						MaxTrafficWeight: &maxTrafficWeight,
+					},
+				},
+			},
+		},
+	}
+	assert.Equal(t, maxTrafficWeight, GetMaxTrafficWeightOrDefault(blueGreenNonDefaultValue))
+	rolloutNoTrafficRoutingDefaultValue := &v1alpha1.Rollout{
+		Spec: v1alpha1.RolloutSpec{
+			Strategy: v1alpha1.RolloutStrategy{
+				Canary: &v1alpha1.CanaryStrategy{},
+			},
+		},
+	}
+	assert.Equal(t, DefaultMaxTrafficWeight, GetMaxTrafficWeightOrDefault(rolloutNoTrafficRoutingDefaultValue))
+	assert.Equal(t, DefaultMaxTrafficWeight, GetMaxTrafficWeightOrDefault(&v1alpha1.Rollout{}))
+}
@@ -1,8 +1,11 @@
 package evaluate
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"strconv"
+	"strings"
 
 	"github.com/antonmedv/expr"
 	"github.com/sirupsen/logrus"
@@ -10,30 +13,83 @@ import (
 	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
 )
 
-func EvaluateResult(result interface{}, metric v1alpha1.Metric, logCtx logrus.Entry) v1alpha1.AnalysisPhase {
+// EvaluateResult evaluates the conditions of the metric with the result. An error is returned along with the Error
+// phase, so that it is recorded as the message of the measurement
+func EvaluateResult(result interface{}, metric v1alpha1.Metric, logCtx logrus.Entry) (v1alpha1.AnalysisPhase, error) {
+	return EvaluateResultWithVars(result, nil, metric, logCtx)
+}
+
+// EvaluateResultWithVars evaluates the conditions of the metric with the result along with additional variables,
+// such as the canary and baseline values of a comparison
+func EvaluateResultWithVars(result interface{}, vars map[string]interface{}, metric v1alpha1.Metric, logCtx logrus.Entry) (v1alpha1.AnalysisPhase, error) {
 	successCondition := false
 	failCondition := false
 	var err error
 
+	rawResult := result
+	if metric.Transform != "" {
+		result, err = transformResult(result, vars, metric.Transform)
+		if err != nil {
+			return v1alpha1.AnalysisPhaseError, err
+		}
+	}
+
+	if metric.NaNHandling != "" && (isNaNOrInf(result) || varsContainNaNOrInf(vars)) {
+		logCtx.Infof("result contains NaN or Inf, assessing with nanHandling '%s'", metric.NaNHandling)
+		switch metric.NaNHandling {
+		case v1alpha1.NaNHandlingFail:
+			return v1alpha1.AnalysisPhaseFailed, nil
+		case v1alpha1.NaNHandlingPass:
+			return v1alpha1.AnalysisPhaseSuccessful, nil
+		default:
+			return v1alpha1.AnalysisPhaseError, errors.New("metric value is NaN or Inf")
+		}
+	}
+
+	// The recent results are added after the NaN check, so a NaN result of a previous measurement assessed
+	// with nanHandling does not affect the assessment of the following measurements
+	if metric.RecentResultsWindow > 0 {
+		vars = withRecentResults(vars, rawResult, metric.RecentValues)
+	}
+
+	if metric.InconclusiveCondition != "" {
+		inconclusiveCondition, err := evalCondition(result, vars, metric.InconclusiveCondition)
+		if err != nil {
+			return v1alpha1.AnalysisPhaseError, err
+		}
+		if inconclusiveCondition {
+			return v1alpha1.AnalysisPhaseInconclusive, nil
+		}
+	}
+
+	if metric.MarginalBand != nil {
+		marginal, err := inMarginalBand(result, *metric.MarginalBand)
+		if err != nil {
+			return v1alpha1.AnalysisPhaseError, err
+		}
+		if marginal {
+			logCtx.Infof("result is within the marginal band [%s, %s]", metric.MarginalBand.Lower, metric.MarginalBand.Upper)
+			return v1alpha1.AnalysisPhaseInconclusive, nil
+		}
+	}
+
 	if metric.SuccessCondition != "" {
-		successCondition, err = EvalCondition(result, metric.SuccessCondition)
+		successCondition, err = evalCondition(result, vars, metric.SuccessCondition)
 		if err != nil {
-			logCtx.Warning(err.Error())
-			return v1alpha1.AnalysisPhaseError
+			return v1alpha1.AnalysisPhaseError, err
 		}
 	}
 	if metric.FailureCondition != "" {
-		failCondition, err = EvalCondition(result, metric.FailureCondition)
+		failCondition, err = evalCondition(result, vars, metric.FailureCondition)
 		if err != nil {
-			logCtx.Warning(err.Error())
-			return v1alpha1.AnalysisPhaseError
+			return v1alpha1.AnalysisPhaseError, err
 		}
 	}
 
 	switch {
 	case metric.SuccessCondition == "" && metric.FailureCondition == "":
 		//Always return success unless there is an error
-		return v1alpha1.AnalysisPhaseSuccessful
+		return v1alpha1.AnalysisPhaseSuccessful, nil
 	case metric.SuccessCondition != "" && metric.FailureCondition == "":
 		// Without a failure condition, a measurement is considered a failure if the measurement's success condition is not true
 		failCondition = !successCondition
@@ -43,26 +99,26 @@ func EvaluateResult(result interface{}, metric v1alpha1.Metric, logCtx logrus.En
 	}
 
 	if failCondition {
-		return v1alpha1.AnalysisPhaseFailed
+		return v1alpha1.AnalysisPhaseFailed, nil
 	}
 
 	if !failCondition && !successCondition {
-		return v1alpha1.AnalysisPhaseInconclusive
+		return v1alpha1.AnalysisPhaseInconclusive, nil
 	}
 
 	// If we reach this code path, failCondition is false and successCondition is true
-	return v1alpha1.AnalysisPhaseSuccessful
+	return v1alpha1.AnalysisPhaseSuccessful, nil
 }
 
 // EvalCondition evaluates the condition with the resultValue as an input
 func EvalCondition(resultValue interface{}, condition string) (bool, error) {
+	return evalCondition(resultValue, nil, condition)
+}
+
+func evalCondition(resultValue interface{}, vars map[string]interface{}, condition string) (bool, error) {
 	var err error
 
-	env := map[string]interface{}{
-		"result":  resultValue,
-		"asInt":   asInt,
-		"asFloat": asFloat,
-	}
+	env := newEnv(resultValue, vars)
 
 	// Setup a clean recovery in case the eval code panics.
 	// TODO: this actually might not be nessary since it seems evaluation lib handles panics from functions internally
@@ -85,6 +141,205 @@ func EvalCondition(resultValue interface{}, condition string) (bool, error) {
 	return output.(bool), err
 }
 
+// transformResult evaluates the transform expression with the resultValue as an input and returns its output
+func transformResult(resultValue interface{}, vars map[string]interface{}, transform string) (output interface{}, err error) {
+	env := newEnv(resultValue, vars)
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("transform logic panicked: %v", r)
+		}
+	}()
+
+	program, err := expr.Compile(transform, expr.Env(env))
+	if err != nil {
+		return nil, fmt.Errorf("invalid transform: %v", err)
+	}
+	output, err = expr.Run(program, env)
+	if err != nil {
+		return nil, fmt.Errorf("unable to transform result: %v", err)
+	}
+	return output, nil
+}
+
+// EvaluateMessage evaluates the message expression with the result along with additional variables, such as the
+// labels of a Prometheus sample, and returns its output as a string
+func EvaluateMessage(result interface{}, vars map[string]interface{}, message string) (output string, err error) {
+	env := newEnv(result, vars)
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("message logic panicked: %v", r)
+		}
+	}()
+
+	program, err := expr.Compile(message, expr.Env(env))
+	if err != nil {
+		return "", fmt.Errorf("invalid message: %v", err)
+	}
+	out, err := expr.Run(program, env)
+	if err != nil {
+		return "", fmt.Errorf("unable to evaluate message: %v", err)
+	}
+	return fmt.Sprint(out), nil
+}
+
+// inMarginalBand returns whether the result is within the thresholds of the marginal band. The result must be a
+// number, or a list holding a single number such as a Prometheus vector with a single sample
+func inMarginalBand(result interface{}, band v1alpha1.MarginalBand) (bool, error) {
+	lower, err := strconv.ParseFloat(band.Lower, 64)
+	if err != nil {
+		return false, fmt.Errorf("invalid marginalBand lower threshold '%s': %v", band.Lower, err)
+	}
+	upper, err := strconv.ParseFloat(band.Upper, 64)
+	if err != nil {
+		return false, fmt.Errorf("invalid marginalBand upper threshold '%s': %v", band.Upper, err)
+	}
+	value, err := resultAsFloat(result)
+	if err != nil {
+		return false, err
+	}
+	return value >= lower && value <= upper, nil
+}
+
+// ValueAsFloat returns the value of a measurement as a float64, which must be a number or a list holding a single
+// number such as a Prometheus vector with a single sample
+func ValueAsFloat(value string) (float64, error) {
+	return resultAsFloat(parseValue(value))
+}
+
+// resultAsFloat returns the result as a float64, unwrapping a list which holds a single value
+func resultAsFloat(result interface{}) (float64, error) {
+	switch value := result.(type) {
+	case float64:
+		return value, nil
+	case float32:
+		return float64(value), nil
+	case int:
+		return float64(value), nil
+	case int32:
+		return float64(value), nil
+	case int64:
+		return float64(value), nil
+	case string:
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f, nil
+		}
+	case []float64:
+		if len(value) == 1 {
+			return value[0], nil
+		}
+	case []interface{}:
+		if len(value) == 1 {
+			return resultAsFloat(value[0])
+		}
+	}
+	return 0, fmt.Errorf("result '%v' is not a single number to compare with the marginal band", result)
+}
+
+// withRecentResults returns a copy of the variables with the recentResults list, which holds the parsed values
+// of the recent measurements followed by the current result
+func withRecentResults(vars map[string]interface{}, result interface{}, recentValues []string) map[string]interface{} {
+	recentResults := make([]interface{}, 0, len(recentValues)+1)
+	for _, value := range recentValues {
+		recentResults = append(recentResults, parseValue(value))
+	}
+	recentResults = append(recentResults, result)
+
+	newVars := map[string]interface{}{}
+	for name, value := range vars {
+		newVars[name] = value
+	}
+	newVars["recentResults"] = recentResults
+	return newVars
+}
+
+// parseValue parses the value of a measurement: numbers are converted to float64 and lists, such as the vectors
+// returned by Prometheus, are converted to a list of parsed values. Other values are kept as strings
+func parseValue(value string) interface{} {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+		values := []interface{}{}
+		if inner := strings.TrimSpace(value[1 : len(value)-1]); inner != "" {
+			for _, v := range strings.Split(inner, ",") {
+				values = append(values, parseValue(v))
+			}
+		}
+		return values
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return f
+	}
+	return value
+}
+
+// reservedNames are the names of the variables and functions of the conditions
+var reservedNames = map[string]bool{
+	"result":        true,
+	"recentResults": true,
+	"asInt":         true,
+	"asFloat":       true,
+	"avg":           true,
+	"sum":           true,
+	"min":           true,
+	"max":           true,
+}
+
+// IsReservedName returns whether the name is one of the variables or functions of the conditions, which the variables
+// defined by a metric cannot replace
+func IsReservedName(name string) bool {
+	return reservedNames[name]
+}
+
+func newEnv(resultValue interface{}, vars map[string]interface{}) map[string]interface{} {
+	env := map[string]interface{}{
+		"result":  resultValue,
+		"asInt":   asInt,
+		"asFloat": asFloat,
+		"avg":     avg,
+		"sum":     sum,
+		"min":     min,
+		"max":     max,
+	}
+	for name, value := range vars {
+		env[name] = value
+	}
+	return env
+}
+
+// isNaNOrInf returns whether or not the result, or any value within a list result, is NaN or Inf
+func isNaNOrInf(result interface{}) bool {
+	switch value := result.(type) {
+	case float64:
+		return math.IsNaN(value) || math.IsInf(value, 0)
+	case float32:
+		return isNaNOrInf(float64(value))
+	case []float64:
+		for _, v := range value {
+			if isNaNOrInf(v) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, v := range value {
+			if isNaNOrInf(v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// varsContainNaNOrInf returns whether or not any of the variables is NaN or Inf
+func varsContainNaNOrInf(vars map[string]interface{}) bool {
+	for _, value := range vars {
+		if isNaNOrInf(value) {
+			return true
+		}
+	}
+	return false
+}
+
 func asInt(in string) int64 {
 	inAsInt, err := strconv.ParseInt(in, 10, 64)
 	if err == nil {
@@ -100,3 +355,66 @@ func asFloat(in string) float64 {
 	}
 	panic(err)
 }
+
+// toFloats converts a list of numbers, or of strings holding numbers, to a list of float64
+func toFloats(in []interface{}) []float64 {
+	values := make([]float64, 0, len(in))
+	for _, v := range in {
+		switch value := v.(type) {
+		case float64:
+			values = append(values, value)
+		case float32:
+			values = append(values, float64(value))
+		case int:
+			values = append(values, float64(value))
+		case int32:
+			values = append(values, float64(value))
+		case int64:
+			values = append(values, float64(value))
+		case string:
+			values = append(values, asFloat(value))
+		default:
+			panic(fmt.Errorf("value '%v' of type %T is not a number", v, v))
+		}
+	}
+	return values
+}
+
+func sum(in []interface{}) float64 {
+	total := 0.0
+	for _, value := range toFloats(in) {
+		total += value
+	}
+	return total
+}
+
+func avg(in []interface{}) float64 {
+	if len(in) == 0 {
+		panic(fmt.Errorf("avg of an empty list"))
+	}
+	return sum(in) / float64(len(in))
+}
+
+func min(in []interface{}) float64 {
+	values := toFloats(in)
+	if len(values) == 0 {
+		panic(fmt.Errorf("min of an empty list"))
+	}
+	minimum := values[0]
+	for _, value := range values[1:] {
+		minimum = math.Min(minimum, value)
+	}
+	return minimum
+}
+
+func max(in []interface{}) float64 {
+	values := toFloats(in)
+	if len(values) == 0 {
+		panic(fmt.Errorf("max of an empty list"))
+	}
+	maximum := values[0]
+	for _, value := range values[1:] {
+		maximum = math.Max(maximum, value)
+	}
+	return maximum
+}
@@ -2,6 +2,7 @@ package evaluate
 
 import (
 	"fmt"
+	"math"
 	"testing"
 
 	"github.com/sirupsen/logrus"
@@ -16,8 +17,9 @@ func TestEvaluateResultWithSuccess(t *testing.T) {
 		FailureCondition: "false",
 	}
 	logCtx := logrus.WithField("test", "test")
-	status := EvaluateResult(true, metric, *logCtx)
+	status, err := EvaluateResult(true, metric, *logCtx)
 	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, status)
+	assert.NoError(t, err)
 }
 
 func TestEvaluateResultWithFailure(t *testing.T) {
@@ -26,8 +28,9 @@ func TestEvaluateResultWithFailure(t *testing.T) {
 		FailureCondition: "true",
 	}
 	logCtx := logrus.WithField("test", "test")
-	status := EvaluateResult(true, metric, *logCtx)
+	status, err := EvaluateResult(true, metric, *logCtx)
 	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, status)
+	assert.NoError(t, err)
 
 }
 
@@ -37,8 +40,113 @@ func TestEvaluateResultInconclusive(t *testing.T) {
 		FailureCondition: "false",
 	}
 	logCtx := logrus.WithField("test", "test")
-	status := EvaluateResult(true, metric, *logCtx)
+	status, err := EvaluateResult(true, metric, *logCtx)
+	assert.Equal(t, v1alpha1.AnalysisPhaseInconclusive, status)
+	assert.NoError(t, err)
+}
+
+func TestEvaluateResultWithInconclusiveCondition(t *testing.T) {
+	metric := v1alpha1.Metric{
+		SuccessCondition:      "result[0] >= 0.9",
+		InconclusiveCondition: "len(result) == 0",
+	}
+	logCtx := logrus.WithField("test", "test")
+	// The success condition is not evaluated when there is not enough data
+	status, err := EvaluateResult([]float64{}, metric, *logCtx)
+	assert.Equal(t, v1alpha1.AnalysisPhaseInconclusive, status)
+	assert.NoError(t, err)
+	status, err = EvaluateResult([]float64{0.95}, metric, *logCtx)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, status)
+	assert.NoError(t, err)
+	status, err = EvaluateResult([]float64{0.5}, metric, *logCtx)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, status)
+	assert.NoError(t, err)
+}
+
+func TestEvaluateResultWithMarginalBand(t *testing.T) {
+	metric := v1alpha1.Metric{
+		SuccessCondition: "result[0] >= 0.9",
+		MarginalBand: &v1alpha1.MarginalBand{
+			Lower: "0.9",
+			Upper: "0.95",
+		},
+	}
+	logCtx := logrus.WithField("test", "test")
+	status, err := EvaluateResult([]float64{0.99}, metric, *logCtx)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, status)
+	assert.NoError(t, err)
+	// The thresholds of the band are inclusive and take precedence over the success condition
+	status, err = EvaluateResult([]float64{0.95}, metric, *logCtx)
+	assert.Equal(t, v1alpha1.AnalysisPhaseInconclusive, status)
+	assert.NoError(t, err)
+	status, err = EvaluateResult([]interface{}{0.92}, metric, *logCtx)
 	assert.Equal(t, v1alpha1.AnalysisPhaseInconclusive, status)
+	assert.NoError(t, err)
+	status, err = EvaluateResult([]float64{0.9}, metric, *logCtx)
+	assert.Equal(t, v1alpha1.AnalysisPhaseInconclusive, status)
+	assert.NoError(t, err)
+	status, err = EvaluateResult([]float64{0.5}, metric, *logCtx)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, status)
+	assert.NoError(t, err)
+}
+
+func TestEvaluateResultWithMarginalBandAfterTransform(t *testing.T) {
+	metric := v1alpha1.Metric{
+		Transform:        "result[0] * 100",
+		SuccessCondition: "result >= 90",
+		MarginalBand: &v1alpha1.MarginalBand{
+			Lower: "90",
+			Upper: "95",
+		},
+	}
+	logCtx := logrus.WithField("test", "test")
+	status, err := EvaluateResult([]float64{0.93}, metric, *logCtx)
+	assert.Equal(t, v1alpha1.AnalysisPhaseInconclusive, status)
+	assert.NoError(t, err)
+	status, err = EvaluateResult([]float64{0.97}, metric, *logCtx)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, status)
+	assert.NoError(t, err)
+}
+
+func TestEvaluateResultWithMarginalBandNonNumericResult(t *testing.T) {
+	metric := v1alpha1.Metric{
+		SuccessCondition: "len(result) > 0",
+		MarginalBand: &v1alpha1.MarginalBand{
+			Lower: "0.9",
+			Upper: "0.95",
+		},
+	}
+	logCtx := logrus.WithField("test", "test")
+	status, err := EvaluateResult([]float64{0.91, 0.92}, metric, *logCtx)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, status)
+	assert.Error(t, err)
+	status, err = EvaluateResult("ok", metric, *logCtx)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, status)
+	assert.Error(t, err)
+}
+
+func TestValueAsFloat(t *testing.T) {
+	value, err := ValueAsFloat("[0.99]")
+	assert.NoError(t, err)
+	assert.Equal(t, 0.99, value)
+	value, err = ValueAsFloat("42")
+	assert.NoError(t, err)
+	assert.Equal(t, float64(42), value)
+	for _, invalid := range []string{"", "[0.99, 0.98]", "[]", "ok"} {
+		_, err = ValueAsFloat(invalid)
+		assert.Error(t, err)
+	}
+}
+
+func TestEvaluateResultWithErrorOnInconclusiveCondition(t *testing.T) {
+	metric := v1alpha1.Metric{
+		SuccessCondition:      "true",
+		InconclusiveCondition: "a == true",
+	}
+	logCtx := logrus.WithField("test", "test")
+	status, err := EvaluateResult(true, metric, *logCtx)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, status)
+	assert.Error(t, err)
 }
 
 func TestEvaluateResultNoSuccessConditionAndNotFailing(t *testing.T) {
@@ -47,8 +155,9 @@ func TestEvaluateResultNoSuccessConditionAndNotFailing(t *testing.T) {
 		FailureCondition: "false",
 	}
 	logCtx := logrus.WithField("test", "test")
-	status := EvaluateResult(true, metric, *logCtx)
+	status, err := EvaluateResult(true, metric, *logCtx)
 	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, status)
+	assert.NoError(t, err)
 }
 
 func TestEvaluateResultNoFailureConditionAndNotSuccessful(t *testing.T) {
@@ -57,8 +166,9 @@ func TestEvaluateResultNoFailureConditionAndNotSuccessful(t *testing.T) {
 		FailureCondition: "",
 	}
 	logCtx := logrus.WithField("test", "test")
-	status := EvaluateResult(true, metric, *logCtx)
+	status, err := EvaluateResult(true, metric, *logCtx)
 	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, status)
+	assert.NoError(t, err)
 }
 
 func TestEvaluateResultNoFailureConditionAndNoSuccessCondition(t *testing.T) {
@@ -67,8 +177,9 @@ func TestEvaluateResultNoFailureConditionAndNoSuccessCondition(t *testing.T) {
 		FailureCondition: "",
 	}
 	logCtx := logrus.WithField("test", "test")
-	status := EvaluateResult(true, metric, *logCtx)
+	status, err := EvaluateResult(true, metric, *logCtx)
 	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, status)
+	assert.NoError(t, err)
 }
 
 func TestEvaluateResultWithErrorOnSuccessCondition(t *testing.T) {
@@ -77,8 +188,9 @@ func TestEvaluateResultWithErrorOnSuccessCondition(t *testing.T) {
 		FailureCondition: "true",
 	}
 	logCtx := logrus.WithField("test", "test")
-	status := EvaluateResult(true, metric, *logCtx)
+	status, err := EvaluateResult(true, metric, *logCtx)
 	assert.Equal(t, v1alpha1.AnalysisPhaseError, status)
+	assert.Error(t, err)
 }
 
 func TestEvaluateResultWithErrorOnFailureCondition(t *testing.T) {
@@ -87,8 +199,9 @@ func TestEvaluateResultWithErrorOnFailureCondition(t *testing.T) {
 		FailureCondition: "a == true",
 	}
 	logCtx := logrus.WithField("test", "test")
-	status := EvaluateResult(true, metric, *logCtx)
+	status, err := EvaluateResult(true, metric, *logCtx)
 	assert.Equal(t, v1alpha1.AnalysisPhaseError, status)
+	assert.Error(t, err)
 
 }
 
@@ -205,3 +318,191 @@ func TestAsFloat(t *testing.T) {
 		}
 	}
 }
+
+func TestEvaluateResultNaNHandling(t *testing.T) {
+	logCtx := logrus.WithField("test", "test")
+	tests := []struct {
+		nanHandling v1alpha1.NaNHandling
+		result      interface{}
+		phase       v1alpha1.AnalysisPhase
+	}{
+		{v1alpha1.NaNHandlingError, math.NaN(), v1alpha1.AnalysisPhaseError},
+		{v1alpha1.NaNHandlingFail, math.NaN(), v1alpha1.AnalysisPhaseFailed},
+		{v1alpha1.NaNHandlingPass, math.NaN(), v1alpha1.AnalysisPhaseSuccessful},
+		{v1alpha1.NaNHandlingFail, math.Inf(1), v1alpha1.AnalysisPhaseFailed},
+		{v1alpha1.NaNHandlingFail, []float64{1, math.Inf(-1)}, v1alpha1.AnalysisPhaseFailed},
+		{v1alpha1.NaNHandlingFail, []interface{}{1.0, math.NaN()}, v1alpha1.AnalysisPhaseFailed},
+		{v1alpha1.NaNHandlingFail, []float64{1, 2}, v1alpha1.AnalysisPhaseSuccessful},
+	}
+
+	for _, test := range tests {
+		metric := v1alpha1.Metric{
+			SuccessCondition: "true",
+			NaNHandling:      test.nanHandling,
+		}
+		status, err := EvaluateResult(test.result, metric, *logCtx)
+		assert.Equal(t, test.phase, status, "nanHandling: %s, result: %v", test.nanHandling, test.result)
+		if test.phase == v1alpha1.AnalysisPhaseError {
+			assert.EqualError(t, err, "metric value is NaN or Inf")
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+}
+
+func TestEvaluateResultWithVars(t *testing.T) {
+	logCtx := logrus.WithField("test", "test")
+	metric := v1alpha1.Metric{
+		SuccessCondition: "canary <= baseline * 1.2",
+	}
+	status, err := EvaluateResultWithVars(1.1, map[string]interface{}{"canary": 1.1, "baseline": 1.0}, metric, *logCtx)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, status)
+	assert.NoError(t, err)
+	status, err = EvaluateResultWithVars(1.5, map[string]interface{}{"canary": 1.5, "baseline": 1.0}, metric, *logCtx)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, status)
+	assert.NoError(t, err)
+}
+
+func TestEvaluateResultWithVarsNaNHandling(t *testing.T) {
+	logCtx := logrus.WithField("test", "test")
+	metric := v1alpha1.Metric{
+		SuccessCondition: "canary <= baseline * 1.2",
+		NaNHandling:      v1alpha1.NaNHandlingFail,
+	}
+	status, err := EvaluateResultWithVars(1.0, map[string]interface{}{"canary": 1.0, "baseline": math.NaN()}, metric, *logCtx)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, status)
+	assert.NoError(t, err)
+}
+
+func TestEvaluateResultWithTransform(t *testing.T) {
+	logCtx := logrus.WithField("test", "test")
+	metric := v1alpha1.Metric{
+		SuccessCondition: "result < 512",
+		FailureCondition: "result >= 512",
+		Transform:        "result / 1024 / 1024",
+	}
+	// 256MB
+	status, err := EvaluateResult(float64(268435456), metric, *logCtx)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, status)
+	assert.NoError(t, err)
+	// 1GB
+	status, err = EvaluateResult(float64(1073741824), metric, *logCtx)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, status)
+	assert.NoError(t, err)
+}
+
+func TestEvaluateResultWithTransformOfString(t *testing.T) {
+	logCtx := logrus.WithField("test", "test")
+	metric := v1alpha1.Metric{
+		SuccessCondition: "result >= 90",
+		Transform:        "asFloat(result) * 100",
+	}
+	status, err := EvaluateResult("0.95", metric, *logCtx)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, status)
+	assert.NoError(t, err)
+}
+
+func TestEvaluateResultWithTransformError(t *testing.T) {
+	logCtx := logrus.WithField("test", "test")
+	metric := v1alpha1.Metric{
+		SuccessCondition: "result > 0",
+		Transform:        "asFloat(result) * 100",
+	}
+	status, err := EvaluateResult("abc", metric, *logCtx)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, status)
+	assert.Error(t, err)
+}
+
+func TestTransformResult(t *testing.T) {
+	output, err := transformResult(float64(2048), nil, "result / 1024")
+	assert.NoError(t, err)
+	assert.Equal(t, float64(2), output)
+
+	_, err = transformResult(float64(2048), nil, "result / (1024")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid transform")
+}
+
+func TestEvaluateMessage(t *testing.T) {
+	vars := map[string]interface{}{
+		"labels": map[string]interface{}{"path": "/checkout"},
+	}
+	message, err := EvaluateMessage([]float64{0.8}, vars, `"latency of " + labels.path + " exceeded 500ms"`)
+	assert.NoError(t, err)
+	assert.Equal(t, "latency of /checkout exceeded 500ms", message)
+
+	message, err = EvaluateMessage([]float64{0.8}, vars, `result[0]`)
+	assert.NoError(t, err)
+	assert.Equal(t, "0.8", message)
+
+	_, err = EvaluateMessage(float64(1), nil, `"latency of " + (`)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid message")
+}
+
+func TestEvaluateResultWithRecentResults(t *testing.T) {
+	logCtx := logrus.WithField("test", "test")
+	metric := v1alpha1.Metric{
+		SuccessCondition:    "avg(recentResults) < 0.05",
+		RecentResultsWindow: 3,
+		RecentValues:        []string{"0.02", "0.04"},
+	}
+	status, err := EvaluateResult(0.06, metric, *logCtx)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, status)
+	assert.NoError(t, err)
+	status, err = EvaluateResult(0.12, metric, *logCtx)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, status)
+	assert.NoError(t, err)
+
+	// At the start of a run the window only holds the current result
+	metric.RecentValues = nil
+	metric.SuccessCondition = "len(recentResults) == 1 && avg(recentResults) < 0.05"
+	status, err = EvaluateResult(0.01, metric, *logCtx)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, status)
+	assert.NoError(t, err)
+}
+
+func TestEvaluateResultWithRecentResultsNaNHandling(t *testing.T) {
+	logCtx := logrus.WithField("test", "test")
+	metric := v1alpha1.Metric{
+		SuccessCondition:    "len(recentResults) == 2 && result < 0.05",
+		NaNHandling:         v1alpha1.NaNHandlingFail,
+		RecentResultsWindow: 2,
+		RecentValues:        []string{"NaN"},
+	}
+	// A NaN result of a previous measurement does not trigger nanHandling again
+	status, err := EvaluateResult(0.01, metric, *logCtx)
+	assert.Equal(t, v1alpha1.AnalysisPhaseSuccessful, status)
+	assert.NoError(t, err)
+	status, err = EvaluateResult(math.NaN(), metric, *logCtx)
+	assert.Equal(t, v1alpha1.AnalysisPhaseFailed, status)
+	assert.NoError(t, err)
+}
+
+func TestEvaluateResultWithoutRecentResultsWindow(t *testing.T) {
+	logCtx := logrus.WithField("test", "test")
+	metric := v1alpha1.Metric{
+		SuccessCondition: "len(recentResults) > 0",
+	}
+	status, err := EvaluateResult(0.01, metric, *logCtx)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, status)
+	assert.Error(t, err)
+}
+
+func TestParseValue(t *testing.T) {
+	assert.Equal(t, 0.95, parseValue("0.95"))
+	assert.Equal(t, "ok", parseValue("ok"))
+	assert.Equal(t, []interface{}{0.95, 0.5}, parseValue("[0.95,0.5]"))
+	assert.Equal(t, []interface{}{}, parseValue("[]"))
+}
+
+func TestAggregateFunctions(t *testing.T) {
+	values := []interface{}{float64(1), 2, "3", int64(6)}
+	assert.Equal(t, float64(12), sum(values))
+	assert.Equal(t, float64(3), avg(values))
+	assert.Equal(t, float64(1), min(values))
+	assert.Equal(t, float64(6), max(values))
+
+	assert.Panics(t, func() { avg([]interface{}{}) })
+	assert.Panics(t, func() { sum([]interface{}{true}) })
+}
@@ -18,6 +18,8 @@ const (
 	ALBIngressAnnotation = "alb.ingress.kubernetes.io"
 	// ALBActionPrefix the prefix to specific actions within an ALB ingress.
 	ALBActionPrefix = "/actions."
+	// managedActionKeysSeparator separates the keys of the actions managed by a rollout in the ManagedActionsAnnotation
+	managedActionKeysSeparator = ";"
 )
 
 // ALBAction describes an ALB action that configure the behavior of an ALB. This struct is marshaled into a string
@@ -29,7 +31,15 @@ type ALBAction struct {
 
 // ALBForwardConfig describes a list of target groups that the ALB should route traffic towards
 type ALBForwardConfig struct {
-	TargetGroups []ALBTargetGroup `json:"TargetGroups"`
+	TargetGroups                []ALBTargetGroup                `json:"TargetGroups"`
+	TargetGroupStickinessConfig *ALBTargetGroupStickinessConfig `json:"TargetGroupStickinessConfig,omitempty"`
+}
+
+// ALBTargetGroupStickinessConfig describes the stickiness of the target groups of an ALB forward action
+type ALBTargetGroupStickinessConfig struct {
+	Enabled bool `json:"Enabled"`
+	// The duration of the stickiness. The range is 1 to 604800 seconds.
+	DurationSeconds int64 `json:"DurationSeconds,omitempty"`
 }
 
 // ALBTargetGroup holds the weight to send to a specific destination consisting of a K8s service and port or ARN
@@ -117,6 +127,19 @@ func (m ManagedALBActions) String() string {
 	return str[:len(str)-1]
 }
 
+// ActionKeys returns the keys of the ALB actions managed by the rollout
+func (m ManagedALBActions) ActionKeys(rollout string) []string {
+	if m[rollout] == "" {
+		return nil
+	}
+	return strings.Split(m[rollout], managedActionKeysSeparator)
+}
+
+// SetActionKeys sets the keys of the ALB actions managed by the rollout
+func (m ManagedALBActions) SetActionKeys(rollout string, keys []string) {
+	m[rollout] = strings.Join(keys, managedActionKeysSeparator)
+}
+
 // NewManagedALBActions converts a string into a mapping of the rollouts to managed ALB actions
 func NewManagedALBActions(annotation string) (ManagedALBActions, error) {
 	m := ManagedALBActions{}
@@ -136,13 +159,41 @@ func NewManagedALBActions(annotation string) (ManagedALBActions, error) {
 
 // ALBActionAnnotationKey returns the annotation key for a specific action
 func ALBActionAnnotationKey(r *v1alpha1.Rollout) string {
-	prefix := ALBIngressAnnotation
-	if r.Spec.Strategy.Canary.TrafficRouting.ALB.AnnotationPrefix != "" {
-		prefix = r.Spec.Strategy.Canary.TrafficRouting.ALB.AnnotationPrefix
-	}
 	actionService := r.Spec.Strategy.Canary.StableService
 	if r.Spec.Strategy.Canary.TrafficRouting.ALB.RootService != "" {
 		actionService = r.Spec.Strategy.Canary.TrafficRouting.ALB.RootService
 	}
+	return ALBActionAnnotationKeyForService(r, actionService)
+}
+
+// ALBActionAnnotationKeyForService returns the annotation key of the action of a service
+func ALBActionAnnotationKeyForService(r *v1alpha1.Rollout, actionService string) string {
+	prefix := ALBIngressAnnotation
+	if r.Spec.Strategy.Canary.TrafficRouting.ALB.AnnotationPrefix != "" {
+		prefix = r.Spec.Strategy.Canary.TrafficRouting.ALB.AnnotationPrefix
+	}
 	return fmt.Sprintf("%s%s%s", prefix, ALBActionPrefix, actionService)
 }
+
+// ALBListenerRules returns the rules of the ALB Ingress whose actions are managed by the rollout: the rule of the root
+// service followed by the additional listener rules, with their service port defaulted
+func ALBListenerRules(r *v1alpha1.Rollout) []v1alpha1.ALBListenerRule {
+	alb := r.Spec.Strategy.Canary.TrafficRouting.ALB
+	rootService := r.Spec.Strategy.Canary.StableService
+	if alb.RootService != "" {
+		rootService = alb.RootService
+	}
+	rules := []v1alpha1.ALBListenerRule{{RootService: rootService, ServicePort: alb.ServicePort}}
+	seen := map[string]bool{rootService: true}
+	for _, rule := range alb.ListenerRules {
+		if seen[rule.RootService] {
+			continue
+		}
+		seen[rule.RootService] = true
+		if rule.ServicePort == 0 {
+			rule.ServicePort = alb.ServicePort
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
@@ -170,3 +170,45 @@ func TestALBActionAnnotationKey(t *testing.T) {
 	assert.Equal(t, "alb.ingress.kubernetes.io/actions.root-svc", ALBActionAnnotationKey(r))
 
 }
+
+func TestManagedALBActionKeys(t *testing.T) {
+	m, err := NewManagedALBActions("ro1:alb.ingress.kubernetes.io/actions.svc1;alb.ingress.kubernetes.io/actions.svc2,ro2:alb.ingress.kubernetes.io/actions.svc3")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"alb.ingress.kubernetes.io/actions.svc1", "alb.ingress.kubernetes.io/actions.svc2"}, m.ActionKeys("ro1"))
+	assert.Equal(t, []string{"alb.ingress.kubernetes.io/actions.svc3"}, m.ActionKeys("ro2"))
+	assert.Nil(t, m.ActionKeys("ro3"))
+
+	m.SetActionKeys("ro2", []string{"alb.ingress.kubernetes.io/actions.svc3", "alb.ingress.kubernetes.io/actions.svc4"})
+	assert.Equal(t, "alb.ingress.kubernetes.io/actions.svc3;alb.ingress.kubernetes.io/actions.svc4", m["ro2"])
+}
+
+func TestALBListenerRules(t *testing.T) {
+	r := &v1alpha1.Rollout{
+		Spec: v1alpha1.RolloutSpec{
+			Strategy: v1alpha1.RolloutStrategy{
+				Canary: &v1alpha1.CanaryStrategy{
+					StableService: "svc",
+					TrafficRouting: &v1alpha1.RolloutTrafficRouting{
+						ALB: &v1alpha1.ALBTrafficRouting{
+							ServicePort: 443,
+						},
+					},
+				},
+			},
+		},
+	}
+	assert.Equal(t, []v1alpha1.ALBListenerRule{{RootService: "svc", ServicePort: 443}}, ALBListenerRules(r))
+
+	r.Spec.Strategy.Canary.TrafficRouting.ALB.RootService = "root-svc"
+	r.Spec.Strategy.Canary.TrafficRouting.ALB.ListenerRules = []v1alpha1.ALBListenerRule{
+		{RootService: "http-svc", ServicePort: 80},
+		{RootService: "root-svc"},
+		{RootService: "internal-svc"},
+	}
+	expected := []v1alpha1.ALBListenerRule{
+		{RootService: "root-svc", ServicePort: 443},
+		{RootService: "http-svc", ServicePort: 80},
+		{RootService: "internal-svc", ServicePort: 443},
+	}
+	assert.Equal(t, expected, ALBListenerRules(r))
+}
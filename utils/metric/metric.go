@@ -1,11 +1,42 @@
 package metric
 
 import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
 )
 
+const (
+	// ResponseBodyMetadataKey is the key of the measurement metadata holding the response body of a failed provider call
+	ResponseBodyMetadataKey = "responseBody"
+	// MaxResponseBodyLength is the number of bytes of a response body recorded in the measurement metadata
+	MaxResponseBodyLength = 1024
+	// DefaultMaxResponseBytes is the default maximum size of the response bodies read by the HTTP based providers
+	DefaultMaxResponseBytes = 10 * 1024 * 1024
+	// redactedValue replaces the secrets in the recorded response bodies
+	redactedValue = "<redacted>"
+)
+
+// sensitiveFieldRegex matches the JSON string fields whose name looks like they hold a secret (e.g. "password": "...")
+var sensitiveFieldRegex = regexp.MustCompile(`(?i)("[^"]*(?:password|passwd|secret|token|api_?key|authorization|credential)[^"]*"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+// ResponseError is the error of a provider call which received a response, holding the body of the response
+type ResponseError struct {
+	Err  error
+	Body []byte
+}
+
+func (e *ResponseError) Error() string {
+	return e.Err.Error()
+}
+
 // MarkMeasurementError sets an error message on a measurement along with finish time
 func MarkMeasurementError(m v1alpha1.Measurement, err error) v1alpha1.Measurement {
 	m.Phase = v1alpha1.AnalysisPhaseError
@@ -16,3 +47,116 @@ func MarkMeasurementError(m v1alpha1.Measurement, err error) v1alpha1.Measuremen
 	}
 	return m
 }
+
+// MarkMeasurementResponseError sets an error message on a measurement like MarkMeasurementError. When
+// recordResponseBody is true and the error is a ResponseError, the response body is also recorded in the metadata of
+// the measurement, with the given secrets redacted
+func MarkMeasurementResponseError(m v1alpha1.Measurement, err error, recordResponseBody bool, secrets ...string) v1alpha1.Measurement {
+	if responseErr, ok := err.(*ResponseError); ok && recordResponseBody && len(responseErr.Body) > 0 {
+		if m.Metadata == nil {
+			m.Metadata = map[string]string{}
+		}
+		m.Metadata[ResponseBodyMetadataKey] = RedactResponseBody(responseErr.Body, secrets...)
+	}
+	return MarkMeasurementError(m, err)
+}
+
+// RedactResponseBody returns the response body with the given secrets and the values of the JSON fields with a
+// sensitive name redacted, truncated to MaxResponseBodyLength bytes
+func RedactResponseBody(body []byte, secrets ...string) string {
+	s := string(body)
+	for _, secret := range secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, redactedValue)
+		}
+	}
+	s = sensitiveFieldRegex.ReplaceAllString(s, `${1}"`+redactedValue+`"`)
+	// The body is truncated after the redaction so that no partial secret is left at the end
+	if len(s) > MaxResponseBodyLength {
+		s = fmt.Sprintf("%s... (%d bytes truncated)", strings.ToValidUTF8(s[:MaxResponseBodyLength], ""), len(s)-MaxResponseBodyLength)
+	}
+	return s
+}
+
+// ResponseTooLargeError is the error of reading a response body larger than the maximum size
+type ResponseTooLargeError struct {
+	MaxBytes int64
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("response body exceeds the maximum size of %d bytes", e.MaxBytes)
+}
+
+// maxBytesReader reads a response body up to a maximum number of bytes, and fails with a ResponseTooLargeError once
+// the body exceeds it, without reading the rest of the body
+type maxBytesReader struct {
+	body      io.ReadCloser
+	maxBytes  int64
+	remaining int64
+}
+
+func (r *maxBytesReader) Read(p []byte) (int, error) {
+	if r.remaining < 0 {
+		return 0, &ResponseTooLargeError{MaxBytes: r.maxBytes}
+	}
+	// One byte more than the remaining bytes is read to find out whether the body exceeds the maximum size
+	if int64(len(p)) > r.remaining+1 {
+		p = p[:r.remaining+1]
+	}
+	n, err := r.body.Read(p)
+	if int64(n) > r.remaining {
+		n = int(r.remaining)
+		r.remaining = -1
+		return n, &ResponseTooLargeError{MaxBytes: r.maxBytes}
+	}
+	r.remaining -= int64(n)
+	return n, err
+}
+
+func (r *maxBytesReader) Close() error {
+	return r.body.Close()
+}
+
+// maxResponseBytesTransport limits the size of the bodies of the responses of its transport
+type maxResponseBytesTransport struct {
+	transport http.RoundTripper
+	maxBytes  int64
+}
+
+func (t *maxResponseBytesTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = &maxBytesReader{body: resp.Body, maxBytes: t.maxBytes, remaining: t.maxBytes}
+	return resp, nil
+}
+
+// ReadResponseBody reads a response body. The error is a ResponseTooLargeError when the body exceeds the maximum size
+// of the client
+func ReadResponseBody(body io.Reader) ([]byte, error) {
+	bodyBytes, err := ioutil.ReadAll(body)
+	if err != nil {
+		if _, ok := err.(*ResponseTooLargeError); ok {
+			return nil, err
+		}
+		return nil, fmt.Errorf("Received no bytes in response: %v", err)
+	}
+	return bodyBytes, nil
+}
+
+// LimitResponseBytes returns a copy of the client whose response bodies fail with a ResponseTooLargeError when they
+// are read past maxBytes, which keeps a misbehaving endpoint from exhausting the memory of the controller. The client
+// is returned as is when maxBytes is not positive.
+func LimitResponseBytes(client *http.Client, maxBytes int64) *http.Client {
+	if maxBytes <= 0 {
+		return client
+	}
+	transport := client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	limited := *client
+	limited.Transport = &maxResponseBytesTransport{transport: transport, maxBytes: maxBytes}
+	return &limited
+}
@@ -2,6 +2,11 @@ package metric
 
 import (
 	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -20,3 +25,106 @@ func TestMarkMeasurementError(t *testing.T) {
 	assert.Equal(t, err.Error(), m.Message)
 	assert.NotNil(t, m.FinishedAt)
 }
+
+func TestMarkMeasurementResponseError(t *testing.T) {
+	err := &ResponseError{
+		Err:  errors.New("received non 2xx response code: 500"),
+		Body: []byte(`{"error": "query failed", "token": "abc"}`),
+	}
+
+	m := MarkMeasurementResponseError(v1alpha1.Measurement{}, err, false)
+	assert.Equal(t, v1alpha1.AnalysisPhaseError, m.Phase)
+	assert.Equal(t, "received non 2xx response code: 500", m.Message)
+	assert.Nil(t, m.Metadata)
+
+	m = MarkMeasurementResponseError(v1alpha1.Measurement{Metadata: map[string]string{"foo": "bar"}}, err, true)
+	assert.Equal(t, "received non 2xx response code: 500", m.Message)
+	assert.Equal(t, map[string]string{
+		"foo":                   "bar",
+		ResponseBodyMetadataKey: `{"error": "query failed", "token": "<redacted>"}`,
+	}, m.Metadata)
+
+	m = MarkMeasurementResponseError(v1alpha1.Measurement{}, errors.New("connection refused"), true)
+	assert.Equal(t, "connection refused", m.Message)
+	assert.Nil(t, m.Metadata)
+}
+
+func TestRedactResponseBody(t *testing.T) {
+	body := `{"user": "admin", "Password": "hunter2", "api_key": "k\"ey", "accessToken": "t0k3n", "message": "invalid credentials for Bearer s3cr3t"}`
+	assert.Equal(t,
+		`{"user": "admin", "Password": "<redacted>", "api_key": "<redacted>", "accessToken": "<redacted>", "message": "invalid credentials for Bearer <redacted>"}`,
+		RedactResponseBody([]byte(body), "s3cr3t", ""))
+}
+
+func TestRedactResponseBodyTruncates(t *testing.T) {
+	body := strings.Repeat("a", MaxResponseBodyLength-1) + "é" + strings.Repeat("b", 99)
+	redacted := RedactResponseBody([]byte(body))
+	assert.Equal(t, strings.Repeat("a", MaxResponseBodyLength-1)+"... (100 bytes truncated)", redacted)
+
+	// The secrets are redacted before the truncation
+	secret := "s3cr3t"
+	body = strings.Repeat("a", MaxResponseBodyLength-3) + secret
+	redacted = RedactResponseBody([]byte(body), secret)
+	assert.Equal(t, strings.Repeat("a", MaxResponseBodyLength-3)+"<re... (7 bytes truncated)", redacted)
+	assert.NotContains(t, redacted, "s3c")
+
+	assert.Equal(t, "short", RedactResponseBody([]byte("short")))
+}
+
+func TestLimitResponseBytes(t *testing.T) {
+	body := strings.Repeat("a", 100)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		io.WriteString(rw, body)
+	}))
+	defer server.Close()
+
+	get := func(client *http.Client) ([]byte, error) {
+		response, err := client.Get(server.URL)
+		assert.NoError(t, err)
+		defer response.Body.Close()
+		return ReadResponseBody(response.Body)
+	}
+
+	// The client is not limited when the maximum size is not positive
+	assert.Equal(t, server.Client(), LimitResponseBytes(server.Client(), 0))
+
+	bodyBytes, err := get(LimitResponseBytes(server.Client(), 100))
+	assert.NoError(t, err)
+	assert.Equal(t, body, string(bodyBytes))
+
+	bodyBytes, err = get(LimitResponseBytes(server.Client(), 99))
+	assert.Equal(t, &ResponseTooLargeError{MaxBytes: 99}, err)
+	assert.EqualError(t, err, "response body exceeds the maximum size of 99 bytes")
+	assert.Nil(t, bodyBytes)
+}
+
+func TestMaxBytesReader(t *testing.T) {
+	r := &maxBytesReader{body: ioutil.NopCloser(strings.NewReader("abcdef")), maxBytes: 4, remaining: 4}
+	// The bytes up to the maximum size are returned along with the error
+	p := make([]byte, 10)
+	n, err := r.Read(p)
+	assert.Equal(t, 4, n)
+	assert.Equal(t, "abcd", string(p[:n]))
+	assert.Equal(t, &ResponseTooLargeError{MaxBytes: 4}, err)
+
+	n, err = r.Read(p)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, &ResponseTooLargeError{MaxBytes: 4}, err)
+}
+
+func TestReadResponseBody(t *testing.T) {
+	bodyBytes, err := ReadResponseBody(strings.NewReader("abc"))
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", string(bodyBytes))
+
+	_, err = ReadResponseBody(&errorReader{err: errors.New("connection reset")})
+	assert.EqualError(t, err, "Received no bytes in response: connection reset")
+}
+
+type errorReader struct {
+	err error
+}
+
+func (r *errorReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
@@ -1,7 +1,9 @@
 package replicaset
 
 import (
+	"fmt"
 	"math"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 
@@ -28,6 +30,7 @@ func AtDesiredReplicaCountsForCanary(rollout *v1alpha1.Rollout, newRS, stableRS
 func DesiredReplicaCountsForCanary(rollout *v1alpha1.Rollout, newRS, stableRS *appsv1.ReplicaSet) (int32, int32) {
 	rolloutSpecReplica := defaults.GetReplicasOrDefault(rollout.Spec.Replicas)
 	replicas, weight := GetCanaryReplicasOrWeight(rollout)
+	maxWeight := float64(defaults.GetMaxTrafficWeightOrDefault(rollout))
 
 	desiredNewRSReplicaCount := int32(0)
 	desiredStableRSReplicaCount := int32(0)
@@ -35,8 +38,8 @@ func DesiredReplicaCountsForCanary(rollout *v1alpha1.Rollout, newRS, stableRS *a
 		desiredNewRSReplicaCount = *replicas
 		desiredStableRSReplicaCount = rolloutSpecReplica
 	} else {
-		desiredNewRSReplicaCount = int32(math.Ceil(float64(rolloutSpecReplica) * (float64(weight) / 100)))
-		desiredStableRSReplicaCount = int32(math.Ceil(float64(rolloutSpecReplica) * (1 - (float64(weight) / 100))))
+		desiredNewRSReplicaCount = int32(math.Ceil(float64(rolloutSpecReplica) * (float64(weight) / maxWeight)))
+		desiredStableRSReplicaCount = int32(math.Ceil(float64(rolloutSpecReplica) * (1 - (float64(weight) / maxWeight))))
 	}
 
 	if !CheckStableRSExists(newRS, stableRS) {
@@ -58,8 +61,10 @@ func DesiredReplicaCountsForCanary(rollout *v1alpha1.Rollout, newRS, stableRS *a
 // CalculateReplicaCountsForCanary calculates the number of replicas for the newRS and the stableRS.  The function
 // calculates the desired number of replicas for the new and stable RS using the following equations:
 //
-// newRS Replica count = spec.Replica * (setweight / 100)
-// stableRS Replica count = spec.Replica * (1 - setweight / 100)
+// newRS Replica count = spec.Replica * (setweight / maxTrafficWeight)
+// stableRS Replica count = spec.Replica * (1 - setweight / maxTrafficWeight)
+//
+// The maxTrafficWeight is 100 unless the traffic routing of the rollout sets weights with a finer precision.
 //
 // In both equations, the function rounds the desired replica count up if the math does not divide into whole numbers
 // because the rollout guarantees at least one replica for both the stable and new RS when the setWeight is not 0 or 100.
@@ -96,10 +101,22 @@ func CalculateReplicaCountsForCanary(rollout *v1alpha1.Rollout, newRS *appsv1.Re
 		return *replicas, rolloutSpecReplica
 	}
 
-	desiredStableRSReplicaCount := int32(math.Ceil(float64(rolloutSpecReplica) * (1 - (float64(weight) / 100))))
-	desiredNewRSReplicaCount := int32(math.Ceil(float64(rolloutSpecReplica) * (float64(weight) / 100)))
+	maxTrafficWeight := defaults.GetMaxTrafficWeightOrDefault(rollout)
+	maxWeight := float64(maxTrafficWeight)
+	desiredStableRSReplicaCount := int32(math.Ceil(float64(rolloutSpecReplica) * (1 - (float64(weight) / maxWeight))))
+	desiredNewRSReplicaCount := int32(math.Ceil(float64(rolloutSpecReplica) * (float64(weight) / maxWeight)))
 
 	if rollout.Spec.Strategy.Canary.TrafficRouting != nil {
+		if InCanaryQuarantine(rollout, nowFn()) {
+			// The quarantined canary pods are kept without traffic once the abort ramp down has finished
+			quarantineReplicas := defaults.GetQuarantineReplicasOrDefault(rollout)
+			if quarantineReplicas > rolloutSpecReplica {
+				quarantineReplicas = rolloutSpecReplica
+			}
+			if desiredNewRSReplicaCount < quarantineReplicas {
+				desiredNewRSReplicaCount = quarantineReplicas
+			}
+		}
 		return desiredNewRSReplicaCount, rolloutSpecReplica
 	}
 
@@ -121,7 +138,7 @@ func CalculateReplicaCountsForCanary(rollout *v1alpha1.Rollout, newRS *appsv1.Re
 
 	maxSurge := MaxSurge(rollout)
 
-	if extraReplicaAdded(rolloutSpecReplica, weight) {
+	if extraReplicaAdded(rolloutSpecReplica, weight, maxTrafficWeight) {
 		// In the case where the weight of the stable and canary replica counts cannot be divided evenly,
 		// the controller needs to surges by one to account for both replica counts being rounded up.
 		maxSurge = maxSurge + 1
@@ -247,9 +264,9 @@ func GetReplicasForScaleDown(rs *appsv1.ReplicaSet) int32 {
 
 // extraReplicaAdded checks if an extra replica is added because the stable and canary replicas count are both
 // rounded up. The controller rounds both of the replica counts when the setWeight does not distribute evenly
-// in order to prevent either from having a 0 replica count.
-func extraReplicaAdded(replicas int32, setWeight int32) bool {
-	_, frac := math.Modf(float64(replicas) * (float64(setWeight) / 100))
+// in order to prevent either from having a 0 replica count. The setWeight is out of the maxTrafficWeight.
+func extraReplicaAdded(replicas int32, setWeight int32, maxTrafficWeight int32) bool {
+	_, frac := math.Modf(float64(replicas) * (float64(setWeight) / float64(maxTrafficWeight)))
 	return frac != 0.0
 }
 
@@ -269,39 +286,318 @@ func GetCurrentCanaryStep(rollout *v1alpha1.Rollout) (*v1alpha1.CanaryStep, *int
 	return &rollout.Spec.Strategy.Canary.Steps[currentStepIndex], &currentStepIndex
 }
 
-// GetCanaryReplicasOrWeight either returns a static set of replicas or a weight percentage
+// GetCanaryReplicasOrWeight either returns a static set of replicas or a weight relative to the maxTrafficWeight of
+// the rollout
 func GetCanaryReplicasOrWeight(rollout *v1alpha1.Rollout) (*int32, int32) {
+	if WaitingForDependentRollout(rollout) || InCanaryWarmup(rollout) {
+		return nil, GetCurrentSetWeight(rollout)
+	}
 	if scs := UseSetCanaryScale(rollout); scs != nil {
 		if scs.Replicas != nil {
 			return scs.Replicas, 0
 		} else if scs.Weight != nil {
-			return nil, *scs.Weight
+			// The weight of the setCanaryScale is a percentage of the replicas
+			return nil, *scs.Weight * defaults.GetMaxTrafficWeightOrDefault(rollout) / 100
 		}
 	}
+	if IsPreTrafficAnalysisStep(rollout) {
+		replicas := PreTrafficAnalysisReplicas
+		return &replicas, 0
+	}
 	return nil, GetCurrentSetWeight(rollout)
 }
 
+// PreTrafficAnalysisReplicas is the number of canary replicas kept scaled up during a pre-traffic analysis step,
+// unless a previous setCanaryScale step scales the canary
+const PreTrafficAnalysisReplicas = int32(1)
+
+// IsPreTrafficAnalysisStep returns whether the current step is an analysis step which runs before any traffic is
+// shifted to the canary. Pre-traffic analysis requires TrafficRouting, which keeps all the traffic on the stable
+// while the canary is scaled up for the analysis.
+func IsPreTrafficAnalysisStep(rollout *v1alpha1.Rollout) bool {
+	if rollout.Spec.Strategy.Canary == nil || rollout.Spec.Strategy.Canary.TrafficRouting == nil || rollout.Status.Abort {
+		return false
+	}
+	currentStep, _ := GetCurrentCanaryStep(rollout)
+	if currentStep == nil || currentStep.Analysis == nil {
+		return false
+	}
+	return getStepSetWeight(rollout) == 0
+}
+
 // GetCurrentSetWeight grabs the current setWeight used by the rollout by iterating backwards from the current step
-// until it finds a setWeight step. The controller defaults to 100 if it iterates through all the steps with no
-// setWeight or if there is no current step (i.e. the controller has already stepped through all the steps).
+// until it finds a setWeight step. The controller defaults to the maxTrafficWeight (100 unless set by the traffic
+// routing) if there is no current step (i.e. the controller has already stepped through all the steps).
 func GetCurrentSetWeight(rollout *v1alpha1.Rollout) int32 {
 	if rollout.Status.Abort {
+		return GetAbortRampDownWeight(rollout, nowFn())
+	}
+	return getStepSetWeight(rollout)
+}
+
+// InCanaryWarmup returns whether the canary of the rollout is held at the warmup weight before the first step,
+// which lasts until the canary pods passed the warmup ready check
+func InCanaryWarmup(rollout *v1alpha1.Rollout) bool {
+	canary := rollout.Spec.Strategy.Canary
+	if canary == nil || canary.WarmupWeight == nil || rollout.Status.Abort || rollout.Status.Canary.WarmupCompleted {
+		return false
+	}
+	if rollout.Status.StableRS == "" || rollout.Status.StableRS == rollout.Status.CurrentPodHash {
+		return false
+	}
+	currentStep, currentStepIndex := GetCurrentCanaryStep(rollout)
+	return currentStep != nil && *currentStepIndex == 0
+}
+
+// WaitingForCanaryReadinessGate returns whether the traffic of the canary of the rollout is held at 0 until all the
+// canary pods satisfy the readiness gate
+func WaitingForCanaryReadinessGate(rollout *v1alpha1.Rollout) bool {
+	canary := rollout.Spec.Strategy.Canary
+	if canary == nil || canary.ReadinessGate == nil || canary.TrafficRouting == nil || rollout.Status.Abort || rollout.Status.Canary.ReadinessGateSatisfied {
+		return false
+	}
+	if rollout.Status.StableRS == "" || rollout.Status.StableRS == rollout.Status.CurrentPodHash {
+		return false
+	}
+	currentStep, _ := GetCurrentCanaryStep(rollout)
+	return currentStep != nil
+}
+
+// WaitingForDependentRollout returns whether the canary of the rollout is held at a weight of 0, without running its
+// analyses, until the rollout it depends on is Healthy
+func WaitingForDependentRollout(rollout *v1alpha1.Rollout) bool {
+	canary := rollout.Spec.Strategy.Canary
+	if canary == nil || canary.DependsOnRollout == "" || rollout.Status.Abort || rollout.Status.Canary.DependencySatisfied {
+		return false
+	}
+	if rollout.Status.StableRS == "" || rollout.Status.StableRS == rollout.Status.CurrentPodHash {
+		return false
+	}
+	currentStep, currentStepIndex := GetCurrentCanaryStep(rollout)
+	return currentStep != nil && *currentStepIndex == 0
+}
+
+// RebalancedCanaryWeight returns the weight of the current step reduced proportionally to the available canary pods,
+// and whether the canary lost pods after the current step reached its replica counts. The weight is only rebalanced
+// when the canary strategy rebalances the weight on replica loss.
+func RebalancedCanaryWeight(rollout *v1alpha1.Rollout, newRS, stableRS *appsv1.ReplicaSet) (int32, bool) {
+	canary := rollout.Spec.Strategy.Canary
+	if canary == nil || !canary.RebalanceWeightOnReplicaLoss || newRS == nil || newRS.Spec.Replicas == nil {
+		return 0, false
+	}
+	_, currentStepIndex := GetCurrentCanaryStep(rollout)
+	reachedStepIndex := rollout.Status.Canary.ReplicasReachedStepIndex
+	if currentStepIndex == nil || reachedStepIndex == nil || *reachedStepIndex != *currentStepIndex {
+		return 0, false
+	}
+	// A canary scaling to a new replica count is not short of replicas because of lost pods
+	desiredNewRSReplicaCount, _ := DesiredReplicaCountsForCanary(rollout, newRS, stableRS)
+	if desiredNewRSReplicaCount == 0 || *newRS.Spec.Replicas != desiredNewRSReplicaCount || newRS.Status.AvailableReplicas >= desiredNewRSReplicaCount {
+		return 0, false
+	}
+	return GetCurrentSetWeight(rollout) * newRS.Status.AvailableReplicas / desiredNewRSReplicaCount, true
+}
+
+// getStepSetWeight returns the setWeight of the current step, or the warmup weight while the canary is warming up,
+// capped by the MaxWeightSchedule. The weight is 0 while the rollout waits for the rollout it depends on.
+func getStepSetWeight(rollout *v1alpha1.Rollout) int32 {
+	if WaitingForDependentRollout(rollout) {
 		return 0
 	}
+	// An error evaluating the MaxWeightSchedule is reported by the rollout controller. The weight is still capped by
+	// the lowest max weight of the windows, so the canary never exceeds the schedule
+	if InCanaryWarmup(rollout) {
+		weight, _ := CapWeightByMaxWeightSchedule(rollout, *rollout.Spec.Strategy.Canary.WarmupWeight, nowFn())
+		return weight
+	}
 	currentStep, currentStepIndex := GetCurrentCanaryStep(rollout)
 	if currentStep == nil {
-		return 100
+		return defaults.GetMaxTrafficWeightOrDefault(rollout)
 	}
 
 	for i := *currentStepIndex; i >= 0; i-- {
 		step := rollout.Spec.Strategy.Canary.Steps[i]
 		if step.SetWeight != nil {
-			return *step.SetWeight
+			weight, _ := CapWeightByMaxWeightSchedule(rollout, *step.SetWeight, nowFn())
+			return weight
 		}
 	}
 	return 0
 }
 
+// abortRampDownSteps is the number of steps in which the traffic is shifted back to the stable version during an
+// abort ramp down
+const abortRampDownSteps = 10
+
+// getAbortRampDown returns the duration of the abort ramp down of the rollout, or zero if it is not configured
+func getAbortRampDown(rollout *v1alpha1.Rollout) time.Duration {
+	if rollout.Spec.Strategy.Canary == nil || rollout.Spec.Strategy.Canary.TrafficRouting == nil {
+		return 0
+	}
+	rampDownSeconds := rollout.Spec.Strategy.Canary.TrafficRouting.AbortRampDownSeconds
+	if rampDownSeconds == nil || *rampDownSeconds <= 0 {
+		return 0
+	}
+	return time.Duration(*rampDownSeconds) * time.Second
+}
+
+// GetAbortRampDownWeight returns the canary weight of an aborted rollout at the given time. The weight at the time
+// of the abort is lowered to zero in equal steps over the AbortRampDownSeconds of the trafficRouting. Zero is
+// returned if the rollout has no abort ramp down or the ramp down has finished.
+func GetAbortRampDownWeight(rollout *v1alpha1.Rollout, now time.Time) int32 {
+	rampDown := getAbortRampDown(rollout)
+	if rampDown == 0 {
+		return 0
+	}
+	if rollout.Status.AbortedAt == nil {
+		// The controller has not reconciled the abort yet, so the ramp down has not started
+		return getStepSetWeight(rollout)
+	}
+	stepDuration := rampDown / abortRampDownSteps
+	elapsed := now.Sub(rollout.Status.AbortedAt.Time)
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	completedSteps := int32(elapsed / stepDuration)
+	if completedSteps >= abortRampDownSteps {
+		return 0
+	}
+	return rollout.Status.Canary.AbortedWeight * (abortRampDownSteps - completedSteps) / abortRampDownSteps
+}
+
+// GetNextAbortRampDownStep returns the duration until the next step of the abort ramp down after the given time.
+// Nil is returned if the rollout is not aborted, has no abort ramp down or the ramp down has finished.
+func GetNextAbortRampDownStep(rollout *v1alpha1.Rollout, now time.Time) *time.Duration {
+	rampDown := getAbortRampDown(rollout)
+	if !rollout.Status.Abort || rampDown == 0 || rollout.Status.AbortedAt == nil {
+		return nil
+	}
+	stepDuration := rampDown / abortRampDownSteps
+	elapsed := now.Sub(rollout.Status.AbortedAt.Time)
+	if elapsed >= rampDown {
+		return nil
+	}
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	untilNextStep := stepDuration - elapsed%stepDuration
+	return &untilNextStep
+}
+
+// InCanaryQuarantine returns whether the canary pods of the aborted rollout are kept in quarantine at the given time.
+// The quarantine lasts for the TTLSeconds of the QuarantineOnFailure after the abort, and ends as soon as the abort
+// is removed.
+func InCanaryQuarantine(rollout *v1alpha1.Rollout, now time.Time) bool {
+	canary := rollout.Spec.Strategy.Canary
+	if canary == nil || canary.QuarantineOnFailure == nil || canary.TrafficRouting == nil || !rollout.Status.Abort {
+		return false
+	}
+	if rollout.Status.StableRS == "" || rollout.Status.StableRS == rollout.Status.CurrentPodHash {
+		return false
+	}
+	if rollout.Status.AbortedAt == nil {
+		// The controller has not reconciled the abort yet, so the quarantine has not started
+		return true
+	}
+	return now.Before(getCanaryQuarantineExpiration(rollout))
+}
+
+// GetCanaryQuarantineExpiration returns the duration until the quarantine of the canary pods of the aborted rollout
+// expires after the given time. Nil is returned if the canary pods are not in quarantine or the controller has not
+// reconciled the abort yet.
+func GetCanaryQuarantineExpiration(rollout *v1alpha1.Rollout, now time.Time) *time.Duration {
+	if !InCanaryQuarantine(rollout, now) || rollout.Status.AbortedAt == nil {
+		return nil
+	}
+	untilExpiration := getCanaryQuarantineExpiration(rollout).Sub(now)
+	return &untilExpiration
+}
+
+func getCanaryQuarantineExpiration(rollout *v1alpha1.Rollout) time.Time {
+	ttl := time.Duration(defaults.GetQuarantineTTLSecondsOrDefault(rollout)) * time.Second
+	return rollout.Status.AbortedAt.Add(ttl)
+}
+
+// nowFn is used to get the current time when evaluating the MaxWeightSchedule and the abort ramp down and can be
+// overridden in tests
+var nowFn = func() time.Time { return time.Now() }
+
+// CapWeightByMaxWeightSchedule returns the weight capped by the max weight of the MaxWeightSchedule window active
+// at the given time. The weight is returned as is if the rollout has no MaxWeightSchedule or no window is active.
+// If the schedule cannot be evaluated, such as when the time zone database is unavailable, the weight is capped by
+// the lowest max weight of the windows and an error is returned.
+func CapWeightByMaxWeightSchedule(rollout *v1alpha1.Rollout, weight int32, now time.Time) (int32, error) {
+	if rollout.Spec.Strategy.Canary == nil || rollout.Spec.Strategy.Canary.MaxWeightSchedule == nil {
+		return weight, nil
+	}
+	schedule := rollout.Spec.Strategy.Canary.MaxWeightSchedule
+	loc, err := schedule.Location()
+	if err != nil {
+		return capWeightByLowestMaxWeight(schedule, weight), fmt.Errorf("unable to load the time zone '%s' of the maxWeightSchedule: %v", schedule.TimeZone, err)
+	}
+	local := now.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	for _, window := range schedule.Windows {
+		start, end, err := window.Minutes()
+		if err != nil {
+			return capWeightByLowestMaxWeight(schedule, weight), fmt.Errorf("invalid maxWeightSchedule window %s-%s: %v", window.StartTime, window.EndTime, err)
+		}
+		active := start <= minute && minute < end
+		if start > end {
+			// the window spans midnight
+			active = minute >= start || minute < end
+		}
+		if active && weight > window.MaxWeight {
+			return window.MaxWeight, nil
+		}
+	}
+	return weight, nil
+}
+
+// capWeightByLowestMaxWeight returns the weight capped by the lowest max weight of the windows of the schedule
+func capWeightByLowestMaxWeight(schedule *v1alpha1.MaxWeightSchedule, weight int32) int32 {
+	for _, window := range schedule.Windows {
+		if weight > window.MaxWeight {
+			weight = window.MaxWeight
+		}
+	}
+	return weight
+}
+
+// GetNextMaxWeightScheduleTransition returns the duration until the next start or end of a MaxWeightSchedule
+// window after the given time. Nil is returned if the rollout has no MaxWeightSchedule, and an error if the schedule
+// cannot be evaluated.
+func GetNextMaxWeightScheduleTransition(rollout *v1alpha1.Rollout, now time.Time) (*time.Duration, error) {
+	if rollout.Spec.Strategy.Canary == nil || rollout.Spec.Strategy.Canary.MaxWeightSchedule == nil {
+		return nil, nil
+	}
+	schedule := rollout.Spec.Strategy.Canary.MaxWeightSchedule
+	loc, err := schedule.Location()
+	if err != nil {
+		return nil, fmt.Errorf("unable to load the time zone '%s' of the maxWeightSchedule: %v", schedule.TimeZone, err)
+	}
+	local := now.In(loc)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	var next *time.Duration
+	for _, window := range schedule.Windows {
+		start, end, err := window.Minutes()
+		if err != nil {
+			return nil, fmt.Errorf("invalid maxWeightSchedule window %s-%s: %v", window.StartTime, window.EndTime, err)
+		}
+		for _, minute := range []int{start, end} {
+			transition := midnight.Add(time.Duration(minute) * time.Minute)
+			if !transition.After(local) {
+				transition = transition.AddDate(0, 0, 1)
+			}
+			untilTransition := transition.Sub(local)
+			if next == nil || untilTransition < *next {
+				next = &untilTransition
+			}
+		}
+	}
+	return next, nil
+}
+
 // UseSetCanaryScale will return a SetCanaryScale if specified and should be used, returns nil otherwise.
 // TrafficRouting is required to be set for SetCanaryScale to be applicable.
 // If MatchTrafficWeight is set after a previous SetCanaryScale step, it will likewise be ignored.
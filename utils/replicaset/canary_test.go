@@ -2,6 +2,7 @@ package replicaset
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	appsv1 "k8s.io/api/apps/v1"
@@ -474,6 +475,197 @@ func TestCalculateReplicaCountsForCanaryTrafficRouting(t *testing.T) {
 	assert.Equal(t, int32(10), stableRSReplicaCount)
 }
 
+func TestExtraReplicaAdded(t *testing.T) {
+	assert.True(t, extraReplicaAdded(10, 25, 100))
+	assert.False(t, extraReplicaAdded(10, 50, 100))
+	// the weight is out of the maxTrafficWeight rather than out of 100
+	assert.True(t, extraReplicaAdded(3, 500, 1000))
+	assert.False(t, extraReplicaAdded(4, 250, 1000))
+	assert.True(t, extraReplicaAdded(2, 50, 1000))
+}
+
+func newPreTrafficAnalysisRollout() *v1alpha1.Rollout {
+	rollout := newRollout(10, 10, intstr.FromInt(0), intstr.FromInt(1), "canary", "stable", nil, &v1alpha1.RolloutTrafficRouting{})
+	rollout.Spec.Strategy.Canary.Steps = []v1alpha1.CanaryStep{
+		{Analysis: &v1alpha1.RolloutAnalysis{TemplateName: "synthetic-check"}},
+		{SetWeight: pointer.Int32Ptr(20)},
+		{Analysis: &v1alpha1.RolloutAnalysis{TemplateName: "success-rate"}},
+	}
+	rollout.Status.CurrentStepIndex = pointer.Int32Ptr(0)
+	return rollout
+}
+
+func TestIsPreTrafficAnalysisStep(t *testing.T) {
+	rollout := newPreTrafficAnalysisRollout()
+	assert.True(t, IsPreTrafficAnalysisStep(rollout))
+
+	rollout.Status.Abort = true
+	assert.False(t, IsPreTrafficAnalysisStep(rollout))
+	rollout.Status.Abort = false
+
+	rollout.Spec.Strategy.Canary.TrafficRouting = nil
+	assert.False(t, IsPreTrafficAnalysisStep(rollout))
+	rollout.Spec.Strategy.Canary.TrafficRouting = &v1alpha1.RolloutTrafficRouting{}
+
+	// the analysis step runs after traffic is shifted to the canary
+	rollout.Status.CurrentStepIndex = pointer.Int32Ptr(2)
+	assert.False(t, IsPreTrafficAnalysisStep(rollout))
+
+	rollout.Status.CurrentStepIndex = pointer.Int32Ptr(1)
+	assert.False(t, IsPreTrafficAnalysisStep(rollout))
+}
+
+func TestInCanaryWarmup(t *testing.T) {
+	rollout := newRollout(10, 50, intstr.FromInt(0), intstr.FromInt(1), "canary", "stable", nil, nil)
+	rollout.Status.StableRS = "stable"
+	rollout.Status.CurrentStepIndex = pointer.Int32Ptr(0)
+	assert.False(t, InCanaryWarmup(rollout))
+	assert.Equal(t, int32(50), GetCurrentSetWeight(rollout))
+
+	rollout.Spec.Strategy.Canary.WarmupWeight = pointer.Int32Ptr(1)
+	assert.True(t, InCanaryWarmup(rollout))
+	assert.Equal(t, int32(1), GetCurrentSetWeight(rollout))
+	newRSReplicaCount, stableRSReplicaCount := CalculateReplicaCountsForCanary(rollout, newRS("canary", 0, 0), newRS("stable", 10, 10), nil)
+	assert.Equal(t, int32(1), newRSReplicaCount)
+	assert.Equal(t, int32(10), stableRSReplicaCount)
+
+	// the warmup weight takes precedence over a setCanaryScale first step
+	rollout.Spec.Strategy.Canary.Steps[0].SetCanaryScale = newSetCanaryScale(pointer.Int32Ptr(5), nil, false)
+	replicas, weight := GetCanaryReplicasOrWeight(rollout)
+	assert.Nil(t, replicas)
+	assert.Equal(t, int32(1), weight)
+	rollout.Spec.Strategy.Canary.Steps[0].SetCanaryScale = nil
+
+	rollout.Status.Canary.WarmupCompleted = true
+	assert.False(t, InCanaryWarmup(rollout))
+	assert.Equal(t, int32(50), GetCurrentSetWeight(rollout))
+	rollout.Status.Canary.WarmupCompleted = false
+
+	rollout.Status.Abort = true
+	assert.False(t, InCanaryWarmup(rollout))
+	rollout.Status.Abort = false
+
+	// the new RS is already the stable RS
+	rollout.Status.CurrentPodHash = "stable"
+	assert.False(t, InCanaryWarmup(rollout))
+	rollout.Status.CurrentPodHash = "canary"
+
+	rollout.Status.CurrentStepIndex = pointer.Int32Ptr(1)
+	assert.False(t, InCanaryWarmup(rollout))
+}
+
+func TestWaitingForCanaryReadinessGate(t *testing.T) {
+	rollout := newRollout(10, 50, intstr.FromInt(0), intstr.FromInt(1), "canary", "stable", nil, nil)
+	rollout.Status.StableRS = "stable"
+	rollout.Status.CurrentStepIndex = pointer.Int32Ptr(0)
+	rollout.Spec.Strategy.Canary.ReadinessGate = &v1alpha1.CanaryReadinessGate{ConditionType: "MeshConfigured"}
+	// the readiness gate requires traffic routing
+	assert.False(t, WaitingForCanaryReadinessGate(rollout))
+
+	rollout.Spec.Strategy.Canary.TrafficRouting = &v1alpha1.RolloutTrafficRouting{}
+	assert.True(t, WaitingForCanaryReadinessGate(rollout))
+
+	rollout.Status.Canary.ReadinessGateSatisfied = true
+	assert.False(t, WaitingForCanaryReadinessGate(rollout))
+	rollout.Status.Canary.ReadinessGateSatisfied = false
+
+	rollout.Status.Abort = true
+	assert.False(t, WaitingForCanaryReadinessGate(rollout))
+	rollout.Status.Abort = false
+
+	// the new RS is already the stable RS
+	rollout.Status.CurrentPodHash = "stable"
+	assert.False(t, WaitingForCanaryReadinessGate(rollout))
+	rollout.Status.CurrentPodHash = "canary"
+
+	// all the steps are completed
+	rollout.Status.CurrentStepIndex = pointer.Int32Ptr(1)
+	assert.False(t, WaitingForCanaryReadinessGate(rollout))
+}
+
+func TestRebalancedCanaryWeight(t *testing.T) {
+	rollout := newRollout(10, 50, intstr.FromInt(0), intstr.FromInt(1), "canary", "stable", nil, &v1alpha1.RolloutTrafficRouting{})
+	rollout.Status.StableRS = "stable"
+	rollout.Status.CurrentStepIndex = pointer.Int32Ptr(0)
+	rollout.Status.Canary.ReplicasReachedStepIndex = pointer.Int32Ptr(0)
+	stableRS := newRS("stable", 10, 10)
+	canaryRS := newRS("canary", 5, 3)
+	// the weight is only rebalanced when enabled
+	_, lostReplicas := RebalancedCanaryWeight(rollout, canaryRS, stableRS)
+	assert.False(t, lostReplicas)
+
+	rollout.Spec.Strategy.Canary.RebalanceWeightOnReplicaLoss = true
+	weight, lostReplicas := RebalancedCanaryWeight(rollout, canaryRS, stableRS)
+	assert.True(t, lostReplicas)
+	assert.Equal(t, int32(30), weight)
+
+	// the canary recovered
+	canaryRS.Status.AvailableReplicas = 5
+	_, lostReplicas = RebalancedCanaryWeight(rollout, canaryRS, stableRS)
+	assert.False(t, lostReplicas)
+	canaryRS.Status.AvailableReplicas = 3
+
+	// the canary is scaling to the replica count of the step
+	rollout.Status.Canary.ReplicasReachedStepIndex = nil
+	_, lostReplicas = RebalancedCanaryWeight(rollout, canaryRS, stableRS)
+	assert.False(t, lostReplicas)
+	rollout.Status.Canary.ReplicasReachedStepIndex = pointer.Int32Ptr(0)
+	canaryRS.Spec.Replicas = pointer.Int32Ptr(4)
+	_, lostReplicas = RebalancedCanaryWeight(rollout, canaryRS, stableRS)
+	assert.False(t, lostReplicas)
+}
+
+func TestWaitingForDependentRollout(t *testing.T) {
+	rollout := newRollout(10, 50, intstr.FromInt(0), intstr.FromInt(1), "canary", "stable", nil, nil)
+	rollout.Status.StableRS = "stable"
+	rollout.Status.CurrentStepIndex = pointer.Int32Ptr(0)
+	assert.False(t, WaitingForDependentRollout(rollout))
+
+	rollout.Spec.Strategy.Canary.DependsOnRollout = "payments"
+	assert.True(t, WaitingForDependentRollout(rollout))
+	// the canary is held at a weight of 0
+	assert.Equal(t, int32(0), GetCurrentSetWeight(rollout))
+	replicas, weight := GetCanaryReplicasOrWeight(rollout)
+	assert.Nil(t, replicas)
+	assert.Equal(t, int32(0), weight)
+
+	rollout.Status.Canary.DependencySatisfied = true
+	assert.False(t, WaitingForDependentRollout(rollout))
+	assert.Equal(t, int32(50), GetCurrentSetWeight(rollout))
+	rollout.Status.Canary.DependencySatisfied = false
+
+	rollout.Status.Abort = true
+	assert.False(t, WaitingForDependentRollout(rollout))
+	rollout.Status.Abort = false
+
+	// the new RS is already the stable RS
+	rollout.Status.CurrentPodHash = "stable"
+	assert.False(t, WaitingForDependentRollout(rollout))
+	rollout.Status.CurrentPodHash = "canary"
+
+	// all the steps are completed
+	rollout.Status.CurrentStepIndex = pointer.Int32Ptr(1)
+	assert.False(t, WaitingForDependentRollout(rollout))
+}
+
+func TestCalculateReplicaCountsForCanaryPreTrafficAnalysis(t *testing.T) {
+	rollout := newPreTrafficAnalysisRollout()
+	stableRS := newRS("stable", 10, 10)
+	canaryRS := newRS("canary", 0, 0)
+	newRSReplicaCount, stableRSReplicaCount := CalculateReplicaCountsForCanary(rollout, canaryRS, stableRS, nil)
+	assert.Equal(t, PreTrafficAnalysisReplicas, newRSReplicaCount)
+	assert.Equal(t, int32(10), stableRSReplicaCount)
+	assert.Equal(t, int32(0), GetCurrentSetWeight(rollout))
+
+	// a setCanaryScale step takes precedence over the pre-traffic analysis replicas
+	rollout.Spec.Strategy.Canary.Steps = append([]v1alpha1.CanaryStep{{
+		SetCanaryScale: newSetCanaryScale(pointer.Int32Ptr(3), nil, false),
+	}}, rollout.Spec.Strategy.Canary.Steps...)
+	rollout.Status.CurrentStepIndex = pointer.Int32Ptr(1)
+	newRSReplicaCount, _ = CalculateReplicaCountsForCanary(rollout, canaryRS, stableRS, nil)
+	assert.Equal(t, int32(3), newRSReplicaCount)
+}
+
 func TestCalculateReplicaCountsForCanaryStableRSdEdgeCases(t *testing.T) {
 	rollout := newRollout(10, 10, intstr.FromInt(0), intstr.FromInt(1), "", "", nil, nil)
 	newRS := newRS("stable", 9, 9)
@@ -608,6 +800,231 @@ func TestGetCurrentSetWeight(t *testing.T) {
 
 }
 
+func TestCapWeightByMaxWeightSchedule(t *testing.T) {
+	rollout := newRollout(10, 10, intstr.FromInt(0), intstr.FromInt(1), "", "", nil, nil)
+	capWeight := func(weight int32, now time.Time) int32 {
+		capped, err := CapWeightByMaxWeightSchedule(rollout, weight, now)
+		assert.NoError(t, err)
+		return capped
+	}
+	assert.Equal(t, int32(50), capWeight(50, time.Now()))
+
+	rollout.Spec.Strategy.Canary.MaxWeightSchedule = &v1alpha1.MaxWeightSchedule{
+		TimeZone: "America/New_York",
+		Windows: []v1alpha1.MaxWeightWindow{
+			{StartTime: "09:00", EndTime: "17:00", MaxWeight: 10},
+			{StartTime: "22:00", EndTime: "02:00", MaxWeight: 30},
+		},
+	}
+	loc, err := time.LoadLocation("America/New_York")
+	assert.NoError(t, err)
+	// during business hours
+	assert.Equal(t, int32(10), capWeight(50, time.Date(2020, 6, 1, 9, 0, 0, 0, loc)))
+	assert.Equal(t, int32(5), capWeight(5, time.Date(2020, 6, 1, 12, 0, 0, 0, loc)))
+	// outside of the windows
+	assert.Equal(t, int32(50), capWeight(50, time.Date(2020, 6, 1, 17, 0, 0, 0, loc)))
+	assert.Equal(t, int32(50), capWeight(50, time.Date(2020, 6, 1, 8, 59, 0, 0, loc)))
+	// during the window spanning midnight
+	assert.Equal(t, int32(30), capWeight(50, time.Date(2020, 6, 1, 23, 0, 0, 0, loc)))
+	assert.Equal(t, int32(30), capWeight(50, time.Date(2020, 6, 2, 1, 0, 0, 0, loc)))
+	// the time is converted to the time zone of the schedule
+	assert.Equal(t, int32(10), capWeight(50, time.Date(2020, 6, 1, 16, 0, 0, 0, time.UTC)))
+}
+
+func TestCapWeightByMaxWeightScheduleUnknownTimeZone(t *testing.T) {
+	rollout := newRollout(10, 10, intstr.FromInt(0), intstr.FromInt(1), "", "", nil, nil)
+	rollout.Spec.Strategy.Canary.MaxWeightSchedule = &v1alpha1.MaxWeightSchedule{
+		TimeZone: "Mars/Olympus_Mons",
+		Windows: []v1alpha1.MaxWeightWindow{
+			{StartTime: "09:00", EndTime: "17:00", MaxWeight: 10},
+			{StartTime: "22:00", EndTime: "02:00", MaxWeight: 30},
+		},
+	}
+	// The weight is capped by the lowest max weight of the windows instead of being released
+	weight, err := CapWeightByMaxWeightSchedule(rollout, 50, time.Date(2020, 6, 1, 18, 0, 0, 0, time.UTC))
+	assert.Error(t, err)
+	assert.Equal(t, int32(10), weight)
+	weight, err = CapWeightByMaxWeightSchedule(rollout, 5, time.Date(2020, 6, 1, 18, 0, 0, 0, time.UTC))
+	assert.Error(t, err)
+	assert.Equal(t, int32(5), weight)
+
+	next, err := GetNextMaxWeightScheduleTransition(rollout, time.Date(2020, 6, 1, 18, 0, 0, 0, time.UTC))
+	assert.Error(t, err)
+	assert.Nil(t, next)
+}
+
+func TestGetNextMaxWeightScheduleTransition(t *testing.T) {
+	rollout := newRollout(10, 10, intstr.FromInt(0), intstr.FromInt(1), "", "", nil, nil)
+	next, err := GetNextMaxWeightScheduleTransition(rollout, time.Now())
+	assert.NoError(t, err)
+	assert.Nil(t, next)
+
+	rollout.Spec.Strategy.Canary.MaxWeightSchedule = &v1alpha1.MaxWeightSchedule{
+		Windows: []v1alpha1.MaxWeightWindow{
+			{StartTime: "09:00", EndTime: "17:00", MaxWeight: 10},
+		},
+	}
+	next, err = GetNextMaxWeightScheduleTransition(rollout, time.Date(2020, 6, 1, 8, 30, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, 30*time.Minute, *next)
+	next, err = GetNextMaxWeightScheduleTransition(rollout, time.Date(2020, 6, 1, 9, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, 8*time.Hour, *next)
+	next, err = GetNextMaxWeightScheduleTransition(rollout, time.Date(2020, 6, 1, 18, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, 15*time.Hour, *next)
+}
+
+func TestGetCurrentSetWeightWithMaxWeightSchedule(t *testing.T) {
+	defer func() {
+		nowFn = func() time.Time { return time.Now() }
+	}()
+	stepIndex := int32(0)
+	rollout := newRollout(10, 50, intstr.FromInt(0), intstr.FromInt(1), "", "", nil, nil)
+	rollout.Status.CurrentStepIndex = &stepIndex
+	rollout.Spec.Strategy.Canary.MaxWeightSchedule = &v1alpha1.MaxWeightSchedule{
+		Windows: []v1alpha1.MaxWeightWindow{
+			{StartTime: "09:00", EndTime: "17:00", MaxWeight: 20},
+		},
+	}
+	nowFn = func() time.Time { return time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC) }
+	assert.Equal(t, int32(20), GetCurrentSetWeight(rollout))
+
+	nowFn = func() time.Time { return time.Date(2020, 6, 1, 20, 0, 0, 0, time.UTC) }
+	assert.Equal(t, int32(50), GetCurrentSetWeight(rollout))
+
+	// the weight is not capped once the rollout completed all the steps
+	stepIndex = 1
+	nowFn = func() time.Time { return time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC) }
+	assert.Equal(t, int32(100), GetCurrentSetWeight(rollout))
+}
+
+func TestGetAbortRampDownWeight(t *testing.T) {
+	abortedAt := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	rollout := newRollout(10, 50, intstr.FromInt(0), intstr.FromInt(1), "", "", nil, &v1alpha1.RolloutTrafficRouting{})
+	rollout.Status.Abort = true
+	rollout.Status.AbortedAt = &metav1.Time{Time: abortedAt}
+	rollout.Status.Canary.AbortedWeight = 50
+	// the traffic is shifted back instantly without an abort ramp down
+	assert.Equal(t, int32(0), GetAbortRampDownWeight(rollout, abortedAt))
+
+	rampDownSeconds := int32(100)
+	rollout.Spec.Strategy.Canary.TrafficRouting.AbortRampDownSeconds = &rampDownSeconds
+	// the weight is lowered by a tenth of the aborted weight every tenth of the ramp down
+	for _, test := range []struct {
+		elapsed        time.Duration
+		expectedWeight int32
+	}{
+		{0, 50},
+		{9 * time.Second, 50},
+		{10 * time.Second, 45},
+		{25 * time.Second, 40},
+		{50 * time.Second, 25},
+		{95 * time.Second, 5},
+		{100 * time.Second, 0},
+		{time.Hour, 0},
+	} {
+		assert.Equal(t, test.expectedWeight, GetAbortRampDownWeight(rollout, abortedAt.Add(test.elapsed)), test.elapsed.String())
+	}
+
+	// the ramp down starts from the weight of the current step until the abort is reconciled
+	stepIndex := int32(0)
+	rollout.Status.CurrentStepIndex = &stepIndex
+	rollout.Status.AbortedAt = nil
+	assert.Equal(t, int32(50), GetAbortRampDownWeight(rollout, abortedAt.Add(time.Hour)))
+}
+
+func TestGetNextAbortRampDownStep(t *testing.T) {
+	abortedAt := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	rampDownSeconds := int32(100)
+	rollout := newRollout(10, 50, intstr.FromInt(0), intstr.FromInt(1), "", "", nil, &v1alpha1.RolloutTrafficRouting{
+		AbortRampDownSeconds: &rampDownSeconds,
+	})
+	assert.Nil(t, GetNextAbortRampDownStep(rollout, abortedAt))
+
+	rollout.Status.Abort = true
+	rollout.Status.AbortedAt = &metav1.Time{Time: abortedAt}
+	assert.Equal(t, 10*time.Second, *GetNextAbortRampDownStep(rollout, abortedAt))
+	assert.Equal(t, 6*time.Second, *GetNextAbortRampDownStep(rollout, abortedAt.Add(14*time.Second)))
+	assert.Equal(t, time.Second, *GetNextAbortRampDownStep(rollout, abortedAt.Add(99*time.Second)))
+	assert.Nil(t, GetNextAbortRampDownStep(rollout, abortedAt.Add(100*time.Second)))
+}
+
+func TestGetCurrentSetWeightWithAbortRampDown(t *testing.T) {
+	defer func() {
+		nowFn = func() time.Time { return time.Now() }
+	}()
+	abortedAt := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	rampDownSeconds := int32(60)
+	rollout := newRollout(10, 40, intstr.FromInt(0), intstr.FromInt(1), "", "", nil, &v1alpha1.RolloutTrafficRouting{
+		AbortRampDownSeconds: &rampDownSeconds,
+	})
+	rollout.Status.Abort = true
+	rollout.Status.AbortedAt = &metav1.Time{Time: abortedAt}
+	rollout.Status.Canary.AbortedWeight = 40
+
+	nowFn = func() time.Time { return abortedAt.Add(30 * time.Second) }
+	assert.Equal(t, int32(20), GetCurrentSetWeight(rollout))
+
+	nowFn = func() time.Time { return abortedAt.Add(time.Minute) }
+	assert.Equal(t, int32(0), GetCurrentSetWeight(rollout))
+}
+
+func TestInCanaryQuarantine(t *testing.T) {
+	abortedAt := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	rollout := newRollout(10, 40, intstr.FromInt(0), intstr.FromInt(1), "canary", "stable", nil, &v1alpha1.RolloutTrafficRouting{})
+	rollout.Status.StableRS = "stable"
+	rollout.Spec.Strategy.Canary.QuarantineOnFailure = &v1alpha1.CanaryQuarantine{TTLSeconds: pointer.Int32Ptr(600)}
+	assert.False(t, InCanaryQuarantine(rollout, abortedAt))
+	assert.Nil(t, GetCanaryQuarantineExpiration(rollout, abortedAt))
+
+	rollout.Status.Abort = true
+	// the quarantine starts before the abort is reconciled
+	assert.True(t, InCanaryQuarantine(rollout, abortedAt))
+	assert.Nil(t, GetCanaryQuarantineExpiration(rollout, abortedAt))
+
+	rollout.Status.AbortedAt = &metav1.Time{Time: abortedAt}
+	assert.True(t, InCanaryQuarantine(rollout, abortedAt.Add(9*time.Minute)))
+	assert.Equal(t, time.Minute, *GetCanaryQuarantineExpiration(rollout, abortedAt.Add(9*time.Minute)))
+	// the quarantine expires after the TTL
+	assert.False(t, InCanaryQuarantine(rollout, abortedAt.Add(10*time.Minute)))
+	assert.Nil(t, GetCanaryQuarantineExpiration(rollout, abortedAt.Add(10*time.Minute)))
+
+	// the quarantine requires traffic routing to keep the canary pods without traffic
+	rollout.Spec.Strategy.Canary.TrafficRouting = nil
+	assert.False(t, InCanaryQuarantine(rollout, abortedAt))
+	rollout.Spec.Strategy.Canary.TrafficRouting = &v1alpha1.RolloutTrafficRouting{}
+
+	// the new RS is already the stable RS
+	rollout.Status.CurrentPodHash = "stable"
+	assert.False(t, InCanaryQuarantine(rollout, abortedAt))
+}
+
+func TestCalculateReplicaCountsForCanaryQuarantine(t *testing.T) {
+	defer func() {
+		nowFn = func() time.Time { return time.Now() }
+	}()
+	abortedAt := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	rollout := newRollout(10, 40, intstr.FromInt(0), intstr.FromInt(1), "canary", "stable", nil, &v1alpha1.RolloutTrafficRouting{})
+	rollout.Status.StableRS = "stable"
+	rollout.Spec.Strategy.Canary.QuarantineOnFailure = &v1alpha1.CanaryQuarantine{Replicas: pointer.Int32Ptr(2)}
+	rollout.Status.Abort = true
+	rollout.Status.AbortedAt = &metav1.Time{Time: abortedAt}
+	stableRS := newRS("stable", 10, 10)
+	canaryRS := newRS("canary", 4, 4)
+
+	// the canary is scaled down to the quarantined pods
+	nowFn = func() time.Time { return abortedAt.Add(time.Minute) }
+	newRSReplicaCount, stableRSReplicaCount := CalculateReplicaCountsForCanary(rollout, canaryRS, stableRS, nil)
+	assert.Equal(t, int32(2), newRSReplicaCount)
+	assert.Equal(t, int32(10), stableRSReplicaCount)
+
+	// the canary is scaled down once the quarantine expires
+	nowFn = func() time.Time { return abortedAt.Add(time.Hour) }
+	newRSReplicaCount, _ = CalculateReplicaCountsForCanary(rollout, canaryRS, stableRS, nil)
+	assert.Equal(t, int32(0), newRSReplicaCount)
+}
+
 func TestGetCurrentExperiment(t *testing.T) {
 	rollout := &v1alpha1.Rollout{
 		Spec: v1alpha1.RolloutSpec{
@@ -48,9 +48,10 @@ func FindNewReplicaSet(rollout *v1alpha1.Rollout, rsList []*appsv1.ReplicaSet) *
 	// When this (rare) situation arises, we do not want to return nil, since nil is considered a
 	// PodTemplate change, which in turn would triggers an unexpected redeploy of the replicaset.
 	for _, rs := range rsList {
-		// Remove anti-affinity from template.Spec.Affinity before comparing
+		// Remove anti-affinity from template.Spec.Affinity and the readiness gate of the canary before comparing
 		live := rs.Spec.Template.DeepCopy()
 		live.Spec.Affinity = RemoveInjectedAntiAffinityRule(live.Spec.Affinity, *rollout)
+		live.Spec.ReadinessGates = RemoveInjectedReadinessGate(live.Spec.ReadinessGates, *rollout)
 
 		desired := rollout.Spec.Template.DeepCopy()
 		if PodTemplateEqualIgnoreHash(live, desired) {
@@ -198,6 +199,49 @@ func IfInjectedAntiAffinityRuleNeedsUpdate(affinity *corev1.Affinity, rollout v1
 	return false
 }
 
+// GenerateReplicaSetReadinessGates returns the readiness gates of the pods of the new RS of the rollout. The readiness
+// gate of the canary is injected in the pods of a canary RS only, so that the pods of the first RS of the rollout are
+// not held by it.
+func GenerateReplicaSetReadinessGates(rollout v1alpha1.Rollout) []corev1.PodReadinessGate {
+	readinessGates := rollout.Spec.Template.Spec.ReadinessGates
+	canary := rollout.Spec.Strategy.Canary
+	if canary == nil || canary.ReadinessGate == nil || canary.TrafficRouting == nil {
+		return readinessGates
+	}
+	currentPodHash := controller.ComputeHash(&rollout.Spec.Template, rollout.Status.CollisionCount)
+	if rollout.Status.StableRS == "" || rollout.Status.StableRS == currentPodHash {
+		return readinessGates
+	}
+	for _, readinessGate := range readinessGates {
+		if readinessGate.ConditionType == canary.ReadinessGate.ConditionType {
+			return readinessGates
+		}
+	}
+	injected := append([]corev1.PodReadinessGate{}, readinessGates...)
+	return append(injected, corev1.PodReadinessGate{ConditionType: canary.ReadinessGate.ConditionType})
+}
+
+// RemoveInjectedReadinessGate removes the readiness gate of the canary injected in the pods of a canary RS, unless
+// the pod template of the rollout also declares it
+func RemoveInjectedReadinessGate(readinessGates []corev1.PodReadinessGate, rollout v1alpha1.Rollout) []corev1.PodReadinessGate {
+	canary := rollout.Spec.Strategy.Canary
+	if canary == nil || canary.ReadinessGate == nil {
+		return readinessGates
+	}
+	for _, readinessGate := range rollout.Spec.Template.Spec.ReadinessGates {
+		if readinessGate.ConditionType == canary.ReadinessGate.ConditionType {
+			return readinessGates
+		}
+	}
+	var removed []corev1.PodReadinessGate
+	for _, readinessGate := range readinessGates {
+		if readinessGate.ConditionType != canary.ReadinessGate.ConditionType {
+			removed = append(removed, readinessGate)
+		}
+	}
+	return removed
+}
+
 func NeedsRestart(rollout *v1alpha1.Rollout) bool {
 	now := metav1.Now().UTC()
 	if rollout.Spec.RestartAt == nil {
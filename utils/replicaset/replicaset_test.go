@@ -923,6 +923,51 @@ func TestHasInjectedAntiAffinityRule(t *testing.T) {
 	assert.NotEqual(t, -1, i)
 }
 
+func TestGenerateReplicaSetReadinessGates(t *testing.T) {
+	ro := generateRollout("nginx")
+	ro.Spec.Strategy.Canary = &v1alpha1.CanaryStrategy{
+		ReadinessGate:  &v1alpha1.CanaryReadinessGate{ConditionType: "MeshConfigured"},
+		TrafficRouting: &v1alpha1.RolloutTrafficRouting{},
+	}
+	// the first RS of the rollout is not a canary
+	assert.Nil(t, GenerateReplicaSetReadinessGates(ro))
+	ro.Status.StableRS = controller.ComputeHash(&ro.Spec.Template, ro.Status.CollisionCount)
+	assert.Nil(t, GenerateReplicaSetReadinessGates(ro))
+
+	ro.Status.StableRS = "stable"
+	ro.Spec.Template.Spec.ReadinessGates = []corev1.PodReadinessGate{{ConditionType: "Other"}}
+	readinessGates := GenerateReplicaSetReadinessGates(ro)
+	assert.Equal(t, []corev1.PodReadinessGate{{ConditionType: "Other"}, {ConditionType: "MeshConfigured"}}, readinessGates)
+	// the rollout template is not modified
+	assert.Len(t, ro.Spec.Template.Spec.ReadinessGates, 1)
+
+	// the readiness gate is not injected twice
+	ro.Spec.Template.Spec.ReadinessGates = []corev1.PodReadinessGate{{ConditionType: "MeshConfigured"}}
+	assert.Equal(t, []corev1.PodReadinessGate{{ConditionType: "MeshConfigured"}}, GenerateReplicaSetReadinessGates(ro))
+
+	// the readiness gate requires traffic routing
+	ro.Spec.Template.Spec.ReadinessGates = nil
+	ro.Spec.Strategy.Canary.TrafficRouting = nil
+	assert.Nil(t, GenerateReplicaSetReadinessGates(ro))
+}
+
+func TestRemoveInjectedReadinessGate(t *testing.T) {
+	ro := generateRollout("nginx")
+	ro.Status.StableRS = "stable"
+	ro.Spec.Strategy.Canary = &v1alpha1.CanaryStrategy{
+		ReadinessGate:  &v1alpha1.CanaryReadinessGate{ConditionType: "MeshConfigured"},
+		TrafficRouting: &v1alpha1.RolloutTrafficRouting{},
+	}
+	assert.Nil(t, RemoveInjectedReadinessGate(GenerateReplicaSetReadinessGates(ro), ro))
+
+	ro.Spec.Template.Spec.ReadinessGates = []corev1.PodReadinessGate{{ConditionType: "Other"}}
+	assert.Equal(t, ro.Spec.Template.Spec.ReadinessGates, RemoveInjectedReadinessGate(GenerateReplicaSetReadinessGates(ro), ro))
+
+	// the readiness gate declared by the rollout template is kept
+	ro.Spec.Template.Spec.ReadinessGates = []corev1.PodReadinessGate{{ConditionType: "MeshConfigured"}}
+	assert.Equal(t, ro.Spec.Template.Spec.ReadinessGates, RemoveInjectedReadinessGate(GenerateReplicaSetReadinessGates(ro), ro))
+}
+
 func TestRemoveInjectedAntiAffinityRule(t *testing.T) {
 	ro := generateRollout("nginx")
 	ro.Status.StableRS = "test"
@@ -5,8 +5,10 @@ import (
 	"io"
 	"strconv"
 	"strings"
+	"text/template"
 	"time"
 
+	"github.com/Masterminds/sprig/v3"
 	"github.com/valyala/fasttemplate"
 	appsv1 "k8s.io/api/apps/v1"
 
@@ -17,6 +19,7 @@ const (
 	openBracket               = "{{"
 	closeBracket              = "}}"
 	experimentPodTemplateHash = "templates.%s.podTemplateHash"
+	experimentTemplateHash    = "templates.%s.hash"
 	experimentAvailableAt     = "experiment.availableAt"
 	experimentEndsAt          = "experiment.finishedAt"
 )
@@ -41,46 +44,139 @@ func ResolveExperimentArgsValue(argTemplate string, ex *v1alpha1.Experiment, tem
 	for _, template := range ex.Spec.Templates {
 		if rs, ok := templateRSs[template.Name]; ok {
 			argsMap[fmt.Sprintf(experimentPodTemplateHash, template.Name)] = rs.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
+			argsMap[ExperimentTemplateHashArg(template.Name)] = rs.Labels[v1alpha1.DefaultRolloutUniqueLabelKey]
 		}
 	}
 	return resolve(t, argsMap)
 }
 
-// ResolveArgs substitute the supplied arguments in the given template
+// ExperimentTemplateHashArg returns the name of the argument injected into the AnalysisRuns of an experiment with the
+// pod hash of the template
+func ExperimentTemplateHashArg(templateName string) string {
+	return fmt.Sprintf(experimentTemplateHash, templateName)
+}
+
+// ResolveArgs substitute the supplied arguments in the given template. Besides the {{args.<name>}} references, a
+// template can hold Go template pipelines using the Sprig functions, with the arguments as the data of the pipeline
+// (e.g. {{ .env | lower }})
 func ResolveArgs(template string, args []v1alpha1.Argument) (string, error) {
+	return resolveArgs(template, args, false)
+}
+
+// ResolveQuotedArgs is used for substituting templates which need quotes escaped such as when args
+// are used in JSON which we marshal and unmarshal
+func ResolveQuotedArgs(template string, args []v1alpha1.Argument) (string, error) {
+	return resolveArgs(template, args, true)
+}
+
+func resolveArgs(template string, args []v1alpha1.Argument, quoted bool) (string, error) {
 	t, err := fasttemplate.NewTemplate(template, openBracket, closeBracket)
 	if err != nil {
 		return "", err
 	}
 	argsMap := make(map[string]string)
+	data := make(map[string]interface{})
 	for i := range args {
 		arg := args[i]
 		if arg.Value == nil {
 			return "", fmt.Errorf("argument \"%s\" was not supplied", arg.Name)
 		}
 		argsMap[fmt.Sprintf("args.%s", arg.Name)] = *arg.Value
+		data[arg.Name] = *arg.Value
 	}
-	return resolve(t, argsMap)
-}
-
-// ResolveQuotedArgs is used for substituting templates which need quotes escaped such as when args
-// are used in JSON which we marshal and unmarshal
-func ResolveQuotedArgs(template string, args []v1alpha1.Argument) (string, error) {
-	quotedArgs := make([]v1alpha1.Argument, len(args))
-	for i, arg := range args {
-		quotedArg := v1alpha1.Argument{
-			Name: arg.Name,
+	var unresolvedErr error
+	s := t.ExecuteFuncString(func(w io.Writer, tag string) (int, error) {
+		value, ok := argsMap[strings.TrimSpace(tag)]
+		if !ok {
+			rendered, err := renderPipeline(tag, data, quoted)
+			if err != nil {
+				unresolvedErr = err
+				return w.Write([]byte(""))
+			}
+			value = rendered
 		}
-		if arg.Value != nil {
+		if quoted {
 			// The following escapes any special characters (e.g. newlines, tabs, etc...)
 			// in preparation for substitution
-			replacement := strconv.Quote(*arg.Value)
-			replacement = replacement[1 : len(replacement)-1]
-			quotedArg.Value = &replacement
+			value = strconv.Quote(value)
+			value = value[1 : len(value)-1]
+		}
+		return w.Write([]byte(value))
+	})
+	return s, unresolvedErr
+}
+
+// renderPipeline renders the tag as a Go template pipeline with the Sprig functions. A quoted tag, taken from
+// marshaled JSON, is unescaped before it is parsed
+func renderPipeline(tag string, data map[string]interface{}, quoted bool) (string, error) {
+	pipeline := tag
+	if quoted {
+		unquoted, err := strconv.Unquote(`"` + tag + `"`)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve {{%s}}", tag)
+		}
+		pipeline = unquoted
+	}
+	t, err := template.New("").Funcs(pipelineFuncs).Option("missingkey=error").Parse(openBracket + pipeline + closeBracket)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve {{%s}}", tag)
+	}
+	var out strings.Builder
+	if err := t.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("failed to resolve {{%s}}: %v", tag, err)
+	}
+	return out.String(), nil
+}
+
+// pipelineFuncs are the Sprig functions available to the pipelines, without the functions which read the environment
+// of the controller or query the network
+var pipelineFuncs = func() template.FuncMap {
+	funcs := sprig.TxtFuncMap()
+	for _, name := range []string{"env", "expandenv", "getHostByName"} {
+		delete(funcs, name)
+	}
+	return funcs
+}()
+
+// ResolveSuppliedQuotedArgs substitutes the supplied arguments like ResolveQuotedArgs, but leaves the references to
+// other arguments untouched so that they can be substituted later
+func ResolveSuppliedQuotedArgs(template string, args []v1alpha1.Argument) (string, error) {
+	t, err := fasttemplate.NewTemplate(template, openBracket, closeBracket)
+	if err != nil {
+		return "", err
+	}
+	argsMap := make(map[string]string)
+	for _, arg := range args {
+		if arg.Value == nil {
+			return "", fmt.Errorf("argument \"%s\" was not supplied", arg.Name)
 		}
-		quotedArgs[i] = quotedArg
+		replacement := strconv.Quote(*arg.Value)
+		argsMap[fmt.Sprintf("args.%s", arg.Name)] = replacement[1 : len(replacement)-1]
 	}
-	return ResolveArgs(template, quotedArgs)
+	s := t.ExecuteFuncString(func(w io.Writer, tag string) (int, error) {
+		if value, ok := argsMap[strings.TrimSpace(tag)]; ok {
+			return w.Write([]byte(value))
+		}
+		return w.Write([]byte(openBracket + tag + closeBracket))
+	})
+	return s, nil
+}
+
+// ReferencedArgs returns the names of the arguments referenced in the given template
+func ReferencedArgs(template string) (map[string]bool, error) {
+	t, err := fasttemplate.NewTemplate(template, openBracket, closeBracket)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool)
+	t.ExecuteFuncString(func(w io.Writer, tag string) (int, error) {
+		cleanedTag := strings.TrimSpace(tag)
+		if strings.HasPrefix(cleanedTag, "args.") {
+			names[strings.TrimPrefix(cleanedTag, "args.")] = true
+		}
+		return 0, nil
+	})
+	return names, nil
 }
 
 func resolve(t *fasttemplate.Template, argsMap map[string]string) (string, error) {
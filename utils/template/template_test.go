@@ -57,6 +57,9 @@ func TestResolveExperimentArgsValue(t *testing.T) {
 	argValue, err := ResolveExperimentArgsValue("{{templates.test.podTemplateHash}}", ex, rsMap)
 	assert.Nil(t, err)
 	assert.Equal(t, "abcd", argValue)
+	argValue, err = ResolveExperimentArgsValue("{{templates.test.hash}}", ex, rsMap)
+	assert.Nil(t, err)
+	assert.Equal(t, "abcd", argValue)
 	argValue, err = ResolveExperimentArgsValue("{{experiment.availableAt}}", ex, rsMap)
 	assert.Nil(t, err)
 	assert.Equal(t, now.Format(time.RFC3339), argValue)
@@ -126,3 +129,66 @@ func TestResolveQuotedArgs(t *testing.T) {
 		assert.Equal(t, "test-double quotes\"newline\nand tab\t", query)
 	}
 }
+
+func TestResolveArgsWithSprigFunctions(t *testing.T) {
+	args := []v1alpha1.Argument{
+		{
+			Name:  "env",
+			Value: pointer.StringPtr(" Staging "),
+		},
+		{
+			Name:  "service",
+			Value: pointer.StringPtr("checkout"),
+		},
+	}
+	query, err := ResolveArgs(`env:{{ .env | trim | lower }},service:{{ .service | upper }},{{args.service}}`, args)
+	assert.Nil(t, err)
+	assert.Equal(t, "env:staging,service:CHECKOUT,checkout", query)
+
+	_, err = ResolveArgs("{{ .missing | lower }}", args)
+	assert.Contains(t, err.Error(), "failed to resolve {{ .missing | lower }}")
+	assert.Contains(t, err.Error(), "map has no entry for key \"missing\"")
+}
+
+func TestResolveArgsWithoutSideEffectFunctions(t *testing.T) {
+	for _, tag := range []string{`{{ env "HOME" }}`, `{{ expandenv "$HOME" }}`, `{{ getHostByName "example.com" }}`} {
+		_, err := ResolveArgs(tag, nil)
+		assert.EqualError(t, err, fmt.Sprintf("failed to resolve %s", tag))
+	}
+}
+
+func TestResolveQuotedArgsWithSprigFunctions(t *testing.T) {
+	args := []v1alpha1.Argument{{
+		Name:  "service",
+		Value: pointer.StringPtr("Checkout \"v2\""),
+	}}
+	// The pipeline is unescaped before it is rendered, and its output is escaped
+	query, err := ResolveQuotedArgs(`{{ .service | lower | replace \" \" \"-\" }}`, args)
+	assert.Nil(t, err)
+	assert.Equal(t, `checkout-\"v2\"`, query)
+}
+
+func TestResolveSuppliedQuotedArgs(t *testing.T) {
+	args := []v1alpha1.Argument{{
+		Name:  "service",
+		Value: pointer.StringPtr("checkout \"v2\""),
+	}}
+	query, err := ResolveSuppliedQuotedArgs("{{args.service}}-{{ args.namespace }}", args)
+	assert.Nil(t, err)
+	assert.Equal(t, "checkout \\\"v2\\\"-{{ args.namespace }}", query)
+
+	_, err = ResolveSuppliedQuotedArgs("{{args.service}}", []v1alpha1.Argument{{Name: "service"}})
+	assert.Equal(t, fmt.Errorf("argument \"service\" was not supplied"), err)
+
+	_, err = ResolveSuppliedQuotedArgs("test-{{args.var", args)
+	assert.NotNil(t, err)
+}
+
+func TestReferencedArgs(t *testing.T) {
+	names, err := ReferencedArgs("{{args.service}}-{{ args.namespace }}-{{templates.canary.podTemplateHash}}")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]bool{"service": true, "namespace": true}, names)
+
+	_, err = ReferencedArgs("test-{{args.var")
+	assert.NotNil(t, err)
+}
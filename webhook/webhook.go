@@ -0,0 +1,313 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/argoproj/argo-rollouts/metricproviders"
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	clientset "github.com/argoproj/argo-rollouts/pkg/client/clientset/versioned"
+	analysisutil "github.com/argoproj/argo-rollouts/utils/analysis"
+)
+
+const (
+	// ValidateRolloutPath is the endpoint of the validating admission webhook of rollouts
+	ValidateRolloutPath = "/validate-rollout"
+	// ValidateAnalysisPath is the endpoint of the validating admission webhook of AnalysisRuns, AnalysisTemplates
+	// and ClusterAnalysisTemplates
+	ValidateAnalysisPath = "/validate-analysis"
+)
+
+// ServerConfig contains the configuration of the webhook server
+type ServerConfig struct {
+	Addr              string
+	ArgoprojClientset clientset.Interface
+	// AllowedProviders are the metric provider types which analyses may use. All the provider types are allowed
+	// when empty
+	AllowedProviders []string
+}
+
+// WebhookServer serves the validating admission webhooks which reject rollouts referencing missing
+// AnalysisTemplates and ClusterAnalysisTemplates, and analyses using disallowed metric providers
+type WebhookServer struct {
+	*http.Server
+}
+
+// NewWebhookServer returns a new webhook server which validates rollouts and analyses on create and update
+func NewWebhookServer(cfg ServerConfig) *WebhookServer {
+	mux := http.NewServeMux()
+	mux.Handle(ValidateRolloutPath, admissionHandler{&rolloutValidator{
+		argoprojclientset: cfg.ArgoprojClientset,
+	}})
+	mux.Handle(ValidateAnalysisPath, admissionHandler{&analysisValidator{
+		allowedProviders: cfg.AllowedProviders,
+	}})
+	return &WebhookServer{
+		Server: &http.Server{
+			Addr:    cfg.Addr,
+			Handler: mux,
+		},
+	}
+}
+
+// validator validates the object of an admission request
+type validator interface {
+	validate(req *admissionv1beta1.AdmissionRequest) *admissionv1beta1.AdmissionResponse
+}
+
+// admissionHandler decodes the AdmissionReview of the request and responds with the result of the validator
+type admissionHandler struct {
+	validator
+}
+
+func (h admissionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	review := admissionv1beta1.AdmissionReview{}
+	if err := json.Unmarshal(body, &review); err != nil || review.Request == nil {
+		http.Error(w, "invalid AdmissionReview", http.StatusBadRequest)
+		return
+	}
+	review.Response = h.validate(review.Request)
+	review.Response.UID = review.Request.UID
+	review.Request = nil
+	resp, err := json.Marshal(review)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(resp)
+}
+
+type rolloutValidator struct {
+	argoprojclientset clientset.Interface
+}
+
+func (v *rolloutValidator) validate(req *admissionv1beta1.AdmissionRequest) *admissionv1beta1.AdmissionResponse {
+	if req.Operation != admissionv1beta1.Create && req.Operation != admissionv1beta1.Update {
+		return &admissionv1beta1.AdmissionResponse{Allowed: true}
+	}
+	rollout := v1alpha1.Rollout{}
+	if err := json.Unmarshal(req.Object.Raw, &rollout); err != nil {
+		return deny(fmt.Sprintf("unable to decode the rollout: %v", err))
+	}
+	if rollout.Namespace == "" {
+		rollout.Namespace = req.Namespace
+	}
+	allErrs := ValidateRolloutAnalysisTemplates(&rollout, v.argoprojclientset)
+	if len(allErrs) > 0 {
+		message := fmt.Sprintf("The Rollout \"%s\" is invalid: %s", rollout.Name, allErrs.ToAggregate().Error())
+		log.WithField("rollout", rollout.Name).WithField("namespace", rollout.Namespace).Info(message)
+		return deny(message)
+	}
+	return &admissionv1beta1.AdmissionResponse{Allowed: true}
+}
+
+type analysisValidator struct {
+	allowedProviders []string
+}
+
+func (v *analysisValidator) validate(req *admissionv1beta1.AdmissionRequest) *admissionv1beta1.AdmissionResponse {
+	if req.Operation != admissionv1beta1.Create && req.Operation != admissionv1beta1.Update {
+		return &admissionv1beta1.AdmissionResponse{Allowed: true}
+	}
+	obj := struct {
+		Kind     string            `json:"kind"`
+		Metadata metav1.ObjectMeta `json:"metadata"`
+		Spec     struct {
+			Args    []v1alpha1.Argument `json:"args"`
+			Metrics []v1alpha1.Metric   `json:"metrics"`
+		} `json:"spec"`
+	}{}
+	if err := json.Unmarshal(req.Object.Raw, &obj); err != nil {
+		return deny(fmt.Sprintf("unable to decode the analysis: %v", err))
+	}
+	// AnalysisRuns, AnalysisTemplates and ClusterAnalysisTemplates share the metrics of their spec
+	err := metricproviders.ValidateAllowedProviders(obj.Spec.Metrics, v.allowedProviders)
+	if err == nil {
+		err = analysisutil.ValidateJobMetricArgs(obj.Spec.Metrics, obj.Spec.Args)
+	}
+	if err != nil {
+		message := fmt.Sprintf("The %s \"%s\" is invalid: %v", obj.Kind, obj.Metadata.Name, err)
+		log.WithField("namespace", req.Namespace).Info(message)
+		return deny(message)
+	}
+	return &admissionv1beta1.AdmissionResponse{Allowed: true}
+}
+
+func deny(message string) *admissionv1beta1.AdmissionResponse {
+	return &admissionv1beta1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Reason:  metav1.StatusReasonInvalid,
+			Code:    http.StatusUnprocessableEntity,
+			Message: message,
+		},
+	}
+}
+
+// ValidateRolloutAnalysisTemplates validates that the AnalysisTemplates and ClusterAnalysisTemplates referenced by
+// the rollout exist, and that the arguments of the templates are satisfied by the arguments of the rollout
+func ValidateRolloutAnalysisTemplates(rollout *v1alpha1.Rollout, argoprojclientset clientset.Interface) field.ErrorList {
+	allErrs := field.ErrorList{}
+	fldPath := field.NewPath("spec", "strategy")
+	if blueGreen := rollout.Spec.Strategy.BlueGreen; blueGreen != nil {
+		if blueGreen.PrePromotionAnalysis != nil {
+			allErrs = append(allErrs, validateRolloutAnalysis(rollout, blueGreen.PrePromotionAnalysis, false, fldPath.Child("blueGreen", "prePromotionAnalysis"), argoprojclientset)...)
+		}
+		if blueGreen.PostPromotionAnalysis != nil {
+			allErrs = append(allErrs, validateRolloutAnalysis(rollout, blueGreen.PostPromotionAnalysis, false, fldPath.Child("blueGreen", "postPromotionAnalysis"), argoprojclientset)...)
+		}
+	}
+	if canary := rollout.Spec.Strategy.Canary; canary != nil {
+		if canary.Analysis != nil {
+			allErrs = append(allErrs, validateRolloutAnalysis(rollout, &canary.Analysis.RolloutAnalysis, false, fldPath.Child("canary", "analysis"), argoprojclientset)...)
+		}
+		for i, step := range canary.Steps {
+			if step.Analysis != nil {
+				allErrs = append(allErrs, validateRolloutAnalysis(rollout, step.Analysis, true, fldPath.Child("canary", "steps").Index(i).Child("analysis"), argoprojclientset)...)
+			}
+		}
+	}
+	return allErrs
+}
+
+func validateRolloutAnalysis(rollout *v1alpha1.Rollout, rolloutAnalysis *v1alpha1.RolloutAnalysis, step bool, fldPath *field.Path, argoprojclientset clientset.Interface) field.ErrorList {
+	allErrs := field.ErrorList{}
+	templates := make([]*v1alpha1.AnalysisTemplate, 0)
+	clusterTemplates := make([]*v1alpha1.ClusterAnalysisTemplate, 0)
+	for i, arg := range rolloutAnalysis.Args {
+		if arg.ValueFrom == nil || arg.ValueFrom.FieldRef == nil {
+			continue
+		}
+		// The fields of the rollout are resolved when the AnalysisRun is created, from the same spec as the one admitted
+		if _, err := analysisutil.ExtractRolloutField(rollout, arg.ValueFrom.FieldRef.FieldPath); err != nil {
+			fieldPath := fldPath.Child("args").Index(i).Child("valueFrom", "fieldRef", "fieldPath")
+			allErrs = append(allErrs, field.Invalid(fieldPath, arg.ValueFrom.FieldRef.FieldPath, err.Error()))
+		}
+	}
+	if rolloutAnalysis.TemplateName != "" {
+		template, err := getAnalysisTemplate(rollout.Namespace, rolloutAnalysis.TemplateName, fldPath.Child("templateName"), argoprojclientset)
+		if err != nil {
+			allErrs = append(allErrs, err)
+		} else {
+			templates = append(templates, template)
+		}
+	}
+	for i, templateRef := range rolloutAnalysis.Templates {
+		templatePath := fldPath.Child("templates").Index(i).Child("templateName")
+		if templateRef.ClusterScope {
+			template, err := getClusterAnalysisTemplate(templateRef.TemplateName, templatePath, argoprojclientset)
+			if err != nil {
+				allErrs = append(allErrs, err)
+			} else {
+				clusterTemplates = append(clusterTemplates, template)
+			}
+		} else {
+			template, err := getAnalysisTemplate(rollout.Namespace, templateRef.TemplateName, templatePath, argoprojclientset)
+			if err != nil {
+				allErrs = append(allErrs, err)
+			} else {
+				templates = append(templates, template)
+			}
+		}
+	}
+	if len(allErrs) > 0 {
+		return allErrs
+	}
+
+	template, err := analysisutil.FlattenTemplates(templates, clusterTemplates)
+	if err != nil {
+		return append(allErrs, field.Invalid(fldPath.Child("templates"), templateNames(rolloutAnalysis), err.Error()))
+	}
+	// The inherited arguments are only known once the AnalysisRun of the previous step is created
+	if rolloutAnalysis.InheritArgsFromStep != nil {
+		return allErrs
+	}
+	// The values of the arguments are only known when the AnalysisRun is created, so only their presence is validated
+	args := make([]v1alpha1.Argument, 0, len(rolloutAnalysis.Args)+1)
+	if rollout.Spec.Strategy.Canary != nil && step {
+		args = append(args, analysisutil.CanaryWeightArg(0))
+	}
+	for _, arg := range rolloutAnalysis.Args {
+		value := arg.Value
+		args = append(args, v1alpha1.Argument{Name: arg.Name, Value: &value})
+	}
+	if !step && requiresArg(template.Spec.Args, args, analysisutil.CanaryWeightArgName) {
+		// The background analysis is created once, so the weight of the canary would not follow the steps
+		msg := fmt.Sprintf("the %s argument is only supplied to the analysis steps", analysisutil.CanaryWeightArgName)
+		return append(allErrs, field.Invalid(fldPath.Child("args"), templateNames(rolloutAnalysis), msg))
+	}
+	if _, err := analysisutil.MergeArgs(args, template.Spec.Args); err != nil {
+		return append(allErrs, field.Invalid(fldPath.Child("args"), templateNames(rolloutAnalysis), err.Error()))
+	}
+	return allErrs
+}
+
+// requiresArg returns whether the argument is declared by the template without a value, and is not supplied
+func requiresArg(templateArgs, args []v1alpha1.Argument, name string) bool {
+	for _, arg := range args {
+		if arg.Name == name {
+			return false
+		}
+	}
+	for _, arg := range templateArgs {
+		if arg.Name == name {
+			return arg.Value == nil && arg.ValueFrom == nil
+		}
+	}
+	return false
+}
+
+func getAnalysisTemplate(namespace, name string, fldPath *field.Path, argoprojclientset clientset.Interface) (*v1alpha1.AnalysisTemplate, *field.Error) {
+	template, err := argoprojclientset.ArgoprojV1alpha1().AnalysisTemplates(namespace).Get(name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		msg := fmt.Sprintf("AnalysisTemplate '%s' not found in namespace '%s'", name, namespace)
+		return nil, field.Invalid(fldPath, name, msg)
+	}
+	if err != nil {
+		return nil, field.InternalError(fldPath, err)
+	}
+	return template, nil
+}
+
+func getClusterAnalysisTemplate(name string, fldPath *field.Path, argoprojclientset clientset.Interface) (*v1alpha1.ClusterAnalysisTemplate, *field.Error) {
+	template, err := argoprojclientset.ArgoprojV1alpha1().ClusterAnalysisTemplates().Get(name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		msg := fmt.Sprintf("ClusterAnalysisTemplate '%s' not found", name)
+		return nil, field.Invalid(fldPath, name, msg)
+	}
+	if err != nil {
+		return nil, field.InternalError(fldPath, err)
+	}
+	return template, nil
+}
+
+// templateNames returns the names of the templates referenced by the rollout analysis
+func templateNames(rolloutAnalysis *v1alpha1.RolloutAnalysis) []string {
+	names := []string{}
+	if rolloutAnalysis.TemplateName != "" {
+		names = append(names, rolloutAnalysis.TemplateName)
+	}
+	for _, templateRef := range rolloutAnalysis.Templates {
+		names = append(names, templateRef.TemplateName)
+	}
+	return names
+}
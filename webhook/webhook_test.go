@@ -0,0 +1,363 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/argoproj/argo-rollouts/pkg/client/clientset/versioned/fake"
+)
+
+func newAnalysisTemplate(name string, args ...string) *v1alpha1.AnalysisTemplate {
+	template := &v1alpha1.AnalysisTemplate{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: metav1.NamespaceDefault,
+		},
+		Spec: v1alpha1.AnalysisTemplateSpec{
+			Metrics: []v1alpha1.Metric{{
+				Name: name,
+			}},
+		},
+	}
+	for _, arg := range args {
+		template.Spec.Args = append(template.Spec.Args, v1alpha1.Argument{Name: arg})
+	}
+	return template
+}
+
+func newClusterAnalysisTemplate(name string) *v1alpha1.ClusterAnalysisTemplate {
+	return &v1alpha1.ClusterAnalysisTemplate{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: v1alpha1.AnalysisTemplateSpec{
+			Metrics: []v1alpha1.Metric{{
+				Name: name,
+			}},
+		},
+	}
+}
+
+func newCanaryRollout(analysis *v1alpha1.RolloutAnalysis) *v1alpha1.Rollout {
+	return &v1alpha1.Rollout{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "guestbook",
+			Namespace: metav1.NamespaceDefault,
+		},
+		Spec: v1alpha1.RolloutSpec{
+			Strategy: v1alpha1.RolloutStrategy{
+				Canary: &v1alpha1.CanaryStrategy{
+					Steps: []v1alpha1.CanaryStep{
+						{Pause: &v1alpha1.RolloutPause{}},
+						{Analysis: analysis},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestValidateRolloutAnalysisTemplates(t *testing.T) {
+	client := fake.NewSimpleClientset(newAnalysisTemplate("success-rate", "service-name"), newClusterAnalysisTemplate("error-rate"))
+	rollout := newCanaryRollout(&v1alpha1.RolloutAnalysis{
+		Templates: []v1alpha1.RolloutAnalysisTemplate{
+			{TemplateName: "success-rate"},
+			{TemplateName: "error-rate", ClusterScope: true},
+		},
+		Args: []v1alpha1.AnalysisRunArgument{{Name: "service-name", Value: "guestbook"}},
+	})
+	allErrs := ValidateRolloutAnalysisTemplates(rollout, client)
+	assert.Len(t, allErrs, 0)
+}
+
+func TestValidateRolloutAnalysisTemplatesNotFound(t *testing.T) {
+	client := fake.NewSimpleClientset(newAnalysisTemplate("success-rate"))
+	rollout := newCanaryRollout(&v1alpha1.RolloutAnalysis{
+		Templates: []v1alpha1.RolloutAnalysisTemplate{
+			{TemplateName: "success-rate"},
+			{TemplateName: "sucess-rate"},
+			{TemplateName: "error-rate", ClusterScope: true},
+		},
+	})
+	allErrs := ValidateRolloutAnalysisTemplates(rollout, client)
+	assert.Len(t, allErrs, 2)
+	assert.Equal(t, "spec.strategy.canary.steps[1].analysis.templates[1].templateName: Invalid value: \"sucess-rate\": AnalysisTemplate 'sucess-rate' not found in namespace 'default'", allErrs[0].Error())
+	assert.Equal(t, "spec.strategy.canary.steps[1].analysis.templates[2].templateName: Invalid value: \"error-rate\": ClusterAnalysisTemplate 'error-rate' not found", allErrs[1].Error())
+}
+
+func TestValidateRolloutAnalysisTemplatesUnresolvedArgs(t *testing.T) {
+	client := fake.NewSimpleClientset(newAnalysisTemplate("success-rate", "service-name"))
+	rollout := newCanaryRollout(&v1alpha1.RolloutAnalysis{
+		Templates: []v1alpha1.RolloutAnalysisTemplate{{TemplateName: "success-rate"}},
+		Args:      []v1alpha1.AnalysisRunArgument{{Name: "namespace", Value: "default"}},
+	})
+	allErrs := ValidateRolloutAnalysisTemplates(rollout, client)
+	assert.Len(t, allErrs, 1)
+	assert.Equal(t, "spec.strategy.canary.steps[1].analysis.args", allErrs[0].Field)
+	assert.Contains(t, allErrs[0].Error(), "args.service-name was not resolved")
+
+	// Arguments resolved from the pod template hash satisfy the template
+	podTemplateHash := v1alpha1.Latest
+	rollout.Spec.Strategy.Canary.Steps[1].Analysis.Args = []v1alpha1.AnalysisRunArgument{{
+		Name:      "service-name",
+		ValueFrom: &v1alpha1.ArgumentValueFrom{PodTemplateHashValue: &podTemplateHash},
+	}}
+	allErrs = ValidateRolloutAnalysisTemplates(rollout, client)
+	assert.Len(t, allErrs, 0)
+}
+
+func TestValidateRolloutAnalysisTemplatesInheritedArgs(t *testing.T) {
+	client := fake.NewSimpleClientset(newAnalysisTemplate("success-rate", "service-name"))
+	inheritArgsFromStep := int32(0)
+	rollout := newCanaryRollout(&v1alpha1.RolloutAnalysis{
+		Templates:           []v1alpha1.RolloutAnalysisTemplate{{TemplateName: "success-rate"}},
+		InheritArgsFromStep: &inheritArgsFromStep,
+	})
+	allErrs := ValidateRolloutAnalysisTemplates(rollout, client)
+	assert.Len(t, allErrs, 0)
+}
+
+func TestValidateRolloutAnalysisTemplatesCanaryWeightArg(t *testing.T) {
+	client := fake.NewSimpleClientset(newAnalysisTemplate("success-rate", "canary-weight"))
+	rollout := newCanaryRollout(&v1alpha1.RolloutAnalysis{
+		Templates: []v1alpha1.RolloutAnalysisTemplate{{TemplateName: "success-rate"}},
+	})
+	allErrs := ValidateRolloutAnalysisTemplates(rollout, client)
+	assert.Len(t, allErrs, 0)
+}
+
+func TestValidateRolloutAnalysisTemplatesFieldRefArgs(t *testing.T) {
+	client := fake.NewSimpleClientset(newAnalysisTemplate("success-rate", "team", "stable-service"))
+	rollout := newCanaryRollout(&v1alpha1.RolloutAnalysis{
+		Templates: []v1alpha1.RolloutAnalysisTemplate{{TemplateName: "success-rate"}},
+		Args: []v1alpha1.AnalysisRunArgument{
+			{
+				Name:      "team",
+				ValueFrom: &v1alpha1.ArgumentValueFrom{FieldRef: &v1alpha1.FieldRef{FieldPath: "metadata.labels['team']"}},
+			},
+			{
+				Name:      "stable-service",
+				ValueFrom: &v1alpha1.ArgumentValueFrom{FieldRef: &v1alpha1.FieldRef{FieldPath: "spec.strategy.canary.stableService"}},
+			},
+		},
+	})
+	rollout.Labels = map[string]string{"team": "payments"}
+	rollout.Spec.Strategy.Canary.StableService = "guestbook-stable"
+	allErrs := ValidateRolloutAnalysisTemplates(rollout, client)
+	assert.Len(t, allErrs, 0)
+
+	rollout.Spec.Strategy.Canary.Steps[1].Analysis.Args[0].ValueFrom.FieldRef.FieldPath = "metadata.team"
+	rollout.Spec.Strategy.Canary.Steps[1].Analysis.Args[1].ValueFrom.FieldRef.FieldPath = "spec.strategy.canary.stableServices"
+	allErrs = ValidateRolloutAnalysisTemplates(rollout, client)
+	assert.Len(t, allErrs, 2)
+	assert.Equal(t, "spec.strategy.canary.steps[1].analysis.args[0].valueFrom.fieldRef.fieldPath", allErrs[0].Field)
+	assert.Contains(t, allErrs[0].Error(), "unsupported fieldPath: metadata.team")
+	assert.Equal(t, "spec.strategy.canary.steps[1].analysis.args[1].valueFrom.fieldRef.fieldPath", allErrs[1].Field)
+	assert.Contains(t, allErrs[1].Error(), "field path 'spec.strategy.canary.stableServices' is not set in the rollout")
+}
+
+func TestValidateRolloutAnalysisTemplatesBackgroundCanaryWeightArg(t *testing.T) {
+	client := fake.NewSimpleClientset(newAnalysisTemplate("success-rate", "canary-weight"))
+	rollout := newCanaryRollout(&v1alpha1.RolloutAnalysis{
+		Templates: []v1alpha1.RolloutAnalysisTemplate{{TemplateName: "success-rate"}},
+	})
+	rollout.Spec.Strategy.Canary.Analysis = &v1alpha1.RolloutAnalysisBackground{
+		RolloutAnalysis: v1alpha1.RolloutAnalysis{
+			Templates: []v1alpha1.RolloutAnalysisTemplate{{TemplateName: "success-rate"}},
+		},
+	}
+	allErrs := ValidateRolloutAnalysisTemplates(rollout, client)
+	assert.Len(t, allErrs, 1)
+	assert.Equal(t, "spec.strategy.canary.analysis.args", allErrs[0].Field)
+	assert.Equal(t, "the canary-weight argument is only supplied to the analysis steps", allErrs[0].Detail)
+
+	// The background analysis may supply the argument itself
+	rollout.Spec.Strategy.Canary.Analysis.Args = []v1alpha1.AnalysisRunArgument{{Name: "canary-weight", Value: "100"}}
+	allErrs = ValidateRolloutAnalysisTemplates(rollout, client)
+	assert.Len(t, allErrs, 0)
+}
+
+func TestValidateRolloutAnalysisTemplatesBlueGreen(t *testing.T) {
+	client := fake.NewSimpleClientset(newAnalysisTemplate("smoke-tests"))
+	rollout := &v1alpha1.Rollout{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "guestbook",
+			Namespace: metav1.NamespaceDefault,
+		},
+		Spec: v1alpha1.RolloutSpec{
+			Strategy: v1alpha1.RolloutStrategy{
+				BlueGreen: &v1alpha1.BlueGreenStrategy{
+					PrePromotionAnalysis: &v1alpha1.RolloutAnalysis{
+						Templates: []v1alpha1.RolloutAnalysisTemplate{{TemplateName: "smoke-tests"}},
+					},
+					PostPromotionAnalysis: &v1alpha1.RolloutAnalysis{
+						Templates: []v1alpha1.RolloutAnalysisTemplate{{TemplateName: "missing"}},
+					},
+				},
+			},
+		},
+	}
+	allErrs := ValidateRolloutAnalysisTemplates(rollout, client)
+	assert.Len(t, allErrs, 1)
+	assert.Equal(t, "spec.strategy.blueGreen.postPromotionAnalysis.templates[0].templateName", allErrs[0].Field)
+}
+
+func newAdmissionReview(t *testing.T, operation admissionv1beta1.Operation, obj interface{}) []byte {
+	raw, err := json.Marshal(obj)
+	assert.NoError(t, err)
+	review := admissionv1beta1.AdmissionReview{
+		Request: &admissionv1beta1.AdmissionRequest{
+			UID:       types.UID("review-uid"),
+			Operation: operation,
+			Namespace: metav1.NamespaceDefault,
+			Object:    runtime.RawExtension{Raw: raw},
+		},
+	}
+	body, err := json.Marshal(review)
+	assert.NoError(t, err)
+	return body
+}
+
+func serveAdmissionReview(t *testing.T, server *WebhookServer, body []byte) *admissionv1beta1.AdmissionResponse {
+	req := httptest.NewRequest(http.MethodPost, ValidateRolloutPath, bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	server.Handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	review := admissionv1beta1.AdmissionReview{}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &review))
+	assert.Equal(t, types.UID("review-uid"), review.Response.UID)
+	return review.Response
+}
+
+func TestWebhookServerRejectsMissingTemplate(t *testing.T) {
+	server := NewWebhookServer(ServerConfig{ArgoprojClientset: fake.NewSimpleClientset()})
+	rollout := newCanaryRollout(&v1alpha1.RolloutAnalysis{
+		Templates: []v1alpha1.RolloutAnalysisTemplate{{TemplateName: "success-rate"}},
+	})
+	response := serveAdmissionReview(t, server, newAdmissionReview(t, admissionv1beta1.Create, rollout))
+	assert.False(t, response.Allowed)
+	assert.Equal(t, int32(http.StatusUnprocessableEntity), response.Result.Code)
+	assert.Contains(t, response.Result.Message, "The Rollout \"guestbook\" is invalid")
+	assert.Contains(t, response.Result.Message, "AnalysisTemplate 'success-rate' not found in namespace 'default'")
+}
+
+func TestWebhookServerAllowsExistingTemplate(t *testing.T) {
+	server := NewWebhookServer(ServerConfig{ArgoprojClientset: fake.NewSimpleClientset(newAnalysisTemplate("success-rate"))})
+	rollout := newCanaryRollout(&v1alpha1.RolloutAnalysis{
+		Templates: []v1alpha1.RolloutAnalysisTemplate{{TemplateName: "success-rate"}},
+	})
+	response := serveAdmissionReview(t, server, newAdmissionReview(t, admissionv1beta1.Update, rollout))
+	assert.True(t, response.Allowed)
+}
+
+func TestWebhookServerIgnoresDelete(t *testing.T) {
+	server := NewWebhookServer(ServerConfig{ArgoprojClientset: fake.NewSimpleClientset()})
+	rollout := newCanaryRollout(&v1alpha1.RolloutAnalysis{
+		Templates: []v1alpha1.RolloutAnalysisTemplate{{TemplateName: "success-rate"}},
+	})
+	response := serveAdmissionReview(t, server, newAdmissionReview(t, admissionv1beta1.Delete, rollout))
+	assert.True(t, response.Allowed)
+}
+
+func TestWebhookServerInvalidRequest(t *testing.T) {
+	server := NewWebhookServer(ServerConfig{ArgoprojClientset: fake.NewSimpleClientset()})
+
+	req := httptest.NewRequest(http.MethodGet, ValidateRolloutPath, nil)
+	rr := httptest.NewRecorder()
+	server.Handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+
+	req = httptest.NewRequest(http.MethodPost, ValidateRolloutPath, bytes.NewReader([]byte("{}")))
+	rr = httptest.NewRecorder()
+	server.Handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func serveAnalysisAdmissionReview(t *testing.T, server *WebhookServer, body []byte) *admissionv1beta1.AdmissionResponse {
+	req := httptest.NewRequest(http.MethodPost, ValidateAnalysisPath, bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	server.Handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	review := admissionv1beta1.AdmissionReview{}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &review))
+	return review.Response
+}
+
+func TestWebhookServerRejectsDisallowedProvider(t *testing.T) {
+	server := NewWebhookServer(ServerConfig{ArgoprojClientset: fake.NewSimpleClientset(), AllowedProviders: []string{"Prometheus"}})
+	run := &v1alpha1.AnalysisRun{
+		TypeMeta: metav1.TypeMeta{Kind: "AnalysisRun"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "guestbook-analysis",
+			Namespace: metav1.NamespaceDefault,
+		},
+		Spec: v1alpha1.AnalysisRunSpec{
+			Metrics: []v1alpha1.Metric{{
+				Name: "integration-test",
+				Provider: v1alpha1.MetricProvider{
+					Job: &v1alpha1.JobMetric{},
+				},
+			}},
+		},
+	}
+	response := serveAnalysisAdmissionReview(t, server, newAdmissionReview(t, admissionv1beta1.Create, run))
+	assert.False(t, response.Allowed)
+	assert.Equal(t, "The AnalysisRun \"guestbook-analysis\" is invalid: provider 'job' of metric 'integration-test' is not allowed", response.Result.Message)
+
+	// Templates are validated as well
+	template := newClusterAnalysisTemplate("integration-test")
+	template.Kind = "ClusterAnalysisTemplate"
+	template.Spec.Metrics[0].Provider.Job = &v1alpha1.JobMetric{}
+	response = serveAnalysisAdmissionReview(t, server, newAdmissionReview(t, admissionv1beta1.Create, template))
+	assert.False(t, response.Allowed)
+	assert.Contains(t, response.Result.Message, "The ClusterAnalysisTemplate \"integration-test\" is invalid")
+
+	run.Spec.Metrics[0].Provider = v1alpha1.MetricProvider{Prometheus: &v1alpha1.PrometheusMetric{}}
+	response = serveAnalysisAdmissionReview(t, server, newAdmissionReview(t, admissionv1beta1.Create, run))
+	assert.True(t, response.Allowed)
+}
+
+func TestWebhookServerAllowsAllProvidersByDefault(t *testing.T) {
+	server := NewWebhookServer(ServerConfig{ArgoprojClientset: fake.NewSimpleClientset()})
+	template := newAnalysisTemplate("integration-test")
+	template.Spec.Metrics[0].Provider.Job = &v1alpha1.JobMetric{}
+	response := serveAnalysisAdmissionReview(t, server, newAdmissionReview(t, admissionv1beta1.Create, template))
+	assert.True(t, response.Allowed)
+}
+
+func TestWebhookServerRejectsUndeclaredJobArgs(t *testing.T) {
+	server := NewWebhookServer(ServerConfig{ArgoprojClientset: fake.NewSimpleClientset()})
+	template := newAnalysisTemplate("integration-test", "environment")
+	template.Kind = "AnalysisTemplate"
+	template.Spec.Metrics[0].Provider.Job = &v1alpha1.JobMetric{
+		Spec: batchv1.JobSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name:    "test",
+						Command: []string{"./run-tests.sh", "--env", "{{args.environment}}", "--region", "{{args.region}}"},
+					}},
+				},
+			},
+		},
+	}
+	response := serveAnalysisAdmissionReview(t, server, newAdmissionReview(t, admissionv1beta1.Create, template))
+	assert.False(t, response.Allowed)
+	assert.Equal(t, "The AnalysisTemplate \"integration-test\" is invalid: metric 'integration-test': container 'test' references args.region which is not an argument", response.Result.Message)
+
+	template.Spec.Args = append(template.Spec.Args, v1alpha1.Argument{Name: "region"})
+	response = serveAnalysisAdmissionReview(t, server, newAdmissionReview(t, admissionv1beta1.Create, template))
+	assert.True(t, response.Allowed)
+}